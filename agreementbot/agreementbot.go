@@ -28,6 +28,7 @@ const DATABASE_HEARTBEAT = "AgbotDatabaseHeartBeat"
 const GOVERN_AGREEMENTS = "AgBotGovernAgreements"
 const GOVERN_ARCHIVED_AGREEMENTS = "AgBotGovernArchivedAgreements"
 const GOVERN_BC_NEEDS = "AgBotGovernBlockchain"
+const GOVERN_ROLLOUTS = "AgBotGovernRollouts"
 const POLICY_WATCHER = "AgBotPolicyWatcher"
 const GENERATE_POLICY = "AgBotPolicyGenerator"
 const STALE_PARTITIONS = "AgbotStaleDatabasePartition"
@@ -337,6 +338,7 @@ func (w *AgreementBotWorker) Initialize() bool {
 	w.DispatchSubworker(GOVERN_AGREEMENTS, w.GovernAgreements, int(w.BaseWorker.Manager.Config.AgreementBot.ProcessGovernanceIntervalS))
 	w.DispatchSubworker(GOVERN_ARCHIVED_AGREEMENTS, w.GovernArchivedAgreements, 1800)
 	w.DispatchSubworker(GOVERN_BC_NEEDS, w.GovernBlockchainNeeds, 60)
+	w.DispatchSubworker(GOVERN_ROLLOUTS, w.GovernRollouts, int(w.BaseWorker.Manager.Config.AgreementBot.ProcessGovernanceIntervalS))
 	if w.Config.AgreementBot.CheckUpdatedPolicyS != 0 {
 		// Use custom subworker APIs for the policy watcher because it is stateful and already does its own time management.
 		ch := w.AddSubworker(POLICY_WATCHER)
@@ -389,6 +391,10 @@ func (w *AgreementBotWorker) CommandHandler(command worker.Command) bool {
 				}
 			}
 
+			// Start a staged rollout if the policy is upgrading to a new service version that has a rollout policy. This
+			// has to happen before the protocol handlers cancel the agreements that don't match the changed policy.
+			w.updateRollout(cmd.Msg.Org(), pol)
+
 			// Send the policy change command to all protocol handlers just in case an agreement protocol was
 			// deleted from the new policy file.
 			for agp, _ := range w.consumerPH {
@@ -412,6 +418,11 @@ func (w *AgreementBotWorker) CommandHandler(command worker.Command) bool {
 			w.pm.DeletePolicy(cmd.Msg.Org(), pol)
 			glog.V(5).Infof("AgreementBotWorker deleted policy from PM.")
 
+			// Remove the staged rollout of the policy, if there is one.
+			if err := w.db.DeleteRollout(pol.Header.Name); err != nil {
+				glog.Errorf(fmt.Sprintf("AgreementBotWorker unable to delete rollout for policy %v, error: %v", pol.Header.Name, err))
+			}
+
 			// Queue the command to the correct protocol worker pool(s) for further processing. The deleted policy
 			// might not contain a supported protocol, so we need to check that first.
 			for _, agp := range pol.AgreementProtocols {
@@ -993,7 +1004,7 @@ func (w *AgreementBotWorker) syncOnInit() error {
 							glog.Errorf(AWlogString(fmt.Sprintf("error marking agreement %v terminated: %v", ag.CurrentAgreementId, err)))
 						}
 						w.consumerPH[agp].HandleAgreementTimeout(NewAgreementTimeoutCommand(ag.CurrentAgreementId, ag.AgreementProtocol, w.consumerPH[agp].GetTerminationCode(TERM_REASON_POLICY_CHANGED)), w.consumerPH[agp])
-					} else if err := w.pm.MatchesMine(ag.Org, pol); err != nil {
+					} else if err := w.pm.MatchesMine(ag.Org, pol); err != nil && !rolloutDefersUpgrade(w.db, w.pm, ag.Org, &ag, pol) {
 						glog.Warningf(AWlogString(fmt.Sprintf("agreement %v has a policy %v that has changed: %v", ag.CurrentAgreementId, pol.Header.Name, err)))
 
 						// Remove any workload usage records (non-HA) or mark for pending upgrade (HA). There might not be a workload usage record
@@ -1010,10 +1021,10 @@ func (w *AgreementBotWorker) syncOnInit() error {
 								}
 							}
 							// Choose this device's agreement within the HA group to start upgrading
-							w.cleanupAgreement(&ag)
+							w.cleanupAgreement(&ag, policyChangeReason(existingPol, &ag))
 						} else {
 							// Non-HA device or agrement without workload priority in the policy, re-make the agreement
-							w.cleanupAgreement(&ag)
+							w.cleanupAgreement(&ag, policyChangeReason(existingPol, &ag))
 						}
 					} else if err := w.pm.AttemptingAgreement([]policy.Policy{*existingPol}, ag.CurrentAgreementId, ag.Org); err != nil {
 						glog.Errorf(AWlogString(fmt.Sprintf("cannot update agreement count for %v, error: %v", ag.CurrentAgreementId, err)))
@@ -1087,7 +1098,7 @@ func (w *AgreementBotWorker) syncOnInit() error {
 	return nil
 }
 
-func (w *AgreementBotWorker) cleanupAgreement(ag *persistence.Agreement) {
+func (w *AgreementBotWorker) cleanupAgreement(ag *persistence.Agreement, reason string) {
 	// Update state in exchange
 	if err := DeleteConsumerAgreement(w.Config.Collaborators.HTTPClientFactory.NewHTTPClient(nil), w.GetExchangeURL(), w.GetExchangeId(), w.GetExchangeToken(), ag.CurrentAgreementId); err != nil {
 		glog.Errorf(AWlogString(fmt.Sprintf("error deleting agreement %v in exchange: %v", ag.CurrentAgreementId, err)))
@@ -1103,7 +1114,7 @@ func (w *AgreementBotWorker) cleanupAgreement(ag *persistence.Agreement) {
 		glog.Errorf(AWlogString(fmt.Sprintf("error marking agreement %v terminated: %v", ag.CurrentAgreementId, err)))
 	}
 
-	w.consumerPH[ag.AgreementProtocol].HandleAgreementTimeout(NewAgreementTimeoutCommand(ag.CurrentAgreementId, ag.AgreementProtocol, w.consumerPH[ag.AgreementProtocol].GetTerminationCode(TERM_REASON_POLICY_CHANGED)), w.consumerPH[ag.AgreementProtocol])
+	w.consumerPH[ag.AgreementProtocol].HandleAgreementTimeout(NewAgreementTimeoutCommand(ag.CurrentAgreementId, ag.AgreementProtocol, w.consumerPH[ag.AgreementProtocol].GetTerminationCode(reason)), w.consumerPH[ag.AgreementProtocol])
 }
 
 func (w *AgreementBotWorker) recordConsumerAgreementState(agreementId string, pol *policy.Policy, org string, state string) error {
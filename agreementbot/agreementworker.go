@@ -100,6 +100,8 @@ type CancelAgreement struct {
 	Protocol    string
 	Reason      uint
 	MessageId   int
+	DeviceId    string
+	Prefetch    *policy.Workload // The workload version the device can fetch before the cancel, which it might hold.
 }
 
 func (c CancelAgreement) Type() string {
@@ -255,13 +257,22 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 	found := true // if the service policy can be found from the businesspol_manager
 	var sPol *externalpolicy.ExternalPolicy
 
+	// A staged rollout of the policy might hold the device at a lower priority workload until it is the device's turn to upgrade.
+	rolloutPriority := rolloutStartingPriority(b.db, wi.Device.Id, wi.ConsumerPolicy.Header.Name)
+
 	for !foundWorkload {
 
 		if wlUsage, err := b.db.FindSingleWorkloadUsageByDeviceAndPolicyName(wi.Device.Id, wi.ConsumerPolicy.Header.Name); err != nil {
 			glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error searching for persistent workload usage records for device %v with policy %v, error: %v", wi.Device.Id, wi.ConsumerPolicy.Header.Name, err)))
 			return
 		} else if wlUsage == nil {
-			workload = wi.ConsumerPolicy.NextHighestPriorityWorkload(0, 0, 0)
+			workload = wi.ConsumerPolicy.NextHighestPriorityWorkload(rolloutPriority, 0, 0)
+		} else if rolloutPriority != 0 && wlUsage.Priority < rolloutPriority {
+			workload = wi.ConsumerPolicy.NextHighestPriorityWorkload(rolloutPriority, 0, 0)
+			if _, err := b.db.UpdatePriority(wi.Device.Id, wi.ConsumerPolicy.Header.Name, workload.Priority.PriorityValue, workload.Priority.RetryDurationS, workload.Priority.VerifiedDurationS, agreementIdString); err != nil {
+				glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error updating priority in persistent workload usage records for device %v with policy %v, error: %v", wi.Device.Id, wi.ConsumerPolicy.Header.Name, err)))
+				return
+			}
 		} else if wlUsage.DisableRetry {
 			workload = wi.ConsumerPolicy.NextHighestPriorityWorkload(wlUsage.Priority, 0, wlUsage.FirstTryTime)
 		} else if wlUsage != nil {
@@ -517,6 +528,7 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 		// Initiate the protocol
 	} else if proposal, err := protocolHandler.InitiateAgreement(agreementIdString, &wi.ProducerPolicy, &wi.ConsumerPolicy, wi.Org, cph.GetExchangeId(), mt, workload, b.config.AgreementBot.DefaultWorkloadPW, b.config.AgreementBot.NoDataIntervalS, cph.GetSendMessage()); err != nil {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error initiating agreement: %v", err)))
+		proposalAttempts.Inc(cph.Name(), "failed")
 
 		// Remove pending agreement from database
 		if err := b.db.DeleteAgreement(agreementIdString, cph.Name()); err != nil {
@@ -526,8 +538,11 @@ func (b *BaseAgreementWorker) InitiateNewAgreement(cph ConsumerProtocolHandler,
 		// TODO: Publish error on the message bus
 
 		// Update the agreement in the DB with the proposal and policy
-	} else if err := cph.PersistAgreement(wi, proposal, workerId); err != nil {
-		glog.Errorf(err.Error())
+	} else {
+		proposalAttempts.Inc(cph.Name(), "sent")
+		if err := cph.PersistAgreement(wi, proposal, workerId); err != nil {
+			glog.Errorf(err.Error())
+		}
 	}
 
 }
@@ -676,6 +691,12 @@ func (b *BaseAgreementWorker) VerifyUserInputForServiceDef(sdef *exchange.Servic
 				//if err := cutil.VerifyWorkloadVarTypes(mui.Value, ui.Type); err != nil {
 				//	return fmt.Errorf("Failed to validate the user input type for variable %v for service %v/%v %v %v. %v",  ui.Name, svcOrg, sdef.URL, sdef.Version, sdef.Arch, err)
 				//}
+				if mui.IsSecret() && mui.Value == policy.SECRET_PLACEHOLDER {
+					// the value of a secret is only on the node, which checks it against the schema
+					continue
+				} else if err := ui.Schema.Check(ui.Type, mui.Value); err != nil {
+					return fmt.Errorf("The user input value for variable %v for service %v/%v %v %v does not meet the schema, %v.", ui.Name, svcOrg, sdef.URL, sdef.Version, sdef.Arch, err)
+				}
 			}
 		}
 
@@ -748,12 +769,15 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 				// There could have been a change in the system such that the chosen workload is no longer the right choice. If this
 				// is the case, then we need to reject the agreement and start over.
 
-				workload := consumerPolicy.NextHighestPriorityWorkload(0, 0, 0)
+				// A staged rollout might be holding the device at a lower priority workload. The workload usage record is removed when
+				// the agreement ends, so that the next agreement starts again from the priority the rollout allows at that time.
+				rolloutPriority := rolloutStartingPriority(b.db, wi.SenderId, consumerPolicy.Header.Name)
+				workload := consumerPolicy.NextHighestPriorityWorkload(rolloutPriority, 0, 0)
 				if !workload.Priority.IsSame(pol.Workloads[0].Priority) {
 					// Need a new workload usage record but not the same as the highest priority. That can't be right.
 					ackReplyAsValid = false
 				} else if !pol.Workloads[0].HasEmptyPriority() {
-					if err := b.db.NewWorkloadUsage(wi.SenderId, pol.HAGroup.Partners, agreement.Policy, consumerPolicy.Header.Name, pol.Workloads[0].Priority.PriorityValue, pol.Workloads[0].Priority.RetryDurationS, pol.Workloads[0].Priority.VerifiedDurationS, false, reply.AgreementId()); err != nil {
+					if err := b.db.NewWorkloadUsage(wi.SenderId, pol.HAGroup.Partners, agreement.Policy, consumerPolicy.Header.Name, pol.Workloads[0].Priority.PriorityValue, pol.Workloads[0].Priority.RetryDurationS, pol.Workloads[0].Priority.VerifiedDurationS, rolloutPriority != 0, reply.AgreementId()); err != nil {
 						glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("error creating persistent workload usage records for device %v with policy %v, error: %v", wi.SenderId, consumerPolicy.Header.Name, err)))
 					}
 				}
@@ -854,6 +878,7 @@ func (b *BaseAgreementWorker) HandleAgreementReply(cph ConsumerProtocolHandler,
 
 	} else {
 		glog.Errorf(BAWlogstring(workerId, fmt.Sprintf("received rejection from producer %v", reply)))
+		proposalRejections.Inc(cph.Name())
 
 		b.CancelAgreement(cph, reply.AgreementId(), cph.GetTerminationCode(TERM_REASON_NEGATIVE_REPLY), workerId)
 	}
@@ -10,6 +10,7 @@ import (
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/metrics"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/worker"
 	"io/ioutil"
@@ -46,6 +47,12 @@ func NewAPIListener(name string, config *config.HorizonConfig, db persistence.Ag
 		em:   events.NewEventStateManager(),
 	}
 
+	// The agreement and partition metrics are computed from the database when the metrics are scraped.
+	if db != nil {
+		metrics.RegisterCollector("agbot agreements", listener.collectAgreementMetrics)
+		metrics.RegisterCollector("agbot partitions", listener.collectPartitionMetrics)
+	}
+
 	listener.listen(config.AgreementBot.APIListen)
 	return listener
 }
@@ -187,9 +194,12 @@ func (a *API) listen(apiListen string) {
 		router.HandleFunc("/policy/{org}/{name}", a.policy).Methods("GET", "OPTIONS")
 		router.HandleFunc("/policy/{name}/upgrade", a.policy).Methods("POST", "OPTIONS")
 		router.HandleFunc("/workloadusage", a.workloadusage).Methods("GET", "OPTIONS")
+		router.HandleFunc("/rollout", a.rollout).Methods("GET", "OPTIONS")
+		router.HandleFunc("/rollout/{org}/{name}", a.rollout).Methods("GET", "OPTIONS")
 		router.HandleFunc("/status", a.status).Methods("GET", "OPTIONS")
 		router.HandleFunc("/status/workers", a.workerstatus).Methods("GET", "OPTIONS")
 		router.HandleFunc("/node", a.node).Methods("GET", "DELETE", "OPTIONS")
+		router.HandleFunc("/metrics", metrics.Handler).Methods("GET", "OPTIONS")
 
 		if err := http.ListenAndServe(apiListen, nocache(router)); err != nil {
 			glog.Fatalf(APIlogString(fmt.Sprintf("failed to start listener on %v, error %v", apiListen, err)))
@@ -459,6 +469,44 @@ func (a *API) workloadusage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Return the staged rollouts of business policy service versions, or the rollout of one business policy.
+func (a *API) rollout(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case "GET":
+		pathVars := mux.Vars(r)
+		org := pathVars["org"]
+		name := pathVars["name"]
+
+		if org == "" {
+			if rollouts, err := a.db.FindRollouts([]persistence.ROFilter{}); err != nil {
+				glog.Error(APIlogString(fmt.Sprintf("error finding all rollouts, error: %v", err)))
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+			} else {
+
+				// do sort
+				sort.Sort(RolloutsByPolicyName(rollouts))
+
+				// write output
+				writeResponse(w, rollouts, http.StatusOK)
+			}
+		} else if rollout, err := a.db.FindSingleRolloutByPolicyName(fmt.Sprintf("%v/%v", org, name)); err != nil {
+			glog.Error(APIlogString(fmt.Sprintf("error finding rollout for %v/%v, error: %v", org, name, err)))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		} else if rollout == nil {
+			writeInputErr(w, http.StatusBadRequest, &APIUserInputError{Input: "name", Error: "rollout not found"})
+		} else {
+			writeResponse(w, rollout, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 func (a *API) status(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
@@ -708,6 +756,21 @@ func (s WorkloadUsagesByDeviceId) Less(i, j int) bool {
 	return s[i].DeviceId < s[j].DeviceId
 }
 
+// Helper functions for sorting rollouts
+type RolloutsByPolicyName []persistence.Rollout
+
+func (s RolloutsByPolicyName) Len() int {
+	return len(s)
+}
+
+func (s RolloutsByPolicyName) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+}
+
+func (s RolloutsByPolicyName) Less(i, j int) bool {
+	return s[i].PolicyName < s[j].PolicyName
+}
+
 // Log string prefix api
 var APIlogString = func(v interface{}) string {
 	return fmt.Sprintf("AgreementBotWorker API %v", v)
@@ -73,6 +73,27 @@ func (b BAgreementVerification) String() string {
 		b.workType, b.Verify, b.From, b.SenderId, pkey, b.MessageId)
 }
 
+const WORKLOAD_PREFETCH = "WORKLOAD_PREFETCH"
+
+type BWorkloadPrefetch struct {
+	workType    string
+	AgreementId string
+	DeviceId    string
+	Workload    policy.Workload
+}
+
+func (b BWorkloadPrefetch) Type() string {
+	return b.workType
+}
+
+func (b BWorkloadPrefetch) String() string {
+	return fmt.Sprintf("WorkType: %v, "+
+		"AgreementId: %v, "+
+		"DeviceId: %v, "+
+		"Workload: %v",
+		b.workType, b.AgreementId, b.DeviceId, b.Workload.ShortString())
+}
+
 // This function receives an event to "make a new agreement" from the Process function, and then synchronously calls a function
 // to actually work through the agreement protocol.
 func (a *BasicAgreementWorker) start(work chan AgreementWork, random *rand.Rand) {
@@ -108,6 +129,9 @@ func (a *BasicAgreementWorker) start(work chan AgreementWork, random *rand.Rand)
 
 		} else if workItem.Type() == CANCEL {
 			wi := workItem.(CancelAgreement)
+			if wi.Prefetch != nil {
+				a.sendWorkloadPrefetch(wi.AgreementId, wi.DeviceId, wi.Prefetch)
+			}
 			a.CancelAgreementWithLock(a.protocolHandler, wi.AgreementId, wi.Reason, a.workerID)
 
 			// Get rid of the original agreement cancellation message.
@@ -156,6 +180,10 @@ func (a *BasicAgreementWorker) start(work chan AgreementWork, random *rand.Rand)
 				}
 			}
 
+		} else if workItem.Type() == WORKLOAD_PREFETCH {
+			wi := workItem.(BWorkloadPrefetch)
+			a.sendWorkloadPrefetch(wi.AgreementId, wi.DeviceId, &wi.Workload)
+
 		} else if workItem.Type() == MMS_OBJECT_POLICY {
 			// Handle an update to an object policy. The source for this function is in the policy.go file.
 			wi := workItem.(ObjectPolicyChange)
@@ -178,6 +206,21 @@ func (a *BasicAgreementWorker) start(work chan AgreementWork, random *rand.Rand)
 
 }
 
+// Tell the device which workload version its agreement is going to be upgraded to.
+func (a *BasicAgreementWorker) sendWorkloadPrefetch(agreementId string, deviceId string, workload *policy.Workload) {
+	if whisperTo, pubkeyTo, err := a.protocolHandler.GetDeviceMessageEndpoint(deviceId, a.workerID); err != nil {
+		glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error obtaining message target for workload prefetch: %v", err)))
+	} else if mt, err := exchange.CreateMessageTarget(deviceId, nil, pubkeyTo, whisperTo); err != nil {
+		glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error creating message target: %v", err)))
+	} else if aph, ok := a.protocolHandler.AgreementProtocolHandler("", "", "").(*basicprotocol.ProtocolHandler); !ok {
+		glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error casting to basic protocol handler (%T)", a.protocolHandler.AgreementProtocolHandler("", "", ""))))
+	} else if err := aph.SendWorkloadPrefetch(agreementId, workload, mt, a.protocolHandler.GetSendMessage()); err != nil {
+		glog.Errorf(bwlogstring(a.workerID, fmt.Sprintf("error trying to send workload prefetch for %v to %v, error: %v", agreementId, mt, err)))
+	} else {
+		glog.V(3).Infof(bwlogstring(a.workerID, fmt.Sprintf("sent workload prefetch of version %v for agreement %v", workload.Version, agreementId)))
+	}
+}
+
 var bwlogstring = func(workerID string, v interface{}) string {
 	return fmt.Sprintf("BasicAgreementWorker (%v): %v", workerID, v)
 }
@@ -162,6 +162,8 @@ func (c *BasicProtocolHandler) GetTerminationCode(reason string) uint {
 		return basicprotocol.AB_CANCEL_NODE_HEARTBEAT
 	case TERM_REASON_AG_MISSING:
 		return basicprotocol.AB_CANCEL_AG_MISSING
+	case TERM_REASON_WORKLOAD_UPGRADE:
+		return basicprotocol.AB_CANCEL_WORKLOAD_UPGRADE
 	default:
 		return 999
 	}
@@ -199,6 +201,18 @@ func (b *BasicProtocolHandler) PostReply(agreementId string, proposal abstractpr
 
 }
 
+// Queue a workload prefetch hint to the device of the agreement.
+func (b *BasicProtocolHandler) PrefetchWorkload(agreement *persistence.Agreement, workload *policy.Workload) {
+	agreementWork := BWorkloadPrefetch{
+		workType:    WORKLOAD_PREFETCH,
+		AgreementId: agreement.CurrentAgreementId,
+		DeviceId:    agreement.DeviceId,
+		Workload:    *workload,
+	}
+	b.WorkQueue() <- agreementWork
+	glog.V(5).Infof(BsCPHlogString(fmt.Sprintf("queued workload prefetch for agreement %v", agreement.CurrentAgreementId)))
+}
+
 func (b *BasicProtocolHandler) HandleExtensionMessage(cmd *NewProtocolMessageCommand) error {
 	glog.V(5).Infof(BsCPHlogString(fmt.Sprintf("received inbound exchange message.")))
 	// Figure out what kind of message this is
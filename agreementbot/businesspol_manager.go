@@ -54,6 +54,7 @@ type BusinessPolicyEntry struct {
 	Updated         uint64                         `json:"updatedTime,omitempty"`     // the time when this entry was updated
 	Hash            []byte                         `json:"hash,omitempty"`            // a hash of the business policy to compare for matadata changes in the exchange
 	ServicePolicies map[string]*ServicePolicyEntry `json:"servicePolicies,omitempty"` // map of the service id and service policies
+	Rollout         *businesspolicy.RolloutPolicy  `json:"rollout,omitempty"`         // the staged rollout policy of the highest priority service version
 }
 
 // Create a new BusinessPolicyEntry. It converts the businesspolicy to internal policy format.
@@ -76,6 +77,7 @@ func NewBusinessPolicyEntry(pol *businesspolicy.BusinessPolicy, polId string) (*
 		return nil, fmt.Errorf("Failed to convert the business policy to internal policy format: %v. %v", *pol, err)
 	} else {
 		pBE.Policy = pPolicy
+		pBE.Rollout = pol.Service.HighestPriorityChoice().Upgrade.Rollout
 	}
 
 	return pBE, nil
@@ -186,6 +188,7 @@ func (p *BusinessPolicyEntry) UpdateEntry(pol *businesspolicy.BusinessPolicy, po
 		return nil, fmt.Errorf("Failed to convert the business policy to internal policy format: %v. %v", *pol, err)
 	} else {
 		p.Policy = pPolicy
+		p.Rollout = pol.Service.HighestPriorityChoice().Upgrade.Rollout
 		return pPolicy, nil
 	}
 }
@@ -61,6 +61,7 @@ type ConsumerProtocolHandler interface {
 	PostReply(agreementId string, proposal abstractprotocol.Proposal, reply abstractprotocol.ProposalReply, consumerPolicy *policy.Policy, org string, workerId string) error
 	UpdateProducer(ag *persistence.Agreement)
 	HandleExtensionMessage(cmd *NewProtocolMessageCommand) error
+	PrefetchWorkload(agreement *persistence.Agreement, workload *policy.Workload)
 	AlreadyReceivedReply(ag *persistence.Agreement) bool
 	GetKnownBlockchain(ag *persistence.Agreement) (string, string, string)
 	CanSendMeterRecord(ag *persistence.Agreement) bool
@@ -266,9 +267,15 @@ func (b *BaseConsumerProtocolHandler) HandlePolicyChanged(cmd *PolicyChangedComm
 					// This agreement is using a policy different from the one that changed.
 					glog.V(5).Infof(BCPHlogstring(b.Name(), fmt.Sprintf("policy change handler skipping agreement %v because it is using a policy that did not change.", ag.CurrentAgreementId)))
 					continue
-				} else if err := b.pm.MatchesMine(cmd.Msg.Org(), pol); err != nil {
+				} else if err := b.pm.MatchesMine(cmd.Msg.Org(), pol); err != nil && rolloutDefersUpgrade(b.db, b.pm, cmd.Msg.Org(), &ag, pol) {
+					glog.V(3).Infof(BCPHlogstring(b.Name(), fmt.Sprintf("agreement %v has a policy %v that has changed, the staged rollout of the policy will upgrade it", ag.CurrentAgreementId, pol.Header.Name)))
+					if workload := upgradeWorkload(eventPol, &ag); workload != nil {
+						cph.PrefetchWorkload(&ag, workload)
+					}
+				} else if err != nil {
 					glog.Warningf(BCPHlogstring(b.Name(), fmt.Sprintf("agreement %v has a policy %v that has changed: %v", ag.CurrentAgreementId, pol.Header.Name, err)))
-					b.CancelAgreement(ag, TERM_REASON_POLICY_CHANGED, cph)
+					// The device might hold the cancel until its maintenance window opens, let it fetch the new version in the meantime.
+					b.CancelAgreement(ag, policyChangeReason(eventPol, &ag), upgradeWorkload(eventPol, &ag), cph)
 				} else {
 					glog.V(5).Infof(BCPHlogstring(b.Name(), fmt.Sprintf("for agreement %v, no policy content differences detected", ag.CurrentAgreementId)))
 				}
@@ -280,6 +287,42 @@ func (b *BaseConsumerProtocolHandler) HandlePolicyChanged(cmd *PolicyChangedComm
 	}
 }
 
+// Returns the workload that the agreement is going to be upgraded to now that its policy has changed, or nil when the
+// highest priority workload of the changed policy is a different service or the version the agreement runs.
+func upgradeWorkload(changedPol *policy.Policy, ag *persistence.Agreement) *policy.Workload {
+	if len(changedPol.Workloads) == 0 {
+		return nil
+	}
+
+	current, err := agreementWorkload(ag)
+	if err != nil {
+		glog.Errorf(BCPHlogstring(ag.AgreementProtocol, fmt.Sprintf("unable to read the workload of agreement %v, error %v", ag.CurrentAgreementId, err)))
+		return nil
+	} else if current == nil {
+		return nil
+	}
+
+	next := *changedPol.NextHighestPriorityWorkload(0, 0, 0)
+	if next.WorkloadURL != current.WorkloadURL || next.Org != current.Org || next.Version == current.Version {
+		return nil
+	}
+
+	// The workload in the agreement has the arch of the device.
+	if next.Arch == "" || next.Arch == "*" {
+		next.Arch = current.Arch
+	}
+	return &next
+}
+
+// Returns the termination reason of an agreement whose policy changed. The device is told that it is an upgrade only when
+// the changed policy moves the agreement to another version of its workload.
+func policyChangeReason(changedPol *policy.Policy, ag *persistence.Agreement) string {
+	if upgradeWorkload(changedPol, ag) != nil {
+		return TERM_REASON_WORKLOAD_UPGRADE
+	}
+	return TERM_REASON_POLICY_CHANGED
+}
+
 func (b *BaseConsumerProtocolHandler) HandlePolicyDeleted(cmd *PolicyDeletedCommand, cph ConsumerProtocolHandler) {
 	glog.V(5).Infof(BCPHlogstring(b.Name(), "received policy deleted command."))
 
@@ -331,7 +374,7 @@ func (b *BaseConsumerProtocolHandler) HandleServicePolicyChanged(cmd *ServicePol
 			if ag.Pattern == "" && ag.PolicyName == fmt.Sprintf("%v/%v", cmd.Msg.BusinessPolOrg, cmd.Msg.BusinessPolName) && ag.ServiceId[0] == cmd.Msg.ServiceId {
 
 				glog.Warningf(BCPHlogstring(b.Name(), fmt.Sprintf("agreement %v has a service policy %v that has changed.", ag.CurrentAgreementId, ag.ServiceId)))
-				b.CancelAgreement(ag, TERM_REASON_POLICY_CHANGED, cph)
+				b.CancelAgreement(ag, TERM_REASON_POLICY_CHANGED, nil, cph)
 			}
 		}
 	} else {
@@ -383,7 +426,8 @@ func (b *BaseConsumerProtocolHandler) HandleMMSObjectPolicy(cmd *MMSObjectPolicy
 	glog.V(5).Infof(BCPHlogstring(b.Name(), fmt.Sprintf("queued object policy change command.")))
 }
 
-func (b *BaseConsumerProtocolHandler) CancelAgreement(ag persistence.Agreement, reason string, cph ConsumerProtocolHandler) {
+// Cancel the agreement. When prefetch is not nil, the device is told to fetch that workload before it receives the cancel.
+func (b *BaseConsumerProtocolHandler) CancelAgreement(ag persistence.Agreement, reason string, prefetch *policy.Workload, cph ConsumerProtocolHandler) {
 	// Remove any workload usage records (non-HA) or mark for pending upgrade (HA). There might not be a workload usage record
 	// if the consumer policy does not specify the workload priority section.
 	if wlUsage, err := b.db.FindSingleWorkloadUsageByDeviceAndPolicyName(ag.DeviceId, ag.PolicyName); err != nil {
@@ -407,6 +451,8 @@ func (b *BaseConsumerProtocolHandler) CancelAgreement(ag persistence.Agreement,
 			AgreementId: ag.CurrentAgreementId,
 			Protocol:    ag.AgreementProtocol,
 			Reason:      cph.GetTerminationCode(reason),
+			DeviceId:    ag.DeviceId,
+			Prefetch:    prefetch,
 		}
 		cph.WorkQueue() <- agreementWork
 	} else {
@@ -420,6 +466,8 @@ func (b *BaseConsumerProtocolHandler) CancelAgreement(ag persistence.Agreement,
 			AgreementId: ag.CurrentAgreementId,
 			Protocol:    ag.AgreementProtocol,
 			Reason:      cph.GetTerminationCode(reason),
+			DeviceId:    ag.DeviceId,
+			Prefetch:    prefetch,
 		}
 		cph.WorkQueue() <- agreementWork
 	}
@@ -625,6 +673,10 @@ func (c *BaseConsumerProtocolHandler) CanSendMeterRecord(ag *persistence.Agreeme
 	return true
 }
 
+func (b *BaseConsumerProtocolHandler) PrefetchWorkload(agreement *persistence.Agreement, workload *policy.Workload) {
+	return
+}
+
 func (b *BaseConsumerProtocolHandler) SendEventMessage(event events.Message) {
 	b.messages <- event
 }
@@ -643,6 +695,7 @@ const TERM_REASON_CANCEL_FORCED_UPGRADE = "ForceUpgrade"
 const TERM_REASON_CANCEL_BC_WRITE_FAILED = "WriteFailed"
 const TERM_REASON_NODE_HEARTBEAT = "NodeHeartbeat"
 const TERM_REASON_AG_MISSING = "AgreementMissing"
+const TERM_REASON_WORKLOAD_UPGRADE = "WorkloadUpgrade"
 
 var BCPHlogstring = func(p string, v interface{}) string {
 	return fmt.Sprintf("Base Consumer Protocol Handler (%v) %v", p, v)
@@ -0,0 +1,68 @@
+// +build unit
+
+package agreementbot
+
+import (
+	"encoding/json"
+	"github.com/open-horizon/anax/abstractprotocol"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/policy"
+	"testing"
+)
+
+// an agreement whose proposal runs the workload
+func testAgreement(t *testing.T, workload *policy.Workload) *persistence.Agreement {
+	tsandcs := policy.Policy_Factory("merged")
+	tsandcs.Workloads = append(tsandcs.Workloads, *workload)
+	if polBytes, err := json.Marshal(tsandcs); err != nil {
+		t.Errorf("unable to marshal the policy, error %v", err)
+	} else if propBytes, err := json.Marshal(abstractprotocol.NewProposal("Basic", 2, string(polBytes), "", "ag1", "agbot1")); err != nil {
+		t.Errorf("unable to marshal the proposal, error %v", err)
+	} else {
+		return &persistence.Agreement{CurrentAgreementId: "ag1", AgreementProtocol: "Basic", Proposal: string(propBytes)}
+	}
+	return nil
+}
+
+// the workload an agreement is upgraded to when its policy changes
+func Test_upgradeWorkload(t *testing.T) {
+
+	newPolicy := func(versions ...string) *policy.Policy {
+		pol := policy.Policy_Factory("test")
+		for ix, v := range versions {
+			wl := policy.Workload_Factory("http://mydomain.com/gps", "myorg", v, "")
+			wl.Priority.PriorityValue = ix + 1
+			pol.Workloads = append(pol.Workloads, *wl)
+		}
+		return pol
+	}
+
+	ag := testAgreement(t, policy.Workload_Factory("http://mydomain.com/gps", "myorg", "1.0.0", "amd64"))
+
+	if wl := upgradeWorkload(newPolicy("2.0.0", "1.0.0"), ag); wl == nil {
+		t.Errorf("the new highest priority version should be the upgrade")
+	} else if wl.Version != "2.0.0" || wl.Arch != "amd64" {
+		t.Errorf("the upgrade should be version 2.0.0 with the arch of the device, but got %v", wl)
+	}
+
+	if wl := upgradeWorkload(newPolicy("1.0.0", "0.9.0"), ag); wl != nil {
+		t.Errorf("there should be no upgrade when the highest priority version is running, but got %v", wl)
+	}
+
+	other := newPolicy("2.0.0")
+	other.Workloads[0].WorkloadURL = "http://mydomain.com/cpu"
+	if wl := upgradeWorkload(other, ag); wl != nil {
+		t.Errorf("there should be no upgrade to a different service, but got %v", wl)
+	}
+
+	if wl := upgradeWorkload(newPolicy(), ag); wl != nil {
+		t.Errorf("there should be no upgrade without workloads, but got %v", wl)
+	}
+
+	// only a change of the workload version is cancelled as an upgrade
+	if reason := policyChangeReason(newPolicy("2.0.0", "1.0.0"), ag); reason != TERM_REASON_WORKLOAD_UPGRADE {
+		t.Errorf("a new workload version should be an upgrade, but got %v", reason)
+	} else if reason := policyChangeReason(newPolicy("1.0.0", "0.9.0"), ag); reason != TERM_REASON_POLICY_CHANGED {
+		t.Errorf("a policy change without a new workload version should not be an upgrade, but got %v", reason)
+	}
+}
@@ -0,0 +1,80 @@
+package agreementbot
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/metrics"
+	"github.com/open-horizon/anax/policy"
+)
+
+// The states that an agreement goes through in the agbot, as reported in the agreement metrics.
+const (
+	AGREEMENT_STATE_PROPOSED    = "proposed"    // the proposal was sent, waiting for the node to reply
+	AGREEMENT_STATE_AGREED      = "agreed"      // the node accepted the proposal
+	AGREEMENT_STATE_FINALIZED   = "finalized"   // the agreement was recorded by the agreement protocol
+	AGREEMENT_STATE_TERMINATING = "terminating" // the agreement is being cancelled
+)
+
+// Metrics for the agreements and proposals made by the agbot.
+var agbotAgreementsGauge = metrics.NewGauge("agbot_agreements", "The number of active agreements in the agbot, by state and agreement protocol.", "state", "protocol")
+var proposalAttempts = metrics.NewCounter("agbot_proposal_attempts_total", "The number of agreement proposals the agbot tried to send, by whether the proposal was sent or failed.", "protocol", "result")
+var proposalRejections = metrics.NewCounter("agbot_proposal_rejections_total", "The number of agreement proposals rejected by nodes.", "protocol")
+
+// The owner of each partition in a postgresql agbot database.
+var partitionOwnerGauge = metrics.NewGauge("agbot_partition_owner", "The agbot instance that owns each database partition, always 1.", "partition", "owner")
+
+// Return the state of an agreement based on the timestamps recorded as it progresses.
+func agbotAgreementState(ag *persistence.Agreement) string {
+	if ag.AgreementTimedout != 0 {
+		return AGREEMENT_STATE_TERMINATING
+	} else if ag.AgreementFinalizedTime != 0 {
+		return AGREEMENT_STATE_FINALIZED
+	} else if ag.AgreementCreationTime != 0 {
+		return AGREEMENT_STATE_AGREED
+	}
+	return AGREEMENT_STATE_PROPOSED
+}
+
+// Called each time the metrics are scraped to count the unarchived agreements by state and protocol.
+func (a *API) collectAgreementMetrics() {
+	counts := make(map[[2]string]int)
+	for _, agp := range policy.AllAgreementProtocols() {
+		ags, err := a.db.FindAgreements([]persistence.AFilter{persistence.UnarchivedAFilter()}, agp)
+		if err != nil {
+			glog.Errorf(APIlogString(fmt.Sprintf("unable to read agreements for metrics, error: %v", err)))
+			return
+		}
+		for i := range ags {
+			counts[[2]string{agbotAgreementState(&ags[i]), agp}]++
+		}
+	}
+
+	agbotAgreementsGauge.Reset()
+	for key, count := range counts {
+		agbotAgreementsGauge.Set(float64(count), key[0], key[1])
+	}
+}
+
+// Called each time the metrics are scraped to report the owner of each partition. Only agbots that share a
+// postgresql database have partitions.
+func (a *API) collectPartitionMetrics() {
+	if !a.Config.IsPostgresqlConfigured() {
+		return
+	}
+
+	partitions, err := a.db.FindPartitions()
+	if err != nil {
+		glog.Errorf(APIlogString(fmt.Sprintf("unable to read partitions for metrics, error: %v", err)))
+		return
+	}
+
+	partitionOwnerGauge.Reset()
+	for _, p := range partitions {
+		if owner, err := a.db.GetPartitionOwner(p); err != nil {
+			glog.Errorf(APIlogString(fmt.Sprintf("unable to read partition %v owner for metrics, error: %v", p, err)))
+		} else {
+			partitionOwnerGauge.Set(1, p, owner)
+		}
+	}
+}
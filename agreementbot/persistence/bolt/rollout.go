@@ -0,0 +1,135 @@
+package bolt
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+const ROLLOUTS = "rollouts" // The bolt DB bucket name for rollout objects, keyed by policy name.
+
+func (db *AgbotBoltDB) NewRollout(rollout *persistence.Rollout) error {
+	if rollout == nil {
+		return fmt.Errorf("Missing required arg rollout")
+	} else if err := db.persistNew(rollout.PolicyName, ROLLOUTS, rollout); err != nil {
+		return err
+	} else {
+		return nil
+	}
+}
+
+func (db *AgbotBoltDB) FindSingleRolloutByPolicyName(policyName string) (*persistence.Rollout, error) {
+	filters := make([]persistence.ROFilter, 0)
+	filters = append(filters, persistence.PROFilter(policyName))
+
+	if rollouts, err := db.FindRollouts(filters); err != nil {
+		return nil, err
+	} else if len(rollouts) > 1 {
+		return nil, fmt.Errorf("Expected only one record for policy: %v, but retrieved: %v", policyName, rollouts)
+	} else if len(rollouts) == 0 {
+		return nil, nil
+	} else {
+		return &rollouts[0], nil
+	}
+}
+
+func (db *AgbotBoltDB) FindRollouts(filters []persistence.ROFilter) ([]persistence.Rollout, error) {
+	rollouts := make([]persistence.Rollout, 0)
+
+	readErr := db.db.View(func(tx *bolt.Tx) error {
+
+		if b := tx.Bucket([]byte(ROLLOUTS)); b != nil {
+			b.ForEach(func(k, v []byte) error {
+
+				var r persistence.Rollout
+
+				if err := json.Unmarshal(v, &r); err != nil {
+					glog.Errorf("Unable to deserialize db record: %v", v)
+				} else {
+					exclude := false
+					for _, filterFn := range filters {
+						if !filterFn(r) {
+							exclude = true
+						}
+					}
+					if !exclude {
+						rollouts = append(rollouts, r)
+					}
+				}
+				return nil
+			})
+		}
+
+		return nil // end the transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	} else {
+		return rollouts, nil
+	}
+}
+
+func (db *AgbotBoltDB) SingleRolloutUpdate(policyName string, fn func(persistence.Rollout) *persistence.Rollout) (*persistence.Rollout, error) {
+	if rollout, err := db.FindSingleRolloutByPolicyName(policyName); err != nil {
+		return nil, err
+	} else if rollout == nil {
+		return nil, fmt.Errorf("Unable to locate rollout for policy: %v", policyName)
+	} else {
+		updated := fn(*rollout)
+		return updated, db.persistUpdatedRollout(policyName, updated)
+	}
+}
+
+// does whole-member replacements of values that are legal to change during the course of a rollout
+func (db *AgbotBoltDB) persistUpdatedRollout(policyName string, update *persistence.Rollout) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		if b, err := tx.CreateBucketIfNotExists([]byte(ROLLOUTS)); err != nil {
+			return err
+		} else {
+			current := b.Get([]byte(policyName))
+			var mod persistence.Rollout
+
+			if current == nil {
+				return fmt.Errorf("No rollout for policy %v available to update", policyName)
+			} else if err := json.Unmarshal(current, &mod); err != nil {
+				return fmt.Errorf("Failed to unmarshal rollout DB data: %v", string(current))
+			} else {
+
+				// This code is running in a database transaction. Within the tx, the current record (mod) is
+				// read and then updated according to the updates within the input update record. It is critical
+				// to check for correct data transitions within the tx.
+				persistence.ValidateRolloutStateTransition(&mod, update)
+
+				if serialized, err := json.Marshal(mod); err != nil {
+					return fmt.Errorf("Failed to serialize rollout record: %v", mod)
+				} else if err := b.Put([]byte(policyName), serialized); err != nil {
+					return fmt.Errorf("Failed to write rollout record with key: %v", policyName)
+				} else {
+					glog.V(2).Infof("Succeeded updating rollout record to %v", mod)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (db *AgbotBoltDB) DeleteRollout(policyName string) error {
+	if policyName == "" {
+		return fmt.Errorf("Missing required arg policyName")
+	} else {
+
+		return db.db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte(ROLLOUTS))
+			if b == nil || b.Get([]byte(policyName)) == nil {
+				glog.Errorf("Warning: record deletion requested, but rollout does not exist: %v", policyName)
+				return nil // handle already-deleted rollout as success
+			}
+
+			glog.V(3).Infof("Deleting rollout record for policy %v", policyName)
+			return b.Delete([]byte(policyName))
+		})
+	}
+}
@@ -66,4 +66,13 @@ type AgbotDatabase interface {
 	DisableRollbackChecking(deviceid string, policyName string) (*WorkloadUsage, error)
 
 	DeleteWorkloadUsage(deviceid string, policyName string) error
+
+	// Staged rollout related functions
+	NewRollout(rollout *Rollout) error
+	FindSingleRolloutByPolicyName(policyName string) (*Rollout, error)
+	FindRollouts(filters []ROFilter) ([]Rollout, error)
+
+	SingleRolloutUpdate(policyName string, fn func(Rollout) *Rollout) (*Rollout, error)
+
+	DeleteRollout(policyName string) error
 }
@@ -79,6 +79,11 @@ func (db *AgbotPostgresqlDB) Initialize(cfg *config.HorizonConfig) error {
 			return errors.New(fmt.Sprintf("unable to create agreements partition table index, error: %v", err))
 		}
 
+		// Create the rollout table if necessary.
+		if _, err := db.db.Exec(ROLLOUT_CREATE_MAIN_TABLE); err != nil {
+			return errors.New(fmt.Sprintf("unable to create rollouts table, error: %v", err))
+		}
+
 		glog.V(3).Infof("Postgresql primary partition database tables exist.")
 
 		// Migrate the database tables if necessary. Extract the current schema version from the version table,
@@ -245,6 +245,10 @@ func (db *AgbotPostgresqlDB) MovePartition(timeout uint64) error {
 			return err
 		} else if _, err := tx.Exec(db.GetWorkloadUsagePartitionMove(fromPartition, db.PrimaryPartition())); err != nil {
 			return err
+		} else if _, err := tx.Exec(ROLLOUT_MOVE, fromPartition, db.PrimaryPartition()); err != nil {
+			return err
+		} else if _, err := tx.Exec(ROLLOUT_DELETE_PARTITION, fromPartition); err != nil {
+			return err
 		} else if _, err := tx.Exec(db.GetAgreementPartitionTableDrop(fromPartition)); err != nil {
 			return err
 		} else if _, err := tx.Exec(db.GetWorkloadUsagePartitionTableDrop(fromPartition)); err != nil {
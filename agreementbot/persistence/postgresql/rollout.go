@@ -0,0 +1,175 @@
+package postgresql
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+)
+
+// Constants for the SQL statements that are used to work with staged rollouts. A rollout upgrades the devices that an agbot
+// has agreements with, and those agreements are partitioned by agbot instance. So each agbot instance runs its own rollout of
+// a policy over the devices in its primary partition, and the rollout records are keyed by policy name and partition. There are
+// only a few rollout records, so they are kept in a single table rather than in a table per partition. When an agbot takes over
+// a stale partition, the rollouts in that partition are moved into its primary partition unless it already has a rollout for the
+// same policy.
+
+// rollouts schema:
+// policy_name: The name of the policy being rolled out.
+// partition:   The agbot partition that this rollout lives in.
+// rollout:     The rollout object which is a JSON blob. The blob schema is defined by the Rollout struct in the persistence package.
+// updated:     A timestamp to record last updated time.
+//
+const ROLLOUT_CREATE_MAIN_TABLE = `CREATE TABLE IF NOT EXISTS rollouts (
+	policy_name text NOT NULL,
+	partition text NOT NULL,
+	rollout jsonb NOT NULL,
+	updated timestamp with time zone DEFAULT current_timestamp,
+	PRIMARY KEY (policy_name, partition)
+);`
+
+const ROLLOUT_QUERY = `SELECT rollout FROM rollouts WHERE policy_name = $1 AND partition = $2;`
+const ALL_ROLLOUT_QUERY = `SELECT rollout FROM rollouts WHERE partition = $1;`
+
+const ROLLOUT_INSERT = `INSERT INTO rollouts (policy_name, partition, rollout) VALUES ($1, $2, $3);`
+const ROLLOUT_UPDATE = `UPDATE rollouts SET rollout = $3, updated = current_timestamp WHERE policy_name = $1 AND partition = $2;`
+const ROLLOUT_DELETE = `DELETE FROM rollouts WHERE policy_name = $1 AND partition = $2;`
+
+const ROLLOUT_MOVE = `INSERT INTO rollouts (policy_name, partition, rollout)
+SELECT a.policy_name, $2, a.rollout FROM rollouts a WHERE a.partition = $1
+AND NOT EXISTS (SELECT 1 FROM rollouts b WHERE b.policy_name = a.policy_name AND b.partition = $2);`
+const ROLLOUT_DELETE_PARTITION = `DELETE FROM rollouts WHERE partition = $1;`
+
+func (db *AgbotPostgresqlDB) internalFindSingleRolloutByPolicyName(tx *sql.Tx, policyName string) (*persistence.Rollout, error) {
+
+	rBytes := make([]byte, 0, 2048)
+	rollout := new(persistence.Rollout)
+
+	var qerr error
+	if tx == nil {
+		qerr = db.db.QueryRow(ROLLOUT_QUERY, policyName, db.PrimaryPartition()).Scan(&rBytes)
+	} else {
+		qerr = tx.QueryRow(ROLLOUT_QUERY, policyName, db.PrimaryPartition()).Scan(&rBytes)
+	}
+
+	if qerr == sql.ErrNoRows {
+		return nil, nil
+	} else if qerr != nil {
+		return nil, errors.New(fmt.Sprintf("error scanning row for rollout of policy name %v, error: %v", policyName, qerr))
+	} else if err := json.Unmarshal(rBytes, rollout); err != nil {
+		return nil, errors.New(fmt.Sprintf("error demarshalling row: %v, error: %v", string(rBytes), err))
+	}
+	return rollout, nil
+}
+
+func (db *AgbotPostgresqlDB) FindSingleRolloutByPolicyName(policyName string) (*persistence.Rollout, error) {
+	return db.internalFindSingleRolloutByPolicyName(nil, policyName)
+}
+
+func (db *AgbotPostgresqlDB) FindRollouts(filters []persistence.ROFilter) ([]persistence.Rollout, error) {
+	rollouts := make([]persistence.Rollout, 0, 10)
+
+	rows, err := db.db.Query(ALL_ROLLOUT_QUERY, db.PrimaryPartition())
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error querying for rollouts, error: %v", err))
+	}
+
+	// If the rows object doesnt get closed, memory and connections will grow and/or leak.
+	defer rows.Close()
+	for rows.Next() {
+		rBytes := make([]byte, 0, 2048)
+		rollout := new(persistence.Rollout)
+		if err := rows.Scan(&rBytes); err != nil {
+			return nil, errors.New(fmt.Sprintf("error scanning row: %v", err))
+		} else if err := json.Unmarshal(rBytes, rollout); err != nil {
+			return nil, errors.New(fmt.Sprintf("error demarshalling row: %v, error: %v", string(rBytes), err))
+		} else {
+			exclude := false
+			for _, filterFn := range filters {
+				if !filterFn(*rollout) {
+					exclude = true
+				}
+			}
+			if !exclude {
+				rollouts = append(rollouts, *rollout)
+			}
+		}
+	}
+
+	// The rows.Next() function will exit with false when done or an error occurred. Get any error encountered during iteration.
+	if err = rows.Err(); err != nil {
+		return nil, errors.New(fmt.Sprintf("error iterating: %v", err))
+	}
+
+	return rollouts, nil
+}
+
+func (db *AgbotPostgresqlDB) NewRollout(rollout *persistence.Rollout) error {
+	if rollout == nil {
+		return errors.New("Missing required arg rollout")
+	} else if existing, err := db.FindSingleRolloutByPolicyName(rollout.PolicyName); err != nil {
+		return err
+	} else if existing != nil {
+		return fmt.Errorf("Rollout record for policy name %v already exists in partition %v.", rollout.PolicyName, db.PrimaryPartition())
+	} else if rm, err := json.Marshal(rollout); err != nil {
+		return err
+	} else if _, err := db.db.Exec(ROLLOUT_INSERT, rollout.PolicyName, db.PrimaryPartition(), rm); err != nil {
+		return err
+	}
+	glog.V(2).Infof("Succeeded creating rollout record %v", *rollout)
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) SingleRolloutUpdate(policyName string, fn func(persistence.Rollout) *persistence.Rollout) (*persistence.Rollout, error) {
+	if rollout, err := db.FindSingleRolloutByPolicyName(policyName); err != nil {
+		return nil, err
+	} else if rollout == nil {
+		return nil, fmt.Errorf("Unable to locate rollout for policy: %v", policyName)
+	} else {
+		updated := fn(*rollout)
+		return updated, db.wrapRolloutTransaction(policyName, updated)
+	}
+}
+
+func (db *AgbotPostgresqlDB) wrapRolloutTransaction(policyName string, updated *persistence.Rollout) error {
+
+	if tx, err := db.db.Begin(); err != nil {
+		return err
+	} else if err := db.persistUpdatedRollout(tx, policyName, updated); err != nil {
+		tx.Rollback()
+		return err
+	} else {
+		return tx.Commit()
+	}
+
+}
+
+// This function runs inside a transaction. It will atomicly read the rollout from the DB, verify that the updated
+// rollout object contains valid state transitions, and then write the updated rollout back to the database.
+func (db *AgbotPostgresqlDB) persistUpdatedRollout(tx *sql.Tx, policyName string, update *persistence.Rollout) error {
+
+	if mod, err := db.internalFindSingleRolloutByPolicyName(tx, policyName); err != nil {
+		return err
+	} else if mod == nil {
+		return errors.New(fmt.Sprintf("No rollout with policy name %v available to update.", policyName))
+	} else {
+		persistence.ValidateRolloutStateTransition(mod, update)
+		if rm, err := json.Marshal(mod); err != nil {
+			return err
+		} else if _, err := tx.Exec(ROLLOUT_UPDATE, policyName, db.PrimaryPartition(), rm); err != nil {
+			return err
+		}
+		glog.V(2).Infof("Succeeded writing rollout record %v", *mod)
+	}
+	return nil
+}
+
+func (db *AgbotPostgresqlDB) DeleteRollout(policyName string) error {
+	if _, err := db.db.Exec(ROLLOUT_DELETE, policyName, db.PrimaryPartition()); err != nil {
+		return err
+	}
+	glog.V(5).Infof("Succeeded deleting rollout for policy %v from database.", policyName)
+	return nil
+}
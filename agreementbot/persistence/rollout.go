@@ -0,0 +1,146 @@
+package persistence
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// The states of a staged rollout.
+const ROLLOUT_IN_PROGRESS = "in_progress"
+const ROLLOUT_COMPLETED = "completed"
+const ROLLOUT_ROLLED_BACK = "rolled_back"
+
+// A rollout record tracks the staged upgrade of the nodes of a business policy to the highest priority service version.
+// The nodes are upgraded in waves. The devices in the current wave are running (or being upgraded to) the target version
+// and are being watched. When the wave has been healthy for the wave duration, its devices are moved to the upgraded list
+// and the next wave is started. When too many devices of a wave fail, all the devices are rolled back to the rollback
+// version. There is at most 1 rollout record per policy.
+type Rollout struct {
+	PolicyName       string   `json:"policy_name"`       // the name of the policy being rolled out, immutable after construction
+	State            string   `json:"state"`             // the state of the rollout, in_progress, completed or rolled_back
+	TargetVersion    string   `json:"target_version"`    // the service version being rolled out
+	TargetPriority   int      `json:"target_priority"`   // the priority of the service version being rolled out
+	RollbackVersion  string   `json:"rollback_version"`  // the service version to roll back to
+	RollbackPriority int      `json:"rollback_priority"` // the priority of the service version to roll back to
+	WavePercent      int      `json:"wave_percent"`      // the percentage of the nodes to upgrade in each wave
+	WaveCount        int      `json:"wave_count"`        // the number of nodes to upgrade in each wave
+	WaveDurationS    int      `json:"wave_durations"`    // the number of seconds a wave has to stay healthy before the next wave starts
+	FailureThreshold int      `json:"failure_threshold"` // the percentage of unhealthy nodes in a wave that causes a rollback
+	Wave             int      `json:"wave"`              // the number of the current wave, 0 until the first wave starts
+	WaveStartTime    uint64   `json:"wave_start_time"`   // the time when the current wave started
+	WaveDevices      []string `json:"wave_devices"`      // the devices being upgraded in the current wave
+	UpgradedDevices  []string `json:"upgraded_devices"`  // the devices that were upgraded in the previous waves
+	FailedDevices    []string `json:"failed_devices"`    // the devices of the current wave that were not healthy when the wave was checked
+	StartTime        uint64   `json:"start_time"`        // the time when the rollout started
+	LastUpdateTime   uint64   `json:"last_update_time"`  // the time when the rollout record was last updated
+	Reason           string   `json:"reason"`            // the reason the rollout was rolled back
+}
+
+func (r Rollout) String() string {
+	return fmt.Sprintf("PolicyName: %v, "+
+		"State: %v, "+
+		"TargetVersion: %v, "+
+		"TargetPriority: %v, "+
+		"RollbackVersion: %v, "+
+		"RollbackPriority: %v, "+
+		"WavePercent: %v, "+
+		"WaveCount: %v, "+
+		"WaveDurationS: %v, "+
+		"FailureThreshold: %v, "+
+		"Wave: %v, "+
+		"WaveStartTime: %v, "+
+		"WaveDevices: %v, "+
+		"UpgradedDevices: %v, "+
+		"FailedDevices: %v, "+
+		"StartTime: %v, "+
+		"LastUpdateTime: %v, "+
+		"Reason: %v",
+		r.PolicyName, r.State, r.TargetVersion, r.TargetPriority, r.RollbackVersion, r.RollbackPriority, r.WavePercent,
+		r.WaveCount, r.WaveDurationS, r.FailureThreshold, r.Wave, r.WaveStartTime, r.WaveDevices, r.UpgradedDevices,
+		r.FailedDevices, r.StartTime, r.LastUpdateTime, r.Reason)
+}
+
+// Returns true if the device is in the current wave or was upgraded in a previous wave.
+func (r Rollout) IsDeviceUpgrading(deviceId string) bool {
+	for _, d := range r.WaveDevices {
+		if d == deviceId {
+			return true
+		}
+	}
+	for _, d := range r.UpgradedDevices {
+		if d == deviceId {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the workload priority that a new agreement with the device should start with. Zero means that the rollout
+// does not restrict the device, so the highest priority workload is used.
+func (r Rollout) StartingPriority(deviceId string) int {
+	if r.State == ROLLOUT_ROLLED_BACK || (r.State == ROLLOUT_IN_PROGRESS && !r.IsDeviceUpgrading(deviceId)) {
+		return r.RollbackPriority
+	}
+	return 0
+}
+
+// factory method for rollout w/out persistence safety:
+func NewRollout(policyName string, targetVersion string, targetPriority int, rollbackVersion string, rollbackPriority int, wavePercent int, waveCount int, waveDurationS int, failureThreshold int) (*Rollout, error) {
+
+	if policyName == "" || targetPriority == 0 || rollbackPriority == 0 || (wavePercent == 0 && waveCount == 0) {
+		return nil, errors.New("Illegal input: one of policyName, targetPriority, rollbackPriority or wave size is empty")
+	} else {
+		now := uint64(time.Now().Unix())
+		return &Rollout{
+			PolicyName:       policyName,
+			State:            ROLLOUT_IN_PROGRESS,
+			TargetVersion:    targetVersion,
+			TargetPriority:   targetPriority,
+			RollbackVersion:  rollbackVersion,
+			RollbackPriority: rollbackPriority,
+			WavePercent:      wavePercent,
+			WaveCount:        waveCount,
+			WaveDurationS:    waveDurationS,
+			FailureThreshold: failureThreshold,
+			Wave:             0,
+			WaveStartTime:    0,
+			WaveDevices:      []string{},
+			UpgradedDevices:  []string{},
+			FailedDevices:    []string{},
+			StartTime:        now,
+			LastUpdateTime:   now,
+		}, nil
+	}
+}
+
+// This code is running in a database transaction. Within the tx, the current record is
+// read and then updated according to the updates within the input update record. A rollout
+// only leaves the in progress state once, and it never goes back.
+func ValidateRolloutStateTransition(mod *Rollout, update *Rollout) {
+	if mod.State == ROLLOUT_IN_PROGRESS {
+		mod.State = update.State
+		mod.Reason = update.Reason
+	}
+	if mod.Wave < update.Wave { // Always moves forward
+		mod.Wave = update.Wave
+	}
+	if mod.WaveStartTime < update.WaveStartTime { // Always moves forward
+		mod.WaveStartTime = update.WaveStartTime
+	}
+	mod.WaveDevices = update.WaveDevices
+	mod.UpgradedDevices = update.UpgradedDevices
+	mod.FailedDevices = update.FailedDevices
+	mod.LastUpdateTime = uint64(time.Now().Unix())
+}
+
+// Filters
+func PROFilter(policyName string) ROFilter {
+	return func(a Rollout) bool { return a.PolicyName == policyName }
+}
+
+func SROFilter(state string) ROFilter {
+	return func(a Rollout) bool { return a.State == state }
+}
+
+type ROFilter func(Rollout) bool
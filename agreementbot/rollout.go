@@ -0,0 +1,330 @@
+package agreementbot
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/abstractprotocol"
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/policy"
+	"sort"
+	"time"
+)
+
+// A staged rollout upgrades the devices of a business policy to a new highest priority service version a wave at a time.
+// While a rollout is in progress, the agreements running the old version are not cancelled when only the workload versions
+// of the policy change, and new agreements with devices that are not part of a wave yet are made with the rollback version.
+// The GovernRollouts subworker starts each wave by forcing a workload upgrade on the devices in the wave, and watches the
+// wave for the wave duration. If too many devices in the wave fail, all the devices running the new version are forced back
+// to the rollback version.
+
+// Start a staged rollout when the highest priority workload of a business policy has changed and the business policy has a
+// rollout policy for it. A rollout for a workload that is no longer the highest priority, or whose rollout policy has been
+// removed, is deleted so that the policy change upgrades all the devices as usual.
+func (w *AgreementBotWorker) updateRollout(org string, pol *policy.Policy) {
+
+	var rolloutPol *businesspolicy.RolloutPolicy
+	if w.BusinessPolManager != nil {
+		if pBE := w.BusinessPolManager.GetBusinessPolicyEntry(org, pol); pBE != nil {
+			rolloutPol = pBE.Rollout
+		}
+	}
+
+	existing, err := w.db.FindSingleRolloutByPolicyName(pol.Header.Name)
+	if err != nil {
+		glog.Errorf(RolloutlogString(fmt.Sprintf("unable to read rollout for policy %v, error: %v", pol.Header.Name, err)))
+		return
+	} else if existing == nil && rolloutPol == nil {
+		return
+	}
+
+	target := pol.NextHighestPriorityWorkload(0, 0, 0)
+	if existing != nil && rolloutPol != nil && existing.TargetVersion == target.Version && existing.TargetPriority == target.Priority.PriorityValue {
+		glog.V(5).Infof(RolloutlogString(fmt.Sprintf("rollout of version %v for policy %v is %v", existing.TargetVersion, pol.Header.Name, existing.State)))
+		return
+	} else if existing != nil {
+		glog.V(3).Infof(RolloutlogString(fmt.Sprintf("removing rollout of version %v for policy %v", existing.TargetVersion, pol.Header.Name)))
+		if err := w.db.DeleteRollout(pol.Header.Name); err != nil {
+			glog.Errorf(RolloutlogString(fmt.Sprintf("unable to delete rollout for policy %v, error: %v", pol.Header.Name, err)))
+			return
+		}
+	}
+
+	if rolloutPol == nil {
+		return
+	} else if rollback := nextLowerPriorityWorkload(pol, target.Priority.PriorityValue); rollback == nil {
+		glog.Warningf(RolloutlogString(fmt.Sprintf("policy %v has no workload to roll back to from version %v, the rollout is ignored", pol.Header.Name, target.Version)))
+	} else if rollout, err := persistence.NewRollout(pol.Header.Name, target.Version, target.Priority.PriorityValue, rollback.Version, rollback.Priority.PriorityValue, rolloutPol.WavePercent, rolloutPol.WaveCount, rolloutPol.WaveDurationS, rolloutPol.FailureThreshold); err != nil {
+		glog.Errorf(RolloutlogString(fmt.Sprintf("unable to create rollout for policy %v, error: %v", pol.Header.Name, err)))
+	} else if err := w.db.NewRollout(rollout); err != nil {
+		glog.Errorf(RolloutlogString(fmt.Sprintf("unable to save rollout for policy %v, error: %v", pol.Header.Name, err)))
+	} else {
+		glog.V(3).Infof(RolloutlogString(fmt.Sprintf("started rollout %v", rollout)))
+	}
+}
+
+// Returns the workload with the next lower priority (numerically higher) than the input priority, or nil if there is none.
+func nextLowerPriorityWorkload(pol *policy.Policy, priority int) *policy.Workload {
+	var next *policy.Workload
+	for ix, wl := range pol.Workloads {
+		if wl.Priority.PriorityValue > priority && (next == nil || wl.Priority.PriorityValue < next.Priority.PriorityValue) {
+			next = &pol.Workloads[ix]
+		}
+	}
+	return next
+}
+
+// Returns true if an agreement that no longer matches a changed policy should be left alone because a staged rollout will
+// upgrade it in one of its waves, or because the rollout was rolled back and the agreement is already running the rollback version.
+// Only a change of the workload versions is left to the rollout, any other change of the policy cancels the agreement.
+func rolloutDefersUpgrade(db persistence.AgbotDatabase, pm *policy.PolicyManager, org string, ag *persistence.Agreement, agPol *policy.Policy) bool {
+	running, err := agreementWorkload(ag)
+	if err != nil {
+		glog.Errorf(RolloutlogString(fmt.Sprintf("unable to read the workload of agreement %v, error: %v", ag.CurrentAgreementId, err)))
+		return false
+	} else if running == nil {
+		return false
+	} else if rollout, err := db.FindSingleRolloutByPolicyName(agPol.Header.Name); err != nil {
+		glog.Errorf(RolloutlogString(fmt.Sprintf("unable to read rollout for policy %v, error: %v", agPol.Header.Name, err)))
+		return false
+	} else if rollout == nil || rollout.State == persistence.ROLLOUT_COMPLETED {
+		return false
+	} else if !onlyWorkloadVersionsChanged(pm, org, agPol, running) {
+		return false
+	} else if rollout.State == persistence.ROLLOUT_IN_PROGRESS {
+		return running.Version != rollout.TargetVersion
+	} else {
+		return running.Version == rollout.RollbackVersion
+	}
+}
+
+// Returns true if the version the agreement runs is still a workload of the changed policy, with the same details, and
+// the rest of the agreement policy matches the changed policy.
+func onlyWorkloadVersionsChanged(pm *policy.PolicyManager, org string, agPol *policy.Policy, running *policy.Workload) bool {
+	changedPol := pm.GetPolicy(org, agPol.Header.Name)
+	if changedPol == nil {
+		return false
+	}
+
+	findRunning := func(pol *policy.Policy) *policy.Workload {
+		for ix, wl := range pol.Workloads {
+			if wl.WorkloadURL == running.WorkloadURL && wl.Org == running.Org && wl.Version == running.Version {
+				return &pol.Workloads[ix]
+			}
+		}
+		return nil
+	}
+
+	agWL, changedWL := findRunning(agPol), findRunning(changedPol)
+	if agWL == nil || changedWL == nil {
+		return false
+	}
+	reprioritized := *agWL
+	reprioritized.Priority = changedWL.Priority
+	if !reprioritized.IsSame(*changedWL) {
+		return false
+	}
+
+	withChangedWorkloads := *agPol
+	withChangedWorkloads.Workloads = changedPol.Workloads
+	if err := pm.MatchesMine(org, &withChangedWorkloads); err != nil {
+		glog.V(5).Infof(RolloutlogString(fmt.Sprintf("policy %v has changed more than its workload versions: %v", agPol.Header.Name, err)))
+		return false
+	}
+	return true
+}
+
+// Returns the workload the agreement runs, from the terms and conditions of its proposal. The agreement policy has all the
+// workloads of the consumer policy.
+func agreementWorkload(ag *persistence.Agreement) (*policy.Workload, error) {
+	if ag.Proposal == "" {
+		return nil, nil
+	} else if proposal, err := abstractprotocol.DemarshalProposal(ag.Proposal); err != nil {
+		return nil, err
+	} else if tsandcs, err := policy.DemarshalPolicy(proposal.TsAndCs()); err != nil {
+		return nil, err
+	} else if len(tsandcs.Workloads) == 0 {
+		return nil, nil
+	} else {
+		return &tsandcs.Workloads[0], nil
+	}
+}
+
+// Returns the workload priority that a new agreement with the device should start from, zero means the highest priority.
+func rolloutStartingPriority(db persistence.AgbotDatabase, deviceId string, policyName string) int {
+	if rollout, err := db.FindSingleRolloutByPolicyName(policyName); err != nil {
+		glog.Errorf(RolloutlogString(fmt.Sprintf("unable to read rollout for policy %v, error: %v", policyName, err)))
+		return 0
+	} else if rollout == nil {
+		return 0
+	} else {
+		return rollout.StartingPriority(deviceId)
+	}
+}
+
+// The subworker that moves the in progress rollouts from one wave to the next, or rolls them back.
+func (w *AgreementBotWorker) GovernRollouts() int {
+
+	rollouts, err := w.db.FindRollouts([]persistence.ROFilter{persistence.SROFilter(persistence.ROLLOUT_IN_PROGRESS)})
+	if err != nil {
+		glog.Errorf(RolloutlogString(fmt.Sprintf("unable to read rollouts, error: %v", err)))
+		return 0
+	}
+
+	// A filter for limiting the returned set of agreements to the in progress agreements of a policy.
+	policyFilter := func(policyName string) persistence.AFilter {
+		return func(a persistence.Agreement) bool {
+			return a.PolicyName == policyName && a.AgreementCreationTime != 0 && a.AgreementTimedout == 0
+		}
+	}
+
+	for _, rollout := range rollouts {
+
+		now := uint64(time.Now().Unix())
+		if rollout.Wave != 0 && rollout.WaveStartTime+uint64(rollout.WaveDurationS) > now {
+			glog.V(5).Infof(RolloutlogString(fmt.Sprintf("wave %v of policy %v is still being watched", rollout.Wave, rollout.PolicyName)))
+			continue
+		}
+
+		// Collect the active agreements of the policy being rolled out, keyed by device.
+		agreements := make(map[string]persistence.Agreement)
+		versions := make(map[string]string)
+		for _, agp := range policy.AllAgreementProtocols() {
+			if ags, err := w.db.FindAgreements([]persistence.AFilter{persistence.UnarchivedAFilter(), policyFilter(rollout.PolicyName)}, agp); err != nil {
+				glog.Errorf(RolloutlogString(fmt.Sprintf("unable to read agreements for policy %v, error: %v", rollout.PolicyName, err)))
+				return 0
+			} else {
+				for _, ag := range ags {
+					if wl, err := agreementWorkload(&ag); err != nil {
+						glog.Errorf(RolloutlogString(fmt.Sprintf("unable to read the workload of agreement %v, error %v", ag.CurrentAgreementId, err)))
+					} else if wl != nil {
+						agreements[ag.DeviceId] = ag
+						versions[ag.DeviceId] = wl.Version
+					}
+				}
+			}
+		}
+
+		// Check the health of the devices in the current wave.
+		failed, settling := rolloutWaveFailures(&rollout, agreements, versions, now, w.BaseWorker.Manager.Config.AgreementBot.AgreementTimeoutS)
+		if settling {
+			glog.V(5).Infof(RolloutlogString(fmt.Sprintf("wave %v of policy %v is waiting for the devices to make their new agreements", rollout.Wave, rollout.PolicyName)))
+			continue
+		}
+
+		if rolloutWaveFailed(&rollout, len(failed)) {
+			reason := fmt.Sprintf("%v of %v devices in wave %v were not healthy, the failure threshold is %v%%", len(failed), len(rollout.WaveDevices), rollout.Wave, rollout.FailureThreshold)
+			glog.Warningf(RolloutlogString(fmt.Sprintf("rolling back policy %v to version %v, %v", rollout.PolicyName, rollout.RollbackVersion, reason)))
+			if _, err := w.db.SingleRolloutUpdate(rollout.PolicyName, func(r persistence.Rollout) *persistence.Rollout {
+				r.State = persistence.ROLLOUT_ROLLED_BACK
+				r.Reason = reason
+				r.FailedDevices = failed
+				return &r
+			}); err != nil {
+				glog.Errorf(RolloutlogString(fmt.Sprintf("unable to update rollout for policy %v, error: %v", rollout.PolicyName, err)))
+				continue
+			}
+
+			// Force every device running the new version back to the rollback version.
+			for deviceId, ag := range agreements {
+				if versions[deviceId] == rollout.TargetVersion {
+					w.Messages() <- events.NewABApiWorkloadUpgradeMessage(events.WORKLOAD_UPGRADE, ag.AgreementProtocol, ag.CurrentAgreementId, ag.DeviceId, ag.PolicyName)
+				}
+			}
+			continue
+		}
+
+		// The wave is healthy, pick the devices for the next wave from the devices still running another version.
+		upgraded := append(append([]string{}, rollout.UpgradedDevices...), rollout.WaveDevices...)
+		remaining := make([]string, 0)
+		for deviceId := range agreements {
+			if !rollout.IsDeviceUpgrading(deviceId) && versions[deviceId] != rollout.TargetVersion {
+				remaining = append(remaining, deviceId)
+			}
+		}
+		sort.Strings(remaining)
+		wave := nextRolloutWave(&rollout, len(upgraded)+len(remaining), remaining)
+
+		if _, err := w.db.SingleRolloutUpdate(rollout.PolicyName, func(r persistence.Rollout) *persistence.Rollout {
+			r.UpgradedDevices = upgraded
+			r.WaveDevices = wave
+			r.FailedDevices = failed
+			if len(wave) == 0 {
+				r.State = persistence.ROLLOUT_COMPLETED
+			} else {
+				r.Wave = r.Wave + 1
+				r.WaveStartTime = now
+			}
+			return &r
+		}); err != nil {
+			glog.Errorf(RolloutlogString(fmt.Sprintf("unable to update rollout for policy %v, error: %v", rollout.PolicyName, err)))
+			continue
+		}
+
+		if len(wave) == 0 {
+			glog.V(3).Infof(RolloutlogString(fmt.Sprintf("completed rollout of version %v for policy %v", rollout.TargetVersion, rollout.PolicyName)))
+		} else {
+			glog.V(3).Infof(RolloutlogString(fmt.Sprintf("starting wave %v of policy %v with devices %v", rollout.Wave+1, rollout.PolicyName, wave)))
+			for _, deviceId := range wave {
+				ag := agreements[deviceId]
+				w.Messages() <- events.NewABApiWorkloadUpgradeMessage(events.WORKLOAD_UPGRADE, ag.AgreementProtocol, ag.CurrentAgreementId, ag.DeviceId, ag.PolicyName)
+			}
+		}
+	}
+
+	return 0
+}
+
+// Returns the devices of the current wave that are not healthy, and true when the wave is still settling. The old
+// agreements of the devices in a wave are cancelled when the wave starts, so a device that is not healthy yet is only
+// counted as failed once its new agreement had the agreement timeout to form. Until then, the wave is settling.
+func rolloutWaveFailures(rollout *persistence.Rollout, agreements map[string]persistence.Agreement, versions map[string]string, now uint64, agreementTimeoutS uint64) ([]string, bool) {
+	failed := make([]string, 0)
+	for _, deviceId := range rollout.WaveDevices {
+		if ag, ok := agreements[deviceId]; ok && versions[deviceId] == rollout.TargetVersion && rolloutAgreementHealthy(&ag) {
+			continue
+		} else if rollout.WaveStartTime+agreementTimeoutS > now {
+			return nil, true
+		}
+		failed = append(failed, deviceId)
+	}
+	return failed, false
+}
+
+// A device in a wave is healthy when its agreement has been finalized and, if the agreement verifies data, data has been
+// received since the agreement was made. Agreements with nodes that stop heartbeating are cancelled by agreement governance.
+func rolloutAgreementHealthy(ag *persistence.Agreement) bool {
+	if ag.AgreementTimedout != 0 || ag.AgreementFinalizedTime == 0 {
+		return false
+	}
+	return ag.DisableDataVerificationChecks || ag.DataVerifiedTime > ag.AgreementCreationTime
+}
+
+// Returns true when the percentage of failed devices in the current wave is over the failure threshold.
+func rolloutWaveFailed(rollout *persistence.Rollout, failed int) bool {
+	if len(rollout.WaveDevices) == 0 || failed == 0 {
+		return false
+	}
+	return failed*100 > rollout.FailureThreshold*len(rollout.WaveDevices)
+}
+
+// Returns the devices for the next wave. The wave size is a percentage of all the devices (rounded up) or a fixed count,
+// and is at least 1.
+func nextRolloutWave(rollout *persistence.Rollout, total int, remaining []string) []string {
+	size := rollout.WaveCount
+	if rollout.WavePercent != 0 {
+		size = (total*rollout.WavePercent + 99) / 100
+	}
+	if size < 1 {
+		size = 1
+	}
+	if size > len(remaining) {
+		size = len(remaining)
+	}
+	return remaining[:size]
+}
+
+var RolloutlogString = func(v interface{}) string {
+	return fmt.Sprintf("AgreementBot Rollout: %v", v)
+}
@@ -0,0 +1,201 @@
+// +build unit
+
+package agreementbot
+
+import (
+	"github.com/open-horizon/anax/agreementbot/persistence"
+	"github.com/open-horizon/anax/policy"
+	"reflect"
+	"testing"
+)
+
+// wave sizes by percentage and by count
+func Test_nextRolloutWave(t *testing.T) {
+
+	remaining := []string{"d1", "d2", "d3", "d4", "d5", "d6", "d7"}
+
+	tests := []struct {
+		rollout  persistence.Rollout
+		total    int
+		expected []string
+	}{
+		{persistence.Rollout{WavePercent: 25}, 10, []string{"d1", "d2", "d3"}},
+		{persistence.Rollout{WavePercent: 1}, 10, []string{"d1"}},
+		{persistence.Rollout{WavePercent: 100}, 10, remaining},
+		{persistence.Rollout{WaveCount: 2}, 10, []string{"d1", "d2"}},
+		{persistence.Rollout{WaveCount: 20}, 10, remaining},
+	}
+
+	for _, test := range tests {
+		if wave := nextRolloutWave(&test.rollout, test.total, remaining); !reflect.DeepEqual(wave, test.expected) {
+			t.Errorf("rollout %v with %v devices should have wave %v, but got %v", test.rollout, test.total, test.expected, wave)
+		}
+	}
+
+	if wave := nextRolloutWave(&persistence.Rollout{WaveCount: 2}, 3, []string{}); len(wave) != 0 {
+		t.Errorf("there should be no wave when no devices remain, but got %v", wave)
+	}
+}
+
+// the failure threshold is a percentage of the wave
+func Test_rolloutWaveFailed(t *testing.T) {
+
+	rollout := &persistence.Rollout{FailureThreshold: 20, WaveDevices: []string{"d1", "d2", "d3", "d4", "d5"}}
+
+	if rolloutWaveFailed(rollout, 0) {
+		t.Errorf("wave with no failures should not fail")
+	} else if rolloutWaveFailed(rollout, 1) {
+		t.Errorf("wave with 20%% failures should not fail with a 20%% threshold")
+	} else if !rolloutWaveFailed(rollout, 2) {
+		t.Errorf("wave with 40%% failures should fail with a 20%% threshold")
+	}
+
+	rollout.FailureThreshold = 0
+	if !rolloutWaveFailed(rollout, 1) {
+		t.Errorf("wave with 1 failure should fail with a 0%% threshold")
+	}
+
+	rollout.WaveDevices = []string{}
+	if rolloutWaveFailed(rollout, 0) {
+		t.Errorf("empty wave should not fail")
+	}
+}
+
+func Test_rolloutAgreementHealthy(t *testing.T) {
+
+	ag := &persistence.Agreement{AgreementCreationTime: 100, AgreementFinalizedTime: 110, DataVerifiedTime: 100}
+	if rolloutAgreementHealthy(ag) {
+		t.Errorf("agreement without verified data should not be healthy")
+	}
+
+	ag.DataVerifiedTime = 150
+	if !rolloutAgreementHealthy(ag) {
+		t.Errorf("agreement with verified data should be healthy")
+	}
+
+	ag.AgreementFinalizedTime = 0
+	if rolloutAgreementHealthy(ag) {
+		t.Errorf("agreement that is not finalized should not be healthy")
+	}
+
+	ag = &persistence.Agreement{AgreementCreationTime: 100, AgreementFinalizedTime: 110, DataVerifiedTime: 100, DisableDataVerificationChecks: true}
+	if !rolloutAgreementHealthy(ag) {
+		t.Errorf("finalized agreement without data verification should be healthy")
+	}
+}
+
+// a device is only failed once its new agreement had time to form
+func Test_rolloutWaveFailures(t *testing.T) {
+
+	rollout := &persistence.Rollout{TargetVersion: "2.0.0", WaveStartTime: 1000, WaveDevices: []string{"d1", "d2"}}
+	healthy := persistence.Agreement{AgreementCreationTime: 1010, AgreementFinalizedTime: 1020, DisableDataVerificationChecks: true}
+	agreements := map[string]persistence.Agreement{"d1": healthy}
+	versions := map[string]string{"d1": "2.0.0"}
+
+	if failed, settling := rolloutWaveFailures(rollout, agreements, versions, 1100, 360); !settling || failed != nil {
+		t.Errorf("a device without its new agreement should not fail within the agreement timeout, got %v %v", failed, settling)
+	}
+
+	if failed, settling := rolloutWaveFailures(rollout, agreements, versions, 1360, 360); settling || !reflect.DeepEqual(failed, []string{"d2"}) {
+		t.Errorf("a device without its new agreement should fail after the agreement timeout, got %v %v", failed, settling)
+	}
+
+	agreements["d2"] = healthy
+	versions["d2"] = "2.0.0"
+	if failed, settling := rolloutWaveFailures(rollout, agreements, versions, 1100, 360); settling || len(failed) != 0 {
+		t.Errorf("a wave of healthy devices should not wait for the agreement timeout, got %v %v", failed, settling)
+	}
+
+	versions["d2"] = "1.0.0"
+	if failed, settling := rolloutWaveFailures(rollout, agreements, versions, 2000, 360); settling || !reflect.DeepEqual(failed, []string{"d2"}) {
+		t.Errorf("a device running another version should fail, got %v %v", failed, settling)
+	}
+}
+
+func Test_nextLowerPriorityWorkload(t *testing.T) {
+
+	pol := policy.Policy_Factory("test")
+	for _, p := range []int{3, 1, 2} {
+		wl := policy.Workload{}
+		wl.Priority.PriorityValue = p
+		pol.Workloads = append(pol.Workloads, wl)
+	}
+
+	if wl := nextLowerPriorityWorkload(pol, 1); wl == nil || wl.Priority.PriorityValue != 2 {
+		t.Errorf("next lower priority than 1 should be 2, but got %v", wl)
+	} else if wl := nextLowerPriorityWorkload(pol, 3); wl != nil {
+		t.Errorf("there should be no lower priority than 3, but got %v", wl)
+	}
+}
+
+func Test_onlyWorkloadVersionsChanged(t *testing.T) {
+
+	newPolicy := func(versions ...string) *policy.Policy {
+		pol := policy.Policy_Factory("test")
+		for ix, v := range versions {
+			wl := policy.Workload_Factory("http://mydomain.com/gps", "myorg", v, "amd64")
+			wl.Priority.PriorityValue = ix + 1
+			pol.Workloads = append(pol.Workloads, *wl)
+		}
+		return pol
+	}
+
+	pm := policy.PolicyManager_Factory(false, false)
+	if err := pm.AddPolicy("myorg", newPolicy("2.0.0", "1.0.0")); err != nil {
+		t.Errorf("unable to add the policy, error %v", err)
+	}
+
+	agPol := newPolicy("1.0.0", "0.9.0")
+	if !onlyWorkloadVersionsChanged(pm, "myorg", agPol, &agPol.Workloads[0]) {
+		t.Errorf("an agreement running a version the rollout moves to a lower priority should be left to the rollout")
+	} else if onlyWorkloadVersionsChanged(pm, "myorg", agPol, &agPol.Workloads[1]) {
+		t.Errorf("an agreement running a version that was removed from the policy should not be left to the rollout")
+	}
+
+	agPol.MaxAgreements = 10
+	if onlyWorkloadVersionsChanged(pm, "myorg", agPol, &agPol.Workloads[0]) {
+		t.Errorf("an agreement whose policy changed more than its workload versions should not be left to the rollout")
+	}
+}
+
+func Test_agreementWorkload(t *testing.T) {
+
+	if wl, err := agreementWorkload(testAgreement(t, policy.Workload_Factory("http://mydomain.com/gps", "myorg", "1.0.0", "amd64"))); err != nil {
+		t.Errorf("unexpected error %v", err)
+	} else if wl == nil || wl.Version != "1.0.0" {
+		t.Errorf("the workload of the agreement should be version 1.0.0, but got %v", wl)
+	}
+
+	if wl, err := agreementWorkload(&persistence.Agreement{}); err != nil || wl != nil {
+		t.Errorf("an agreement without a proposal has no workload, but got %v, %v", wl, err)
+	}
+}
+
+func Test_Rollout_StartingPriority(t *testing.T) {
+
+	rollout, err := persistence.NewRollout("org/bp", "2.0.0", 1, "1.0.0", 2, 10, 0, 60, 20)
+	if err != nil {
+		t.Errorf("unexpected error creating rollout: %v", err)
+		return
+	}
+	rollout.WaveDevices = []string{"d1"}
+	rollout.UpgradedDevices = []string{"d2"}
+
+	if p := rollout.StartingPriority("d1"); p != 0 {
+		t.Errorf("device in the wave should start at the highest priority, but got %v", p)
+	} else if p := rollout.StartingPriority("d2"); p != 0 {
+		t.Errorf("upgraded device should start at the highest priority, but got %v", p)
+	} else if p := rollout.StartingPriority("d3"); p != 2 {
+		t.Errorf("device not in a wave should start at the rollback priority, but got %v", p)
+	}
+
+	rollout.State = persistence.ROLLOUT_ROLLED_BACK
+	if p := rollout.StartingPriority("d1"); p != 2 {
+		t.Errorf("rolled back device should start at the rollback priority, but got %v", p)
+	}
+
+	rollout.State = persistence.ROLLOUT_COMPLETED
+	if p := rollout.StartingPriority("d3"); p != 0 {
+		t.Errorf("devices should start at the highest priority after the rollout completes, but got %v", p)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/open-horizon/anax/apicommon"
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/metrics"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/worker"
@@ -62,6 +63,9 @@ func NewAPIListener(name string, cfg *config.HorizonConfig, db *bolt.DB, pm *pol
 		listener.EC = worker.NewExchangeContext(fmt.Sprintf("%v/%v", pDevice.Org, pDevice.Id), pDevice.Token, cfg.Edge.ExchangeURL, cfg.GetCSSURL(), cfg.Collaborators.HTTPClientFactory)
 	}
 
+	// The agreement metrics are computed from the database when the metrics are scraped.
+	metrics.RegisterCollector("node agreements", listener.collectAgreementMetrics)
+
 	listener.listen(cfg)
 	return listener
 }
@@ -82,6 +86,9 @@ func (a *API) router(includeStaticRedirects bool) *mux.Router {
 	router.HandleFunc("/service/config", a.serviceconfig).Methods("GET", "POST", "OPTIONS")
 	router.HandleFunc("/service/configstate", a.service_configstate).Methods("GET", "POST", "OPTIONS")
 	router.HandleFunc("/service/policy", a.servicepolicy).Methods("GET", "OPTIONS")
+	router.HandleFunc("/service/log", a.servicelog).Methods("GET", "OPTIONS")
+	router.HandleFunc("/service/object", a.serviceobject).Methods("GET", "OPTIONS")
+	router.HandleFunc("/service/credential/rotate", a.servicecredentialrotate).Methods("POST", "OPTIONS")
 
 	// Connectivity and blockchain status info
 	router.HandleFunc("/status", a.status).Methods("GET", "OPTIONS")
@@ -97,10 +104,19 @@ func (a *API) router(includeStaticRedirects bool) *mux.Router {
 	router.HandleFunc("/node/userinput", a.nodeuserinput).Methods("GET", "HEAD", "PUT", "POST", "PATCH", "DELETE", "OPTIONS")
 
 	// Used to get the event logs on this node.
-	// get the eventlogs for current registration.
-	router.HandleFunc("/eventlog", a.eventlog).Methods("GET", "OPTIONS")
+	// get the eventlogs for current registration, or delete the eventlogs saved before a time.
+	router.HandleFunc("/eventlog", a.eventlog).Methods("GET", "DELETE", "OPTIONS")
 	// get the eventlogs for all registrations.
 	router.HandleFunc("/eventlog/all", a.eventlog).Methods("GET", "OPTIONS")
+	// stream the eventlogs as they are saved, catching up on those of the current registration or of all registrations.
+	router.HandleFunc("/eventlog/stream", a.eventlogStream).Methods("GET", "OPTIONS")
+	router.HandleFunc("/eventlog/all/stream", a.eventlogStream).Methods("GET", "OPTIONS")
+
+	// Used to list or remove the service images that are no longer used.
+	router.HandleFunc("/image/gc", a.imagegc).Methods("GET", "POST", "OPTIONS")
+
+	// Operational metrics in the Prometheus text format
+	router.HandleFunc("/metrics", metrics.Handler).Methods("GET", "OPTIONS")
 
 	// For importing workload public signing keys (RSA-PSS key pair public key)
 	router.HandleFunc("/{p:(?:publickey|trust)}", a.publickey).Methods("GET", "OPTIONS")
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/resource"
+	"net/http"
+)
+
+// POST rotates the FSS (ESS) credentials of all the running services now, e.g. after a credential may have leaked.
+// The previous tokens keep authenticating for the configured grace period.
+func (a *API) servicecredentialrotate(w http.ResponseWriter, r *http.Request) {
+
+	am := resource.NewAuthenticationManager(a.Config.GetFileSyncServiceAuthPath())
+
+	resource := "service/credential/rotate"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "POST":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		if rotated, err := am.RotateCredentials(0, a.Config.GetESSCredentialGracePeriodS()); err != nil {
+			errorHandler(NewSystemError(fmt.Sprintf("Error rotating service credentials, error %v", err)))
+		} else {
+			writeResponse(w, rotated, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "POST, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -1,13 +1,18 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/golang/glog"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/persistence"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
-// get the eventlogs for current registration.
+// get the eventlogs for current registration, or delete the eventlogs saved before the time given by the before parameter.
 func (a *API) eventlog(w http.ResponseWriter, r *http.Request) {
 
 	resource := "eventlog"
@@ -34,11 +39,145 @@ func (a *API) eventlog(w http.ResponseWriter, r *http.Request) {
 			writeResponse(w, out, http.StatusOK)
 		}
 
+	case "DELETE":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v with parameters %v", r.Method, resource, r.URL.Query())))
+
+		if errHandled, out := DeleteEventLogs(r.URL.Query().Get("before"), errorHandler, a.db); errHandled {
+			return
+		} else {
+			writeResponse(w, out, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		if r.URL != nil && strings.Contains(r.URL.Path, "all") {
+			w.Header().Set("Allow", "GET, OPTIONS")
+		} else {
+			w.Header().Set("Allow", "GET, DELETE, OPTIONS")
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+
+}
+
+// How often a comment is sent on an idle event log stream so that clients and proxies keep the connection open.
+const EVENTLOG_STREAM_KEEPALIVE = 30 * time.Second
+
+// Stream the event logs as they are saved, using Server-Sent Events. The selections are the same as for the
+// eventlog API. A client that reconnects sends the id of the last event it received in the Last-Event-ID header,
+// the event logs saved after that one are sent first, from the current registration or from all registrations.
+func (a *API) eventlogStream(w http.ResponseWriter, r *http.Request) {
+
+	resource := "eventlog/stream"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET":
+		all_logs := false
+		if r.URL != nil && strings.Contains(r.URL.Path, "all") {
+			all_logs = true
+			resource = "eventlog/all/stream"
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			errorHandler(NewSystemError(fmt.Sprintf("Unable to stream %v, the connection does not support streaming.", resource)))
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			errorHandler(NewAPIUserInputError(fmt.Sprintf("Error parsing the selections %v. %v", r.Form, err), "selection"))
+			return
+		}
+
+		selectors, err := persistence.ConvertToSelectors(r.Form)
+		if err != nil {
+			errorHandler(NewAPIUserInputError(fmt.Sprintf("Error converting the selections into Selectors: %v", err), "selection"))
+			return
+		}
+
+		last_id := uint64(0)
+		replay := r.Header.Get("Last-Event-ID") != ""
+		if replay {
+			if last_id, err = strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64); err != nil {
+				errorHandler(NewAPIUserInputError(fmt.Sprintf("The last event id %v is not a record id.", r.Header.Get("Last-Event-ID")), "Last-Event-ID"))
+				return
+			}
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v with selection %v, last event id %v", r.Method, resource, r.Form, last_id)))
+
+		// Subscribe before looking for the saved event logs, so that none are missed in between. Duplicates are
+		// skipped by record id.
+		sub := eventlog.Subscribe(selectors)
+		defer sub.Close()
+
+		missed := []persistence.EventLog{}
+		if replay {
+			if missed, err = FindEventLogsAfterRecord(a.db, all_logs, last_id, r.Form); err != nil {
+				errorHandler(NewSystemError(fmt.Sprintf("Error getting %v for output, error %v", resource, err)))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+
+		for _, el := range missed {
+			if last_id, err = writeEventLogEvent(w, el, last_id); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		keepalive := time.NewTicker(EVENTLOG_STREAM_KEEPALIVE)
+		defer keepalive.Stop()
+
+		for {
+			select {
+			case el, ok := <-sub.Events:
+				if !ok {
+					// The subscription ended because this client fell behind, it will reconnect and catch up.
+					return
+				} else if last_id, err = writeEventLogEvent(w, el, last_id); err != nil {
+					return
+				}
+			case <-keepalive.C:
+				if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				glog.V(5).Infof(apiLogString(fmt.Sprintf("Client closed the %v connection", resource)))
+				return
+			}
+			flusher.Flush()
+		}
+
 	case "OPTIONS":
 		w.Header().Set("Allow", "GET, OPTIONS")
 		w.WriteHeader(http.StatusOK)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
+}
+
+// Write an event log as a server sent event, unless it was already sent. Returns the record id of the last event sent.
+func writeEventLogEvent(w http.ResponseWriter, el persistence.EventLog, last_id uint64) (uint64, error) {
+	id, err := strconv.ParseUint(el.Id, 10, 64)
+	if err != nil || id <= last_id {
+		return last_id, nil
+	}
 
+	serial, err := json.Marshal(el)
+	if err != nil {
+		glog.Errorf(apiLogString(fmt.Sprintf("Unable to serialize event log %v, error %v", el, err)))
+		return last_id, nil
+	}
+
+	if _, err := fmt.Fprintf(w, "id: %v\nevent: eventlog\ndata: %s\n\n", el.Id, serial); err != nil {
+		return last_id, err
+	}
+	return id, nil
 }
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/imagefetch"
+	"net/http"
+)
+
+// GET returns the unused service images that would be removed, POST removes them now. The image removal policy is
+// the same as for the periodic removal, whether or not it is enabled.
+func (a *API) imagegc(w http.ResponseWriter, r *http.Request) {
+
+	resource := "image/gc"
+
+	errorHandler := GetHTTPErrorHandler(w)
+
+	switch r.Method {
+	case "GET", "POST":
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		client, err := docker.NewClient(a.Config.Edge.DockerEndpoint)
+		if err != nil {
+			errorHandler(NewSystemError(fmt.Sprintf("Unable to connect to docker, error %v", err)))
+			return
+		}
+
+		if report, err := imagefetch.CollectImages(a.Config, client, a.db, r.Method == "GET"); err != nil {
+			errorHandler(NewSystemError(fmt.Sprintf("Error removing unused images, error %v", err)))
+		} else {
+			writeResponse(w, report, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, POST, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/metrics"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+)
+
+// The states that an agreement goes through on the node, as reported in the agreement metrics.
+const (
+	AGREEMENT_STATE_CREATED     = "created"     // the node accepted the proposal
+	AGREEMENT_STATE_ACCEPTED    = "accepted"    // the agbot acknowledged the node's reply
+	AGREEMENT_STATE_FINALIZED   = "finalized"   // the agreement was recorded by the agreement protocol
+	AGREEMENT_STATE_EXECUTING   = "executing"   // the workload containers are running
+	AGREEMENT_STATE_TERMINATING = "terminating" // the agreement is being cancelled
+)
+
+var agreementsGauge = metrics.NewGauge("anax_agreements", "The number of active agreements on the node, by state and agreement protocol.", "state", "protocol")
+
+// Return the state of an agreement based on the timestamps recorded as it progresses.
+func agreementState(ag *persistence.EstablishedAgreement) string {
+	if ag.AgreementTerminatedTime != 0 {
+		return AGREEMENT_STATE_TERMINATING
+	} else if ag.AgreementExecutionStartTime != 0 {
+		return AGREEMENT_STATE_EXECUTING
+	} else if ag.AgreementFinalizedTime != 0 {
+		return AGREEMENT_STATE_FINALIZED
+	} else if ag.AgreementAcceptedTime != 0 {
+		return AGREEMENT_STATE_ACCEPTED
+	}
+	return AGREEMENT_STATE_CREATED
+}
+
+// Called each time the metrics are scraped to count the unarchived agreements by state and protocol.
+func (a *API) collectAgreementMetrics() {
+	ags, err := persistence.FindEstablishedAgreementsAllProtocols(a.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()})
+	if err != nil {
+		glog.Errorf(apiLogString(fmt.Sprintf("unable to read agreements for metrics, error %v", err)))
+		return
+	}
+
+	counts := make(map[[2]string]int)
+	for i := range ags {
+		counts[[2]string{agreementState(&ags[i]), ags[i].AgreementProtocol}]++
+	}
+
+	agreementsGauge.Reset()
+	for key, count := range counts {
+		agreementsGauge.Set(float64(count), key[0], key[1])
+	}
+}
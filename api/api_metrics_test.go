@@ -0,0 +1,51 @@
+// +build unit
+
+package api
+
+import (
+	"bytes"
+	"github.com/open-horizon/anax/metrics"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"strings"
+	"testing"
+)
+
+func Test_collectAgreementMetrics(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	wi := &persistence.WorkloadInfo{URL: "myservice", Org: "myorg", Version: "1.0.0", Arch: "amd64"}
+	for _, agId := range []string{"ag1", "ag2", "ag3"} {
+		if _, err := persistence.NewEstablishedAgreement(db, "tsandcs", agId, "agbot1", "{}", policy.BasicProtocol, 1, persistence.ServiceSpecs{}, "", "", "", "", "", wi); err != nil {
+			t.Fatalf("unexpected error creating agreement %v: %v", agId, err)
+		}
+	}
+	if _, err := persistence.AgreementStateAccepted(db, "ag2", policy.BasicProtocol); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, err := persistence.AgreementStateExecutionStarted(db, "ag3", policy.BasicProtocol); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := &API{db: db}
+	a.collectAgreementMetrics()
+
+	var buf bytes.Buffer
+	if err := metrics.DefaultRegistry().WriteText(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, line := range []string{
+		`anax_agreements{state="created",protocol="Basic"} 1`,
+		`anax_agreements{state="accepted",protocol="Basic"} 1`,
+		`anax_agreements{state="executing",protocol="Basic"} 1`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("metrics output is missing %v: %v", line, buf.String())
+		}
+	}
+}
@@ -6,12 +6,14 @@ import (
 	"fmt"
 	"github.com/golang/glog"
 	"github.com/gorilla/mux"
+	"github.com/open-horizon/anax/container"
 	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/persistence"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 )
 
 func (a *API) service(w http.ResponseWriter, r *http.Request) {
@@ -239,3 +241,125 @@ func (a *API) servicepolicy(w http.ResponseWriter, r *http.Request) {
 	}
 
 }
+
+// Returns the version of each model management object that the services on this node have consumed. The type and id
+// parameters limit the output to one object type, or one object.
+func (a *API) serviceobject(w http.ResponseWriter, r *http.Request) {
+
+	resource := "service/object"
+	errorhandler := GetHTTPErrorHandler(w)
+
+	_, errWritten := a.existingDeviceOrError(w)
+	if errWritten {
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v", r.Method, resource)))
+
+		objectType := r.URL.Query().Get("type")
+		objectId := r.URL.Query().Get("id")
+
+		filters := []persistence.COFilter{}
+		if objectType != "" {
+			filters = append(filters, persistence.ObjectCOFilter(objectType, objectId))
+		} else if objectId != "" {
+			errorhandler(NewAPIUserInputError("the type parameter must be specified with the id parameter", "id"))
+			return
+		}
+
+		if out, err := persistence.FindConsumedObjects(a.db, filters); err != nil {
+			errorhandler(NewSystemError(fmt.Sprintf("Error getting %v for output, error %v", resource, err)))
+		} else {
+			writeResponse(w, out, http.StatusOK)
+		}
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+
+}
+
+// Stream the stdout and stderr of the containers of a service. The service is given by the url and org parameters,
+// the tail parameter is the number of lines from the end of the logs to start with, and the follow parameter keeps the
+// response open for new log lines.
+func (a *API) servicelog(w http.ResponseWriter, r *http.Request) {
+
+	resource := "service/log"
+	errorhandler := GetHTTPErrorHandler(w)
+
+	_, errWritten := a.existingDeviceOrError(w)
+	if errWritten {
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		url := r.URL.Query().Get("url")
+		org := r.URL.Query().Get("org")
+		if url == "" {
+			errorhandler(NewAPIUserInputError("the service url must be specified", "url"))
+			return
+		}
+
+		tail, err := parseLogTail(r.URL.Query().Get("tail"))
+		if err != nil {
+			errorhandler(NewAPIUserInputError(err.Error(), "tail"))
+			return
+		}
+
+		follow := false
+		if f := r.URL.Query().Get("follow"); f != "" {
+			if follow, err = strconv.ParseBool(f); err != nil {
+				errorhandler(NewAPIUserInputError(fmt.Sprintf("follow %v is not true or false", f), "follow"))
+				return
+			}
+		}
+
+		glog.V(5).Infof(apiLogString(fmt.Sprintf("Handling %v on resource %v for service %v, tail %v, follow %v", r.Method, resource, cutil.FormOrgSpecUrl(url, org), tail, follow)))
+
+		cw, err := container.CreateAPIContainerWorker(a.Config, a.db)
+		if err != nil {
+			errorhandler(NewSystemError(fmt.Sprintf("Unable to connect to docker, error %v", err)))
+			return
+		}
+
+		containers, err := cw.FindServiceContainers(url, org)
+		if err != nil {
+			errorhandler(NewSystemError(fmt.Sprintf("Error finding the containers of service %v, error %v", cutil.FormOrgSpecUrl(url, org), err)))
+			return
+		} else if len(containers) == 0 {
+			errorhandler(NewNotFoundError(fmt.Sprintf("No containers are running service %v on this node.", cutil.FormOrgSpecUrl(url, org)), "url"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		cw.CopyContainerLogs(r.Context(), containers, w, tail, follow)
+
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// The tail of the logs is a number of lines or all of them, which is the default.
+func parseLogTail(tail string) (string, error) {
+	if tail == "" || tail == "all" {
+		return "all", nil
+	} else if n, err := strconv.Atoi(tail); err != nil || n < 0 {
+		return "", fmt.Errorf("tail %v is not a number of lines or all", tail)
+	}
+	return tail, nil
+}
@@ -22,15 +22,16 @@ func (c *Configstate) String() string {
 }
 
 type HorizonDevice struct {
-	Id                 *string      `json:"id"`
-	Org                *string      `json:"organization"`
-	Pattern            *string      `json:"pattern"` // a simple name, not prefixed with the org
-	Name               *string      `json:"name,omitempty"`
-	Token              *string      `json:"token,omitempty"`
-	TokenLastValidTime *uint64      `json:"token_last_valid_time,omitempty"`
-	TokenValid         *bool        `json:"token_valid,omitempty"`
-	HA                 *bool        `json:"ha,omitempty"`
-	Config             *Configstate `json:"configstate,omitempty"`
+	Id                 *string                       `json:"id"`
+	Org                *string                       `json:"organization"`
+	Pattern            *string                       `json:"pattern"` // a simple name, not prefixed with the org
+	Name               *string                       `json:"name,omitempty"`
+	Token              *string                       `json:"token,omitempty"`
+	TokenLastValidTime *uint64                       `json:"token_last_valid_time,omitempty"`
+	TokenValid         *bool                         `json:"token_valid,omitempty"`
+	HA                 *bool                         `json:"ha,omitempty"`
+	Config             *Configstate                  `json:"configstate,omitempty"`
+	PendingUpgrades    *[]persistence.PendingUpgrade `json:"pending_upgrades,omitempty"` // output only, the upgrades held until a maintenance window opens
 }
 
 func (h HorizonDevice) String() string {
@@ -2,6 +2,7 @@ package api
 
 import (
 	dockerclient "github.com/fsouza/go-dockerclient"
+	"github.com/open-horizon/anax/imagefetch"
 	"github.com/open-horizon/anax/persistence"
 	"strconv"
 )
@@ -50,8 +51,9 @@ func NewMicroserviceOutput() *AllMicroservices {
 
 // The output format for microservice instances
 type MicroserviceInstanceOutput struct {
-	persistence.MicroserviceInstance                               // an embedded field
-	Containers                       *[]dockerclient.APIContainers `json:"containers"` // the docker info for a running container
+	persistence.MicroserviceInstance                                // an embedded field
+	Containers                       *[]dockerclient.APIContainers  `json:"containers"`           // the docker info for a running container
+	ImagePull                        []imagefetch.ImagePullProgress `json:"image_pull,omitempty"` // the progress of the image pulls of the instance, kept for a while after they finish
 }
 
 func NewMicroserviceInstanceOutput(mi persistence.MicroserviceInstance, containers *[]dockerclient.APIContainers) *MicroserviceInstanceOutput {
@@ -110,6 +112,22 @@ func NewServiceOutput() *AllServices {
 	}
 }
 
+// The output format for an agreement in GET agreement
+type AgreementOutput struct {
+	persistence.EstablishedAgreement                                // an embedded field
+	ImagePull                        []imagefetch.ImagePullProgress `json:"image_pull,omitempty"` // the progress of the image pulls of the agreement, kept for a while after they finish
+}
+
+func NewAgreementOutput(ag persistence.EstablishedAgreement, imagePull []imagefetch.ImagePullProgress) *AgreementOutput {
+	if len(imagePull) == 0 {
+		imagePull = nil
+	}
+	return &AgreementOutput{
+		EstablishedAgreement: ag,
+		ImagePull:            imagePull,
+	}
+}
+
 // Functions and types that plug into the go sorting feature
 type EstablishedAgreementsByAgreementCreationTime []persistence.EstablishedAgreement
 
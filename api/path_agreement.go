@@ -6,12 +6,13 @@ import (
 	"github.com/boltdb/bolt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/imagefetch"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"sort"
 )
 
-func FindAgreementsForOutput(db *bolt.DB) (map[string]map[string][]persistence.EstablishedAgreement, error) {
+func FindAgreementsForOutput(db *bolt.DB) (map[string]map[string][]AgreementOutput, error) {
 
 	agreements, err := persistence.FindEstablishedAgreementsAllProtocols(db, policy.AllAgreementProtocols(), []persistence.EAFilter{})
 	if err != nil {
@@ -23,23 +24,34 @@ func FindAgreementsForOutput(db *bolt.DB) (map[string]map[string][]persistence.E
 	var archivedKey = "archived"
 	var activeKey = "active"
 
-	wrap := make(map[string]map[string][]persistence.EstablishedAgreement, 0)
-	wrap[agreementsKey] = make(map[string][]persistence.EstablishedAgreement, 0)
-	wrap[agreementsKey][archivedKey] = []persistence.EstablishedAgreement{}
-	wrap[agreementsKey][activeKey] = []persistence.EstablishedAgreement{}
+	active := []persistence.EstablishedAgreement{}
+	archived := []persistence.EstablishedAgreement{}
 
 	for _, agreement := range agreements {
 		// The archived agreements and the agreements being terminated are returned as archived.
 		if agreement.Archived || agreement.AgreementTerminatedTime != 0 {
-			wrap[agreementsKey][archivedKey] = append(wrap[agreementsKey][archivedKey], agreement)
+			archived = append(archived, agreement)
 		} else {
-			wrap[agreementsKey][activeKey] = append(wrap[agreementsKey][activeKey], agreement)
+			active = append(active, agreement)
 		}
 	}
 
 	// do sorts
-	sort.Sort(EstablishedAgreementsByAgreementCreationTime(wrap[agreementsKey][activeKey]))
-	sort.Sort(EstablishedAgreementsByAgreementTerminatedTime(wrap[agreementsKey][archivedKey]))
+	sort.Sort(EstablishedAgreementsByAgreementCreationTime(active))
+	sort.Sort(EstablishedAgreementsByAgreementTerminatedTime(archived))
+
+	// The active agreements show the progress of their image pulls.
+	wrap := make(map[string]map[string][]AgreementOutput, 0)
+	wrap[agreementsKey] = make(map[string][]AgreementOutput, 0)
+	wrap[agreementsKey][archivedKey] = make([]AgreementOutput, 0, len(archived))
+	wrap[agreementsKey][activeKey] = make([]AgreementOutput, 0, len(active))
+
+	for _, agreement := range active {
+		wrap[agreementsKey][activeKey] = append(wrap[agreementsKey][activeKey], *NewAgreementOutput(agreement, imagefetch.GetImagePullProgress(agreement.CurrentAgreementId)))
+	}
+	for _, agreement := range archived {
+		wrap[agreementsKey][archivedKey] = append(wrap[agreementsKey][archivedKey], *NewAgreementOutput(agreement, nil))
+	}
 
 	return wrap, nil
 }
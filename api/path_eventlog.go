@@ -7,6 +7,8 @@ import (
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/persistence"
 	"sort"
+	"strconv"
+	"time"
 )
 
 // This API returns the event logs saved on the db.
@@ -30,3 +32,47 @@ func FindEventLogsForOutput(db *bolt.DB, all_logs bool, selections map[string][]
 		return event_logs, nil
 	}
 }
+
+// This API returns the event logs, from the current registration or from all registrations, saved after the given
+// record id. It is used to catch up a client that is following the event logs.
+func FindEventLogsAfterRecord(db *bolt.DB, all_logs bool, last_id uint64, selections map[string][]string) ([]persistence.EventLog, error) {
+
+	event_logs, err := FindEventLogsForOutput(db, all_logs, selections)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]persistence.EventLog, 0)
+	for _, el := range event_logs {
+		if id, err := strconv.ParseUint(el.Id, 10, 64); err == nil && id > last_id {
+			out = append(out, el)
+		}
+	}
+	return out, nil
+}
+
+// This API deletes the event logs, from all registrations, saved before the given time. The time is in seconds since
+// the epoch. Returns the number of event logs deleted.
+func DeleteEventLogs(before string, errorhandler ErrorHandler, db *bolt.DB) (bool, map[string]int) {
+
+	if before == "" {
+		return errorhandler(NewAPIUserInputError("must be specified", "before")), nil
+	}
+
+	timestamp, err := strconv.ParseUint(before, 10, 64)
+	if err != nil {
+		return errorhandler(NewAPIUserInputError(fmt.Sprintf("must be a number of seconds since the epoch, error %v", err), "before")), nil
+	}
+
+	deleted, err := persistence.DeleteEventLogsBefore(db, timestamp)
+	if err != nil {
+		return errorhandler(NewSystemError(fmt.Sprintf("unable to delete the event logs, error %v", err))), nil
+	}
+
+	glog.V(3).Infof(apiLogString(fmt.Sprintf("Deleted %v event logs saved before %v.", deleted, timestamp)))
+	eventlog.LogDatabaseEvent(db, persistence.SEVERITY_INFO,
+		fmt.Sprintf("Deleted %v event logs saved before %v.", deleted, time.Unix(int64(timestamp), 0).Format(time.RFC3339)),
+		persistence.EC_EVENTLOGS_PURGED)
+
+	return false, map[string]int{"deleted": deleted}
+}
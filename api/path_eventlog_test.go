@@ -3,12 +3,17 @@
 package api
 
 import (
+	"bufio"
 	"flag"
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func init() {
@@ -112,3 +117,123 @@ func Test_FindEventLogsForOutput(t *testing.T) {
 	}
 
 }
+
+func Test_DeleteEventLogs(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	source := persistence.NewNodeEventSource("node1", "myorg", "", "configured")
+	for i := 0; i < 5; i++ {
+		el := persistence.NewEventLog(persistence.SEVERITY_INFO, "node event", persistence.EC_NODE_CONFIG_REG_COMPLETE, persistence.SRC_TYPE_NODE, *source)
+		el.Timestamp = uint64(1000 + i*100)
+		if err := persistence.SaveEventLog(db, el); err != nil {
+			t.Fatalf("error saving event log: %v", err)
+		}
+	}
+
+	var myError error
+	errorhandler := GetPassThroughErrorHandler(&myError)
+
+	// the time is required and must be a number
+	errHandled, out := DeleteEventLogs("", errorhandler, db)
+	assert.True(t, errHandled, "A missing time should be an error.")
+	assert.IsType(t, &APIUserInputError{}, myError, "The error should be a user input error.")
+	assert.Nil(t, out, "Nothing should be returned.")
+
+	myError = nil
+	errHandled, _ = DeleteEventLogs("2019-01-01", errorhandler, db)
+	assert.True(t, errHandled, "An invalid time should be an error.")
+	assert.IsType(t, &APIUserInputError{}, myError, "The error should be a user input error.")
+
+	myError = nil
+	errHandled, out = DeleteEventLogs("1250", errorhandler, db)
+	assert.False(t, errHandled, "Deleting should not fail.")
+	assert.Nil(t, myError, "There should be no error.")
+	assert.Equal(t, 3, out["deleted"], "The event logs before the time should be deleted.")
+
+	// the older event logs are gone, the purge itself is logged
+	elogs, err := FindEventLogsForOutput(db, true, map[string][]string{})
+	assert.Nil(t, err, "Finding event logs should not fail.")
+	assert.Equal(t, 3, len(elogs), "The newer event logs and the purge event log should be left.")
+	assert.Equal(t, persistence.EC_EVENTLOGS_PURGED, elogs[2].EventCode, "The purge should be logged.")
+}
+
+func Test_EventlogStream(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	// two event logs saved before the client connects
+	for _, msg := range []string{"node registered.", "service started."} {
+		if err := eventlog.LogNodeEvent(db, persistence.SEVERITY_INFO, msg, persistence.EC_NODE_CONFIG_REG_COMPLETE, "node1", "myorg", "", "configured"); err != nil {
+			t.Errorf("error saving event log: %v", err)
+		}
+	}
+
+	if elogs, err := FindEventLogsAfterRecord(db, true, 1, map[string][]string{}); err != nil {
+		t.Errorf("failed to get event logs: %v", err)
+	} else {
+		assert.Equal(t, 1, len(elogs), "Only the event logs after the record id should be returned.")
+	}
+
+	// the event logs saved before the node was unregistered are only caught up on from all registrations
+	if err := persistence.SaveLastUnregistrationTime(db, uint64(time.Now().Unix())); err != nil {
+		t.Errorf("error saving the unregistration time: %v", err)
+	} else if elogs, err := FindEventLogsAfterRecord(db, false, 0, map[string][]string{}); err != nil {
+		t.Errorf("failed to get event logs: %v", err)
+	} else {
+		assert.Equal(t, 0, len(elogs), "The event logs of an earlier registration should not be returned.")
+	}
+
+	a := &API{db: db}
+	server := httptest.NewServer(http.HandlerFunc(a.eventlogStream))
+	defer server.Close()
+
+	// reconnect after the first event log, only errors
+	req, _ := http.NewRequest("GET", server.URL+"?severity=error", nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to connect to the stream: %v", err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"), "The stream should use server sent events.")
+
+	if err := eventlog.LogNodeEvent(db, persistence.SEVERITY_INFO, "not an error.", persistence.EC_NODE_CONFIG_REG_COMPLETE, "node1", "myorg", "", "configured"); err != nil {
+		t.Errorf("error saving event log: %v", err)
+	} else if err := eventlog.LogNodeEvent(db, persistence.SEVERITY_ERROR, "an error.", persistence.EC_NODE_CONFIG_REG_COMPLETE, "node1", "myorg", "", "configured"); err != nil {
+		t.Errorf("error saving event log: %v", err)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	lines := []string{}
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read the stream: %v", err)
+		} else if line != "\n" {
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+	}
+
+	assert.Equal(t, "id: 4", lines[0], "Only the error should be streamed.")
+	assert.Equal(t, "event: eventlog", lines[1], "The event type should be set.")
+	assert.True(t, strings.HasPrefix(lines[2], "data: ") && strings.Contains(lines[2], "an error."), "The event log should be sent as json.")
+
+	// a bad last event id
+	req, _ = http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	if resp, err := http.DefaultClient.Do(req); err != nil {
+		t.Errorf("failed to connect to the stream: %v", err)
+	} else {
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "A bad last event id should be rejected.")
+	}
+}
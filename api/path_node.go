@@ -77,6 +77,13 @@ func FindHorizonDeviceForOutput(db *bolt.DB) (*HorizonDevice, error) {
 		}
 	} else {
 		device = ConvertFromPersistentHorizonDevice(pDevice)
+
+		// Show the upgrades that the node is holding until its next maintenance window.
+		if pus, err := persistence.FindPendingUpgrades(db, []persistence.PUFilter{}); err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to read pending upgrades, error %v", err))
+		} else if len(pus) != 0 {
+			device.PendingUpgrades = &pus
+		}
 	}
 
 	return device, nil
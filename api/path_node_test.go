@@ -776,3 +776,33 @@ func getBasicDevice(org string, pattern string) *HorizonDevice {
 	}
 	return hd
 }
+
+// Verify that the upgrades held for a maintenance window are shown with the node.
+func Test_FindHorizonDeviceForOutput_PendingUpgrades(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	device := getBasicDevice("myorg", "testPattern")
+	if _, err := persistence.SaveNewExchangeDevice(db, *device.Id, *device.Token, *device.Name, false, *device.Org, *device.Pattern, persistence.CONFIGSTATE_CONFIGURED); err != nil {
+		t.Errorf("unexpected error creating device %v", err)
+	}
+
+	if dev, err := FindHorizonDeviceForOutput(db); err != nil {
+		t.Errorf("failed to find device in db, error %v", err)
+	} else if dev.PendingUpgrades != nil {
+		t.Errorf("there should be no pending upgrades, but found %v", *dev.PendingUpgrades)
+	}
+
+	pu := &persistence.PendingUpgrade{Key: "ag1", Type: persistence.PENDING_UPGRADE_AGREEMENT, ServiceURL: "myservice", ServiceOrg: "myorg", CurrentVersion: "1.0.0"}
+	if err := persistence.SavePendingUpgrade(db, pu); err != nil {
+		t.Errorf("unexpected error saving pending upgrade %v", err)
+	} else if dev, err := FindHorizonDeviceForOutput(db); err != nil {
+		t.Errorf("failed to find device in db, error %v", err)
+	} else if dev.PendingUpgrades == nil || len(*dev.PendingUpgrades) != 1 || (*dev.PendingUpgrades)[0].Key != "ag1" {
+		t.Errorf("the pending upgrade should be shown with the node, but found %v", dev.PendingUpgrades)
+	}
+}
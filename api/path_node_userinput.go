@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/exchangesync"
@@ -27,6 +29,7 @@ func FindNodeUserInputForOutput(db *bolt.DB) ([]policy.UserInput, error) {
 func UpdateNodeUserInput(userInput []policy.UserInput,
 	errorhandler DeviceErrorHandler,
 	getDevice exchange.DeviceHandler,
+	getService exchange.ServiceHandler,
 	patchDevice exchange.PatchDeviceHandler,
 	db *bolt.DB) (bool, []policy.UserInput, []*events.NodeUserInputMessage) {
 
@@ -39,13 +42,22 @@ func UpdateNodeUserInput(userInput []policy.UserInput,
 		return errorhandler(nil, NewNotFoundError("Exchange registration not recorded. Complete account and node registration with an exchange and then record node registration using this API's /node path.", "node")), nil, nil
 	}
 
-	if changedSvcs, err := exchangesync.UpdateNodeUserInput(pDevice, db, userInput, getDevice, patchDevice); err != nil {
+	userInput, err = classifyNodeUserInput(db, getService, userInput)
+	if err != nil {
+		return errorhandler(pDevice, NewAPIUserInputError(err.Error(), "userinput")), nil, nil
+	}
+
+	if err := validateNodeUserInputSchema(db, userInput); err != nil {
+		return errorhandler(pDevice, NewAPIUserInputError(err.Error(), "userinput")), nil, nil
+	}
+
+	if changedSvcs, changedSecrets, err := exchangesync.UpdateNodeUserInput(pDevice, db, userInput, getDevice, patchDevice); err != nil {
 		return errorhandler(pDevice, NewSystemError(fmt.Sprintf("Unable to update the node user input. %v", err))), nil, nil
 	} else {
 		LogDeviceEvent(db, persistence.SEVERITY_INFO, fmt.Sprintf("New node user input: %v", userInput), persistence.EC_NODE_USERINPUT_UPDATED, pDevice)
 
-		nodeUserInputUpdated := events.NewNodeUserInputMessage(events.UPDATE_NODE_USERINPUT, changedSvcs)
-		return false, userInput, []*events.NodeUserInputMessage{nodeUserInputUpdated}
+		public, _ := policy.SplitSecretUserInput(userInput)
+		return false, public, nodeUserInputMessages(changedSvcs, changedSecrets)
 	}
 }
 
@@ -53,6 +65,7 @@ func UpdateNodeUserInput(userInput []policy.UserInput,
 func PatchNodeUserInput(patchObject []policy.UserInput,
 	errorhandler DeviceErrorHandler,
 	getDevice exchange.DeviceHandler,
+	getService exchange.ServiceHandler,
 	patchDevice exchange.PatchDeviceHandler,
 	db *bolt.DB) (bool, []policy.UserInput, []*events.NodeUserInputMessage) {
 
@@ -63,19 +76,138 @@ func PatchNodeUserInput(patchObject []policy.UserInput,
 		return errorhandler(nil, NewNotFoundError("Exchange registration not recorded. Complete account and node registration with an exchange and then record node registration using this API's /node path.", "node")), nil, nil
 	}
 
-	if err := exchangesync.PatchNodeUserInput(pDevice, db, patchObject, getDevice, patchDevice); err != nil {
+	patchObject, err = classifyNodeUserInput(db, getService, patchObject)
+	if err != nil {
+		return errorhandler(pDevice, NewAPIUserInputError(err.Error(), "userinput")), nil, nil
+	}
+
+	if err := validateNodeUserInputSchema(db, patchObject); err != nil {
+		return errorhandler(pDevice, NewAPIUserInputError(err.Error(), "userinput")), nil, nil
+	}
+
+	if changedSecrets, err := exchangesync.PatchNodeUserInput(pDevice, db, patchObject, getDevice, patchDevice); err != nil {
 		return errorhandler(pDevice, NewSystemError(fmt.Sprintf("Unable patch the user input. %v", err))), nil, nil
 	} else {
 		LogDeviceEvent(db, persistence.SEVERITY_INFO, fmt.Sprintf("New node user input: %v", patchObject), persistence.EC_NODE_USERINPUT_UPDATED, pDevice)
 
+		// a service whose patch only has secrets is restarted with the new secrets
+		public, _ := policy.SplitSecretUserInput(patchObject)
 		chnagedSvcSpecs := new(persistence.ServiceSpecs)
-		for _, ui := range patchObject {
-			chnagedSvcSpecs.AppendServiceSpec(persistence.ServiceSpec{Url: ui.ServiceUrl, Org: ui.ServiceOrgid})
+		for _, ui := range public {
+			for _, in := range ui.Inputs {
+				if !in.IsSecret() {
+					chnagedSvcSpecs.AppendServiceSpec(persistence.ServiceSpec{Url: ui.ServiceUrl, Org: ui.ServiceOrgid})
+					break
+				}
+			}
+		}
+		return false, public, nodeUserInputMessages(*chnagedSvcSpecs, changedSecrets)
+	}
+}
+
+// Give the secret type to the inputs that the service definitions declare as secrets, so that their values stay on
+// the node. The definitions are those of the services on the node and the latest definition of each service in the
+// exchange. A service with no definition keeps the types given by the client.
+func classifyNodeUserInput(db *bolt.DB, getService exchange.ServiceHandler, userInput []policy.UserInput) ([]policy.UserInput, error) {
+
+	msdefs, err := persistence.FindMicroserviceDefs(db, []persistence.MSFilter{persistence.UnarchivedMSFilter()})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to read the service definitions, error %v", err))
+	}
 
+	// the names of the declared secrets of each service
+	declared := make(map[string]map[string]bool)
+	for _, ui := range userInput {
+		svcId := cutil.FormOrgSpecUrl(ui.ServiceUrl, ui.ServiceOrgid)
+		if _, ok := declared[svcId]; ok {
+			continue
 		}
-		nodeUserInputUpdated := events.NewNodeUserInputMessage(events.UPDATE_NODE_USERINPUT, *chnagedSvcSpecs)
-		return false, patchObject, []*events.NodeUserInputMessage{nodeUserInputUpdated}
+		secrets := make(map[string]bool)
+		for _, msdef := range msdefs {
+			if msdef.SpecRef == ui.ServiceUrl && msdef.Org == ui.ServiceOrgid {
+				for _, sui := range msdef.UserInputs {
+					if sui.Type == policy.USER_INPUT_TYPE_SECRET {
+						secrets[sui.Name] = true
+					}
+				}
+			}
+		}
+
+		arch := ui.ServiceArch
+		if arch == "" {
+			arch = cutil.ArchString()
+		}
+		if sdef, _, err := getService(ui.ServiceUrl, ui.ServiceOrgid, ui.ServiceVersionRange, arch); err != nil {
+			glog.Warningf(apiLogString(fmt.Sprintf("Unable to get the definition of service %v from the exchange to find its secrets, error %v", svcId, err)))
+		} else if sdef != nil {
+			for _, sui := range sdef.UserInputs {
+				if sui.Type == policy.USER_INPUT_TYPE_SECRET {
+					secrets[sui.Name] = true
+				}
+			}
+		}
+		declared[svcId] = secrets
+	}
+
+	return policy.ClassifySecretUserInput(userInput, func(ui policy.UserInput, name string) bool {
+		return declared[cutil.FormOrgSpecUrl(ui.ServiceUrl, ui.ServiceOrgid)][name]
+	})
+}
+
+// Verify the node user input values against the schemas declared by the definitions of the services on the node. The
+// services that are not on the node yet are verified by the agbot before it makes an agreement.
+func validateNodeUserInputSchema(db *bolt.DB, userInput []policy.UserInput) error {
+
+	msdefs, err := persistence.FindMicroserviceDefs(db, []persistence.MSFilter{persistence.UnarchivedMSFilter()})
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to read the service definitions, error %v", err))
+	}
+
+	for _, msdef := range msdefs {
+		ui, err := policy.FindUserInput(msdef.SpecRef, msdef.Org, msdef.Version, msdef.Arch, userInput)
+		if err != nil {
+			return err
+		} else if ui == nil {
+			continue
+		}
+		for _, in := range ui.Inputs {
+			inputType := ""
+			if in.IsSecret() {
+				// a placeholder keeps the current value of the secret, which was checked when it was set
+				if in.Value == policy.SECRET_PLACEHOLDER {
+					continue
+				}
+				inputType = policy.USER_INPUT_TYPE_SECRET
+			}
+			if sui := msdef.GetUserInputName(in.Name); sui != nil {
+				if inputType == "" {
+					inputType = sui.Type
+				}
+				if err := sui.Schema.Check(inputType, in.Value); err != nil {
+					return errors.New(fmt.Sprintf(cutil.ANAX_SVC_INVALID_VALUE+"%v.", in.Name, cutil.FormOrgSpecUrl(msdef.SpecRef, msdef.Org), err))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the messages for a change of the node user input. The agreements of the changed services are cancelled. The
+// services whose only change is a secret are restarted with the new secret instead.
+func nodeUserInputMessages(changedSvcs persistence.ServiceSpecs, changedSecrets persistence.ServiceSpecs) []*events.NodeUserInputMessage {
+
+	msgs := []*events.NodeUserInputMessage{events.NewNodeUserInputMessage(events.UPDATE_NODE_USERINPUT, changedSvcs)}
+
+	secretOnly := new(persistence.ServiceSpecs)
+	for _, sp := range changedSecrets {
+		if !changedSvcs.SupportService(sp.Url, sp.Org) || len(changedSvcs) == 0 {
+			secretOnly.AppendServiceSpec(sp)
+		}
+	}
+	if len(*secretOnly) != 0 {
+		msgs = append(msgs, events.NewNodeUserInputMessage(events.UPDATE_NODE_SECRETS, *secretOnly))
 	}
+	return msgs
 }
 
 // Delete the node policy object.
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/boltdb/bolt"
 	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/imagefetch"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"sort"
@@ -57,7 +58,9 @@ func FindServicesForOutput(pm *policy.PolicyManager,
 			if err != nil {
 				return nil, errors.New(fmt.Sprintf("unable to get docker container info, error %v", err))
 			}
-			wrap.Instances[activeKey] = append(wrap.Instances[activeKey], *NewMicroserviceInstanceOutput(msinst, &containers))
+			out := NewMicroserviceInstanceOutput(msinst, &containers)
+			out.ImagePull = imagefetch.GetImagePullProgress(msinst.GetKey())
+			wrap.Instances[activeKey] = append(wrap.Instances[activeKey], *out)
 		}
 	}
 
@@ -70,7 +73,9 @@ func FindServicesForOutput(pm *policy.PolicyManager,
 			if err != nil {
 				return nil, errors.New(fmt.Sprintf("unable to get docker container info, error %v", err))
 			}
-			wrap.Instances[activeKey] = append(wrap.Instances[activeKey], *NewAgreementServiceInstanceOutput(&agInst, &containers))
+			out := NewAgreementServiceInstanceOutput(&agInst, &containers)
+			out.ImagePull = imagefetch.GetImagePullProgress(agInst.CurrentAgreementId)
+			wrap.Instances[activeKey] = append(wrap.Instances[activeKey], *out)
 		}
 	}
 
@@ -321,6 +321,8 @@ func CreateService(service *Service,
 				if ui := msdef.GetUserInputName(varName); ui != nil {
 					if err := cutil.VerifyWorkloadVarTypes(varValue, ui.Type); err != nil {
 						return errorhandler(NewAPIUserInputError(fmt.Sprintf(cutil.ANAX_SVC_WRONG_TYPE+"%v", varName, cutil.FormOrgSpecUrl(*service.Url, *service.Org), err), "variables")), nil
+					} else if err := ui.Schema.Check(ui.Type, varValue); err != nil {
+						return errorhandler(NewAPIUserInputError(fmt.Sprintf(cutil.ANAX_SVC_INVALID_VALUE+"%v.", varName, cutil.FormOrgSpecUrl(*service.Url, *service.Org), err), "variables")), nil
 					}
 				}
 			}
@@ -420,6 +422,15 @@ func CreateService(service *Service,
 		}
 	}
 
+	// the values of the inputs declared as secrets stay on the node
+	userInput, err = policy.ClassifySecretUserInput(userInput, func(ui policy.UserInput, name string) bool {
+		sui := sdef.GetUserInputName(name)
+		return sui != nil && sui.Type == policy.USER_INPUT_TYPE_SECRET
+	})
+	if err != nil {
+		return errorhandler(NewAPIUserInputError(err.Error(), "service.[attribute].mappings")), nil, nil
+	}
+
 	// merge the user input with the pattern and existing node user input to get a whole user input for this service
 	merged_ui := mergedUserInput
 	if len(userInput) > 0 {
@@ -442,8 +453,13 @@ func CreateService(service *Service,
 		}
 	}
 
+	// make sure the user settings meet the constraints in the service definition.
+	if varName, err := checkUserInputSchemas(sdef, merged_ui); err != nil {
+		return errorhandler(NewAPIUserInputError(fmt.Sprintf(cutil.ANAX_SVC_INVALID_VALUE+"%v.", varName, cutil.FormOrgSpecUrl(*service.Url, *service.Org), err), "service.[attribute].mappings")), nil, nil
+	}
+
 	if from_user && len(userInput) > 0 {
-		if err := exchangesync.PatchNodeUserInput(pDevice, db, userInput, getDevice, patchDevice); err != nil {
+		if _, err := exchangesync.PatchNodeUserInput(pDevice, db, userInput, getDevice, patchDevice); err != nil {
 			return errorhandler(NewSystemError(fmt.Sprintf("Failed to add the user input %v to node. %v", userInput, err))), nil, nil
 		}
 	}
@@ -567,6 +583,29 @@ func validateUserInput(sdef *exchange.ServiceDefinition, mergedUserInput *policy
 	return true, ""
 }
 
+// Check the user input values against the schemas in the service definition. Returns the name of the first variable
+// whose value does not meet its schema, and the reason.
+func checkUserInputSchemas(sdef *exchange.ServiceDefinition, mergedUserInput *policy.UserInput) (string, error) {
+	if mergedUserInput == nil {
+		return "", nil
+	}
+
+	for _, ui := range sdef.UserInputs {
+		if input := mergedUserInput.FindInput(ui.Name); input == nil {
+			continue
+		} else if input.IsSecret() && input.Value == policy.SECRET_PLACEHOLDER {
+			continue
+		} else if input.IsSecret() {
+			if err := ui.Schema.Check(policy.USER_INPUT_TYPE_SECRET, input.Value); err != nil {
+				return ui.Name, err
+			}
+		} else if err := ui.Schema.Check(ui.Type, input.Value); err != nil {
+			return ui.Name, err
+		}
+	}
+	return "", nil
+}
+
 // get the pattern from exchange
 func getExchangePattern(patOrg string, patName string, getPatterns exchange.PatternHandler) (*exchange.Pattern, error) {
 	pattern, err := getPatterns(patOrg, patName)
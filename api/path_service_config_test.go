@@ -8,6 +8,7 @@ import (
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
+	"strings"
 	"testing"
 )
 
@@ -133,3 +134,49 @@ func Test_validateUserInput(t *testing.T) {
 		t.Errorf("missedName should be var4 but got: %v.", missedName)
 	}
 }
+
+func Test_checkUserInputSchemas(t *testing.T) {
+	max := float64(10)
+	ui := []exchange.UserInput{
+		exchange.UserInput{Name: "var1", Type: "string", Schema: &policy.UserInputSchema{Enum: []interface{}{"low", "high"}}},
+		exchange.UserInput{Name: "var2", Type: "int", Schema: &policy.UserInputSchema{Maximum: &max}},
+		exchange.UserInput{Name: "var3", Type: "string", Schema: &policy.UserInputSchema{Pattern: "^[a-z]+$"}},
+		exchange.UserInput{Name: "var4", Type: "bool"},
+	}
+
+	sdef := exchange.ServiceDefinition{UserInputs: ui}
+
+	userInput := policy.UserInput{
+		ServiceOrgid: "mycomp",
+		ServiceUrl:   "cpu",
+		Inputs: []policy.Input{policy.Input{Name: "var1", Value: "low"},
+			policy.Input{Name: "var2", Value: float64(10)},
+			policy.Input{Name: "var3", Value: policy.SECRET_PLACEHOLDER, Type: policy.USER_INPUT_TYPE_SECRET},
+		},
+	}
+
+	if varName, err := checkUserInputSchemas(&sdef, &userInput); err != nil {
+		t.Errorf("checkUserInputSchemas should not return an error for %v, but got: %v.", varName, err)
+	}
+
+	userInput.Inputs[1].Value = float64(11)
+	if varName, err := checkUserInputSchemas(&sdef, &userInput); err == nil {
+		t.Errorf("checkUserInputSchemas should return an error, but not.")
+	} else if varName != "var2" {
+		t.Errorf("varName should be var2 but got: %v.", varName)
+	}
+
+	userInput.Inputs[1].Value = float64(1)
+	userInput.Inputs[2].Value = "ABC"
+	if varName, err := checkUserInputSchemas(&sdef, &userInput); err == nil {
+		t.Errorf("checkUserInputSchemas should return an error, but not.")
+	} else if varName != "var3" {
+		t.Errorf("varName should be var3 but got: %v.", varName)
+	} else if strings.Contains(err.Error(), "ABC") {
+		t.Errorf("the error should not show the secret value, but got: %v.", err)
+	}
+
+	if _, err := checkUserInputSchemas(&sdef, nil); err != nil {
+		t.Errorf("checkUserInputSchemas should not return an error without user input, but got: %v.", err)
+	}
+}
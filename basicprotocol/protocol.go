@@ -19,6 +19,7 @@ const PROTOCOL_CURRENT_VERSION = 1
 // Extended message types
 const MsgTypeVerifyAgreement = "basicagreementverification"
 const MsgTypeVerifyAgreementReply = "basicagreementverificationreply"
+const MsgTypeWorkloadPrefetch = "basicworkloadprefetch"
 
 // This message enables a producer to ask the consumer to verify that a specific agreement still exists. If the
 // consumer replies with NO (false), the producer can cancel the agreement.
@@ -69,6 +70,38 @@ func NewBAgreementVerifyReply(bp *abstractprotocol.BaseProtocolMessage, exists b
 	}
 }
 
+// This message enables a consumer to tell the producer which workload version an agreement is going to be upgraded to,
+// so that the producer can fetch the workload's images before the upgrade. The producer is free to ignore it.
+type BWorkloadPrefetch struct {
+	*abstractprotocol.BaseProtocolMessage
+	URL     string `json:"url"`
+	Org     string `json:"org"`
+	Version string `json:"version"`
+	Arch    string `json:"arch"`
+}
+
+func (b *BWorkloadPrefetch) String() string {
+	return b.BaseProtocolMessage.String() + fmt.Sprintf(", URL: %v, Org: %v, Version: %v, Arch: %v", b.URL, b.Org, b.Version, b.Arch)
+}
+
+func (b *BWorkloadPrefetch) ShortString() string {
+	return b.String()
+}
+
+func (b *BWorkloadPrefetch) IsValid() bool {
+	return b.BaseProtocolMessage.IsValid() && b.MsgType == MsgTypeWorkloadPrefetch && b.URL != "" && b.Org != "" && b.Version != ""
+}
+
+func NewBWorkloadPrefetch(bp *abstractprotocol.BaseProtocolMessage, url string, org string, version string, arch string) *BWorkloadPrefetch {
+	return &BWorkloadPrefetch{
+		BaseProtocolMessage: bp,
+		URL:                 url,
+		Org:                 org,
+		Version:             version,
+		Arch:                arch,
+	}
+}
+
 // This is the object which users of the agreement protocol use to get access to the protocol functions. It MUST
 // implement all the functions in the abstract ProtocolHandler interface.
 type ProtocolHandler struct {
@@ -173,6 +206,28 @@ func (p *ProtocolHandler) SendAgreementVerificationReply(
 
 }
 
+func (p *ProtocolHandler) SendWorkloadPrefetch(
+	agreementId string,
+	workload *policy.Workload,
+	messageTarget interface{},
+	sendMessage func(mt interface{}, pay []byte) error) error {
+
+	prefetch := NewBWorkloadPrefetch(&abstractprotocol.BaseProtocolMessage{
+		MsgType:   MsgTypeWorkloadPrefetch,
+		AProtocol: p.Name(),
+		AVersion:  PROTOCOL_CURRENT_VERSION,
+		AgreeId:   agreementId,
+	},
+		workload.WorkloadURL, workload.Org, workload.Version, workload.Arch)
+
+	// Send the message
+	if err := abstractprotocol.SendProtocolMessage(messageTarget, prefetch, sendMessage); err != nil {
+		return errors.New(fmt.Sprintf("Protocol %v error sending workload prefetch %v, %v", p.Name(), prefetch, err))
+	}
+	return nil
+
+}
+
 // The following methods dont implement any extensions to the base agreement protocol.
 func (p *ProtocolHandler) Confirm(replyValid bool,
 	agreementId string,
@@ -261,6 +316,21 @@ func (p *ProtocolHandler) ValidateAgreementVerifyReply(verify string) (*BAgreeme
 
 }
 
+func (p *ProtocolHandler) ValidateWorkloadPrefetch(prefetch string) (*BWorkloadPrefetch, error) {
+
+	// attempt deserialization of message
+	pObj := new(BWorkloadPrefetch)
+
+	if err := json.Unmarshal([]byte(prefetch), pObj); err != nil {
+		return nil, errors.New(fmt.Sprintf("Error deserializing workload prefetch: %s, error: %v", prefetch, err))
+	} else if !pObj.IsValid() {
+		return nil, errors.New(fmt.Sprintf("Message is not a workload prefetch."))
+	} else {
+		return pObj, nil
+	}
+
+}
+
 func (p *ProtocolHandler) DemarshalProposal(proposal string) (abstractprotocol.Proposal, error) {
 	return abstractprotocol.DemarshalProposal(proposal)
 }
@@ -348,6 +418,7 @@ const CANCEL_MS_IMAGE_FETCH_FAILURE = 117
 const CANCEL_MS_DOWNGRADE_REQUIRED = 118
 const CANCEL_SERVICE_SUSPENDED = 119
 const CANCEL_NODE_USERINPUT_CHANGED = 120
+const CANCEL_CONTAINER_UNHEALTHY = 121
 
 // These constants represent consumer cancellation reason codes
 // const AB_CANCEL_NOT_FINALIZED_TIMEOUT = 200  // xc8
@@ -360,6 +431,7 @@ const AB_USER_REQUESTED = 206
 const AB_CANCEL_FORCED_UPGRADE = 207
 const AB_CANCEL_NODE_HEARTBEAT = 208
 const AB_CANCEL_AG_MISSING = 209
+const AB_CANCEL_WORKLOAD_UPGRADE = 210
 
 // const AB_CANCEL_BC_WRITE_FAILED       = 208  // xd0
 
@@ -385,6 +457,7 @@ func DecodeReasonCode(code uint64) string {
 		CANCEL_NODE_SHUTDOWN:            "node was unconfigured",
 		CANCEL_SERVICE_SUSPENDED:        "service suspended",
 		CANCEL_NODE_USERINPUT_CHANGED:   "node user input changed",
+		CANCEL_CONTAINER_UNHEALTHY:      "service health check failed",
 		// AB_CANCEL_NOT_FINALIZED_TIMEOUT: "agreement bot never detected agreement on the blockchain",
 		AB_CANCEL_NO_REPLY:         "agreement bot never received reply to proposal",
 		AB_CANCEL_NEGATIVE_REPLY:   "agreement bot received negative reply",
@@ -394,8 +467,9 @@ func DecodeReasonCode(code uint64) string {
 		AB_USER_REQUESTED:          "agreement bot user requested",
 		AB_CANCEL_FORCED_UPGRADE:   "agreement bot user requested service upgrade",
 		// AB_CANCEL_BC_WRITE_FAILED:   "agreement bot agreement write failed"}
-		AB_CANCEL_NODE_HEARTBEAT: "agreement bot detected node heartbeat stopped",
-		AB_CANCEL_AG_MISSING:     "agreement bot detected agreement missing from node"}
+		AB_CANCEL_NODE_HEARTBEAT:   "agreement bot detected node heartbeat stopped",
+		AB_CANCEL_AG_MISSING:       "agreement bot detected agreement missing from node",
+		AB_CANCEL_WORKLOAD_UPGRADE: "agreement bot policy changed the service version"}
 
 	if reasonString, ok := codeMeanings[code]; !ok {
 		return "unknown reason code, device might be downlevel"
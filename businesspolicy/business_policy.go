@@ -64,14 +64,53 @@ func (w WorkloadPriority) String() string {
 }
 
 type UpgradePolicy struct {
-	Lifecycle string `json:"lifecycle,omitempty"` // immediate, never, agreement
-	Time      string `json:"time,omitempty"`      // the time of the upgrade
+	Lifecycle string         `json:"lifecycle,omitempty"` // immediate, never, agreement
+	Time      string         `json:"time,omitempty"`      // the maintenance window for the upgrade, a time of day HH:MM or a cron-like schedule followed by a duration
+	Rollout   *RolloutPolicy `json:"rollout,omitempty"`   // upgrade the nodes to this version in waves instead of all at once
 }
 
 func (w UpgradePolicy) String() string {
-	return fmt.Sprintf("Lifecycle: %v, Time: %v",
+	return fmt.Sprintf("Lifecycle: %v, Time: %v, Rollout: %v",
 		w.Lifecycle,
-		w.Time)
+		w.Time,
+		w.Rollout)
+}
+
+// A staged rollout upgrades the nodes to a new service version a wave at a time. The size of a wave is either a
+// percentage of the nodes or a fixed number of nodes. Each wave has to stay healthy for the wave duration before
+// the next wave starts. When more than the failure threshold percentage of the nodes in a wave are not healthy,
+// all the nodes are rolled back to the next lower priority service version.
+type RolloutPolicy struct {
+	WavePercent      int `json:"wave_percent,omitempty"`      // The percentage of the nodes to upgrade in each wave
+	WaveCount        int `json:"wave_count,omitempty"`        // The number of nodes to upgrade in each wave
+	WaveDurationS    int `json:"wave_durations,omitempty"`    // The number of seconds a wave has to stay healthy before the next wave starts
+	FailureThreshold int `json:"failure_threshold,omitempty"` // The percentage of unhealthy nodes in a wave that causes a rollback
+}
+
+func (w RolloutPolicy) String() string {
+	return fmt.Sprintf("WavePercent: %v, WaveCount: %v, WaveDurationS: %v, FailureThreshold: %v",
+		w.WavePercent,
+		w.WaveCount,
+		w.WaveDurationS,
+		w.FailureThreshold)
+}
+
+func (w *RolloutPolicy) Validate() error {
+	if w.WavePercent < 0 || w.WavePercent > 100 {
+		return fmt.Errorf("wave_percent %v must be between 0 and 100.", w.WavePercent)
+	} else if w.WaveCount < 0 {
+		return fmt.Errorf("wave_count %v must not be negative.", w.WaveCount)
+	} else if w.WavePercent != 0 && w.WaveCount != 0 {
+		return fmt.Errorf("only one of wave_percent and wave_count can be specified.")
+	} else if w.WavePercent == 0 && w.WaveCount == 0 {
+		return fmt.Errorf("one of wave_percent or wave_count must be specified.")
+	} else if w.WaveDurationS <= 0 {
+		// the nodes of a wave need time to make their new agreements before the wave is checked
+		return fmt.Errorf("wave_durations %v must be greater than 0.", w.WaveDurationS)
+	} else if w.FailureThreshold < 0 || w.FailureThreshold > 100 {
+		return fmt.Errorf("failure_threshold %v must be between 0 and 100.", w.FailureThreshold)
+	}
+	return nil
 }
 
 type WorkloadChoice struct {
@@ -109,6 +148,12 @@ func (b *BusinessPolicy) Validate() error {
 		return fmt.Errorf("The serviceVersions array is empty.")
 	}
 
+	// Validate the rollout policy. Only the highest priority version can be rolled out, and there has to be
+	// a lower priority version to roll back to.
+	if err := b.Service.validateRollout(); err != nil {
+		return err
+	}
+
 	// Validate the PropertyList.
 	if b != nil && len(b.Properties) != 0 {
 		if err := b.Properties.Validate(); err != nil {
@@ -125,6 +170,34 @@ func (b *BusinessPolicy) Validate() error {
 	return nil
 }
 
+func (s *ServiceRef) validateRollout() error {
+	highest := s.HighestPriorityChoice()
+	for _, wl := range s.ServiceVersions {
+		if wl.Upgrade.Rollout == nil {
+			continue
+		} else if err := wl.Upgrade.Rollout.Validate(); err != nil {
+			return fmt.Errorf("The rollout policy of service version %v is not valid: %v", wl.Version, err)
+		} else if wl.Priority.PriorityValue == 0 || wl.Priority.PriorityValue != highest.Priority.PriorityValue {
+			return fmt.Errorf("The rollout policy of service version %v is only allowed on the highest priority service version.", wl.Version)
+		} else if len(s.ServiceVersions) < 2 {
+			return fmt.Errorf("The rollout policy of service version %v needs a lower priority service version to roll back to.", wl.Version)
+		}
+	}
+	return nil
+}
+
+// Returns the service version with the highest priority, which is the one with the lowest priority value. Versions
+// without a priority only count when none of the versions has one.
+func (s *ServiceRef) HighestPriorityChoice() *WorkloadChoice {
+	var highest *WorkloadChoice
+	for ix, wl := range s.ServiceVersions {
+		if highest == nil || (wl.Priority.PriorityValue != 0 && (highest.Priority.PriorityValue == 0 || wl.Priority.PriorityValue < highest.Priority.PriorityValue)) {
+			highest = &s.ServiceVersions[ix]
+		}
+	}
+	return highest
+}
+
 // Convert business policy to a policy object.
 func (b *BusinessPolicy) GenPolicyFromBusinessPolicy(policyName string) (*policy.Policy, error) {
 
@@ -174,6 +247,7 @@ func (b *BusinessPolicy) GenPolicyFromBusinessPolicy(policyName string) (*policy
 func ConvertChoice(wl WorkloadChoice, url string, org string, arch string, pol *policy.Policy) {
 	newWL := policy.Workload_Factory(url, org, wl.Version, arch)
 	newWL.Priority = (*policy.Workload_Priority_Factory(wl.Priority.PriorityValue, wl.Priority.Retries, wl.Priority.RetryDurationS, wl.Priority.VerifiedDurationS))
+	newWL.UpgradeTime = wl.Upgrade.Time
 	pol.Add_Workload(newWL)
 }
 
@@ -208,6 +208,88 @@ func Test_Validate_Succeeded4(t *testing.T) {
 	}
 }
 
+// rollout policies
+func Test_Validate_Rollout(t *testing.T) {
+
+	rollout := &RolloutPolicy{WavePercent: 10, WaveDurationS: 600, FailureThreshold: 20}
+
+	service := ServiceRef{
+		Name: "cpu",
+		Org:  "mycomp",
+		Arch: "amd64",
+		ServiceVersions: []WorkloadChoice{
+			{Version: "1.0.0", Priority: WorkloadPriority{PriorityValue: 2}},
+			{Version: "2.0.0", Priority: WorkloadPriority{PriorityValue: 1}, Upgrade: UpgradePolicy{Rollout: rollout}},
+		},
+	}
+
+	bPolicy := BusinessPolicy{
+		Owner:   "me",
+		Label:   "my business policy",
+		Service: service,
+	}
+
+	if err := bPolicy.Validate(); err != nil {
+		t.Errorf("Validate should have not have returned error but got: %v", err)
+	} else if hp := bPolicy.Service.HighestPriorityChoice(); hp.Version != "2.0.0" {
+		t.Errorf("The highest priority version should be 2.0.0 but got %v", hp.Version)
+	}
+
+	// the rollout is on a lower priority version
+	bPolicy.Service.ServiceVersions[0].Upgrade.Rollout = rollout
+	if err := bPolicy.Validate(); err == nil {
+		t.Errorf("Validate should have returned error but not.")
+	} else if !strings.Contains(err.Error(), "only allowed on the highest priority service version") {
+		t.Errorf("Wrong error string: %v", err)
+	}
+
+	// no version to roll back to
+	bPolicy.Service.ServiceVersions = bPolicy.Service.ServiceVersions[1:]
+	if err := bPolicy.Validate(); err == nil {
+		t.Errorf("Validate should have returned error but not.")
+	} else if !strings.Contains(err.Error(), "needs a lower priority service version") {
+		t.Errorf("Wrong error string: %v", err)
+	}
+
+	invalid := []RolloutPolicy{
+		{},
+		{WavePercent: 10, WaveCount: 2},
+		{WavePercent: 101},
+		{WaveCount: -1},
+		{WaveCount: 2, WaveDurationS: -1},
+		{WaveCount: 2},
+		{WaveCount: 2, WaveDurationS: 600, FailureThreshold: 200},
+	}
+	for _, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("Validate of rollout %v should have returned error but not.", r)
+		}
+	}
+}
+
+// the upgrade time is passed to the node in the workload
+func Test_GenPolicyFromBusinessPolicy_UpgradeTime(t *testing.T) {
+
+	bPolicy := BusinessPolicy{
+		Owner: "me",
+		Label: "my business policy",
+		Service: ServiceRef{
+			Name: "cpu",
+			Org:  "mycomp",
+			Arch: "amd64",
+			ServiceVersions: []WorkloadChoice{
+				{Version: "1.0.0", Upgrade: UpgradePolicy{Time: "0 2 * * 6 4h"}},
+			},
+		},
+	}
+
+	if pol, err := bPolicy.GenPolicyFromBusinessPolicy("mybp"); err != nil {
+		t.Errorf("GenPolicyFromBusinessPolicy should not have returned error but got: %v", err)
+	} else if pol.Workloads[0].UpgradeTime != "0 2 * * 6 4h" {
+		t.Errorf("The upgrade time should be passed to the workload, but got %v", pol.Workloads[0].UpgradeTime)
+	}
+}
+
 func Test_GenPolicyFromBusinessPolicy_Simple(t *testing.T) {
 
 	wlc := WorkloadChoice{
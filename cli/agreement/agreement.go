@@ -3,7 +3,9 @@ package agreement
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/imagefetch"
 	"github.com/open-horizon/anax/persistence"
 )
 
@@ -18,6 +20,31 @@ type ActiveAgreement struct {
 	AgreementDataReceivedTime   string                   `json:"agreement_data_received_time"`
 	AgreementProtocol           string                   `json:"agreement_protocol"` // the agreement protocol being used. It is also in the proposal.
 	Workload                    persistence.WorkloadInfo `json:"workload_to_run"`
+	ImagePull                   []ImagePull              `json:"image_pull,omitempty"` // the image pulls of the agreement, while they are in progress and for a while after
+}
+
+// The progress of an image pull of an agreement
+type ImagePull struct {
+	Image         string `json:"image"`
+	Source        string `json:"source,omitempty"`
+	Status        string `json:"status"`
+	Attempt       int    `json:"attempt"`
+	Progress      string `json:"progress"`
+	NextRetryTime string `json:"next_retry_time,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// CopyImagePullInto copies the image pull progress into our output struct
+func (p *ImagePull) CopyImagePullInto(progress imagefetch.ImagePullProgress) {
+	p.Image = progress.Image
+	p.Source = progress.Source
+	p.Status = progress.Status
+	p.Attempt = progress.Attempt
+	p.Progress = fmt.Sprintf("%v%% (%.1f of %.1f MB)", progress.Percent(), float64(progress.CurrentBytes)/(1024*1024), float64(progress.TotalBytes)/(1024*1024))
+	if progress.NextRetryTime != 0 {
+		p.NextRetryTime = cliutils.ConvertTime(progress.NextRetryTime)
+	}
+	p.Error = progress.Error
 }
 
 // CopyAgreementInto copies the agreement info into our output struct
@@ -74,9 +101,9 @@ func (a *ArchivedAgreement) CopyAgreementInto(agreement persistence.EstablishedA
 	a.TerminatedDescription = agreement.TerminatedDescription
 }
 
-func getAgreements(archivedAgreements bool) (apiAgreements []persistence.EstablishedAgreement) {
+func getAgreements(archivedAgreements bool) (apiAgreements []api.AgreementOutput) {
 	// Get horizon api agreement output and drill down to the category we want
-	apiOutput := make(map[string]map[string][]persistence.EstablishedAgreement, 0)
+	apiOutput := make(map[string]map[string][]api.AgreementOutput, 0)
 	cliutils.HorizonGet("agreement", []int{200}, &apiOutput, false)
 	var ok bool
 	if _, ok = apiOutput["agreements"]; !ok {
@@ -115,7 +142,12 @@ func List(archivedAgreements bool, agreementId string) {
 		if !archivedAgreements {
 			agreements := make([]ActiveAgreement, len(apiAgreements))
 			for i := range apiAgreements {
-				agreements[i].CopyAgreementInto(apiAgreements[i])
+				agreements[i].CopyAgreementInto(apiAgreements[i].EstablishedAgreement)
+				for _, progress := range apiAgreements[i].ImagePull {
+					var pull ImagePull
+					pull.CopyImagePullInto(progress)
+					agreements[i].ImagePull = append(agreements[i].ImagePull, pull)
+				}
 			}
 			jsonBytes, err := json.MarshalIndent(agreements, "", cliutils.JSON_INDENT)
 			if err != nil {
@@ -126,7 +158,7 @@ func List(archivedAgreements bool, agreementId string) {
 			// Archived agreements
 			agreements := make([]ArchivedAgreement, len(apiAgreements))
 			for i := range apiAgreements {
-				agreements[i].CopyAgreementInto(apiAgreements[i])
+				agreements[i].CopyAgreementInto(apiAgreements[i].EstablishedAgreement)
 			}
 			jsonBytes, err := json.MarshalIndent(agreements, "", cliutils.JSON_INDENT)
 			if err != nil {
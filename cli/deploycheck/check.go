@@ -0,0 +1,113 @@
+package deploycheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/cli/cliconfig"
+	"github.com/open-horizon/anax/cli/cliutils"
+	cliexchange "github.com/open-horizon/anax/cli/exchange"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/json_language"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Check which nodes a business policy would deploy its service to, using only local files. The service policy files
+// belong to the service definition files in the same position. A single service policy file applies to all of the
+// service definitions.
+func DeployCheck(businessPolFile string, nodePolFiles []string, serviceFiles []string, servicePolFiles []string, arch string) {
+
+	if len(servicePolFiles) > 1 && len(servicePolFiles) != len(serviceFiles) {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "the number of --service-pol flags (%v) must be 1 or the same as the number of --service flags (%v).", len(servicePolFiles), len(serviceFiles))
+	} else if len(servicePolFiles) != 0 && len(serviceFiles) == 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "--service-pol requires at least one --service.")
+	}
+
+	if arch == "" {
+		arch = os.Getenv("ARCH")
+	}
+
+	var bp businesspolicy.BusinessPolicy
+	readJsonFile(businessPolFile, &bp)
+
+	nodePolicies := make(map[string]*externalpolicy.ExternalPolicy)
+	for _, nodePolFile := range nodePolFiles {
+		nodePol := new(externalpolicy.ExternalPolicy)
+		readJsonFile(nodePolFile, nodePol)
+		name := nodeName(nodePolFile)
+		if _, ok := nodePolicies[name]; ok {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "more than one node policy file is named %v.", name)
+		}
+		nodePolicies[name] = nodePol
+	}
+
+	services := make(map[string]exchange.ServiceDefinition)
+	servicePolicies := make(map[string]*externalpolicy.ExternalPolicy)
+	for ix, serviceFile := range serviceFiles {
+		var sf cliexchange.ServiceFile
+		readJsonFile(serviceFile, &sf)
+		sf.SupportVersionRange()
+
+		// the service is assumed to be in the org of the business policy service if the file does not say
+		if sf.Org == "" {
+			sf.Org = bp.Service.Org
+		}
+		if sf.URL == "" || sf.Version == "" || sf.Arch == "" {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "service definition %v must specify url, version and arch.", serviceFile)
+		}
+
+		sId := FormServiceId(sf.URL, sf.Org, sf.Version, sf.Arch)
+		services[sId] = exchange.ServiceDefinition{
+			Label:            sf.Label,
+			Description:      sf.Description,
+			Public:           sf.Public,
+			URL:              sf.URL,
+			Version:          sf.Version,
+			Arch:             sf.Arch,
+			Sharable:         sf.Sharable,
+			RequiredServices: sf.RequiredServices,
+			UserInputs:       sf.UserInputs,
+		}
+
+		if len(servicePolFiles) != 0 {
+			servicePolFile := servicePolFiles[0]
+			if len(servicePolFiles) > 1 {
+				servicePolFile = servicePolFiles[ix]
+			}
+			servicePol := new(externalpolicy.ExternalPolicy)
+			readJsonFile(servicePolFile, servicePol)
+			if err := servicePol.Validate(); err != nil {
+				cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "invalid service policy %v: %v", servicePolFile, err)
+			}
+			servicePolicies[sId] = servicePol
+		}
+	}
+
+	output, err := CheckDeployment(businessPolFile, &bp, nodePolicies, services, servicePolicies, DefaultArchSynonyms(), arch)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "unable to check the deployment of business policy %v: %v", businessPolFile, err)
+	}
+
+	jsonOutput, err := cliutils.DisplayAsJson(output)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, "failed to marshal 'hzn deploycheck' output: %v", err)
+	}
+	fmt.Println(jsonOutput)
+}
+
+// The node name is the node policy file name without the directory and extension.
+func nodeName(nodePolFile string) string {
+	base := filepath.Base(nodePolFile)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+func readJsonFile(filePath string, obj interface{}) {
+	newBytes := cliconfig.ReadJsonFileWithLocalConfig(filePath)
+	if err := json.Unmarshal(newBytes, obj); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, "failed to unmarshal json input file %s: %v", filePath, err)
+	}
+}
@@ -0,0 +1,281 @@
+package deploycheck
+
+import (
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/semanticversion"
+	"sort"
+	"strings"
+)
+
+// The purpose of this file is to simulate the agbot's agreement initiation for a business policy, without a connection
+// to the exchange. The node policies, service definitions and service policies are all supplied by the caller. For each
+// node, the service versions in the business policy are tried in priority order, the same way the agbot tries them,
+// and the first version that resolves to a service definition with a compatible policy is chosen.
+
+// The arch synonyms that the agbot is configured with out of the box.
+func DefaultArchSynonyms() config.ArchSynonyms {
+	a := config.NewArchSynonyms()
+	a["x86_64"] = "amd64"
+	a["armhf"] = "arm"
+	a["aarch64"] = "arm64"
+	return a
+}
+
+// The result of trying one of the service versions in the business policy on a node.
+type WorkloadCheck struct {
+	Version         string `json:"version"`                   // The version or version range in the business policy
+	Priority        int    `json:"priority,omitempty"`        // The priority of this version in the business policy
+	Arch            string `json:"arch"`                      // The arch used to search for the service definition
+	ServiceId       string `json:"serviceId,omitempty"`       // The service definition that the version resolved to
+	ResolvedVersion string `json:"resolvedVersion,omitempty"` // The version of that service definition
+	Compatible      bool   `json:"compatible"`
+	Reason          string `json:"reason,omitempty"` // Why the service version cannot be deployed to the node
+}
+
+func (w WorkloadCheck) String() string {
+	return fmt.Sprintf("Version: %v, Priority: %v, Arch: %v, ServiceId: %v, ResolvedVersion: %v, Compatible: %v, Reason: %v",
+		w.Version, w.Priority, w.Arch, w.ServiceId, w.ResolvedVersion, w.Compatible, w.Reason)
+}
+
+// The result of checking the business policy against one node.
+type NodeCheck struct {
+	Node       string          `json:"node"`
+	Arch       string          `json:"arch"`
+	Compatible bool            `json:"compatible"`
+	ServiceId  string          `json:"serviceId,omitempty"` // The service definition that would be deployed
+	Version    string          `json:"version,omitempty"`   // The service version that would be deployed
+	Workloads  []WorkloadCheck `json:"workloads"`           // The service versions tried, in the order the agbot tries them
+}
+
+func (n NodeCheck) String() string {
+	return fmt.Sprintf("Node: %v, Arch: %v, Compatible: %v, ServiceId: %v, Version: %v, Workloads: %v",
+		n.Node, n.Arch, n.Compatible, n.ServiceId, n.Version, n.Workloads)
+}
+
+// The result of checking the business policy against all the nodes.
+type DeployCheckOutput struct {
+	BusinessPolicy string      `json:"businessPolicy"`
+	MatchingNodes  []string    `json:"matchingNodes"`
+	Nodes          []NodeCheck `json:"nodes"`
+}
+
+func (d DeployCheckOutput) String() string {
+	return fmt.Sprintf("BusinessPolicy: %v, MatchingNodes: %v, Nodes: %v", d.BusinessPolicy, d.MatchingNodes, d.Nodes)
+}
+
+// Form the service id used by the exchange, org/url_version_arch.
+func FormServiceId(url string, org string, version string, arch string) string {
+	return fmt.Sprintf("%v/%v_%v_%v", org, url, version, arch)
+}
+
+// Returns a service handler that finds service definitions in the input map instead of the exchange. The map is keyed
+// by service id. The lookup is the same as the exchange lookup, a single version must match exactly and a version range
+// resolves to the highest version within the range.
+func GetLocalServiceHandler(services map[string]exchange.ServiceDefinition, archSynonyms config.ArchSynonyms) exchange.ServiceHandler {
+
+	// the required services use the version range field, just like the service definitions returned by the exchange
+	(&exchange.GetServicesResponse{Services: services}).SupportVersionRange()
+
+	return func(wUrl string, wOrg string, wVersion string, wArch string) (*exchange.ServiceDefinition, string, error) {
+
+		searchVersion := ""
+		if wVersion == "" || semanticversion.IsVersionExpression(wVersion) {
+			// search for all versions
+		} else if semanticversion.IsVersionString(wVersion) {
+			searchVersion = wVersion
+		} else {
+			return nil, "", errors.New(fmt.Sprintf("input version %v is not a valid version string", wVersion))
+		}
+
+		candidates := make(map[string]exchange.ServiceDefinition)
+		for sId, sDef := range services {
+			if sDef.URL != wUrl || !strings.HasPrefix(sId, wOrg+"/") || canonicalArch(sDef.Arch, archSynonyms) != canonicalArch(wArch, archSynonyms) {
+				continue
+			} else if searchVersion != "" && sDef.Version != searchVersion {
+				continue
+			}
+			candidates[sId] = sDef
+		}
+
+		if len(candidates) == 0 {
+			return nil, "", nil
+		}
+
+		vRange, _ := semanticversion.Version_Expression_Factory("0.0.0")
+		if searchVersion == "" && wVersion != "" {
+			var err error
+			if vRange, err = semanticversion.Version_Expression_Factory(wVersion); err != nil {
+				return nil, "", errors.New(fmt.Sprintf("version range %v in error: %v", wVersion, err))
+			}
+		}
+
+		if highest, sDef, sId, err := exchange.GetHighestVersion(candidates, vRange); err != nil {
+			return nil, "", err
+		} else if highest == "" {
+			return nil, "", nil
+		} else {
+			return &sDef, sId, nil
+		}
+	}
+}
+
+// Check which of the input nodes the business policy would deploy its service to, and which service version would be
+// chosen on each node. The node policies are keyed by node name and the service policies are keyed by service id. The
+// node's arch is taken from its built-in arch property, or defaultArch when the node policy does not have one. User
+// input is not checked because the node's user input is not known.
+func CheckDeployment(bpName string, bp *businesspolicy.BusinessPolicy, nodePolicies map[string]*externalpolicy.ExternalPolicy,
+	services map[string]exchange.ServiceDefinition, servicePolicies map[string]*externalpolicy.ExternalPolicy,
+	archSynonyms config.ArchSynonyms, defaultArch string) (*DeployCheckOutput, error) {
+
+	businessPol, err := bp.GenPolicyFromBusinessPolicy(bpName)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &DeployCheckOutput{
+		BusinessPolicy: bpName,
+		MatchingNodes:  make([]string, 0),
+		Nodes:          make([]NodeCheck, 0, len(nodePolicies)),
+	}
+
+	nodeNames := make([]string, 0, len(nodePolicies))
+	for name := range nodePolicies {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+
+	handler := GetLocalServiceHandler(services, archSynonyms)
+
+	for _, name := range nodeNames {
+		nodePol := nodePolicies[name]
+		producerPol, err := policy.GenPolicyFromExternalPolicy(nodePol, policy.MakeExternalPolicyHeaderName(name))
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("invalid policy for node %v: %v", name, err))
+		}
+
+		nodeArch := defaultArch
+		for _, prop := range nodePol.Properties {
+			if arch, ok := prop.Value.(string); ok && prop.Name == externalpolicy.PROP_NODE_ARCH {
+				nodeArch = arch
+			}
+		}
+
+		nc := NodeCheck{
+			Node:      name,
+			Arch:      canonicalArch(nodeArch, archSynonyms),
+			Workloads: make([]WorkloadCheck, 0),
+		}
+
+		for _, workload := range workloadsInPriorityOrder(businessPol) {
+			wc, err := checkWorkload(workload, nc.Arch, businessPol, producerPol, handler, servicePolicies, archSynonyms)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("unable to check service version %v on node %v: %v", workload.Version, name, err))
+			}
+			nc.Workloads = append(nc.Workloads, *wc)
+
+			if wc.Compatible {
+				nc.Compatible = true
+				nc.ServiceId = wc.ServiceId
+				nc.Version = wc.ResolvedVersion
+				break
+			} else if workload.HasEmptyPriority() {
+				// the agbot does not move on to another version when there are no priorities
+				break
+			}
+		}
+
+		if nc.Compatible {
+			output.MatchingNodes = append(output.MatchingNodes, name)
+		}
+		output.Nodes = append(output.Nodes, nc)
+	}
+
+	return output, nil
+}
+
+// Try one service version from the business policy on a node, the same way the agbot does.
+func checkWorkload(workload policy.Workload, nodeArch string, businessPol *policy.Policy, producerPol *policy.Policy,
+	handler exchange.ServiceHandler, servicePolicies map[string]*externalpolicy.ExternalPolicy, archSynonyms config.ArchSynonyms) (*WorkloadCheck, error) {
+
+	// An empty or '*' arch means any arch, in which case the node's arch is used to search for the service.
+	arch := workload.Arch
+	if arch == "" || arch == "*" {
+		arch = nodeArch
+	}
+	arch = canonicalArch(arch, archSynonyms)
+
+	wc := &WorkloadCheck{
+		Version:  workload.Version,
+		Priority: workload.Priority.PriorityValue,
+		Arch:     arch,
+	}
+
+	if arch != nodeArch {
+		wc.Reason = fmt.Sprintf("service arch %v does not match the node arch %v", arch, nodeArch)
+		return wc, nil
+	}
+
+	_, sDef, sId, err := exchange.ServiceResolver(workload.WorkloadURL, workload.Org, workload.Version, arch, handler)
+	if err != nil {
+		wc.Reason = err.Error()
+		return wc, nil
+	}
+	wc.ServiceId = sId
+	wc.ResolvedVersion = sDef.Version
+
+	// merge the business policy with the top level service policy + service built-in policy
+	builtInSvcPol := externalpolicy.CreateServiceBuiltInPolicy(workload.WorkloadURL, workload.Org, workload.Version, arch)
+	var mergedSvcPol externalpolicy.ExternalPolicy
+	if servicePol, ok := servicePolicies[sId]; ok && servicePol != nil {
+		mergedSvcPol = *servicePol
+		(&mergedSvcPol).MergeWith(builtInSvcPol, false)
+	} else {
+		mergedSvcPol = *builtInSvcPol
+	}
+
+	consumerPol, err := policy.MergePolicyWithExternalPolicy(businessPol, &mergedSvcPol)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("error merging business policy with service policy for service %v: %v", sId, err))
+	}
+
+	report, err := policy.Explain_Compatibility(producerPol, consumerPol)
+	if err != nil {
+		return nil, err
+	} else if !report.Compatible {
+		reasons := make([]string, 0)
+		for _, check := range report.Checks {
+			if !check.Compatible {
+				reasons = append(reasons, check.Reason)
+			}
+		}
+		wc.Reason = strings.Join(reasons, " ")
+	} else {
+		wc.Compatible = true
+	}
+
+	return wc, nil
+}
+
+// Return the workloads in the order the agbot tries them, from the highest priority (lowest priority value) to the lowest.
+func workloadsInPriorityOrder(pol *policy.Policy) []policy.Workload {
+	workloads := make([]policy.Workload, len(pol.Workloads))
+	copy(workloads, pol.Workloads)
+	sort.SliceStable(workloads, func(i, j int) bool {
+		return workloads[i].Priority.PriorityValue < workloads[j].Priority.PriorityValue
+	})
+	return workloads
+}
+
+// Convert the arch to the GOARCH standard using the synonyms.
+func canonicalArch(arch string, archSynonyms config.ArchSynonyms) string {
+	if ca := archSynonyms.GetCanonicalArch(arch); ca != "" {
+		return ca
+	}
+	return arch
+}
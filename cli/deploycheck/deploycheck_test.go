@@ -0,0 +1,114 @@
+// +build unit
+
+package deploycheck
+
+import (
+	"github.com/open-horizon/anax/businesspolicy"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/externalpolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"testing"
+)
+
+func Test_CheckDeployment(t *testing.T) {
+
+	bp := &businesspolicy.BusinessPolicy{
+		Service: businesspolicy.ServiceRef{
+			Name: "http://mycompany.com/netspeed",
+			Org:  "myorg",
+			Arch: "*",
+			ServiceVersions: []businesspolicy.WorkloadChoice{
+				{Version: "2.0.0", Priority: businesspolicy.WorkloadPriority{PriorityValue: 1, Retries: 1, RetryDurationS: 3600}},
+				{Version: "1.0.0", Priority: businesspolicy.WorkloadPriority{PriorityValue: 2, Retries: 1, RetryDurationS: 3600}},
+			},
+		},
+		Constraints: externalpolicy.ConstraintExpression{"purpose == network-testing"},
+	}
+
+	// version 2.0.0 is only published for amd64, and requires a service that is not available
+	services := map[string]exchange.ServiceDefinition{
+		"myorg/http://mycompany.com/netspeed_2.0.0_amd64": {URL: "http://mycompany.com/netspeed", Version: "2.0.0", Arch: "amd64"},
+		"myorg/http://mycompany.com/netspeed_1.0.0_amd64": {URL: "http://mycompany.com/netspeed", Version: "1.0.0", Arch: "amd64",
+			RequiredServices: []exchange.ServiceDependency{{URL: "http://mycompany.com/gps", Org: "myorg", VersionRange: "1.0.0", Arch: "amd64"}}},
+		"myorg/http://mycompany.com/netspeed_1.0.0_arm": {URL: "http://mycompany.com/netspeed", Version: "1.0.0", Arch: "arm"},
+		"myorg/http://mycompany.com/gps_1.2.0_amd64":    {URL: "http://mycompany.com/gps", Version: "1.2.0", Arch: "amd64"},
+	}
+
+	// the service policy of the amd64 2.0.0 version requires a gpu
+	servicePolicies := map[string]*externalpolicy.ExternalPolicy{
+		"myorg/http://mycompany.com/netspeed_2.0.0_amd64": {Constraints: externalpolicy.ConstraintExpression{"gpu == true"}},
+	}
+
+	nodePolicies := map[string]*externalpolicy.ExternalPolicy{
+		"node1": {Properties: externalpolicy.PropertyList{
+			*(externalpolicy.Property_Factory("purpose", "network-testing")),
+			*(externalpolicy.Property_Factory("gpu", true)),
+		}},
+		"node2": {Properties: externalpolicy.PropertyList{
+			*(externalpolicy.Property_Factory("purpose", "network-testing")),
+		}},
+		"node3": {Properties: externalpolicy.PropertyList{
+			*(externalpolicy.Property_Factory("purpose", "network-testing")),
+			*(externalpolicy.Property_Factory(externalpolicy.PROP_NODE_ARCH, "armhf")),
+		}},
+		"node4": {Properties: externalpolicy.PropertyList{
+			*(externalpolicy.Property_Factory("purpose", "other")),
+		}},
+	}
+
+	output, err := CheckDeployment("bp1", bp, nodePolicies, services, servicePolicies, DefaultArchSynonyms(), "x86_64")
+	if err != nil {
+		t.Fatalf("Error: unable to check deployment: %v", err)
+	}
+
+	if len(output.MatchingNodes) != 3 || output.MatchingNodes[0] != "node1" || output.MatchingNodes[1] != "node2" || output.MatchingNodes[2] != "node3" {
+		t.Errorf("Error: wrong matching nodes %v", output.MatchingNodes)
+	} else if len(output.Nodes) != 4 {
+		t.Errorf("Error: expecting 4 nodes, got %v", output.Nodes)
+	}
+
+	// node1 satisfies the service policy of the highest priority version
+	if n := output.Nodes[0]; !n.Compatible || n.Version != "2.0.0" || n.Arch != "amd64" || len(n.Workloads) != 1 {
+		t.Errorf("Error: wrong result for node1 %v", n)
+	}
+
+	// node2 falls back to the 1.0.0 version, which also resolves its required service
+	if n := output.Nodes[1]; !n.Compatible || n.Version != "1.0.0" || n.ServiceId != "myorg/http://mycompany.com/netspeed_1.0.0_amd64" || len(n.Workloads) != 2 {
+		t.Errorf("Error: wrong result for node2 %v", n)
+	} else if n.Workloads[0].Compatible || n.Workloads[0].Reason == "" {
+		t.Errorf("Error: version 2.0.0 should not be compatible with node2 %v", n.Workloads[0])
+	}
+
+	// node3 is an arm node, version 2.0.0 is not published for arm
+	if n := output.Nodes[2]; !n.Compatible || n.Version != "1.0.0" || n.Arch != "arm" || n.Workloads[0].ServiceId != "" {
+		t.Errorf("Error: wrong result for node3 %v", n)
+	}
+
+	// node4 does not satisfy the business policy constraints
+	if n := output.Nodes[3]; n.Compatible || n.Version != "" || len(n.Workloads) != 2 {
+		t.Errorf("Error: wrong result for node4 %v", n)
+	}
+}
+
+func Test_GetLocalServiceHandler(t *testing.T) {
+
+	services := map[string]exchange.ServiceDefinition{
+		"myorg/svc_1.0.0_amd64": {URL: "svc", Version: "1.0.0", Arch: "amd64"},
+		"myorg/svc_1.5.0_amd64": {URL: "svc", Version: "1.5.0", Arch: "amd64"},
+		"myorg/svc_2.0.0_amd64": {URL: "svc", Version: "2.0.0", Arch: "amd64"},
+		"other/svc_3.0.0_amd64": {URL: "svc", Version: "3.0.0", Arch: "amd64"},
+	}
+	handler := GetLocalServiceHandler(services, DefaultArchSynonyms())
+
+	if sDef, sId, err := handler("svc", "myorg", "1.0.0", "x86_64"); err != nil || sDef == nil || sId != "myorg/svc_1.0.0_amd64" {
+		t.Errorf("Error: expecting version 1.0.0, got %v %v %v", sDef, sId, err)
+	} else if sDef, sId, err := handler("svc", "myorg", "[1.0.0,2.0.0)", "amd64"); err != nil || sDef == nil || sId != "myorg/svc_1.5.0_amd64" {
+		t.Errorf("Error: expecting version 1.5.0, got %v %v %v", sDef, sId, err)
+	} else if sDef, sId, err := handler("svc", "myorg", "", "amd64"); err != nil || sDef == nil || sId != "myorg/svc_2.0.0_amd64" {
+		t.Errorf("Error: expecting version 2.0.0, got %v %v %v", sDef, sId, err)
+	} else if sDef, _, err := handler("svc", "myorg", "1.2.0", "amd64"); err != nil || sDef != nil {
+		t.Errorf("Error: expecting no service, got %v %v", sDef, err)
+	} else if _, _, err := handler("svc", "myorg", "abc", "amd64"); err == nil {
+		t.Errorf("Error: expecting an error for an invalid version")
+	}
+}
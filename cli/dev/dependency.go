@@ -319,19 +319,20 @@ func ValidateService(directory string, fInfo os.FileInfo, userInputs *register.I
 
 func validateDependencyUserInputs(d cliexchange.AbstractServiceFile, uis []exchange.UserInput, configUserInputs []register.MicroWork, userInputsFilePath string) error {
 	for _, ui := range uis {
-		if ui.DefaultValue == "" {
-			found := false
-			for _, msUI := range configUserInputs {
-				if d.GetURL() == msUI.Url && (d.GetOrg() == "" || msUI.Org == "" || d.GetOrg() == msUI.Org) {
-					if _, ok := msUI.Variables[ui.Name]; ok {
-						found = true
-						break
+		found := false
+		for _, msUI := range configUserInputs {
+			if d.GetURL() == msUI.Url && (d.GetOrg() == "" || msUI.Org == "" || d.GetOrg() == msUI.Org) {
+				if varValue, ok := msUI.Variables[ui.Name]; ok {
+					if err := ui.Schema.Check(ui.Type, varValue); err != nil {
+						return errors.New(fmt.Sprintf("variable %v in %v does not meet the schema, %v.", ui.Name, userInputsFilePath, err))
 					}
+					found = true
+					break
 				}
 			}
-			if !found {
-				return errors.New(fmt.Sprintf("variable %v has no default and must be specified in %v", ui.Name, userInputsFilePath))
-			}
+		}
+		if !found && ui.DefaultValue == "" {
+			return errors.New(fmt.Sprintf("variable %v has no default and must be specified in %v", ui.Name, userInputsFilePath))
 		}
 	}
 	return nil
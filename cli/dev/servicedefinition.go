@@ -114,6 +114,8 @@ func ValidateServiceDefinition(directory string, fileName string) error {
 		for ix, ui := range sDef.UserInputs {
 			if (ui.Name != "" && ui.Type == "") || (ui.Name == "" && (ui.Type != "" || ui.DefaultValue != "")) {
 				return errors.New(fmt.Sprintf("%v: userInput array index %v does not have name and type specified.", filePath, ix))
+			} else if err := ui.Schema.Validate(ui.Type); err != nil {
+				return errors.New(fmt.Sprintf("%v: userInput array index %v has an invalid schema, %v.", filePath, ix, err))
 			}
 		}
 	}
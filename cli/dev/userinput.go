@@ -194,6 +194,10 @@ func ValidateUserInput(i *register.InputFile, directory string, originalUserInpu
 				if err := validateConfiguredVariables(ms.Variables, sDef.DefinesVariable); err != nil {
 					return errors.New(fmt.Sprintf("%v: services array element at index %v is %v %v", originalUserInputFilePath, ix, ms, err))
 				}
+				// For every variable that is set, make sure the value meets the schema in the service definition.
+				if err := validateVariableSchemas(ms.Variables, sDef.GetUserInputs()); err != nil {
+					return errors.New(fmt.Sprintf("%v: services array element at index %v %v", originalUserInputFilePath, ix, err))
+				}
 				// For every variable that is defined without a default, make sure it is set.
 				if err := sDef.RequiredVariablesAreSet(ms.Variables); err != nil {
 					return errors.New(fmt.Sprintf("%v: %v", originalUserInputFilePath, err))
@@ -245,6 +249,17 @@ func validateConfiguredVariables(variables map[string]interface{}, definesVar fu
 	return nil
 }
 
+func validateVariableSchemas(variables map[string]interface{}, uis []exchange.UserInput) error {
+	for _, ui := range uis {
+		if varValue, ok := variables[ui.Name]; ok {
+			if err := ui.Schema.Check(ui.Type, varValue); err != nil {
+				return errors.New(fmt.Sprintf("sets variable %v using a value that does not meet the schema, %v.", ui.Name, err))
+			}
+		}
+	}
+	return nil
+}
+
 func getConfiguredVariables(configEntries []register.MicroWork, url string) map[string]interface{} {
 	// Get the variables intended to configure this dependency from this project's userinput file.
 	var configVars map[string]interface{}
@@ -1,11 +1,15 @@
 package eventlog
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/persistence"
+	"io"
+	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -62,17 +66,19 @@ func getSelectionString(selections []string) (string, error) {
 	return strings.Join(sels, "&"), nil
 }
 
-func List(all bool, detail bool, selections []string) {
+func List(all bool, detail bool, selections []string, follow bool) {
 
 	// format the eventlog api string
 	url_s := "eventlog"
 	if all {
 		url_s = fmt.Sprintf("%v/all", url_s)
 	}
+	sel_s := ""
 	if len(selections) > 0 {
 		if s, err := getSelectionString(selections); err != nil {
 			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
 		} else {
+			sel_s = s
 			url_s = fmt.Sprintf("%v?%v", url_s, s)
 		}
 	}
@@ -81,6 +87,19 @@ func List(all bool, detail bool, selections []string) {
 	apiOutput := make([]persistence.EventLogRaw, 0)
 	cliutils.HorizonGet(url_s, []int{200}, &apiOutput, false)
 
+	// in follow mode, the event logs are printed one at a time and then new ones are printed as they are saved.
+	// The stream catches up from the last one listed, or from the start when none were listed, so that the event logs
+	// saved in between are not missed.
+	if follow {
+		last_id := "0"
+		for _, v := range apiOutput {
+			printEventLog(v, detail)
+			last_id = v.Id
+		}
+		followEventLogs(all, sel_s, last_id, detail)
+		return
+	}
+
 	//output
 	if detail {
 		long_output := make([]EventLog, len(apiOutput))
@@ -112,3 +131,149 @@ func List(all bool, detail bool, selections []string) {
 		fmt.Printf("%s\n", jsonBytes)
 	}
 }
+
+// Print a single event log, used in follow mode.
+func printEventLog(v persistence.EventLogRaw, detail bool) {
+	if detail {
+		output := EventLog{
+			Id:         v.Id,
+			Timestamp:  cliutils.ConvertTime(v.Timestamp),
+			Severity:   v.Severity,
+			Message:    v.Message,
+			EventCode:  v.EventCode,
+			SourceType: v.SourceType,
+			Source:     v.Source,
+		}
+		jsonBytes, err := json.MarshalIndent(output, "", cliutils.JSON_INDENT)
+		if err != nil {
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, "failed to marshal 'hzn eventlog list' output: %v", err)
+		}
+		fmt.Printf("%s\n", jsonBytes)
+	} else {
+		t := time.Unix(int64(v.Timestamp), 0)
+		fmt.Printf("%v:   %v\n", t.Format("2006-01-02 15:04:05"), v.Message)
+	}
+}
+
+// How long to wait before reconnecting to the event log stream.
+const FOLLOW_RECONNECT_DELAY = 2 * time.Second
+
+// Print the event logs as anax saves them, until the user interrupts the command. If the connection to anax is
+// lost, reconnect and ask for the event logs saved after the last one printed, from the current registration or from
+// all registrations.
+func followEventLogs(all bool, selections string, last_id string, detail bool) {
+
+	url := cliutils.GetHorizonUrlBase() + "/eventlog/stream"
+	if all {
+		url = cliutils.GetHorizonUrlBase() + "/eventlog/all/stream"
+	}
+	if selections != "" {
+		url = fmt.Sprintf("%v?%v", url, selections)
+	}
+
+	for {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			cliutils.Fatal(cliutils.HTTP_ERROR, "%s new request failed: %v", url, err)
+		}
+		req.Header.Set("Accept", "text/event-stream")
+		req.Header.Set("Last-Event-ID", last_id)
+
+		cliutils.Verbose(http.MethodGet + " " + url)
+		if resp, err := http.DefaultClient.Do(req); err != nil {
+			cliutils.Verbose("Unable to connect to the event log stream, error: %v", err)
+		} else if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			cliutils.Fatal(cliutils.HTTP_ERROR, "bad HTTP code from %s: %d", url, resp.StatusCode)
+		} else {
+			last_id = readEventLogStream(resp.Body, last_id, detail)
+			resp.Body.Close()
+			cliutils.Verbose("The event log stream ended, reconnecting.")
+		}
+
+		time.Sleep(FOLLOW_RECONNECT_DELAY)
+	}
+}
+
+// Read server sent events from the stream and print the event logs in them until the stream ends. Returns the
+// record id of the last event log printed.
+func readEventLogStream(body io.Reader, last_id string, detail bool) string {
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	data := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			// a blank line ends the event
+			if data != "" {
+				var el persistence.EventLogRaw
+				if err := json.Unmarshal([]byte(data), &el); err != nil {
+					cliutils.Verbose("Unable to demarshal event log %v, error: %v", data, err)
+				} else {
+					printEventLog(el, detail)
+					last_id = el.Id
+				}
+			}
+			data = ""
+		case strings.HasPrefix(line, "data:"):
+			data += strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+		}
+	}
+	return last_id
+}
+
+// Convert the time given to 'hzn eventlog purge' into seconds since the epoch. The time can be a date, a date and
+// time in local time, the number of seconds since the epoch, or an age in days or hours relative to now.
+func parsePurgeTime(before string, now time.Time) (int64, error) {
+	before = strings.TrimSpace(before)
+
+	if secs, err := strconv.ParseInt(before, 10, 64); err == nil {
+		return secs, nil
+	}
+
+	if len(before) > 1 {
+		if n, err := strconv.ParseInt(before[:len(before)-1], 10, 64); err == nil && n >= 0 {
+			switch before[len(before)-1] {
+			case 'd':
+				return now.Add(-time.Duration(n) * 24 * time.Hour).Unix(), nil
+			case 'h':
+				return now.Add(-time.Duration(n) * time.Hour).Unix(), nil
+			}
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, before, time.Local); err == nil {
+			return t.Unix(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("'%v' is not a valid time. Use 'yyyy-mm-dd', 'yyyy-mm-dd hh:mm:ss', the number of seconds since the epoch, or an age such as '7d' or '36h'.", before)
+}
+
+// Delete the event logs saved before the given time.
+func Purge(before string, force bool) {
+
+	timestamp, err := parsePurgeTime(before, time.Now())
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v", err)
+	}
+
+	if !force {
+		cliutils.ConfirmRemove(fmt.Sprintf("Are you sure you want to delete the event logs saved before %v?", time.Unix(timestamp, 0).Format("2006-01-02 15:04:05")))
+	}
+
+	_, resp := cliutils.HorizonPutPost(http.MethodDelete, fmt.Sprintf("eventlog?before=%v", timestamp), []int{200}, []byte{})
+	if cliutils.IsDryRun() {
+		return
+	}
+
+	var out map[string]int
+	if err := json.Unmarshal([]byte(resp), &out); err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, "failed to unmarshal the response %v: %v", resp, err)
+	}
+	fmt.Printf("Deleted %v event logs.\n", out["deleted"])
+}
@@ -34,7 +34,10 @@ const BUSINESS_POLICY_TEMPLATE_OBJECT = `{
     }
   ],
   "constraints": [        /* A list of constraint expressions of the form <property name> <operator> <property value>, */
-                          /* separated by boolean operators AND (&&) or OR (||). */
+                          /* separated by boolean operators AND (&&) or OR (||), grouped with */
+                          /* parentheses and negated with NOT (!). */
+                          /* A constraint can also be a JSON object such as {"name": "", "op": "==", "value": ""}, */
+                          /* combined with {"and": [...]}, {"or": [...]} or {"not": [...]}. */
     ""
   ],
   "userInput": [          /* A list of userInput variables to set when the service runs, listed by service. */
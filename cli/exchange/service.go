@@ -10,6 +10,7 @@ import (
 	"github.com/open-horizon/anax/containermessage"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/externalpolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/json_language"
 	_ "github.com/open-horizon/anax/externalpolicy/text_language"
 	"github.com/open-horizon/rsapss-tool/verify"
 	"net/http"
@@ -27,7 +28,10 @@ const SERVICE_POLICY_TEMPLATE_OBJECT = `{
     }
   ],
   "constraints": [  /* A list of constraint expressions of the form <property name> <operator> <property value>, */
-                    /* separated by boolean operators AND (&&) or OR (||). */
+                    /* separated by boolean operators AND (&&) or OR (||), grouped with */
+                    /* parentheses and negated with NOT (!). */
+                    /* A constraint can also be a JSON object such as {"name": "", "op": "==", "value": ""}, */
+                    /* combined with {"and": [...]}, {"or": [...]} or {"not": [...]}. */
     ""
   ]
 }`
@@ -235,6 +239,13 @@ func ServicePublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath strin
 	// Compensate for old service definition files
 	svcFile.SupportVersionRange()
 
+	// Verify that the user input schemas can be enforced
+	for _, ui := range svcFile.UserInputs {
+		if err := ui.Schema.Validate(ui.Type); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "the schema of user input %v in %s is invalid: %v", ui.Name, jsonFilePath, err)
+		}
+	}
+
 	svcFile.SignAndPublish(org, userPw, jsonFilePath, keyFilePath, pubKeyFilePath, dontTouchImage, pullImage, registryTokens, !overwrite)
 
 	// create service policy if servicePolicyFilePath is defined
@@ -4,6 +4,7 @@ package main
 import (
 	"flag"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/open-horizon/anax/cli/agreement"
@@ -11,10 +12,12 @@ import (
 	"github.com/open-horizon/anax/cli/attribute"
 	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cli/deploycheck"
 	"github.com/open-horizon/anax/cli/dev"
 	"github.com/open-horizon/anax/cli/eventlog"
 	"github.com/open-horizon/anax/cli/exchange"
 	_ "github.com/open-horizon/anax/cli/helm_deployment"
+	"github.com/open-horizon/anax/cli/image"
 	"github.com/open-horizon/anax/cli/key"
 	"github.com/open-horizon/anax/cli/metering"
 	_ "github.com/open-horizon/anax/cli/native_deployment"
@@ -320,6 +323,10 @@ Environment Variables:
 	policyPatchInput := policyPatchCmd.Arg("patch", "The new constraints or properties in the format '{\"constraints\":[<constraint list>]'} or '{\"properties\":[<property list>]}'").Required().String()
 	policyRemoveCmd := policyCmd.Command("remove", "Remove the node's policy.")
 	policyRemoveForce := policyRemoveCmd.Flag("force", "Skip the 'are you sure?' prompt.").Short('f').Bool()
+	policyExplainCmd := policyCmd.Command("explain", "Explain why a node policy is or is not compatible with a business policy or a service policy. Each constraint is evaluated against the properties of the other policy and the values, missing properties and type mismatches are displayed. The policies are read from local files.")
+	policyExplainNodePol := policyExplainCmd.Flag("node-pol", "The JSON input file name containing the node policy.").Required().String()
+	policyExplainBusinessPol := policyExplainCmd.Flag("business-pol", "The JSON input file name containing the business policy.").String()
+	policyExplainServicePol := policyExplainCmd.Flag("service-pol", "The JSON input file name containing the service policy. If a business policy is also specified, the service policy is merged into it.").String()
 
 	agreementCmd := app.Command("agreement", "List or manage the active or archived agreements this edge node has made with a Horizon agreement bot.")
 	agreementListCmd := agreementCmd.Command("list", "List the active or archived agreements this edge node has made with a Horizon agreement bot.")
@@ -349,6 +356,10 @@ Environment Variables:
 	serviceCmd := app.Command("service", "List or manage the services that are currently registered on this Horizon edge node.")
 	serviceListCmd := serviceCmd.Command("list", "List the services variable configuration that has been done on this Horizon edge node.")
 	serviceRegisteredCmd := serviceCmd.Command("registered", "List the services that are currently registered on this Horizon edge node.")
+	serviceLogCmd := serviceCmd.Command("log", "Show the container logs for a service running on this Horizon edge node.")
+	logServiceName := serviceLogCmd.Arg("service", "The URL of the service, optionally prefixed with its organization and a slash, e.g. myorg/https://bluehorizon.network/services/gps").Required().String()
+	logFollow := serviceLogCmd.Flag("follow", "Continue streaming the logs as they are written.").Short('f').Bool()
+	logTail := serviceLogCmd.Flag("tail", "Show only the given number of lines from the end of the logs. The default is all of them.").Default("-1").Int()
 	serviceConfigStateCmd := serviceCmd.Command("configstate", "List or manage the configuration state for the services that are currently registered on this Horizon edge node.")
 	serviceConfigStateListCmd := serviceConfigStateCmd.Command("list", "List the configuration state for the services that are currently registered on this Horizon edge node.")
 	serviceConfigStateSuspendCmd := serviceConfigStateCmd.Command("suspend", "Change the configuration state to 'suspend' for a service.")
@@ -370,11 +381,30 @@ Environment Variables:
 	statusCmd := app.Command("status", "Display the current horizon internal status for the node.")
 	statusLong := statusCmd.Flag("long", "Show detailed status").Short('l').Bool()
 
-	eventlogCmd := app.Command("eventlog", "List the event logs for the current or all registrations.")
+	eventlogCmd := app.Command("eventlog", "List or purge the event logs for the current or all registrations.")
 	eventlogListCmd := eventlogCmd.Command("list", "List the event logs for the current or all registrations.")
 	listAllEventlogs := eventlogListCmd.Flag("all", "List all the event logs including the previous registrations.").Short('a').Bool()
 	listDetailedEventlogs := eventlogListCmd.Flag("long", "List event logs with details.").Short('l').Bool()
+	followEventlogs := eventlogListCmd.Flag("follow", "Keep running and print the new event logs as they are saved, until interrupted with Ctrl-C.").Short('f').Bool()
 	listSelectedEventlogs := eventlogListCmd.Flag("select", "Selection string. This flag can be repeated which means 'AND'. Each flag should be in the format of attribute=value, attribute~value, \"attribute>value\" or \"attribute<value\", where '~' means contains. The common attribute names are timestamp, severity, message, event_code, source_type, agreement_id, service_url etc. Use the '-l' flag to see all the attribute names.").Short('s').Strings()
+	eventlogPurgeCmd := eventlogCmd.Command("purge", "Delete the event logs, from all registrations, saved before the given time.")
+	purgeEventlogsBefore := eventlogPurgeCmd.Flag("before", "Delete the event logs saved before this time. The time is in the format of 'yyyy-mm-dd', 'yyyy-mm-dd hh:mm:ss' (local time), the number of seconds since the epoch, or an age such as '7d' or '36h'.").Short('b').Required().String()
+	forcePurgeEventlogs := eventlogPurgeCmd.Flag("force", "Skip the 'are you sure?' prompt.").Short('f').Bool()
+
+	imageCmd := app.Command("image", "Manage the service container images on this Horizon edge node.")
+	imageGCCmd := imageCmd.Command("gc", "Remove the service images that are no longer used by any agreement, service or container, keeping the newest images of each repository as configured in the agent's ImageGC settings. Use the global --dry-run flag to only list the images that would be removed.")
+	forceImageGC := imageGCCmd.Flag("force", "Skip the 'are you sure?' prompt.").Short('f').Bool()
+	imageSignCmd := imageCmd.Command("sign", "Sign the digest of a service image, so that Horizon edge nodes that trust the public key can verify the image before pulling it. The signature is displayed; put it in the 'image_signature' field of the deployment, or store it in the signature directory of the nodes.")
+	imageSignImage := imageSignCmd.Arg("image", "The image to sign, pinned by digest, in the format 'repository@sha256:<digest>'.").Required().String()
+	imageSignPrivKeyFile := imageSignCmd.Flag("private-key-file", "The path of a private key file to be used to sign the image. If not specified, the environment variable HZN_PRIVATE_KEY_FILE will be used. If none of them are set, ~/.hzn/keys/service.private.key is the default.").Short('k').ExistingFile()
+	imageSignDir := imageSignCmd.Flag("signature-dir", "Also store the signature in this signature directory, in the layout of the agent's ImageVerification.SignatureDir setting.").Short('d').String()
+
+	deployCheckCmd := app.Command("deploycheck", "Check which nodes a business policy would deploy its service to, and which service version each node would get, before the business policy is published. The same policy compatibility, arch and version range checks that the agreement bot makes are run against local files, without contacting the Horizon exchange. The node user input is not checked.")
+	deployCheckBusinessPol := deployCheckCmd.Flag("business-pol", "The JSON input file name containing the business policy.").Short('b').Required().String()
+	deployCheckNodePols := deployCheckCmd.Flag("node-pol", "The JSON input file name containing a node policy. The file name, without the extension, is used as the node name. This flag can be repeated to check more than one node.").Short('n').Required().Strings()
+	deployCheckServices := deployCheckCmd.Flag("service", "The JSON input file name containing a service definition, in the format used by 'hzn exchange service publish'. Required services must also be specified. This flag can be repeated.").Short('s').Strings()
+	deployCheckServicePols := deployCheckCmd.Flag("service-pol", "The JSON input file name containing a service policy. If the flag is specified once, the service policy applies to all the service definitions. Otherwise it must be repeated once for each --service flag, in the same order.").Short('p').Strings()
+	deployCheckArch := deployCheckCmd.Flag("arch", "The architecture of the nodes whose node policy does not contain the openhorizon.arch property. If omitted, the architecture of this machine is used.").Short('a').String()
 
 	devCmd := app.Command("dev", "Development tools for creation of services.")
 	devHomeDirectory := devCmd.Flag("directory", "Directory containing Horizon project metadata. If omitted, a subdirectory called 'horizon' under current directory will be used.").Short('d').String()
@@ -455,9 +485,18 @@ Environment Variables:
 	mmsObjectPublishPat := mmsObjectPublishCmd.Flag("pattern", "If you want the object to be deployed on nodes using a given pattern, specify it using this flag. This flag is optionla and can only be used with --type and --id. It is mutually exclusive with -m").Short('p').String()
 	mmsObjectPublishDef := mmsObjectPublishCmd.Flag("def", "The definition of the object to publish. A blank template can be obtained from the 'hzn mss object new' command.").Short('m').String()
 	mmsObjectPublishObj := mmsObjectPublishCmd.Flag("object", "The object (in the form of a file) to publish. This flag is optional so that you can update only the object's definition.").Short('f').String()
+	mmsObjectPublishHistory := mmsObjectPublishCmd.Flag("history", "The number of earlier versions of the object to keep in its history. The version being replaced is added to the history and the oldest versions are removed. Specify 0 to leave the history unchanged.").Default(strconv.Itoa(sync_service.MMS_HISTORY_DEFAULT)).Int()
 	mmsObjectDeleteCmd := mmsObjectCmd.Command("delete", "Publish an object in the Horizon Model Management Service, making it available for services deployed on nodes.")
 	mmsObjectDeleteType := mmsObjectDeleteCmd.Flag("type", "The type of the object to delete.").Short('t').Required().String()
 	mmsObjectDeleteId := mmsObjectDeleteCmd.Flag("id", "The id of the object to delete.").Short('i').Required().String()
+	mmsObjectHistoryCmd := mmsObjectCmd.Command("history", "List the earlier versions of an object in the Horizon Model Management Service, newest first.")
+	mmsObjectHistoryType := mmsObjectHistoryCmd.Flag("type", "The type of the object.").Short('t').Required().String()
+	mmsObjectHistoryId := mmsObjectHistoryCmd.Flag("id", "The id of the object.").Short('i').Required().String()
+	mmsObjectRollbackCmd := mmsObjectCmd.Command("rollback", "Publish an earlier version of an object in the Horizon Model Management Service again. The object keeps its current destinations.")
+	mmsObjectRollbackType := mmsObjectRollbackCmd.Flag("type", "The type of the object.").Short('t').Required().String()
+	mmsObjectRollbackId := mmsObjectRollbackCmd.Flag("id", "The id of the object.").Short('i').Required().String()
+	mmsObjectRollbackRevision := mmsObjectRollbackCmd.Flag("revision", "The revision to publish again, as listed by 'hzn mms object history'.").Short('r').Required().Int()
+	mmsObjectRollbackHistory := mmsObjectRollbackCmd.Flag("history", "The number of earlier versions of the object to keep in its history. The version being replaced is added to the history so that the rollback can be undone. Specify 0 to leave the history unchanged.").Default(strconv.Itoa(sync_service.MMS_HISTORY_DEFAULT)).Int()
 
 	app.Version("Run 'hzn version' to see the Horizon version.")
 	/* trying to override the base --version behavior does not work....
@@ -566,6 +605,8 @@ Environment Variables:
 		exSvcPubKeyFile = cliutils.WithDefaultEnvVar(exSvcPubKeyFile, "HZN_PUBLIC_KEY_FILE")
 	case "key import":
 		keyImportPubKeyFile = cliutils.WithDefaultEnvVar(keyImportPubKeyFile, "HZN_PUBLIC_KEY_FILE")
+	case "image sign":
+		imageSignPrivKeyFile = cliutils.WithDefaultEnvVar(imageSignPrivKeyFile, "HZN_PRIVATE_KEY_FILE")
 	}
 
 	// set env variable ARCH if it is not set
@@ -699,6 +740,8 @@ Environment Variables:
 		policy.Patch(*policyPatchInput)
 	case policyRemoveCmd.FullCommand():
 		policy.Remove(*policyRemoveForce)
+	case policyExplainCmd.FullCommand():
+		policy.Explain(*policyExplainNodePol, *policyExplainBusinessPol, *policyExplainServicePol)
 	case agreementListCmd.FullCommand():
 		agreement.List(*listArchivedAgreements, *listAgreementId)
 	case agreementCancelCmd.FullCommand():
@@ -721,6 +764,8 @@ Environment Variables:
 		service.List()
 	case serviceRegisteredCmd.FullCommand():
 		service.Registered()
+	case serviceLogCmd.FullCommand():
+		service.Log(*logServiceName, *logFollow, *logTail)
 	case serviceConfigStateListCmd.FullCommand():
 		service.ListConfigState()
 	case serviceConfigStateSuspendCmd.FullCommand():
@@ -732,7 +777,15 @@ Environment Variables:
 	case statusCmd.FullCommand():
 		status.DisplayStatus(*statusLong, false)
 	case eventlogListCmd.FullCommand():
-		eventlog.List(*listAllEventlogs, *listDetailedEventlogs, *listSelectedEventlogs)
+		eventlog.List(*listAllEventlogs, *listDetailedEventlogs, *listSelectedEventlogs, *followEventlogs)
+	case eventlogPurgeCmd.FullCommand():
+		eventlog.Purge(*purgeEventlogsBefore, *forcePurgeEventlogs)
+	case imageGCCmd.FullCommand():
+		image.GC(*forceImageGC)
+	case imageSignCmd.FullCommand():
+		image.Sign(*imageSignImage, *imageSignPrivKeyFile, *imageSignDir)
+	case deployCheckCmd.FullCommand():
+		deploycheck.DeployCheck(*deployCheckBusinessPol, *deployCheckNodePols, *deployCheckServices, *deployCheckServicePols, *deployCheckArch)
 	case devServiceNewCmd.FullCommand():
 		dev.ServiceNew(*devHomeDirectory, *devServiceNewCmdOrg, *devServiceNewCmdName, *devServiceNewCmdVer, *devServiceNewCmdImage, *devServiceNewCmdNoImageGen, *devServiceNewCmdCfg, *devServiceNewCmdNoPattern, *devServiceNewCmdNoPolicy)
 	case devServiceStartTestCmd.FullCommand():
@@ -770,8 +823,12 @@ Environment Variables:
 	case mmsObjectNewCmd.FullCommand():
 		sync_service.ObjectNew(*mmsOrg)
 	case mmsObjectPublishCmd.FullCommand():
-		sync_service.ObjectPublish(*mmsOrg, *mmsUserPw, *mmsObjectPublishType, *mmsObjectPublishId, *mmsObjectPublishPat, *mmsObjectPublishDef, *mmsObjectPublishObj)
+		sync_service.ObjectPublish(*mmsOrg, *mmsUserPw, *mmsObjectPublishType, *mmsObjectPublishId, *mmsObjectPublishPat, *mmsObjectPublishDef, *mmsObjectPublishObj, *mmsObjectPublishHistory)
 	case mmsObjectDeleteCmd.FullCommand():
 		sync_service.ObjectDelete(*mmsOrg, *mmsUserPw, *mmsObjectDeleteType, *mmsObjectDeleteId)
+	case mmsObjectHistoryCmd.FullCommand():
+		sync_service.ObjectHistory(*mmsOrg, *mmsUserPw, *mmsObjectHistoryType, *mmsObjectHistoryId)
+	case mmsObjectRollbackCmd.FullCommand():
+		sync_service.ObjectRollback(*mmsOrg, *mmsUserPw, *mmsObjectRollbackType, *mmsObjectRollbackId, *mmsObjectRollbackRevision, *mmsObjectRollbackHistory)
 	}
 }
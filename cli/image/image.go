@@ -0,0 +1,100 @@
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/imagefetch"
+	"github.com/open-horizon/rsapss-tool/sign"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Remove the service images that are no longer used. With the global --dry-run flag, only list the images that
+// would be removed.
+func GC(force bool) {
+
+	var report imagefetch.ImageGCReport
+	if cliutils.IsDryRun() {
+		cliutils.HorizonGet("image/gc", []int{200}, &report, false)
+	} else {
+		if !force {
+			cliutils.ConfirmRemove("Are you sure you want to remove the service images that are no longer used on this node?")
+		}
+
+		_, resp := cliutils.HorizonPutPost(http.MethodPost, "image/gc", []int{200}, []byte{})
+		if err := json.Unmarshal([]byte(resp), &report); err != nil {
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, "failed to unmarshal the response %v: %v", resp, err)
+		}
+	}
+
+	verb := "Removed"
+	if report.DryRun {
+		verb = "Would remove"
+	}
+
+	if len(report.Images) == 0 {
+		fmt.Printf("No unused images to remove. The disk usage is %v%%.\n", report.DiskUsagePercent)
+		return
+	}
+	for _, image := range report.Images {
+		fmt.Printf("%v %v %v (%.1f MB)\n", verb, shortId(image.ID), image.RepoTags, float64(image.Size)/(1024*1024))
+	}
+	fmt.Printf("%v %v images, %.1f MB. The disk usage is %v%%.\n", verb, len(report.Images), float64(report.ReclaimedBytes)/(1024*1024), report.DiskUsagePercent)
+	if report.DryRun && report.HighWaterMarkPercent != 0 {
+		fmt.Printf("The images are removed oldest first, until the disk usage is below the high-water mark of %v%%.\n", report.HighWaterMarkPercent)
+	}
+}
+
+// Sign the digest of an image and display the signature. With a signature directory, the signature is also stored
+// there as the next signature of the image.
+func Sign(image string, privKeyFilePath string, signatureDir string) {
+
+	privKeyFilePath = cliutils.VerifySigningKeyInput(privKeyFilePath, false)
+
+	payload, err := containermessage.ImageSignaturePayload(image)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "%v. Use the image digest, for example with 'docker inspect --format \"{{index .RepoDigests 0}}\" %v'.", err, image)
+	}
+
+	signature, err := sign.Input(privKeyFilePath, payload)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, "problem signing image %v with %s: %v", image, privKeyFilePath, err)
+	}
+	fmt.Println(signature)
+
+	if signatureDir == "" {
+		return
+	}
+
+	repo, digest, _ := containermessage.ImageDigestReference(image)
+	dir := imagefetch.ImageSignatureDir(signatureDir, repo, digest)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		cliutils.Fatal(cliutils.FILE_IO_ERROR, "unable to create signature directory %v: %v", dir, err)
+	}
+
+	// the signatures are numbered from 1, the new one follows the last stored signature
+	for ix := 1; ; ix++ {
+		file := filepath.Join(dir, fmt.Sprintf("signature-%v", ix))
+		if _, err := os.Stat(file); err == nil {
+			continue
+		}
+		if err := ioutil.WriteFile(file, []byte(signature), 0644); err != nil {
+			cliutils.Fatal(cliutils.FILE_IO_ERROR, "unable to write signature file %v: %v", file, err)
+		}
+		cliutils.Verbose("Stored the signature in %v", file)
+		break
+	}
+}
+
+func shortId(id string) string {
+	id = strings.TrimPrefix(id, "sha256:")
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
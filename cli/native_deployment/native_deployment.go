@@ -156,7 +156,7 @@ func (p *NativeDeploymentConfigPlugin) Validate(dep interface{}) (bool, error) {
 }
 
 // This can't be a const because a map literal isn't a const in go
-var VALID_DEPLOYMENT_FIELDS = map[string]int8{"image": 1, "privileged": 1, "cap_add": 1, "environment": 1, "devices": 1, "binds": 1, "specific_ports": 1, "command": 1, "ports": 1, "ephemeral_ports": 1, "tmpfs": 1}
+var VALID_DEPLOYMENT_FIELDS = map[string]int8{"image": 1, "privileged": 1, "cap_add": 1, "environment": 1, "devices": 1, "binds": 1, "specific_ports": 1, "command": 1, "ports": 1, "ephemeral_ports": 1, "tmpfs": 1, "resources": 1, "health_check": 1, "restart_policy": 1, "egress": 1, "image_signature": 1}
 
 // CheckDeploymentService verifies it has the required 'image' key, and checks for keys we don't recognize.
 // For now it only prints a warning for unrecognized keys, in case we recently added a key to anax and haven't updated hzn yet.
@@ -165,6 +165,34 @@ func CheckDeploymentService(svcName string, depSvc map[string]interface{}) error
 		return errors.New(fmt.Sprintf("service '%s' defined under 'deployment.services' does not have mandatory 'image' field", svcName))
 	}
 
+	// Check that the resource limits, health check, restart policy and egress policy can be applied.
+	fields := map[string]interface{ Validate() error }{
+		"resources":      new(containermessage.ResourceLimits),
+		"health_check":   new(containermessage.HealthCheck),
+		"restart_policy": new(containermessage.RestartPolicy),
+		"egress":         new(containermessage.EgressPolicy),
+	}
+	for field, value := range fields {
+		if r, ok := depSvc[field]; ok {
+			if jb, err := json.Marshal(r); err != nil {
+				return errors.New(fmt.Sprintf("service '%s' defined under 'deployment.services' has invalid '%s' field: %v", svcName, field, err))
+			} else if err := json.Unmarshal(jb, value); err != nil {
+				return errors.New(fmt.Sprintf("service '%s' defined under 'deployment.services' has invalid '%s' field: %v", svcName, field, err))
+			} else if err := value.Validate(); err != nil {
+				return errors.New(fmt.Sprintf("service '%s' defined under 'deployment.services' has invalid '%s' field: %v", svcName, field, err))
+			}
+		}
+	}
+
+	// An image signature is made for the image digest, so the image must be pinned by digest.
+	if _, ok := depSvc["image_signature"]; ok {
+		if image, ok := depSvc["image"].(string); !ok {
+			return errors.New(fmt.Sprintf("service '%s' defined under 'deployment.services' has an 'image' field that is not a string", svcName))
+		} else if _, _, err := containermessage.ImageDigestReference(image); err != nil {
+			return errors.New(fmt.Sprintf("service '%s' defined under 'deployment.services' has an 'image_signature' field, but %v", svcName, err))
+		}
+	}
+
 	// Check the rest of the keys for unrecognized ones
 	for k := range depSvc {
 		if _, ok := VALID_DEPLOYMENT_FIELDS[k]; !ok {
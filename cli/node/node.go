@@ -15,6 +15,19 @@ type Configstate struct {
 	LastUpdateTime string  `json:"last_update_time"` // removed omitempty
 }
 
+// An upgrade that the node is holding until its next maintenance window, with the times converted
+type PendingUpgrade struct {
+	Type           string `json:"type"`
+	ServiceURL     string `json:"service_url"`
+	ServiceOrg     string `json:"service_org"`
+	CurrentVersion string `json:"current_version"`
+	NewVersion     string `json:"new_version,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+	Windows        string `json:"maintenance_windows"`
+	ReceivedTime   string `json:"received_time"`
+	NextWindowTime string `json:"next_window_time"`
+}
+
 // This is a combo of anax's HorizonDevice and Info (status) structs
 type NodeAndStatus struct {
 	// from api.HorizonDevice
@@ -23,10 +36,11 @@ type NodeAndStatus struct {
 	Pattern *string `json:"pattern"` // a simple name, not prefixed with the org
 	Name    *string `json:"name"`    // removed omitempty
 	//Token              *string     `json:"token"`                 // removed omitempty
-	TokenLastValidTime string      `json:"token_last_valid_time"` // removed omitempty
-	TokenValid         *bool       `json:"token_valid"`           // removed omitempty
-	HA                 *bool       `json:"ha"`                    // removed omitempty
-	Config             Configstate `json:"configstate"`           // removed omitempty
+	TokenLastValidTime string           `json:"token_last_valid_time"` // removed omitempty
+	TokenValid         *bool            `json:"token_valid"`           // removed omitempty
+	HA                 *bool            `json:"ha"`                    // removed omitempty
+	Config             Configstate      `json:"configstate"`           // removed omitempty
+	PendingUpgrades    []PendingUpgrade `json:"pending_upgrades,omitempty"`
 	// from apicommon.Info
 	Configuration *apicommon.Configuration `json:"configuration"`
 	Connectivity  map[string]bool          `json:"connectivity"`
@@ -49,6 +63,21 @@ func (n *NodeAndStatus) CopyNodeInto(horDevice *api.HorizonDevice) {
 	if horDevice.Config.LastUpdateTime != nil {
 		n.Config.LastUpdateTime = cliutils.ConvertTime(*horDevice.Config.LastUpdateTime)
 	}
+	if horDevice.PendingUpgrades != nil {
+		for _, pu := range *horDevice.PendingUpgrades {
+			n.PendingUpgrades = append(n.PendingUpgrades, PendingUpgrade{
+				Type:           pu.Type,
+				ServiceURL:     pu.ServiceURL,
+				ServiceOrg:     pu.ServiceOrg,
+				CurrentVersion: pu.CurrentVersion,
+				NewVersion:     pu.NewVersion,
+				Reason:         pu.ReasonDesc,
+				Windows:        pu.Windows,
+				ReceivedTime:   cliutils.ConvertTime(pu.ReceivedTime),
+				NextWindowTime: cliutils.ConvertTime(pu.NextWindowTime),
+			})
+		}
+	}
 }
 
 // CopyStatusInto copies the status info into our output struct
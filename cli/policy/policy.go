@@ -3,9 +3,13 @@ package policy
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/open-horizon/anax/businesspolicy"
 	"github.com/open-horizon/anax/cli/cliconfig"
 	"github.com/open-horizon/anax/cli/cliutils"
 	"github.com/open-horizon/anax/externalpolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/json_language"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"github.com/open-horizon/anax/policy"
 	"net/http"
 )
 
@@ -17,7 +21,10 @@ const POLICY_TEMPLATE_OBJECT = `{
     }
   ],
   "constraints": [  /* A list of constraint expressions of the form <property name> <operator> <property value>, */
-                    /* separated by boolean operators AND (&&) or OR (||). */
+                    /* separated by boolean operators AND (&&) or OR (||), grouped with */
+                    /* parentheses and negated with NOT (!). */
+                    /* A constraint can also be a JSON object such as {"name": "", "op": "==", "value": ""}, */
+                    /* combined with {"and": [...]}, {"or": [...]} or {"not": [...]}. */
     ""
   ]
 }`
@@ -75,3 +82,65 @@ func Remove(force bool) {
 func New() {
 	fmt.Println(POLICY_TEMPLATE_OBJECT)
 }
+
+// Explain why a node policy is or is not compatible with a business policy and/or a service policy. The policies
+// are read from files, so nothing needs to be published and the agent does not need to be running.
+func Explain(nodePolFile string, businessPolFile string, servicePolFile string) {
+
+	if businessPolFile == "" && servicePolFile == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "either --business-pol or --service-pol must be specified.")
+	}
+
+	// the node policy is the producer side of the compatibility check
+	nodePol := new(externalpolicy.ExternalPolicy)
+	readInputFile(nodePolFile, nodePol)
+	producerPol, err := policy.GenPolicyFromExternalPolicy(nodePol, policy.MakeExternalPolicyHeaderName(nodePolFile))
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "invalid node policy %v: %v", nodePolFile, err)
+	}
+
+	// the business policy and service policy make up the consumer side
+	consumerPol := policy.Policy_Factory("")
+	if businessPolFile != "" {
+		var bp businesspolicy.BusinessPolicy
+		newBytes := cliconfig.ReadJsonFileWithLocalConfig(businessPolFile)
+		if err := json.Unmarshal(newBytes, &bp); err != nil {
+			cliutils.Fatal(cliutils.JSON_PARSING_ERROR, "failed to unmarshal json input file %s: %v", businessPolFile, err)
+		}
+		if consumerPol, err = bp.GenPolicyFromBusinessPolicy(businessPolFile); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "invalid business policy %v: %v", businessPolFile, err)
+		}
+
+		// add the built-in properties of the highest priority service version, the agbot does the same for each version it tries
+		svc := bp.Service
+		builtInSvcPol := externalpolicy.CreateServiceBuiltInPolicy(svc.Name, svc.Org, svc.ServiceVersions[0].Version, svc.Arch)
+		if consumerPol, err = policy.MergePolicyWithExternalPolicy(consumerPol, builtInSvcPol); err != nil {
+			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, "failed to merge the built-in service properties into the business policy: %v", err)
+		}
+	} else {
+		consumerPol.Header.Name = policy.MakeExternalPolicyHeaderName(servicePolFile)
+	}
+
+	if servicePolFile != "" {
+		servicePol := new(externalpolicy.ExternalPolicy)
+		readInputFile(servicePolFile, servicePol)
+		if err := servicePol.Validate(); err != nil {
+			cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "invalid service policy %v: %v", servicePolFile, err)
+		}
+		if consumerPol, err = policy.MergePolicyWithExternalPolicy(consumerPol, servicePol); err != nil {
+			cliutils.Fatal(cliutils.CLI_GENERAL_ERROR, "failed to merge the service policy: %v", err)
+		}
+	}
+
+	report, err := policy.Explain_Compatibility(producerPol, consumerPol)
+	if err != nil {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "failed to check policy compatibility: %v", err)
+	}
+
+	output, err := cliutils.DisplayAsJson(report)
+	if err != nil {
+		cliutils.Fatal(cliutils.JSON_PARSING_ERROR, "failed to marshal 'hzn policy explain' output: %v", err)
+	}
+
+	fmt.Println(output)
+}
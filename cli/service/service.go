@@ -6,10 +6,17 @@ import (
 	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/apicommon"
 	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/exchange"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 )
 
 type APIServices struct {
@@ -170,3 +177,44 @@ func Resume(applyAll bool, serviceOrg string, serviceUrl string) {
 
 	fmt.Println("Service resuming request sucessfully sent, please use 'hzn agreement' and 'docker ps' to make sure the related agreements and service containers are started. It may take a couple of minutes.")
 }
+
+// Print the stdout and stderr of the containers of a service running on this node. With follow, keep printing the
+// new log lines until the user interrupts the command or the containers stop. A negative tail shows all the lines.
+func Log(serviceName string, follow bool, tail int) {
+
+	// The service URL usually has a scheme, so only split off an organization that is not the scheme.
+	org, serviceUrl := "", serviceName
+	if i := strings.Index(serviceName, "/"); i > 0 && !strings.HasSuffix(serviceName[:i], ":") {
+		org, serviceUrl = cutil.SplitOrgSpecUrl(serviceName)
+	}
+
+	params := url.Values{}
+	params.Set("url", serviceUrl)
+	if org != "" {
+		params.Set("org", org)
+	}
+	if tail >= 0 {
+		params.Set("tail", strconv.Itoa(tail))
+	}
+	if follow {
+		params.Set("follow", "true")
+	}
+
+	apiUrl := cliutils.GetHorizonUrlBase() + "/service/log?" + params.Encode()
+	cliutils.Verbose(http.MethodGet + " " + apiUrl)
+
+	resp, err := http.Get(apiUrl)
+	if err != nil {
+		cliutils.Fatal(cliutils.HTTP_ERROR, "Can't connect to the Horizon REST API to run %s. Run 'systemctl status horizon' to check if the Horizon agent is running. Specific error is: %v", apiUrl, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		cliutils.Fatal(cliutils.HTTP_ERROR, "bad HTTP code %d from %s: %s", resp.StatusCode, apiUrl, string(body))
+	}
+
+	if _, err := io.Copy(os.Stdout, resp.Body); err != nil {
+		cliutils.Fatal(cliutils.HTTP_ERROR, "failed to read the logs of service %v: %v", serviceName, err)
+	}
+}
@@ -11,6 +11,12 @@ import (
 	"path"
 )
 
+// The body of a PUT of an object's metadata.
+type objectWrapper struct {
+	Meta common.MetaData `json:"meta"`
+	Data []byte          `json:"data"`
+}
+
 type MMSObjectInfo struct {
 	Definition   common.MetaData             `json:"definition"`
 	Destinations []common.DestinationsStatus `json:"destinations,omitempty"`
@@ -123,7 +129,7 @@ const HZN_OBJECT_METADATA = `{
                       /* Type can be omitted if the type is discernable from the value, e.g. unquoted true is boolean. */
       }
     ],
-    "constraints": [  /* A list of constraint expressions of the form <property name> <operator> <property value>, separated by boolean operators AND (&&) or OR (||). */
+    "constraints": [  /* A list of constraint expressions of the form <property name> <operator> <property value>, separated by boolean operators AND (&&) or OR (||), grouped with parentheses and negated with NOT (!). */
       ""
     ],
     "services": [     /* The service(s) that will use this object. */
@@ -150,7 +156,7 @@ func ObjectNew(org string) {
 
 // Upload an object to the MMS. The user can provide a copy of the object's metadata in a file, or they can simply provide
 // object id and type.
-func ObjectPublish(org string, userPw string, objType string, objId string, objPattern string, objMetadataFile string, objFile string) {
+func ObjectPublish(org string, userPw string, objType string, objId string, objPattern string, objMetadataFile string, objFile string, keepHistory int) {
 
 	// Validate the inputs because the combination of inputs that are required is complex.
 	if userPw == "" {
@@ -167,6 +173,8 @@ func ObjectPublish(org string, userPw string, objType string, objId string, objP
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "must specify either --type and --id or --def")
 	} else if objPattern != "" && objMetadataFile != "" {
 		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "cannot specify --pattern with --def")
+	} else if keepHistory < 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "--history cannot be negative")
 	}
 
 	// If we were given a full metadata file, read it in and use it to create the object. Otherwise, construct a minimal
@@ -193,12 +201,12 @@ func ObjectPublish(org string, userPw string, objType string, objId string, objP
 		objectMeta.MetaOnly = true
 	}
 
-	type ObjectWrapper struct {
-		Meta common.MetaData `json:"meta"`
-		Data []byte          `json:"data"`
+	// Keep the object that is being replaced in the object's history.
+	if keepHistory > 0 {
+		archiveObject(org, userPw, objectMeta.ObjectType, objectMeta.ObjectID, keepHistory)
 	}
 
-	wrapper := ObjectWrapper{Meta: objectMeta}
+	wrapper := objectWrapper{Meta: objectMeta}
 
 	// Call the MMS service over HTTP to add the object's metadata to the MMS.
 	urlPath := path.Join("api/v1/objects/", org, objectMeta.ObjectType, objectMeta.ObjectID)
@@ -251,6 +259,9 @@ func ObjectDelete(org string, userPw string, objType string, objId string) {
 		cliutils.Fatal(cliutils.NOT_FOUND, "object '%s' of type '%s' not found in org %s", objId, objType, org)
 	}
 
+	// The earlier versions of the object are deleted with it.
+	deleteHistory(org, userPw, objType, objId)
+
 	fmt.Println("Object " + objId + " deleted from org " + org + " in the Model Management Service")
 
 }
@@ -0,0 +1,222 @@
+package sync_service
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/cli/cliutils"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/edge-sync-service/common"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The Model Management Service only keeps the latest version of an object. The earlier versions are kept as objects
+// of their own, in a type named after the object's type and with an id made of the object's id and a revision number.
+// They are never sent to a node.
+const MMS_HISTORY_TYPE_SUFFIX = ".hzn-history"
+const MMS_HISTORY_ID_SEPARATOR = "@"
+
+// The default number of earlier versions of an object kept in its history.
+const MMS_HISTORY_DEFAULT = 5
+
+// An earlier version of an object, as shown by 'hzn mms object history'.
+type MMSObjectRevision struct {
+	Revision    int       `json:"revision"`
+	Version     string    `json:"version"`
+	Description string    `json:"description"`
+	ObjectSize  int64     `json:"objectSize"`
+	Replaced    time.Time `json:"replaced"` // when this version was replaced by a newer one
+}
+
+func historyType(objType string) string {
+	return objType + MMS_HISTORY_TYPE_SUFFIX
+}
+
+func historyId(objId string, revision int) string {
+	return fmt.Sprintf("%v%v%v", objId, MMS_HISTORY_ID_SEPARATOR, revision)
+}
+
+// Returns the revision of a history object of the given object, or false if the history object belongs to another object.
+func historyRevision(objId string, histId string) (int, bool) {
+	prefix := objId + MMS_HISTORY_ID_SEPARATOR
+	if !strings.HasPrefix(histId, prefix) {
+		return 0, false
+	}
+	suffix := strings.TrimPrefix(histId, prefix)
+	if revision, err := strconv.Atoi(suffix); err != nil || revision <= 0 || strconv.Itoa(revision) != suffix {
+		return 0, false
+	} else {
+		return revision, true
+	}
+}
+
+// Returns the revisions in the history of an object, oldest first.
+func historyRevisions(org string, userPw string, objType string, objId string) []int {
+	objectList := new(exchange.ObjectDestinationPolicies)
+	urlPath := path.Join("api/v1/objects/", org, historyType(objType), "?all_objects=true")
+	cliutils.ExchangeGet("Model Management Service", cliutils.GetMMSUrl(), urlPath, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, objectList)
+
+	revisions := make([]int, 0)
+	for _, obj := range *objectList {
+		if revision, ok := historyRevision(objId, obj.ObjectID); ok {
+			revisions = append(revisions, revision)
+		}
+	}
+	sort.Ints(revisions)
+	return revisions
+}
+
+// Get the metadata and data of an object. Returns false if the object does not exist.
+func getObject(org string, userPw string, objType string, objId string) (*common.MetaData, []byte, bool) {
+	objectMeta := new(common.MetaData)
+	urlPath := path.Join("api/v1/objects/", org, objType, objId)
+	if httpCode := cliutils.ExchangeGet("Model Management Service", cliutils.GetMMSUrl(), urlPath, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, objectMeta); httpCode == 404 {
+		return nil, nil, false
+	}
+
+	var data []byte
+	if !objectMeta.NoData {
+		urlPath = path.Join("api/v1/objects/", org, objType, objId, "data")
+		cliutils.ExchangeGet("Model Management Service", cliutils.GetMMSUrl(), urlPath, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &data)
+	}
+	return objectMeta, data, true
+}
+
+// Put the metadata and data of an object.
+func putObject(org string, userPw string, objectMeta common.MetaData, data []byte) {
+
+	// The ids are assigned by the MMS.
+	objectMeta.InstanceID = 0
+	objectMeta.DataID = 0
+	objectMeta.MetaOnly = objectMeta.NoData
+
+	urlPath := path.Join("api/v1/objects/", org, objectMeta.ObjectType, objectMeta.ObjectID)
+	cliutils.ExchangePutPost("Model Management Service", http.MethodPut, cliutils.GetMMSUrl(), urlPath, cliutils.OrgAndCreds(org, userPw), []int{204}, objectWrapper{Meta: objectMeta})
+
+	if !objectMeta.NoData {
+		urlPath = path.Join("api/v1/objects/", org, objectMeta.ObjectType, objectMeta.ObjectID, "data")
+		cliutils.ExchangePutPost("Model Management Service", http.MethodPut, cliutils.GetMMSUrl(), urlPath, cliutils.OrgAndCreds(org, userPw), []int{204}, data)
+	}
+}
+
+// Copy the current version of an object into its history, and remove the oldest versions so that no more than
+// keepHistory versions are kept. Nothing is done when the object does not exist yet.
+func archiveObject(org string, userPw string, objType string, objId string, keepHistory int) {
+
+	current, data, ok := getObject(org, userPw, objType, objId)
+	if !ok {
+		return
+	}
+
+	revisions := historyRevisions(org, userPw, objType, objId)
+	next := 1
+	if len(revisions) != 0 {
+		next = revisions[len(revisions)-1] + 1
+	}
+
+	// The history object is never sent to a node and does not expire with the object.
+	histMeta := *current
+	histMeta.ObjectType = historyType(objType)
+	histMeta.ObjectID = historyId(objId, next)
+	histMeta.DoNotSend = true
+	histMeta.Expiration = ""
+	histMeta.ActivationTime = ""
+	putObject(org, userPw, histMeta, data)
+
+	cliutils.Verbose("Object %v of type %v version %v kept as revision %v", objId, objType, current.Version, next)
+
+	revisions = append(revisions, next)
+	for len(revisions) > keepHistory {
+		urlPath := path.Join("api/v1/objects/", org, historyType(objType), historyId(objId, revisions[0]))
+		cliutils.ExchangeDelete("Model Management Service", cliutils.GetMMSUrl(), urlPath, cliutils.OrgAndCreds(org, userPw), []int{204, 404})
+		cliutils.Verbose("Removed revision %v of object %v of type %v from the history", revisions[0], objId, objType)
+		revisions = revisions[1:]
+	}
+}
+
+// Display the earlier versions of an object, newest first.
+func ObjectHistory(org string, userPw string, objType string, objId string) {
+
+	if userPw == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "must specify exchange credentials to access the model management service")
+	}
+
+	// Set the API key env var if that's what we're using.
+	cliutils.SetWhetherUsingApiKey(userPw)
+
+	revisions := historyRevisions(org, userPw, objType, objId)
+	history := make([]MMSObjectRevision, 0, len(revisions))
+	for i := len(revisions) - 1; i >= 0; i-- {
+		var histMeta common.MetaData
+		urlPath := path.Join("api/v1/objects/", org, historyType(objType), historyId(objId, revisions[i]))
+		if httpCode := cliutils.ExchangeGet("Model Management Service", cliutils.GetMMSUrl(), urlPath, cliutils.OrgAndCreds(org, userPw), []int{200, 404}, &histMeta); httpCode == 404 {
+			continue
+		}
+		history = append(history, MMSObjectRevision{
+			Revision:    revisions[i],
+			Version:     histMeta.Version,
+			Description: histMeta.Description,
+			ObjectSize:  histMeta.ObjectSize,
+			Replaced:    histMeta.LastUpdate,
+		})
+	}
+
+	output := cliutils.MarshalIndent(history, "mms object history")
+	fmt.Println(output)
+}
+
+// Publish an earlier version of an object again. The object keeps its current destinations, the rest of the metadata
+// and the data are those of the earlier version. The version being replaced is kept in the history so that the
+// rollback can be undone.
+func ObjectRollback(org string, userPw string, objType string, objId string, revision int, keepHistory int) {
+
+	if userPw == "" {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "must specify exchange credentials to access the model management service")
+	} else if revision <= 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "--revision must be a revision number from 'hzn mms object history'")
+	} else if keepHistory < 0 {
+		cliutils.Fatal(cliutils.CLI_INPUT_ERROR, "--history cannot be negative")
+	}
+
+	// Set the API key env var if that's what we're using.
+	cliutils.SetWhetherUsingApiKey(userPw)
+
+	current, _, ok := getObject(org, userPw, objType, objId)
+	if !ok {
+		cliutils.Fatal(cliutils.NOT_FOUND, "object '%s' of type '%s' not found in org %s", objId, objType, org)
+	}
+
+	histMeta, data, ok := getObject(org, userPw, historyType(objType), historyId(objId, revision))
+	if !ok {
+		cliutils.Fatal(cliutils.NOT_FOUND, "revision %v of object '%s' of type '%s' not found in org %s", revision, objId, objType, org)
+	}
+
+	if keepHistory > 0 {
+		archiveObject(org, userPw, objType, objId, keepHistory)
+	}
+
+	objectMeta := *histMeta
+	objectMeta.ObjectType = objType
+	objectMeta.ObjectID = objId
+	objectMeta.DoNotSend = current.DoNotSend
+	objectMeta.DestID = current.DestID
+	objectMeta.DestType = current.DestType
+	objectMeta.DestinationsList = current.DestinationsList
+	objectMeta.DestinationPolicy = current.DestinationPolicy
+	objectMeta.Expiration = current.Expiration
+	objectMeta.ActivationTime = current.ActivationTime
+	putObject(org, userPw, objectMeta, data)
+
+	fmt.Printf("Object %v of type %v in org %v rolled back to revision %v, version %v\n", objId, objType, org, revision, objectMeta.Version)
+}
+
+// Remove the history of an object.
+func deleteHistory(org string, userPw string, objType string, objId string) {
+	for _, revision := range historyRevisions(org, userPw, objType, objId) {
+		urlPath := path.Join("api/v1/objects/", org, historyType(objType), historyId(objId, revision))
+		cliutils.ExchangeDelete("Model Management Service", cliutils.GetMMSUrl(), urlPath, cliutils.OrgAndCreds(org, userPw), []int{204, 404})
+	}
+}
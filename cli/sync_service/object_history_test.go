@@ -0,0 +1,26 @@
+// +build unit
+
+package sync_service
+
+import (
+	"testing"
+)
+
+func Test_historyRevision(t *testing.T) {
+
+	if revision, ok := historyRevision("model", historyId("model", 12)); !ok || revision != 12 {
+		t.Errorf("revision 12 of model should be found, got %v %v", revision, ok)
+	}
+
+	// the history of another object, or an id that is not a history id
+	for _, histId := range []string{"model2@1", "model@", "model@0", "model@01", "model@1a", "model@-1", "other@model@1"} {
+		if revision, ok := historyRevision("model", histId); ok {
+			t.Errorf("%v should not be a revision of model, got %v", histId, revision)
+		}
+	}
+
+	// an object id that contains the separator
+	if revision, ok := historyRevision("a@b", "a@b@3"); !ok || revision != 3 {
+		t.Errorf("revision 3 of a@b should be found, got %v %v", revision, ok)
+	}
+}
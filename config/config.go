@@ -35,25 +35,38 @@ type Config struct {
 	ExchangeURL                      string
 	DefaultHTTPClientTimeoutS        uint
 	PolicyPath                       string
-	ExchangeHeartbeat                int       // Seconds between heartbeats
-	ExchangeVersionCheckIntervalM    int64     // Exchange version check interval in minutes. The default is 720.
-	AgreementTimeoutS                uint64    // Number of seconds to wait before declaring agreement not finalized in blockchain
-	DVPrefix                         string    // When passing agreement ids into a workload container, add this prefix to the agreement id
-	RegistrationDelayS               uint64    // The number of seconds to wait after blockchain init before registering with the exchange. This is for testing initialization ONLY.
-	ExchangeMessageTTL               int       // The number of seconds the exchange will keep this message before automatically deleting it
-	UserPublicKeyPath                string    // The location to store user keys uploaded through the REST API
-	ReportDeviceStatus               bool      // whether to report the device status to the exchange or not.
-	TrustCertUpdatesFromOrg          bool      // whether to trust the certs provided by the organization on the exchange or not.
-	TrustDockerAuthFromOrg           bool      // whether to turst the docker auths provided by the organization on the exchange or not.
-	ServiceUpgradeCheckIntervalS     int64     // service upgrade check interval in seconds. The default is 300 seconds.
-	MultipleAnaxInstances            bool      // multiple anax instances running on the same machine
-	DefaultServiceRetryCount         int       // the default service retry count if retries are not specified by the policy file. The default value is 2.
-	DefaultServiceRetryDuration      uint64    // the default retry duration in seconds. The next retry cycle occurs after the duration. The default value is 600
-	ServiceConfigStateCheckIntervalS int       // the service configuration state check interval. The default is 30 seconds.
-	DefaultNodePolicyFile            string    // the default node policy file name.
-	NodePolicyCheckIntervalS         int       // the node policy check interval. The default is 15 seconds.
-	NodeUserInputCheckIntervalS      int       // the node user input check interval. The default is 15 seconds.
-	FileSyncService                  FSSConfig // The config for the embedded ESS sync service.
+	ExchangeHeartbeat                int                     // Seconds between heartbeats
+	ExchangeVersionCheckIntervalM    int64                   // Exchange version check interval in minutes. The default is 720.
+	AgreementTimeoutS                uint64                  // Number of seconds to wait before declaring agreement not finalized in blockchain
+	DVPrefix                         string                  // When passing agreement ids into a workload container, add this prefix to the agreement id
+	RegistrationDelayS               uint64                  // The number of seconds to wait after blockchain init before registering with the exchange. This is for testing initialization ONLY.
+	ExchangeMessageTTL               int                     // The number of seconds the exchange will keep this message before automatically deleting it
+	UserPublicKeyPath                string                  // The location to store user keys uploaded through the REST API
+	ReportDeviceStatus               bool                    // whether to report the device status to the exchange or not.
+	TrustCertUpdatesFromOrg          bool                    // whether to trust the certs provided by the organization on the exchange or not.
+	TrustDockerAuthFromOrg           bool                    // whether to turst the docker auths provided by the organization on the exchange or not.
+	ServiceUpgradeCheckIntervalS     int64                   // service upgrade check interval in seconds. The default is 300 seconds.
+	MultipleAnaxInstances            bool                    // multiple anax instances running on the same machine
+	DefaultServiceRetryCount         int                     // the default service retry count if retries are not specified by the policy file. The default value is 2.
+	DefaultServiceRetryDuration      uint64                  // the default retry duration in seconds. The next retry cycle occurs after the duration. The default value is 600
+	ServiceConfigStateCheckIntervalS int                     // the service configuration state check interval. The default is 30 seconds.
+	DefaultNodePolicyFile            string                  // the default node policy file name.
+	NodePolicyCheckIntervalS         int                     // the node policy check interval. The default is 15 seconds.
+	NodeUserInputCheckIntervalS      int                     // the node user input check interval. The default is 15 seconds.
+	FileSyncService                  FSSConfig               // The config for the embedded ESS sync service.
+	MaintenanceWindows               []string                // the maintenance windows in which workload and service upgrades are allowed, see cutil.ParseMaintenanceWindow. Upgrades are allowed at any time by default.
+	EventLogRetentionDays            int                     // event logs older than this many days are deleted. The default is 0, the event logs are kept forever.
+	EventLogSeverityRetentionDays    map[string]int          // the number of days to keep the event logs of a severity (info, warning, error), overrides EventLogRetentionDays. 0 keeps them forever.
+	EventLogMaxRecords               int                     // the maximum number of event logs kept, the oldest are deleted first. The default is 0, no limit.
+	EventLogPruneIntervalS           int                     // the event log pruning interval. The default is 3600 seconds.
+	ContainerLimits                  ContainerLimitsConfig   // The ceilings on the resources each service container may use.
+	EventLogForwarder                EventLogForwarderConfig // The config for forwarding the event logs to a central log service.
+	ImageGC                          ImageGCConfig           // The config for removing the service images that are no longer used.
+	DisableImagePrefetch             bool                    // whether to stop pulling the images of upcoming service versions before the upgrade. The images are prefetched by default.
+	ImageVerification                ImageVerificationConfig // The config for verifying the signatures of the service images.
+	RegistryMirrors                  RegistryMirrorsConfig   // The config for pulling the service images through registry mirrors.
+	ImagePull                        ImagePullConfig         // The config for retrying the image pulls that fail with a transient error.
+	ServiceSecretsPath               string                  // The host directory holding the secret user input files mounted into the service containers, it should be on a tmpfs. The default is /run/horizon/secrets.
 
 	// these Ids could be provided in config or discovered after startup by the system
 	BlockchainAccountId        string
@@ -123,6 +136,19 @@ func (c *HorizonConfig) GetPartitionStale() uint64 {
 	}
 }
 
+// Returns the host directory holding the secret user input files of the services.
+func (c *HorizonConfig) GetServiceSecretsPath() string {
+	if c.Edge.ServiceSecretsPath == "" {
+		return HZN_SECRETS_PATH_DEFAULT
+	}
+	return c.Edge.ServiceSecretsPath
+}
+
+// Returns the host directory holding the user input files of the services that reload their configuration.
+func (c *HorizonConfig) GetServiceUserInputPath() string {
+	return path.Join(getDefaultBase(), HZN_USERINPUT_PATH)
+}
+
 func (c *HorizonConfig) GetAgbotCSSURL() string {
 	return strings.TrimRight(c.AgreementBot.CSSURL, "/")
 }
@@ -208,6 +234,40 @@ func Read(file string) (*HorizonConfig, error) {
 			config.Edge.NodeUserInputCheckIntervalS = 15
 		}
 
+		if config.Edge.EventLogPruneIntervalS == 0 {
+			config.Edge.EventLogPruneIntervalS = 3600
+		}
+
+		if config.Edge.ContainerLimits.ExceedAction == "" {
+			config.Edge.ContainerLimits.ExceedAction = CONTAINER_LIMITS_REFUSE
+		}
+		if err := config.Edge.ContainerLimits.Validate(); err != nil {
+			return nil, fmt.Errorf("Invalid ContainerLimits in config file: %v", err)
+		}
+
+		if config.Edge.EventLogForwarder.MinSeverity == "" {
+			config.Edge.EventLogForwarder.MinSeverity = "info"
+		}
+		if config.Edge.EventLogForwarder.BufferMaxRecords == 0 {
+			config.Edge.EventLogForwarder.BufferMaxRecords = 10000
+		}
+		if config.Edge.EventLogForwarder.ForwardIntervalS == 0 {
+			config.Edge.EventLogForwarder.ForwardIntervalS = 5
+		}
+		if config.Edge.EventLogForwarder.RetryIntervalS == 0 {
+			config.Edge.EventLogForwarder.RetryIntervalS = 30
+		}
+
+		if config.Edge.ImageGC.IntervalS == 0 {
+			config.Edge.ImageGC.IntervalS = 3600
+		}
+		if config.Edge.ImageGC.KeepVersions == 0 {
+			config.Edge.ImageGC.KeepVersions = 1
+		}
+		if config.Edge.ImageGC.PrefetchKeepH == 0 {
+			config.Edge.ImageGC.PrefetchKeepH = 168
+		}
+
 		// set default retry parameters
 		// the default DefaultServiceRetryCount is 2. It means 2 tries including the original one.
 		// so it is actually 1 retry.
@@ -260,7 +320,7 @@ func (c *HorizonConfig) String() string {
 }
 
 func (con *Config) String() string {
-	return fmt.Sprintf("ServiceStorage %v, APIListen %v, DBPath %v, DockerEndpoint %v, DockerCredFilePath %v, DefaultCPUSet %v, DefaultServiceRegistrationRAM: %v, StaticWebContent: %v, PublicKeyPath: %v, TrustSystemCACerts: %v, CACertsPath: %v, ExchangeURL: %v, DefaultHTTPClientTimeoutS: %v, PolicyPath: %v, ExchangeHeartbeat: %v, ExchangeVersionCheckIntervalM: %v, AgreementTimeoutS: %v, DVPrefix: %v, RegistrationDelayS: %v, ExchangeMessageTTL: %v, UserPublicKeyPath: %v, ReportDeviceStatus: %v, TrustCertUpdatesFromOrg: %v, TrustDockerAuthFromOrg: %v, ServiceUpgradeCheckIntervalS: %v, MultipleAnaxInstances: %v, DefaultServiceRetryCount: %v, DefaultServiceRetryDuration: %v, ServiceConfigStateCheckIntervalS: %v, FileSyncService: {%v}, BlockchainAccountId: %v, BlockchainDirectoryAddress %v", con.ServiceStorage, con.APIListen, con.DBPath, con.DockerEndpoint, con.DockerCredFilePath, con.DefaultCPUSet, con.DefaultServiceRegistrationRAM, con.StaticWebContent, con.PublicKeyPath, con.TrustSystemCACerts, con.CACertsPath, con.ExchangeURL, con.DefaultHTTPClientTimeoutS, con.PolicyPath, con.ExchangeHeartbeat, con.ExchangeVersionCheckIntervalM, con.AgreementTimeoutS, con.DVPrefix, con.RegistrationDelayS, con.ExchangeMessageTTL, con.UserPublicKeyPath, con.ReportDeviceStatus, con.TrustCertUpdatesFromOrg, con.TrustDockerAuthFromOrg, con.ServiceUpgradeCheckIntervalS, con.MultipleAnaxInstances, con.DefaultServiceRetryCount, con.DefaultServiceRetryDuration, con.ServiceConfigStateCheckIntervalS, con.FileSyncService.String(), con.BlockchainAccountId, con.BlockchainDirectoryAddress)
+	return fmt.Sprintf("ServiceStorage %v, APIListen %v, DBPath %v, DockerEndpoint %v, DockerCredFilePath %v, DefaultCPUSet %v, DefaultServiceRegistrationRAM: %v, StaticWebContent: %v, PublicKeyPath: %v, TrustSystemCACerts: %v, CACertsPath: %v, ExchangeURL: %v, DefaultHTTPClientTimeoutS: %v, PolicyPath: %v, ExchangeHeartbeat: %v, ExchangeVersionCheckIntervalM: %v, AgreementTimeoutS: %v, DVPrefix: %v, RegistrationDelayS: %v, ExchangeMessageTTL: %v, UserPublicKeyPath: %v, ReportDeviceStatus: %v, TrustCertUpdatesFromOrg: %v, TrustDockerAuthFromOrg: %v, ServiceUpgradeCheckIntervalS: %v, MultipleAnaxInstances: %v, DefaultServiceRetryCount: %v, DefaultServiceRetryDuration: %v, ServiceConfigStateCheckIntervalS: %v, FileSyncService: {%v}, MaintenanceWindows: %v, EventLogRetentionDays: %v, EventLogSeverityRetentionDays: %v, EventLogMaxRecords: %v, EventLogPruneIntervalS: %v, ContainerLimits: {%v}, EventLogForwarder: {%v}, ImageGC: {%v}, DisableImagePrefetch: %v, ImageVerification: {%v}, RegistryMirrors: {%v}, ImagePull: {%v}, ServiceSecretsPath: %v, BlockchainAccountId: %v, BlockchainDirectoryAddress %v", con.ServiceStorage, con.APIListen, con.DBPath, con.DockerEndpoint, con.DockerCredFilePath, con.DefaultCPUSet, con.DefaultServiceRegistrationRAM, con.StaticWebContent, con.PublicKeyPath, con.TrustSystemCACerts, con.CACertsPath, con.ExchangeURL, con.DefaultHTTPClientTimeoutS, con.PolicyPath, con.ExchangeHeartbeat, con.ExchangeVersionCheckIntervalM, con.AgreementTimeoutS, con.DVPrefix, con.RegistrationDelayS, con.ExchangeMessageTTL, con.UserPublicKeyPath, con.ReportDeviceStatus, con.TrustCertUpdatesFromOrg, con.TrustDockerAuthFromOrg, con.ServiceUpgradeCheckIntervalS, con.MultipleAnaxInstances, con.DefaultServiceRetryCount, con.DefaultServiceRetryDuration, con.ServiceConfigStateCheckIntervalS, con.FileSyncService.String(), con.MaintenanceWindows, con.EventLogRetentionDays, con.EventLogSeverityRetentionDays, con.EventLogMaxRecords, con.EventLogPruneIntervalS, con.ContainerLimits.String(), con.EventLogForwarder.String(), con.ImageGC.String(), con.DisableImagePrefetch, con.ImageVerification.String(), con.RegistryMirrors.String(), con.ImagePull.String(), con.ServiceSecretsPath, con.BlockchainAccountId, con.BlockchainDirectoryAddress)
 }
 
 func (agc *AGConfig) String() string {
@@ -5,6 +5,7 @@ package config
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func Test_enrichFromEnvvars_success(t *testing.T) {
@@ -65,3 +66,53 @@ func Test_enrichFromEnvvars_success(t *testing.T) {
 	}
 
 }
+
+func Test_ImagePullConfig_Backoff(t *testing.T) {
+
+	var defaults ImagePullConfig
+	if defaults.Attempts() != DEFAULT_IMAGE_PULL_ATTEMPTS {
+		t.Errorf("expected the default attempts %v, but got %v", DEFAULT_IMAGE_PULL_ATTEMPTS, defaults.Attempts())
+	} else if defaults.Backoff(1) != DEFAULT_IMAGE_PULL_BACKOFF_S*time.Second {
+		t.Errorf("expected the default backoff %vs, but got %v", DEFAULT_IMAGE_PULL_BACKOFF_S, defaults.Backoff(1))
+	}
+
+	c := ImagePullConfig{MaxAttempts: 3, InitialBackoffS: 10, MaxBackoffS: 35}
+	for attempt, expected := range map[int]time.Duration{1: 10 * time.Second, 2: 20 * time.Second, 3: 35 * time.Second, 8: 35 * time.Second} {
+		if b := c.Backoff(attempt); b != expected {
+			t.Errorf("expected backoff %v after attempt %v, but got %v", expected, attempt, b)
+		}
+	}
+	if c.Attempts() != 3 {
+		t.Errorf("expected 3 attempts, but got %v", c.Attempts())
+	}
+}
+
+func Test_ContainerLimitsConfig_Validate(t *testing.T) {
+
+	valid := []ContainerLimitsConfig{
+		{ExceedAction: CONTAINER_LIMITS_REFUSE},
+		{MaxMemoryMB: 256, MaxCPUShares: 1024, MaxCPUs: 0.01, MaxPids: 100, MaxUlimits: map[string]int64{"nofile": 4096}, ExceedAction: CONTAINER_LIMITS_CLAMP},
+	}
+	for _, c := range valid {
+		if err := c.Validate(); err != nil {
+			t.Errorf("expected %v to be valid, but got %v", c.String(), err)
+		}
+	}
+
+	invalid := []ContainerLimitsConfig{
+		{ExceedAction: ""},
+		{ExceedAction: "warn"},
+		{MaxMemoryMB: -1, ExceedAction: CONTAINER_LIMITS_REFUSE},
+		{MaxMemoryMB: 2, ExceedAction: CONTAINER_LIMITS_REFUSE},
+		{MaxCPUShares: -1, ExceedAction: CONTAINER_LIMITS_REFUSE},
+		{MaxCPUs: -0.5, ExceedAction: CONTAINER_LIMITS_REFUSE},
+		{MaxCPUs: 0.005, ExceedAction: CONTAINER_LIMITS_REFUSE},
+		{MaxPids: -1, ExceedAction: CONTAINER_LIMITS_REFUSE},
+		{MaxUlimits: map[string]int64{"nofile": -1}, ExceedAction: CONTAINER_LIMITS_REFUSE},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("expected %v to be invalid", c.String())
+		}
+	}
+}
@@ -33,9 +33,31 @@ const HZN_FSS_AUTH_PATH = "ess-auth"
 // The name of the file mount that a service uses to find its FSS credential file.
 const HZN_FSS_AUTH_MOUNT = "/" + HZN_FSS_AUTH_PATH
 
+// The relative path of the user input files of the services that reload their configuration. This path should be combined with the HZN_VAR_BASE_DEFAULT.
+const HZN_USERINPUT_PATH = "service-userinput"
+
+// The name of the file mount that a service uses to find its user input file when it reloads its configuration.
+const HZN_USERINPUT_MOUNT = "/service_userinput"
+
+// The name of the file holding the node user input of a service, as a JSON object of the variable names and values.
+const HZN_USERINPUT_FILE = "userinput.json"
+
+// The name of the file mount that a service uses to find its secret user inputs, one file per secret.
+const HZN_SECRETS_MOUNT = "/run/secrets"
+
+// The default host directory holding the secret user input files of the services. It should be on a tmpfs so that the
+// secrets are never written to disk.
+const HZN_SECRETS_PATH_DEFAULT = "/run/horizon/secrets"
+
 // The name of the authentication file that a service can use to authenticate to the FSS (ESS) API.
 const HZN_FSS_AUTH_FILE = "auth.json"
 
+// The name of the file holding the previous token of a rotated FSS credential, until its grace period ends.
+const HZN_FSS_AUTH_PREVIOUS_FILE = ".auth.previous.json"
+
+// The number of seconds that the previous token of a rotated FSS credential still authenticates.
+const HZN_FSS_CREDENTIAL_GRACE_PERIOD_S = 300
+
 // The relative path of SSL client certificate used by services to access the sync service.
 const HZN_FSS_CERT_PATH = "ess-cert"
 
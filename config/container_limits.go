@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+)
+
+// What the agent does with a deployment whose resource limits exceed the node's ceilings.
+const (
+	CONTAINER_LIMITS_REFUSE = "refuse" // the deployment is not started
+	CONTAINER_LIMITS_CLAMP  = "clamp"  // the limits are lowered to the ceilings
+)
+
+// The most resources a single service container may use on this node. A zero value means there is no ceiling. When
+// a deployment does not set a limit that has a ceiling, the ceiling is used as the limit.
+type ContainerLimitsConfig struct {
+	MaxMemoryMB  int64            // The highest memory limit in MB.
+	MaxCPUShares int64            // The highest relative CPU weight.
+	MaxCPUs      float64          // The highest CPU quota, as a number of CPUs.
+	MaxPids      int64            // The highest limit on the number of processes.
+	MaxUlimits   map[string]int64 // The highest hard limit for each ulimit name, e.g. nofile.
+	ExceedAction string           // Can be 'refuse' or 'clamp'. The default is refuse.
+}
+
+func (c *ContainerLimitsConfig) String() string {
+	return fmt.Sprintf("MaxMemoryMB: %v, MaxCPUShares: %v, MaxCPUs: %v, MaxPids: %v, MaxUlimits: %v, ExceedAction: %v", c.MaxMemoryMB, c.MaxCPUShares, c.MaxCPUs, c.MaxPids, c.MaxUlimits, c.ExceedAction)
+}
+
+// Check that the ceilings can be applied to a container. The ExceedAction is expected to have its default already.
+func (c *ContainerLimitsConfig) Validate() error {
+	if c.MaxMemoryMB < 0 {
+		return fmt.Errorf("MaxMemoryMB %v must not be negative", c.MaxMemoryMB)
+	} else if c.MaxMemoryMB > 0 && c.MaxMemoryMB < 4 {
+		// docker requires at least 4MB
+		return fmt.Errorf("MaxMemoryMB %v must be at least 4", c.MaxMemoryMB)
+	} else if c.MaxCPUShares < 0 {
+		return fmt.Errorf("MaxCPUShares %v must not be negative", c.MaxCPUShares)
+	} else if c.MaxCPUs < 0 {
+		return fmt.Errorf("MaxCPUs %v must not be negative", c.MaxCPUs)
+	} else if c.MaxCPUs > 0 && c.MaxCPUs < 0.01 {
+		// docker requires a CPU quota of at least 1ms, a hundredth of the 100ms CPU period
+		return fmt.Errorf("MaxCPUs %v must be at least 0.01", c.MaxCPUs)
+	} else if c.MaxPids < 0 {
+		return fmt.Errorf("MaxPids %v must not be negative", c.MaxPids)
+	} else if c.ExceedAction != CONTAINER_LIMITS_REFUSE && c.ExceedAction != CONTAINER_LIMITS_CLAMP {
+		return fmt.Errorf("ExceedAction %v must be %v or %v", c.ExceedAction, CONTAINER_LIMITS_REFUSE, CONTAINER_LIMITS_CLAMP)
+	}
+
+	for name, max := range c.MaxUlimits {
+		if max < 0 {
+			return fmt.Errorf("MaxUlimits %v %v must not be negative", name, max)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"path"
+)
+
+// The kinds of targets the event logs can be forwarded to.
+const (
+	EVENTLOG_FORWARD_SYSLOG = "syslog"
+	EVENTLOG_FORWARD_FILE   = "file"
+	EVENTLOG_FORWARD_HTTP   = "http"
+)
+
+// The config for forwarding the event logs to a central log service. Forwarding is off when Type is empty.
+type EventLogForwarderConfig struct {
+	Type             string            // Can be 'syslog', 'file' or 'http'.
+	Target           string            // For syslog, the address of the syslog server, e.g. udp://host:514, tcp://host:601 or unix:///dev/log. For file, the path of the JSON-lines file. For http, the URL the event logs are POSTed to.
+	HTTPHeaders      map[string]string // Extra headers sent with each http request, e.g. for authorization.
+	MinSeverity      string            // The lowest severity forwarded, info, warning or error. The default is info.
+	BufferPath       string            // The directory where the event logs are kept while the target is unreachable. The default is under the Edge DBPath.
+	BufferMaxRecords int               // The maximum number of event logs kept while the target is unreachable, the oldest are dropped first. The default is 10000.
+	ForwardIntervalS int               // How often new event logs are forwarded. The default is 5 seconds.
+	RetryIntervalS   int               // How long to wait before trying again when the target is unreachable. The default is 30 seconds.
+}
+
+func (f *EventLogForwarderConfig) String() string {
+	headers := make([]string, 0, len(f.HTTPHeaders))
+	for name, _ := range f.HTTPHeaders {
+		headers = append(headers, name)
+	}
+	return fmt.Sprintf("Type: %v, Target: %v, HTTPHeaders: %v, MinSeverity: %v, BufferPath: %v, BufferMaxRecords: %v, ForwardIntervalS: %v, RetryIntervalS: %v", f.Type, f.Target, headers, f.MinSeverity, f.BufferPath, f.BufferMaxRecords, f.ForwardIntervalS, f.RetryIntervalS)
+}
+
+func (c *HorizonConfig) EventLogForwardingEnabled() bool {
+	return c.Edge.EventLogForwarder.Type != ""
+}
+
+func (c *HorizonConfig) GetEventLogForwarderBufferPath() string {
+	if c.Edge.EventLogForwarder.BufferPath == "" {
+		return path.Join(c.Edge.DBPath, "eventlog_forwarder")
+	}
+	return c.Edge.EventLogForwarder.BufferPath
+}
@@ -0,0 +1,21 @@
+package config
+
+import (
+	"fmt"
+)
+
+// The config for removing the service images that are no longer used. Only the images of the repositories that
+// service deployments on this node have used are removed. The images used by a container, an agreement or a service
+// definition are always kept.
+type ImageGCConfig struct {
+	Enabled              bool   // Turns on the periodic removal of unused service images. The default is false.
+	IntervalS            int    // How often the unused images are removed. The default is 3600 seconds.
+	KeepVersions         int    // The number of the newest images of each repository that are kept even when unused. The default is 1.
+	HighWaterMarkPercent int    // Images are only removed while the disk holding them is at least this full. The default is 0, images are removed regardless of the disk usage.
+	DockerRootDir        string // The directory whose disk usage is checked against the high-water mark. The default is docker's root directory.
+	PrefetchKeepH        int    // How long a prefetched image is kept when no container has used it, for an upgrade that does not happen. The default is 168 hours.
+}
+
+func (c *ImageGCConfig) String() string {
+	return fmt.Sprintf("Enabled: %v, IntervalS: %v, KeepVersions: %v, HighWaterMarkPercent: %v, DockerRootDir: %v, PrefetchKeepH: %v", c.Enabled, c.IntervalS, c.KeepVersions, c.HighWaterMarkPercent, c.DockerRootDir, c.PrefetchKeepH)
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// The defaults of the image pull retries.
+const (
+	DEFAULT_IMAGE_PULL_ATTEMPTS      = 5
+	DEFAULT_IMAGE_PULL_BACKOFF_S     = 15
+	DEFAULT_IMAGE_PULL_MAX_BACKOFF_S = 240
+)
+
+// The config for retrying the image pulls that fail with a transient error, such as a network error or a registry
+// that is temporarily unavailable. The wait between the attempts doubles after each attempt. Errors that are not
+// transient, such as an image that does not exist or bad credentials, are not retried.
+type ImagePullConfig struct {
+	MaxAttempts     int // The number of times an image pull is tried. The default is 5.
+	InitialBackoffS int // The wait before the first retry. The default is 15 seconds.
+	MaxBackoffS     int // The longest wait between two attempts. The default is 240 seconds.
+}
+
+func (c *ImagePullConfig) String() string {
+	return fmt.Sprintf("MaxAttempts: %v, InitialBackoffS: %v, MaxBackoffS: %v", c.MaxAttempts, c.InitialBackoffS, c.MaxBackoffS)
+}
+
+// Returns the number of times an image pull is tried.
+func (c *ImagePullConfig) Attempts() int {
+	if c.MaxAttempts <= 0 {
+		return DEFAULT_IMAGE_PULL_ATTEMPTS
+	}
+	return c.MaxAttempts
+}
+
+// Returns the wait after the given failed attempt, counting from 1.
+func (c *ImagePullConfig) Backoff(attempt int) time.Duration {
+	backoff, max := c.InitialBackoffS, c.MaxBackoffS
+	if backoff <= 0 {
+		backoff = DEFAULT_IMAGE_PULL_BACKOFF_S
+	}
+	if max <= 0 {
+		max = DEFAULT_IMAGE_PULL_MAX_BACKOFF_S
+	}
+	for i := 1; i < attempt && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+	return time.Duration(backoff) * time.Second
+}
@@ -0,0 +1,17 @@
+package config
+
+import (
+	"fmt"
+)
+
+// The config for verifying the signatures of the service images before they are pulled. The signatures are checked
+// with the same keys as the service deployments, those under PublicKeyPath and UserPublicKeyPath. A signature in the
+// deployment or in the signature directory is always verified, an invalid one refuses the deployment.
+type ImageVerificationConfig struct {
+	RequireSignatures bool   // Refuses the images that are not pinned by digest or have no signature. The default is false, they are pulled.
+	SignatureDir      string // The directory of locally stored image signatures, laid out as <repository>@sha256=<digest>/signature-<n>. The default is no directory.
+}
+
+func (c *ImageVerificationConfig) String() string {
+	return fmt.Sprintf("RequireSignatures: %v, SignatureDir: %v", c.RequireSignatures, c.SignatureDir)
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The registry of the images that have no registry in their name.
+const DOCKER_HUB_REGISTRY = "docker.io"
+
+// The config for pulling the service images through registry mirrors, such as a pull-through cache shared by the nodes
+// of a site. The mirrors are tried in order, then the registry of the image unless DisableOriginFallback is set. An
+// image pulled from a mirror is tagged with its original name, so the deployment does not change.
+type RegistryMirrorsConfig struct {
+	Mirrors               []RegistryMirror // The mirrors, in the order they are tried.
+	DisableOriginFallback bool             // Do not pull from the registry of the image when no mirror has it. The default is false.
+}
+
+func (c *RegistryMirrorsConfig) String() string {
+	return fmt.Sprintf("Mirrors: %v, DisableOriginFallback: %v", c.Mirrors, c.DisableOriginFallback)
+}
+
+// Returns the mirrors of a registry, in order.
+func (c *RegistryMirrorsConfig) MirrorsOf(registry string) []RegistryMirror {
+	mirrors := make([]RegistryMirror, 0)
+	for _, m := range c.Mirrors {
+		if m.Serves(registry) {
+			mirrors = append(mirrors, m)
+		}
+	}
+	return mirrors
+}
+
+// A registry mirror. The repository of an image is found at the same path on the mirror, after the Prefix and the first
+// matching rewrite rule are applied.
+type RegistryMirror struct {
+	Mirror     string            // The host and port of the mirror, e.g. mirror.local:5000.
+	Registries []string          // The registries the mirror serves, e.g. docker.io or myregistry.com:5000. All registries when empty.
+	Prefix     string            // The path the repositories are under on the mirror, e.g. dockerhub for mirror.local:5000/dockerhub/openhorizon/gps.
+	Rewrites   []RegistryRewrite // The rules that change the repository path on the mirror, the first rule that matches is used.
+	UserName   string            // The user name for the mirror. When UserName and Password are empty, the docker auths of the mirror host are used.
+	Password   string            // The password or token for the mirror.
+}
+
+func (m RegistryMirror) String() string {
+	password := ""
+	if m.Password != "" {
+		password = "********"
+	}
+	return fmt.Sprintf("Mirror: %v, Registries: %v, Prefix: %v, Rewrites: %v, UserName: %v, Password: %v", m.Mirror, m.Registries, m.Prefix, m.Rewrites, m.UserName, password)
+}
+
+// Returns true if the mirror serves the images of the registry. The images without a registry are in docker hub.
+func (m *RegistryMirror) Serves(registry string) bool {
+	if registry == "" {
+		registry = DOCKER_HUB_REGISTRY
+	}
+	if len(m.Registries) == 0 {
+		return true
+	}
+	for _, r := range m.Registries {
+		if r == registry {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the repository on the mirror of a repository path of the registry, without the mirror host.
+func (m *RegistryMirror) Repository(registry string, path string) string {
+	// the official docker hub images are in the library directory
+	if (registry == "" || registry == DOCKER_HUB_REGISTRY) && !strings.Contains(path, "/") {
+		path = "library/" + path
+	}
+	for _, r := range m.Rewrites {
+		if strings.HasPrefix(path, r.From) {
+			path = r.To + strings.TrimPrefix(path, r.From)
+			break
+		}
+	}
+	if prefix := strings.Trim(m.Prefix, "/"); prefix != "" {
+		path = prefix + "/" + path
+	}
+	return path
+}
+
+// A rewrite rule for the repository paths on a mirror, the From prefix of the path is replaced by To.
+type RegistryRewrite struct {
+	From string
+	To   string
+}
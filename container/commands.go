@@ -155,6 +155,40 @@ func (b *ContainerWorker) NewShutdownMicroserviceCommand(key string) *ShutdownMi
 	}
 }
 
+// ==============================================================================================================
+type RotateSecretsCommand struct {
+	ServiceSpecs persistence.ServiceSpecs // the services whose secrets have changed.
+}
+
+func (c RotateSecretsCommand) ShortString() string {
+	return fmt.Sprintf("RotateSecretsCommand: ServiceSpecs %v", c.ServiceSpecs)
+}
+
+func (b *ContainerWorker) NewRotateSecretsCommand(svcSpecs persistence.ServiceSpecs) *RotateSecretsCommand {
+	return &RotateSecretsCommand{
+		ServiceSpecs: svcSpecs,
+	}
+}
+
+// ==============================================================================================================
+type ConfigReloadCommand struct {
+	AgreementProtocol string
+	AgreementId       string
+	UserInput         map[string]interface{}
+}
+
+func (c ConfigReloadCommand) ShortString() string {
+	return fmt.Sprintf("ConfigReloadCommand: AgreementProtocol: %v, AgreementId: %v", c.AgreementProtocol, c.AgreementId)
+}
+
+func (b *ContainerWorker) NewConfigReloadCommand(protocol string, agreementId string, userInput map[string]interface{}) *ConfigReloadCommand {
+	return &ConfigReloadCommand{
+		AgreementProtocol: protocol,
+		AgreementId:       agreementId,
+		UserInput:         userInput,
+	}
+}
+
 // ==============================================================================================================
 // This worker command is used to tell the worker than the node is done shutting down and so it can terminate itself.
 type NodeUnconfigCommand struct {
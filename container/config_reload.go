@@ -0,0 +1,180 @@
+package container
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/persistence"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+)
+
+// The number of seconds to wait for a container to accept the posted user input.
+const CONFIG_RELOAD_POST_TIMEOUT_S = 10
+
+// The number of seconds to wait for a container that failed to reload its configuration to stop before it is killed
+// and started again.
+const CONFIG_RELOAD_RESTART_TIMEOUT_S = 10
+
+// Returns the host directory holding the user input file of an agreement.
+func (b *ContainerWorker) userInputDir(key string) string {
+	return path.Join(b.Config.GetServiceUserInputPath(), key)
+}
+
+// Write the user input of a service into the user input file of an agreement and return the directory and the
+// content of the file. The user input is merged by governance the same way as the environment variables of the
+// service, secrets are not in it, they are given to the containers as secret files.
+func (b *ContainerWorker) createServiceUserInput(key string, userInput map[string]interface{}) (string, []byte, error) {
+
+	if userInput == nil {
+		userInput = make(map[string]interface{})
+	}
+
+	content, err := json.Marshal(userInput)
+	if err != nil {
+		return "", nil, errors.New(fmt.Sprintf("unable to marshal the user input of %v, error %v", key, err))
+	}
+
+	dir := b.userInputDir(key)
+	if err := writeServiceUserInput(dir, content); err != nil {
+		return "", nil, err
+	}
+	return dir, content, nil
+}
+
+// Remove the user input file of an agreement.
+func (b *ContainerWorker) removeServiceUserInput(key string) {
+	if err := os.RemoveAll(b.userInputDir(key)); err != nil {
+		glog.Errorf("Failed to remove the user input file of %v, error %v", key, err)
+	}
+}
+
+// Rewrite the user input file of an agreement and tell each of its containers that opted in to reload its
+// configuration. A container that fails to reload is restarted so that it starts with the new user input.
+func (b *ContainerWorker) reloadServiceConfig(protocol string, agreementId string, userInput map[string]interface{}) {
+
+	ags, err := persistence.FindEstablishedAgreements(b.db, protocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(agreementId)})
+	if err != nil {
+		glog.Errorf("Unable to retrieve agreement %v from database, error %v", agreementId, err)
+		return
+	} else if len(ags) != 1 {
+		glog.Infof("Ignoring the config reload for agreement %v, it is no longer active", agreementId)
+		return
+	}
+	ag := ags[0]
+
+	_, content, err := b.createServiceUserInput(agreementId, userInput)
+	if err != nil {
+		eventlog.LogAgreementEvent(b.db, persistence.SEVERITY_ERROR, fmt.Sprintf("Error writing the user input for agreement %v: %v", agreementId, err), persistence.EC_ERROR_SERVICE_CONFIG_RELOAD, ag)
+		glog.Errorf("Error writing the user input for agreement %v: %v", agreementId, err)
+		return
+	}
+
+	reload := func(container *docker.APIContainers, key string) error {
+		serial, ok := container.Labels[persistence.LABEL_CONFIG_RELOAD]
+		if !ok {
+			return nil
+		}
+
+		var reloadErr error
+		cr := new(containermessage.ConfigReload)
+		if err := json.Unmarshal([]byte(serial), cr); err != nil {
+			reloadErr = fmt.Errorf("unable to demarshal config reload %v, error %v", serial, err)
+		} else if cr.HTTPPost != nil {
+			if detail, err := b.client.InspectContainer(container.ID); err != nil {
+				reloadErr = fmt.Errorf("unable to inspect container, error %v", err)
+			} else {
+				reloadErr = postUserInput(cr.HTTPPost, containerAddress(detail), content, b.Config.Collaborators.HTTPClientFactory.NewHTTPClient)
+			}
+		} else {
+			reloadErr = b.client.KillContainer(docker.KillContainerOptions{ID: container.ID, Signal: cr.DockerSignal()})
+		}
+
+		if reloadErr == nil {
+			glog.V(3).Infof("Container %v of %v reloaded its configuration", container.Names, key)
+			return nil
+		}
+
+		glog.Warningf("Container %v of %v failed to reload its configuration, restarting it: %v", container.Names, key, reloadErr)
+		eventlog.LogAgreementEvent(b.db, persistence.SEVERITY_WARN, fmt.Sprintf("Container %v of agreement %v failed to reload its configuration, restarting it: %v", container.Names, key, reloadErr), persistence.EC_ERROR_SERVICE_CONFIG_RELOAD, ag)
+		return b.client.RestartContainer(container.ID, CONFIG_RELOAD_RESTART_TIMEOUT_S)
+	}
+
+	if err := b.ContainersMatchingAgreement([]string{agreementId}, false, reload); err != nil {
+		eventlog.LogAgreementEvent(b.db, persistence.SEVERITY_ERROR, fmt.Sprintf("Error reloading the configuration of agreement %v: %v", agreementId, err), persistence.EC_ERROR_SERVICE_CONFIG_RELOAD, ag)
+		glog.Errorf("Error reloading the configuration of agreement %v: %v", agreementId, err)
+	} else {
+		eventlog.LogAgreementEvent(b.db, persistence.SEVERITY_INFO, fmt.Sprintf("Reloaded the configuration of agreement %v with the new user input", agreementId), persistence.EC_SERVICE_CONFIG_RELOADED, ag)
+	}
+}
+
+// Returns true when a service of the deployment reloads its configuration.
+func reloadsConfig(dd *containermessage.DeploymentDescription) bool {
+	for _, service := range dd.Services {
+		if service.ConfigReload != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Write the user input file into the directory. The file is replaced by a rename so that a running container never
+// reads a partial file.
+func writeServiceUserInput(dir string, content []byte) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.New(fmt.Sprintf("unable to create the user input directory %v, error %v", dir, err))
+	}
+
+	tmp := path.Join(dir, "."+config.HZN_USERINPUT_FILE+".tmp")
+	if err := ioutil.WriteFile(tmp, content, 0444); err != nil {
+		os.Remove(tmp)
+		return errors.New(fmt.Sprintf("unable to write the user input file, error %v", err))
+	} else if err := os.Rename(tmp, path.Join(dir, config.HZN_USERINPUT_FILE)); err != nil {
+		os.Remove(tmp)
+		return errors.New(fmt.Sprintf("unable to write the user input file, error %v", err))
+	}
+	return nil
+}
+
+// Returns the binding that mounts the user input directory into a container.
+func userInputBinding(dir string) string {
+	return fmt.Sprintf("%v:%v:ro", dir, config.HZN_USERINPUT_MOUNT)
+}
+
+// Post the user input to the container's reload endpoint. Any status code from 200 to 299 is a success.
+func postUserInput(post *containermessage.HTTPPostReload, address string, content []byte, newHTTPClient func(*uint) *http.Client) error {
+	if address == "" {
+		return fmt.Errorf("the container has no IP address")
+	}
+
+	path := post.Path
+	if path == "" {
+		path = "/"
+	}
+	url := fmt.Sprintf("http://%v%v", net.JoinHostPort(address, strconv.Itoa(post.Port)), path)
+
+	timeoutS := uint(CONFIG_RELOAD_POST_TIMEOUT_S)
+	client := newHTTPClient(&timeoutS)
+	resp, err := client.Post(url, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return fmt.Errorf("POST %v failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("POST %v returned HTTP code %v", url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// +build unit
+
+package container
+
+import (
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_writeServiceUserInput(t *testing.T) {
+
+	base, err := ioutil.TempDir("", "userinput")
+	assert.Nil(t, err)
+	defer os.RemoveAll(base)
+
+	dir := path.Join(base, "agreement1")
+	assert.Nil(t, writeServiceUserInput(dir, []byte(`{"VAR1":"a"}`)))
+	assert.Nil(t, writeServiceUserInput(dir, []byte(`{"VAR1":"b"}`)))
+
+	content, err := ioutil.ReadFile(path.Join(dir, config.HZN_USERINPUT_FILE))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"VAR1":"b"}`, string(content), "The file should hold the latest user input.")
+
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files), "The temporary file should be gone.")
+	assert.Equal(t, os.FileMode(0444), files[0].Mode().Perm())
+}
+
+func Test_postUserInput(t *testing.T) {
+
+	var received []byte
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/reload" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		received, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	host, p, _ := net.SplitHostPort(u.Host)
+	port, _ := strconv.Atoi(p)
+	newClient := func(timeoutS *uint) *http.Client { return &http.Client{Timeout: time.Duration(*timeoutS) * time.Second} }
+
+	post := &containermessage.HTTPPostReload{Port: port, Path: "/reload"}
+	assert.Nil(t, postUserInput(post, host, []byte(`{"VAR1":"a"}`), newClient), "The post should succeed.")
+	assert.Equal(t, `{"VAR1":"a"}`, string(received), "The user input should be posted.")
+
+	status = http.StatusInternalServerError
+	assert.NotNil(t, postUserInput(post, host, []byte(`{}`), newClient), "The post should fail on a 500.")
+	assert.NotNil(t, postUserInput(&containermessage.HTTPPostReload{Port: port}, host, []byte(`{}`), newClient), "The post should fail on a 404.")
+	assert.NotNil(t, postUserInput(post, "", []byte(`{}`), newClient), "A container without an address should fail.")
+}
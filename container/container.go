@@ -16,6 +16,7 @@ import (
 	"github.com/open-horizon/anax/cutil"
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/metrics"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/resource"
@@ -28,11 +29,15 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 const LABEL_PREFIX = "openhorizon.anax"
 const IPT_COLONUS_ISOLATED_CHAIN = "OPENHORIZON-ANAX-ISOLATION"
 
+// The number of times the containers of an agreement or service could not be set up.
+var containerStartFailures = metrics.NewCounter("anax_container_start_failures_total", "The number of times the containers for an agreement or service failed to start.")
+
 /*
  *
  * The external representations of the service deployment string; once processed, the data is stored in a persistence.MicroserviceDefinition object for a service
@@ -264,6 +269,57 @@ func (w *ContainerWorker) finalizeDeployment(agreementId string, deployment *con
 			},
 		}
 
+		// Apply the container's resource limits, within what the node allows.
+		if err := applyResourceLimits(&serviceConfig.HostConfig, serviceName, service.Resources, w.Config.Edge.ContainerLimits); err != nil {
+			return nil, err
+		}
+
+		// Apply the restart policy and health check from the deployment. The health check is also kept in a label so
+		// that the health monitor can find the containers to check.
+		if service.RestartPolicy != nil {
+			if err := service.RestartPolicy.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid restart policy for service %v: %v", serviceName, err)
+			}
+			serviceConfig.HostConfig.RestartPolicy = service.RestartPolicy.DockerRestartPolicy()
+		}
+
+		if service.HealthCheck != nil {
+			if err := service.HealthCheck.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid health check for service %v: %v", serviceName, err)
+			} else if check, err := json.Marshal(service.HealthCheck); err != nil {
+				return nil, fmt.Errorf("unable to marshal health check for service %v: %v", serviceName, err)
+			} else {
+				serviceConfig.Config.Healthcheck = service.HealthCheck.DockerHealthConfig()
+				serviceConfig.Config.Labels[LABEL_HEALTH_CHECK] = string(check)
+			}
+		}
+
+		// A service that reloads its configuration is told about node user input changes instead of being restarted,
+		// the label lets the worker find the containers to tell.
+		if service.ConfigReload != nil {
+			if err := service.ConfigReload.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid config reload for service %v: %v", serviceName, err)
+			} else if reload, err := json.Marshal(service.ConfigReload); err != nil {
+				return nil, fmt.Errorf("unable to marshal config reload for service %v: %v", serviceName, err)
+			} else {
+				serviceConfig.Config.Labels[persistence.LABEL_CONFIG_RELOAD] = string(reload)
+			}
+		}
+
+		// The egress policy is enforced once the container is on its networks, it is kept in a label so that the
+		// egress refresh can find the containers with host names to resolve again.
+		if service.Egress != nil {
+			if err := service.Egress.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid egress policy for service %v: %v", serviceName, err)
+			} else if service.NetworkIsolation != nil && len(service.NetworkIsolation.OutboundPermitOnly) != 0 {
+				return nil, fmt.Errorf("service %v cannot have both an egress policy and network_isolation outbound_permit_only", serviceName)
+			} else if policy, err := json.Marshal(service.Egress); err != nil {
+				return nil, fmt.Errorf("unable to marshal egress policy for service %v: %v", serviceName, err)
+			} else {
+				serviceConfig.Config.Labels[LABEL_EGRESS_POLICY] = string(policy)
+			}
+		}
+
 		// Mark each container as infrastructure if the deployment description indicates infrastructure
 		if deployment.Infrastructure {
 			serviceConfig.Config.Labels[LABEL_PREFIX+".infrastructure"] = ""
@@ -393,6 +449,9 @@ type ContainerWorker struct {
 	iptables          *iptables.IPTables
 	authMgr           *resource.AuthenticationManager
 	pattern           string
+	health            *healthMonitor
+	egress            map[string]*containerEgress
+	isolationLock     sync.Mutex // the egress refresh changes the isolation chain from its own goroutine
 }
 
 func (cw *ContainerWorker) GetClient() *docker.Client {
@@ -417,6 +476,8 @@ func CreateCLIContainerWorker(config *config.HorizonConfig) (*ContainerWorker, e
 		iptables:   nil,
 		authMgr:    resource.NewAuthenticationManager(config.GetFileSyncServiceAuthPath()),
 		pattern:    "",
+		health:     newHealthMonitor(),
+		egress:     make(map[string]*containerEgress),
 	}, nil
 }
 
@@ -451,6 +512,8 @@ func NewContainerWorker(name string, config *config.HorizonConfig, db *bolt.DB,
 			iptables:   ipt,
 			authMgr:    am,
 			pattern:    pattern,
+			health:     newHealthMonitor(),
+			egress:     make(map[string]*containerEgress),
 		}
 		worker.SetDeferredDelay(15)
 
@@ -488,6 +551,15 @@ func (w *ContainerWorker) NewEvent(incoming events.Message) {
 			}
 		}
 
+	case *events.ConfigReloadMessage:
+		msg, _ := incoming.(*events.ConfigReloadMessage)
+
+		switch msg.Event().Id {
+		case events.CONFIG_RELOAD:
+			containerCmd := w.NewConfigReloadCommand(msg.AgreementProtocol, msg.AgreementId, msg.UserInput)
+			w.Commands <- containerCmd
+		}
+
 	case *events.GovernanceMaintenanceMessage:
 		msg, _ := incoming.(*events.GovernanceMaintenanceMessage)
 
@@ -533,6 +605,15 @@ func (w *ContainerWorker) NewEvent(incoming events.Message) {
 			w.Commands <- containerCmd
 		}
 
+	case *events.NodeUserInputMessage:
+		msg, _ := incoming.(*events.NodeUserInputMessage)
+
+		switch msg.Event().Id {
+		case events.UPDATE_NODE_SECRETS:
+			containerCmd := w.NewRotateSecretsCommand(msg.ServiceSpecs)
+			w.Commands <- containerCmd
+		}
+
 	case *events.NodeShutdownCompleteMessage:
 		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
 		switch msg.Event().Id {
@@ -919,6 +1000,17 @@ func processPostCreate(ipt *iptables.IPTables, client *docker.Client, agreementI
 						}
 					}
 				}
+
+				if egress := deployment.Services[serviceName].Egress; egress != nil && ipt != nil {
+					if err := applyEgressPolicy(ipt, egress, conDetail, comment, resolveHost); err != nil {
+						return fail(nil, serviceName, fmt.Errorf("Unable to create egress rules for service. Error: %v", err))
+					}
+					if egress.DenyByDefault() {
+						for address, _ := range containerSubnets(conDetail) {
+							newContainerIPs = append(newContainerIPs, address)
+						}
+					}
+				}
 			}
 
 			// no need to handle case of new shared container needing rules to permit traffic from existing agreements, that case is unsupported
@@ -970,6 +1062,7 @@ func (b *ContainerWorker) ResourcesCreate(agreementId string, agreementProtocol
 
 	// local helpers
 	fail := func(container *docker.Container, name string, err error) error {
+		containerStartFailures.Inc()
 		if container != nil {
 			glog.Errorf("Error processing container setup: %v", container)
 		}
@@ -1065,6 +1158,17 @@ func (b *ContainerWorker) ResourcesCreate(agreementId string, agreementProtocol
 	}
 
 	for serviceName, servicePair := range servicePairs {
+		// An image pinned by digest that was pulled from a registry mirror keeps the name of the mirror.
+		if _, err := b.client.InspectImage(servicePair.serviceConfig.Config.Image); err == docker.ErrNoSuchImage {
+			for _, mirrored := range cutil.MirrorImagePaths(&b.Config.Edge.RegistryMirrors, servicePair.serviceConfig.Config.Image) {
+				if _, err := b.client.InspectImage(mirrored); err == nil {
+					glog.V(3).Infof("Using image %v from registry mirror for %v", mirrored, servicePair.serviceConfig.Config.Image)
+					servicePair.serviceConfig.Config.Image = mirrored
+					break
+				}
+			}
+		}
+
 		if image, err := b.client.InspectImage(servicePair.serviceConfig.Config.Image); err != nil {
 			return nil, fail(nil, serviceName, fmt.Errorf("Failed to inspect image: %v. Original error: %v", servicePair.serviceConfig.Config.Image, err))
 		} else if image == nil {
@@ -1198,7 +1302,10 @@ func (b *ContainerWorker) ResourcesCreate(agreementId string, agreementProtocol
 	// check environmentAdditions for MTN_ETHEREUM_ACCOUNT
 	_, hasSpecifiedEthAccount := environmentAdditions[config.ENVVAR_PREFIX+"ETHEREUM_ACCOUNT"]
 
-	if err := processPostCreate(b.iptables, b.client, agreementId, *deployment, configureRaw, hasSpecifiedEthAccount, postCreateContainers, fail); err != nil {
+	b.isolationLock.Lock()
+	err = processPostCreate(b.iptables, b.client, agreementId, *deployment, configureRaw, hasSpecifiedEthAccount, postCreateContainers, fail)
+	b.isolationLock.Unlock()
+	if err != nil {
 		return nil, err
 	}
 
@@ -1210,6 +1317,8 @@ func (b *ContainerWorker) ResourcesCreate(agreementId string, agreementProtocol
 
 func (b *ContainerWorker) Initialize() bool {
 	b.syncupResources()
+	b.DispatchSubworker(CONTAINER_HEALTH_MONITOR, b.monitorHealth, HEALTH_MONITOR_INTERVAL_S)
+	b.DispatchSubworker(CONTAINER_EGRESS_REFRESH, b.refreshEgress, EGRESS_REFRESH_INTERVAL_S)
 	return true
 }
 
@@ -1294,6 +1403,34 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 				deploymentDesc.Services[serviceName].AddFilesystemBinding(fmt.Sprintf("%v:%v:rw", dir, "/service_config"))
 			}
 
+			// Write the secret user inputs of the service into files and mount them read only into the containers.
+			if dir, err := b.createServiceSecrets(agreementId, ags[0].RunningWorkload.URL, ags[0].RunningWorkload.Org, ags[0].RunningWorkload.Version); err != nil {
+				eventlog.LogAgreementEvent(b.db, persistence.SEVERITY_ERROR, fmt.Sprintf("Error writing the secrets for agreement %v: %v", agreementId, err), persistence.EC_ERROR_SERVICE_SECRETS, ags[0])
+				glog.Errorf("Error writing the secrets for agreement %v: %v", agreementId, err)
+				b.Messages() <- events.NewWorkloadMessage(events.EXECUTION_FAILED, cmd.AgreementLaunchContext.AgreementProtocol, agreementId, nil)
+				return true
+			} else {
+				for serviceName := range deploymentDesc.Services {
+					deploymentDesc.Services[serviceName].AddFilesystemBinding(secretsBinding(dir))
+				}
+			}
+
+			// Write the node user input into a file for the services that reload their configuration.
+			if reloadsConfig(deploymentDesc) {
+				if dir, _, err := b.createServiceUserInput(agreementId, cmd.AgreementLaunchContext.UserInput); err != nil {
+					eventlog.LogAgreementEvent(b.db, persistence.SEVERITY_ERROR, fmt.Sprintf("Error writing the user input for agreement %v: %v", agreementId, err), persistence.EC_ERROR_SERVICE_CONFIG_RELOAD, ags[0])
+					glog.Errorf("Error writing the user input for agreement %v: %v", agreementId, err)
+					b.Messages() <- events.NewWorkloadMessage(events.EXECUTION_FAILED, cmd.AgreementLaunchContext.AgreementProtocol, agreementId, nil)
+					return true
+				} else {
+					for serviceName, service := range deploymentDesc.Services {
+						if service.ConfigReload != nil {
+							deploymentDesc.Services[serviceName].AddFilesystemBinding(userInputBinding(dir))
+						}
+					}
+				}
+			}
+
 			// Each service has an identity that is based on its service defintion URL and Org. This identity is what we can use to
 			// authenticate a service to an API that is hosted by Anax.
 			serviceIdentity := cutil.FormOrgSpecUrl(cutil.NormalizeURL(ags[0].RunningWorkload.URL), ags[0].RunningWorkload.Org)
@@ -1422,6 +1559,23 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 			}
 		}
 
+		// Write the secret user inputs of the service into files and mount them read only into the containers.
+		if lc.Blockchain.Name == "" {
+			if dir, err := b.createServiceSecrets(lc.Name, lc.ServicePathElement.URL, lc.ServicePathElement.Org, lc.ServicePathElement.Version); err != nil {
+				eventlog.LogServiceEvent2(b.db, persistence.SEVERITY_ERROR,
+					fmt.Sprintf("Error writing the secrets for service %v: %v", lc.Name, err),
+					persistence.EC_ERROR_SERVICE_SECRETS,
+					"", lc.ServicePathElement.URL, "", lc.ServicePathElement.Version, "", lc.AgreementIds)
+				glog.Errorf("Error writing the secrets for service %v: %v", lc.Name, err)
+				b.Messages() <- events.NewContainerMessage(events.EXECUTION_FAILED, *cmd.ContainerLaunchContext, "", "")
+				return true
+			} else {
+				for serviceName := range deploymentDesc.Services {
+					deploymentDesc.Services[serviceName].AddFilesystemBinding(secretsBinding(dir))
+				}
+			}
+		}
+
 		// Indicate that this deployment description is part of the infrastructure
 		deploymentDesc.Infrastructure = true
 
@@ -1499,13 +1653,18 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 
 			b.ContainersMatchingAgreement([]string{cmd.AgreementId}, true, report)
 
-			if len(serviceNames) == len(cMatches) {
-				glog.V(4).Infof("Found expected count of running containers for agreement %v: %v", cmd.AgreementId, len(cMatches))
-			} else {
+			if len(serviceNames) != len(cMatches) {
 				glog.Errorf("Insufficient running containers found for agreement %v. Found: %v", cmd.AgreementId, cMatches)
 
 				// ask governer to cancel the agreement
 				b.Messages() <- events.NewWorkloadMessage(events.EXECUTION_FAILED, cmd.AgreementProtocol, cmd.AgreementId, cmd.Deployment)
+			} else if unhealthy := b.unhealthyContainers(cMatches); len(unhealthy) != 0 {
+				glog.Errorf("Unhealthy containers found for agreement %v: %v", cmd.AgreementId, unhealthy)
+
+				// ask governer to cancel the agreement
+				b.Messages() <- events.NewWorkloadMessage(events.CONTAINER_UNHEALTHY, cmd.AgreementProtocol, cmd.AgreementId, cmd.Deployment)
+			} else {
+				glog.V(4).Infof("Found expected count of running containers for agreement %v: %v", cmd.AgreementId, len(cMatches))
 			}
 		}
 
@@ -1575,15 +1734,22 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 
 			b.ContainersMatchingAgreement([]string{cmd.MsInstKey}, true, report)
 
-			if len(serviceNames) == len(cMatches) {
-				glog.V(4).Infof("Found expected count of running containers for service instance %v: %v", cmd.MsInstKey, len(cMatches))
-			} else {
+			if len(serviceNames) != len(cMatches) {
 				glog.Errorf("Insufficient running containers found for service instance %v. Found: %v", cmd.MsInstKey, cMatches)
 
 				// ask governer to record it into the db
 				cc := events.NewContainerConfig("", "", "", "", nil)
 				ll := events.NewContainerLaunchContext(cc, nil, events.BlockchainConfig{}, cmd.MsInstKey, []string{}, []events.MicroserviceSpec{}, persistence.NewServiceInstancePathElement("", "", ""), false)
 				b.Messages() <- events.NewContainerMessage(events.EXECUTION_FAILED, *ll, "", "")
+			} else if unhealthy := b.unhealthyContainers(cMatches); len(unhealthy) != 0 {
+				glog.Errorf("Unhealthy containers found for service instance %v: %v", cmd.MsInstKey, unhealthy)
+
+				// ask governer to retry the service
+				cc := events.NewContainerConfig("", "", "", "", nil)
+				ll := events.NewContainerLaunchContext(cc, nil, events.BlockchainConfig{}, cmd.MsInstKey, []string{}, []events.MicroserviceSpec{}, persistence.NewServiceInstancePathElement("", "", ""), false)
+				b.Messages() <- events.NewContainerMessage(events.CONTAINER_UNHEALTHY, *ll, "", "")
+			} else {
+				glog.V(4).Infof("Found expected count of running containers for service instance %v: %v", cmd.MsInstKey, len(cMatches))
 			}
 		}
 	case *ShutdownMicroserviceCommand:
@@ -1602,10 +1768,27 @@ func (b *ContainerWorker) CommandHandler(command worker.Command) bool {
 		// send the event to let others know that the microservice clean up has been processed
 		b.Messages() <- events.NewMicroserviceContainersDestroyedMessage(events.CONTAINER_DESTROYED, cmd.MsInstKey)
 
+	case *RotateSecretsCommand:
+		cmd, _ := command.(*RotateSecretsCommand)
+		glog.V(3).Infof("ContainerWorker received rotate secrets command for services %v", cmd.ServiceSpecs)
+		b.rotateServiceSecrets(cmd.ServiceSpecs)
+
+	case *ConfigReloadCommand:
+		cmd, _ := command.(*ConfigReloadCommand)
+		glog.V(3).Infof("ContainerWorker received config reload command for agreement %v", cmd.AgreementId)
+		b.reloadServiceConfig(cmd.AgreementProtocol, cmd.AgreementId, cmd.UserInput)
+
 	case *NodeUnconfigCommand:
 		if err := b.GetAuthenticationManager().RemoveAll(); err != nil {
 			glog.Errorf("Error handling node unconfig command: %v", err)
 		}
+		if err := os.RemoveAll(b.Config.GetServiceSecretsPath()); err != nil {
+			glog.Errorf("Error removing the service secrets: %v", err)
+		}
+		if err := os.RemoveAll(b.Config.GetServiceUserInputPath()); err != nil {
+			glog.Errorf("Error removing the service user input files: %v", err)
+		}
+		b.TerminateSubworkers()
 		b.Commands <- worker.NewTerminateCommand("shutdown")
 
 	default:
@@ -1876,6 +2059,17 @@ func (b *ContainerWorker) ResourcesRemove(agreements []string) error {
 			glog.Errorf("Failed to remove FSS Authentication credential file for %v, error %v", agreementId, err)
 		}
 
+		// Remove the record of the model management objects consumed by the service.
+		if err := persistence.DeleteConsumedObjects(b.db, agreementId); err != nil {
+			glog.Errorf("Failed to remove the consumed objects of %v, error %v", agreementId, err)
+		}
+
+		// Remove the secret user input files.
+		b.removeServiceSecrets(agreementId)
+
+		// Remove the user input file.
+		b.removeServiceUserInput(agreementId)
+
 	}
 
 	// gather agreement networks to free
@@ -1919,12 +2113,15 @@ func (b *ContainerWorker) ResourcesRemove(agreements []string) error {
 
 	// the primary rule
 	if b.iptables != nil {
+		b.isolationLock.Lock()
+		defer b.isolationLock.Unlock()
+
 		if exists, err := b.iptables.Exists("filter", IPT_COLONUS_ISOLATED_CHAIN, "-j", "RETURN"); err != nil {
 			return fmt.Errorf("Unable to interrogate iptables on host. Error: %v", err)
 		} else if !exists {
 			glog.V(3).Infof("Primary redirect rule missing from %v chain. Skipping agreement rule deletion", IPT_COLONUS_ISOLATED_CHAIN)
 		} else {
-			// free iptables rules for this agreement (will hose access to shared too)
+			// free iptables rules for this agreement, including the egress rules of its containers (will hose access to shared too)
 			rules, err := b.iptables.List("filter", IPT_COLONUS_ISOLATED_CHAIN)
 			if err != nil {
 				return fmt.Errorf("Unable to list rules in %v. Error: %v", IPT_COLONUS_ISOLATED_CHAIN, err)
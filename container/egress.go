@@ -0,0 +1,332 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/coreos/go-iptables/iptables"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/containermessage"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The label holding the egress policy of a container, in JSON.
+const LABEL_EGRESS_POLICY = LABEL_PREFIX + ".egress"
+
+const CONTAINER_EGRESS_REFRESH = "ContainerEgressRefresh"
+
+// How often the egress refresh looks for the containers whose host names are due to be resolved again.
+const EGRESS_REFRESH_INTERVAL_S = 30
+
+// How long to wait for a host name to resolve.
+const EGRESS_RESOLVE_TIMEOUT = 10 * time.Second
+
+// The isolation rules of a container's egress policy have the agreement comment followed by the container id, and the
+// rules for a host name also have the host name, so that they can be replaced when it resolves to other addresses.
+func egressComment(comment string, containerId string) string {
+	return fmt.Sprintf("%v,egress=%v", comment, shortId(containerId))
+}
+
+func egressHostComment(comment string, host string) string {
+	return fmt.Sprintf("%v,egress_host=%v", comment, host)
+}
+
+func shortId(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+var ruleCommentRE = regexp.MustCompile(`--comment "?([^"\s]+)"?`)
+
+// Returns the comment of a rule as listed by iptables.
+func ruleComment(rule string) string {
+	if m := ruleCommentRE.FindStringSubmatch(rule); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// Returns the rule spec of a rule as listed by iptables, without the chain, so that the rule can be deleted by its
+// spec. The comment is the only quoted field of the isolation rules.
+func ruleSpec(rule string) []string {
+	spec := make([]string, 0)
+	for i, field := range strings.Fields(rule) {
+		if i < 2 && (field == "-A" || field == IPT_COLONUS_ISOLATED_CHAIN) {
+			continue
+		}
+		spec = append(spec, strings.Trim(field, `"`))
+	}
+	return spec
+}
+
+// Resolve a host name to its IPv4 addresses, sorted. The isolation chain only filters IPv4 traffic.
+func resolveHost(host string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), EGRESS_RESOLVE_TIMEOUT)
+	defer cancel()
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]string, 0, len(addrs))
+	seen := make(map[string]bool)
+	for _, addr := range addrs {
+		if ip4 := addr.IP.To4(); ip4 != nil && !seen[ip4.String()] {
+			ips = append(ips, ip4.String())
+			seen[ip4.String()] = true
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("host %v has no IPv4 address", host)
+	}
+	sort.Strings(ips)
+	return ips, nil
+}
+
+// Returns the isolation rules of an egress policy for a container address, in the order they are inserted at the head
+// of the isolation chain, so the last one is matched first. With the default of deny, the container can reach its own
+// network and answer the connections made to it. The rules for a host name that did not resolve are left out.
+func egressRules(policy *containermessage.EgressPolicy, address string, subnet string, comment string, resolved map[string][]string) [][]string {
+
+	rules := make([][]string, 0)
+	if policy.DenyByDefault() {
+		rules = append(rules,
+			[]string{"-s", address, "-j", "REJECT", "-m", "comment", "--comment", comment},
+			[]string{"-s", address, "-d", subnet, "-j", "ACCEPT", "-m", "comment", "--comment", comment},
+			[]string{"-s", address, "-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT", "-m", "comment", "--comment", comment})
+	}
+
+	for _, r := range policy.Rules {
+		if r.CIDR != "" {
+			rules = append(rules, egressRule(policy, r, address, r.CIDR, comment))
+		}
+	}
+	for _, host := range policy.Hosts() {
+		rules = append(rules, egressHostRules(policy, host, address, resolved[host], comment)...)
+	}
+	return rules
+}
+
+// Returns the isolation rules for the addresses of a host name, one rule for each address so that the rules can be
+// counted.
+func egressHostRules(policy *containermessage.EgressPolicy, host string, address string, ips []string, comment string) [][]string {
+	rules := make([][]string, 0)
+	for _, r := range policy.Rules {
+		if r.Host == host {
+			for _, ip := range ips {
+				rules = append(rules, egressRule(policy, r, address, ip, egressHostComment(comment, host)))
+			}
+		}
+	}
+	return rules
+}
+
+// Returns the isolation rule that accepts, or with the default of allow rejects, the traffic to the destination.
+func egressRule(policy *containermessage.EgressPolicy, r containermessage.EgressRule, address string, destination string, comment string) []string {
+	rule := []string{"-s", address, "-d", destination}
+	if r.Protocol != "" {
+		rule = append(rule, "-p", r.Protocol)
+	}
+	if ports, _ := r.IPTablesPorts(); ports != "" {
+		rule = append(rule, "-m", "multiport", "--dports", ports)
+	}
+
+	action := "ACCEPT"
+	if !policy.DenyByDefault() {
+		action = "REJECT"
+	}
+	return append(rule, "-j", action, "-m", "comment", "--comment", comment)
+}
+
+// Returns the address and subnet of the container on each of its networks.
+func containerSubnets(container *docker.Container) map[string]string {
+	subnets := make(map[string]string)
+	if container.NetworkSettings != nil {
+		for _, network := range container.NetworkSettings.Networks {
+			if network.IPAddress != "" {
+				subnets[network.IPAddress] = fmt.Sprintf("%v/%v", network.IPAddress, network.IPPrefixLen)
+			}
+		}
+	}
+	return subnets
+}
+
+// Create the isolation rules of a new container's egress policy, for each of its networks. A host name that does not
+// resolve is left out until the egress refresh resolves it.
+func applyEgressPolicy(ipt *iptables.IPTables, policy *containermessage.EgressPolicy, container *docker.Container, comment string, resolve func(string) ([]string, error)) error {
+
+	resolved := make(map[string][]string)
+	for _, host := range policy.Hosts() {
+		if ips, err := resolve(host); err != nil {
+			glog.Warningf("Unable to resolve egress host %v for container %v, it is not reachable until it resolves. Error: %v", host, container.Name, err)
+		} else {
+			resolved[host] = ips
+		}
+	}
+
+	for address, subnet := range containerSubnets(container) {
+		glog.Infof("Creating egress rules for address %v on container %v. Policy: %v, resolved: %v", address, container.Name, policy, resolved)
+		for _, rule := range egressRules(policy, address, subnet, egressComment(comment, container.ID), resolved) {
+			if err := ipt.Insert("filter", IPT_COLONUS_ISOLATED_CHAIN, 1, rule...); err != nil {
+				return fmt.Errorf("Unable to create egress rule %v. Error: %v", rule, err)
+			}
+		}
+	}
+	return nil
+}
+
+// The host names of a container's egress policy and the addresses that its rules were last created with. The state is
+// only used by the egress refresh subworker.
+type containerEgress struct {
+	policy      *containermessage.EgressPolicy
+	resolved    map[string][]string // nil until the rules are refreshed once since the agent started
+	nextRefresh time.Time
+}
+
+// Resolve the host names of the egress policies again and replace the rules of the hosts whose addresses changed.
+func (b *ContainerWorker) refreshEgress() int {
+
+	if b.iptables == nil {
+		return 0
+	}
+
+	containers, err := b.client.ListContainers(docker.ListContainersOptions{Filters: map[string][]string{"label": []string{LABEL_EGRESS_POLICY}}})
+	if err != nil {
+		glog.Errorf("Egress refresh unable to list containers, error: %v", err)
+		return 0
+	}
+
+	running := make(map[string]bool)
+	for _, container := range containers {
+		if container.State != "running" {
+			continue
+		}
+		running[container.ID] = true
+
+		ce, ok := b.egress[container.ID]
+		if !ok {
+			policy := new(containermessage.EgressPolicy)
+			if err := json.Unmarshal([]byte(container.Labels[LABEL_EGRESS_POLICY]), policy); err != nil {
+				glog.Errorf("Egress refresh unable to read the egress policy of container %v, error: %v", container.ID, err)
+				continue
+			}
+			ce = &containerEgress{policy: policy}
+			b.egress[container.ID] = ce
+		}
+
+		now := time.Now()
+		if now.Before(ce.nextRefresh) {
+			continue
+		}
+		ce.nextRefresh = now.Add(ce.policy.RefreshInterval())
+
+		hosts := ce.policy.Hosts()
+		if len(hosts) == 0 {
+			continue
+		}
+
+		detail, err := b.client.InspectContainer(container.ID)
+		if err != nil {
+			glog.Errorf("Egress refresh unable to inspect container %v, error: %v", container.ID, err)
+			continue
+		}
+
+		resolved := make(map[string][]string)
+		for _, host := range hosts {
+			ips, err := resolveHost(host)
+			if err != nil {
+				glog.Warningf("Egress refresh unable to resolve host %v for container %v, keeping its rules. Error: %v", host, containerName(&container), err)
+				ips = ce.resolved[host]
+			} else if ce.resolved == nil || !sameAddresses(ce.resolved[host], ips) {
+				if err := b.replaceEgressHostRules(detail, ce.policy, host, ips); err != nil {
+					glog.Errorf("Egress refresh unable to replace the rules of host %v for container %v, error: %v", host, containerName(&container), err)
+					ips = ce.resolved[host]
+					ce.nextRefresh = now
+				}
+			}
+			resolved[host] = ips
+		}
+		ce.resolved = resolved
+	}
+
+	// Forget the containers that are gone or stopped, their rules are removed with the rest of their resources.
+	for id, _ := range b.egress {
+		if !running[id] {
+			delete(b.egress, id)
+		}
+	}
+
+	return 0
+}
+
+// Replace the isolation rules of a host name for a container with rules for its new addresses. The new rules are
+// created before the old ones are deleted, so that the traffic to the host is not interrupted. The old rules are
+// deleted by their rule spec because the rules of other agreements can be added or removed in the meantime.
+func (b *ContainerWorker) replaceEgressHostRules(container *docker.Container, policy *containermessage.EgressPolicy, host string, ips []string) error {
+
+	b.isolationLock.Lock()
+	defer b.isolationLock.Unlock()
+
+	rules, err := b.iptables.List("filter", IPT_COLONUS_ISOLATED_CHAIN)
+	if err != nil {
+		return fmt.Errorf("Unable to list rules in %v. Error: %v", IPT_COLONUS_ISOLATED_CHAIN, err)
+	}
+
+	// The comment of the container's egress rules, without a host name, and the rules of the host.
+	tag := ",egress=" + shortId(container.ID)
+	comment := ""
+	old := make([]string, 0)
+	for _, rule := range rules {
+		c := ruleComment(rule)
+		if i := strings.Index(c, tag); i >= 0 {
+			comment = c[:i+len(tag)]
+			if c == egressHostComment(comment, host) {
+				old = append(old, rule)
+			}
+		}
+	}
+	if comment == "" {
+		glog.Warningf("No egress rules found for container %v, its host rules are not refreshed.", container.Name)
+		return nil
+	}
+
+	for address, _ := range containerSubnets(container) {
+		for _, rule := range egressHostRules(policy, host, address, ips, comment) {
+			if err := b.iptables.Insert("filter", IPT_COLONUS_ISOLATED_CHAIN, 1, rule...); err != nil {
+				return fmt.Errorf("Unable to create egress rule %v. Error: %v", rule, err)
+			}
+		}
+	}
+
+	// The old rule of an address that the host still resolves to is the same as its new rule, deleting it by its spec
+	// leaves one of the two.
+	for _, rule := range old {
+		glog.V(3).Infof("Deleting egress rule: %v", rule)
+		if err := b.iptables.Delete("filter", IPT_COLONUS_ISOLATED_CHAIN, ruleSpec(rule)...); err != nil {
+			return err
+		}
+	}
+
+	glog.Infof("Egress rules of host %v for container %v now apply to addresses %v", host, container.Name, ips)
+	return nil
+}
+
+func sameAddresses(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+// +build unit
+
+package container
+
+import (
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_egressRules_deny(t *testing.T) {
+
+	policy := &containermessage.EgressPolicy{Rules: []containermessage.EgressRule{
+		{Host: "mqtt.example.com", Protocol: "tcp", Ports: []string{"8883"}},
+		{CIDR: "10.20.0.0/16"},
+		{Host: "unresolved.example.com"},
+	}}
+	comment := egressComment("agreement_id=ag1", "0123456789abcdef")
+	resolved := map[string][]string{"mqtt.example.com": []string{"1.2.3.4", "1.2.3.5"}}
+
+	rules := egressRules(policy, "172.17.0.2", "172.17.0.2/16", comment, resolved)
+	assert.Equal(t, 6, len(rules), "There should be the default rules, the cidr rule and a rule for each host address.")
+
+	assert.Equal(t, "-s 172.17.0.2 -j REJECT -m comment --comment agreement_id=ag1,egress=0123456789ab", strings.Join(rules[0], " "), "Everything else should be rejected.")
+	assert.Equal(t, "-s 172.17.0.2 -d 172.17.0.2/16 -j ACCEPT -m comment --comment agreement_id=ag1,egress=0123456789ab", strings.Join(rules[1], " "), "The container's network should be reachable.")
+	assert.Contains(t, strings.Join(rules[2], " "), "--ctstate ESTABLISHED,RELATED -j ACCEPT", "The container should be able to answer connections.")
+	assert.Equal(t, "-s 172.17.0.2 -d 10.20.0.0/16 -j ACCEPT -m comment --comment agreement_id=ag1,egress=0123456789ab", strings.Join(rules[3], " "), "The cidr should be reachable.")
+	assert.Equal(t, "-s 172.17.0.2 -d 1.2.3.4 -p tcp -m multiport --dports 8883 -j ACCEPT -m comment --comment agreement_id=ag1,egress=0123456789ab,egress_host=mqtt.example.com", strings.Join(rules[4], " "), "The host should be reachable on its port.")
+	assert.Equal(t, "1.2.3.5", rules[5][3], "Each address of the host should have a rule.")
+}
+
+func Test_egressRules_allow(t *testing.T) {
+
+	policy := &containermessage.EgressPolicy{Default: containermessage.EGRESS_ALLOW, Rules: []containermessage.EgressRule{{CIDR: "169.254.169.254", Protocol: "tcp"}}}
+
+	rules := egressRules(policy, "172.17.0.2", "172.17.0.2/16", "agreement_id=ag1,egress=c1", nil)
+	assert.Equal(t, 1, len(rules), "Only the rule should be created when the default is allow.")
+	assert.Equal(t, "-s 172.17.0.2 -d 169.254.169.254 -p tcp -j REJECT -m comment --comment agreement_id=ag1,egress=c1", strings.Join(rules[0], " "), "The destination should be rejected.")
+}
+
+func Test_ruleComment(t *testing.T) {
+	assert.Equal(t, "agreement_id=ag1,egress=c1", ruleComment(`-A OPENHORIZON-ANAX-ISOLATION -s 172.17.0.2/32 -m comment --comment "agreement_id=ag1,egress=c1" -j REJECT --reject-with icmp-port-unreachable`), "A quoted comment should be found.")
+	assert.Equal(t, "agreement_id=ag1", ruleComment(`-A OPENHORIZON-ANAX-ISOLATION -s 172.17.0.2/32 -m comment --comment agreement_id=ag1 -j REJECT`), "An unquoted comment should be found.")
+	assert.Equal(t, "", ruleComment(`-A OPENHORIZON-ANAX-ISOLATION -j RETURN`), "A rule without a comment has none.")
+}
+
+func Test_ruleSpec(t *testing.T) {
+	rule := `-A OPENHORIZON-ANAX-ISOLATION -s 172.17.0.2/32 -d 10.1.2.3/32 -p tcp -m multiport --dports 443 -m comment --comment "agreement_id=ag1,egress=c1,egress_host=example.com" -j ACCEPT`
+	assert.Equal(t, []string{"-s", "172.17.0.2/32", "-d", "10.1.2.3/32", "-p", "tcp", "-m", "multiport", "--dports", "443", "-m", "comment", "--comment", "agreement_id=ag1,egress=c1,egress_host=example.com", "-j", "ACCEPT"}, ruleSpec(rule), "The spec should not have the chain or the quotes.")
+}
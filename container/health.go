@@ -0,0 +1,288 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The label holding the health check of a container, in JSON.
+const LABEL_HEALTH_CHECK = LABEL_PREFIX + ".health_check"
+
+const CONTAINER_HEALTH_MONITOR = "ContainerHealthMonitor"
+
+// How often the health monitor looks for the containers that are due for a check.
+const HEALTH_MONITOR_INTERVAL_S = 5
+
+// The health states of a container, the same as docker's.
+const (
+	HEALTH_STARTING  = "starting"
+	HEALTH_HEALTHY   = "healthy"
+	HEALTH_UNHEALTHY = "unhealthy"
+)
+
+// The health of one container with a health check.
+type containerHealth struct {
+	check     *containermessage.HealthCheck
+	status    string
+	failures  int
+	reason    string
+	started   time.Time
+	nextCheck time.Time
+}
+
+func newContainerHealth(check *containermessage.HealthCheck, started time.Time) *containerHealth {
+	return &containerHealth{
+		check:   check,
+		status:  HEALTH_STARTING,
+		started: started,
+	}
+}
+
+// Record the result of a check made by the agent. Returns true when the health status changed. The failures during
+// the start period do not count, unless the container was already healthy.
+func (c *containerHealth) record(checkErr error, now time.Time) bool {
+	old := c.status
+	if checkErr == nil {
+		c.status = HEALTH_HEALTHY
+		c.failures = 0
+		c.reason = ""
+	} else {
+		c.reason = checkErr.Error()
+		if c.status != HEALTH_STARTING || now.Sub(c.started) >= c.check.StartPeriod() {
+			c.failures++
+			if c.failures >= c.check.Threshold() {
+				c.status = HEALTH_UNHEALTHY
+			}
+		}
+	}
+	return c.status != old
+}
+
+// Keeps the health of the containers with a health check, by container id. The health monitor subworker updates it
+// and the maintenance commands read it.
+type healthMonitor struct {
+	lock       sync.Mutex
+	containers map[string]*containerHealth
+}
+
+func newHealthMonitor() *healthMonitor {
+	return &healthMonitor{
+		containers: make(map[string]*containerHealth),
+	}
+}
+
+// Returns true if the container has a health check and is unhealthy.
+func (m *healthMonitor) Unhealthy(id string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	ch, ok := m.containers[id]
+	return ok && ch.status == HEALTH_UNHEALTHY
+}
+
+// Returns the names of the containers that are unhealthy.
+func (b *ContainerWorker) unhealthyContainers(containers []docker.APIContainers) []string {
+	unhealthy := make([]string, 0)
+	for _, container := range containers {
+		if b.health.Unhealthy(container.ID) {
+			unhealthy = append(unhealthy, strings.Join(container.Names, ","))
+		}
+	}
+	return unhealthy
+}
+
+// Check the health of the containers that have a health check and report the changes. The command checks are run by
+// docker, the monitor only picks up their result. The HTTP and TCP checks are made here.
+func (b *ContainerWorker) monitorHealth() int {
+
+	containers, err := b.client.ListContainers(docker.ListContainersOptions{Filters: map[string][]string{"label": []string{LABEL_HEALTH_CHECK}}})
+	if err != nil {
+		glog.Errorf("Health monitor unable to list containers, error: %v", err)
+		return 0
+	}
+
+	running := make(map[string]bool)
+	for _, container := range containers {
+		if container.State != "running" {
+			continue
+		}
+		running[container.ID] = true
+
+		b.health.lock.Lock()
+		ch, ok := b.health.containers[container.ID]
+		b.health.lock.Unlock()
+
+		if !ok {
+			check := new(containermessage.HealthCheck)
+			if err := json.Unmarshal([]byte(container.Labels[LABEL_HEALTH_CHECK]), check); err != nil {
+				glog.Errorf("Health monitor unable to read the health check of container %v, error: %v", container.ID, err)
+				continue
+			}
+			ch = newContainerHealth(check, time.Now())
+			b.health.lock.Lock()
+			b.health.containers[container.ID] = ch
+			b.health.lock.Unlock()
+		}
+
+		now := time.Now()
+		if now.Before(ch.nextCheck) {
+			continue
+		}
+		ch.nextCheck = now.Add(ch.check.Interval())
+
+		detail, err := b.client.InspectContainer(container.ID)
+		if err != nil {
+			glog.Errorf("Health monitor unable to inspect container %v, error: %v", container.ID, err)
+			continue
+		}
+
+		var changed bool
+		if ch.check.DockerHealthConfig() != nil {
+			changed = b.health.recordDocker(ch, &detail.State.Health)
+		} else {
+			checkErr := probeContainer(ch.check, containerAddress(detail), b.Config.Collaborators.HTTPClientFactory.NewHTTPClient)
+			b.health.lock.Lock()
+			changed = ch.record(checkErr, time.Now())
+			b.health.lock.Unlock()
+		}
+
+		if changed {
+			b.reportHealth(&container, ch)
+		}
+	}
+
+	// Forget the containers that are gone or stopped, a restarted container starts over.
+	b.health.lock.Lock()
+	for id, _ := range b.health.containers {
+		if !running[id] {
+			delete(b.health.containers, id)
+		}
+	}
+	b.health.lock.Unlock()
+
+	return 0
+}
+
+// Record the result of a command health check from docker. Returns true when the health status changed.
+func (m *healthMonitor) recordDocker(ch *containerHealth, health *docker.Health) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if health.Status == "" || health.Status == ch.status {
+		return false
+	}
+	ch.status = health.Status
+	ch.failures = health.FailingStreak
+	ch.reason = ""
+	if len(health.Log) != 0 && health.Log[len(health.Log)-1].ExitCode != 0 {
+		ch.reason = strings.TrimSpace(health.Log[len(health.Log)-1].Output)
+	}
+	return true
+}
+
+// Log the change of health as an event log and tell the other workers about it.
+func (b *ContainerWorker) reportHealth(container *docker.APIContainers, ch *containerHealth) {
+
+	containerName := containerName(container)
+	serviceName := container.Labels[LABEL_PREFIX+".service_name"]
+	owner := container.Labels[LABEL_PREFIX+".agreement_id"]
+
+	switch ch.status {
+	case HEALTH_UNHEALTHY:
+		msg := fmt.Sprintf("Service container %v for %v is unhealthy after %v failed health checks: %v", containerName, serviceName, ch.failures, ch.reason)
+		glog.Error(msg)
+		b.logHealthEvent(persistence.SEVERITY_ERROR, msg, persistence.EC_CONTAINER_UNHEALTHY, owner)
+		b.Messages() <- events.NewContainerHealthMessage(events.CONTAINER_UNHEALTHY, containerName, serviceName, owner, ch.reason)
+	case HEALTH_HEALTHY:
+		msg := fmt.Sprintf("Service container %v for %v is healthy.", containerName, serviceName)
+		glog.Info(msg)
+		b.logHealthEvent(persistence.SEVERITY_INFO, msg, persistence.EC_CONTAINER_HEALTHY, owner)
+		b.Messages() <- events.NewContainerHealthMessage(events.CONTAINER_HEALTHY, containerName, serviceName, owner, "")
+	}
+}
+
+// Log a health event against the agreement or service instance that owns the container. A shared container has
+// no owner.
+func (b *ContainerWorker) logHealthEvent(severity string, message string, eventCode string, owner string) {
+	if owner != "" {
+		if ags, err := persistence.FindEstablishedAgreementsAllProtocols(b.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(owner)}); err == nil && len(ags) == 1 {
+			eventlog.LogAgreementEvent(b.db, severity, message, eventCode, ags[0])
+			return
+		} else if msinst, err := persistence.FindMicroserviceInstanceWithKey(b.db, owner); err == nil && msinst != nil {
+			eventlog.LogServiceEvent(b.db, severity, message, eventCode, *msinst)
+			return
+		}
+	}
+	eventlog.LogServiceEvent2(b.db, severity, message, eventCode, "", "", "", "", "", []string{})
+}
+
+// Returns the IP address of the container on the first of its networks, by name.
+func containerAddress(container *docker.Container) string {
+	if container.NetworkSettings == nil {
+		return ""
+	}
+
+	names := make([]string, 0, len(container.NetworkSettings.Networks))
+	for name, network := range container.NetworkSettings.Networks {
+		if network.IPAddress != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return container.NetworkSettings.IPAddress
+	}
+	sort.Strings(names)
+	return container.NetworkSettings.Networks[names[0]].IPAddress
+}
+
+// Make an HTTP or TCP health check against the container's address. An HTTP check passes when the status code is
+// from 200 to 399.
+func probeContainer(check *containermessage.HealthCheck, address string, newHTTPClient func(*uint) *http.Client) error {
+	if address == "" {
+		return fmt.Errorf("the container has no IP address")
+	}
+
+	timeout := check.Timeout()
+	if check.HTTPGet != nil {
+		path := check.HTTPGet.Path
+		if path == "" {
+			path = "/"
+		}
+		url := fmt.Sprintf("http://%v%v", net.JoinHostPort(address, strconv.Itoa(check.HTTPGet.Port)), path)
+
+		timeoutS := uint(timeout / time.Second)
+		client := newHTTPClient(&timeoutS)
+		resp, err := client.Get(url)
+		if err != nil {
+			return fmt.Errorf("GET %v failed: %v", url, err)
+		}
+		defer resp.Body.Close()
+		ioutil.ReadAll(resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode > 399 {
+			return fmt.Errorf("GET %v returned HTTP code %v", url, resp.StatusCode)
+		}
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(address, strconv.Itoa(check.TCPPort)), timeout)
+	if err != nil {
+		return fmt.Errorf("unable to connect to port %v: %v", check.TCPPort, err)
+	}
+	conn.Close()
+	return nil
+}
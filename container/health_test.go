@@ -0,0 +1,98 @@
+// +build unit
+
+package container
+
+import (
+	"errors"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_containerHealth_record(t *testing.T) {
+
+	start := time.Now()
+	check := &containermessage.HealthCheck{TCPPort: 80, StartPeriodS: 60, FailureThreshold: 2}
+	ch := newContainerHealth(check, start)
+	failed := errors.New("connection refused")
+
+	// failures during the start period do not count
+	for i := 0; i < 5; i++ {
+		assert.False(t, ch.record(failed, start.Add(10*time.Second)), "The container should still be starting.")
+	}
+	assert.Equal(t, HEALTH_STARTING, ch.status, "The container should still be starting.")
+
+	assert.True(t, ch.record(nil, start.Add(20*time.Second)), "A passed check should make the container healthy.")
+	assert.Equal(t, HEALTH_HEALTHY, ch.status, "The container should be healthy.")
+
+	// once healthy, the start period no longer applies
+	assert.False(t, ch.record(failed, start.Add(30*time.Second)), "One failure is under the threshold.")
+	assert.True(t, ch.record(failed, start.Add(40*time.Second)), "The threshold of failures should make the container unhealthy.")
+	assert.Equal(t, HEALTH_UNHEALTHY, ch.status, "The container should be unhealthy.")
+	assert.Equal(t, "connection refused", ch.reason, "The reason should be the last failure.")
+
+	assert.True(t, ch.record(nil, start.Add(50*time.Second)), "The container should recover.")
+	assert.Equal(t, 0, ch.failures, "The failures should be reset.")
+}
+
+func Test_healthMonitor_recordDocker(t *testing.T) {
+
+	m := newHealthMonitor()
+	ch := newContainerHealth(&containermessage.HealthCheck{Command: []string{"false"}}, time.Now())
+	m.containers["c1"] = ch
+
+	assert.False(t, m.recordDocker(ch, &docker.Health{Status: "starting"}), "No change while starting.")
+	assert.True(t, m.recordDocker(ch, &docker.Health{Status: "unhealthy", FailingStreak: 3, Log: []docker.HealthCheck{{ExitCode: 1, Output: "not ready\n"}}}), "The change should be recorded.")
+	assert.True(t, m.Unhealthy("c1"), "The container should be unhealthy.")
+	assert.Equal(t, "not ready", ch.reason, "The reason should be the output of the last check.")
+	assert.False(t, m.Unhealthy("c2"), "A container without a health check is never unhealthy.")
+}
+
+func Test_probeContainer(t *testing.T) {
+
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	host, p, _ := net.SplitHostPort(u.Host)
+	port, _ := strconv.Atoi(p)
+	newClient := func(timeoutS *uint) *http.Client { return &http.Client{Timeout: time.Duration(*timeoutS) * time.Second} }
+
+	check := &containermessage.HealthCheck{HTTPGet: &containermessage.HTTPGetCheck{Port: port, Path: "/health"}}
+	assert.Nil(t, probeContainer(check, host, newClient), "The HTTP check should pass.")
+
+	status = http.StatusServiceUnavailable
+	assert.NotNil(t, probeContainer(check, host, newClient), "The HTTP check should fail on a 503.")
+
+	check = &containermessage.HealthCheck{TCPPort: port, TimeoutS: 1}
+	assert.Nil(t, probeContainer(check, host, newClient), "The TCP check should pass.")
+
+	server.Close()
+	assert.NotNil(t, probeContainer(check, host, newClient), "The TCP check should fail when nothing listens.")
+	assert.NotNil(t, probeContainer(check, "", newClient), "A container without an address should fail.")
+}
+
+func Test_containerAddress(t *testing.T) {
+
+	c := &docker.Container{NetworkSettings: &docker.NetworkSettings{Networks: map[string]docker.ContainerNetwork{
+		"b-net": {IPAddress: "10.0.0.2"},
+		"a-net": {IPAddress: "10.0.0.1"},
+		"c-net": {},
+	}}}
+	assert.Equal(t, "10.0.0.1", containerAddress(c), "The address on the first network should be used.")
+	assert.Equal(t, "", containerAddress(&docker.Container{}), "A container without network settings has no address.")
+}
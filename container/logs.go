@@ -0,0 +1,212 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/boltdb/bolt"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/worker"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Create a container worker that is not started, so that the API can find the containers of a service and read
+// their logs.
+func CreateAPIContainerWorker(config *config.HorizonConfig, db *bolt.DB) (*ContainerWorker, error) {
+	client, err := docker.NewClient(config.Edge.DockerEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ContainerWorker{
+		BaseWorker: worker.NewBaseWorker("api", config, nil),
+		db:         db,
+		client:     client,
+		health:     newHealthMonitor(),
+	}, nil
+}
+
+// Returns the containers of a service, found through the agreements that run it as the top level service and the
+// service instances that run it as a dependent service. An empty org matches the service in any org. The containers
+// are sorted by name.
+func (b *ContainerWorker) FindServiceContainers(url string, org string) ([]docker.APIContainers, error) {
+
+	found := make(map[string]docker.APIContainers)
+	collect := func(serviceNames []string) func(*docker.APIContainers, string) error {
+		return func(container *docker.APIContainers, owner string) error {
+			for _, name := range serviceNames {
+				if container.Labels[LABEL_PREFIX+".service_name"] == name {
+					found[container.ID] = *container
+				}
+			}
+			return nil
+		}
+	}
+
+	agreements, err := persistence.FindEstablishedAgreementsAllProtocols(b.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read agreements, error %v", err)
+	}
+	for _, ag := range agreements {
+		if ag.AgreementTerminatedTime != 0 || ag.RunningWorkload.URL != url || (org != "" && ag.RunningWorkload.Org != org) {
+			continue
+		}
+		if deployment := ag.GetDeploymentConfig(); deployment != nil && deployment.IsNative() {
+			nd := deployment.(*persistence.NativeDeploymentConfig)
+			b.ContainersMatchingAgreement([]string{ag.CurrentAgreementId}, true, collect(persistence.ServiceConfigNames(&nd.Services)))
+		}
+	}
+
+	msinsts, err := persistence.FindMicroserviceInstances(b.db, []persistence.MIFilter{persistence.UnarchivedMIFilter(), persistence.NotCleanedUpMIFilter()})
+	if err != nil {
+		return nil, fmt.Errorf("unable to read service instances, error %v", err)
+	}
+	for _, msinst := range msinsts {
+		if msinst.SpecRef != url || (org != "" && msinst.Org != org) {
+			continue
+		}
+		if serviceNames, err := b.findMicroserviceDefContainerNames(msinst.SpecRef, msinst.Org, msinst.Version, msinst.MicroserviceDefId); err != nil {
+			return nil, err
+		} else if len(serviceNames) != 0 {
+			b.ContainersMatchingAgreement([]string{msinst.GetKey()}, true, collect(serviceNames))
+		}
+	}
+
+	containers := make([]docker.APIContainers, 0, len(found))
+	for _, container := range found {
+		containers = append(containers, container)
+	}
+	sort.Slice(containers, func(i, j int) bool { return containerName(&containers[i]) < containerName(&containers[j]) })
+
+	glog.V(5).Infof("The containers for service %v are: %v", cutil.FormOrgSpecUrl(url, org), containers)
+	return containers, nil
+}
+
+// Copy the stdout and stderr of the containers to the writer, from the given number of lines back, or all of them if
+// tail is "all". When there is more than one container, each line starts with the name of its container. With follow,
+// the logs are copied as they are written until the context is done or all the containers stop. A container whose logs
+// cannot be read gets an error line telling where to find them, the other containers are not affected.
+func (b *ContainerWorker) CopyContainerLogs(ctx context.Context, containers []docker.APIContainers, out io.Writer, tail string, follow bool) {
+
+	lock := new(sync.Mutex)
+	var wg sync.WaitGroup
+
+	for i := range containers {
+		container := &containers[i]
+		prefix := ""
+		if len(containers) > 1 {
+			prefix = containerName(container) + " | "
+		}
+		stdout := &logLineWriter{lock: lock, out: out, prefix: prefix}
+		stderr := &logLineWriter{lock: lock, out: out, prefix: prefix}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := b.client.Logs(docker.LogsOptions{
+				Context:      ctx,
+				Container:    container.ID,
+				OutputStream: stdout,
+				ErrorStream:  stderr,
+				Stdout:       true,
+				Stderr:       true,
+				Follow:       follow,
+				Tail:         tail,
+			})
+			stdout.Close()
+			stderr.Close()
+
+			if err != nil && ctx.Err() == nil {
+				glog.Errorf("Unable to read the logs of container %v, error: %v", containerName(container), err)
+				stderr.Write([]byte(b.logsError(container, err) + "\n"))
+				stderr.Close()
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// Explain why the logs of a container could not be read. The service containers log to syslog, which docker can only
+// read back with dual logging, so when docker fails the error tells where to find the logs of the container.
+func (b *ContainerWorker) logsError(container *docker.APIContainers, err error) string {
+	if detail, ierr := b.client.InspectContainer(container.ID); ierr == nil {
+		return logDriverError(containerName(container), detail.HostConfig, err)
+	}
+	return fmt.Sprintf("Unable to read the logs of container %v: %v", containerName(container), err)
+}
+
+// The error line for a container whose logs docker failed to read, with its log driver and the tag of its log entries
+// when it has one.
+func logDriverError(name string, hostConfig *docker.HostConfig, err error) string {
+	if hostConfig == nil || hostConfig.LogConfig.Type == "" {
+		return fmt.Sprintf("Unable to read the logs of container %v: %v", name, err)
+	} else if tag := hostConfig.LogConfig.Config["tag"]; tag != "" {
+		return fmt.Sprintf("Unable to read the logs of container %v from its %v log driver: %v. Look for the tag %v in the host's %v.", name, hostConfig.LogConfig.Type, err, tag, hostConfig.LogConfig.Type)
+	}
+	return fmt.Sprintf("Unable to read the logs of container %v from its %v log driver: %v", name, hostConfig.LogConfig.Type, err)
+}
+
+func containerName(container *docker.APIContainers) string {
+	if len(container.Names) == 0 {
+		return container.ID
+	}
+	return strings.TrimPrefix(container.Names[0], "/")
+}
+
+// Writes whole lines to a writer shared with other containers' logs, each starting with the prefix, and flushes
+// them to the client if the writer can be flushed.
+type logLineWriter struct {
+	lock   *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (l *logLineWriter) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if err := l.writeLine(l.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Write the last line, if it did not end with a new line.
+func (l *logLineWriter) Close() error {
+	if len(l.buf) == 0 {
+		return nil
+	}
+	line := append(l.buf, '\n')
+	l.buf = nil
+	return l.writeLine(line)
+}
+
+func (l *logLineWriter) writeLine(line []byte) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if _, err := io.WriteString(l.out, l.prefix); err != nil {
+		return err
+	} else if _, err := l.out.Write(line); err != nil {
+		return err
+	}
+	if f, ok := l.out.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// +build unit
+
+package container
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func Test_logLineWriter(t *testing.T) {
+
+	out := new(bytes.Buffer)
+	lock := new(sync.Mutex)
+	w1 := &logLineWriter{lock: lock, out: out, prefix: "web | "}
+	w2 := &logLineWriter{lock: lock, out: out, prefix: "db | "}
+
+	w1.Write([]byte("first li"))
+	w2.Write([]byte("ready\nlistening\n"))
+	assert.Equal(t, "db | ready\ndb | listening\n", out.String(), "Only whole lines should be written.")
+
+	w1.Write([]byte("ne\nsecond"))
+	w1.Close()
+	assert.Equal(t, "db | ready\ndb | listening\nweb | first line\nweb | second\n", out.String(), "The last partial line should be written on close.")
+
+	w1.Close()
+	assert.Equal(t, "db | ready\ndb | listening\nweb | first line\nweb | second\n", out.String(), "Closing again should not write anything.")
+}
+
+func Test_containerName(t *testing.T) {
+	assert.Equal(t, "abc-web", containerName(&docker.APIContainers{ID: "123", Names: []string{"/abc-web"}}), "The leading slash should be removed.")
+	assert.Equal(t, "123", containerName(&docker.APIContainers{ID: "123"}), "A container without a name should use its id.")
+}
+
+// The service containers log to syslog, docker reads their logs back with dual logging and otherwise fails.
+func Test_CopyContainerLogs_syslog(t *testing.T) {
+
+	dualLogging := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/logs") && dualLogging:
+			// the logs of a container without a tty are multiplexed, each frame has an 8 byte header
+			line := "Running netspeed test\n"
+			header := []byte{1, 0, 0, 0, 0, 0, 0, byte(len(line))}
+			w.Write(append(header, line...))
+		case strings.HasSuffix(r.URL.Path, "/logs"):
+			http.Error(w, "configured logging driver does not support reading", http.StatusNotImplemented)
+		case strings.HasSuffix(r.URL.Path, "/json"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"Id":"123","HostConfig":{"LogConfig":{"Type":"syslog","Config":{"tag":"workload-abc_web"}}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client, err := docker.NewClient(server.URL)
+	assert.Nil(t, err)
+	b := &ContainerWorker{client: client}
+	containers := []docker.APIContainers{{ID: "123", Names: []string{"/abc-web"}}}
+
+	out := new(bytes.Buffer)
+	b.CopyContainerLogs(context.Background(), containers, out, "all", false)
+	assert.Equal(t, "Running netspeed test\n", out.String(), "The syslog logs should be read with dual logging.")
+
+	dualLogging = false
+	out.Reset()
+	b.CopyContainerLogs(context.Background(), containers, out, "all", false)
+	assert.Contains(t, out.String(), "Unable to read the logs of container abc-web from its syslog log driver", "The log driver should be reported when docker cannot read the logs.")
+	assert.Contains(t, out.String(), "Look for the tag workload-abc_web in the host's syslog.", "The tag of the log entries should be reported.")
+}
+
+func Test_logDriverError(t *testing.T) {
+	err := errors.New("API error (501): configured logging driver does not support reading")
+
+	assert.Equal(t, "Unable to read the logs of container abc-web: "+err.Error(), logDriverError("abc-web", nil, err), "A container without a host config has no log driver to report.")
+	assert.Equal(t, "Unable to read the logs of container abc-web from its none log driver: "+err.Error(), logDriverError("abc-web", &docker.HostConfig{LogConfig: docker.LogConfig{Type: "none"}}, err))
+	assert.Equal(t, "Unable to read the logs of container abc-web from its syslog log driver: "+err.Error()+". Look for the tag workload-abc_web in the host's syslog.",
+		logDriverError("abc-web", &docker.HostConfig{LogConfig: docker.LogConfig{Type: "syslog", Config: map[string]string{"tag": "workload-abc_web"}}}, err))
+}
@@ -0,0 +1,113 @@
+package container
+
+import (
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/containermessage"
+	"math"
+	"sort"
+)
+
+// The CPU period used with the CPU quota, the docker default of 100ms.
+const CPU_PERIOD = 100000
+
+const MB = 1024 * 1024
+
+// Apply the resource limits from the service's deployment to the container's host config, within the ceilings of the
+// node. When a limit is over its ceiling, the deployment is refused or the limit is lowered to the ceiling, as
+// configured. A limit that the deployment does not set gets the ceiling, if there is one. The host config is expected
+// to already have the node's default memory limit.
+func applyResourceLimits(hostConfig *docker.HostConfig, serviceName string, limits *containermessage.ResourceLimits, ceilings config.ContainerLimitsConfig) error {
+
+	if limits == nil {
+		limits = new(containermessage.ResourceLimits)
+	} else if err := limits.Validate(); err != nil {
+		return fmt.Errorf("invalid resource limits for service %v: %v", serviceName, err)
+	}
+
+	clamp := ceilings.ExceedAction == config.CONTAINER_LIMITS_CLAMP
+
+	// The default memory limit is lowered to the ceiling without complaint, since the deployment did not ask for it.
+	if limits.MemoryMB != 0 {
+		if mem, err := withinCeiling(serviceName, "memory_mb", limits.MemoryMB, ceilings.MaxMemoryMB, clamp); err != nil {
+			return err
+		} else {
+			hostConfig.Memory = mem * MB
+		}
+	} else if ceilings.MaxMemoryMB != 0 && (hostConfig.Memory == 0 || hostConfig.Memory > ceilings.MaxMemoryMB*MB) {
+		hostConfig.Memory = ceilings.MaxMemoryMB * MB
+	}
+
+	if shares, err := withinCeiling(serviceName, "cpu_shares", limits.CPUShares, ceilings.MaxCPUShares, clamp); err != nil {
+		return err
+	} else {
+		hostConfig.CPUShares = shares
+	}
+
+	// Compare the CPU quota in thousandths of a CPU, rounded since e.g. 0.58*1000 is just under 580.
+	if milliCPUs, err := withinCeiling(serviceName, "cpus", int64(math.Round(limits.CPUs*1000)), int64(math.Round(ceilings.MaxCPUs*1000)), clamp); err != nil {
+		return err
+	} else if milliCPUs != 0 {
+		hostConfig.CPUPeriod = CPU_PERIOD
+		hostConfig.CPUQuota = milliCPUs * CPU_PERIOD / 1000
+	}
+
+	if pids, err := withinCeiling(serviceName, "pids_limit", limits.PidsLimit, ceilings.MaxPids, clamp); err != nil {
+		return err
+	} else if pids != 0 {
+		hostConfig.PidsLimit = &pids
+	}
+
+	// The ulimits from the deployment, then the ceilings for the ulimits that the deployment does not set.
+	ulimits := make([]docker.ULimit, 0, len(limits.Ulimits)+len(ceilings.MaxUlimits))
+	set := make(map[string]bool)
+	for _, u := range limits.Ulimits {
+		hard := u.Hard
+		if hard == 0 {
+			hard = u.Soft
+		}
+		hard, err := withinCeiling(serviceName, "ulimit "+u.Name, hard, ceilings.MaxUlimits[u.Name], clamp)
+		if err != nil {
+			return err
+		}
+		soft := u.Soft
+		if soft == 0 || soft > hard {
+			soft = hard
+		}
+		ulimits = append(ulimits, docker.ULimit{Name: u.Name, Soft: soft, Hard: hard})
+		set[u.Name] = true
+	}
+
+	names := make([]string, 0, len(ceilings.MaxUlimits))
+	for name, _ := range ceilings.MaxUlimits {
+		if !set[name] && ceilings.MaxUlimits[name] > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ulimits = append(ulimits, docker.ULimit{Name: name, Soft: ceilings.MaxUlimits[name], Hard: ceilings.MaxUlimits[name]})
+	}
+
+	if len(ulimits) != 0 {
+		hostConfig.Ulimits = ulimits
+	}
+
+	return nil
+}
+
+// Returns the value of a limit given its ceiling. A ceiling of zero means there is no ceiling, and a limit of zero
+// means the limit is not set and gets the ceiling.
+func withinCeiling(serviceName string, name string, limit int64, ceiling int64, clamp bool) (int64, error) {
+	if ceiling <= 0 || (limit != 0 && limit <= ceiling) {
+		return limit, nil
+	} else if limit == 0 {
+		return ceiling, nil
+	} else if !clamp {
+		return 0, fmt.Errorf("service %v %v %v exceeds the node's limit of %v", serviceName, name, limit, ceiling)
+	}
+	glog.Warningf("Lowering service %v %v from %v to the node's limit of %v", serviceName, name, limit, ceiling)
+	return ceiling, nil
+}
@@ -0,0 +1,107 @@
+// +build unit
+
+package container
+
+import (
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_applyResourceLimits_NoCeilings(t *testing.T) {
+
+	// nothing set, the default memory limit is kept
+	hc := docker.HostConfig{Memory: 512 * MB}
+	assert.Nil(t, applyResourceLimits(&hc, "svc", nil, config.ContainerLimitsConfig{}), "No limits should be fine.")
+	assert.Equal(t, int64(512*MB), hc.Memory, "The default memory limit should be kept.")
+	assert.Nil(t, hc.PidsLimit, "There should be no pids limit.")
+	assert.Equal(t, 0, len(hc.Ulimits), "There should be no ulimits.")
+
+	limits := &containermessage.ResourceLimits{
+		MemoryMB:  128,
+		CPUShares: 512,
+		CPUs:      0.5,
+		PidsLimit: 100,
+		Ulimits:   []containermessage.Ulimit{{Name: "nofile", Soft: 1024, Hard: 2048}, {Name: "nproc", Soft: 50}},
+	}
+	hc = docker.HostConfig{Memory: 512 * MB}
+	assert.Nil(t, applyResourceLimits(&hc, "svc", limits, config.ContainerLimitsConfig{}), "The limits should be applied.")
+	assert.Equal(t, int64(128*MB), hc.Memory, "The memory limit should be set.")
+	assert.Equal(t, int64(512), hc.CPUShares, "The CPU shares should be set.")
+	assert.Equal(t, int64(CPU_PERIOD), hc.CPUPeriod, "The CPU period should be set.")
+	assert.Equal(t, int64(CPU_PERIOD/2), hc.CPUQuota, "The CPU quota should be half the period.")
+	assert.Equal(t, int64(100), *hc.PidsLimit, "The pids limit should be set.")
+	assert.Equal(t, []docker.ULimit{{Name: "nofile", Soft: 1024, Hard: 2048}, {Name: "nproc", Soft: 50, Hard: 50}}, hc.Ulimits, "The ulimits should be set.")
+
+	// the CPUs are rounded to thousandths, not truncated
+	hc = docker.HostConfig{}
+	assert.Nil(t, applyResourceLimits(&hc, "svc", &containermessage.ResourceLimits{CPUs: 0.58}, config.ContainerLimitsConfig{}), "The CPUs should be applied.")
+	assert.Equal(t, int64(58000), hc.CPUQuota, "The CPU quota should not lose a thousandth of a CPU.")
+
+	// invalid limits are refused
+	hc = docker.HostConfig{}
+	err := applyResourceLimits(&hc, "svc", &containermessage.ResourceLimits{PidsLimit: -1}, config.ContainerLimitsConfig{})
+	assert.NotNil(t, err, "A negative pids limit should be refused.")
+	err = applyResourceLimits(&hc, "svc", &containermessage.ResourceLimits{Ulimits: []containermessage.Ulimit{{Name: "nofile", Soft: 10, Hard: 5}}}, config.ContainerLimitsConfig{})
+	assert.NotNil(t, err, "A soft limit over the hard limit should be refused.")
+}
+
+func Test_applyResourceLimits_Ceilings(t *testing.T) {
+
+	ceilings := config.ContainerLimitsConfig{
+		MaxMemoryMB:  256,
+		MaxCPUShares: 1024,
+		MaxCPUs:      1,
+		MaxPids:      200,
+		MaxUlimits:   map[string]int64{"nofile": 4096, "core": 0},
+		ExceedAction: config.CONTAINER_LIMITS_REFUSE,
+	}
+
+	// limits that are not set get the ceilings, the default memory is lowered to the ceiling
+	hc := docker.HostConfig{Memory: 512 * MB}
+	assert.Nil(t, applyResourceLimits(&hc, "svc", nil, ceilings), "No limits should be fine.")
+	assert.Equal(t, int64(256*MB), hc.Memory, "The memory should be lowered to the ceiling.")
+	assert.Equal(t, int64(1024), hc.CPUShares, "The CPU shares should be the ceiling.")
+	assert.Equal(t, int64(CPU_PERIOD), hc.CPUQuota, "The CPU quota should be the ceiling.")
+	assert.Equal(t, int64(200), *hc.PidsLimit, "The pids limit should be the ceiling.")
+	assert.Equal(t, []docker.ULimit{{Name: "nofile", Soft: 4096, Hard: 4096}}, hc.Ulimits, "The ulimit ceiling should be set.")
+
+	// limits within the ceilings are kept
+	limits := &containermessage.ResourceLimits{MemoryMB: 64, CPUs: 0.25, Ulimits: []containermessage.Ulimit{{Name: "nofile", Soft: 100, Hard: 200}}}
+	hc = docker.HostConfig{Memory: 512 * MB}
+	assert.Nil(t, applyResourceLimits(&hc, "svc", limits, ceilings), "The limits should be within the ceilings.")
+	assert.Equal(t, int64(64*MB), hc.Memory, "The memory limit should be kept.")
+	assert.Equal(t, int64(CPU_PERIOD/4), hc.CPUQuota, "The CPU quota should be kept.")
+	assert.Equal(t, []docker.ULimit{{Name: "nofile", Soft: 100, Hard: 200}}, hc.Ulimits, "The ulimit should be kept.")
+
+	// a limit equal to the ceiling is within it
+	hc = docker.HostConfig{}
+	ceilings.MaxCPUs = 0.58
+	assert.Nil(t, applyResourceLimits(&hc, "svc", &containermessage.ResourceLimits{CPUs: 0.58}, ceilings), "The CPUs at the ceiling should be within it.")
+	assert.Equal(t, int64(58000), hc.CPUQuota, "The CPU quota should be the ceiling.")
+	ceilings.MaxCPUs = 1
+
+	// limits over the ceilings are refused
+	for _, l := range []*containermessage.ResourceLimits{
+		{MemoryMB: 1024},
+		{CPUShares: 2048},
+		{CPUs: 1.5},
+		{PidsLimit: 1000},
+		{Ulimits: []containermessage.Ulimit{{Name: "nofile", Soft: 8192, Hard: 8192}}},
+	} {
+		hc = docker.HostConfig{}
+		assert.NotNil(t, applyResourceLimits(&hc, "svc", l, ceilings), "Limits over the ceilings should be refused: "+l.String())
+	}
+
+	// or lowered to the ceilings
+	ceilings.ExceedAction = config.CONTAINER_LIMITS_CLAMP
+	limits = &containermessage.ResourceLimits{MemoryMB: 1024, CPUs: 2, PidsLimit: 1000, Ulimits: []containermessage.Ulimit{{Name: "nofile", Soft: 8192, Hard: 16384}}}
+	hc = docker.HostConfig{}
+	assert.Nil(t, applyResourceLimits(&hc, "svc", limits, ceilings), "Limits over the ceilings should be clamped.")
+	assert.Equal(t, int64(256*MB), hc.Memory, "The memory limit should be clamped.")
+	assert.Equal(t, int64(CPU_PERIOD), hc.CPUQuota, "The CPU quota should be clamped.")
+	assert.Equal(t, int64(200), *hc.PidsLimit, "The pids limit should be clamped.")
+	assert.Equal(t, []docker.ULimit{{Name: "nofile", Soft: 4096, Hard: 4096}}, hc.Ulimits, "The ulimit should be clamped.")
+}
@@ -0,0 +1,139 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// The number of seconds docker waits for a container to stop before killing it when it is restarted with new secrets.
+const SECRETS_RESTART_TIMEOUT_S = 10
+
+// Returns the host directory holding the secret files of an agreement or service instance.
+func (b *ContainerWorker) secretsDir(key string) string {
+	return path.Join(b.Config.GetServiceSecretsPath(), key)
+}
+
+// Write the secret user inputs of a service into the secrets directory of an agreement or service instance and return
+// the directory. The directory is always created so that a secret added later can be given to the running containers
+// by restarting them.
+func (b *ContainerWorker) createServiceSecrets(key string, url string, org string, version string) (string, error) {
+
+	secrets, err := persistence.FindServiceSecrets(b.db, url, org, version, "")
+	if err != nil {
+		return "", err
+	}
+
+	dir := b.secretsDir(key)
+	if err := writeServiceSecrets(dir, secrets); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// Remove the secret files of an agreement or service instance.
+func (b *ContainerWorker) removeServiceSecrets(key string) {
+	if err := os.RemoveAll(b.secretsDir(key)); err != nil {
+		glog.Errorf("Failed to remove the secrets of %v, error %v", key, err)
+	}
+}
+
+// Rewrite the secret files of the running agreements and service instances of the given services and restart their
+// containers so that they read the new secrets. The other services and the agreements are left alone.
+func (b *ContainerWorker) rotateServiceSecrets(svcSpecs persistence.ServiceSpecs) {
+
+	restart := func(container *docker.APIContainers, key string) error {
+		glog.V(3).Infof("Restarting container %v of %v with new secrets", container.Names, key)
+		return b.client.RestartContainer(container.ID, SECRETS_RESTART_TIMEOUT_S)
+	}
+
+	if ags, err := persistence.FindEstablishedAgreementsAllProtocols(b.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter()}); err != nil {
+		glog.Errorf("Unable to retrieve agreements from database, error %v", err)
+	} else {
+		for _, ag := range ags {
+			if ag.AgreementExecutionStartTime == 0 || ag.AgreementTerminatedTime != 0 || !svcSpecs.SupportService(ag.RunningWorkload.URL, ag.RunningWorkload.Org) {
+				continue
+			}
+			if _, err := b.createServiceSecrets(ag.CurrentAgreementId, ag.RunningWorkload.URL, ag.RunningWorkload.Org, ag.RunningWorkload.Version); err != nil {
+				eventlog.LogAgreementEvent(b.db, persistence.SEVERITY_ERROR, fmt.Sprintf("Error writing the secrets for agreement %v: %v", ag.CurrentAgreementId, err), persistence.EC_ERROR_SERVICE_SECRETS, ag)
+				glog.Errorf("Error writing the secrets for agreement %v: %v", ag.CurrentAgreementId, err)
+			} else if err := b.ContainersMatchingAgreement([]string{ag.CurrentAgreementId}, false, restart); err != nil {
+				eventlog.LogAgreementEvent(b.db, persistence.SEVERITY_ERROR, fmt.Sprintf("Error restarting the containers for agreement %v with new secrets: %v", ag.CurrentAgreementId, err), persistence.EC_ERROR_SERVICE_SECRETS, ag)
+			} else {
+				eventlog.LogAgreementEvent(b.db, persistence.SEVERITY_INFO, fmt.Sprintf("Restarted the containers for agreement %v with new secrets", ag.CurrentAgreementId), persistence.EC_SERVICE_SECRETS_ROTATED, ag)
+			}
+		}
+	}
+
+	if msis, err := persistence.FindMicroserviceInstances(b.db, []persistence.MIFilter{persistence.UnarchivedMIFilter()}); err != nil {
+		glog.Errorf("Unable to retrieve service instances from database, error %v", err)
+	} else {
+		for _, msi := range msis {
+			if msi.ExecutionStartTime == 0 || msi.CleanupStartTime != 0 || !svcSpecs.SupportService(msi.SpecRef, msi.Org) {
+				continue
+			}
+			key := msi.GetKey()
+			if _, err := b.createServiceSecrets(key, msi.SpecRef, msi.Org, msi.Version); err != nil {
+				eventlog.LogServiceEvent2(b.db, persistence.SEVERITY_ERROR, fmt.Sprintf("Error writing the secrets for service %v: %v", key, err), persistence.EC_ERROR_SERVICE_SECRETS, key, msi.SpecRef, msi.Org, msi.Version, msi.Arch, msi.AssociatedAgreements)
+				glog.Errorf("Error writing the secrets for service %v: %v", key, err)
+			} else if err := b.ContainersMatchingAgreement([]string{key}, false, restart); err != nil {
+				eventlog.LogServiceEvent2(b.db, persistence.SEVERITY_ERROR, fmt.Sprintf("Error restarting the containers for service %v with new secrets: %v", key, err), persistence.EC_ERROR_SERVICE_SECRETS, key, msi.SpecRef, msi.Org, msi.Version, msi.Arch, msi.AssociatedAgreements)
+			} else {
+				eventlog.LogServiceEvent2(b.db, persistence.SEVERITY_INFO, fmt.Sprintf("Restarted the containers for service %v with new secrets", key), persistence.EC_SERVICE_SECRETS_ROTATED, key, msi.SpecRef, msi.Org, msi.Version, msi.Arch, msi.AssociatedAgreements)
+			}
+		}
+	}
+}
+
+// Write one read only file per secret into the directory and remove the files of the secrets that are gone. Each
+// file is replaced by a rename so that a running container never reads a partial secret. The parent directory is
+// only accessible by the agent.
+func writeServiceSecrets(dir string, secrets map[string]string) error {
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0700); err != nil {
+		return errors.New(fmt.Sprintf("unable to create the secrets directory %v, error %v", filepath.Dir(dir), err))
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.New(fmt.Sprintf("unable to create the secrets directory %v, error %v", dir, err))
+	}
+
+	for name, value := range secrets {
+		if name == "" || filepath.Base(name) != name || name == "." || name == ".." {
+			return errors.New(fmt.Sprintf("secret name %v is not a valid file name", name))
+		}
+		tmp := path.Join(dir, "."+name+".tmp")
+		if err := ioutil.WriteFile(tmp, []byte(value), 0444); err != nil {
+			os.Remove(tmp)
+			return errors.New(fmt.Sprintf("unable to write secret %v, error %v", name, err))
+		} else if err := os.Rename(tmp, path.Join(dir, name)); err != nil {
+			os.Remove(tmp)
+			return errors.New(fmt.Sprintf("unable to write secret %v, error %v", name, err))
+		}
+	}
+
+	if files, err := ioutil.ReadDir(dir); err != nil {
+		return errors.New(fmt.Sprintf("unable to read the secrets directory %v, error %v", dir, err))
+	} else {
+		for _, f := range files {
+			if _, ok := secrets[f.Name()]; !ok {
+				if err := os.Remove(path.Join(dir, f.Name())); err != nil {
+					return errors.New(fmt.Sprintf("unable to remove secret %v, error %v", f.Name(), err))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the binding that mounts the secrets directory into a container.
+func secretsBinding(dir string) string {
+	return fmt.Sprintf("%v:%v:ro", dir, config.HZN_SECRETS_MOUNT)
+}
@@ -0,0 +1,54 @@
+// +build unit
+
+package container
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_writeServiceSecrets(t *testing.T) {
+
+	base, err := ioutil.TempDir("", "secrets")
+	assert.Nil(t, err)
+	defer os.RemoveAll(base)
+
+	dir := path.Join(base, "horizon", "agreement1")
+
+	// the directory is created even without secrets
+	assert.Nil(t, writeServiceSecrets(dir, map[string]string{}))
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(files))
+
+	info, err := os.Stat(path.Dir(dir))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0700), info.Mode().Perm())
+
+	// one read only file per secret
+	assert.Nil(t, writeServiceSecrets(dir, map[string]string{"PASSWORD": "pw1", "TOKEN": "tk1"}))
+	content, err := ioutil.ReadFile(path.Join(dir, "PASSWORD"))
+	assert.Nil(t, err)
+	assert.Equal(t, "pw1", string(content))
+
+	info, err = os.Stat(path.Join(dir, "TOKEN"))
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0444), info.Mode().Perm())
+
+	// a rotation replaces the changed secrets and removes the ones that are gone
+	assert.Nil(t, writeServiceSecrets(dir, map[string]string{"PASSWORD": "pw2"}))
+	content, err = ioutil.ReadFile(path.Join(dir, "PASSWORD"))
+	assert.Nil(t, err)
+	assert.Equal(t, "pw2", string(content))
+
+	files, err = ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+
+	// a secret name cannot escape the directory
+	assert.NotNil(t, writeServiceSecrets(dir, map[string]string{"../PASSWORD": "pw3"}))
+	assert.NotNil(t, writeServiceSecrets(dir, map[string]string{"..": "pw3"}))
+}
@@ -6,8 +6,12 @@ import (
 	"errors"
 	"fmt"
 	docker "github.com/fsouza/go-dockerclient"
+	"github.com/open-horizon/anax/cutil"
+	"net"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
 )
 
 /*
@@ -19,6 +23,7 @@ import (
  *   "services": {
  *     "service_a": {
  *       "image": "...",
+ *       "image_signature": "...",
  *       "privileged": true,
  *       "environment": [
  *         "FOO=bar"
@@ -35,7 +40,44 @@ import (
  *           "HostPort":"5200:6414/tcp",
  *           "HostIP": "0.0.0.0"
  *         }
- *       ]
+ *       ],
+ *       "resources": {
+ *         "memory_mb": 256,
+ *         "cpus": 0.5,
+ *         "pids_limit": 100,
+ *         "ulimits": [
+ *           {
+ *             "name": "nofile",
+ *             "soft": 1024,
+ *             "hard": 2048
+ *           }
+ *         ]
+ *       },
+ *       "health_check": {
+ *         "http_get": {
+ *           "port": 8080,
+ *           "path": "/health"
+ *         },
+ *         "interval_s": 15,
+ *         "failure_threshold": 3
+ *       },
+ *       "restart_policy": {
+ *         "name": "on-failure",
+ *         "max_retries": 5
+ *       },
+ *       "egress": {
+ *         "default": "deny",
+ *         "rules": [
+ *           {
+ *             "host": "mqtt.example.com",
+ *             "protocol": "tcp",
+ *             "ports": ["8883"]
+ *           },
+ *           {
+ *             "cidr": "10.20.0.0/16"
+ *           }
+ *         ]
+ *       }
  *     },
  *     "service_b": {
  *       "image": "...",
@@ -138,6 +180,7 @@ func (p *Pattern) IsShared(tp string, serviceName string) bool {
 // Service Only those marked "omitempty" may be omitted
 type Service struct {
 	Image            string               `json:"image"`
+	ImageSignature   string               `json:"image_signature,omitempty"` // the signature of the image digest, see ImageSignaturePayload. The image must be pinned by digest.
 	VariationLabel   string               `json:"variation_label,omitempty"`
 	Privileged       bool                 `json:"privileged"`
 	Environment      []string             `json:"environment,omitempty"`
@@ -150,6 +193,11 @@ type Service struct {
 	Ports            []docker.PortBinding `json:"ports,omitempty"`
 	EphemeralPorts   []Port               `json:"ephemeral_ports,omitempty"`
 	SpecificPorts    []docker.PortBinding `json:"specific_ports,omitempty"` // obselete. for backward compatibility only, new way should use ports instead.
+	Resources        *ResourceLimits      `json:"resources,omitempty"`      // the limits on the resources the container can use, the node's defaults apply when omitted.
+	HealthCheck      *HealthCheck         `json:"health_check,omitempty"`
+	RestartPolicy    *RestartPolicy       `json:"restart_policy,omitempty"` // the container is always restarted when omitted.
+	Egress           *EgressPolicy        `json:"egress,omitempty"`         // the outbound traffic is not restricted when omitted.
+	ConfigReload     *ConfigReload        `json:"config_reload,omitempty"`  // the agreement is cancelled when the node user input changes if omitted.
 }
 
 func (s *Service) AddFilesystemBinding(bind string) {
@@ -227,6 +275,390 @@ type Port struct {
 	PortAndProtocol string `json:"port_and_protocol"`
 }
 
+// The resource limits of a container. A zero value means the limit is not set by the deployment.
+type ResourceLimits struct {
+	MemoryMB  int64    `json:"memory_mb,omitempty"`  // the hard memory limit in MB
+	CPUShares int64    `json:"cpu_shares,omitempty"` // the relative CPU weight of the container, the docker default is 1024
+	CPUs      float64  `json:"cpus,omitempty"`       // the CPU quota as a number of CPUs, e.g. 0.5 is half of one CPU
+	PidsLimit int64    `json:"pids_limit,omitempty"` // the maximum number of processes in the container
+	Ulimits   []Ulimit `json:"ulimits,omitempty"`
+}
+
+func (r *ResourceLimits) String() string {
+	return fmt.Sprintf("MemoryMB: %v, CPUShares: %v, CPUs: %v, PidsLimit: %v, Ulimits: %v", r.MemoryMB, r.CPUShares, r.CPUs, r.PidsLimit, r.Ulimits)
+}
+
+// Check that the limits make sense on their own, without regard to what the node allows.
+func (r *ResourceLimits) Validate() error {
+	if r.MemoryMB < 0 {
+		return fmt.Errorf("memory_mb %v must not be negative", r.MemoryMB)
+	} else if r.MemoryMB > 0 && r.MemoryMB < 4 {
+		// docker requires at least 4MB
+		return fmt.Errorf("memory_mb %v must be at least 4", r.MemoryMB)
+	} else if r.CPUShares < 0 {
+		return fmt.Errorf("cpu_shares %v must not be negative", r.CPUShares)
+	} else if r.CPUs < 0 {
+		return fmt.Errorf("cpus %v must not be negative", r.CPUs)
+	} else if r.CPUs > 0 && r.CPUs < 0.01 {
+		// docker requires a CPU quota of at least 1ms, a hundredth of the 100ms CPU period
+		return fmt.Errorf("cpus %v must be at least 0.01", r.CPUs)
+	} else if r.PidsLimit < 0 {
+		return fmt.Errorf("pids_limit %v must not be negative", r.PidsLimit)
+	}
+
+	names := make(map[string]bool)
+	for _, u := range r.Ulimits {
+		if u.Name == "" {
+			return fmt.Errorf("ulimit %v must have a name", u)
+		} else if names[u.Name] {
+			return fmt.Errorf("ulimit %v is specified more than once", u.Name)
+		} else if u.Soft < 0 || u.Hard < 0 {
+			return fmt.Errorf("ulimit %v must not be negative", u.Name)
+		} else if u.Hard != 0 && u.Soft > u.Hard {
+			return fmt.Errorf("ulimit %v soft limit %v is greater than the hard limit %v", u.Name, u.Soft, u.Hard)
+		}
+		names[u.Name] = true
+	}
+	return nil
+}
+
+// A ulimit of the container, such as nofile or nproc.
+type Ulimit struct {
+	Name string `json:"name"`
+	Soft int64  `json:"soft"`
+	Hard int64  `json:"hard"`
+}
+
+// The defaults of the health check settings.
+const (
+	HEALTH_CHECK_DEFAULT_INTERVAL_S    = 30
+	HEALTH_CHECK_DEFAULT_TIMEOUT_S     = 10
+	HEALTH_CHECK_DEFAULT_FAILURE_LIMIT = 3
+)
+
+// The health check of a container. Exactly one of the command, the HTTP GET or the TCP port is specified. The command
+// is run inside the container by docker; a command with a single element is run by the container's shell. The HTTP
+// and TCP checks are made by the agent against the container's address. The container is unhealthy after the failure
+// threshold of checks in a row fail, not counting the failures during the start period.
+type HealthCheck struct {
+	Command          []string      `json:"command,omitempty"`
+	HTTPGet          *HTTPGetCheck `json:"http_get,omitempty"`
+	TCPPort          int           `json:"tcp_port,omitempty"`
+	IntervalS        int           `json:"interval_s,omitempty"`
+	TimeoutS         int           `json:"timeout_s,omitempty"`
+	StartPeriodS     int           `json:"start_period_s,omitempty"`
+	FailureThreshold int           `json:"failure_threshold,omitempty"`
+}
+
+func (h *HealthCheck) String() string {
+	return fmt.Sprintf("Command: %v, HTTPGet: %v, TCPPort: %v, IntervalS: %v, TimeoutS: %v, StartPeriodS: %v, FailureThreshold: %v", h.Command, h.HTTPGet, h.TCPPort, h.IntervalS, h.TimeoutS, h.StartPeriodS, h.FailureThreshold)
+}
+
+func (h *HealthCheck) Validate() error {
+	checks := 0
+	if len(h.Command) != 0 {
+		checks++
+	}
+	if h.HTTPGet != nil {
+		checks++
+		if h.HTTPGet.Port <= 0 || h.HTTPGet.Port > 65535 {
+			return fmt.Errorf("http_get port %v is not valid", h.HTTPGet.Port)
+		} else if h.HTTPGet.Path != "" && !strings.HasPrefix(h.HTTPGet.Path, "/") {
+			return fmt.Errorf("http_get path %v must start with /", h.HTTPGet.Path)
+		}
+	}
+	if h.TCPPort != 0 {
+		checks++
+		if h.TCPPort < 0 || h.TCPPort > 65535 {
+			return fmt.Errorf("tcp_port %v is not valid", h.TCPPort)
+		}
+	}
+
+	if checks != 1 {
+		return fmt.Errorf("exactly one of command, http_get or tcp_port must be specified")
+	} else if h.IntervalS < 0 {
+		return fmt.Errorf("interval_s %v must not be negative", h.IntervalS)
+	} else if h.TimeoutS < 0 {
+		return fmt.Errorf("timeout_s %v must not be negative", h.TimeoutS)
+	} else if h.StartPeriodS < 0 {
+		return fmt.Errorf("start_period_s %v must not be negative", h.StartPeriodS)
+	} else if h.FailureThreshold < 0 {
+		return fmt.Errorf("failure_threshold %v must not be negative", h.FailureThreshold)
+	}
+	return nil
+}
+
+func (h *HealthCheck) Interval() time.Duration {
+	if h.IntervalS == 0 {
+		return HEALTH_CHECK_DEFAULT_INTERVAL_S * time.Second
+	}
+	return time.Duration(h.IntervalS) * time.Second
+}
+
+// The timeout is never longer than the interval.
+func (h *HealthCheck) Timeout() time.Duration {
+	timeout := time.Duration(h.TimeoutS) * time.Second
+	if h.TimeoutS == 0 {
+		timeout = HEALTH_CHECK_DEFAULT_TIMEOUT_S * time.Second
+	}
+	if timeout > h.Interval() {
+		return h.Interval()
+	}
+	return timeout
+}
+
+func (h *HealthCheck) StartPeriod() time.Duration {
+	return time.Duration(h.StartPeriodS) * time.Second
+}
+
+func (h *HealthCheck) Threshold() int {
+	if h.FailureThreshold == 0 {
+		return HEALTH_CHECK_DEFAULT_FAILURE_LIMIT
+	}
+	return h.FailureThreshold
+}
+
+// Returns the docker health check for a command health check, or nil for the checks made by the agent.
+func (h *HealthCheck) DockerHealthConfig() *docker.HealthConfig {
+	if len(h.Command) == 0 {
+		return nil
+	}
+
+	test := append([]string{"CMD"}, h.Command...)
+	if len(h.Command) == 1 {
+		test = []string{"CMD-SHELL", h.Command[0]}
+	}
+	return &docker.HealthConfig{
+		Test:        test,
+		Interval:    h.Interval(),
+		Timeout:     h.Timeout(),
+		StartPeriod: h.StartPeriod(),
+		Retries:     h.Threshold(),
+	}
+}
+
+type HTTPGetCheck struct {
+	Port int    `json:"port"`
+	Path string `json:"path,omitempty"` // defaults to /, any status code from 200 to 399 is healthy
+}
+
+func (c *HTTPGetCheck) String() string {
+	return fmt.Sprintf("Port: %v, Path: %v", c.Port, c.Path)
+}
+
+// The restart policies of a container, as defined by docker.
+const (
+	RESTART_ALWAYS         = "always"
+	RESTART_UNLESS_STOPPED = "unless-stopped"
+	RESTART_ON_FAILURE     = "on-failure"
+	RESTART_NO             = "no"
+)
+
+// The restart policy of a container. The maximum number of retries only applies to the on-failure policy, zero means
+// no limit.
+type RestartPolicy struct {
+	Name       string `json:"name"`
+	MaxRetries int    `json:"max_retries,omitempty"`
+}
+
+func (r *RestartPolicy) String() string {
+	return fmt.Sprintf("Name: %v, MaxRetries: %v", r.Name, r.MaxRetries)
+}
+
+func (r *RestartPolicy) Validate() error {
+	switch r.Name {
+	case RESTART_ALWAYS, RESTART_UNLESS_STOPPED, RESTART_NO:
+		if r.MaxRetries != 0 {
+			return fmt.Errorf("max_retries can only be specified with the %v restart policy", RESTART_ON_FAILURE)
+		}
+	case RESTART_ON_FAILURE:
+		if r.MaxRetries < 0 {
+			return fmt.Errorf("max_retries %v must not be negative", r.MaxRetries)
+		}
+	default:
+		return fmt.Errorf("restart policy %v is not supported, use %v, %v, %v or %v", r.Name, RESTART_ALWAYS, RESTART_UNLESS_STOPPED, RESTART_ON_FAILURE, RESTART_NO)
+	}
+	return nil
+}
+
+func (r *RestartPolicy) DockerRestartPolicy() docker.RestartPolicy {
+	switch r.Name {
+	case RESTART_UNLESS_STOPPED:
+		return docker.RestartUnlessStopped()
+	case RESTART_ON_FAILURE:
+		return docker.RestartOnFailure(r.MaxRetries)
+	case RESTART_NO:
+		return docker.NeverRestart()
+	default:
+		return docker.AlwaysRestart()
+	}
+}
+
+// The signals that can tell a container to reload its configuration.
+var configReloadSignals = map[string]docker.Signal{
+	"SIGHUP":  docker.SIGHUP,
+	"SIGUSR1": docker.SIGUSR1,
+	"SIGUSR2": docker.SIGUSR2,
+}
+
+const CONFIG_RELOAD_DEFAULT_SIGNAL = "SIGHUP"
+
+// The way a container reloads its configuration when the node user input of its service changes. The agent rewrites
+// the user input file mounted in the container and then either sends the signal to the container or posts the user
+// input to the container's HTTP endpoint. At most one of the signal or the HTTP POST is specified, the container
+// gets a SIGHUP when neither is.
+type ConfigReload struct {
+	Signal   string          `json:"signal,omitempty"`
+	HTTPPost *HTTPPostReload `json:"http_post,omitempty"`
+}
+
+func (c *ConfigReload) String() string {
+	return fmt.Sprintf("Signal: %v, HTTPPost: %v", c.Signal, c.HTTPPost)
+}
+
+func (c *ConfigReload) Validate() error {
+	if c.Signal != "" && c.HTTPPost != nil {
+		return fmt.Errorf("only one of signal or http_post can be specified")
+	} else if _, ok := configReloadSignals[c.GetSignal()]; c.HTTPPost == nil && !ok {
+		return fmt.Errorf("signal %v is not supported, use SIGHUP, SIGUSR1 or SIGUSR2", c.Signal)
+	} else if c.HTTPPost != nil && (c.HTTPPost.Port <= 0 || c.HTTPPost.Port > 65535) {
+		return fmt.Errorf("http_post port %v is not valid", c.HTTPPost.Port)
+	} else if c.HTTPPost != nil && c.HTTPPost.Path != "" && !strings.HasPrefix(c.HTTPPost.Path, "/") {
+		return fmt.Errorf("http_post path %v must start with /", c.HTTPPost.Path)
+	}
+	return nil
+}
+
+// Returns the name of the signal to send, or an empty string when the user input is posted instead.
+func (c *ConfigReload) GetSignal() string {
+	if c.HTTPPost != nil {
+		return ""
+	} else if c.Signal == "" {
+		return CONFIG_RELOAD_DEFAULT_SIGNAL
+	}
+	return c.Signal
+}
+
+func (c *ConfigReload) DockerSignal() docker.Signal {
+	return configReloadSignals[c.GetSignal()]
+}
+
+type HTTPPostReload struct {
+	Port int    `json:"port"`
+	Path string `json:"path,omitempty"` // defaults to /, any status code from 200 to 299 is a success
+}
+
+// The default actions of an egress policy.
+const (
+	EGRESS_DENY  = "deny"
+	EGRESS_ALLOW = "allow"
+)
+
+// How often the host names of an egress policy are resolved again, by default.
+const EGRESS_DEFAULT_REFRESH_INTERVAL_S = 300
+
+// The outbound network traffic a container may send. With the default action of deny, the container can only reach its
+// own networks and the destinations of the rules; with allow, the rules list the destinations it cannot reach. The host
+// names in the rules are resolved by the agent when the container starts and again every refresh interval.
+type EgressPolicy struct {
+	Default          string       `json:"default,omitempty"` // deny when omitted
+	Rules            []EgressRule `json:"rules,omitempty"`
+	RefreshIntervalS int          `json:"refresh_interval_s,omitempty"`
+}
+
+func (e *EgressPolicy) String() string {
+	return fmt.Sprintf("Default: %v, Rules: %v, RefreshIntervalS: %v", e.Default, e.Rules, e.RefreshIntervalS)
+}
+
+func (e *EgressPolicy) Validate() error {
+	if e.Default != "" && e.Default != EGRESS_DENY && e.Default != EGRESS_ALLOW {
+		return fmt.Errorf("default %v is not supported, use %v or %v", e.Default, EGRESS_DENY, EGRESS_ALLOW)
+	} else if e.RefreshIntervalS < 0 {
+		return fmt.Errorf("refresh_interval_s %v must not be negative", e.RefreshIntervalS)
+	}
+	for _, r := range e.Rules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns true if the traffic that no rule matches is rejected.
+func (e *EgressPolicy) DenyByDefault() bool {
+	return e.Default != EGRESS_ALLOW
+}
+
+func (e *EgressPolicy) RefreshInterval() time.Duration {
+	if e.RefreshIntervalS == 0 {
+		return EGRESS_DEFAULT_REFRESH_INTERVAL_S * time.Second
+	}
+	return time.Duration(e.RefreshIntervalS) * time.Second
+}
+
+// Returns the host names in the rules, without duplicates.
+func (e *EgressPolicy) Hosts() []string {
+	hosts := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, r := range e.Rules {
+		if r.Host != "" && !seen[r.Host] {
+			hosts = append(hosts, r.Host)
+			seen[r.Host] = true
+		}
+	}
+	return hosts
+}
+
+// One destination of an egress policy, either a CIDR or a host name. The protocol is tcp, udp or omitted for any
+// protocol. The ports are single ports or ranges like 8000-8100, and need a protocol; all ports match when omitted.
+type EgressRule struct {
+	CIDR     string   `json:"cidr,omitempty"`
+	Host     string   `json:"host,omitempty"`
+	Protocol string   `json:"protocol,omitempty"`
+	Ports    []string `json:"ports,omitempty"`
+}
+
+func (r EgressRule) String() string {
+	return fmt.Sprintf("CIDR: %v, Host: %v, Protocol: %v, Ports: %v", r.CIDR, r.Host, r.Protocol, r.Ports)
+}
+
+func (r *EgressRule) Validate() error {
+	if (r.CIDR == "") == (r.Host == "") {
+		return fmt.Errorf("egress rule %v must have exactly one of cidr or host", r)
+	} else if r.CIDR != "" {
+		if _, _, err := net.ParseCIDR(r.CIDR); err != nil && net.ParseIP(r.CIDR) == nil {
+			return fmt.Errorf("egress rule cidr %v is not a CIDR or an IP address", r.CIDR)
+		}
+	} else if strings.ContainsAny(r.Host, " ,/:") {
+		return fmt.Errorf("egress rule host %v is not a host name", r.Host)
+	}
+
+	if r.Protocol != "" && r.Protocol != "tcp" && r.Protocol != "udp" {
+		return fmt.Errorf("egress rule protocol %v is not supported, use tcp or udp", r.Protocol)
+	} else if len(r.Ports) != 0 && r.Protocol == "" {
+		return fmt.Errorf("egress rule %v must have a protocol to restrict the ports", r)
+	}
+	if _, err := r.IPTablesPorts(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Returns the ports in the form of the iptables multiport match, e.g. 443,8000:8100.
+func (r *EgressRule) IPTablesPorts() (string, error) {
+	ports := make([]string, 0, len(r.Ports))
+	for _, p := range r.Ports {
+		bounds := strings.SplitN(p, "-", 2)
+		for _, b := range bounds {
+			if n, err := strconv.Atoi(b); err != nil || n <= 0 || n > 65535 {
+				return "", fmt.Errorf("egress rule port %v is not a port or a range of ports", p)
+			}
+		}
+		ports = append(ports, strings.Join(bounds, ":"))
+	}
+	return strings.Join(ports, ","), nil
+}
+
 type DynamicOutboundPermitValue struct {
 	DdKey    string   `json:"dd_key"`
 	Encoding Encoding `json:"encoding"`
@@ -290,6 +722,65 @@ func (n *NetworkIsolation) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// The type of the payload of an image signature. The signatures are made with the RSA-PSS keys of the service
+// deployments, so the payload has its own type and is not mistaken for the signature of another tool.
+const IMAGE_SIGNATURE_TYPE = "open-horizon container image signature"
+
+// The content that is signed to sign an image, laid out like the simple signing format of the container signature
+// lookaside stores. It binds the repository of the image to its manifest digest, so a signature cannot be moved to
+// another image.
+type ImageSignedPayload struct {
+	Critical ImageSignedCritical    `json:"critical"`
+	Optional map[string]interface{} `json:"optional"`
+}
+
+type ImageSignedCritical struct {
+	Identity ImageSignedIdentity `json:"identity"`
+	Image    ImageSignedImage    `json:"image"`
+	Type     string              `json:"type"`
+}
+
+type ImageSignedIdentity struct {
+	DockerReference string `json:"docker-reference"`
+}
+
+type ImageSignedImage struct {
+	DockerManifestDigest string `json:"docker-manifest-digest"`
+}
+
+// Returns the repository and the digest of an image that is pinned by digest, or an error when the image has no digest.
+func ImageDigestReference(image string) (string, string, error) {
+	domain, path, _, digest := cutil.ParseDockerImagePath(image)
+	if path == "" {
+		return "", "", fmt.Errorf("unable to parse image %v", image)
+	} else if digest == "" {
+		return "", "", fmt.Errorf("image %v is not pinned by digest", image)
+	}
+
+	repo := path
+	if domain != "" {
+		repo = domain + "/" + path
+	}
+	return repo, digest, nil
+}
+
+// Returns the bytes that are signed to sign the digest of the image.
+func ImageSignaturePayload(image string) ([]byte, error) {
+	repo, digest, err := ImageDigestReference(image)
+	if err != nil {
+		return nil, err
+	}
+
+	payload := ImageSignedPayload{
+		Critical: ImageSignedCritical{
+			Identity: ImageSignedIdentity{DockerReference: repo},
+			Image:    ImageSignedImage{DockerManifestDigest: digest},
+			Type:     IMAGE_SIGNATURE_TYPE,
+		},
+	}
+	return json.Marshal(payload)
+}
+
 // Given a deployment string, unmarshal it as a native Horizon Deployment object. It might not be a native Deployment, so
 // we have to verify what was just unmarshalled.
 func GetNativeDeployment(depStr string) (*DeploymentDescription, error) {
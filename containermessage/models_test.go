@@ -5,6 +5,7 @@ package containermessage
 import (
 	docker "github.com/fsouza/go-dockerclient"
 	"testing"
+	"time"
 )
 
 func Test_HasSpecificPortBinding(t *testing.T) {
@@ -178,3 +179,178 @@ func Test_AddSpecificPortBinding(t *testing.T) {
 		t.Errorf("Service should have 2 specific port bindings but not.")
 	}
 }
+
+func Test_ResourceLimits_Validate(t *testing.T) {
+
+	r := &ResourceLimits{MemoryMB: 256, CPUShares: 512, CPUs: 0.5, PidsLimit: 100, Ulimits: []Ulimit{{Name: "nofile", Soft: 1024, Hard: 2048}, {Name: "nproc", Soft: 50}}}
+	if err := r.Validate(); err != nil {
+		t.Errorf("resource limits %v should be valid, error %v", r, err)
+	}
+
+	for _, r := range []*ResourceLimits{
+		{MemoryMB: -1},
+		{MemoryMB: 2},
+		{CPUShares: -1},
+		{CPUs: -0.5},
+		{CPUs: 0.0005},
+		{CPUs: 0.005},
+		{PidsLimit: -1},
+		{Ulimits: []Ulimit{{Soft: 1}}},
+		{Ulimits: []Ulimit{{Name: "nofile", Soft: 1}, {Name: "nofile", Soft: 2}}},
+		{Ulimits: []Ulimit{{Name: "nofile", Soft: 20, Hard: 10}}},
+	} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("resource limits %v should not be valid", r)
+		}
+	}
+}
+
+func Test_HealthCheck_Validate(t *testing.T) {
+
+	for _, h := range []*HealthCheck{
+		{Command: []string{"curl -f http://localhost/"}},
+		{HTTPGet: &HTTPGetCheck{Port: 8080, Path: "/health"}, IntervalS: 10, FailureThreshold: 5},
+		{TCPPort: 5432, StartPeriodS: 60},
+	} {
+		if err := h.Validate(); err != nil {
+			t.Errorf("health check %v should be valid, error %v", h, err)
+		}
+	}
+
+	for _, h := range []*HealthCheck{
+		{},
+		{Command: []string{"true"}, TCPPort: 80},
+		{HTTPGet: &HTTPGetCheck{Port: 0}},
+		{HTTPGet: &HTTPGetCheck{Port: 80, Path: "health"}},
+		{TCPPort: 70000},
+		{TCPPort: 80, IntervalS: -1},
+		{TCPPort: 80, FailureThreshold: -1},
+	} {
+		if err := h.Validate(); err == nil {
+			t.Errorf("health check %v should not be valid", h)
+		}
+	}
+}
+
+func Test_ConfigReload_Validate(t *testing.T) {
+
+	for _, c := range []*ConfigReload{
+		{},
+		{Signal: "SIGUSR1"},
+		{HTTPPost: &HTTPPostReload{Port: 8080, Path: "/config"}},
+	} {
+		if err := c.Validate(); err != nil {
+			t.Errorf("config reload %v should be valid, error %v", c, err)
+		}
+	}
+
+	for _, c := range []*ConfigReload{
+		{Signal: "SIGKILL"},
+		{Signal: "SIGHUP", HTTPPost: &HTTPPostReload{Port: 8080}},
+		{HTTPPost: &HTTPPostReload{Port: 0}},
+		{HTTPPost: &HTTPPostReload{Port: 80, Path: "config"}},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("config reload %v should not be valid", c)
+		}
+	}
+
+	if c := (&ConfigReload{}); c.GetSignal() != "SIGHUP" || c.DockerSignal() != docker.SIGHUP {
+		t.Errorf("config reload should default to SIGHUP, got %v", c.GetSignal())
+	} else if c := (&ConfigReload{HTTPPost: &HTTPPostReload{Port: 80}}); c.GetSignal() != "" {
+		t.Errorf("config reload with http_post should not have a signal, got %v", c.GetSignal())
+	}
+}
+
+func Test_HealthCheck_Defaults(t *testing.T) {
+
+	h := &HealthCheck{TCPPort: 80}
+	if h.Interval() != 30*time.Second || h.Timeout() != 10*time.Second || h.Threshold() != 3 {
+		t.Errorf("health check %v should have the default settings", h)
+	} else if h.DockerHealthConfig() != nil {
+		t.Errorf("a tcp health check should not be run by docker")
+	}
+
+	// the timeout is never longer than the interval
+	h = &HealthCheck{Command: []string{"pg_isready"}, IntervalS: 5, TimeoutS: 20}
+	if hc := h.DockerHealthConfig(); hc == nil {
+		t.Errorf("a command health check should be run by docker")
+	} else if hc.Timeout != 5*time.Second || hc.Retries != 3 {
+		t.Errorf("docker health config %v should have a 5s timeout and 3 retries", hc)
+	} else if len(hc.Test) != 2 || hc.Test[0] != "CMD-SHELL" {
+		t.Errorf("a single command should be run by the shell, got %v", hc.Test)
+	}
+
+	h = &HealthCheck{Command: []string{"/bin/check", "-q"}}
+	if hc := h.DockerHealthConfig(); len(hc.Test) != 3 || hc.Test[0] != "CMD" {
+		t.Errorf("a command with arguments should be run directly, got %v", hc.Test)
+	}
+}
+
+func Test_RestartPolicy(t *testing.T) {
+
+	for _, r := range []*RestartPolicy{{Name: "always"}, {Name: "unless-stopped"}, {Name: "no"}, {Name: "on-failure", MaxRetries: 5}} {
+		if err := r.Validate(); err != nil {
+			t.Errorf("restart policy %v should be valid, error %v", r, err)
+		} else if r.DockerRestartPolicy().Name != r.Name {
+			t.Errorf("restart policy %v should be docker's %v policy", r, r.Name)
+		}
+	}
+
+	for _, r := range []*RestartPolicy{{}, {Name: "sometimes"}, {Name: "always", MaxRetries: 1}, {Name: "on-failure", MaxRetries: -1}} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("restart policy %v should not be valid", r)
+		}
+	}
+}
+
+func Test_EgressPolicy_Validate(t *testing.T) {
+
+	e := &EgressPolicy{Rules: []EgressRule{
+		{CIDR: "10.20.0.0/16"},
+		{CIDR: "4.2.2.2", Protocol: "udp", Ports: []string{"53"}},
+		{Host: "mqtt.example.com", Protocol: "tcp", Ports: []string{"8883", "9000-9100"}},
+		{Host: "mqtt.example.com", Protocol: "tcp", Ports: []string{"1883"}},
+	}}
+	if err := e.Validate(); err != nil {
+		t.Errorf("egress policy %v should be valid, error %v", e, err)
+	} else if !e.DenyByDefault() {
+		t.Errorf("egress policy %v should deny by default", e)
+	} else if e.RefreshInterval() != EGRESS_DEFAULT_REFRESH_INTERVAL_S*time.Second {
+		t.Errorf("egress policy %v should have the default refresh interval", e)
+	} else if hosts := e.Hosts(); len(hosts) != 1 || hosts[0] != "mqtt.example.com" {
+		t.Errorf("egress policy %v should have one host, got %v", e, hosts)
+	} else if ports, _ := e.Rules[2].IPTablesPorts(); ports != "8883,9000:9100" {
+		t.Errorf("egress rule %v should have the iptables ports 8883,9000:9100, got %v", e.Rules[2], ports)
+	}
+
+	for _, r := range []EgressRule{{}, {CIDR: "10.0.0.0/8", Host: "example.com"}, {CIDR: "10.0.0.0/33"}, {Host: "http://example.com"}, {Host: "example.com", Protocol: "icmp"}, {Host: "example.com", Ports: []string{"443"}}, {Host: "example.com", Protocol: "tcp", Ports: []string{"https"}}, {Host: "example.com", Protocol: "tcp", Ports: []string{"0-80"}}} {
+		if err := r.Validate(); err == nil {
+			t.Errorf("egress rule %v should not be valid", r)
+		}
+	}
+
+	if err := (&EgressPolicy{Default: "reject"}).Validate(); err == nil {
+		t.Errorf("an unknown default should not be valid")
+	}
+}
+
+func Test_ImageSignaturePayload(t *testing.T) {
+
+	image := "myregistry.com:5000/gps@sha256:0123456789abcdef"
+	if payload, err := ImageSignaturePayload(image); err != nil {
+		t.Errorf("image %v should have a signature payload, error %v", image, err)
+	} else if string(payload) != `{"critical":{"identity":{"docker-reference":"myregistry.com:5000/gps"},"image":{"docker-manifest-digest":"sha256:0123456789abcdef"},"type":"open-horizon container image signature"},"optional":null}` {
+		t.Errorf("image %v has the wrong signature payload %v", image, string(payload))
+	}
+
+	if repo, digest, err := ImageDigestReference("openhorizon/gps:1.0@sha256:0123"); err != nil || repo != "openhorizon/gps" || digest != "sha256:0123" {
+		t.Errorf("the tag should not be part of the repository, got %v %v %v", repo, digest, err)
+	}
+
+	for _, image := range []string{"openhorizon/gps:1.0", "openhorizon/gps", ""} {
+		if _, err := ImageSignaturePayload(image); err == nil {
+			t.Errorf("image %v is not pinned by digest and should not have a signature payload", image)
+		}
+	}
+}
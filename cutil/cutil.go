@@ -27,6 +27,7 @@ const (
 	ANAX_SVC_MISSING_VARIABLE = "variable %v for service %v is missing from mappings."
 	ANAX_SVC_MISSING_CONFIG   = "service config for version %v of %v is missing."
 	ANAX_SVC_WRONG_TYPE       = "variable %v for service %v is "
+	ANAX_SVC_INVALID_VALUE    = "variable %v for service %v does not meet the schema, "
 )
 
 func FirstN(n int, ss []string) []string {
@@ -139,14 +140,19 @@ func NativeToEnvVariableMap(envMap map[string]string, varName string, varValue i
 	case json.Number:
 		envMap[varName] = varValue.(json.Number).String()
 	case []interface{}:
-		los := ""
+		elems := make([]string, 0)
 		for _, e := range varValue.([]interface{}) {
 			if _, ok := e.(string); ok {
-				los = los + e.(string) + " "
+				elems = append(elems, e.(string))
+			} else if _, ok := e.([]interface{}); !ok {
+				// the elements of a list whose type is set by the user input schema
+				elemMap := make(map[string]string)
+				if err := NativeToEnvVariableMap(elemMap, varName, e); err == nil {
+					elems = append(elems, elemMap[varName])
+				}
 			}
 		}
-		los = los[:len(los)-1]
-		envMap[varName] = los
+		envMap[varName] = strings.Join(elems, " ")
 	default:
 		return errors.New(fmt.Sprintf("unknown variable type %T for variable %v", varValue, varName))
 	}
@@ -162,7 +168,7 @@ func VerifyWorkloadVarTypes(varValue interface{}, expectedType string) error {
 			return errors.New(fmt.Sprintf("type %T, expecting %v.", varValue, expectedType))
 		}
 	case string:
-		if expectedType != "string" {
+		if expectedType != "string" && expectedType != "secret" {
 			return errors.New(fmt.Sprintf("type %T, expecting %v.", varValue, expectedType))
 		}
 	case json.Number:
@@ -173,7 +179,10 @@ func VerifyWorkloadVarTypes(varValue interface{}, expectedType string) error {
 			return errors.New(fmt.Sprintf("type float, expecting int."))
 		}
 	case []interface{}:
-		if expectedType != "list of strings" {
+		if expectedType == "list" {
+			// the type of the elements is checked against the user input schema
+			return nil
+		} else if expectedType != "list of strings" {
 			return errors.New(fmt.Sprintf("type %T, expecting %v.", varValue, expectedType))
 		} else {
 			for _, e := range varValue.([]interface{}) {
@@ -363,6 +372,19 @@ func FormDockerImageName(domain, path, tag, digest string) string {
 	return image
 }
 
+// Returns the name of the image on each registry mirror that serves its registry, in the order the mirrors are tried.
+func MirrorImagePaths(mirrors *config.RegistryMirrorsConfig, imagePath string) []string {
+	paths := make([]string, 0)
+	domain, path, tag, digest := ParseDockerImagePath(imagePath)
+	if path == "" {
+		return paths
+	}
+	for _, m := range mirrors.MirrorsOf(domain) {
+		paths = append(paths, FormDockerImageName(m.Mirror, m.Repository(domain, path), tag, digest))
+	}
+	return paths
+}
+
 func CopyMap(m1 map[string]interface{}, m2 map[string]interface{}) {
 	for k, v := range m1 {
 		m2[k] = v
@@ -4,6 +4,7 @@ package cutil
 
 import (
 	"fmt"
+	"github.com/open-horizon/anax/config"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -181,6 +182,25 @@ func Test_FormDockerImageName(t *testing.T) {
 
 }
 
+func Test_MirrorImagePaths(t *testing.T) {
+	mirrors := &config.RegistryMirrorsConfig{Mirrors: []config.RegistryMirror{
+		{Mirror: "mirror.local:5000", Registries: []string{"docker.io"}, Prefix: "dockerhub"},
+		{Mirror: "cache.local", Registries: []string{"myregistry.com:5000"}, Rewrites: []config.RegistryRewrite{{From: "edge/", To: "mirrored/edge/"}}},
+		{Mirror: "any.local"},
+	}}
+
+	paths := MirrorImagePaths(mirrors, "openhorizon/gps:1.0")
+	assert.Equal(t, []string{"mirror.local:5000/dockerhub/openhorizon/gps:1.0", "any.local/openhorizon/gps:1.0"}, paths, "A docker hub image should be found on the docker hub mirrors.")
+
+	paths = MirrorImagePaths(mirrors, "ubuntu@sha256:0123")
+	assert.Equal(t, "mirror.local:5000/dockerhub/library/ubuntu@sha256:0123", paths[0], "An official docker hub image should be in the library.")
+
+	paths = MirrorImagePaths(mirrors, "myregistry.com:5000/edge/gps:1.0")
+	assert.Equal(t, []string{"cache.local/mirrored/edge/gps:1.0", "any.local/edge/gps:1.0"}, paths, "The rewrite rule should change the repository on the mirror.")
+
+	assert.Equal(t, 0, len(MirrorImagePaths(&config.RegistryMirrorsConfig{}, "openhorizon/gps:1.0")), "There are no mirrors by default.")
+}
+
 func Test_TruncateDisplayString(t *testing.T) {
 	s1 := "1234567890"
 	assert.Equal(t, "12...", TruncateDisplayString(s1, 2), fmt.Sprintf("Should only show the first 2 charactors"))
@@ -0,0 +1,269 @@
+package cutil
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The longest maintenance window that is supported.
+const MAX_MAINTENANCE_WINDOW = 7 * 24 * time.Hour
+
+// The length of a window that is specified as just a time of day.
+const DEFAULT_MAINTENANCE_WINDOW = time.Hour
+
+// A maintenance window is a cron-like schedule for the start of the window followed by the length of the window. For example,
+// "0 2 * * 6 4h" opens a 4 hour window at 2am every Saturday. The schedule fields are minute (0-59), hour (0-23), day of
+// month (1-31), month (1-12) and day of week (0-6, Sunday is 0). Each field is a '*', a number, a range (1-5), a step (*/15 or
+// 0-30/10) or a comma separated list of these. When both the day of month and the day of week are restricted, a day matching
+// either one of them is in the schedule, just like cron. A window can also be specified as a time of day, "HH:MM" or "HH.MM"
+// optionally with AM or PM, e.g. "2:00AM" or "2:00 AM", followed by an optional length, which opens a window at that time
+// every day.
+type MaintenanceWindow struct {
+	Spec     string
+	Duration time.Duration
+	minutes  uint64
+	hours    uint64
+	doms     uint64
+	months   uint64
+	dows     uint64
+	domStar  bool
+	dowStar  bool
+}
+
+func (m MaintenanceWindow) String() string {
+	return m.Spec
+}
+
+type MaintenanceWindows []MaintenanceWindow
+
+func (m MaintenanceWindows) String() string {
+	specs := make([]string, 0, len(m))
+	for _, w := range m {
+		specs = append(specs, w.Spec)
+	}
+	return strings.Join(specs, "; ")
+}
+
+// Parse a single maintenance window.
+func ParseMaintenanceWindow(spec string) (*MaintenanceWindow, error) {
+
+	fields := strings.Fields(spec)
+	mw := &MaintenanceWindow{Spec: strings.Join(fields, " "), Duration: DEFAULT_MAINTENANCE_WINDOW}
+
+	// A time of day can have a space before AM or PM, e.g. "2:00 AM".
+	if len(fields) > 1 && strings.ContainsAny(fields[0], ":.") && (strings.EqualFold(fields[1], "AM") || strings.EqualFold(fields[1], "PM")) {
+		fields = append([]string{fields[0] + fields[1]}, fields[2:]...)
+	}
+
+	if len(fields) == 1 || (len(fields) == 2 && strings.ContainsAny(fields[0], ":.")) {
+		// A time of day, with an optional length.
+		if hour, minute, err := parseTimeOfDay(fields[0]); err != nil {
+			return nil, errors.New(fmt.Sprintf("maintenance window %v has an invalid time of day, %v", spec, err))
+		} else {
+			fields = append([]string{strconv.Itoa(minute), strconv.Itoa(hour), "*", "*", "*"}, fields[1:]...)
+		}
+	}
+
+	if len(fields) != 5 && len(fields) != 6 {
+		return nil, errors.New(fmt.Sprintf("maintenance window %v must have 5 schedule fields followed by a duration", spec))
+	}
+
+	var err error
+	if mw.minutes, err = parseScheduleField(fields[0], 0, 59); err != nil {
+		return nil, errors.New(fmt.Sprintf("maintenance window %v has an invalid minute field, %v", spec, err))
+	} else if mw.hours, err = parseScheduleField(fields[1], 0, 23); err != nil {
+		return nil, errors.New(fmt.Sprintf("maintenance window %v has an invalid hour field, %v", spec, err))
+	} else if mw.doms, err = parseScheduleField(fields[2], 1, 31); err != nil {
+		return nil, errors.New(fmt.Sprintf("maintenance window %v has an invalid day of month field, %v", spec, err))
+	} else if mw.months, err = parseScheduleField(fields[3], 1, 12); err != nil {
+		return nil, errors.New(fmt.Sprintf("maintenance window %v has an invalid month field, %v", spec, err))
+	} else if mw.dows, err = parseScheduleField(fields[4], 0, 6); err != nil {
+		return nil, errors.New(fmt.Sprintf("maintenance window %v has an invalid day of week field, %v", spec, err))
+	}
+	mw.domStar = strings.HasPrefix(fields[2], "*")
+	mw.dowStar = strings.HasPrefix(fields[4], "*")
+
+	if len(fields) == 6 {
+		if d, err := time.ParseDuration(fields[5]); err != nil {
+			return nil, errors.New(fmt.Sprintf("maintenance window %v has an invalid duration %v, %v", spec, fields[5], err))
+		} else if d < time.Minute || d > MAX_MAINTENANCE_WINDOW {
+			return nil, errors.New(fmt.Sprintf("maintenance window %v duration must be between 1m and %v", spec, MAX_MAINTENANCE_WINDOW))
+		} else {
+			mw.Duration = d
+		}
+	}
+
+	return mw, nil
+}
+
+// Parse a list of maintenance windows, ignoring empty ones.
+func ParseMaintenanceWindows(specs []string) (MaintenanceWindows, error) {
+	windows := make(MaintenanceWindows, 0, len(specs))
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		} else if mw, err := ParseMaintenanceWindow(spec); err != nil {
+			return nil, err
+		} else {
+			windows = append(windows, *mw)
+		}
+	}
+	return windows, nil
+}
+
+// Returns true if the window is open at the given time.
+func (m MaintenanceWindow) IsOpen(t time.Time) bool {
+	// The window is open if it started within the last Duration.
+	start := m.nextStart(t.Add(-m.Duration).Add(time.Nanosecond))
+	return !start.IsZero() && !start.After(t)
+}
+
+// Returns the time when the window next opens, which is the given time when the window is already open. The zero time is
+// returned if the schedule never matches, e.g. February 30th.
+func (m MaintenanceWindow) NextOpen(t time.Time) time.Time {
+	if m.IsOpen(t) {
+		return t
+	}
+	return m.nextStart(t)
+}
+
+// Returns true if any of the windows is open at the given time. A node without any maintenance windows is always open.
+func (m MaintenanceWindows) IsOpen(t time.Time) bool {
+	if len(m) == 0 {
+		return true
+	}
+	for _, w := range m {
+		if w.IsOpen(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the earliest time when one of the windows opens, or the zero time if none of them ever opens.
+func (m MaintenanceWindows) NextOpen(t time.Time) time.Time {
+	if m.IsOpen(t) {
+		return t
+	}
+	next := time.Time{}
+	for _, w := range m {
+		if n := w.NextOpen(t); !n.IsZero() && (next.IsZero() || n.Before(next)) {
+			next = n
+		}
+	}
+	return next
+}
+
+// Find the first scheduled start time at or after the given time. The search is limited to 5 years so that schedules which
+// can never match end the search.
+func (m MaintenanceWindow) nextStart(t time.Time) time.Time {
+
+	// Start times are on a minute boundary, round up to the next minute.
+	start := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	if start.Before(t) {
+		start = start.Add(time.Minute)
+	}
+
+	end := start.AddDate(5, 0, 0)
+	for start.Before(end) {
+		if m.months&(1<<uint(start.Month())) == 0 {
+			start = time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, start.Location())
+		} else if !m.dayMatches(start) {
+			start = time.Date(start.Year(), start.Month(), start.Day()+1, 0, 0, 0, 0, start.Location())
+		} else if m.hours&(1<<uint(start.Hour())) == 0 {
+			start = time.Date(start.Year(), start.Month(), start.Day(), start.Hour()+1, 0, 0, 0, start.Location())
+		} else if m.minutes&(1<<uint(start.Minute())) == 0 {
+			start = start.Add(time.Minute)
+		} else {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+func (m MaintenanceWindow) dayMatches(t time.Time) bool {
+	dom := m.doms&(1<<uint(t.Day())) != 0
+	dow := m.dows&(1<<uint(t.Weekday())) != 0
+	if m.domStar || m.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Parse a time of day, HH:MM or HH.MM on a 24 hour clock, or followed by AM or PM on a 12 hour clock, with or without a
+// space before it.
+func parseTimeOfDay(tod string) (int, int, error) {
+
+	upper := strings.ToUpper(strings.TrimSpace(tod))
+	ampm := ""
+	if strings.HasSuffix(upper, "AM") || strings.HasSuffix(upper, "PM") {
+		ampm = upper[len(upper)-2:]
+		upper = strings.TrimSpace(upper[:len(upper)-2])
+	}
+
+	hm := strings.FieldsFunc(upper, func(r rune) bool { return r == ':' || r == '.' })
+	if len(hm) != 2 {
+		return 0, 0, errors.New(fmt.Sprintf("%v is not HH:MM", tod))
+	}
+
+	hour, err := strconv.Atoi(hm[0])
+	if err != nil || hour < 0 || hour > 23 || (ampm != "" && (hour < 1 || hour > 12)) {
+		return 0, 0, errors.New(fmt.Sprintf("invalid hour %v", hm[0]))
+	}
+	minute, err := strconv.Atoi(hm[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, errors.New(fmt.Sprintf("invalid minute %v", hm[1]))
+	}
+
+	if ampm != "" {
+		hour = hour % 12
+		if ampm == "PM" {
+			hour += 12
+		}
+	}
+	return hour, minute, nil
+}
+
+// Parse a schedule field into a bit set of the values it matches.
+func parseScheduleField(field string, min int, max int) (uint64, error) {
+	bits := uint64(0)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i != -1 {
+			s, err := strconv.Atoi(part[i+1:])
+			if err != nil || s <= 0 {
+				return 0, errors.New(fmt.Sprintf("invalid step in %v", part))
+			}
+			rangePart, step = part[:i], s
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, errors.New(fmt.Sprintf("invalid value %v", bounds[0]))
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, errors.New(fmt.Sprintf("invalid value %v", bounds[1]))
+				}
+			} else if step != 1 {
+				// A single value with a step, e.g. 5/15, runs to the end of the range.
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, errors.New(fmt.Sprintf("%v is outside of %v-%v", part, min, max))
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,152 @@
+// +build unit
+
+package cutil
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_ParseMaintenanceWindow_Errors(t *testing.T) {
+
+	bad := []string{
+		"",
+		"0 2 * *",
+		"0 2 * * * 4h 5",
+		"60 2 * * *",
+		"0 24 * * *",
+		"0 2 0 * *",
+		"0 2 * 13 *",
+		"0 2 * * 7",
+		"0 2 * * 5-1",
+		"*/0 2 * * *",
+		"a 2 * * *",
+		"0 2 * * * forever",
+		"0 2 * * * 30s",
+		"0 2 * * * 200h",
+		"25:00",
+		"02:61",
+		"13:00PM",
+		"13:00 PM",
+		"2:00 AM PM",
+		"2",
+	}
+
+	for _, spec := range bad {
+		if _, err := ParseMaintenanceWindow(spec); err == nil {
+			t.Errorf("maintenance window %v should not parse", spec)
+		}
+	}
+
+	if _, err := ParseMaintenanceWindows([]string{"0 2 * * 6 4h", "nope"}); err == nil {
+		t.Errorf("a list with an invalid window should not parse")
+	}
+}
+
+func Test_MaintenanceWindow_IsOpen(t *testing.T) {
+
+	// Saturday, 2am for 4 hours.
+	mw, err := ParseMaintenanceWindow("0 2 * * 6 4h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// 2019-11-02 is a Saturday
+	tests := []struct {
+		t    time.Time
+		open bool
+	}{
+		{time.Date(2019, 11, 2, 1, 59, 59, 0, time.UTC), false},
+		{time.Date(2019, 11, 2, 2, 0, 0, 0, time.UTC), true},
+		{time.Date(2019, 11, 2, 5, 59, 59, 0, time.UTC), true},
+		{time.Date(2019, 11, 2, 6, 0, 0, 0, time.UTC), false},
+		{time.Date(2019, 11, 3, 3, 0, 0, 0, time.UTC), false},
+	}
+	for _, test := range tests {
+		if open := mw.IsOpen(test.t); open != test.open {
+			t.Errorf("window %v open at %v should be %v", mw, test.t, test.open)
+		}
+	}
+
+	// A window that runs over midnight.
+	mw, err = ParseMaintenanceWindow("30 23 * * 1-5 2h")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !mw.IsOpen(time.Date(2019, 11, 5, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("window %v should still be open after midnight", mw)
+	} else if mw.IsOpen(time.Date(2019, 11, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("window %v should not be open on Sunday morning", mw)
+	}
+
+	// A time of day window.
+	mw, err = ParseMaintenanceWindow("03:15 30m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if !mw.IsOpen(time.Date(2019, 11, 5, 3, 44, 0, 0, time.UTC)) {
+		t.Errorf("window %v should be open", mw)
+	} else if mw.IsOpen(time.Date(2019, 11, 5, 3, 45, 0, 0, time.UTC)) {
+		t.Errorf("window %v should be closed", mw)
+	}
+
+	// Time of day windows on a 12 hour clock.
+	for spec, hour := range map[string]int{"01.00AM": 1, "12:30am": 0, "12.30PM": 12, "11:30pm": 23, "2:30 AM": 2, "11:30 pm 30m": 23} {
+		if mw, err := ParseMaintenanceWindow(spec); err != nil {
+			t.Errorf("unexpected error parsing %v: %v", spec, err)
+		} else if !mw.IsOpen(time.Date(2019, 11, 5, hour, 45, 0, 0, time.UTC)) {
+			t.Errorf("window %v should be open at %v:45", spec, hour)
+		}
+	}
+}
+
+func Test_MaintenanceWindow_NextOpen(t *testing.T) {
+
+	now := time.Date(2019, 11, 4, 12, 0, 0, 0, time.UTC) // a Monday
+
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"0 2 * * 6 4h", time.Date(2019, 11, 9, 2, 0, 0, 0, time.UTC)},
+		{"*/20 13 * * *", time.Date(2019, 11, 4, 13, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 11 * * * 2h", now},
+		{"15,45 3 * * *", time.Date(2019, 11, 5, 3, 15, 0, 0, time.UTC)},
+		// day of month or day of week, like cron
+		{"0 0 15 * 3", time.Date(2019, 11, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 5 * 6", time.Date(2019, 11, 5, 0, 0, 0, 0, time.UTC)},
+		// never
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, test := range tests {
+		if mw, err := ParseMaintenanceWindow(test.spec); err != nil {
+			t.Errorf("unexpected error parsing %v: %v", test.spec, err)
+		} else if next := mw.NextOpen(now); !next.Equal(test.next) {
+			t.Errorf("window %v should next open at %v, but got %v", test.spec, test.next, next)
+		}
+	}
+}
+
+func Test_MaintenanceWindows(t *testing.T) {
+
+	now := time.Date(2019, 11, 4, 12, 0, 0, 0, time.UTC)
+
+	if windows, err := ParseMaintenanceWindows([]string{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if !windows.IsOpen(now) {
+		t.Errorf("no windows should always be open")
+	}
+
+	windows, err := ParseMaintenanceWindows([]string{"0 2 * * 6 4h", " ", "0 22 * * 3"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if len(windows) != 2 {
+		t.Errorf("there should be 2 windows, but got %v", windows)
+	} else if windows.IsOpen(now) {
+		t.Errorf("windows %v should be closed at %v", windows, now)
+	} else if next := windows.NextOpen(now); !next.Equal(time.Date(2019, 11, 6, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("windows %v should next open on Wednesday night, but got %v", windows, next)
+	} else if !windows.IsOpen(time.Date(2019, 11, 6, 22, 30, 0, 0, time.UTC)) {
+		t.Errorf("windows %v should be open on Wednesday night", windows)
+	}
+}
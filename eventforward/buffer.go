@@ -0,0 +1,190 @@
+package eventforward
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/glog"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const BUFFER_FILE = "buffer.jsonl"
+const POSITION_FILE = "position"
+
+// The event logs waiting to be forwarded, one JSON record per line, kept on disk so that they survive the target
+// being unreachable and anax restarting. The position file holds the record id of the last event log added to the
+// buffer, which is where forwarding picks up from after a restart. An event log can be forwarded twice if anax stops
+// between saving the buffer and saving the position, but it is never lost.
+type diskBuffer struct {
+	dir        string
+	maxRecords int
+	records    []json.RawMessage
+	lastId     uint64
+}
+
+// Open the buffer in the given directory, creating it if necessary, and load the records that were not forwarded yet.
+func openDiskBuffer(dir string, maxRecords int) (*diskBuffer, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("unable to create event log buffer directory %v, error %v", dir, err)
+	}
+
+	b := &diskBuffer{
+		dir:        dir,
+		maxRecords: maxRecords,
+		records:    make([]json.RawMessage, 0),
+	}
+
+	if pos, err := ioutil.ReadFile(b.positionFile()); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("unable to read event log forwarding position %v, error %v", b.positionFile(), err)
+	} else if err == nil {
+		if b.lastId, err = strconv.ParseUint(strings.TrimSpace(string(pos)), 10, 64); err != nil {
+			glog.Warningf(fwdlog(fmt.Sprintf("ignoring invalid event log forwarding position %v, error %v", string(pos), err)))
+		}
+	}
+
+	f, err := os.Open(b.bufferFile())
+	if os.IsNotExist(err) {
+		return b, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to open event log buffer %v, error %v", b.bufferFile(), err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		} else if !json.Valid(line) {
+			// A partly written line from a crash.
+			glog.Warningf(fwdlog(fmt.Sprintf("dropping invalid event log buffer record %v", string(line))))
+			continue
+		}
+		b.records = append(b.records, json.RawMessage(append([]byte{}, line...)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read event log buffer %v, error %v", b.bufferFile(), err)
+	}
+
+	b.trim()
+	return b, nil
+}
+
+func (b *diskBuffer) bufferFile() string {
+	return path.Join(b.dir, BUFFER_FILE)
+}
+
+func (b *diskBuffer) positionFile() string {
+	return path.Join(b.dir, POSITION_FILE)
+}
+
+// The record id of the last event log added to the buffer.
+func (b *diskBuffer) LastId() uint64 {
+	return b.lastId
+}
+
+// The records waiting to be forwarded, oldest first.
+func (b *diskBuffer) Records() []json.RawMessage {
+	return b.records
+}
+
+func (b *diskBuffer) Len() int {
+	return len(b.records)
+}
+
+// Add records to the end of the buffer and move the position to the given record id. Returns the number of old
+// records dropped to stay within the maximum size.
+func (b *diskBuffer) Add(records []json.RawMessage, lastId uint64) (int, error) {
+
+	dropped := 0
+	if len(records) != 0 {
+		b.records = append(b.records, records...)
+		if dropped = b.trim(); dropped != 0 {
+			if err := b.save(); err != nil {
+				return dropped, err
+			}
+		} else if err := b.append(records); err != nil {
+			return dropped, err
+		}
+	}
+
+	if lastId != b.lastId {
+		b.lastId = lastId
+		if err := writeFileAtomic(b.positionFile(), []byte(strconv.FormatUint(lastId, 10))); err != nil {
+			return dropped, fmt.Errorf("unable to save event log forwarding position, error %v", err)
+		}
+	}
+	return dropped, nil
+}
+
+// Remove the given number of records from the front of the buffer, once they have been forwarded.
+func (b *diskBuffer) Remove(n int) error {
+	if n > len(b.records) {
+		n = len(b.records)
+	}
+	b.records = b.records[n:]
+	return b.save()
+}
+
+// Forget the position, so that forwarding starts again from the first event log in the db.
+func (b *diskBuffer) ResetPosition() error {
+	b.lastId = 0
+	if err := os.Remove(b.positionFile()); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove event log forwarding position, error %v", err)
+	}
+	return nil
+}
+
+// Drop the oldest records over the maximum size. Returns the number dropped.
+func (b *diskBuffer) trim() int {
+	if b.maxRecords <= 0 || len(b.records) <= b.maxRecords {
+		return 0
+	}
+	dropped := len(b.records) - b.maxRecords
+	b.records = b.records[dropped:]
+	return dropped
+}
+
+func (b *diskBuffer) append(records []json.RawMessage) error {
+	f, err := os.OpenFile(b.bufferFile(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("unable to open event log buffer %v, error %v", b.bufferFile(), err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(joinLines(records)); err != nil {
+		return fmt.Errorf("unable to write event log buffer %v, error %v", b.bufferFile(), err)
+	}
+	return nil
+}
+
+func (b *diskBuffer) save() error {
+	if err := writeFileAtomic(b.bufferFile(), joinLines(b.records)); err != nil {
+		return fmt.Errorf("unable to write event log buffer %v, error %v", b.bufferFile(), err)
+	}
+	return nil
+}
+
+func joinLines(records []json.RawMessage) []byte {
+	var buf bytes.Buffer
+	for _, r := range records {
+		buf.Write(r)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// Write the file through a temporary file so that a crash never leaves it half written.
+func writeFileAtomic(name string, data []byte) error {
+	tmp := name + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
@@ -0,0 +1,20 @@
+package eventforward
+
+import (
+	"fmt"
+)
+
+// Forward the event logs now. When Final is true, the forwarder stops listening for new event logs afterwards.
+type ForwardCommand struct {
+	Final bool
+}
+
+func (f ForwardCommand) ShortString() string {
+	return fmt.Sprintf("%v", f)
+}
+
+func NewForwardCommand(final bool) *ForwardCommand {
+	return &ForwardCommand{
+		Final: final,
+	}
+}
@@ -0,0 +1,289 @@
+package eventforward
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/worker"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// The maximum number of event logs sent to the target at one time.
+const FORWARD_BATCH_SIZE = 100
+
+// The event log forwarder sends the event logs, as they are saved, to a central log service. The event logs are first
+// written to a buffer on disk and removed from it once the target has received them, so that event logs saved while
+// the target is unreachable are sent when it comes back. When the forwarder starts for the first time, all the event
+// logs already in the db are sent.
+type EventForwardWorker struct {
+	worker.BaseWorker // embedded field
+	db                *bolt.DB
+	sink              Sink
+	buffer            *diskBuffer
+	sub               *eventlog.Subscription
+	host              string
+	minSeverity       int
+	retryTime         time.Time
+	targetDown        bool
+}
+
+func NewEventForwardWorker(name string, cfg *config.HorizonConfig, db *bolt.DB) *EventForwardWorker {
+
+	worker := &EventForwardWorker{
+		BaseWorker: worker.NewBaseWorker(name, cfg, nil),
+		db:         db,
+	}
+
+	glog.Info(fwdlog(fmt.Sprintf("Starting Event Log Forwarder worker")))
+	worker.Start(worker, cfg.Edge.EventLogForwarder.ForwardIntervalS)
+	return worker
+}
+
+func (w *EventForwardWorker) Messages() chan events.Message {
+	return w.BaseWorker.Manager.Messages
+}
+
+func (w *EventForwardWorker) NewEvent(incoming events.Message) {
+
+	switch incoming.(type) {
+	case *events.NodeShutdownCompleteMessage:
+		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
+		switch msg.Event().Id {
+		case events.UNCONFIGURE_COMPLETE:
+			// Send the event logs of the unregistration before terminating.
+			w.Commands <- NewForwardCommand(true)
+			w.Commands <- worker.NewTerminateCommand("shutdown")
+		}
+
+	default: //nothing
+
+	}
+
+	return
+}
+
+func (w *EventForwardWorker) Initialize() bool {
+
+	fc := w.Config.Edge.EventLogForwarder
+
+	if w.minSeverity = severityRank(fc.MinSeverity); w.minSeverity < 0 {
+		glog.Errorf(fwdlog(fmt.Sprintf("the minimum severity %v is not valid, use %v, %v or %v", fc.MinSeverity, persistence.SEVERITY_INFO, persistence.SEVERITY_WARN, persistence.SEVERITY_ERROR)))
+		return false
+	}
+
+	if sink, err := NewSink(w.Config); err != nil {
+		glog.Errorf(fwdlog(fmt.Sprintf("unable to forward event logs, %v", err)))
+		return false
+	} else {
+		w.sink = sink
+	}
+
+	if buffer, err := openDiskBuffer(w.Config.GetEventLogForwarderBufferPath(), fc.BufferMaxRecords); err != nil {
+		glog.Errorf(fwdlog(fmt.Sprintf("unable to forward event logs, %v", err)))
+		return false
+	} else {
+		w.buffer = buffer
+	}
+
+	if host, err := os.Hostname(); err != nil {
+		glog.Warningf(fwdlog(fmt.Sprintf("unable to get the host name, error %v", err)))
+	} else {
+		w.host = host
+	}
+
+	glog.V(3).Infof(fwdlog(fmt.Sprintf("forwarding event logs to %v %v, %v event logs are buffered", fc.Type, fc.Target, w.buffer.Len())))
+
+	w.forward()
+	return true
+}
+
+func (w *EventForwardWorker) CommandHandler(command worker.Command) bool {
+
+	switch command.(type) {
+	case *ForwardCommand:
+		cmd, _ := command.(*ForwardCommand)
+		w.forward()
+		if cmd.Final {
+			w.stop()
+		}
+
+	default:
+		return false
+	}
+	return true
+}
+
+func (w *EventForwardWorker) NoWorkHandler() {
+	w.forward()
+}
+
+// Buffer the new event logs and send the buffered event logs to the target.
+func (w *EventForwardWorker) forward() {
+	w.collect()
+	w.send()
+}
+
+// End the subscription and close the connection to the target.
+func (w *EventForwardWorker) stop() {
+	if w.sub != nil {
+		w.sub.Close()
+		w.sub = nil
+	}
+	w.sink.Close()
+}
+
+// Move the event logs saved since the last time into the buffer.
+func (w *EventForwardWorker) collect() {
+
+	// When there is no subscription, subscribe first and then find the event logs saved without a subscription in
+	// the db, so that none are missed. The ones received twice are skipped by record id.
+	var missed []persistence.EventLog
+	if w.sub == nil {
+		w.sub = eventlog.Subscribe(map[string][]persistence.Selector{})
+		if elogs, err := w.missedEventLogs(); err != nil {
+			glog.Errorf(fwdlog(fmt.Sprintf("unable to get the event logs from the db, error %v", err)))
+			w.sub.Close()
+			w.sub = nil
+			return
+		} else {
+			missed = elogs
+		}
+	}
+
+	lastId := w.buffer.LastId()
+	records := make([]json.RawMessage, 0)
+
+	add := func(el *persistence.EventLog) {
+		id, err := strconv.ParseUint(el.Id, 10, 64)
+		if err != nil || id <= lastId {
+			return
+		}
+		lastId = id
+		if severityRank(el.Severity) < w.minSeverity {
+			return
+		}
+		if r, err := json.Marshal(NewEventRecord(el, w.host)); err != nil {
+			glog.Errorf(fwdlog(fmt.Sprintf("unable to marshal event log %v, error %v", el.Id, err)))
+		} else {
+			records = append(records, r)
+		}
+	}
+
+	for i := range missed {
+		add(&missed[i])
+	}
+
+	for draining := true; draining; {
+		select {
+		case el, ok := <-w.sub.Events:
+			if !ok {
+				glog.Warningf(fwdlog("the event log subscription ended, the missed event logs will be read from the db"))
+				w.sub = nil
+				draining = false
+			} else {
+				add(&el)
+			}
+		default:
+			draining = false
+		}
+	}
+
+	if dropped, err := w.buffer.Add(records, lastId); err != nil {
+		glog.Errorf(fwdlog(fmt.Sprintf("unable to buffer event logs, error %v", err)))
+	} else if dropped != 0 {
+		glog.Warningf(fwdlog(fmt.Sprintf("the event log buffer is full, dropped the %v oldest event logs", dropped)))
+	}
+}
+
+// Return the event logs from all registrations, sorted by record id. If the db has been recreated since the
+// forwarding position was saved, the position is reset so that the event logs in the new db are sent.
+func (w *EventForwardWorker) missedEventLogs() ([]persistence.EventLog, error) {
+
+	elogs, err := persistence.FindEventLogsWithSelectors(w.db, true, map[string][]persistence.Selector{})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]uint64, len(elogs))
+	maxId := uint64(0)
+	for _, el := range elogs {
+		id, _ := strconv.ParseUint(el.Id, 10, 64)
+		ids[el.Id] = id
+		if id > maxId {
+			maxId = id
+		}
+	}
+	sort.Slice(elogs, func(i, j int) bool { return ids[elogs[i].Id] < ids[elogs[j].Id] })
+
+	if maxId < w.buffer.LastId() {
+		glog.V(3).Infof(fwdlog(fmt.Sprintf("the event log db was recreated, forwarding from the first event log")))
+		if err := w.buffer.ResetPosition(); err != nil {
+			return nil, err
+		}
+	}
+	return elogs, nil
+}
+
+// Send the buffered event logs to the target, unless it was unreachable recently.
+func (w *EventForwardWorker) send() {
+
+	if w.buffer.Len() == 0 || time.Now().Before(w.retryTime) {
+		return
+	}
+
+	for w.buffer.Len() != 0 {
+		batch := w.buffer.Records()
+		if len(batch) > FORWARD_BATCH_SIZE {
+			batch = batch[:FORWARD_BATCH_SIZE]
+		}
+
+		sent, err := w.sink.Send(batch)
+		if sent != 0 {
+			if err := w.buffer.Remove(sent); err != nil {
+				glog.Errorf(fwdlog(fmt.Sprintf("unable to remove the forwarded event logs from the buffer, error %v", err)))
+			}
+		}
+
+		if err != nil {
+			if !w.targetDown {
+				glog.Warningf(fwdlog(fmt.Sprintf("%v, the event logs are buffered until the target can be reached", err)))
+			} else {
+				glog.V(5).Infof(fwdlog(fmt.Sprintf("%v, %v event logs are buffered", err, w.buffer.Len())))
+			}
+			w.targetDown = true
+			w.retryTime = time.Now().Add(time.Duration(w.Config.Edge.EventLogForwarder.RetryIntervalS) * time.Second)
+			return
+		}
+	}
+
+	if w.targetDown {
+		glog.Infof(fwdlog(fmt.Sprintf("the event log forwarding target can be reached again, the buffered event logs were sent")))
+		w.targetDown = false
+	}
+}
+
+// Order the severities so that the minimum severity can be applied. Returns -1 for an unknown severity.
+func severityRank(severity string) int {
+	switch severity {
+	case persistence.SEVERITY_INFO:
+		return 0
+	case persistence.SEVERITY_WARN:
+		return 1
+	case persistence.SEVERITY_ERROR:
+		return 2
+	default:
+		return -1
+	}
+}
+
+var fwdlog = func(v interface{}) string {
+	return fmt.Sprintf("Event Log Forwarder: %v", v)
+}
@@ -0,0 +1,263 @@
+// +build unit
+
+package eventforward
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/worker"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Forwarder_File(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	// an event log saved before the forwarder starts is exported
+	assert.Nil(t, eventlog.LogNodeEvent(db, persistence.SEVERITY_INFO, "node registered", persistence.EC_NODE_CONFIG_REG_COMPLETE, "node1", "myorg", "", "configured"), "The event should be saved.")
+
+	target := path.Join(dir, "eventlogs.jsonl")
+	w := newTestWorker(dir, db, config.EventLogForwarderConfig{Type: config.EVENTLOG_FORWARD_FILE, Target: target, MinSeverity: persistence.SEVERITY_INFO})
+	assert.True(t, w.Initialize(), "The forwarder should initialize.")
+	defer w.stop()
+
+	assert.Nil(t, eventlog.LogServiceEvent2(db, persistence.SEVERITY_ERROR, "service failed", persistence.EC_ERROR_START_CONTAINER, "inst1", "http://sensor1.org", "myorg", "1.0.0", "amd64", []string{}), "The event should be saved.")
+	w.forward()
+
+	records := readRecords(t, target)
+	assert.Equal(t, 2, len(records), "Both event logs should be forwarded.")
+	assert.Equal(t, "node registered", records[0]["message"], "The old event log should be forwarded first.")
+	assert.Equal(t, persistence.SRC_TYPE_SVC, records[1]["source_type"], "The source type should be forwarded.")
+	source, _ := records[1]["event_source"].(map[string]interface{})
+	assert.Equal(t, "http://sensor1.org", source["service_url"], "The typed event source should be forwarded.")
+
+	// nothing is sent twice
+	w.forward()
+	assert.Equal(t, 2, len(readRecords(t, target)), "The event logs should be forwarded once.")
+	assert.Equal(t, 0, w.buffer.Len(), "The buffer should be empty.")
+}
+
+func Test_Forwarder_HTTP_Buffering(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	up := false
+	received := make([]map[string]interface{}, 0)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"), "The configured headers should be sent.")
+		var batch []map[string]interface{}
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Nil(t, json.Unmarshal(body, &batch), "The body should be a json array.")
+		received = append(received, batch...)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	fc := config.EventLogForwarderConfig{Type: config.EVENTLOG_FORWARD_HTTP, Target: server.URL, HTTPHeaders: map[string]string{"Authorization": "Bearer token"}, MinSeverity: persistence.SEVERITY_WARN}
+	w := newTestWorker(dir, db, fc)
+	assert.True(t, w.Initialize(), "The forwarder should initialize.")
+
+	assert.Nil(t, eventlog.LogDatabaseEvent(db, persistence.SEVERITY_INFO, "db info", persistence.EC_DATABASE_ERROR), "The event should be saved.")
+	assert.Nil(t, eventlog.LogDatabaseEvent(db, persistence.SEVERITY_WARN, "db warning", persistence.EC_DATABASE_ERROR), "The event should be saved.")
+	assert.Nil(t, eventlog.LogDatabaseEvent(db, persistence.SEVERITY_ERROR, "db error", persistence.EC_DATABASE_ERROR), "The event should be saved.")
+	w.forward()
+
+	assert.Equal(t, 0, len(received), "Nothing should be received while the target is down.")
+	assert.Equal(t, 2, w.buffer.Len(), "The warning and error event logs should be buffered.")
+	w.stop()
+
+	// the buffer survives a restart, and the event logs saved meanwhile are picked up from the db
+	assert.Nil(t, eventlog.LogDatabaseEvent(db, persistence.SEVERITY_ERROR, "db error 2", persistence.EC_DATABASE_ERROR), "The event should be saved.")
+	up = true
+	w = newTestWorker(dir, db, fc)
+	assert.True(t, w.Initialize(), "The forwarder should initialize.")
+	defer w.stop()
+
+	assert.Equal(t, 3, len(received), "The buffered and missed event logs should be sent.")
+	assert.Equal(t, "db warning", received[0]["message"], "The oldest event log should be sent first.")
+	assert.Equal(t, "db error 2", received[2]["message"], "The missed event log should be sent last.")
+	assert.Equal(t, 0, w.buffer.Len(), "The buffer should be empty.")
+}
+
+func Test_Forwarder_BufferLimit(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "utbuffer-")
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	b, err := openDiskBuffer(dir, 3)
+	assert.Nil(t, err, "The buffer should open.")
+
+	records := []json.RawMessage{json.RawMessage(`{"record_id":"1"}`), json.RawMessage(`{"record_id":"2"}`)}
+	dropped, err := b.Add(records, 2)
+	assert.Nil(t, err, "Adding should not fail.")
+	assert.Equal(t, 0, dropped, "Nothing should be dropped.")
+
+	records = []json.RawMessage{json.RawMessage(`{"record_id":"3"}`), json.RawMessage(`{"record_id":"4"}`)}
+	dropped, err = b.Add(records, 4)
+	assert.Nil(t, err, "Adding should not fail.")
+	assert.Equal(t, 1, dropped, "The oldest record should be dropped.")
+
+	// a partly written record is ignored when the buffer is loaded
+	f, _ := os.OpenFile(path.Join(dir, BUFFER_FILE), os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"record_id":"5`)
+	f.Close()
+
+	b, err = openDiskBuffer(dir, 3)
+	assert.Nil(t, err, "The buffer should open.")
+	assert.Equal(t, uint64(4), b.LastId(), "The position should be loaded.")
+	assert.Equal(t, 3, b.Len(), "The valid records should be loaded.")
+	assert.Equal(t, `{"record_id":"2"}`, string(b.Records()[0]), "The oldest record should be first.")
+
+	assert.Nil(t, b.Remove(2), "Removing should not fail.")
+	b, err = openDiskBuffer(dir, 3)
+	assert.Nil(t, err, "The buffer should open.")
+	assert.Equal(t, 1, b.Len(), "The removed records should stay removed.")
+}
+
+func Test_syslogSink(t *testing.T) {
+
+	record := json.RawMessage(`{"record_id":"7","timestamp":"2019-11-02T03:00:00Z","host":"node1","severity":"error","message":"failed","event_code":"error_start_container"}`)
+	expected := `<27>1 2019-11-02T03:00:00Z node1 anax `
+
+	// udp, one message per datagram
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err, "The udp listener should start.")
+	defer conn.Close()
+
+	sink, err := newSyslogSink("udp://" + conn.LocalAddr().String())
+	assert.Nil(t, err, "The syslog sink should be created.")
+	sent, err := sink.Send([]json.RawMessage{record})
+	assert.Nil(t, err, "Sending should not fail.")
+	assert.Equal(t, 1, sent, "The record should be sent.")
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.Nil(t, err, "The message should be received.")
+	msg := string(buf[:n])
+	assert.True(t, strings.HasPrefix(msg, expected), "The message should have an RFC5424 header: "+msg)
+	assert.True(t, strings.HasSuffix(msg, " error_start_container - "+string(record)), "The message should end with the json record: "+msg)
+	sink.Close()
+
+	// tcp, octet counting framing
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err, "The tcp listener should start.")
+	defer ln.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		if c, err := ln.Accept(); err == nil {
+			defer c.Close()
+			line, _ := bufio.NewReader(c).ReadString('}')
+			lines <- line
+		}
+	}()
+
+	sink, err = newSyslogSink("tcp://" + ln.Addr().String())
+	assert.Nil(t, err, "The syslog sink should be created.")
+	defer sink.Close()
+	_, err = sink.Send([]json.RawMessage{record})
+	assert.Nil(t, err, "Sending should not fail.")
+
+	select {
+	case line := <-lines:
+		frame := string(sink.format(record))
+		assert.Equal(t, strings.Split(line, " ")[0], strconv.Itoa(len(frame)), "The message should start with its length.")
+	case <-time.After(5 * time.Second):
+		t.Errorf("The tcp message was not received.")
+	}
+
+	_, err = newSyslogSink("tls://host:6514")
+	assert.NotNil(t, err, "An unsupported syslog transport should be rejected.")
+}
+
+func newTestWorker(dir string, db *bolt.DB, fc config.EventLogForwarderConfig) *EventForwardWorker {
+	fc.BufferPath = path.Join(dir, "buffer")
+	fc.BufferMaxRecords = 100
+	fc.RetryIntervalS = 0
+
+	cfg := &config.HorizonConfig{
+		Collaborators: config.Collaborators{
+			HTTPClientFactory: &config.HTTPClientFactory{
+				NewHTTPClient: func(overrideTimeoutS *uint) *http.Client { return &http.Client{Timeout: 5 * time.Second} },
+			},
+		},
+	}
+	cfg.Edge.EventLogForwarder = fc
+
+	return &EventForwardWorker{
+		BaseWorker: worker.NewBaseWorker("EventLogForwarder", cfg, nil),
+		db:         db,
+	}
+}
+
+func readRecords(t *testing.T, file string) []map[string]interface{} {
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("unable to open %v: %v", file, err)
+	}
+	defer f.Close()
+
+	records := make([]map[string]interface{}, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid json line %v: %v", scanner.Text(), err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+func utsetup() (string, *bolt.DB, error) {
+	dir, err := ioutil.TempDir("", "utdb-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	db, err := bolt.Open(path.Join(dir, "anax-int.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return dir, nil, err
+	}
+
+	return dir, db, nil
+}
+
+func cleanTestDir(dirPath string) error {
+	if _, err := os.Stat(dirPath); !os.IsNotExist(err) {
+		if err := os.RemoveAll(dirPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
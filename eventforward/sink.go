@@ -0,0 +1,241 @@
+package eventforward
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/persistence"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// The format of a forwarded event log. It is the event log returned by the /eventlog API, with the time stamp in
+// RFC3339 format and the name of the host the event log came from. The event source is in the format of its
+// source type.
+type EventRecord struct {
+	RecordId   string                           `json:"record_id"`
+	Timestamp  string                           `json:"timestamp"`
+	Host       string                           `json:"host"`
+	Severity   string                           `json:"severity"`
+	Message    string                           `json:"message"`
+	EventCode  string                           `json:"event_code"`
+	SourceType string                           `json:"source_type"`
+	Source     persistence.EventSourceInterface `json:"event_source"`
+}
+
+func NewEventRecord(el *persistence.EventLog, host string) *EventRecord {
+	return &EventRecord{
+		RecordId:   el.Id,
+		Timestamp:  time.Unix(int64(el.Timestamp), 0).UTC().Format(time.RFC3339),
+		Host:       host,
+		Severity:   el.Severity,
+		Message:    el.Message,
+		EventCode:  el.EventCode,
+		SourceType: el.SourceType,
+		Source:     el.Source,
+	}
+}
+
+// The fields of a buffered record needed to build a syslog header.
+type recordHeader struct {
+	Timestamp string `json:"timestamp"`
+	Host      string `json:"host"`
+	Severity  string `json:"severity"`
+	EventCode string `json:"event_code"`
+}
+
+// A sink sends the event log records to where they are forwarded. Send returns the number of records, from the
+// front of the list, that were sent before an error occurred.
+type Sink interface {
+	Send(records []json.RawMessage) (int, error)
+	Close()
+}
+
+// Create the sink for the forwarding target in the config.
+func NewSink(cfg *config.HorizonConfig) (Sink, error) {
+	fc := cfg.Edge.EventLogForwarder
+	if fc.Target == "" {
+		return nil, fmt.Errorf("the event log forwarding target must be specified")
+	}
+
+	switch fc.Type {
+	case config.EVENTLOG_FORWARD_SYSLOG:
+		return newSyslogSink(fc.Target)
+	case config.EVENTLOG_FORWARD_FILE:
+		return &fileSink{path: fc.Target}, nil
+	case config.EVENTLOG_FORWARD_HTTP:
+		if u, err := url.Parse(fc.Target); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return nil, fmt.Errorf("the event log forwarding target %v is not an http or https URL", fc.Target)
+		}
+		return &httpSink{url: fc.Target, headers: fc.HTTPHeaders, client: cfg.Collaborators.HTTPClientFactory.NewHTTPClient(nil)}, nil
+	default:
+		return nil, fmt.Errorf("the event log forwarding type %v is not supported, use %v, %v or %v", fc.Type, config.EVENTLOG_FORWARD_SYSLOG, config.EVENTLOG_FORWARD_FILE, config.EVENTLOG_FORWARD_HTTP)
+	}
+}
+
+// The syslog facility used for the event logs, daemon.
+const SYSLOG_FACILITY = 3
+
+const SYSLOG_APP_NAME = "anax"
+
+const SYSLOG_TIMEOUT = 10 * time.Second
+
+// Sends each record as an RFC5424 syslog message whose message is the JSON record. Over tcp, the messages are framed
+// by octet counting as described in RFC6587. Over udp and unix sockets, each message is a datagram.
+type syslogSink struct {
+	network string
+	address string
+	conn    net.Conn
+	procId  int
+}
+
+func newSyslogSink(target string) (*syslogSink, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("the syslog address %v is not valid, error %v", target, err)
+	}
+
+	switch u.Scheme {
+	case "udp", "tcp":
+		if u.Host == "" {
+			return nil, fmt.Errorf("the syslog address %v has no host", target)
+		}
+		return &syslogSink{network: u.Scheme, address: u.Host, procId: os.Getpid()}, nil
+	case "unix":
+		if u.Path == "" {
+			return nil, fmt.Errorf("the syslog address %v has no socket path", target)
+		}
+		return &syslogSink{network: "unixgram", address: u.Path, procId: os.Getpid()}, nil
+	default:
+		return nil, fmt.Errorf("the syslog address %v must start with udp://, tcp:// or unix://", target)
+	}
+}
+
+func (s *syslogSink) Send(records []json.RawMessage) (int, error) {
+	if s.conn == nil {
+		conn, err := net.DialTimeout(s.network, s.address, SYSLOG_TIMEOUT)
+		if err != nil {
+			return 0, fmt.Errorf("unable to connect to syslog %v %v, error %v", s.network, s.address, err)
+		}
+		s.conn = conn
+	}
+
+	for i, r := range records {
+		msg := s.format(r)
+		if s.network == "tcp" {
+			msg = append([]byte(fmt.Sprintf("%d ", len(msg))), msg...)
+		}
+
+		s.conn.SetWriteDeadline(time.Now().Add(SYSLOG_TIMEOUT))
+		if _, err := s.conn.Write(msg); err != nil {
+			s.Close()
+			return i, fmt.Errorf("unable to send to syslog %v %v, error %v", s.network, s.address, err)
+		}
+	}
+	return len(records), nil
+}
+
+// Format the record as an RFC5424 message. The event code is the message id.
+func (s *syslogSink) format(record json.RawMessage) []byte {
+	var h recordHeader
+	json.Unmarshal(record, &h)
+
+	timestamp := h.Timestamp
+	if timestamp == "" {
+		timestamp = "-"
+	}
+	host := h.Host
+	if host == "" {
+		host = "-"
+	}
+	msgId := h.EventCode
+	if msgId == "" {
+		msgId = "-"
+	} else if len(msgId) > 32 {
+		msgId = msgId[:32]
+	}
+
+	header := fmt.Sprintf("<%d>1 %v %v %v %d %v - ", SYSLOG_FACILITY*8+syslogSeverity(h.Severity), timestamp, host, SYSLOG_APP_NAME, s.procId, msgId)
+	return append([]byte(header), record...)
+}
+
+func (s *syslogSink) Close() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+}
+
+// Convert the event log severity to the syslog severity.
+func syslogSeverity(severity string) int {
+	switch severity {
+	case persistence.SEVERITY_ERROR:
+		return 3
+	case persistence.SEVERITY_WARN:
+		return 4
+	default:
+		return 6
+	}
+}
+
+// Appends the records to a file, one JSON record per line.
+type fileSink struct {
+	path string
+}
+
+func (s *fileSink) Send(records []json.RawMessage) (int, error) {
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return 0, fmt.Errorf("unable to open event log file %v, error %v", s.path, err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(joinLines(records)); err != nil {
+		return 0, fmt.Errorf("unable to write event log file %v, error %v", s.path, err)
+	}
+	return len(records), nil
+}
+
+func (s *fileSink) Close() {}
+
+// POSTs the records to a URL as a JSON array.
+type httpSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func (s *httpSink) Send(records []json.RawMessage) (int, error) {
+	body, err := json.Marshal(records)
+	if err != nil {
+		return 0, fmt.Errorf("unable to marshal the event logs, error %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewBuffer(body))
+	if err != nil {
+		return 0, fmt.Errorf("unable to create request for %v, error %v", s.url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("unable to send the event logs to %v, error %v", s.url, err)
+	}
+	defer resp.Body.Close()
+	ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("unable to send the event logs to %v, HTTP code %v", s.url, resp.StatusCode)
+	}
+	return len(records), nil
+}
+
+func (s *httpSink) Close() {}
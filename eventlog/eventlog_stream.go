@@ -0,0 +1,86 @@
+package eventlog
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/persistence"
+	"sync"
+)
+
+// The number of event logs that can be waiting for a subscriber before the subscriber is considered too slow.
+const SUBSCRIPTION_BUFFER_SIZE = 100
+
+// A subscription receives the event logs that match its selectors as they are saved. If the subscriber does
+// not keep up with the event logs being saved, the subscription is ended and the Events channel is closed. The
+// subscriber can then find the event logs it missed in the db by record id.
+type Subscription struct {
+	Events           chan persistence.EventLog
+	id               uint64
+	base_selectors   map[string][]persistence.Selector
+	source_selectors map[string][]persistence.Selector
+}
+
+var subscriptions = make(map[uint64]*Subscription)
+var subscriptionsLock sync.Mutex
+var nextSubscriptionId uint64
+
+// Subscribe to the event logs that match the given selectors. The selectors have the same format as those used by
+// GetEventLogs. The caller must call Close when the subscription is no longer needed.
+func Subscribe(selectors map[string][]persistence.Selector) *Subscription {
+	subscriptionsLock.Lock()
+	defer subscriptionsLock.Unlock()
+
+	base_selectors, source_selectors := persistence.GroupSelectors(selectors)
+
+	nextSubscriptionId++
+	sub := &Subscription{
+		Events:           make(chan persistence.EventLog, SUBSCRIPTION_BUFFER_SIZE),
+		id:               nextSubscriptionId,
+		base_selectors:   base_selectors,
+		source_selectors: source_selectors,
+	}
+	subscriptions[sub.id] = sub
+	return sub
+}
+
+// End the subscription. It is safe to call Close on a subscription that has already ended.
+func (s *Subscription) Close() {
+	subscriptionsLock.Lock()
+	defer subscriptionsLock.Unlock()
+	s.end()
+}
+
+// The caller must hold the subscriptions lock.
+func (s *Subscription) end() {
+	if _, ok := subscriptions[s.id]; ok {
+		delete(subscriptions, s.id)
+		close(s.Events)
+	}
+}
+
+// Send a newly saved event log to the subscribers whose selectors it matches.
+func publish(event_log *persistence.EventLog) {
+	subscriptionsLock.Lock()
+	defer subscriptionsLock.Unlock()
+
+	for _, sub := range subscriptions {
+		if !event_log.Matches2(sub.base_selectors, sub.source_selectors) {
+			continue
+		}
+		select {
+		case sub.Events <- *event_log:
+		default:
+			glog.Warningf("Ending event log subscription %v, the subscriber is not keeping up with the event logs.", sub.id)
+			sub.end()
+		}
+	}
+}
+
+// Save the eventlog into the db and send it to the subscribers.
+func saveEventLog(db *bolt.DB, event_log *persistence.EventLog) error {
+	if err := persistence.SaveEventLog(db, event_log); err != nil {
+		return err
+	}
+	publish(event_log)
+	return nil
+}
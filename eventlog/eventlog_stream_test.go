@@ -0,0 +1,69 @@
+// +build unit
+
+package eventlog
+
+import (
+	"github.com/open-horizon/anax/persistence"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Subscribe(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	// one subscriber for everything, one for the errors of a single service
+	all := Subscribe(map[string][]persistence.Selector{})
+	defer all.Close()
+
+	selectors, err := persistence.ConvertToSelectors(map[string][]string{"severity": []string{"error"}, "service_url": []string{"~sensor1"}})
+	assert.Nil(t, err, "The selectors should convert.")
+	svcErrors := Subscribe(selectors)
+
+	assert.Nil(t, LogServiceEvent2(db, persistence.SEVERITY_INFO, "service started", "service_started", "inst1", "http://sensor1.org", "myorg", "1.0.0", "amd64", []string{}), "The event should be saved.")
+	assert.Nil(t, LogServiceEvent2(db, persistence.SEVERITY_ERROR, "service failed", "service_failed", "inst1", "http://sensor1.org", "myorg", "1.0.0", "amd64", []string{}), "The event should be saved.")
+	assert.Nil(t, LogServiceEvent2(db, persistence.SEVERITY_ERROR, "service failed", "service_failed", "inst2", "http://sensor2.org", "myorg", "1.0.0", "amd64", []string{}), "The event should be saved.")
+	assert.Nil(t, LogNodeEvent(db, persistence.SEVERITY_INFO, "node registered", "node_registered", "node1", "myorg", "", "configured"), "The event should be saved.")
+
+	assert.Equal(t, 4, len(all.Events), "The subscriber for everything should receive all the events.")
+	assert.Equal(t, 1, len(svcErrors.Events), "The subscriber for the service errors should receive one event.")
+
+	el := <-svcErrors.Events
+	assert.Equal(t, "service failed", el.Message, "The service error should be received.")
+	assert.Equal(t, "2", el.Id, "The record id should be set before the event is sent.")
+
+	// a closed subscription gets nothing more
+	svcErrors.Close()
+	svcErrors.Close()
+	assert.Nil(t, LogServiceEvent2(db, persistence.SEVERITY_ERROR, "service failed", "service_failed", "inst1", "http://sensor1.org", "myorg", "1.0.0", "amd64", []string{}), "The event should be saved.")
+	_, ok := <-svcErrors.Events
+	assert.False(t, ok, "The events channel should be closed.")
+	assert.Equal(t, 5, len(all.Events), "The subscriber for everything should still receive events.")
+}
+
+func Test_Subscribe_SlowSubscriber(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	slow := Subscribe(map[string][]persistence.Selector{})
+	defer slow.Close()
+
+	for i := 0; i <= SUBSCRIPTION_BUFFER_SIZE; i++ {
+		assert.Nil(t, LogDatabaseEvent(db, persistence.SEVERITY_INFO, "db event", "db_event"), "The event should be saved.")
+	}
+
+	// the buffered events are still delivered, then the channel is closed
+	count := 0
+	for range slow.Events {
+		count++
+	}
+	assert.Equal(t, SUBSCRIPTION_BUFFER_SIZE, count, "The buffered events should be received before the subscription ends.")
+}
@@ -41,6 +41,9 @@ const (
 	IMAGE_FETCH_ERROR      EventId = "IMAGE_FETCH_ERROR"
 	IMAGE_FETCH_AUTH_ERROR EventId = "IMAGE_FETCH_AUTH_ERROR"
 	IMAGE_SIG_VERIF_ERROR  EventId = "IMAGE_SIG_VERIF_ERROR"
+	IMAGE_PREFETCH         EventId = "IMAGE_PREFETCH"
+	IMAGE_PREFETCHED       EventId = "IMAGE_PREFETCHED"
+	IMAGE_PREFETCH_ERROR   EventId = "IMAGE_PREFETCH_ERROR"
 
 	// container-related
 	EXECUTION_FAILED    EventId = "EXECUTION_FAILED"
@@ -49,11 +52,14 @@ const (
 	CONTAINER_STOPPING  EventId = "CONTAINER_STOPPING"
 	CONTAINER_DESTROYED EventId = "CONTAINER_DESTROYED"
 	CONTAINER_MAINTAIN  EventId = "CONTAINER_MAINTAIN"
+	CONFIG_RELOAD       EventId = "CONFIG_RELOAD"
 	LOAD_CONTAINER      EventId = "LOAD_CONTAINER"
 	START_MICROSERVICE  EventId = "START_MICROSERVICE"
 	CANCEL_MICROSERVICE EventId = "CANCEL_MICROSERVICE"
 	NEW_BC_CLIENT       EventId = "NEW_BC_CONTAINER"
 	IMAGE_LOAD_FAILED   EventId = "IMAGE_LOAD_FAILED"
+	CONTAINER_UNHEALTHY EventId = "CONTAINER_UNHEALTHY"
+	CONTAINER_HEALTHY   EventId = "CONTAINER_HEALTHY"
 
 	// policy-related
 	NEW_POLICY             EventId = "NEW_POLICY"
@@ -84,6 +90,7 @@ const (
 	NODE_HEARTBEAT_FAILED   EventId = "HEARTBEAT_FAILED"
 	NODE_HEARTBEAT_RESTORED EventId = "HEARTBEAT_RESTORED"
 	UPDATE_NODE_USERINPUT   EventId = "UPDATE_USER_INPUT"
+	UPDATE_NODE_SECRETS     EventId = "UPDATE_NODE_SECRETS"
 
 	// Service related
 	SERVICE_SUSPENDED EventId = "SERVICE_SUSPENDED"
@@ -100,6 +107,7 @@ const (
 	AG_TERMINATED EndContractCause = "AG_TERMINATED"
 	AG_ERROR      EndContractCause = "AG_ERROR"
 	AG_FULFILLED  EndContractCause = "AG_FULFILLED"
+	AG_UPGRADED   EndContractCause = "AG_UPGRADED" // the agbot ended the agreement to upgrade the workload
 )
 
 type Message interface {
@@ -126,6 +134,8 @@ type AgreementLaunchContext struct {
 	ConfigureRaw         []byte
 	EnvironmentAdditions *map[string]string // provided by platform, not but user
 	Microservices        []MicroserviceSpec // for ms split.
+	// The merged user input, written to a file for the services that reload their configuration.
+	UserInput map[string]interface{}
 }
 
 func (c AgreementLaunchContext) String() string {
@@ -691,6 +701,7 @@ type ImageFetchMessage struct {
 	event                 Event
 	DeploymentDescription *containermessage.DeploymentDescription
 	LaunchContext         interface{}
+	ImageSources          map[string]string // the registry or mirror each image was pulled from
 }
 
 // fulfill interface of events.Message
@@ -699,14 +710,14 @@ func (b *ImageFetchMessage) Event() Event {
 }
 
 func (b *ImageFetchMessage) String() string {
-	return fmt.Sprintf("event: %v, deploymentDescription: %v, launchContext: %v", b.event, b.DeploymentDescription, b.LaunchContext)
+	return fmt.Sprintf("event: %v, deploymentDescription: %v, launchContext: %v, imageSources: %v", b.event, b.DeploymentDescription, b.LaunchContext, b.ImageSources)
 }
 
 func (b *ImageFetchMessage) ShortString() string {
-	return fmt.Sprintf("event: %v, deploymentDescription: %v, launchContext: %v", b.event, b.DeploymentDescription, b.LaunchContext)
+	return fmt.Sprintf("event: %v, deploymentDescription: %v, launchContext: %v, imageSources: %v", b.event, b.DeploymentDescription, b.LaunchContext, b.ImageSources)
 }
 
-func NewImageFetchMessage(id EventId, deploymentDescription *containermessage.DeploymentDescription, launchContext interface{}) *ImageFetchMessage {
+func NewImageFetchMessage(id EventId, deploymentDescription *containermessage.DeploymentDescription, launchContext interface{}, imageSources map[string]string) *ImageFetchMessage {
 
 	return &ImageFetchMessage{
 		event: Event{
@@ -714,6 +725,48 @@ func NewImageFetchMessage(id EventId, deploymentDescription *containermessage.De
 		},
 		DeploymentDescription: deploymentDescription,
 		LaunchContext:         launchContext,
+		ImageSources:          imageSources,
+	}
+}
+
+// The images of a service version that the node is likely to upgrade to. Governance asks imagefetch to pull the images
+// ahead of the upgrade with IMAGE_PREFETCH, and imagefetch replies with IMAGE_PREFETCHED or IMAGE_PREFETCH_ERROR.
+type ImagePrefetchMessage struct {
+	event        Event
+	ServiceURL   string
+	Org          string
+	Version      string
+	Arch         string
+	AgreementId  string // the agreement that is going to be upgraded, if any
+	Config       ContainerConfig
+	ImageSources map[string]string // the registry or mirror each image was pulled from
+}
+
+func (m *ImagePrefetchMessage) Event() Event {
+	return m.event
+}
+
+func (m ImagePrefetchMessage) String() string {
+	return fmt.Sprintf("event: %v, ServiceURL: %v, Org: %v, Version: %v, Arch: %v, AgreementId: %v, Config: %v, ImageSources: %v", m.event, m.ServiceURL, m.Org, m.Version, m.Arch, m.AgreementId, m.Config, m.ImageSources)
+}
+
+func (m ImagePrefetchMessage) ShortString() string {
+	return fmt.Sprintf("event: %v, ServiceURL: %v, Org: %v, Version: %v, Arch: %v, AgreementId: %v", m.event, m.ServiceURL, m.Org, m.Version, m.Arch, m.AgreementId)
+}
+
+func NewImagePrefetchMessage(id EventId, url string, org string, version string, arch string, agreementId string, config ContainerConfig, imageSources map[string]string) *ImagePrefetchMessage {
+
+	return &ImagePrefetchMessage{
+		event: Event{
+			Id: id,
+		},
+		ServiceURL:   url,
+		Org:          org,
+		Version:      version,
+		Arch:         arch,
+		AgreementId:  agreementId,
+		Config:       config,
+		ImageSources: imageSources,
 	}
 }
 
@@ -752,6 +805,38 @@ func NewGovernanceMaintenanceMessage(id EventId, protocol string, agreementId st
 	}
 }
 
+// The user input of a running agreement changed and its services reload their configuration. The user input is merged
+// the same way as when the agreement was deployed.
+type ConfigReloadMessage struct {
+	event             Event
+	AgreementProtocol string
+	AgreementId       string
+	UserInput         map[string]interface{}
+}
+
+func (m *ConfigReloadMessage) Event() Event {
+	return m.event
+}
+
+func (m ConfigReloadMessage) String() string {
+	return fmt.Sprintf("Event: %v, AgreementProtocol: %v, AgreementId: %v, UserInput: %v", m.event, m.AgreementProtocol, m.AgreementId, m.UserInput)
+}
+
+func (m ConfigReloadMessage) ShortString() string {
+	return fmt.Sprintf("Event: %v, AgreementProtocol: %v, AgreementId: %v", m.event, m.AgreementProtocol, m.AgreementId)
+}
+
+func NewConfigReloadMessage(id EventId, protocol string, agreementId string, userInput map[string]interface{}) *ConfigReloadMessage {
+	return &ConfigReloadMessage{
+		event: Event{
+			Id: id,
+		},
+		AgreementProtocol: protocol,
+		AgreementId:       agreementId,
+		UserInput:         userInput,
+	}
+}
+
 type GovernanceWorkloadCancelationMessage struct {
 	GovernanceMaintenanceMessage
 	Message
@@ -1508,6 +1593,39 @@ func NewMicroserviceMaintenanceMessage(id EventId, key string) *MicroserviceMain
 	}
 }
 
+// Sent when the health check of a container changes its result.
+type ContainerHealthMessage struct {
+	event         Event
+	ContainerName string
+	ServiceName   string // the name of the service in the deployment description
+	Owner         string // the agreement id or service instance key from the container's label, empty for a shared container
+	Reason        string // the output of the last failed check
+}
+
+func (m *ContainerHealthMessage) Event() Event {
+	return m.event
+}
+
+func (m ContainerHealthMessage) String() string {
+	return m.ShortString()
+}
+
+func (m ContainerHealthMessage) ShortString() string {
+	return fmt.Sprintf("Event: %v, ContainerName: %v, ServiceName: %v, Owner: %v, Reason: %v", m.event, m.ContainerName, m.ServiceName, m.Owner, m.Reason)
+}
+
+func NewContainerHealthMessage(id EventId, containerName string, serviceName string, owner string, reason string) *ContainerHealthMessage {
+	return &ContainerHealthMessage{
+		event: Event{
+			Id: id,
+		},
+		ContainerName: containerName,
+		ServiceName:   serviceName,
+		Owner:         owner,
+		Reason:        reason,
+	}
+}
+
 type MicroserviceCancellationMessage struct {
 	event     Event
 	MsInstKey string // the key to the microservice instance
@@ -271,6 +271,7 @@ func ConvertToPolicies(patternId string, p *Pattern) ([]*policy.Policy, error) {
 func ConvertChoice(wl WorkloadChoice, url string, org string, arch string, pol *policy.Policy) {
 	newWL := policy.Workload_Factory(url, org, wl.Version, arch)
 	newWL.Priority = (*policy.Workload_Priority_Factory(wl.Priority.PriorityValue, wl.Priority.Retries, wl.Priority.RetryDurationS, wl.Priority.VerifiedDurationS))
+	newWL.UpgradeTime = wl.Upgrade.Time
 	newWL.DeploymentOverrides = wl.DeploymentOverrides
 	newWL.DeploymentOverridesSignature = wl.DeploymentOverridesSignature
 	pol.Add_Workload(newWL)
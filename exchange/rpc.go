@@ -10,6 +10,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/metrics"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/semanticversion"
 	"github.com/open-horizon/edge-sync-service/common"
@@ -609,10 +610,29 @@ func GetNodeHealthStatus(httpClientFactory *config.HTTPClientFactory, pattern st
 
 }
 
+// Metrics for the calls made to the exchange.
+var exchangeRequestDuration = metrics.NewHistogram("anax_exchange_request_duration_seconds", "The time taken by calls to the exchange.", metrics.DefaultBuckets, "method")
+var exchangeRequestErrors = metrics.NewCounter("anax_exchange_request_errors_total", "The number of calls to the exchange that failed, by the kind of error (request or transport).", "method", "kind")
+
 // This function is used to invoke an exchange API
 // For GET, the given resp parameter will be untouched when http returns code 404.
 func InvokeExchange(httpClient *http.Client, method string, url string, user string, pw string, params interface{}, resp *interface{}) (error, error) {
 
+	start := time.Now()
+	err, tpErr := invokeExchange(httpClient, method, url, user, pw, params, resp)
+
+	exchangeRequestDuration.ObserveSince(start, method)
+	if tpErr != nil {
+		exchangeRequestErrors.Inc(method, "transport")
+	} else if err != nil {
+		exchangeRequestErrors.Inc(method, "request")
+	}
+
+	return err, tpErr
+}
+
+func invokeExchange(httpClient *http.Client, method string, url string, user string, pw string, params interface{}, resp *interface{}) (error, error) {
+
 	if len(method) == 0 {
 		return errors.New(fmt.Sprintf("Error invoking exchange, method name must be specified")), nil
 	} else if len(url) == 0 {
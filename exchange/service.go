@@ -47,13 +47,17 @@ func (sd ServiceDependency) String() string {
 // This type is used to describe a configuration variable that the node owner/user has to set before the
 // service is able to execute on the edge node.
 type UserInput struct {
-	Name         string `json:"name"`
-	Label        string `json:"label"`
-	Type         string `json:"type"` // Valid values are "string", "int", "float", "boolean", "list of strings"
-	DefaultValue string `json:"defaultValue"`
+	Name         string                  `json:"name"`
+	Label        string                  `json:"label"`
+	Type         string                  `json:"type"` // Valid values are "string", "int", "float", "boolean", "list of strings", "list", "secret"
+	DefaultValue string                  `json:"defaultValue"`
+	Schema       *policy.UserInputSchema `json:"schema,omitempty"` // the constraints on the value, such as the allowed values, a range or a pattern
 }
 
 func (ui UserInput) String() string {
+	if ui.Schema != nil {
+		return fmt.Sprintf("{Name: %v, :Label: %v, Type: %v, DefaultValue: %v, Schema: %v}", ui.Name, ui.Label, ui.Type, ui.DefaultValue, ui.Schema)
+	}
 	return fmt.Sprintf("{Name: %v, :Label: %v, Type: %v, DefaultValue: %v}", ui.Name, ui.Label, ui.Type, ui.DefaultValue)
 }
 
@@ -68,6 +68,11 @@ func SyncLocalUserInputWithExchange(db *bolt.DB, pDevice *persistence.ExchangeDe
 			return true, nil, fmt.Errorf("Failed to save the user input hash %v to the local db. %v", exchHash, err)
 		}
 
+		// forget the secrets that were removed from the exchange copy
+		if _, err := saveUserInputSecrets(db, []policy.UserInput{}, exchDevice.UserInput); err != nil {
+			return true, nil, err
+		}
+
 		glog.V(3).Infof("Node synced with exchange. New node user input is: %v", exchDevice.UserInput)
 
 		// Get a list of what services has been changed
@@ -298,57 +303,93 @@ func DeleteNodeUserInput(pDevice *persistence.ExchangeDevice, db *bolt.DB,
 
 	// delete the node policy from the exchange if it exists
 	userInputs := []policy.UserInput{}
-	return SaveNodeUserInput(pDevice, db, userInputs, getDevice, patchDevice)
+	if err := SaveNodeUserInput(pDevice, db, userInputs, getDevice, patchDevice); err != nil {
+		return err
+	}
+	return persistence.DeleteNodeUserInputSecrets(db)
 }
 
 // Update the node user input on local db and the exchange
 func UpdateNodeUserInput(pDevice *persistence.ExchangeDevice, db *bolt.DB,
 	userInputs []policy.UserInput,
 	getDevice exchange.DeviceHandler,
-	patchDevice exchange.PatchDeviceHandler) (persistence.ServiceSpecs, error) {
+	patchDevice exchange.PatchDeviceHandler) (persistence.ServiceSpecs, persistence.ServiceSpecs, error) {
 
 	// check if the user input is changed or not on the exchange since last observation,
 	// if it is changed, then reject the patch.
 	changed, exchUserInput, err := ExchangeNodeUserInputChanged(pDevice, db, getDevice)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to check the exchange for the node user input: %v.", err)
+		return nil, nil, fmt.Errorf("Failed to check the exchange for the node user input: %v.", err)
 	} else if changed {
-		return nil, fmt.Errorf("Cannot accept this node user input because the local node user input is out of sync with the exchange copy. Please wait a minute and try again.")
+		return nil, nil, fmt.Errorf("Cannot accept this node user input because the local node user input is out of sync with the exchange copy. Please wait a minute and try again.")
 	}
 
-	if err := SaveNodeUserInput(pDevice, db, userInputs, getDevice, patchDevice); err != nil {
-		return nil, fmt.Errorf("Failed to save the user input %v. %v.", userInputs, err)
+	// the secrets stay on the node
+	public, secrets := policy.SplitSecretUserInput(userInputs)
+	if err := SaveNodeUserInput(pDevice, db, public, getDevice, patchDevice); err != nil {
+		return nil, nil, fmt.Errorf("Failed to save the user input %v. %v.", public, err)
+	}
+	changedSecrets, err := saveUserInputSecrets(db, secrets, public)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Get a list of what services has been changed
-	changedServiceSpecs := GetChangedServices(exchUserInput, userInputs)
+	changedServiceSpecs := GetChangedServices(exchUserInput, public)
 
-	return changedServiceSpecs, nil
+	return changedServiceSpecs, changedSecrets, nil
 }
 
 // Add the given user input to the exchange node user input.
 func PatchNodeUserInput(pDevice *persistence.ExchangeDevice, db *bolt.DB,
 	userInputs []policy.UserInput,
 	getDevice exchange.DeviceHandler,
-	patchDevice exchange.PatchDeviceHandler) error {
+	patchDevice exchange.PatchDeviceHandler) (persistence.ServiceSpecs, error) {
 
 	if userInputs == nil || len(userInputs) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	// check if the user input is changed or not on the exchange since last observation,
 	// if it is changed, then reject the patch.
 	changed, exchUserInput, err := ExchangeNodeUserInputChanged(pDevice, db, getDevice)
 	if err != nil {
-		return fmt.Errorf("Failed to check the exchange for the node user input: %v.", err)
+		return nil, fmt.Errorf("Failed to check the exchange for the node user input: %v.", err)
 	} else if changed {
-		return fmt.Errorf("Cannot accept this node user input because the local node user input is out of sync with the exchange copy. Please wait a minute and try again.")
+		return nil, fmt.Errorf("Cannot accept this node user input because the local node user input is out of sync with the exchange copy. Please wait a minute and try again.")
+	}
+
+	// patch the exchange userinput with the newly added one on the node, the secrets stay on the node
+	public, secrets := policy.SplitSecretUserInput(userInputs)
+	new_ui := policy.MergeUserInputArrays(exchUserInput, public, true)
+
+	if err := SaveNodeUserInput(pDevice, db, new_ui, getDevice, patchDevice); err != nil {
+		return nil, err
+	}
+	return saveUserInputSecrets(db, secrets, new_ui)
+}
+
+// Add the given secrets to the secrets of the node and save them in the local db. The secrets that no longer have a
+// placeholder in the node user input are removed. Returns the services whose secrets changed.
+func saveUserInputSecrets(db *bolt.DB, secrets []policy.UserInput, userInputs []policy.UserInput) (persistence.ServiceSpecs, error) {
+
+	oldSecrets, err := persistence.FindNodeUserInputSecrets(db)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get the user input secrets from the local db. %v", err)
 	}
 
-	// patch the exchange userinput with the newly added one on the node
-	new_ui := policy.MergeUserInputArrays(exchUserInput, userInputs, true)
+	newSecrets := policy.PruneSecretUserInput(policy.MergeUserInputArrays(oldSecrets, secrets, true), userInputs)
 
-	return SaveNodeUserInput(pDevice, db, new_ui, getDevice, patchDevice)
+	changedServiceSpecs := GetChangedServices(oldSecrets, newSecrets)
+	if len(changedServiceSpecs) == 0 {
+		return changedServiceSpecs, nil
+	}
+
+	glog.V(3).Infof("Updating local db with new user input secrets for services %v.", changedServiceSpecs)
+	if err := persistence.SaveNodeUserInputSecrets(db, newSecrets); err != nil {
+		return nil, fmt.Errorf("Failed to save the user input secrets to the local db. %v", err)
+	}
+	return changedServiceSpecs, nil
 }
 
 // This fuction saves the given user input into exchange, and local db
@@ -15,6 +15,9 @@ const (
 	PROP_NODE_MEMORY = "openhorizon.memory" //The amount of memory in MBs
 	PROP_NODE_ARCH   = "openhorizon.arch"   //The hardware architecture of the node (e.g. amd64, armv6, etc)
 
+	// set by the user in the node policy
+	PROP_NODE_MAINTENANCE_WINDOW = "openhorizon.maintenanceWindow" // The maintenance windows in which the node accepts upgrades, separated by ';'
+
 	// for service policy
 	PROP_SVC_URL     = "openhorizon.service.url"     // The unique name of the service.
 	PROP_SVC_NAME    = "openhorizon.service.name"    // The unique name of the service.
@@ -0,0 +1,273 @@
+package externalpolicy
+
+import (
+	"errors"
+	"fmt"
+	"github.com/open-horizon/anax/externalpolicy/plugin_registry"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The purpose of this file is to explain why a ConstraintExpression is, or is not, satisfied by a
+// set of properties. The constraint expression is converted into a RequiredProperty object and evaluated
+// in the same way that IsSatisfiedBy evaluates it, so the verdict in an explanation always matches the verdict
+// used during agreement negotiation. The difference is that every property expression is evaluated (there is
+// no short circuit) and the details of each evaluation are captured.
+
+// The result of evaluating a single property expression (e.g. "location == us") against a property list.
+type PropertyExpressionExplanation struct {
+	Name         string      `json:"name"`                   // The name of the property referenced by the expression
+	Op           string      `json:"op"`                     // The comparison operator
+	Value        interface{} `json:"value"`                  // The value the expression compares against
+	Found        bool        `json:"found"`                  // True if the property is in the property list
+	ActualValue  interface{} `json:"actualValue,omitempty"`  // The value of the property in the property list
+	ActualType   string      `json:"actualType,omitempty"`   // The declared type of the property in the property list
+	TypeMismatch bool        `json:"typeMismatch,omitempty"` // True if the property value cannot be compared with the expression value
+	Satisfied    bool        `json:"satisfied"`              // True if the property list satisfies the expression
+	Reason       string      `json:"reason,omitempty"`       // Why the expression is not satisfied
+}
+
+func (p PropertyExpressionExplanation) String() string {
+	return fmt.Sprintf("Name: %v, Op: %v, Value: %v, Found: %v, ActualValue: %v, ActualType: %v, TypeMismatch: %v, Satisfied: %v, Reason: %v",
+		p.Name, p.Op, p.Value, p.Found, p.ActualValue, p.ActualType, p.TypeMismatch, p.Satisfied, p.Reason)
+}
+
+// A node in the explanation tree. A node is either a control operator (and, or, not) with child nodes, or a
+// leaf holding the evaluation of a single property expression.
+type ExpressionExplanation struct {
+	Op        string                         `json:"op,omitempty"`       // The control operator, empty for a leaf
+	Satisfied bool                           `json:"satisfied"`          // True if this part of the expression is satisfied
+	Property  *PropertyExpressionExplanation `json:"property,omitempty"` // The evaluated property expression for a leaf
+	Children  []ExpressionExplanation        `json:"children,omitempty"` // The operands of the control operator
+}
+
+// The result of evaluating one of the constraint strings in a ConstraintExpression.
+type ConstraintClauseExplanation struct {
+	Constraint string                `json:"constraint"` // The constraint as written in the policy
+	Satisfied  bool                  `json:"satisfied"`
+	Expression ExpressionExplanation `json:"expression"`
+}
+
+// The result of evaluating a full ConstraintExpression against a list of properties. All the constraint
+// strings in the expression have to be satisfied.
+type ConstraintExplanation struct {
+	Satisfied         bool                          `json:"satisfied"`
+	Clauses           []ConstraintClauseExplanation `json:"clauses"`
+	MissingProperties []string                      `json:"missingProperties,omitempty"` // Properties referenced by the constraints but not in the property list
+	TypeMismatches    []string                      `json:"typeMismatches,omitempty"`    // Properties whose values cannot be compared with the constraints
+}
+
+func (c ConstraintExplanation) String() string {
+	return fmt.Sprintf("Satisfied: %v, Clauses: %v, MissingProperties: %v, TypeMismatches: %v", c.Satisfied, c.Clauses, c.MissingProperties, c.TypeMismatches)
+}
+
+// This function explains how an input set of properties and values is evaluated against the ConstraintExpression.
+// The Satisfied field of the returned object is true exactly when IsSatisfiedBy returns no error. An error is
+// returned only if the constraint expression cannot be parsed.
+func (self *ConstraintExpression) Explain(props []Property) (*ConstraintExplanation, error) {
+
+	explanation := &ConstraintExplanation{
+		Satisfied: true,
+		Clauses:   make([]ConstraintClauseExplanation, 0),
+	}
+
+	// If there is no expression at all, then there is nothing to satisify
+	if self == nil || len(*self) == 0 {
+		return explanation, nil
+	}
+
+	missing := make(map[string]bool)
+	mismatched := make(map[string]bool)
+
+	// Each constraint string is converted and explained separately so that the result can be tied back to
+	// the text in the policy.
+	for _, constraint := range *self {
+		ce := ConstraintExpression([]string{constraint})
+		rp, err := RequiredPropertyFromConstraint(&ce)
+		if err != nil {
+			return nil, err
+		} else if err := rp.IsValid(); err != nil {
+			return nil, err
+		}
+
+		clause := ConstraintClauseExplanation{
+			Constraint: constraint,
+			Satisfied:  true,
+		}
+
+		// Skip the top level AND that groups the constraint strings together, there is only 1 element in it.
+		if tle := rp.TopLevelElements(); len(tle) == 1 {
+			if cop := isControlOp(tle[0]); cop != nil {
+				clause.Expression = rp.explain(cop, &props)
+				clause.Satisfied = clause.Expression.Satisfied
+			}
+		}
+
+		collectPropertyIssues(&clause.Expression, missing, mismatched)
+
+		if !clause.Satisfied {
+			explanation.Satisfied = false
+		}
+		explanation.Clauses = append(explanation.Clauses, clause)
+	}
+
+	for name := range missing {
+		explanation.MissingProperties = append(explanation.MissingProperties, name)
+	}
+	for name := range mismatched {
+		explanation.TypeMismatches = append(explanation.TypeMismatches, name)
+	}
+	sort.Strings(explanation.MissingProperties)
+	sort.Strings(explanation.TypeMismatches)
+
+	return explanation, nil
+}
+
+// This function does the real work of explaining the expression. It mirrors the satisfied() function so that the
+// verdicts are the same, but it evaluates all of the operands of each control operator. This function is called
+// recursively because control operators can be nested n levels deep.
+func (self *RequiredProperty) explain(cop *map[string]interface{}, props *[]Property) ExpressionExplanation {
+
+	controlOp := self.getControlOperator(cop)
+	exp := ExpressionExplanation{
+		Op:       controlOp,
+		Children: make([]ExpressionExplanation, 0),
+	}
+
+	propArray, ok := (*cop)[controlOp].([]interface{})
+	if !ok {
+		return exp
+	}
+
+	satisfiedCount := 0
+	for _, p := range propArray {
+		var child ExpressionExplanation
+		if prop := isPropertyExpression(p); prop != nil {
+			pe := explainPropertyExpression(prop, props)
+			child = ExpressionExplanation{Satisfied: pe.Satisfied, Property: &pe}
+		} else if childCop := isControlOp(p); childCop != nil {
+			child = self.explain(childCop, props)
+		} else {
+			continue
+		}
+		if child.Satisfied {
+			satisfiedCount += 1
+		}
+		exp.Children = append(exp.Children, child)
+	}
+
+	if controlOp == OP_AND {
+		exp.Satisfied = satisfiedCount == len(exp.Children)
+	} else if controlOp == OP_OR {
+		exp.Satisfied = satisfiedCount != 0
+	} else if controlOp == OP_NOT {
+		exp.Satisfied = satisfiedCount != len(exp.Children)
+	} else {
+		exp.Satisfied = true
+	}
+
+	return exp
+}
+
+// Evaluate a single property expression against the property list and record the details.
+func explainPropertyExpression(propexp *PropertyExpression, props *[]Property) PropertyExpressionExplanation {
+
+	pe := PropertyExpressionExplanation{
+		Name:  propexp.Name,
+		Op:    propexp.Op,
+		Value: propexp.Value,
+	}
+
+	for _, p := range *props {
+		if p.Name == propexp.Name {
+			pe.Found = true
+			pe.ActualValue = p.Value
+			pe.ActualType = p.Type
+			if err := checkComparable(&p, propexp); err != nil {
+				pe.TypeMismatch = true
+				pe.Reason = err.Error()
+			}
+			break
+		}
+	}
+
+	pe.Satisfied = propertyInArray(propexp, props)
+
+	if !pe.Found {
+		pe.Reason = fmt.Sprintf("property %v is not defined", propexp.Name)
+	} else if !pe.Satisfied && pe.Reason == "" {
+		pe.Reason = fmt.Sprintf("property value %v does not satisfy %v %v %v", pe.ActualValue, propexp.Name, propexp.Op, propexp.Value)
+	}
+
+	return pe
+}
+
+// This function returns an error if the value of the property cannot be compared with the value in the
+// property expression using the expression's operator. It follows the type handling in propertyInArray.
+func checkComparable(p *Property, propexp *PropertyExpression) error {
+	if isFloat64(p.Value) {
+		if isFloat64(propexp.Value) {
+			return nil
+		} else if s, ok := propexp.Value.(string); !ok {
+			return errors.New(fmt.Sprintf("property %v is numeric, but the constraint value %v is type %T", p.Name, propexp.Value, propexp.Value))
+		} else if propexp.Op == isin {
+			return checkNumberListOrRange(p.Name, removeSpaces(removeQuotes(s)))
+		} else if propexp.Op == matches {
+			return errors.New(fmt.Sprintf("operator %v is not supported for numeric property %v", propexp.Op, p.Name))
+		} else if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return errors.New(fmt.Sprintf("property %v is numeric, but the constraint value %v is not a number", p.Name, propexp.Value))
+		}
+	} else if isBoolean(p.Value) {
+		if propexp.Op != equalto && propexp.Op != notequalto {
+			return errors.New(fmt.Sprintf("operator %v is not supported for boolean property %v", propexp.Op, p.Name))
+		} else if isBoolean(propexp.Value) {
+			return nil
+		} else if s, ok := propexp.Value.(string); !ok {
+			return errors.New(fmt.Sprintf("property %v is boolean, but the constraint value %v is type %T", p.Name, propexp.Value, propexp.Value))
+		} else if _, err := strconv.ParseBool(s); err != nil {
+			return errors.New(fmt.Sprintf("property %v is boolean, but the constraint value %v is not a boolean", p.Name, propexp.Value))
+		}
+	} else if isString(p.Value) {
+		if !isString(propexp.Value) {
+			return errors.New(fmt.Sprintf("property %v is a string, but the constraint value %v is type %T", p.Name, propexp.Value, propexp.Value))
+		} else if _, ok := stringOperators()[propexp.Op]; !ok {
+			return errors.New(fmt.Sprintf("operator %v is not supported for string property %v", propexp.Op, p.Name))
+		} else if propexp.Op == matches {
+			if _, err := regexp.Compile(removeQuotes(propexp.Value.(string))); err != nil {
+				return errors.New(fmt.Sprintf("the constraint value %v for property %v is not a valid regular expression: %v", propexp.Value, p.Name, err))
+			}
+		}
+	} else {
+		return errors.New(fmt.Sprintf("property %v has value %v of type %T which cannot be compared", p.Name, p.Value, p.Value))
+	}
+	return nil
+}
+
+// Returns an error if the constraint value is neither a comma separated list of numbers nor a numeric range.
+func checkNumberListOrRange(name string, constr string) error {
+	if _, _, _, _, ok := plugin_registry.ParseNumericRange(constr); ok {
+		return nil
+	}
+	for _, constrValue := range strings.Split(constr, ",") {
+		if _, err := strconv.ParseFloat(removeQuotes(removeSpaces(constrValue)), 64); err != nil {
+			return errors.New(fmt.Sprintf("property %v is numeric, but the constraint value %v is not a list of numbers or a numeric range", name, constr))
+		}
+	}
+	return nil
+}
+
+// Walk the explanation tree and remember the properties that were missing or could not be compared.
+func collectPropertyIssues(exp *ExpressionExplanation, missing map[string]bool, mismatched map[string]bool) {
+	if exp.Property != nil {
+		if !exp.Property.Found {
+			missing[exp.Property.Name] = true
+		} else if exp.Property.TypeMismatch {
+			mismatched[exp.Property.Name] = true
+		}
+	}
+	for ix := range exp.Children {
+		collectPropertyIssues(&exp.Children[ix], missing, mismatched)
+	}
+}
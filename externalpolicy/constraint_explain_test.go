@@ -0,0 +1,96 @@
+// +build unit
+
+package externalpolicy
+
+import (
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"testing"
+)
+
+// ================================================================================================================
+// Verify that the explanation of a constraint expression always agrees with IsSatisfiedBy.
+//
+func Test_Explain_matches_IsSatisfiedBy(t *testing.T) {
+
+	props := []Property{
+		*(Property_Factory("prop", true)),
+		*(Property_Factory("prop2", "value2")),
+		*(Property_Factory("cpu", float64(4))),
+		Property{Name: "locations", Value: "us,eu", Type: LIST_TYPE},
+		Property{Name: "version", Value: "1.5.0", Type: VERSION_TYPE},
+	}
+
+	constraints := [][]string{
+		{"prop == true && prop2 == value2"},
+		{"prop == false || prop2 == value2"},
+		{"prop == false && prop2 == value2"},
+		{"cpu >= 2 && cpu < 8"},
+		{"cpu > 4"},
+		{"locations == us"},
+		{"locations in \"eu,asia\""},
+		{"version in [1.0.0,2.0.0)"},
+		{"version in [2.0.0,INFINITY)"},
+		{"missing == value"},
+		{"prop2 == value2", "cpu == 4", "missing == value || prop == true"},
+		{"prop2 == value2", "cpu == 3"},
+		{"NOT (prop == false || cpu > 4)"},
+		{"NOT prop2 == value2 || (cpu in [2,8) && locations in \"asia,eu\")"},
+		{"prop2 matches ^value[0-9]$ && NOT missing == value"},
+		{"cpu in \"1,2,3\""},
+	}
+
+	for _, c := range constraints {
+		ce := ConstraintExpression(c)
+		satisfied := ce.IsSatisfiedBy(props) == nil
+		if exp, err := ce.Explain(props); err != nil {
+			t.Errorf("Error: unable to explain %v: %v", c, err)
+		} else if exp.Satisfied != satisfied {
+			t.Errorf("Error: explanation of %v has verdict %v, IsSatisfiedBy has %v, explanation: %v", c, exp.Satisfied, satisfied, exp)
+		} else if len(exp.Clauses) != len(c) {
+			t.Errorf("Error: explanation of %v should have %v clauses, has %v", c, len(c), len(exp.Clauses))
+		}
+	}
+}
+
+func Test_Explain_details(t *testing.T) {
+
+	props := []Property{
+		*(Property_Factory("prop", true)),
+		*(Property_Factory("prop2", "value2")),
+		*(Property_Factory("cpu", float64(4))),
+	}
+
+	// an empty expression is always satisfied
+	ce := new(ConstraintExpression)
+	if exp, err := ce.Explain(props); err != nil {
+		t.Errorf("Error: unable to explain empty expression: %v", err)
+	} else if !exp.Satisfied || len(exp.Clauses) != 0 {
+		t.Errorf("Error: empty expression should be satisfied with no clauses, is %v", exp)
+	}
+
+	// missing properties and type mismatches are reported
+	ce = &ConstraintExpression{"prop2 == value2 && missing == value", "cpu == abc || prop > 5"}
+	if exp, err := ce.Explain(props); err != nil {
+		t.Errorf("Error: unable to explain %v: %v", *ce, err)
+	} else if exp.Satisfied {
+		t.Errorf("Error: %v should not be satisfied", *ce)
+	} else if len(exp.MissingProperties) != 1 || exp.MissingProperties[0] != "missing" {
+		t.Errorf("Error: missing properties should be [missing], is %v", exp.MissingProperties)
+	} else if len(exp.TypeMismatches) != 2 || exp.TypeMismatches[0] != "cpu" || exp.TypeMismatches[1] != "prop" {
+		t.Errorf("Error: type mismatches should be [cpu prop], is %v", exp.TypeMismatches)
+	} else if exp.Clauses[0].Constraint != (*ce)[0] || exp.Clauses[0].Satisfied || exp.Clauses[1].Satisfied {
+		t.Errorf("Error: wrong clause explanations %v", exp.Clauses)
+	}
+
+	// the values seen in the properties are reported
+	ce = &ConstraintExpression{"prop2 == other"}
+	if exp, err := ce.Explain(props); err != nil {
+		t.Errorf("Error: unable to explain %v: %v", *ce, err)
+	} else if or := exp.Clauses[0].Expression; or.Op != OP_OR || len(or.Children) != 1 {
+		t.Errorf("Error: expecting a top level or with 1 operand, is %v", or)
+	} else if and := or.Children[0]; and.Op != OP_AND || len(and.Children) != 1 || and.Children[0].Property == nil {
+		t.Errorf("Error: expecting an and with 1 property expression, is %v", and)
+	} else if pe := and.Children[0].Property; !pe.Found || pe.Satisfied || pe.ActualValue != "value2" || pe.Value != "other" || pe.Reason == "" {
+		t.Errorf("Error: wrong property expression explanation %v", pe)
+	}
+}
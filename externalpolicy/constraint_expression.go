@@ -1,6 +1,8 @@
 package externalpolicy
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/open-horizon/anax/externalpolicy/plugin_registry"
@@ -10,14 +12,53 @@ import (
 // This type implements all the ConstraintLanguage Plugin methods and delegates to plugin system.
 type ConstraintExpression []string
 
+// Each constraint string is validated on its own, so the constraint language is picked for each of them. This allows
+// a merged policy to contain constraints written in different languages.
 func (c *ConstraintExpression) Validate() error {
-	return plugin_registry.ConstraintLanguagePlugins.ValidatedByOne((*c).GetStrings())
+	for _, constraint := range (*c).GetStrings() {
+		if err := plugin_registry.ConstraintLanguagePlugins.ValidatedByOne([]string{constraint}); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (c *ConstraintExpression) GetLanguageHandler() (plugin_registry.ConstraintLanguagePlugin, error) {
 	return plugin_registry.ConstraintLanguagePlugins.GetLanguageHandlerByOne((*c).GetStrings())
 }
 
+// The constraints can be written as strings, or as JSON objects in the structured constraint language. An object is
+// kept as a compact JSON string, so the plugins see all the constraints as strings.
+func (c *ConstraintExpression) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	} else if raw == nil {
+		(*c) = nil
+		return nil
+	}
+
+	constraints := make([]string, 0, len(raw))
+	for _, r := range raw {
+		var constraint string
+		if err := json.Unmarshal(r, &constraint); err == nil {
+			constraints = append(constraints, constraint)
+			continue
+		}
+
+		compact := new(bytes.Buffer)
+		if trimmed := bytes.TrimSpace(r); len(trimmed) == 0 || trimmed[0] != '{' {
+			return errors.New(fmt.Sprintf("constraint %v must be a string or a JSON object", string(r)))
+		} else if err := json.Compact(compact, trimmed); err != nil {
+			return err
+		}
+		constraints = append(constraints, compact.String())
+	}
+
+	(*c) = constraints
+	return nil
+}
+
 // Create a simple, empty ConstraintExpression Object.
 func Constraint_Factory() *ConstraintExpression {
 	ce := new(ConstraintExpression)
@@ -114,15 +155,12 @@ func (self *ConstraintExpression) GetStrings() []string {
 
 // Create a RequiredProperty Object based on the constraint expression in an external policy. The constraint expression
 // contains references to properties and provides a comparison operator and value on that property. These can be converted
-// into our internal format.
+// into our internal format. Each constraint string becomes an "or" of "and" arrays. A parenthesized group becomes a nested
+// "or" and a negated expression or group becomes a "not".
 func RequiredPropertyFromConstraint(extConstraint *ConstraintExpression) (*RequiredProperty, error) {
 
-	const OP_AND = "and"
-	const OP_OR = "or"
-	const OP_NOT = "not"
-
 	var err error
-	var nextExpression, remainder, controlOp string
+	var remainder string
 	var handler plugin_registry.ConstraintLanguagePlugin
 
 	allPropArray := make([]interface{}, 0)
@@ -133,79 +171,139 @@ func RequiredPropertyFromConstraint(extConstraint *ConstraintExpression) (*Requi
 	}
 
 	for _, remainder = range []string(*extConstraint) {
-		extConstraint.Validate()
-
-		// Create a new Required Property structure and initialize it with a top level OR followed by a top level AND. This will allow us
-		// to drop expressions into the structure as they come in through the GetNextExpression function.
-
-		andArray := make([]interface{}, 0) // An array of PropertyExpression.
-		orArray := make([]interface{}, 0)  // An array of "and" structures, each with an array of PropertyExpression.
 
-		// Get a handle to the specific language handler we will be using.
-		handler, err = extConstraint.GetLanguageHandler()
+		// Get a handle to the specific language handler we will be using for this constraint.
+		handler, err = plugin_registry.ConstraintLanguagePlugins.GetLanguageHandlerByOne([]string{remainder})
 		if err != nil {
 			return nil, errors.New(fmt.Sprintf("unable to obtain policy constraint language handler, error %v", err))
 		}
 
-		// Loop until there are no more property expressions to consume.
-		for {
+		// Consume the property expressions and operators in the constraint, building up the internal format as we go.
+		parser := &constraintParser{handler: handler, remainder: remainder}
+		orArray, err := parser.parseOr(true)
+		if err != nil {
+			return nil, err
+		} else if parser.lookahead == plugin_registry.GROUP_END {
+			return nil, errors.New(fmt.Sprintf("unable to convert policy constraint %v into internal format, error found %v without a matching %v", remainder, plugin_registry.GROUP_END, plugin_registry.GROUP_START))
+		}
 
-			// Get a property expression from the constraint expression. If there is no expression returned, then it's the
-			// end of the expression.
-			nextExpression, remainder, err = handler.GetNextExpression(remainder)
-			if err != nil {
-				return nil, errors.New(fmt.Sprintf("unable to convert policy constraint %v into internal format, error %v", remainder, err))
-			} else if nextExpression == "" {
-				break
-			}
+		newRP := map[string]interface{}{OP_OR: orArray}
+		allPropArray = append(allPropArray, newRP)
+	}
+	allRP.Initialize(&map[string]interface{}{
+		OP_AND: allPropArray,
+	})
 
-			// Convert the expression string into JSON and add it into the RequiredProperty object that we're building.
-			pieces := strings.Split(nextExpression, " ")
-			fullValue := pieces[2]
-			if len(pieces) > 3 {
-				for _, piece := range pieces[3:] {
-					fullValue = fmt.Sprintf("%s %s", fullValue, piece)
-				}
-			}
-			pe := PropertyExpression_Factory(pieces[0], fullValue, pieces[1])
-			andArray = append(andArray, *pe)
-
-			// Get control operator. If no control operator is returned, then it's the end of the expression.
-			controlOp, remainder, err = handler.GetNextOperator(remainder)
-			if err != nil {
-				return nil, errors.New(fmt.Sprintf("unable to convert policy constraint %v into internal format, error %v", remainder, err))
-			} else if controlOp == "" {
-				break
-			}
+	return allRP, nil
+}
 
-			// Based on the control operator expression that we get back, add the current list of ANDed expressions into the structure.
-			if strings.Contains(controlOp, "&&") || strings.Contains(strings.ToUpper(controlOp), "AND") {
-				// Just consume the operator and keep going.
+// The constraintParser converts a single constraint string into the internal format. It gets the tokens from the
+// constraint language handler. && binds tighter than ||, and groups and negations are converted recursively.
+type constraintParser struct {
+	handler   plugin_registry.ConstraintLanguagePlugin
+	remainder string // The part of the constraint string that has not been consumed yet
+	lookahead string // An operator that ended an "and" array and has not been consumed yet
+}
 
-			} else {
-				// OR means we need a new element in the "or" array.
-				innerAnd := map[string]interface{}{
-					OP_AND: andArray,
-				}
-				orArray = append(orArray, innerAnd)
-				andArray = make([]interface{}, 0)
+// Parse expressions separated by OR. The result is an array of "and" structures, each with an array of expressions.
+// Only a whole constraint string is allowed to be empty.
+func (cp *constraintParser) parseOr(allowEmpty bool) ([]interface{}, error) {
+	orArray := make([]interface{}, 0)
+	for {
+		andArray, err := cp.parseAnd(allowEmpty && len(orArray) == 0)
+		if err != nil {
+			return nil, err
+		}
+		orArray = append(orArray, map[string]interface{}{OP_AND: andArray})
 
-			}
+		// The "and" array ended at the end of the expression, the end of a group or an OR.
+		if cp.lookahead == "" || cp.lookahead == plugin_registry.GROUP_END {
+			return orArray, nil
+		}
+		cp.lookahead = ""
+	}
+}
 
+// Parse expressions separated by AND, stopping at the end of the expression, the end of a group or an OR.
+func (cp *constraintParser) parseAnd(allowEmpty bool) ([]interface{}, error) {
+	andArray := make([]interface{}, 0)
+	for {
+		operand, err := cp.parseOperand()
+		if err != nil {
+			return nil, err
+		} else if operand == nil && (!allowEmpty || len(andArray) != 0) {
+			return nil, errors.New("unable to convert policy constraint into internal format, error expecting an expression after an operator")
+		} else if operand == nil {
+			return andArray, nil
 		}
+		andArray = append(andArray, operand)
 
-		// Done with the expression, so close up the current and array initialize the RequiredProperty, and return it.
-		innerAnd := map[string]interface{}{
-			OP_AND: andArray,
+		// Get control operator. If no control operator is returned, then it's the end of the expression.
+		controlOp, remainder, err := cp.handler.GetNextOperator(cp.remainder)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("unable to convert policy constraint %v into internal format, error %v", cp.remainder, err))
+		}
+		cp.remainder = remainder
+
+		if controlOp == "" || controlOp == plugin_registry.GROUP_END {
+			cp.lookahead = controlOp
+			return andArray, nil
+		} else if strings.Contains(controlOp, "&&") || strings.Contains(strings.ToUpper(controlOp), "AND") {
+			// Just consume the operator and keep going.
+		} else {
+			// OR means we need a new element in the "or" array.
+			cp.lookahead = controlOp
+			return andArray, nil
 		}
-		orArray = append(orArray, innerAnd)
+	}
+}
 
-		newRP := map[string]interface{}{OP_OR: orArray}
-		allPropArray = append(allPropArray, newRP)
+// Parse a single property expression, a group or a negation. Nil is returned at the end of the expression.
+func (cp *constraintParser) parseOperand() (interface{}, error) {
+
+	// Get a property expression from the constraint expression. If there is no expression returned, then it's the
+	// end of the expression.
+	nextExpression, remainder, err := cp.handler.GetNextExpression(cp.remainder)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("unable to convert policy constraint %v into internal format, error %v", cp.remainder, err))
 	}
-	allRP.Initialize(&map[string]interface{}{
-		OP_AND: allPropArray,
-	})
+	cp.remainder = remainder
 
-	return allRP, nil
+	switch nextExpression {
+	case "":
+		return nil, nil
+
+	case plugin_registry.GROUP_START:
+		orArray, err := cp.parseOr(false)
+		if err != nil {
+			return nil, err
+		} else if cp.lookahead != plugin_registry.GROUP_END {
+			return nil, errors.New(fmt.Sprintf("unable to convert policy constraint into internal format, error %v is missing a matching %v", plugin_registry.GROUP_START, plugin_registry.GROUP_END))
+		}
+		cp.lookahead = ""
+		return map[string]interface{}{OP_OR: orArray}, nil
+
+	case plugin_registry.NOT_OPERATOR:
+		operand, err := cp.parseOperand()
+		if err != nil {
+			return nil, err
+		} else if operand == nil {
+			return nil, errors.New(fmt.Sprintf("unable to convert policy constraint into internal format, error %v must be followed by an expression", plugin_registry.NOT_OPERATOR))
+		}
+		return map[string]interface{}{OP_NOT: []interface{}{operand}}, nil
+
+	default:
+		// Convert the expression string into JSON so that it can be added into the RequiredProperty object.
+		pieces := strings.Split(nextExpression, " ")
+		if len(pieces) < 3 {
+			return nil, errors.New(fmt.Sprintf("unable to convert policy constraint %v into internal format, expecting <property> <operator> <value>", nextExpression))
+		}
+		fullValue := pieces[2]
+		if len(pieces) > 3 {
+			for _, piece := range pieces[3:] {
+				fullValue = fmt.Sprintf("%s %s", fullValue, piece)
+			}
+		}
+		return *PropertyExpression_Factory(pieces[0], fullValue, pieces[1]), nil
+	}
 }
@@ -3,6 +3,8 @@
 package externalpolicy
 
 import (
+	"encoding/json"
+	_ "github.com/open-horizon/anax/externalpolicy/json_language"
 	_ "github.com/open-horizon/anax/externalpolicy/text_language"
 	"testing"
 )
@@ -165,3 +167,143 @@ func Test_MergeWith(t *testing.T) {
 		t.Errorf("Error: constraints %v should have 4 elements but got %v", ce1, len(*ce1))
 	}
 }
+
+// Verify the set membership, regular expression, numeric range, negation and grouping support.
+func Test_IsSatisfiedBy_operators_and_grouping(t *testing.T) {
+
+	prop_list := `[{"name":"location", "value":"us-east"},{"name":"hostname", "value":"edge-042"},{"name":"cpu", "value":4},{"name":"memory", "value":1.5},{"name":"gpu", "value":false},{"name":"zones", "value":"a,b", "type":"list of string"},{"name":"version","value":"1.2.1","type":"version"}]`
+	props := create_property_list(prop_list, t)
+
+	satisfied := []string{
+		"location in \"us-east,us-west\"",
+		"location in us-east, us-west",
+		"location in us-east",
+		"hostname matches \"^edge-[0-9]+$\"",
+		"zones matches ^b$",
+		"cpu in [4,8)",
+		"cpu in \"2,4,8\"",
+		"memory in (1,2]",
+		"cpu in [2,INFINITY)",
+		"NOT location == eu",
+		"! gpu == true",
+		"NOT missing == value",
+		"(location == eu || location == us-east) && cpu >= 4",
+		"location == eu || location == us-east && cpu >= 4",
+		"NOT (location == eu || gpu == true) && (cpu == 2 || (memory < 2 && NOT hostname matches ^lab))",
+		"version in [1.0.0,2.0.0) && NOT version in [1.2.0,1.3.0) || cpu == 4",
+	}
+	for _, c := range satisfied {
+		ce := ConstraintExpression([]string{c})
+		if err := ce.Validate(); err != nil {
+			t.Errorf("Error: %v should be valid: %v", c, err)
+		} else if err := ce.IsSatisfiedBy(*props); err != nil {
+			t.Errorf("Error: %v should be satisfied: %v", c, err)
+		}
+	}
+
+	notSatisfied := []string{
+		"location in \"eu,asia\"",
+		"hostname matches \"^lab-\"",
+		"cpu in (4,8)",
+		"cpu in [1,4)",
+		"memory in \"1,2\"",
+		"cpu matches 4",
+		"NOT location == us-east",
+		"NOT (location == eu || cpu == 4)",
+		"(location == eu || location == us-east) && cpu > 4",
+		"location == us-east && (cpu == 2 || memory > 2)",
+		"version in [1.0.0,2.0.0) && NOT version in [1.2.0,1.3.0)",
+	}
+	for _, c := range notSatisfied {
+		ce := ConstraintExpression([]string{c})
+		if err := ce.Validate(); err != nil {
+			t.Errorf("Error: %v should be valid: %v", c, err)
+		} else if err := ce.IsSatisfiedBy(*props); err == nil {
+			t.Errorf("Error: %v should not be satisfied", c)
+		}
+	}
+
+	// grouping and negation are converted into nested or and not operators
+	ce := &ConstraintExpression{"NOT (location == eu || gpu == true) && cpu == 4"}
+	if rp, err := RequiredPropertyFromConstraint(ce); err != nil {
+		t.Errorf("Error: unable to convert %v: %v", *ce, err)
+	} else if and := rp.TopLevelElements()[0].(map[string]interface{})[OP_OR].([]interface{})[0].(map[string]interface{})[OP_AND].([]interface{}); len(and) != 2 {
+		t.Errorf("Error: expecting 2 anded expressions, got %v", and)
+	} else if not, ok := and[0].(map[string]interface{})[OP_NOT].([]interface{}); !ok || len(not) != 1 {
+		t.Errorf("Error: expecting a negation, got %v", and[0])
+	} else if _, ok := not[0].(map[string]interface{})[OP_OR]; !ok {
+		t.Errorf("Error: expecting a negated group, got %v", not[0])
+	}
+
+	// unbalanced groups and dangling operators are errors
+	for _, c := range []string{"(cpu == 4", "cpu == 4)", "NOT", "cpu == 4 ||"} {
+		ce := ConstraintExpression([]string{c})
+		if _, err := RequiredPropertyFromConstraint(&ce); err == nil {
+			t.Errorf("Error: %v should not be converted", c)
+		}
+	}
+}
+
+// ================================================================================================================
+// Verify that constraints in the JSON constraint language evaluate the same as the equivalent text constraints.
+//
+func Test_IsSatisfiedBy_json_language(t *testing.T) {
+
+	prop_list := `[{"name":"location", "value":"us-east"},{"name":"hostname", "value":"edge-042"},{"name":"cpu", "value":4},{"name":"memory", "value":1.5},{"name":"gpu", "value":false},{"name":"version","value":"1.2.1","type":"version"}]`
+	props := create_property_list(prop_list, t)
+
+	equivalent := map[string]string{
+		`{"name":"location","value":"us-east"}`:                                    "location == us-east",
+		`{"name":"location","op":"in","value":["eu","asia"]}`:                      "location in \"eu,asia\"",
+		`{"name":"hostname","op":"matches","value":"^edge-[0-9]+$"}`:               "hostname matches \"^edge-[0-9]+$\"",
+		`{"name":"cpu","op":"in","value":"[4,8)"}`:                                 "cpu in [4,8)",
+		`{"name":"cpu","op":">","value":4}`:                                        "cpu > 4",
+		`{"name":"gpu","value":false}`:                                             "gpu == false",
+		`{"not":[{"name":"location","value":"eu"}]}`:                               "NOT location == eu",
+		`{"not":[{"name":"location","value":"us-east"},{"name":"cpu","value":4}]}`: "NOT (location == us-east && cpu == 4)",
+		`{"and":[{"or":[{"name":"location","value":"eu"},{"name":"gpu","value":true}]},{"name":"cpu","op":">=","value":4}]}`:             "(location == eu || gpu == true) && cpu >= 4",
+		`{"or":[{"name":"location","value":"eu"},{"and":[{"name":"location","value":"us-east"},{"name":"memory","op":"<","value":1}]}]}`: "location == eu || location == us-east && memory < 1",
+		`{"and":[{"name":"version","op":"in","value":"[1.0.0,2.0.0)"},{"not":[{"name":"version","op":"in","value":"[1.2.0,1.3.0)"}]}]}`:  "version in [1.0.0,2.0.0) && NOT version in [1.2.0,1.3.0)",
+	}
+	for jc, tc := range equivalent {
+		jce := ConstraintExpression([]string{jc})
+		tce := ConstraintExpression([]string{tc})
+		if err := jce.Validate(); err != nil {
+			t.Errorf("Error: %v should be valid: %v", jc, err)
+		} else if jerr, terr := jce.IsSatisfiedBy(*props), tce.IsSatisfiedBy(*props); (jerr == nil) != (terr == nil) {
+			t.Errorf("Error: %v and %v should evaluate the same, got %v and %v", jc, tc, jerr, terr)
+		}
+	}
+
+	// a merged policy can have constraints in both languages
+	ce := ConstraintExpression{"cpu == 4", `{"name":"location","op":"in","value":["us-east","us-west"]}`}
+	if err := ce.Validate(); err != nil {
+		t.Errorf("Error: %v should be valid: %v", ce, err)
+	} else if err := ce.IsSatisfiedBy(*props); err != nil {
+		t.Errorf("Error: %v should be satisfied: %v", ce, err)
+	}
+
+	for _, c := range []string{`{"name":"cpu","op":"~","value":4}`, `{"and":[]}`, `{"name":"cpu"}`, `{"name":"cpu","value":4`} {
+		ce := ConstraintExpression([]string{c})
+		if err := ce.Validate(); err == nil {
+			t.Errorf("Error: %v should not be valid", c)
+		}
+	}
+}
+
+// ================================================================================================================
+// Verify that constraints can be written as strings or as JSON objects in a policy.
+//
+func Test_ConstraintExpression_UnmarshalJSON(t *testing.T) {
+
+	var ep ExternalPolicy
+	if err := json.Unmarshal([]byte(`{"constraints":["cpu == 4", {"name": "location", "value": "us-east"}]}`), &ep); err != nil {
+		t.Errorf("Error: unable to unmarshal policy: %v", err)
+	} else if len(ep.Constraints) != 2 || ep.Constraints[0] != "cpu == 4" || ep.Constraints[1] != `{"name":"location","value":"us-east"}` {
+		t.Errorf("Error: wrong constraints %v", ep.Constraints)
+	} else if err := json.Unmarshal([]byte(`{"constraints":[4]}`), &ep); err == nil {
+		t.Errorf("Error: a number should not be a constraint")
+	} else if err := json.Unmarshal([]byte(`{"constraints":null}`), &ep); err != nil || ep.Constraints != nil {
+		t.Errorf("Error: expecting no constraints, got %v %v", ep.Constraints, err)
+	}
+}
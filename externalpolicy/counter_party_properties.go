@@ -3,7 +3,9 @@ package externalpolicy
 import (
 	"errors"
 	"fmt"
+	"github.com/open-horizon/anax/externalpolicy/plugin_registry"
 	"github.com/open-horizon/anax/semanticversion"
+	"regexp"
 	"strconv"
 	"strings"
 )
@@ -23,8 +25,12 @@ import (
 // _control_operator_    = {"and", "or", "not"}
 // _expression_          = _control_operator_: [_expression_] || property
 // _property_            = "name": _property_name_, "value": _property_value, "op": _comparison_operator_
-// _comparison_operator_ = {"<", "=", ">", "<=", ">=", "!="}
-// The "=" and "!=" comparison operators can be applied to strings and integers.
+// _comparison_operator_ = {"<", "==", ">", "<=", ">=", "!=", "in", "matches"}
+// The "==" and "!=" comparison operators can be applied to strings and integers.
+// The "in" operator checks membership in a comma separated list of values, a version range or a numeric range
+// such as [2,8).
+// The "matches" operator checks a string against a regular expression.
+// The "not" control operator is satisfied when its array of expressions, ANDed together, is not satisfied.
 // If the "op" key is missing, then equal is assumed.
 //
 // See the unit tests for examples of valid and invalid syntax
@@ -64,6 +70,7 @@ const lessthaneq = "<="
 const greaterthaneq = ">="
 const notequalto = "!="
 const isin = "in"
+const matches = "matches"
 
 // This struct represents property value expressions to be satisfied
 type PropertyExpression struct {
@@ -165,6 +172,14 @@ func (self *RequiredProperty) satisfied(cop *map[string]interface{}, props *[]Pr
 
 	} else if controlOp == OP_NOT {
 
+		// The operands are ANDed together and the result is negated.
+		propArray := (*cop)[controlOp].([]interface{})
+		andOp := map[string]interface{}{OP_AND: propArray}
+		if err := self.satisfied(&andOp, props); err == nil {
+			return errors.New(fmt.Sprintf("Negated Required Properties %v are satisfied by %v\n", propArray, props))
+		}
+		return nil
+
 	}
 
 	return nil
@@ -270,20 +285,19 @@ func (self *RequiredProperty) getControlOperator(m *map[string]interface{}) stri
 // Return a map of control operators so that it's easy to check if a string is equivalent to one
 // of the supported control operators.
 func controlOperators() map[string]int {
-	// return map[string]int {and:0, or:0, not:0}
-	return map[string]int{OP_AND: 0, OP_OR: 0}
+	return map[string]int{OP_AND: 0, OP_OR: 0, OP_NOT: 0}
 }
 
 // Return a map of comparison operators so that it's easy to check if a string is equivalent to one
 // of the supported comparison operators.
 func comparisonOperators() map[string]int {
 	// return map[string]int {and:0, or:0, not:0}
-	return map[string]int{lessthan: 0, greaterthan: 0, equalto: 0, lessthaneq: 0, greaterthaneq: 0, notequalto: 0, isin: 0}
+	return map[string]int{lessthan: 0, greaterthan: 0, equalto: 0, lessthaneq: 0, greaterthaneq: 0, notequalto: 0, isin: 0, matches: 0}
 }
 
 // Return a map of comparison operators that only work on strings
 func stringOperators() map[string]int {
-	return map[string]int{equalto: 0, notequalto: 0, isin: 0}
+	return map[string]int{equalto: 0, notequalto: 0, isin: 0, matches: 0}
 }
 
 // This function checks the type of the input interface object to see if it's a map of string to
@@ -414,6 +428,11 @@ func propertyInArray(propexp *PropertyExpression, props *[]Property) bool {
 			continue
 		} else {
 			if isFloat64(p.Value) {
+				if propexp.Op == matches {
+					return false
+				} else if propexp.Op == isin && isString(propexp.Value) {
+					return numberInListOrRange(p.Value.(float64), removeSpaces(removeQuotes(propexp.Value.(string))))
+				}
 				var propexpFloat float64
 				if isFloat64(propexp.Value) {
 					propexpFloat = propexp.Value.(float64)
@@ -465,6 +484,11 @@ func propertyInArray(propexp *PropertyExpression, props *[]Property) bool {
 						return !stringListContains(propexpValue, pValue)
 					}
 					return pValue != propexpValue
+				} else if propexp.Op == matches {
+					if p.Type == LIST_TYPE {
+						return stringListMatches(pValue, removeQuotes(propexp.Value.(string)))
+					}
+					return stringMatches(pValue, removeQuotes(propexp.Value.(string)))
 				} else if propexp.Op == isin {
 					if p.Type == VERSION_TYPE {
 						return containsVersion(pValue, propexpValue)
@@ -490,7 +514,7 @@ func removeSpaces(value string) string {
 }
 func removeQuotes(value string) string {
 	quote := fmt.Sprint("\"")
-	if value[0:1] == quote && value[len(value)-1:len(value)] == quote {
+	if len(value) >= 2 && value[0:1] == quote && value[len(value)-1:len(value)] == quote {
 		value = value[1 : len(value)-1]
 	}
 	return value
@@ -527,3 +551,34 @@ func stringListContainsOneOfStringList(propList string, constrList string) bool
 	}
 	return false
 }
+
+// Returns true if the number is in the comma separated list of numbers, or within the numeric range. A numeric range
+// uses the same syntax as a version range, for example [2,8) or (0.5,INFINITY).
+func numberInListOrRange(num float64, constr string) bool {
+	if low, high, lowInc, highInc, ok := plugin_registry.ParseNumericRange(constr); ok {
+		return (num > low || (lowInc && num == low)) && (num < high || (highInc && num == high))
+	}
+
+	for _, constrValue := range strings.Split(constr, ",") {
+		if constrFloat, err := strconv.ParseFloat(removeQuotes(removeSpaces(constrValue)), 64); err == nil && constrFloat == num {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the string matches the regular expression. An invalid regular expression matches nothing.
+func stringMatches(value string, expr string) bool {
+	matched, err := regexp.MatchString(expr, value)
+	return err == nil && matched
+}
+
+// Returns true if one of the values in the comma separated list matches the regular expression.
+func stringListMatches(propList string, expr string) bool {
+	for _, propVal := range strings.Split(propList, ",") {
+		if stringMatches(removeQuotes(removeSpaces(propVal)), expr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,243 @@
+package json_language
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/externalpolicy/plugin_registry"
+	"github.com/open-horizon/anax/externalpolicy/text_language"
+)
+
+// The JSON constraint language is a structured form of the text constraint language. Each constraint is a JSON
+// object, which is either a control operator or a property expression:
+//
+// {"and": [_constraint_, ...]}, {"or": [_constraint_, ...]}, {"not": [_constraint_, ...]}
+// {"name": _property_name_, "op": _comparison_operator_, "value": _property_value_}
+//
+// The comparison operators are the same as in the text language, and "==" is assumed when "op" is missing. A value
+// is a string, a number, a boolean or an array of strings and numbers, which is the same as a comma separated list.
+// The operands of "not" are ANDed together before they are negated.
+//
+// A JSON constraint is converted into the equivalent text constraint, which the text language then validates and
+// parses. This guarantees that equivalent constraints in both languages evaluate identically.
+
+const OP_AND = "and"
+const OP_OR = "or"
+const OP_NOT = "not"
+
+func init() {
+	plugin_registry.Register("json", NewJSONConstraintLanguagePlugin())
+}
+
+type JSONConstraintLanguagePlugin struct {
+	textPlugin plugin_registry.ConstraintLanguagePlugin
+}
+
+func NewJSONConstraintLanguagePlugin() plugin_registry.ConstraintLanguagePlugin {
+	return &JSONConstraintLanguagePlugin{
+		textPlugin: text_language.NewTextConstraintLanguagePlugin(),
+	}
+}
+
+// Claim the constraints when each of them is a JSON object. Once claimed, an error means that a constraint is not valid.
+func (p *JSONConstraintLanguagePlugin) Validate(dconstraints interface{}) (bool, error) {
+
+	constraints, ok := dconstraints.([]string)
+	if !ok {
+		return false, errors.New(fmt.Sprintf("The constraint expression: %v is type %T, but is expected to be an array of strings", dconstraints, dconstraints))
+	}
+
+	for _, constraint := range constraints {
+		if !IsJSONConstraint(constraint) {
+			return false, errors.New(fmt.Sprintf("The constraint: %v is not a JSON object", constraint))
+		}
+	}
+
+	for _, constraint := range constraints {
+		if textConstraint, err := ConvertToText(constraint); err != nil {
+			return true, err
+		} else if _, err := p.textPlugin.Validate([]string{textConstraint}); err != nil {
+			return true, errors.New(fmt.Sprintf("The constraint: %v is not valid, error: %v", constraint, err))
+		}
+	}
+
+	return true, nil
+}
+
+// The first call for a constraint receives the JSON object, which is converted into text. After that, the remainder
+// is text.
+func (p *JSONConstraintLanguagePlugin) GetNextExpression(expression string) (string, string, error) {
+	if IsJSONConstraint(expression) {
+		textConstraint, err := ConvertToText(expression)
+		if err != nil {
+			return "", "", err
+		}
+		glog.V(5).Infof(formatLogString(fmt.Sprintf("converted %v to %v", expression, textConstraint)))
+		expression = textConstraint
+	}
+	return p.textPlugin.GetNextExpression(expression)
+}
+
+func (p *JSONConstraintLanguagePlugin) GetNextOperator(expression string) (string, string, error) {
+	return p.textPlugin.GetNextOperator(expression)
+}
+
+// Returns true if the constraint looks like a JSON object. It might not be a valid one.
+func IsJSONConstraint(constraint string) bool {
+	return strings.HasPrefix(strings.TrimSpace(constraint), "{")
+}
+
+// Convert a JSON constraint into the equivalent text constraint.
+func ConvertToText(constraint string) (string, error) {
+	var ast interface{}
+	if err := json.Unmarshal([]byte(constraint), &ast); err != nil {
+		return "", errors.New(fmt.Sprintf("The constraint: %v is not valid JSON, error: %v", constraint, err))
+	}
+	return convertNode(ast, true)
+}
+
+// Convert one node of the constraint tree. Nested control operators are grouped in parentheses.
+func convertNode(node interface{}, top bool) (string, error) {
+
+	obj, ok := node.(map[string]interface{})
+	if !ok {
+		return "", errors.New(fmt.Sprintf("constraint %v is type %T, expecting an object", node, node))
+	}
+
+	if _, ok := obj["name"]; ok {
+		return convertPropertyExpression(obj)
+	} else if len(obj) != 1 {
+		return "", errors.New(fmt.Sprintf("constraint %v should have 1 key, one of %v, %v or %v, or be a property expression with name, op and value", obj, OP_AND, OP_OR, OP_NOT))
+	}
+
+	for op, operands := range obj {
+		if op != OP_AND && op != OP_OR && op != OP_NOT {
+			return "", errors.New(fmt.Sprintf("constraint %v has control operator %v, expecting one of %v, %v or %v", obj, op, OP_AND, OP_OR, OP_NOT))
+		}
+
+		operandArray, ok := operands.([]interface{})
+		if !ok || len(operandArray) == 0 {
+			return "", errors.New(fmt.Sprintf("the %v control operator in constraint %v should have a non-empty array of constraints", op, obj))
+		}
+
+		texts := make([]string, 0, len(operandArray))
+		for _, operand := range operandArray {
+			if text, err := convertNode(operand, false); err != nil {
+				return "", err
+			} else {
+				texts = append(texts, text)
+			}
+		}
+
+		switch op {
+		case OP_NOT:
+			if len(texts) == 1 {
+				return fmt.Sprintf("NOT %v", texts[0]), nil
+			}
+			return fmt.Sprintf("NOT (%v)", strings.Join(texts, " && ")), nil
+		case OP_AND:
+			return group(strings.Join(texts, " && "), top || len(texts) == 1), nil
+		default:
+			return group(strings.Join(texts, " || "), top || len(texts) == 1), nil
+		}
+	}
+
+	return "", nil
+}
+
+func group(text string, top bool) string {
+	if top {
+		return text
+	}
+	return fmt.Sprintf("(%v)", text)
+}
+
+// Convert a property expression into text, for example {"name": "location", "op": "in", "value": ["us", "eu"]} becomes
+// location in "us,eu".
+func convertPropertyExpression(obj map[string]interface{}) (string, error) {
+
+	for key := range obj {
+		if key != "name" && key != "op" && key != "value" {
+			return "", errors.New(fmt.Sprintf("property expression %v has an unexpected key %v, expecting name, op and value", obj, key))
+		}
+	}
+
+	name, ok := obj["name"].(string)
+	if !ok || name == "" || strings.ContainsAny(name, " \"()") {
+		return "", errors.New(fmt.Sprintf("property expression %v should have a name that is a non-empty string without spaces, quotes or parentheses", obj))
+	}
+
+	op := "=="
+	if o, ok := obj["op"]; ok {
+		if op, ok = o.(string); !ok {
+			return "", errors.New(fmt.Sprintf("property expression %v should have an op that is a string", obj))
+		}
+	}
+
+	value, ok := obj["value"]
+	if !ok {
+		return "", errors.New(fmt.Sprintf("property expression %v does not have a value", obj))
+	}
+
+	valueText, err := convertValue(value)
+	if err != nil {
+		return "", errors.New(fmt.Sprintf("property expression %v has an invalid value, error: %v", obj, err))
+	}
+
+	return fmt.Sprintf("%v %v %v", name, op, valueText), nil
+}
+
+// Convert a value into text. Numbers, booleans and ranges are not quoted, other strings and lists are.
+func convertValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case string:
+		if strings.Contains(v, "\"") {
+			return "", errors.New(fmt.Sprintf("string %v cannot contain a quote", v))
+		} else if isRange(v) {
+			return v, nil
+		}
+		return fmt.Sprintf("\"%v\"", v), nil
+	case []interface{}:
+		if len(v) == 0 {
+			return "", errors.New("a list must not be empty")
+		}
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch i := item.(type) {
+			case float64:
+				items = append(items, strconv.FormatFloat(i, 'f', -1, 64))
+			case string:
+				if strings.ContainsAny(i, "\",") {
+					return "", errors.New(fmt.Sprintf("list item %v cannot contain a quote or a comma", i))
+				}
+				items = append(items, i)
+			default:
+				return "", errors.New(fmt.Sprintf("list item %v is type %T, expecting a string or a number", item, item))
+			}
+		}
+		return fmt.Sprintf("\"%v\"", strings.Join(items, ",")), nil
+	default:
+		return "", errors.New(fmt.Sprintf("value %v is type %T, expecting a string, number, boolean or list", value, value))
+	}
+}
+
+// Returns true if the string is a range of versions or numbers, for example [1.0.0,2.0.0) or (2,8].
+func isRange(s string) bool {
+	if len(s) < 5 || !strings.Contains("[(", s[0:1]) || !strings.Contains("])", s[len(s)-1:]) {
+		return false
+	}
+	bounds := strings.Split(s[1:len(s)-1], ",")
+	return len(bounds) == 2 && bounds[0] != "" && bounds[1] != "" && !strings.ContainsAny(s[1:len(s)-1], " ()[]")
+}
+
+func formatLogString(v interface{}) string {
+	return fmt.Sprintf("Constraint JSON language: %v", v)
+}
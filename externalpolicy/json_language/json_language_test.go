@@ -0,0 +1,102 @@
+// +build unit
+
+package json_language
+
+import (
+	"testing"
+)
+
+func Test_Validate_Succeed(t *testing.T) {
+
+	jsonConstraintLanguagePlugin := NewJSONConstraintLanguagePlugin()
+	constraintStrings := []string{
+		`{"name": "iame2edev", "value": true}`,
+		`{"and": [{"name": "cpu", "op": ">=", "value": 3}, {"name": "memory", "op": "<=", "value": 32}]}`,
+		`{"or": [{"name": "hello", "op": "in", "value": ["world", "test"]}, {"not": [{"name": "version", "op": "in", "value": "[1.1.1,INFINITY)"}]}]}`,
+		` {"name": "hostname", "op": "matches", "value": "^edge-[0-9]+$"}`,
+	}
+
+	if validated, err := jsonConstraintLanguagePlugin.Validate(interface{}(constraintStrings)); !validated || err != nil {
+		t.Errorf("Should validate successfully but not, validated: %v, err: %v", validated, err)
+	}
+}
+
+func Test_Validate_Failed(t *testing.T) {
+
+	jsonConstraintLanguagePlugin := NewJSONConstraintLanguagePlugin()
+
+	// text constraints are not claimed
+	if validated, err := jsonConstraintLanguagePlugin.Validate(interface{}([]string{"cpu == 3"})); validated || err == nil {
+		t.Errorf("Should not claim a text constraint, validated: %v, err: %v", validated, err)
+	} else if validated, err := jsonConstraintLanguagePlugin.Validate(interface{}([]string{`{"name": "cpu", "value": 3}`, "cpu == 3"})); validated || err == nil {
+		t.Errorf("Should not claim a mix of text and JSON constraints, validated: %v, err: %v", validated, err)
+	} else if validated, err := jsonConstraintLanguagePlugin.Validate(interface{}("cpu == 3")); validated || err == nil {
+		t.Errorf("Should not claim a string, validated: %v, err: %v", validated, err)
+	}
+
+	constraintStrings := []string{
+		`{"name": "cpu", "value": 3`,
+		`{"name": "cpu"}`,
+		`{"name": "my cpu", "value": 3}`,
+		`{"name": "cpu", "op": "~", "value": 3}`,
+		`{"name": "cpu", "op": 3, "value": 3}`,
+		`{"name": "cpu", "value": 3, "type": "int"}`,
+		`{"name": "gpu", "op": ">", "value": true}`,
+		`{"name": "hello", "value": "say \"hi\""}`,
+		`{"name": "hello", "op": "in", "value": []}`,
+		`{"name": "hello", "op": "in", "value": ["a,b"]}`,
+		`{"name": "hostname", "op": "matches", "value": "^edge-[0-9+$"}`,
+		`{"and": []}`,
+		`{"or": {"name": "cpu", "value": 3}}`,
+		`{"xor": [{"name": "cpu", "value": 3}]}`,
+		`{"and": [{"name": "cpu", "value": 3}], "or": [{"name": "cpu", "value": 4}]}`,
+		`{"not": ["cpu == 3"]}`,
+	}
+	for _, c := range constraintStrings {
+		if validated, err := jsonConstraintLanguagePlugin.Validate(interface{}([]string{c})); !validated || err == nil {
+			t.Errorf("Constraint %v should be claimed and fail validation, validated: %v, err: %v", c, validated, err)
+		}
+	}
+}
+
+func Test_ConvertToText(t *testing.T) {
+
+	constraints := map[string]string{
+		`{"name": "cpu", "value": 3}`:                                     `cpu == 3`,
+		`{"name": "memory", "op": "<", "value": 1.5}`:                     `memory < 1.5`,
+		`{"name": "gpu", "op": "!=", "value": false}`:                     `gpu != false`,
+		`{"name": "location", "value": "us east"}`:                        `location == "us east"`,
+		`{"name": "location", "op": "in", "value": ["us", "eu", 3]}`:      `location in "us,eu,3"`,
+		`{"name": "version", "op": "in", "value": "[1.0.0,2.0.0)"}`:       `version in [1.0.0,2.0.0)`,
+		`{"and": [{"name": "a", "value": 1}]}`:                            `a == 1`,
+		`{"or": [{"name": "a", "value": 1}, {"name": "b", "value": 2}]}`:  `a == 1 || b == 2`,
+		`{"not": [{"name": "a", "value": 1}]}`:                            `NOT a == 1`,
+		`{"not": [{"name": "a", "value": 1}, {"name": "b", "value": 2}]}`: `NOT (a == 1 && b == 2)`,
+		`{"and": [{"or": [{"name": "a", "value": 1}, {"name": "b", "value": 2}]}, {"not": [{"and": [{"name": "c", "value": 3}, {"name": "d", "value": 4}]}]}]}`: `(a == 1 || b == 2) && NOT (c == 3 && d == 4)`,
+	}
+	for jc, tc := range constraints {
+		if text, err := ConvertToText(jc); err != nil {
+			t.Errorf("Unable to convert %v: %v", jc, err)
+		} else if text != tc {
+			t.Errorf("Constraint %v should be converted to %v, got %v", jc, tc, text)
+		}
+	}
+}
+
+func Test_GetNextExpression(t *testing.T) {
+
+	jsonConstraintLanguagePlugin := NewJSONConstraintLanguagePlugin()
+
+	remainder := `{"or": [{"name": "a", "value": 1}, {"name": "b", "op": "in", "value": ["x", "y"]}]}`
+	if exp, rem, err := jsonConstraintLanguagePlugin.GetNextExpression(remainder); err != nil || exp != "a == 1" {
+		t.Errorf("Expecting expression a == 1, got %v, remainder %v, err: %v", exp, rem, err)
+	} else if op, rem, err := jsonConstraintLanguagePlugin.GetNextOperator(rem); err != nil || op != "||" {
+		t.Errorf("Expecting operator ||, got %v, remainder %v, err: %v", op, rem, err)
+	} else if exp, rem, err := jsonConstraintLanguagePlugin.GetNextExpression(rem); err != nil || exp != "b in \"x,y\"" {
+		t.Errorf("Expecting expression b in \"x,y\", got %v, remainder %v, err: %v", exp, rem, err)
+	}
+
+	if _, _, err := jsonConstraintLanguagePlugin.GetNextExpression(`{"and": []}`); err == nil {
+		t.Errorf("Expecting an error for an invalid constraint")
+	}
+}
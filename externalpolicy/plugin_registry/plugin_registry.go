@@ -3,6 +3,18 @@ package plugin_registry
 import (
 	"errors"
 	"fmt"
+	"github.com/open-horizon/anax/semanticversion"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Besides property expressions and logical operators, GetNextExpression returns these tokens when a group or a
+// negation begins, and GetNextOperator returns GROUP_END when a group ends.
+const (
+	GROUP_START  = "("
+	GROUP_END    = ")"
+	NOT_OPERATOR = "NOT"
 )
 
 // Each constraint language plugin implements this interface.
@@ -62,3 +74,32 @@ func (d ConstraintLanguageRegistry) Get(name string) ConstraintLanguagePlugin {
 	}
 	return nil
 }
+
+// Parse a numeric range of the form [low,high], where either bracket can be replaced by a parenthesis to exclude the
+// bound. The high bound can be INFINITY. Spaces around the range and its bounds are ignored. Returns the bounds,
+// whether each bound is included and whether the string is a numeric range.
+func ParseNumericRange(s string) (float64, float64, bool, bool, bool) {
+	s = strings.TrimSpace(s)
+	if len(s) < 5 || !strings.Contains("[(", s[0:1]) || !strings.Contains("])", s[len(s)-1:]) {
+		return 0, 0, false, false, false
+	}
+
+	bounds := strings.Split(s[1:len(s)-1], ",")
+	if len(bounds) != 2 {
+		return 0, 0, false, false, false
+	}
+
+	low, err := strconv.ParseFloat(strings.TrimSpace(bounds[0]), 64)
+	if err != nil {
+		return 0, 0, false, false, false
+	}
+
+	high := math.Inf(1)
+	if highStr := strings.TrimSpace(bounds[1]); highStr != semanticversion.INF {
+		if high, err = strconv.ParseFloat(highStr, 64); err != nil {
+			return 0, 0, false, false, false
+		}
+	}
+
+	return low, high, s[0] == '[', s[len(s)-1] == ']', true
+}
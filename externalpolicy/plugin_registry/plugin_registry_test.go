@@ -0,0 +1,37 @@
+//go:build unit
+// +build unit
+
+package plugin_registry
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_ParseNumericRange(t *testing.T) {
+
+	type result struct {
+		low, high       float64
+		lowInc, highInc bool
+		ok              bool
+	}
+
+	tests := map[string]result{
+		"[2,8)":            {2, 8, true, false, true},
+		"(0.5,INFINITY)":   {0.5, math.Inf(1), false, false, true},
+		" [ 2 , 8 ] ":      {2, 8, true, true, true},
+		"( 1 , INFINITY )": {1, math.Inf(1), false, false, true},
+		"[2,8":             {},
+		"[a,8)":            {},
+		"[2,8,9)":          {},
+		"2,8":              {},
+		"[1.0.0,INFINITY)": {},
+	}
+
+	for s, expected := range tests {
+		low, high, lowInc, highInc, ok := ParseNumericRange(s)
+		if got := (result{low, high, lowInc, highInc, ok}); got != expected {
+			t.Errorf("ParseNumericRange(%q) returned %v, expected %v", s, got, expected)
+		}
+	}
+}
@@ -25,10 +25,8 @@ func NewTextConstraintLanguagePlugin() plugin_registry.ConstraintLanguagePlugin
 
 func (p *TextConstraintLanguagePlugin) Validate(dconstraints interface{}) (bool, error) {
 
-	var err error
 	var constraints []string
-	var nextExpression, nextLogicalOperator, remainder, constraint string
-	var validated bool
+	var constraint string
 
 	// Validate that the input is a ConstraintExpression type (string[])
 	if !isConstraintExpression(dconstraints) {
@@ -42,93 +40,195 @@ func (p *TextConstraintLanguagePlugin) Validate(dconstraints interface{}) (bool,
 		// 1 constrain inside constrain list
 		// handles space inside quote and inside string list
 		constraint = preprocessConstraintExpression(constraint)
-		remainder = constraint
+		if err := p.validateConstraint(constraint); err != nil {
+			return false, err
+		}
+	}
 
-		for {
-			nextExpression, remainder, err = p.GetNextExpression(remainder)
-			if err != nil {
-				return false, errors.New(fmt.Sprintf("unable to convert policy constraint %v into internal format, error %v", remainder, err))
-			} else if nextExpression == "" {
-				break
-			}
+	return true, nil
+}
+
+// Validate a single constraint string. The property expressions are separated by logical operators and can be grouped
+// with parentheses and negated.
+func (p *TextConstraintLanguagePlugin) validateConstraint(constraint string) error {
 
-			validated, err = validateOneConstraintExpression(nextExpression)
-			if !validated {
-				return false, err
+	var nextLogicalOperator string
+
+	remainder := constraint
+	depth := 0                   // The number of groups that are open
+	expectingExpression := false // An expression has to follow a logical operator, a NOT or the start of a group
+
+	for {
+		nextExpression, rem, err := p.GetNextExpression(remainder)
+		if err != nil {
+			return errors.New(fmt.Sprintf("unable to convert policy constraint %v into internal format, error %v", remainder, err))
+		}
+		remainder = rem
+
+		if nextExpression == "" {
+			if expectingExpression {
+				return errors.New(fmt.Sprintf("Constraint %v ends without an expression after an operator, NOT or %v", constraint, plugin_registry.GROUP_START))
 			}
+			return nil
+		} else if nextExpression == plugin_registry.GROUP_START {
+			depth += 1
+			expectingExpression = true
+			continue
+		} else if nextExpression == plugin_registry.NOT_OPERATOR {
+			expectingExpression = true
+			continue
+		}
+
+		if validated, err := validateOneConstraintExpression(nextExpression); !validated {
+			return err
+		}
 
+		// Consume the ends of groups up to the next logical operator.
+		for {
 			nextLogicalOperator, remainder, err = p.GetNextOperator(remainder)
 			if err != nil {
-				return false, errors.New(fmt.Sprintf("unable to convert policy constraint %v into internal format, error %v", remainder, err))
-			} else if nextLogicalOperator == "" {
+				return errors.New(fmt.Sprintf("unable to convert policy constraint %v into internal format, error %v", constraint, err))
+			} else if nextLogicalOperator != plugin_registry.GROUP_END {
 				break
 			}
-
-			// verify logical operators
-			if !isAllowedLogicalOpType(nextLogicalOperator) {
-				return false, errors.New(fmt.Sprintf("Logical operator %v is not valid, expecting AND, OR, &&, ||", nextLogicalOperator))
+			depth -= 1
+			if depth < 0 {
+				return errors.New(fmt.Sprintf("Constraint %v has a %v without a matching %v", constraint, plugin_registry.GROUP_END, plugin_registry.GROUP_START))
 			}
+		}
 
+		if nextLogicalOperator == "" {
+			if depth != 0 {
+				return errors.New(fmt.Sprintf("Constraint %v has a %v without a matching %v", constraint, plugin_registry.GROUP_START, plugin_registry.GROUP_END))
+			}
+			return nil
 		}
 
+		// verify logical operators
+		if !isAllowedLogicalOpType(nextLogicalOperator) {
+			return errors.New(fmt.Sprintf("Logical operator %v is not valid, expecting AND, OR, &&, ||", nextLogicalOperator))
+		}
+		expectingExpression = true
 	}
-
-	return true, nil
 }
 
 // This function parses out the next property expression and returns it along with the remainder of the expression.
-// It returns, the parsed out expression, and the remainder of the full expression, or an error.
+// It returns, the parsed out expression, and the remainder of the full expression, or an error. When the expression
+// begins with a group or a negation, the GROUP_START or NOT_OPERATOR token is returned instead.
 func (p *TextConstraintLanguagePlugin) GetNextExpression(expression string) (string, string, error) {
 
 	// The input expression string should begin with an expression that can be captured and returned, or it is empty.
 	// This should be true because the full expression should have been validated before calling this function.
 
+	expression = strings.TrimLeft(expression, " ")
 	if len(expression) == 0 {
 		return "", "", nil
 	}
 
-	// Split the expression based on whitespace in the string.
-	pieces := strings.Split(expression, " ")
-	if len(pieces) < 3 {
-		return "", "", errors.New(fmt.Sprintf("found %v token(s), expecting 3 in an expression %v, expected form is <property> == <value>", len(pieces), expression))
+	// Groups and negations.
+	if strings.HasPrefix(expression, plugin_registry.GROUP_START) {
+		return plugin_registry.GROUP_START, expression[1:], nil
+	} else if strings.HasPrefix(expression, notsymbol) {
+		return plugin_registry.NOT_OPERATOR, expression[1:], nil
+	} else if word, rem := nextWord(expression); word == notoperator {
+		// A property could be called NOT, in which case the next word is a comparison operator.
+		if op, _ := nextWord(strings.TrimLeft(rem, " ")); !isAllowedComparisonOpType(op) {
+			return plugin_registry.NOT_OPERATOR, rem, nil
+		}
 	}
-	quote := "\""
-	value := pieces[2]
-	remainder := strings.Join(pieces[3:], " ")
-	if strings.Count(pieces[2], quote)%2 == 1 {
-		for i, piece := range pieces[3:] {
-			value = fmt.Sprintf("%s %s", value, piece)
-			//fmt.Println(value)
-			if strings.Contains(piece, quote) {
-				remainder = strings.Join(pieces[i+4:], " ")
-				break
-			}
+
+	if strings.HasPrefix(expression, plugin_registry.GROUP_END) {
+		return "", "", errors.New(fmt.Sprintf("found %v where an expression is expected in %v", plugin_registry.GROUP_END, expression))
+	}
+
+	// The property name and the operator are separated by whitespace.
+	pieces := make([]string, 0, 3)
+	remainder := expression
+	for i := 0; i < 2; i++ {
+		if end := strings.Index(remainder, " "); end == -1 {
+			remainder = ""
+			break
+		} else {
+			pieces = append(pieces, remainder[:end])
+			remainder = strings.TrimLeft(remainder[end:], " ")
 		}
 	}
+
+	var value string
+	if len(remainder) != 0 {
+		value, remainder = nextValue(remainder)
+	}
+	if len(pieces) < 2 || len(value) == 0 {
+		return "", "", errors.New(fmt.Sprintf("found %v token(s), expecting 3 in an expression %v, expected form is <property> == <value>", len(strings.Fields(expression)), expression))
+	}
+
 	// Reform the expression and return the remainder of the expression.
 	exp := fmt.Sprintf("%v %v %v", pieces[0], pieces[1], value)
 	return exp, remainder, nil
 
 }
 
+// This function parses out the next logical operator and returns it along with the remainder of the expression. When
+// the expression begins with the end of a group, the GROUP_END token is returned instead.
 func (p *TextConstraintLanguagePlugin) GetNextOperator(expression string) (string, string, error) {
 	// The input expression string should begin with an operator (i.e. AND, OR), or it is empty.
 	// This should be true because the full expression should have been validated before calling this function. The
 	// preceding expression has alreday been removed.
 
+	expression = strings.TrimLeft(expression, " ")
 	if len(expression) == 0 {
 		return "", "", nil
 	}
 
-	// Split the expression based on whitespace in the string.
-	pieces := strings.Split(expression, " ")
-	if len(pieces) < 4 {
-		return "", "", errors.New(fmt.Sprintf("found %v token(s), expecting 4 with an operator plus an expression %v, expected form is <operator> <property> == <value>", len(pieces), expression))
+	if strings.HasPrefix(expression, plugin_registry.GROUP_END) {
+		return plugin_registry.GROUP_END, expression[1:], nil
 	}
 
-	// Reform the expression and return the remainder of the expression.
-	//fmt.Printf("full exp: %v op: %v rem: %v\n", expression, pieces[0], strings.Join(pieces[1:], " "))
-	return pieces[0], strings.Join(pieces[1:], " "), nil
+	op, remainder := nextWord(expression)
+	return op, remainder, nil
+}
+
+// Return the first word in the input string, ending at whitespace or the start of a group, and the rest of the string.
+func nextWord(s string) (string, string) {
+	if end := strings.IndexAny(s, " "+plugin_registry.GROUP_START); end != -1 {
+		return s[:end], s[end:]
+	}
+	return s, ""
+}
+
+// Return the value at the start of the input string and the rest of the string. A value is a quoted string, a range
+// such as [1.0.0,2.0.0) or (2,8], or an unquoted word. The commas in an unquoted list may be followed by whitespace.
+// Any ends of groups after an unquoted value are left in the rest of the string.
+func nextValue(s string) (string, string) {
+	if strings.HasPrefix(s, quote) {
+		if end := strings.Index(s[1:], quote); end != -1 {
+			return s[:end+2], s[end+2:]
+		}
+		return s, ""
+	}
+
+	if loc := rangeRegex.FindStringIndex(s); loc != nil {
+		return strings.Replace(s[:loc[1]], " ", "", -1), s[loc[1]:]
+	}
+
+	end := 0
+	for end < len(s) && (s[end] != ' ' || s[end-1] == ',') {
+		if s[end] == ' ' {
+			for end < len(s) && s[end] == ' ' {
+				end += 1
+			}
+			continue
+		}
+		end += 1
+	}
+
+	value := strings.TrimRight(s[:end], " ")
+	remainder := s[len(value):]
+	for strings.HasSuffix(value, plugin_registry.GROUP_END) {
+		value = value[:len(value)-1]
+		remainder = plugin_registry.GROUP_END + remainder
+	}
+	return value, remainder
 }
 
 func isConstraintExpression(x interface{}) bool {
@@ -228,9 +328,10 @@ const lessthaneq = "<="
 const greaterthaneq = ">="
 const notequalto = "!="
 const inoperator = "in"
+const matchesoperator = "matches"
 
 func isAllowedComparisonOpType(s string) bool {
-	if strings.Compare(s, lessthan) == 0 || strings.Compare(s, greaterthan) == 0 || strings.Compare(s, equalto) == 0 || strings.Compare(s, doubleequalto) == 0 || strings.Compare(s, lessthaneq) == 0 || strings.Compare(s, greaterthaneq) == 0 || strings.Compare(s, notequalto) == 0 || strings.Compare(s, inoperator) == 0 {
+	if strings.Compare(s, lessthan) == 0 || strings.Compare(s, greaterthan) == 0 || strings.Compare(s, equalto) == 0 || strings.Compare(s, doubleequalto) == 0 || strings.Compare(s, lessthaneq) == 0 || strings.Compare(s, greaterthaneq) == 0 || strings.Compare(s, notequalto) == 0 || strings.Compare(s, inoperator) == 0 || strings.Compare(s, matchesoperator) == 0 {
 		return true
 	}
 	return false
@@ -241,6 +342,15 @@ const orsymbol = "||"
 const and = "AND"
 const or = "OR"
 
+// These negate the expression or group that follows them
+const notsymbol = "!"
+const notoperator = "NOT"
+
+const quote = "\""
+
+// A range of versions or numbers, the whitespace after the comma is optional
+var rangeRegex = regexp.MustCompile(`^[\[\(][^\s,\[\]\(\)"]+,\s*[^\s,\[\]\(\)"]+[\]\)]`)
+
 func isAllowedLogicalOpType(s string) bool {
 	if strings.Compare(s, andsymbol) == 0 || strings.Compare(s, orsymbol) == 0 || strings.Compare(s, and) == 0 || strings.Compare(s, or) == 0 {
 		return true
@@ -270,6 +380,9 @@ func preprocessConstraintExpression(str string) string {
 // 4. for string types, a quoted string, inside which is a list of comma separated strings provide acceptable values
 // 5. string values that contain spaces must be quoted
 // 6. for the version type, supported values are a single version or a range of versions in the semantic version format (the same as used for service verions). The == operator implies that the value is a single version. The 'in' operator treats the value as a version range. As with service versions, the version 1.0.0 when treated as a version range is equivalent to the explicit range [1.0.0,INFINITY).
+// 7. the 'in' operator also accepts a single value, and for numeric types, a range of numbers such as [2,8) or (0.5,INFINITY).
+// 8. the 'matches' operator compares a string with a regular expression. The regular expression must be quoted if it contains spaces.
+// 9. expressions can be grouped with parentheses and negated with NOT or !. && is evaluated before ||.
 
 func validateOneConstraintExpression(expression string) (bool, error) {
 	if len(expression) == 0 {
@@ -286,11 +399,29 @@ func validateOneConstraintExpression(expression string) (bool, error) {
 
 	// if will failed on case when string values that contain spaces but not quoted (starting from 2nd interation)
 	if !isAllowedComparisonOpType(pieces[1]) {
-		return false, errors.New(fmt.Sprintf("Expression: %v should contain valid comparison operator - wrong operator %v. Allowed operators: %v, %v, %v, %v, %v, %v, %v, %v", expression, pieces[1], lessthan, greaterthan, equalto, doubleequalto, lessthaneq, greaterthaneq, notequalto, inoperator))
+		return false, errors.New(fmt.Sprintf("Expression: %v should contain valid comparison operator - wrong operator %v. Allowed operators: %v, %v, %v, %v, %v, %v, %v, %v, %v", expression, pieces[1], lessthan, greaterthan, equalto, doubleequalto, lessthaneq, greaterthaneq, notequalto, inoperator, matchesoperator))
+	}
+
+	if strings.Compare(compOp, matchesoperator) == 0 {
+		// the spaces inside quotes were replaced when the constraint was preprocessed
+		regex := strings.Replace(strings.TrimSuffix(strings.TrimPrefix(value, quote), quote), "\a", " ", -1)
+		if _, err := regexp.Compile(regex); err != nil {
+			return false, errors.New(fmt.Sprintf("Expression: %v does not contain a valid regular expression, error: %v", expression, err))
+		}
+		return true, nil
+	}
+
+	if low, high, _, _, ok := plugin_registry.ParseNumericRange(value); ok {
+		if strings.Compare(compOp, inoperator) != 0 {
+			return false, errors.New(fmt.Sprintf("Comparison operator: %v is not supported for numeric range: %v", compOp, value))
+		} else if low > high {
+			return false, errors.New(fmt.Sprintf("Numeric range: %v has a lower bound that is greater than the upper bound", value))
+		}
+		return true, nil
 	}
 
 	if canParseToFloat(value) || canParseToInteger(value) {
-		if strings.Compare(compOp, doubleequalto) == 0 || strings.Compare(compOp, equalto) == 0 || strings.Compare(compOp, lessthan) == 0 || strings.Compare(compOp, greaterthan) == 0 || strings.Compare(compOp, lessthaneq) == 0 || strings.Compare(compOp, greaterthaneq) == 0 {
+		if strings.Compare(compOp, doubleequalto) == 0 || strings.Compare(compOp, equalto) == 0 || strings.Compare(compOp, lessthan) == 0 || strings.Compare(compOp, greaterthan) == 0 || strings.Compare(compOp, lessthaneq) == 0 || strings.Compare(compOp, greaterthaneq) == 0 || strings.Compare(compOp, inoperator) == 0 {
 			return true, nil
 		}
 		return false, errors.New(fmt.Sprintf("Comparison operator: %v is not supported for numeric value: %v", compOp, value))
@@ -311,7 +442,7 @@ func validateOneConstraintExpression(expression string) (bool, error) {
 	}
 
 	if canParseToString(value) {
-		if strings.Compare(compOp, doubleequalto) == 0 || strings.Compare(compOp, equalto) == 0 {
+		if strings.Compare(compOp, doubleequalto) == 0 || strings.Compare(compOp, equalto) == 0 || strings.Compare(compOp, inoperator) == 0 {
 			return true, nil
 		}
 		return false, errors.New(fmt.Sprintf("Comparison operator: %v is not supported for string value: %v", compOp, value))
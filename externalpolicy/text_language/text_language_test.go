@@ -162,3 +162,61 @@ func Test_GetNextExpression_Succeed(t *testing.T) {
 	}
 
 }
+
+func Test_Validate_Grouping_Negation_Succeed(t *testing.T) {
+	textConstraintLanguagePlugin := NewTextConstraintLanguagePlugin()
+	constraintStrings := []string{
+		"(location == us-east || location == us-west) && cpu >= 4",
+		"NOT (location == eu || gpu == true)",
+		"!(iame2edev == true) OR (cpu == 3 && (memory <= 32 || NOT memory > 64))",
+		"NOT == true",
+		"location in \"us-east,us-west\" && location in us-east",
+		"location in us-east, us-west",
+		"cpu in [2,8) && memory in (0.5,INFINITY) && cpu in \"2,4,8\"",
+		"hostname matches \"^edge-[0-9]+$\" && description matches \"in the lab\"",
+		"(version in [1.0.0,2.0.0))",
+	}
+
+	if validated, err := textConstraintLanguagePlugin.Validate(interface{}(constraintStrings)); !validated || err != nil {
+		t.Errorf("Should validate successfully but not, err: %v", err)
+	}
+}
+
+func Test_Validate_Grouping_Negation_Failed(t *testing.T) {
+	textConstraintLanguagePlugin := NewTextConstraintLanguagePlugin()
+	constraints := map[string]string{
+		"(location == us-east || location == us-west":       "Constraint (location == us-east || location == us-west has a ( without a matching )",
+		"location == us-east) && cpu >= 4":                  "Constraint location == us-east) && cpu >= 4 has a ) without a matching (",
+		"NOT":                                               "Constraint NOT ends without an expression after an operator, NOT or (",
+		"location == us-east &&":                            "Constraint location == us-east && ends without an expression after an operator, NOT or (",
+		"hostname matches \"^edge-[0-9+$\"":                 "Expression: hostname matches \"^edge-[0-9+$\" does not contain a valid regular expression, error: error parsing regexp: missing closing ]: `[0-9+$`",
+		"cpu in [8,2)":                                      "Numeric range: [8,2) has a lower bound that is greater than the upper bound",
+		"cpu > [2,8)":                                       "Comparison operator: > is not supported for numeric range: [2,8)",
+		"()":                                                "unable to convert policy constraint ) into internal format, error found ) where an expression is expected in )",
+		"location == us-east AND (cpu == 1 XOR cpu == 2)":   "Logical operator XOR is not valid, expecting AND, OR, &&, ||",
+	}
+
+	for c, expected := range constraints {
+		if validated, err := textConstraintLanguagePlugin.Validate(interface{}([]string{c})); validated {
+			t.Errorf("Validation of %v should fail but did not", c)
+		} else if err == nil || err.Error() != expected {
+			t.Errorf("Validation of %v returned error %v, expected %v", c, err, expected)
+		}
+	}
+}
+
+func Test_GetNextExpression_Grouping(t *testing.T) {
+	textConstraintLanguagePlugin := NewTextConstraintLanguagePlugin()
+
+	if exp, rem, err := textConstraintLanguagePlugin.GetNextExpression("(a == 1 || b == 2)"); err != nil || exp != "(" || rem != "a == 1 || b == 2)" {
+		t.Errorf("Expecting a group start, got %v %v %v", exp, rem, err)
+	} else if exp, rem, err := textConstraintLanguagePlugin.GetNextExpression("b == 2))"); err != nil || exp != "b == 2" || rem != "))" {
+		t.Errorf("Expecting b == 2 followed by 2 group ends, got %v %v %v", exp, rem, err)
+	} else if exp, rem, err := textConstraintLanguagePlugin.GetNextExpression("v in (1.0.0,2.0.0])"); err != nil || exp != "v in (1.0.0,2.0.0]" || rem != ")" {
+		t.Errorf("Expecting a version range followed by a group end, got %v %v %v", exp, rem, err)
+	} else if exp, rem, err := textConstraintLanguagePlugin.GetNextExpression("NOT (a == 1)"); err != nil || exp != "NOT" || rem != " (a == 1)" {
+		t.Errorf("Expecting a negation, got %v %v %v", exp, rem, err)
+	} else if exp, rem, err := textConstraintLanguagePlugin.GetNextOperator(") && a == 1"); err != nil || exp != ")" || rem != " && a == 1" {
+		t.Errorf("Expecting a group end, got %v %v %v", exp, rem, err)
+	}
+}
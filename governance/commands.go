@@ -176,6 +176,26 @@ func (w *GovernanceWorker) NewUpgradeMicroserviceCommand(msdef_id string) *Upgra
 	}
 }
 
+// ==============================================================================================================
+// Release an agreement cancellation that was held until a maintenance window opened
+type ReleasePendingUpgradeCommand struct {
+	AgreementId       string
+	AgreementProtocol string
+	Reason            uint
+}
+
+func (c ReleasePendingUpgradeCommand) ShortString() string {
+	return fmt.Sprintf("ReleasePendingUpgradeCommand: AgreementId %v, AgreementProtocol %v, Reason %v", c.AgreementId, c.AgreementProtocol, c.Reason)
+}
+
+func (w *GovernanceWorker) NewReleasePendingUpgradeCommand(agreementId string, protocol string, reason uint) *ReleasePendingUpgradeCommand {
+	return &ReleasePendingUpgradeCommand{
+		AgreementId:       agreementId,
+		AgreementProtocol: protocol,
+		Reason:            reason,
+	}
+}
+
 // ==============================================================================================================
 // Start agreement-less services
 type StartAgreementLessServicesCommand struct {
@@ -261,3 +281,19 @@ func NewNodeUserInputChangedCommand(msg *events.NodeUserInputMessage) *NodeUserI
 		Msg: msg,
 	}
 }
+
+// ==============================================================================================================
+// Check the containers of an agreement or service instance now, one of them has become unhealthy
+type ContainerHealthCommand struct {
+	Msg *events.ContainerHealthMessage
+}
+
+func (c ContainerHealthCommand) ShortString() string {
+	return fmt.Sprintf("ContainerHealthCommand: msg %v", c.Msg)
+}
+
+func (w *GovernanceWorker) NewContainerHealthCommand(msg *events.ContainerHealthMessage) *ContainerHealthCommand {
+	return &ContainerHealthCommand{
+		Msg: msg,
+	}
+}
@@ -0,0 +1,47 @@
+package governance
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/persistence"
+	"time"
+)
+
+const SECONDS_PER_DAY = 24 * 60 * 60
+
+// Convert the event log retention settings in the anax configuration into the retention rules used by the db.
+func eventLogRetention(cfg *config.HorizonConfig) persistence.EventLogRetention {
+	retention := persistence.EventLogRetention{
+		MaxAgeS:         daysToSeconds(cfg.Edge.EventLogRetentionDays),
+		SeverityMaxAgeS: make(map[string]uint64),
+	}
+	for severity, days := range cfg.Edge.EventLogSeverityRetentionDays {
+		retention.SeverityMaxAgeS[severity] = daysToSeconds(days)
+	}
+	if cfg.Edge.EventLogMaxRecords > 0 {
+		retention.MaxRecords = cfg.Edge.EventLogMaxRecords
+	}
+	return retention
+}
+
+func daysToSeconds(days int) uint64 {
+	if days <= 0 {
+		return 0
+	}
+	return uint64(days) * SECONDS_PER_DAY
+}
+
+// Delete the event logs that are older or more numerous than the retention settings allow.
+func (w *GovernanceWorker) governEventLogs() int {
+
+	retention := eventLogRetention(w.Config)
+	glog.V(5).Infof(logString(fmt.Sprintf("pruning event logs with retention %v", retention)))
+
+	if n, err := persistence.PruneEventLogs(w.db, retention, uint64(time.Now().Unix())); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to prune the event logs, error %v", err)))
+	} else if n != 0 {
+		glog.V(3).Infof(logString(fmt.Sprintf("pruned %v event logs", n)))
+	}
+	return 0
+}
@@ -0,0 +1,28 @@
+// +build unit
+
+package governance
+
+import (
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_eventLogRetention(t *testing.T) {
+
+	// nothing configured, the event logs are kept forever
+	cfg := &config.HorizonConfig{}
+	assert.True(t, eventLogRetention(cfg).IsUnlimited(), "The event logs should be kept forever by default.")
+
+	cfg.Edge.EventLogRetentionDays = 30
+	cfg.Edge.EventLogSeverityRetentionDays = map[string]int{persistence.SEVERITY_INFO: 7, persistence.SEVERITY_ERROR: 0}
+	cfg.Edge.EventLogMaxRecords = -1
+
+	retention := eventLogRetention(cfg)
+	assert.False(t, retention.IsUnlimited(), "The event logs should be pruned.")
+	assert.Equal(t, uint64(30*SECONDS_PER_DAY), retention.MaxAgeS, "The retention days should be converted to seconds.")
+	assert.Equal(t, uint64(7*SECONDS_PER_DAY), retention.SeverityMaxAgeS[persistence.SEVERITY_INFO], "The info retention days should be converted to seconds.")
+	assert.Equal(t, uint64(0), retention.SeverityMaxAgeS[persistence.SEVERITY_ERROR], "The error event logs should be kept forever.")
+	assert.Equal(t, 0, retention.MaxRecords, "A negative maximum should mean no limit.")
+}
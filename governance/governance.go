@@ -44,6 +44,8 @@ const CONTAINER_GOVERNOR = "ContainerGovernor"
 const MICROSERVICE_GOVERNOR = "MicroserviceGovernor"
 const BC_GOVERNOR = "BlockchainGovernor"
 const SERVICE_CONFIGSTATE_GOVERNOR = "ServiceConfigStateGovernor"
+const MAINTENANCE_GOVERNOR = "MaintenanceGovernor"
+const EVENTLOG_GOVERNOR = "EventLogGovernor"
 
 type GovernanceWorker struct {
 	worker.BaseWorker   // embedded field
@@ -113,6 +115,9 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 		case events.IMAGE_LOAD_FAILED:
 			cmd := w.NewCleanupExecutionCommand(msg.AgreementProtocol, msg.AgreementId, w.producerPH[msg.AgreementProtocol].GetTerminationCode(producer.TERM_REASON_WL_IMAGE_LOAD_FAILURE), msg.Deployment)
 			w.Commands <- cmd
+		case events.CONTAINER_UNHEALTHY:
+			cmd := w.NewCleanupExecutionCommand(msg.AgreementProtocol, msg.AgreementId, w.producerPH[msg.AgreementProtocol].GetTerminationCode(producer.TERM_REASON_CONTAINER_UNHEALTHY), msg.Deployment)
+			w.Commands <- cmd
 		case events.WORKLOAD_DESTROYED:
 			cmd := w.NewCleanupStatusCommand(msg.AgreementProtocol, msg.AgreementId, STATUS_WORKLOAD_DESTROYED)
 			w.Commands <- cmd
@@ -121,6 +126,13 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 		cmd := w.NewReportDeviceStatusCommand()
 		w.Commands <- cmd
 
+	case *events.ImagePrefetchMessage:
+		msg, _ := incoming.(*events.ImagePrefetchMessage)
+		switch msg.Event().Id {
+		case events.IMAGE_PREFETCHED, events.IMAGE_PREFETCH_ERROR:
+			w.logImagePrefetch(msg)
+		}
+
 	case *events.ImageFetchMessage:
 		msg, _ := incoming.(*events.ImageFetchMessage)
 
@@ -157,7 +169,7 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 					eventlog.LogAgreementEvent(
 						w.db,
 						persistence.SEVERITY_INFO,
-						fmt.Sprintf("Image loaded for %v/%v.", ags[0].RunningWorkload.Org, ags[0].RunningWorkload.URL),
+						fmt.Sprintf("Image loaded for %v/%v%v.", ags[0].RunningWorkload.Org, ags[0].RunningWorkload.URL, imageSourcesString(msg.ImageSources)),
 						persistence.EC_IMAGE_LOADED,
 						ags[0])
 				} else {
@@ -178,7 +190,7 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 				eventlog.LogServiceEvent2(
 					w.db,
 					persistence.SEVERITY_INFO,
-					fmt.Sprintf("Image loaded for service %v/%v.", lc.ServicePathElement.Org, lc.ServicePathElement.URL),
+					fmt.Sprintf("Image loaded for service %v/%v%v.", lc.ServicePathElement.Org, lc.ServicePathElement.URL, imageSourcesString(msg.ImageSources)),
 					persistence.EC_IMAGE_LOADED,
 					"", lc.ServicePathElement.URL, "", lc.ServicePathElement.Version, "", lc.AgreementIds)
 			} else {
@@ -256,11 +268,28 @@ func (w *GovernanceWorker) NewEvent(incoming events.Message) {
 			case events.IMAGE_LOAD_FAILED:
 				cmd := w.NewUpdateMicroserviceCommand(msg.LaunchContext.Name, false, microservice.MS_IMAGE_LOAD_FAILED, microservice.DecodeReasonCode(microservice.MS_IMAGE_LOAD_FAILED))
 				w.Commands <- cmd
+			case events.CONTAINER_UNHEALTHY:
+				cmd := w.NewUpdateMicroserviceCommand(msg.LaunchContext.Name, false, microservice.MS_HEALTH_CHECK_FAILED, microservice.DecodeReasonCode(microservice.MS_HEALTH_CHECK_FAILED))
+				w.Commands <- cmd
 			}
 
 			cmd := w.NewReportDeviceStatusCommand()
 			w.Commands <- cmd
 		}
+	case *events.ContainerHealthMessage:
+		msg, _ := incoming.(*events.ContainerHealthMessage)
+
+		switch msg.Event().Id {
+		case events.CONTAINER_UNHEALTHY:
+			// A shared container has no owner, its users find out at their next maintenance check.
+			if msg.Owner != "" {
+				cmd := w.NewContainerHealthCommand(msg)
+				w.Commands <- cmd
+			}
+		case events.CONTAINER_HEALTHY:
+			glog.V(3).Infof(logString(fmt.Sprintf("container %v for service %v is healthy", msg.ContainerName, msg.ServiceName)))
+		}
+
 	case *events.MicroserviceContainersDestroyedMessage:
 		msg, _ := incoming.(*events.MicroserviceContainersDestroyedMessage)
 
@@ -516,6 +545,23 @@ func (w *GovernanceWorker) governContainers() int {
 	return 0
 }
 
+// A container of the agreement or service instance has become unhealthy. Run the maintenance check for it now,
+// instead of waiting for the next one, so that the agreement is cancelled or the service is retried.
+func (w *GovernanceWorker) checkUnhealthyContainerOwner(owner string) {
+
+	if ags, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(owner)}); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error: %v", owner, err)))
+	} else if len(ags) == 1 {
+		if ags[0].AgreementExecutionStartTime != 0 && ags[0].AgreementTerminatedTime == 0 {
+			w.Messages() <- events.NewGovernanceMaintenanceMessage(events.CONTAINER_MAINTAIN, ags[0].AgreementProtocol, ags[0].CurrentAgreementId, ags[0].GetDeploymentConfig())
+		}
+	} else if msinst, err := persistence.FindMicroserviceInstanceWithKey(w.db, owner); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to retrieve service instance %v from database, error: %v", owner, err)))
+	} else if msinst != nil && !msinst.Archived && msinst.ExecutionStartTime != 0 && msinst.CleanupStartTime == 0 {
+		w.Messages() <- events.NewMicroserviceMaintenanceMessage(events.CONTAINER_MAINTAIN, msinst.GetKey())
+	}
+}
+
 func (w *GovernanceWorker) reportBlockchains() int {
 
 	// go govern
@@ -696,6 +742,14 @@ func (w *GovernanceWorker) Initialize() bool {
 	// Fire up the microservice governor
 	w.DispatchSubworker(MICROSERVICE_GOVERNOR, w.governMicroservices, 60)
 
+	// Fire up the maintenance window governor that releases the held upgrades
+	w.DispatchSubworker(MAINTENANCE_GOVERNOR, w.governMaintenanceWindows, 60)
+
+	// Fire up the event log governor that deletes the event logs the retention settings no longer allow to be kept
+	if !eventLogRetention(w.Config).IsUnlimited() {
+		w.DispatchSubworker(EVENTLOG_GOVERNOR, w.governEventLogs, w.Config.Edge.EventLogPruneIntervalS)
+	}
+
 	// Fire up the service configuration state governer. Only support pattern case for now.
 	if w.devicePattern != "" {
 		w.DispatchSubworker(SERVICE_CONFIGSTATE_GOVERNOR, w.governServiceConfigState, w.Config.Edge.ServiceConfigStateCheckIntervalS)
@@ -968,10 +1022,13 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 						glog.V(5).Infof(logString(fmt.Sprintf("ignoring cancel, agreement %v is terminating", canReceived.AgreementId())))
 						deleteMessage = true
 						err_log_msg = fmt.Sprintf("ignoring cancel, agreement %v is terminating", canReceived.AgreementId())
+					} else if w.holdUpgradeCancel(&ags[0], canReceived.Reason()) {
+						// the cancel will be processed when the next maintenance window opens
+						deleteMessage = true
 					} else {
 						w.cancelAgreement(canReceived.AgreementId(), msgProtocol, canReceived.Reason(), w.producerPH[msgProtocol].GetTerminationReason(canReceived.Reason()))
 						// cleanup workloads if needed
-						w.Messages() <- events.NewGovernanceWorkloadCancelationMessage(events.AGREEMENT_ENDED, events.AG_TERMINATED, ags[0].AgreementProtocol, ags[0].CurrentAgreementId, ags[0].GetDeploymentConfig())
+						w.Messages() <- events.NewGovernanceWorkloadCancelationMessage(events.AGREEMENT_ENDED, w.agreementEndCause(msgProtocol, canReceived.Reason()), ags[0].AgreementProtocol, ags[0].CurrentAgreementId, ags[0].GetDeploymentConfig())
 						// clean up microservice instances if needed
 						w.handleMicroserviceInstForAgEnded(ags[0].CurrentAgreementId, false)
 						deleteMessage = true
@@ -1009,6 +1066,9 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 					} else if len(ags) != 1 {
 						glog.Warningf(logString(fmt.Sprintf("unable to retrieve single agreement %v from database, error %v", agid, err)))
 						deleteMessage = true
+					} else if w.isUpgradeHeld(agid) {
+						// the agbot has already cancelled the agreement, the node is waiting for a maintenance window to do the same
+						glog.V(3).Infof(logString(fmt.Sprintf("agreement %v is no longer valid on the agbot, its cancel is held until the next maintenance window", agid)))
 					} else {
 						eventlog.LogAgreementEvent(
 							w.db,
@@ -1029,6 +1089,11 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 				deleteMessage = handled
 			}
 
+			// The agbot hints at the workload version that an agreement is going to be upgraded to.
+			if agid, workload := w.producerPH[msgProtocol].GetWorkloadPrefetch(&cmd.Msg); workload != nil {
+				w.prefetchWorkload(msgProtocol, agid, exchangeMsg.AgbotId, workload)
+			}
+
 		}
 
 		// Get rid of the exchange message when we're done with it
@@ -1184,6 +1249,9 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 	case *CancelAgreementCommand:
 		cmd, _ := command.(*CancelAgreementCommand)
 		w.cancelAgreement(cmd.AgreementId, cmd.AgreementProtocol, cmd.Reason, cmd.ReasonDescription)
+	case *ReleasePendingUpgradeCommand:
+		cmd, _ := command.(*ReleasePendingUpgradeCommand)
+		w.releasePendingUpgrade(cmd)
 	case *UpdateMicroserviceCommand:
 		cmd, _ := command.(*UpdateMicroserviceCommand)
 
@@ -1229,6 +1297,10 @@ func (w *GovernanceWorker) CommandHandler(command worker.Command) bool {
 				}
 			}
 		}
+	case *ContainerHealthCommand:
+		cmd, _ := command.(*ContainerHealthCommand)
+		w.checkUnhealthyContainerOwner(cmd.Msg.Owner)
+
 	case *UpgradeMicroserviceCommand:
 		cmd, _ := command.(*UpgradeMicroserviceCommand)
 
@@ -1421,6 +1493,14 @@ func (w *GovernanceWorker) RecordReply(proposal abstractprotocol.Proposal, proto
 			sDef.PopulateDefaultUserInput(envAdds)
 		}
 
+		// The services that reload their configuration also get the user input in a file.
+		if userInput, err := w.GetServiceUserInput(workload.WorkloadURL, workload.Org, tcPolicy, serviceDef); err != nil {
+			glog.Errorf(logString(fmt.Sprintf("Error getting user input for %v %v: %v", workload.WorkloadURL, workload.Org, err)))
+			return err
+		} else {
+			lc.UserInput = userInput
+		}
+
 		cutil.SetPlatformEnvvars(envAdds,
 			config.ENVVAR_PREFIX,
 			proposal.AgreementId(),
@@ -1647,6 +1727,68 @@ func (w *GovernanceWorker) GetServicePreference(url string, org string, tcPolicy
 	return envAdds, nil
 }
 
+// Get the user input of a service as it is written to the user input file of the services that reload their
+// configuration. The values are merged the same way as the environment variables of the service: the node user input
+// comes first, then the user input from the business policy or pattern and then the defaults of the service
+// definition. Secrets are left out, they are given to the service as files.
+func (w *GovernanceWorker) GetServiceUserInput(url string, org string, tcPolicy *policy.Policy, sDef *exchange.ServiceDefinition) (map[string]interface{}, error) {
+
+	values := make(map[string]interface{})
+	addUserInput := func(userInput []policy.UserInput) {
+		for _, ui := range userInput {
+			if ui.ServiceUrl != url || ui.ServiceOrgid != org {
+				continue
+			}
+			for _, item := range ui.Inputs {
+				if _, ok := values[item.Name]; !ok && !item.IsSecret() {
+					values[item.Name] = item.Value
+				}
+			}
+		}
+	}
+
+	// add node user input
+	userInput, err := persistence.FindNodeUserInput(w.db)
+	if err != nil {
+		return nil, fmt.Errorf("Failed get user input from local db. %v", err)
+	}
+	addUserInput(userInput)
+
+	// Add user input from business policy or pattern that comes with the proposal.
+	if tcPolicy != nil {
+		addUserInput(tcPolicy.UserInput)
+	}
+
+	// Add the defaults of the service definition.
+	if sDef != nil {
+		for _, ui := range sDef.UserInputs {
+			if _, ok := values[ui.Name]; !ok && ui.DefaultValue != "" && ui.Type != policy.USER_INPUT_TYPE_SECRET {
+				values[ui.Name] = ui.DefaultValue
+			}
+		}
+	}
+
+	return values, nil
+}
+
+// Get the user input of the service that an agreement is running, from the agreement's proposal and the service
+// definition of the running version.
+func (w *GovernanceWorker) agreementUserInput(ag *persistence.EstablishedAgreement) (map[string]interface{}, error) {
+
+	protocolHandler := w.producerPH[ag.AgreementProtocol].AgreementProtocolHandler("", "", "")
+	if proposal, err := protocolHandler.DemarshalProposal(ag.Proposal); err != nil {
+		return nil, fmt.Errorf("unable to demarshal proposal for agreement %v, error %v", ag.CurrentAgreementId, err)
+	} else if tcPolicy, err := policy.DemarshalPolicy(proposal.TsAndCs()); err != nil {
+		return nil, fmt.Errorf("unable to demarshal TsAndCs of agreement %v, error %v", ag.CurrentAgreementId, err)
+	} else if _, sDef, _, err := exchange.GetHTTPServiceResolverHandler(w)(ag.RunningWorkload.URL, ag.RunningWorkload.Org, ag.RunningWorkload.Version, ag.RunningWorkload.Arch); err != nil {
+		return nil, fmt.Errorf("unable to get service metadata for %v/%v, error %v", ag.RunningWorkload.Org, ag.RunningWorkload.URL, err)
+	} else if sDef == nil {
+		return nil, fmt.Errorf("could not find service metadata for %v/%v", ag.RunningWorkload.Org, ag.RunningWorkload.URL)
+	} else {
+		return w.GetServiceUserInput(ag.RunningWorkload.URL, ag.RunningWorkload.Org, tcPolicy, sDef)
+	}
+}
+
 func recordProducerAgreementState(httpClient *http.Client, url string, deviceId string, token string, pattern string, agreementId string, pol *policy.Policy, state string) error {
 
 	glog.V(5).Infof(logString(fmt.Sprintf("setting agreement %v state to %v", agreementId, state)))
@@ -1852,12 +1994,32 @@ func (w *GovernanceWorker) handleNodeUserInputUpdated(svcSpecs persistence.Servi
 		if ag.AgreementTerminatedTime != 0 && ag.AgreementForceTerminatedTime == 0 {
 			glog.V(3).Infof(logString(fmt.Sprintf("skip agreement %v, it is already terminating", agreementId)))
 		} else {
-			bCancel, err := w.agreementRequiresService(ag, svcSpecs)
+			bCancel, bDependency, err := w.agreementRequiresService(ag, svcSpecs)
 			if err != nil {
 				glog.Errorf(fmt.Sprintf("%v", err))
 			}
 
-			if bCancel {
+			// A running workload whose containers reload their configuration is given the new user input
+			// instead of being cancelled, as long as none of its dependencies changed.
+			var userInput map[string]interface{}
+			if bCancel && !bDependency && ag.AgreementExecutionStartTime != 0 && ag.ReloadsConfig() {
+				if userInput, err = w.agreementUserInput(&ag); err != nil {
+					glog.Errorf(logString(fmt.Sprintf("unable to get the user input of agreement %v, the agreement will be cancelled: %v", agreementId, err)))
+				}
+			}
+
+			if userInput != nil {
+				glog.V(3).Infof(logString(fmt.Sprintf("reloading the configuration of agreement: %v", agreementId)))
+
+				eventlog.LogAgreementEvent(
+					w.db,
+					persistence.SEVERITY_INFO,
+					fmt.Sprintf("Start reloading the configuration of %v with the new node user input", ag.RunningWorkload.URL),
+					persistence.EC_RELOAD_SERVICE_CONFIG,
+					ag)
+
+				w.Messages() <- events.NewConfigReloadMessage(events.CONFIG_RELOAD, ag.AgreementProtocol, agreementId, userInput)
+			} else if bCancel {
 				glog.V(3).Infof(logString(fmt.Sprintf("ending the agreement: %v", agreementId)))
 
 				reason := w.producerPH[ag.AgreementProtocol].GetTerminationCode(producer.TERM_REASON_NODE_USERINPUT_CHANGED)
@@ -1880,35 +2042,37 @@ func (w *GovernanceWorker) handleNodeUserInputUpdated(svcSpecs persistence.Servi
 	}
 }
 
-// Check if the agreement uses any of the given services
-func (w *GovernanceWorker) agreementRequiresService(ag persistence.EstablishedAgreement, svcSpecs persistence.ServiceSpecs) (bool, error) {
+// Check if the agreement uses any of the given services. The second return value is true when one of the services
+// is a dependency of the agreement's top level service.
+func (w *GovernanceWorker) agreementRequiresService(ag persistence.EstablishedAgreement, svcSpecs persistence.ServiceSpecs) (bool, bool, error) {
 	if svcSpecs == nil || len(svcSpecs) == 0 {
-		return false, nil
+		return false, false, nil
 	}
 
 	workload := ag.RunningWorkload
 	if workload.URL == "" || workload.Org == "" {
-		return false, nil
+		return false, false, nil
 	}
 
 	asl, _, _, err := exchange.GetHTTPServiceResolverHandler(w)(workload.URL, workload.Org, workload.Version, workload.Arch)
 	if err != nil {
-		return false, fmt.Errorf(logString(fmt.Sprintf("error searching for service details %v, error: %v", workload, err)))
+		return false, false, fmt.Errorf(logString(fmt.Sprintf("error searching for service details %v, error: %v", workload, err)))
 	}
 
+	requires := false
 	for _, sp := range svcSpecs {
 		if workload.URL == sp.Url && workload.Org == sp.Org {
-			return true, nil
+			requires = true
 		}
 		if asl != nil {
 			for _, s := range *asl {
 				if s.SpecRef == sp.Url && s.Org == sp.Org {
-					return true, nil
+					return true, true, nil
 				}
 
 			}
 		}
 	}
 
-	return false, nil
+	return requires, false, nil
 }
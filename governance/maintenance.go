@@ -0,0 +1,301 @@
+package governance
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"strings"
+	"time"
+)
+
+// Maintenance windows restrict when the node accepts upgrades. The node declares its windows in the node policy, using the
+// openhorizon.maintenanceWindow property, or in the anax configuration. When the node does not declare any windows, the
+// upgrade time from the business policy of the workload is used. When there are no windows at all, upgrades happen as
+// soon as they are found.
+//
+// While the windows are closed, the node holds the agreement cancellations that the agbots send in order to move the node
+// to a different workload version, and it holds the upgrades of the services it is running. The held upgrades are
+// recorded in the database so that they can be shown to the user, and they are released by the maintenance governor
+// when a window opens.
+
+// Combine the maintenance windows from the configuration and the node policy. If neither has any windows, the upgrade time
+// of the workload is used. Windows that cannot be parsed are logged and ignored.
+func maintenanceWindows(configWindows []string, nodePol *externalpolicy.ExternalPolicy, upgradeTime string) cutil.MaintenanceWindows {
+
+	specs := make([]string, 0, len(configWindows))
+	specs = append(specs, configWindows...)
+
+	if nodePol != nil {
+		for _, prop := range nodePol.Properties {
+			if prop.Name != externalpolicy.PROP_NODE_MAINTENANCE_WINDOW {
+				continue
+			} else if value, ok := prop.Value.(string); !ok {
+				glog.Warningf(logString(fmt.Sprintf("ignoring node policy property %v, the value %v is not a string", prop.Name, prop.Value)))
+			} else {
+				specs = append(specs, strings.Split(value, ";")...)
+			}
+		}
+	}
+
+	windows := make(cutil.MaintenanceWindows, 0, len(specs))
+	for _, spec := range specs {
+		if strings.TrimSpace(spec) == "" {
+			continue
+		} else if mw, err := cutil.ParseMaintenanceWindow(spec); err != nil {
+			glog.Warningf(logString(fmt.Sprintf("ignoring node maintenance window, %v", err)))
+		} else {
+			windows = append(windows, *mw)
+		}
+	}
+
+	if len(windows) == 0 && upgradeTime != "" {
+		if mw, err := cutil.ParseMaintenanceWindow(upgradeTime); err != nil {
+			glog.Warningf(logString(fmt.Sprintf("ignoring workload upgrade time, %v", err)))
+		} else {
+			windows = append(windows, *mw)
+		}
+	}
+
+	return windows
+}
+
+// Returns the maintenance windows of the node, used for upgrades that do not belong to a workload.
+func (w *GovernanceWorker) nodeMaintenanceWindows() cutil.MaintenanceWindows {
+	nodePol, err := persistence.FindNodePolicy(w.db)
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to read node policy from the local database, error %v", err)))
+	}
+	return maintenanceWindows(w.Config.Edge.MaintenanceWindows, nodePol, "")
+}
+
+// Returns the maintenance windows for upgrading the workload of the given agreement.
+func (w *GovernanceWorker) agreementMaintenanceWindows(ag *persistence.EstablishedAgreement) cutil.MaintenanceWindows {
+	nodePol, err := persistence.FindNodePolicy(w.db)
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to read node policy from the local database, error %v", err)))
+	}
+	return maintenanceWindows(w.Config.Edge.MaintenanceWindows, nodePol, w.agreementUpgradeTime(ag))
+}
+
+// Returns the upgrade time of the workload in the terms and conditions of the agreement.
+func (w *GovernanceWorker) agreementUpgradeTime(ag *persistence.EstablishedAgreement) string {
+	bcType, bcName, bcOrg := w.producerPH[ag.AgreementProtocol].GetKnownBlockchain(ag)
+	if protocolHandler := w.producerPH[ag.AgreementProtocol].AgreementProtocolHandler(bcType, bcName, bcOrg); protocolHandler == nil {
+		glog.Warningf(logString(fmt.Sprintf("unable to get the upgrade time for agreement %v, agreement protocol handler is not ready", ag.CurrentAgreementId)))
+	} else if proposal, err := protocolHandler.DemarshalProposal(ag.Proposal); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("encountered error demarshalling proposal for agreement %v, error %v", ag.CurrentAgreementId, err)))
+	} else if tcPolicy, err := policy.DemarshalPolicy(proposal.TsAndCs()); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to demarshal TsAndCs of agreement %v, error %v", ag.CurrentAgreementId, err)))
+	} else if len(tcPolicy.Workloads) != 0 {
+		return tcPolicy.Workloads[0].UpgradeTime
+	}
+	return ""
+}
+
+// Convert the time a window opens into the format stored in a pending upgrade.
+func nextWindowTime(windows cutil.MaintenanceWindows, now time.Time) uint64 {
+	if next := windows.NextOpen(now); !next.IsZero() {
+		return uint64(next.Unix())
+	}
+	return 0
+}
+
+// Format the time a window opens for the event log.
+func formatWindowTime(t uint64) string {
+	if t == 0 {
+		return "an unknown time"
+	}
+	return time.Unix(int64(t), 0).Format(time.RFC3339)
+}
+
+// Returns true if the agreement is held until a maintenance window opens.
+func (w *GovernanceWorker) isUpgradeHeld(agreementId string) bool {
+	if pu, err := persistence.FindPendingUpgrade(w.db, agreementId); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to read pending upgrade %v from the local database, error %v", agreementId, err)))
+		return false
+	} else {
+		return pu != nil
+	}
+}
+
+// When the agbot cancels an agreement in order to upgrade the workload and the node's maintenance windows are closed,
+// hold the cancellation until a window opens. The workload keeps running in the meantime. Returns true if the
+// cancellation is being held.
+func (w *GovernanceWorker) holdUpgradeCancel(ag *persistence.EstablishedAgreement, reason uint) bool {
+
+	if !w.producerPH[ag.AgreementProtocol].IsUpgradeCancelReason(reason) {
+		return false
+	}
+
+	now := time.Now()
+	windows := w.agreementMaintenanceWindows(ag)
+	if windows.IsOpen(now) {
+		return false
+	}
+
+	pu := &persistence.PendingUpgrade{
+		Key:               ag.CurrentAgreementId,
+		Type:              persistence.PENDING_UPGRADE_AGREEMENT,
+		AgreementProtocol: ag.AgreementProtocol,
+		Reason:            reason,
+		ReasonDesc:        w.producerPH[ag.AgreementProtocol].GetTerminationReason(reason),
+		ServiceURL:        ag.RunningWorkload.URL,
+		ServiceOrg:        ag.RunningWorkload.Org,
+		CurrentVersion:    ag.RunningWorkload.Version,
+		Windows:           windows.String(),
+		ReceivedTime:      uint64(now.Unix()),
+		NextWindowTime:    nextWindowTime(windows, now),
+	}
+
+	if err := persistence.SavePendingUpgrade(w.db, pu); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to save pending upgrade for agreement %v, cancelling it now, error %v", ag.CurrentAgreementId, err)))
+		eventlog.LogDatabaseEvent(w.db, persistence.SEVERITY_ERROR,
+			fmt.Sprintf("Unable to save pending upgrade for agreement %v, error %v", ag.CurrentAgreementId, err),
+			persistence.EC_DATABASE_ERROR)
+		return false
+	}
+
+	glog.V(3).Infof(logString(fmt.Sprintf("holding cancel of agreement %v until the next maintenance window %v", ag.CurrentAgreementId, windows)))
+	eventlog.LogAgreementEvent(w.db, persistence.SEVERITY_INFO,
+		fmt.Sprintf("Holding the upgrade of %v/%v until the next maintenance window opens at %v.", ag.RunningWorkload.Org, ag.RunningWorkload.URL, formatWindowTime(pu.NextWindowTime)),
+		persistence.EC_HOLD_AGREEMENT_UPGRADE, *ag)
+	return true
+}
+
+// Returns the cause of the end of an agreement that is given to the workers. An agreement the agbot cancelled to upgrade
+// the workload ends with AG_UPGRADED, so that the workloads that can be upgraded in place, such as a Helm release, are
+// kept for the next agreement.
+func (w *GovernanceWorker) agreementEndCause(protocol string, reason uint) events.EndContractCause {
+	if w.producerPH[protocol].IsUpgradeCancelReason(reason) {
+		return events.AG_UPGRADED
+	}
+	return events.AG_TERMINATED
+}
+
+// Release a held agreement cancellation. This runs on the governance worker's command thread and does the same
+// cancellation steps as the cancel message handler.
+func (w *GovernanceWorker) releasePendingUpgrade(cmd *ReleasePendingUpgradeCommand) {
+
+	if err := persistence.DeletePendingUpgrade(w.db, cmd.AgreementId); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to delete pending upgrade %v, error %v", cmd.AgreementId, err)))
+	}
+
+	if ags, err := persistence.FindEstablishedAgreements(w.db, cmd.AgreementProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(cmd.AgreementId)}); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", cmd.AgreementId, err)))
+	} else if len(ags) != 1 || ags[0].AgreementTerminatedTime != 0 {
+		glog.V(3).Infof(logString(fmt.Sprintf("agreement %v with a held upgrade is already gone or terminating", cmd.AgreementId)))
+	} else {
+		eventlog.LogAgreementEvent(w.db, persistence.SEVERITY_INFO,
+			fmt.Sprintf("Maintenance window is open, releasing the held upgrade of %v/%v.", ags[0].RunningWorkload.Org, ags[0].RunningWorkload.URL),
+			persistence.EC_RELEASE_AGREEMENT_UPGRADE, ags[0])
+
+		w.cancelAgreement(cmd.AgreementId, cmd.AgreementProtocol, cmd.Reason, w.producerPH[cmd.AgreementProtocol].GetTerminationReason(cmd.Reason))
+		// cleanup workloads if needed
+		w.Messages() <- events.NewGovernanceWorkloadCancelationMessage(events.AGREEMENT_ENDED, w.agreementEndCause(cmd.AgreementProtocol, cmd.Reason), ags[0].AgreementProtocol, ags[0].CurrentAgreementId, ags[0].GetDeploymentConfig())
+		// clean up microservice instances if needed
+		w.handleMicroserviceInstForAgEnded(ags[0].CurrentAgreementId, false)
+	}
+}
+
+// Returns true if a service upgrade has to wait for a maintenance window. The held upgrade is recorded so that it is
+// visible to the user. The periodic service upgrade check finds the upgrade again once a window is open.
+func (w *GovernanceWorker) holdServiceUpgrade(msdef *persistence.MicroserviceDefinition, new_msdef *persistence.MicroserviceDefinition) bool {
+
+	now := time.Now()
+	windows := w.nodeMaintenanceWindows()
+	if windows.IsOpen(now) {
+		if err := persistence.DeletePendingUpgrade(w.db, msdef.Id); err != nil {
+			glog.Errorf(logString(fmt.Sprintf("unable to delete pending upgrade %v, error %v", msdef.Id, err)))
+		}
+		return false
+	}
+
+	pu := &persistence.PendingUpgrade{
+		Key:            msdef.Id,
+		Type:           persistence.PENDING_UPGRADE_SERVICE,
+		ServiceURL:     msdef.SpecRef,
+		ServiceOrg:     msdef.Org,
+		CurrentVersion: msdef.Version,
+		NewVersion:     new_msdef.Version,
+		Windows:        windows.String(),
+		ReceivedTime:   uint64(now.Unix()),
+		NextWindowTime: nextWindowTime(windows, now),
+	}
+
+	if existing, err := persistence.FindPendingUpgrade(w.db, msdef.Id); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to read pending upgrade %v from the local database, error %v", msdef.Id, err)))
+	} else if existing == nil || existing.NewVersion != new_msdef.Version {
+		if existing == nil {
+			eventlog.LogServiceEvent2(w.db, persistence.SEVERITY_INFO,
+				fmt.Sprintf("Holding the upgrade of service %v/%v from version %v to version %v until the next maintenance window opens at %v.", msdef.Org, msdef.SpecRef, msdef.Version, new_msdef.Version, formatWindowTime(pu.NextWindowTime)),
+				persistence.EC_HOLD_UPGRADE_SERVICE,
+				"", msdef.SpecRef, msdef.Org, msdef.Version, msdef.Arch, []string{})
+		}
+		// Fetch the images of the new version while the upgrade waits for the window.
+		w.prefetchService(new_msdef)
+	}
+
+	if err := persistence.SavePendingUpgrade(w.db, pu); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to save pending upgrade for service %v, error %v", msdef.Id, err)))
+	}
+
+	glog.V(3).Infof(logString(fmt.Sprintf("holding upgrade of service %v/%v until the next maintenance window %v", msdef.Org, msdef.SpecRef, windows)))
+	return true
+}
+
+// This function runs periodically in a separate process. It releases the held agreement cancellations when a maintenance
+// window opens, and it removes the held upgrades that no longer exist.
+func (w *GovernanceWorker) governMaintenanceWindows() int {
+
+	glog.V(4).Infof(logString(fmt.Sprintf("governing pending upgrades")))
+
+	pus, err := persistence.FindPendingUpgrades(w.db, []persistence.PUFilter{})
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to read pending upgrades from the local database, error %v", err)))
+		return 0
+	}
+
+	// Service upgrades are released by the service upgrade check, only the ones whose service is gone are removed here.
+	msdefs := make(map[string]bool)
+	if defs, err := persistence.FindMicroserviceDefs(w.db, []persistence.MSFilter{persistence.UnarchivedMSFilter()}); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to read service definitions from the local database, error %v", err)))
+		return 0
+	} else {
+		for _, msdef := range defs {
+			msdefs[msdef.Id] = true
+		}
+	}
+
+	now := time.Now()
+	for _, pu := range pus {
+		if pu.Type == persistence.PENDING_UPGRADE_SERVICE {
+			if !msdefs[pu.Key] {
+				persistence.DeletePendingUpgrade(w.db, pu.Key)
+			}
+			continue
+		}
+
+		if ags, err := persistence.FindEstablishedAgreements(w.db, pu.AgreementProtocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(pu.Key)}); err != nil {
+			glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", pu.Key, err)))
+		} else if len(ags) != 1 || ags[0].AgreementTerminatedTime != 0 {
+			glog.V(3).Infof(logString(fmt.Sprintf("removing pending upgrade for agreement %v, the agreement is gone", pu.Key)))
+			persistence.DeletePendingUpgrade(w.db, pu.Key)
+		} else if windows := w.agreementMaintenanceWindows(&ags[0]); windows.IsOpen(now) {
+			w.Commands <- w.NewReleasePendingUpgradeCommand(pu.Key, pu.AgreementProtocol, pu.Reason)
+		} else if next := nextWindowTime(windows, now); next != pu.NextWindowTime || windows.String() != pu.Windows {
+			// The windows have changed since the upgrade was held.
+			pu.NextWindowTime = next
+			pu.Windows = windows.String()
+			if err := persistence.SavePendingUpgrade(w.db, &pu); err != nil {
+				glog.Errorf(logString(fmt.Sprintf("unable to save pending upgrade for agreement %v, error %v", pu.Key, err)))
+			}
+		}
+	}
+
+	return 0
+}
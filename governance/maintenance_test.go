@@ -0,0 +1,58 @@
+// +build unit
+
+package governance
+
+import (
+	"github.com/open-horizon/anax/externalpolicy"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_maintenanceWindows(t *testing.T) {
+
+	// 2019-11-02 is a Saturday
+	saturday := time.Date(2019, 11, 2, 3, 0, 0, 0, time.Local)
+	sunday := time.Date(2019, 11, 3, 3, 0, 0, 0, time.Local)
+
+	// no windows at all, upgrades are always allowed
+	windows := maintenanceWindows([]string{}, nil, "")
+	assert.Equal(t, 0, len(windows), "There should be no windows.")
+	assert.True(t, windows.IsOpen(sunday), "No windows should always be open.")
+
+	// the workload upgrade time is used when the node has no windows
+	windows = maintenanceWindows([]string{}, nil, "0 2 * * 6 4h")
+	assert.Equal(t, 1, len(windows), "The upgrade time should be used.")
+	assert.True(t, windows.IsOpen(saturday), "The window should be open on Saturday.")
+	assert.False(t, windows.IsOpen(sunday), "The window should be closed on Sunday.")
+
+	// the node's windows replace the workload upgrade time, invalid windows are ignored
+	pol := &externalpolicy.ExternalPolicy{}
+	pol.Properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_NODE_MAINTENANCE_WINDOW, "0 2 * * 0 4h; bogus"), false)
+	windows = maintenanceWindows([]string{"0 12 * * 1"}, pol, "0 2 * * 6 4h")
+	assert.Equal(t, 2, len(windows), "The config and node policy windows should be used.")
+	assert.False(t, windows.IsOpen(saturday), "The window should be closed on Saturday.")
+	assert.True(t, windows.IsOpen(sunday), "The window should be open on Sunday.")
+
+	// an unparseable upgrade time is ignored
+	windows = maintenanceWindows([]string{}, nil, "whenever")
+	assert.Equal(t, 0, len(windows), "An invalid upgrade time should be ignored.")
+
+	// the node policy property has to be a string
+	pol = &externalpolicy.ExternalPolicy{}
+	pol.Properties.Add_Property(externalpolicy.Property_Factory(externalpolicy.PROP_NODE_MAINTENANCE_WINDOW, 5), false)
+	windows = maintenanceWindows([]string{}, pol, "")
+	assert.Equal(t, 0, len(windows), "A property that is not a string should be ignored.")
+}
+
+func Test_nextWindowTime(t *testing.T) {
+
+	now := time.Date(2019, 11, 4, 12, 0, 0, 0, time.Local)
+
+	windows := maintenanceWindows([]string{"0 2 * * 6 4h"}, nil, "")
+	assert.Equal(t, uint64(time.Date(2019, 11, 9, 2, 0, 0, 0, time.Local).Unix()), nextWindowTime(windows, now), "The next window should open on Saturday.")
+
+	windows = maintenanceWindows([]string{"0 0 30 2 *"}, nil, "")
+	assert.Equal(t, uint64(0), nextWindowTime(windows, now), "A window that never opens should have no next time.")
+	assert.Equal(t, "an unknown time", formatWindowTime(0), "An unknown time should be formatted.")
+}
@@ -790,6 +790,8 @@ func (w *GovernanceWorker) handleMicroserviceUpgrade(msdef_id string) {
 			glog.Errorf(logString(fmt.Sprintf("Error finding the new service definition to upgrade to for %v/%v version %v. %v", msdef.Org, msdef.SpecRef, msdef.Version, err)))
 		} else if new_msdef == nil {
 			glog.V(5).Infof(logString(fmt.Sprintf("No changes for service definition %v/%v, no need to upgrade.", msdef.Org, msdef.SpecRef)))
+		} else if w.holdServiceUpgrade(msdef, new_msdef) {
+			glog.V(5).Infof(logString(fmt.Sprintf("Upgrade of service definition %v/%v is held until the next maintenance window.", msdef.Org, msdef.SpecRef)))
 		} else {
 			eventlog.LogServiceEvent2(w.db, persistence.SEVERITY_INFO,
 				fmt.Sprintf("Start upgrading service %v/%v from version %v to version %v.", msdef.Org, msdef.SpecRef, msdef.Version, new_msdef.Version),
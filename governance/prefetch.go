@@ -0,0 +1,147 @@
+package governance
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/eventlog"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"sort"
+	"strings"
+)
+
+// Image pre-fetching pulls the images of a service version that the node is likely to upgrade to, so that the upgrade
+// only has to restart the containers. The agbot sends a workload prefetch hint when the policy of an agreement changes
+// to a new version of its service, and the service upgrade check finds the new versions of the dependent services.
+// When the upgrade is held for a maintenance window, the images are ready by the time the window opens. The images are
+// pulled by the imagefetch worker, which keeps them from being removed as unused images.
+
+// Handle a workload prefetch hint from the agbot. The hint is only taken for the service of the agreement, from the agbot
+// that the agreement is with.
+func (w *GovernanceWorker) prefetchWorkload(protocol string, agreementId string, agbotId string, workload *policy.Workload) {
+
+	if w.Config.Edge.DisableImagePrefetch {
+		glog.V(5).Infof(logString(fmt.Sprintf("ignoring workload prefetch for agreement %v, image prefetching is disabled", agreementId)))
+		return
+	}
+
+	ags, err := persistence.FindEstablishedAgreements(w.db, protocol, []persistence.EAFilter{persistence.UnarchivedEAFilter(), persistence.IdEAFilter(agreementId)})
+	if err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to retrieve agreement %v from database, error %v", agreementId, err)))
+		return
+	} else if len(ags) != 1 {
+		glog.V(3).Infof(logString(fmt.Sprintf("ignoring workload prefetch, agreement %v is gone", agreementId)))
+		return
+	}
+
+	ag := ags[0]
+	if ag.AgreementTerminatedTime != 0 {
+		glog.V(3).Infof(logString(fmt.Sprintf("ignoring workload prefetch, agreement %v is terminating", agreementId)))
+		return
+	} else if ag.ConsumerId != agbotId {
+		glog.Warningf(logString(fmt.Sprintf("ignoring workload prefetch for %v, it came from id %v but agreement is with %v", agreementId, agbotId, ag.ConsumerId)))
+		return
+	} else if workload.WorkloadURL != ag.RunningWorkload.URL || workload.Org != ag.RunningWorkload.Org {
+		glog.Warningf(logString(fmt.Sprintf("ignoring workload prefetch for %v, service %v/%v is not the service %v/%v of the agreement", agreementId, workload.Org, workload.WorkloadURL, ag.RunningWorkload.Org, ag.RunningWorkload.URL)))
+		return
+	} else if workload.Version == ag.RunningWorkload.Version {
+		glog.V(5).Infof(logString(fmt.Sprintf("ignoring workload prefetch for %v, version %v is already running", agreementId, workload.Version)))
+		return
+	}
+
+	arch := workload.Arch
+	if arch == "" {
+		arch = ag.RunningWorkload.Arch
+	}
+
+	if _, sDef, _, err := exchange.GetHTTPServiceResolverHandler(w)(workload.WorkloadURL, workload.Org, workload.Version, arch); err != nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to prefetch service %v/%v version %v, error querying exchange for service metadata: %v", workload.Org, workload.WorkloadURL, workload.Version, err)))
+	} else if sDef == nil {
+		glog.Errorf(logString(fmt.Sprintf("unable to prefetch service %v/%v version %v, the service is not in the exchange", workload.Org, workload.WorkloadURL, workload.Version)))
+	} else {
+		w.prefetchImages(workload.WorkloadURL, workload.Org, workload.Version, arch, agreementId, sDef.GetDeployment(), sDef.GetDeploymentSignature())
+	}
+}
+
+// Pre-fetch the images of a new version of a service found by the service upgrade check.
+func (w *GovernanceWorker) prefetchService(new_msdef *persistence.MicroserviceDefinition) {
+	if w.Config.Edge.DisableImagePrefetch {
+		return
+	}
+	w.prefetchImages(new_msdef.SpecRef, new_msdef.Org, new_msdef.Version, new_msdef.Arch, "", new_msdef.Deployment, new_msdef.DeploymentSignature)
+}
+
+// Ask imagefetch to pull the images of a service version, with the image auths of the service.
+func (w *GovernanceWorker) prefetchImages(url string, org string, version string, arch string, agreementId string, deployment string, deploymentSignature string) {
+
+	if deployment == "" {
+		glog.V(5).Infof(logString(fmt.Sprintf("service %v/%v version %v has no images to prefetch", org, url, version)))
+		return
+	} else if _, err := containermessage.GetNativeDeployment(deployment); err != nil {
+		glog.V(5).Infof(logString(fmt.Sprintf("service %v/%v version %v has no images to prefetch, %v", org, url, version, err)))
+		return
+	}
+
+	img_auths := make([]events.ImageDockerAuth, 0)
+	if w.Config.Edge.TrustDockerAuthFromOrg {
+		if ias, err := exchange.GetHTTPServiceDockerAuthsHandler(w)(url, org, version, arch); err != nil {
+			glog.V(5).Infof(logString(fmt.Sprintf("received error querying exchange for service image auths: %v/%v version %v, error %v", org, url, version, err)))
+		} else {
+			for _, iau_temp := range ias {
+				username := iau_temp.UserName
+				if username == "" {
+					username = "token"
+				}
+				img_auths = append(img_auths, events.ImageDockerAuth{Registry: iau_temp.Registry, UserName: username, Password: iau_temp.Token})
+			}
+		}
+	}
+
+	glog.V(3).Infof(logString(fmt.Sprintf("prefetching images of service %v/%v version %v", org, url, version)))
+	cc := events.NewContainerConfig(deployment, deploymentSignature, "", "", img_auths)
+	w.Messages() <- events.NewImagePrefetchMessage(events.IMAGE_PREFETCH, url, org, version, arch, agreementId, *cc, nil)
+}
+
+// Record the outcome of a prefetch in the event log.
+func (w *GovernanceWorker) logImagePrefetch(msg *events.ImagePrefetchMessage) {
+
+	agIds := []string{}
+	if msg.AgreementId != "" {
+		agIds = append(agIds, msg.AgreementId)
+	}
+
+	if msg.Event().Id == events.IMAGE_PREFETCHED {
+		eventlog.LogServiceEvent2(w.db, persistence.SEVERITY_INFO,
+			fmt.Sprintf("Image prefetched for service %v/%v version %v%v.", msg.Org, msg.ServiceURL, msg.Version, imageSourcesString(msg.ImageSources)),
+			persistence.EC_IMAGE_PREFETCHED,
+			"", msg.ServiceURL, msg.Org, msg.Version, msg.Arch, agIds)
+	} else {
+		eventlog.LogServiceEvent2(w.db, persistence.SEVERITY_WARN,
+			fmt.Sprintf("Unable to prefetch image for service %v/%v version %v, it is fetched when the service is upgraded.", msg.Org, msg.ServiceURL, msg.Version),
+			persistence.EC_ERROR_IMAGE_PREFETCH,
+			"", msg.ServiceURL, msg.Org, msg.Version, msg.Arch, agIds)
+	}
+}
+
+// Describes the registries or mirrors the images were pulled from, for the event log, e.g.
+// " from mirror.local:5000 (openhorizon/gps:1.0)". It is empty when the sources are not known.
+func imageSourcesString(sources map[string]string) string {
+	if len(sources) == 0 {
+		return ""
+	}
+
+	images := make([]string, 0, len(sources))
+	for image := range sources {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+
+	pulls := make([]string, 0, len(images))
+	for _, image := range images {
+		pulls = append(pulls, fmt.Sprintf("%v (%v)", sources[image], image))
+	}
+	return " from " + strings.Join(pulls, ", ")
+}
@@ -108,12 +108,24 @@ func (w *GovernanceWorker) nodeShutdown(cmd *NodeShutdownCommand) {
 		return
 	}
 
+	// Delete node user input secrets from local db
+	if err := persistence.DeleteNodeUserInputSecrets(w.db); err != nil {
+		w.completedWithError(logString(err.Error()))
+		return
+	}
+
 	// Reset the node user input hash
 	if err := persistence.DeleteNodeUserInputHash_Exch(w.db); err != nil {
 		w.completedWithError(logString(err.Error()))
 		return
 	}
 
+	// Forget the upgrades that were held for a maintenance window
+	if err := persistence.DeleteAllPendingUpgrades(w.db); err != nil {
+		w.completedWithError(logString(err.Error()))
+		return
+	}
+
 	// Tell the system that node quiesce is complete without error. The API worker might be waiting for this message.
 	// All the workers in the system will start quiescing as a result of this message.
 	w.Messages() <- events.NewNodeShutdownCompleteMessage(events.UNCONFIGURE_COMPLETE, "")
@@ -0,0 +1,54 @@
+// +build unit
+
+package governance
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+// the user input file gets the same values as the environment variables of the service
+func Test_GetServiceUserInput(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "utdb-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	db, err := bolt.Open(path.Join(dir, "anax-ut.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	assert.Nil(t, err)
+	defer db.Close()
+
+	nodeUI := []policy.UserInput{
+		{ServiceOrgid: "myorg", ServiceUrl: "myservice", Inputs: []policy.Input{{Name: "VAR1", Value: "node"}, {Name: "PASSWORD", Value: "secret", Type: policy.USER_INPUT_TYPE_SECRET}}},
+		{ServiceOrgid: "myorg", ServiceUrl: "otherservice", Inputs: []policy.Input{{Name: "VAR4", Value: "other"}}},
+	}
+	assert.Nil(t, persistence.SaveNodeUserInput(db, nodeUI))
+
+	tcPolicy := &policy.Policy{
+		UserInput: []policy.UserInput{{ServiceOrgid: "myorg", ServiceUrl: "myservice", Inputs: []policy.Input{{Name: "VAR1", Value: "policy"}, {Name: "VAR2", Value: float64(5)}}}},
+	}
+	sDef := &exchange.ServiceDefinition{
+		UserInputs: []exchange.UserInput{
+			{Name: "VAR2", Type: "int", DefaultValue: "1"},
+			{Name: "VAR3", Type: "string", DefaultValue: "default"},
+			{Name: "TOKEN", Type: policy.USER_INPUT_TYPE_SECRET, DefaultValue: "token"},
+		},
+	}
+
+	w := &GovernanceWorker{db: db}
+	values, err := w.GetServiceUserInput("myservice", "myorg", tcPolicy, sDef)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"VAR1": "node", "VAR2": float64(5), "VAR3": "default"}, values, "The node user input should come first, then the policy and then the defaults, without the secrets.")
+
+	values, err = w.GetServiceUserInput("myservice", "myorg", nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"VAR1": "node"}, values, "Only the node user input should be used.")
+}
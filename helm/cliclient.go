@@ -14,6 +14,8 @@ type CliClient struct {
 }
 
 const INSTALL_ARGS = "install -n %v %v"
+const UPGRADE_ARGS = "upgrade --atomic %v %v"
+const ROLLBACK_ARGS = "rollback %v %v"
 const UNINSTALL_ARGS = "delete --purge %v"
 const STATUS_ARGS = "list -a"
 const DEPLOYED = "DEPLOYED"
@@ -48,6 +50,64 @@ func (c *CliClient) Install(b64Package string, releaseName string) error {
 	return nil
 }
 
+// Upgrade a release in place with a new version of its chart. The upgrade is atomic, Helm rolls the release back when
+// the upgrade fails. If the release is still not deployed after that, it is rolled back to the revision it was at
+// before the upgrade.
+func (c *CliClient) Upgrade(b64Package string, releaseName string) error {
+
+	previous, err := c.Status(releaseName)
+	if err != nil {
+		return errors.New(fmt.Sprintf("unable to upgrade Helm release %v, error: %v", releaseName, err))
+	}
+
+	fileName, err := ConvertB64StringToFile(b64Package)
+	if err != nil {
+		return errors.New(fmt.Sprintf("error converting Helm package to file: %v", err))
+	}
+	glog.V(5).Infof(clilogString(fmt.Sprintf("Decoded Helm package to file: %v", fileName)))
+
+	args := fmt.Sprintf(UPGRADE_ARGS, releaseName, fileName)
+	glog.V(5).Infof(clilogString(fmt.Sprintf("Upgrading Helm release: %v", args)))
+	out, err := exec.Command("helm", strings.Fields(args)...).Output()
+	if err == nil {
+		glog.V(5).Infof(clilogString(fmt.Sprintf("Output from upgrade: (%T) %s", out, string(out))))
+		return nil
+	}
+
+	errMsg := ""
+	if exErr, ok := err.(*exec.ExitError); ok {
+		errMsg = string(exErr.Stderr)
+	}
+	upgradeErr := fmt.Sprintf("error upgrading Helm release: (%T) %v error message: %v", err, err, errMsg)
+
+	// Make sure the release is back to a deployed revision.
+	if status, err := c.Status(releaseName); err == nil && status.Status == DEPLOYED {
+		return errors.New(fmt.Sprintf("%v, the release was rolled back to revision %v", upgradeErr, status.Revision))
+	} else if err := c.Rollback(releaseName, previous.Revision); err != nil {
+		return errors.New(fmt.Sprintf("%v, %v", upgradeErr, err))
+	}
+	return errors.New(fmt.Sprintf("%v, the release was rolled back to revision %v", upgradeErr, previous.Revision))
+}
+
+// Roll a release back to one of its previous revisions.
+func (c *CliClient) Rollback(releaseName string, revision string) error {
+
+	args := fmt.Sprintf(ROLLBACK_ARGS, releaseName, revision)
+	glog.V(5).Infof(clilogString(fmt.Sprintf("Rolling back Helm release: %v", args)))
+	argFields := strings.Fields(args)
+	if out, err := exec.Command("helm", argFields...).Output(); err != nil {
+		errMsg := ""
+		if exErr, ok := err.(*exec.ExitError); ok {
+			errMsg = string(exErr.Stderr)
+		}
+		return errors.New(fmt.Sprintf("error rolling back Helm release: (%T) %v error message: %v", err, err, errMsg))
+	} else {
+		glog.V(5).Infof(clilogString(fmt.Sprintf("Output from rollback: (%T) %s", out, string(out))))
+	}
+
+	return nil
+}
+
 func (c *CliClient) UnInstall(releaseName string) error {
 
 	args := fmt.Sprintf(UNINSTALL_ARGS, releaseName)
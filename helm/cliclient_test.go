@@ -0,0 +1,70 @@
+// +build unit
+
+package helm
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A helm command that fails the upgrade without rolling it back, so that the client has to.
+const fakeHelm = `#!/bin/sh
+dir=$(dirname "$0")
+echo "$@" >> "$dir/calls"
+case "$1" in
+list)
+	printf 'myrelease\t%s\tMon Jan 2 15:04:05 2006\t%s\tmychart-1.0.0\tdefault\n' "$(cat "$dir/revision")" "$(cat "$dir/status")";;
+upgrade)
+	echo 2 > "$dir/revision"; echo FAILED > "$dir/status"; echo "timed out waiting for the condition" >&2; exit 1;;
+rollback)
+	echo 3 > "$dir/revision"; echo DEPLOYED > "$dir/status";;
+esac
+`
+
+// Put the fake helm command first on the PATH, with release myrelease deployed at revision 1. The directory also
+// gets the calls made to helm. The returned function restores the PATH and removes the directory.
+func setupFakeHelm(t *testing.T) (string, func()) {
+
+	dir, err := ioutil.TempDir("", "helm-test-")
+	if err != nil {
+		t.Fatalf("unable to create temp dir, error: %v", err)
+	}
+
+	for name, content := range map[string]string{"helm": fakeHelm, "revision": "1\n", "status": "DEPLOYED\n"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0755); err != nil {
+			t.Fatalf("unable to write %v, error: %v", name, err)
+		}
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+
+	return dir, func() {
+		os.Setenv("PATH", path)
+		os.RemoveAll(dir)
+	}
+}
+
+func Test_UpgradeRollback(t *testing.T) {
+
+	dir, cleanup := setupFakeHelm(t)
+	defer cleanup()
+
+	c := NewCliClient()
+	err := c.Upgrade(base64.StdEncoding.EncodeToString([]byte("chart")), "myrelease")
+	if err == nil || !strings.Contains(err.Error(), "rolled back to revision 1") {
+		t.Errorf("expected the failed upgrade to be rolled back to revision 1, but got %v", err)
+	}
+
+	calls, _ := ioutil.ReadFile(filepath.Join(dir, "calls"))
+	if !strings.Contains(string(calls), "upgrade --atomic myrelease") || !strings.Contains(string(calls), "rollback myrelease 1") {
+		t.Errorf("expected an atomic upgrade and a rollback to revision 1, but helm was called with %v", string(calls))
+	}
+
+	if status, err := c.Status("myrelease"); err != nil || status.Status != DEPLOYED || status.Revision != "3" {
+		t.Errorf("expected the release to be deployed at revision 3, but got %v, error: %v", status, err)
+	}
+}
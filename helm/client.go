@@ -21,6 +21,8 @@ type ReleaseStatus struct {
 // The Helm Client interface that we use, regardless of how its implemented under the covers.
 type HelmClient interface {
 	Install(b64Package string, releaseName string) error
+	Upgrade(b64Package string, releaseName string) error
+	Rollback(releaseName string, revision string) error
 	UnInstall(releaseName string) error
 	Status(releaseName string) (*ReleaseStatus, error)
 	ReleaseTimeFormat() string
@@ -2,6 +2,7 @@ package helm
 
 import (
 	"fmt"
+	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/persistence"
 )
 
@@ -23,17 +24,19 @@ type UnInstallCommand struct {
 	AgreementProtocol  string
 	CurrentAgreementId string
 	Deployment         persistence.DeploymentConfig
+	Cause              events.EndContractCause
 }
 
 func (u UnInstallCommand) ShortString() string {
 	return fmt.Sprintf("%v", u)
 }
 
-func NewUnInstallCommand(agp string, agId string, dc persistence.DeploymentConfig) *UnInstallCommand {
+func NewUnInstallCommand(agp string, agId string, dc persistence.DeploymentConfig, cause events.EndContractCause) *UnInstallCommand {
 	return &UnInstallCommand{
 		AgreementProtocol:  agp,
 		CurrentAgreementId: agId,
 		Deployment:         dc,
+		Cause:              cause,
 	}
 }
 
@@ -62,3 +65,15 @@ func NewMaintenanceCommand(protocol string, agreementId string, deployment persi
 		Deployment:        deployment,
 	}
 }
+
+// Uninstall the releases kept for an upgrade in place, before the node is unconfigured.
+type HandoverCleanupCommand struct {
+}
+
+func (c HandoverCleanupCommand) ShortString() string {
+	return fmt.Sprintf("HandoverCleanupCommand")
+}
+
+func NewHandoverCleanupCommand() *HandoverCleanupCommand {
+	return &HandoverCleanupCommand{}
+}
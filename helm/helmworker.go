@@ -8,12 +8,25 @@ import (
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/worker"
+	"time"
 )
 
+// How long a release is kept for the next agreement after the agbot cancelled its agreement to upgrade the workload.
+// The release is uninstalled when no agreement takes it over in that time.
+const RELEASE_HANDOVER_S = 600
+
 type HelmWorker struct {
 	worker.BaseWorker // embedded field
 	db                *bolt.DB
+	handovers         map[string]*releaseHandover // the releases kept for an upgrade in place, by release name. They are saved with the ended agreements.
+}
+
+// A release whose agreement ended so that the workload can be upgraded.
+type releaseHandover struct {
+	deployment *persistence.HelmDeploymentConfig
+	endTime    time.Time
 }
 
 func NewHelmWorker(name string, config *config.HorizonConfig, db *bolt.DB) *HelmWorker {
@@ -21,13 +34,20 @@ func NewHelmWorker(name string, config *config.HorizonConfig, db *bolt.DB) *Helm
 	worker := &HelmWorker{
 		BaseWorker: worker.NewBaseWorker(name, config, nil),
 		db:         db,
+		handovers:  make(map[string]*releaseHandover),
 	}
 
 	glog.Info(hpwlog(fmt.Sprintf("Starting Helm worker")))
-	worker.Start(worker, 0)
+	worker.Start(worker, 60)
 	return worker
 }
 
+// Restore the releases kept for an upgrade in place before the agent restarted.
+func (w *HelmWorker) Initialize() bool {
+	w.restoreHandovers()
+	return true
+}
+
 func (w *HelmWorker) Messages() chan events.Message {
 	return w.BaseWorker.Manager.Messages
 }
@@ -46,7 +66,7 @@ func (w *HelmWorker) NewEvent(incoming events.Message) {
 
 		switch msg.Event().Id {
 		case events.AGREEMENT_ENDED:
-			cmd := NewUnInstallCommand(msg.AgreementProtocol, msg.AgreementId, msg.Deployment)
+			cmd := NewUnInstallCommand(msg.AgreementProtocol, msg.AgreementId, msg.Deployment, msg.Cause)
 			w.Commands <- cmd
 		}
 
@@ -63,6 +83,7 @@ func (w *HelmWorker) NewEvent(incoming events.Message) {
 		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
 		switch msg.Event().Id {
 		case events.UNCONFIGURE_COMPLETE:
+			w.Commands <- NewHandoverCleanupCommand()
 			w.Commands <- worker.NewTerminateCommand("shutdown")
 		}
 
@@ -114,6 +135,11 @@ func (w *HelmWorker) CommandHandler(command worker.Command) bool {
 		if !ok {
 			glog.Warningf(hpwlog(fmt.Sprintf("ignoring non-Helm deployment: %v", cmd.Deployment)))
 			return true
+		} else if _, kept := w.handovers[hdc.ReleaseName]; cmd.Cause == events.AG_UPGRADED || kept {
+			// Keep the release running, the next agreement upgrades it in place. A release that is still kept was not
+			// taken over by this agreement, or this agreement's upgrade failed and it was rolled back to the version
+			// of the agreement that kept it.
+			w.keepRelease(cmd.AgreementProtocol, cmd.CurrentAgreementId, hdc)
 		} else if err := w.uninstallHelmPackage(hdc); err != nil {
 			// Since we have a Helm deployment package, uninstall it.
 			glog.Errorf(hpwlog(fmt.Sprintf("failed to uninstall helm package after agreement cancellation: %v", err)))
@@ -135,6 +161,9 @@ func (w *HelmWorker) CommandHandler(command worker.Command) bool {
 			w.Messages() <- events.NewWorkloadMessage(events.EXECUTION_FAILED, cmd.AgreementProtocol, cmd.AgreementId, hdc)
 		}
 
+	case *HandoverCleanupCommand:
+		w.uninstallHandovers(0)
+
 	default:
 		return false
 	}
@@ -142,6 +171,83 @@ func (w *HelmWorker) CommandHandler(command worker.Command) bool {
 
 }
 
+// Keep the release of an ended agreement for the next agreement to upgrade in place. The handover is saved with the
+// ended agreement so that it survives an agent restart.
+func (w *HelmWorker) keepRelease(protocol string, agreementId string, hdc *persistence.HelmDeploymentConfig) {
+	glog.V(3).Infof(hpwlog(fmt.Sprintf("keeping Helm release %v for an upgrade in place", hdc.ReleaseName)))
+	now := time.Now()
+	hdc.HandoverTime = uint64(now.Unix())
+	if _, err := persistence.AgreementDeploymentStarted(w.db, agreementId, protocol, hdc); err != nil {
+		glog.Errorf(hpwlog(fmt.Sprintf("unable to save the handover of Helm release %v, error: %v", hdc.ReleaseName, err)))
+	}
+	w.handovers[hdc.ReleaseName] = &releaseHandover{deployment: hdc, endTime: now}
+}
+
+// This function gets called when the worker framework has found nothing to do for the "no work interval"
+// that was set when the worker was started.
+func (w *HelmWorker) NoWorkHandler() {
+	w.uninstallHandovers(RELEASE_HANDOVER_S * time.Second)
+}
+
+// Uninstall the releases kept for an upgrade in place that no agreement took over within the wait time.
+func (w *HelmWorker) uninstallHandovers(wait time.Duration) {
+	for name, h := range w.handovers {
+		if time.Since(h.endTime) < wait {
+			continue
+		}
+		glog.V(3).Infof(hpwlog(fmt.Sprintf("no agreement took over Helm release %v for an upgrade", name)))
+		delete(w.handovers, name)
+		if err := w.uninstallHelmPackage(h.deployment); err != nil {
+			glog.Errorf(hpwlog(fmt.Sprintf("failed to uninstall helm package after upgrade handover: %v", err)))
+		}
+	}
+}
+
+// Find the releases that ended agreements kept for an upgrade in place. A release is kept when the last agreement that
+// used it kept it, no active agreement has taken it over, and it is still installed.
+func (w *HelmWorker) restoreHandovers() {
+
+	agreements, err := persistence.FindEstablishedAgreementsAllProtocols(w.db, policy.AllAgreementProtocols(), []persistence.EAFilter{})
+	if err != nil {
+		glog.Errorf(hpwlog(fmt.Sprintf("unable to read the agreements to restore the Helm release handovers, error: %v", err)))
+		return
+	}
+
+	active := make(map[string]bool)
+	last := make(map[string]*persistence.HelmDeploymentConfig)
+	for _, ag := range agreements {
+		hd, ok := ag.GetDeploymentConfig().(*persistence.HelmDeploymentConfig)
+		if !ok {
+			continue
+		} else if !ag.Archived {
+			active[hd.ReleaseName] = true
+		} else if prev, ok := last[hd.ReleaseName]; !ok || releaseEndTime(hd) > releaseEndTime(prev) {
+			last[hd.ReleaseName] = hd
+		}
+	}
+
+	c := NewHelmClient()
+	for name, hd := range last {
+		if hd.HandoverTime == 0 || active[name] {
+			continue
+		} else if _, err := c.Status(name); err != nil {
+			glog.V(3).Infof(hpwlog(fmt.Sprintf("Helm release %v kept for an upgrade is no longer installed: %v", name, err)))
+			continue
+		}
+		glog.V(3).Infof(hpwlog(fmt.Sprintf("restoring Helm release %v kept for an upgrade in place", name)))
+		w.handovers[name] = &releaseHandover{deployment: hd, endTime: time.Unix(int64(hd.HandoverTime), 0)}
+	}
+}
+
+// Returns the last time the agent changed or kept the release.
+func releaseEndTime(hd *persistence.HelmDeploymentConfig) uint64 {
+	end := hd.HandoverTime
+	if len(hd.History) != 0 && hd.History[len(hd.History)-1].Time > end {
+		end = hd.History[len(hd.History)-1].Time
+	}
+	return end
+}
+
 func (w *HelmWorker) getLaunchContext(launchContext interface{}) *events.AgreementLaunchContext {
 	switch launchContext.(type) {
 	case *events.AgreementLaunchContext:
@@ -151,22 +257,63 @@ func (w *HelmWorker) getLaunchContext(launchContext interface{}) *events.Agreeme
 	return nil
 }
 
+// Install the Helm package of an agreement. When the release was kept from an agreement that ended to upgrade the
+// workload, the release is upgraded in place instead, and its history is carried over.
 func (w *HelmWorker) processHelmPackage(launchContext *events.AgreementLaunchContext, hd *persistence.HelmDeploymentConfig) error {
 
-	glog.V(5).Infof(hpwlog(fmt.Sprintf("begin install of Helm Deployment release %v", hd.ReleaseName)))
-
 	// TODO: Verify signature
 
 	c := NewHelmClient()
-	if err := c.Install(hd.ChartArchive, hd.ReleaseName); err != nil {
-		return errors.New(fmt.Sprintf("unable to install Helm package %v, error: %v", hd, err))
-	}
+	action := persistence.HELM_ACTION_INSTALL
+	if h, ok := w.handovers[hd.ReleaseName]; ok {
+		delete(w.handovers, hd.ReleaseName)
+		hd.History = h.deployment.History
+
+		glog.V(5).Infof(hpwlog(fmt.Sprintf("begin upgrade of Helm Deployment release %v", hd.ReleaseName)))
+		action = persistence.HELM_ACTION_UPGRADE
+		if err := c.Upgrade(hd.ChartArchive, hd.ReleaseName); err != nil {
+			// The release is back at the version of the agreement that kept it. It is kept again so that the cleanup
+			// of this agreement does not uninstall it.
+			w.recordRevision(c, launchContext, hd, persistence.HELM_ACTION_ROLLBACK)
+			w.handovers[hd.ReleaseName] = &releaseHandover{deployment: hd, endTime: time.Now()}
+			return errors.New(fmt.Sprintf("unable to upgrade Helm package %v, error: %v", hd, err))
+		}
+		glog.V(5).Infof(hpwlog(fmt.Sprintf("completed upgrade of Helm Deployment release %v", hd.ReleaseName)))
 
-	glog.V(5).Infof(hpwlog(fmt.Sprintf("completed install of Helm Deployment release %v", hd.ReleaseName)))
+	} else {
+		glog.V(5).Infof(hpwlog(fmt.Sprintf("begin install of Helm Deployment release %v", hd.ReleaseName)))
+		if err := c.Install(hd.ChartArchive, hd.ReleaseName); err != nil {
+			return errors.New(fmt.Sprintf("unable to install Helm package %v, error: %v", hd, err))
+		}
+		glog.V(5).Infof(hpwlog(fmt.Sprintf("completed install of Helm Deployment release %v", hd.ReleaseName)))
+	}
 
+	w.recordRevision(c, launchContext, hd, action)
 	return nil
 }
 
+// Add the current revision of the release to its history and save it with the agreement.
+func (w *HelmWorker) recordRevision(c HelmClient, launchContext *events.AgreementLaunchContext, hd *persistence.HelmDeploymentConfig, action string) {
+
+	status, err := c.Status(hd.ReleaseName)
+	if err != nil {
+		glog.Warningf(hpwlog(fmt.Sprintf("unable to record the revision of Helm release %v, error: %v", hd.ReleaseName, err)))
+		return
+	}
+
+	hd.AddRevision(persistence.HelmReleaseRevision{
+		Revision:    status.Revision,
+		Action:      action,
+		Chart:       status.ChartName,
+		Status:      status.Status,
+		Time:        uint64(time.Now().Unix()),
+		AgreementId: launchContext.AgreementId,
+	})
+	if _, err := persistence.AgreementDeploymentStarted(w.db, launchContext.AgreementId, launchContext.AgreementProtocol, hd); err != nil {
+		glog.Errorf(hpwlog(fmt.Sprintf("unable to save the history of Helm release %v, error: %v", hd.ReleaseName, err)))
+	}
+}
+
 func (w *HelmWorker) uninstallHelmPackage(hd *persistence.HelmDeploymentConfig) error {
 
 	glog.V(5).Infof(hpwlog(fmt.Sprintf("begin uninstall of Helm Deployment release %v", hd.ReleaseName)))
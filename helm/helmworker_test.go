@@ -0,0 +1,80 @@
+// +build unit
+
+package helm
+
+import (
+	"encoding/base64"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"github.com/open-horizon/anax/worker"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the last change of a release is its handover or its latest revision
+func Test_releaseEndTime(t *testing.T) {
+
+	hd := persistence.NewHelmDeployment("", "myrelease")
+	if end := releaseEndTime(hd); end != 0 {
+		t.Errorf("a release without history or handover should have no end time, but got %v", end)
+	}
+
+	hd.AddRevision(persistence.HelmReleaseRevision{Revision: "1", Time: 100})
+	hd.AddRevision(persistence.HelmReleaseRevision{Revision: "2", Time: 200})
+	if end := releaseEndTime(hd); end != 200 {
+		t.Errorf("the end time should be the time of the latest revision, but got %v", end)
+	}
+
+	hd.HandoverTime = 300
+	if end := releaseEndTime(hd); end != 300 {
+		t.Errorf("the end time should be the handover time, but got %v", end)
+	}
+}
+
+// a release rolled back after a failed upgrade keeps running the previous version when the agreement is cleaned up
+func Test_RolledBackUpgradeKept(t *testing.T) {
+
+	dir, cleanup := setupFakeHelm(t)
+	defer cleanup()
+
+	db, err := bolt.Open(filepath.Join(dir, "anax-ut.db"), 0600, &bolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("unable to open the database, error: %v", err)
+	}
+	defer db.Close()
+
+	w := &HelmWorker{
+		BaseWorker: worker.NewBaseWorker("helm", &config.HorizonConfig{}, nil),
+		db:         db,
+		handovers:  map[string]*releaseHandover{"myrelease": {deployment: persistence.NewHelmDeployment("", "myrelease"), endTime: time.Now()}},
+	}
+	w.BaseWorker.Manager.Messages = make(chan events.Message, 10)
+
+	lc := &events.AgreementLaunchContext{AgreementId: "ag2", AgreementProtocol: policy.BasicProtocol}
+	hd := persistence.NewHelmDeployment(base64.StdEncoding.EncodeToString([]byte("chart")), "myrelease")
+	if err := w.processHelmPackage(lc, hd); err == nil {
+		t.Fatalf("the upgrade should have failed")
+	} else if _, ok := w.handovers["myrelease"]; !ok {
+		t.Errorf("the rolled back release should be kept")
+	}
+
+	w.CommandHandler(NewUnInstallCommand(policy.BasicProtocol, "ag2", hd, events.AG_TERMINATED))
+	if calls, _ := ioutil.ReadFile(filepath.Join(dir, "calls")); strings.Contains(string(calls), "delete") {
+		t.Errorf("the rolled back release should not be uninstalled with the agreement, but helm was called with %v", string(calls))
+	} else if _, ok := w.handovers["myrelease"]; !ok {
+		t.Errorf("the rolled back release should still be kept for the next agreement")
+	} else if hd.HandoverTime == 0 {
+		t.Errorf("the handover of the rolled back release should be saved with the agreement")
+	}
+
+	w.uninstallHandovers(0)
+	if calls, _ := ioutil.ReadFile(filepath.Join(dir, "calls")); !strings.Contains(string(calls), "delete --purge myrelease") {
+		t.Errorf("the release should be uninstalled when no agreement takes it over, but helm was called with %v", string(calls))
+	}
+}
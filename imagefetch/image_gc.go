@@ -0,0 +1,391 @@
+package imagefetch
+
+import (
+	"fmt"
+	"github.com/boltdb/bolt"
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/persistence"
+	"github.com/open-horizon/anax/policy"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const IMAGE_GC = "ImageGC"
+
+// How long the images of a deployment are held when its containers are never started, for example because its
+// agreement is cancelled before they are created.
+const PENDING_IMAGES_TTL = time.Hour
+
+// Image removals do not overlap, and hold imageGCLock from the time they find the images that are needed until they
+// have removed the others. The images of a deployment are held from before they are pulled until its containers are
+// started or fail to start, so that they are not removed in between.
+var imageGCLock sync.Mutex
+
+// The images held for the agreements and service instances whose containers are not started yet, by the agreement id
+// or service instance key. The map is guarded by imageGCLock, it is lost when the agent restarts.
+var pendingImages = make(map[string]heldImages)
+
+type heldImages struct {
+	refs []string
+	held time.Time
+}
+
+// The references of the images pulled ahead of a service upgrade, with the time they were prefetched. They are not
+// removed until a container has used them or the upgrade does not happen within the PrefetchKeepH of the ImageGC
+// config. The map is guarded by imageGCLock, it is lost when the agent restarts.
+var prefetchedImages = make(map[string]time.Time)
+
+// Hold the images of a deployment for its owner until releaseImages is called.
+func holdImages(owner string, dd *containermessage.DeploymentDescription, mirrors *config.RegistryMirrorsConfig) {
+	imageGCLock.Lock()
+	defer imageGCLock.Unlock()
+
+	refs := make([]string, 0, len(dd.Services))
+	for _, service := range dd.Services {
+		_, serviceRefs := imageReferences(mirrors, service.Image)
+		refs = append(refs, serviceRefs...)
+	}
+	pendingImages[owner] = heldImages{refs: refs, held: time.Now()}
+}
+
+// Release the images held for an owner, its containers use them now or will not be created.
+func releaseImages(owner string) {
+	imageGCLock.Lock()
+	defer imageGCLock.Unlock()
+
+	delete(pendingImages, owner)
+}
+
+// Add the references of the held images to the protected images and forget the holds that are too old, the caller
+// holds imageGCLock.
+func protectPendingImages(protected map[string]bool, now time.Time) {
+	for owner, held := range pendingImages {
+		if now.Sub(held.held) > PENDING_IMAGES_TTL {
+			glog.V(3).Infof("Images %v of %v are no longer held, its containers were not started after %v", held.refs, owner, PENDING_IMAGES_TTL)
+			delete(pendingImages, owner)
+			continue
+		}
+		for _, ref := range held.refs {
+			protected[ref] = true
+		}
+	}
+}
+
+// Remember the images of a prefetched deployment.
+func recordPrefetchedImages(dd *containermessage.DeploymentDescription, mirrors *config.RegistryMirrorsConfig) {
+	imageGCLock.Lock()
+	defer imageGCLock.Unlock()
+
+	for _, service := range dd.Services {
+		_, refs := imageReferences(mirrors, service.Image)
+		for _, ref := range refs {
+			prefetchedImages[ref] = time.Now()
+		}
+	}
+}
+
+// Add the references of the prefetched images to the protected images and forget the images that a container uses
+// now, or that were prefetched longer than keep ago, the caller holds imageGCLock.
+func protectPrefetchedImages(protected map[string]bool, inUse map[string]bool, now time.Time, keep time.Duration) {
+	for ref, prefetched := range prefetchedImages {
+		if inUse[ref] {
+			delete(prefetchedImages, ref)
+		} else if now.Sub(prefetched) > keep {
+			glog.V(3).Infof("Prefetched image %v is no longer kept, no container has used it since %v", ref, prefetched)
+			delete(prefetchedImages, ref)
+		} else {
+			protected[ref] = true
+		}
+	}
+}
+
+// An image that was removed, or would be removed in a dry run.
+type ImageGCImage struct {
+	ID       string   `json:"id"`
+	RepoTags []string `json:"repo_tags"`
+	Created  int64    `json:"created"`
+	Size     int64    `json:"size"`
+}
+
+// The outcome of an image removal. In a dry run, the images are those that would be removed, oldest first; the
+// removal stops once the disk usage is below the high-water mark, so fewer might actually be removed.
+type ImageGCReport struct {
+	DryRun               bool           `json:"dry_run"`
+	DiskUsagePercent     int            `json:"disk_usage_percent"`
+	HighWaterMarkPercent int            `json:"high_water_mark_percent"`
+	Images               []ImageGCImage `json:"images"`
+	ReclaimedBytes       int64          `json:"reclaimed_bytes"`
+}
+
+func (r *ImageGCReport) String() string {
+	return fmt.Sprintf("DryRun: %v, DiskUsagePercent: %v, HighWaterMarkPercent: %v, Images: %v, ReclaimedBytes: %v", r.DryRun, r.DiskUsagePercent, r.HighWaterMarkPercent, r.Images, r.ReclaimedBytes)
+}
+
+// The periodic image removal, run by a subworker when it is enabled.
+func (b *ImageFetchWorker) collectImages() int {
+	if report, err := CollectImages(b.Config, b.client, b.db, false); err != nil {
+		glog.Errorf("Unable to remove unused images, error: %v", err)
+	} else if len(report.Images) != 0 {
+		glog.Infof("Removed %v unused images, reclaimed %v bytes. Report: %v", len(report.Images), report.ReclaimedBytes, report)
+	}
+	return 0
+}
+
+// Remove the service images that are no longer used, or in a dry run, return the images that would be removed.
+func CollectImages(cfg *config.HorizonConfig, client *docker.Client, db *bolt.DB, dryRun bool) (*ImageGCReport, error) {
+
+	imageGCLock.Lock()
+	defer imageGCLock.Unlock()
+
+	gc := cfg.Edge.ImageGC
+	report := &ImageGCReport{DryRun: dryRun, HighWaterMarkPercent: gc.HighWaterMarkPercent, Images: []ImageGCImage{}}
+
+	rootDir := gc.DockerRootDir
+	if rootDir == "" {
+		if info, err := client.Info(); err != nil {
+			return nil, fmt.Errorf("unable to get the docker root directory, error %v", err)
+		} else {
+			rootDir = info.DockerRootDir
+		}
+	}
+
+	usage, err := diskUsagePercent(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the disk usage of %v, error %v", rootDir, err)
+	}
+	report.DiskUsagePercent = usage
+	if usage < gc.HighWaterMarkPercent {
+		glog.V(5).Infof("Disk usage of %v is %v%%, under the high-water mark of %v%%, no images are removed.", rootDir, usage, gc.HighWaterMarkPercent)
+		return report, nil
+	}
+
+	repos, protected, err := serviceImageReferences(db, &cfg.Edge.RegistryMirrors)
+	if err != nil {
+		return nil, err
+	}
+
+	images, err := client.ListImages(docker.ListImagesOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list images, error %v", err)
+	}
+
+	containers, err := client.ListContainers(docker.ListContainersOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list containers, error %v", err)
+	}
+	// The containers list the image they were created with, by reference or by id.
+	inUse := make(map[string]bool)
+	for _, c := range containers {
+		inUse[c.Image] = true
+		if _, ref := imageReference(c.Image); ref != "" {
+			inUse[ref] = true
+		}
+	}
+
+	// The images of the deployments that are not started yet are waiting for their containers.
+	protectPendingImages(protected, time.Now())
+
+	// The prefetched images are waiting for an upgrade, until a container uses them.
+	protectPrefetchedImages(protected, inUse, time.Now(), time.Duration(gc.PrefetchKeepH)*time.Hour)
+
+	for _, image := range unusedImages(images, repos, protected, inUse, gc.KeepVersions) {
+		if !dryRun {
+			if gc.HighWaterMarkPercent != 0 {
+				if usage, err := diskUsagePercent(rootDir); err == nil && usage < gc.HighWaterMarkPercent {
+					report.DiskUsagePercent = usage
+					break
+				}
+			}
+			if err := removeImage(client, image); err != nil {
+				glog.Errorf("Unable to remove unused image %v %v, error: %v", image.ID, image.RepoTags, err)
+				continue
+			}
+			glog.V(3).Infof("Removed unused image %v %v", image.ID, image.RepoTags)
+		}
+		report.Images = append(report.Images, ImageGCImage{ID: image.ID, RepoTags: image.RepoTags, Created: image.Created, Size: image.Size})
+		report.ReclaimedBytes += image.Size
+	}
+
+	if !dryRun && len(report.Images) != 0 {
+		if usage, err := diskUsagePercent(rootDir); err == nil {
+			report.DiskUsagePercent = usage
+		}
+	}
+	return report, nil
+}
+
+// Returns the repositories of the images that the service deployments on this node have used, and the references of
+// the images that are still needed, by the service definitions and the agreements that are not archived. The images
+// can also be in the repositories of the registry mirrors they were pulled from.
+func serviceImageReferences(db *bolt.DB, mirrors *config.RegistryMirrorsConfig) (map[string]bool, map[string]bool, error) {
+
+	repos := make(map[string]bool)
+	protected := make(map[string]bool)
+	add := func(image string, needed bool) {
+		imageRepos, refs := imageReferences(mirrors, image)
+		for ix, repo := range imageRepos {
+			repos[repo] = true
+			if needed {
+				protected[refs[ix]] = true
+			}
+		}
+	}
+
+	msdefs, err := persistence.FindMicroserviceDefs(db, []persistence.MSFilter{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read service definitions, error %v", err)
+	}
+	for _, msdef := range msdefs {
+		if msdef.Deployment == "" {
+			continue
+		} else if dd, err := containermessage.GetNativeDeployment(msdef.Deployment); err == nil {
+			for _, service := range dd.Services {
+				add(service.Image, !msdef.Archived)
+			}
+		}
+	}
+
+	agreements, err := persistence.FindEstablishedAgreementsAllProtocols(db, policy.AllAgreementProtocols(), []persistence.EAFilter{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read agreements, error %v", err)
+	}
+	for _, ag := range agreements {
+		for _, service := range ag.CurrentDeployment {
+			add(service.Config.Image, !ag.Archived && ag.AgreementTerminatedTime == 0)
+		}
+	}
+
+	return repos, protected, nil
+}
+
+// Returns the repositories and references of an image, under its own name and under the names of the registry mirrors
+// it can be pulled from. An image pinned by digest keeps the name of the mirror it was pulled from, see
+// tagMirroredImage, an image pinned by tag loses it unless the mirror tag could not be removed.
+func imageReferences(mirrors *config.RegistryMirrorsConfig, image string) ([]string, []string) {
+	repos, refs := make([]string, 0), make([]string, 0)
+	if repo, ref := imageReference(image); repo != "" {
+		repos, refs = append(repos, repo), append(refs, ref)
+		for _, mirrored := range cutil.MirrorImagePaths(mirrors, image) {
+			if repo, ref := imageReference(mirrored); repo != "" {
+				repos, refs = append(repos, repo), append(refs, ref)
+			}
+		}
+	}
+	return repos, refs
+}
+
+// Returns the repository of an image and the reference docker lists the image under, repo:tag or repo@digest.
+func imageReference(image string) (string, string) {
+	domain, path, tag, digest := cutil.ParseDockerImagePath(image)
+	if path == "" {
+		return "", ""
+	}
+
+	repo := path
+	if domain != "" {
+		repo = domain + "/" + path
+	}
+
+	if digest != "" {
+		return repo, repo + "@" + digest
+	} else if tag == "" {
+		tag = "latest"
+	}
+	return repo, repo + ":" + tag
+}
+
+// Returns the images that can be removed, oldest first. Only the images of the given repositories are removed. An
+// image is kept when its id or one of its references is in use by a container or protected, or it is one of the
+// newest images of one of its repositories.
+func unusedImages(images []docker.APIImages, repos map[string]bool, protected map[string]bool, inUse map[string]bool, keep int) []docker.APIImages {
+
+	sorted := make([]docker.APIImages, len(images))
+	copy(sorted, images)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Created > sorted[j].Created })
+
+	// The images of each repository, newest first.
+	kept := make(map[string]bool)
+	perRepo := make(map[string]int)
+	candidates := make([]docker.APIImages, 0)
+	for _, image := range sorted {
+		refs := append(append([]string{}, image.RepoTags...), image.RepoDigests...)
+		known, needed := false, inUse[image.ID]
+		for _, ref := range refs {
+			if ref == "<none>:<none>" || ref == "<none>@<none>" {
+				continue
+			}
+			repo := imageRepo(ref)
+			if !repos[repo] {
+				// an image that is also in another repository is not removed
+				known = false
+				needed = true
+				break
+			}
+			known = true
+			if protected[ref] || inUse[ref] {
+				needed = true
+			}
+			if perRepo[repo] < keep && !kept[image.ID+repo] {
+				perRepo[repo]++
+				kept[image.ID+repo] = true
+				needed = true
+			}
+		}
+		if known && !needed {
+			candidates = append(candidates, image)
+		}
+	}
+
+	// oldest first
+	for i, j := 0, len(candidates)-1; i < j; i, j = i+1, j-1 {
+		candidates[i], candidates[j] = candidates[j], candidates[i]
+	}
+	return candidates
+}
+
+// Returns the repository of a repo:tag or repo@digest reference. The repository can have a registry port.
+func imageRepo(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		return ref[:i]
+	} else if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return ref[:i]
+	}
+	return ref
+}
+
+// Remove each tag of the image, docker removes the image with its last tag. An image without tags is removed by id.
+func removeImage(client *docker.Client, image docker.APIImages) error {
+	tags := make([]string, 0, len(image.RepoTags))
+	for _, tag := range image.RepoTags {
+		if tag != "<none>:<none>" {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return client.RemoveImage(image.ID)
+	}
+	for _, tag := range tags {
+		if err := client.RemoveImage(tag); err != nil && err != docker.ErrNoSuchImage {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns how full the file system holding the directory is, in percent.
+func diskUsagePercent(dir string) (int, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	} else if stat.Blocks == 0 {
+		return 0, nil
+	}
+	return int((stat.Blocks - stat.Bfree) * 100 / stat.Blocks), nil
+}
@@ -0,0 +1,136 @@
+// +build unit
+
+package imagefetch
+
+import (
+	docker "github.com/fsouza/go-dockerclient"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_imageReference(t *testing.T) {
+
+	repo, ref := imageReference("openhorizon/amd64_gps:2.0.3")
+	assert.Equal(t, "openhorizon/amd64_gps", repo, "The repository should not have the tag.")
+	assert.Equal(t, "openhorizon/amd64_gps:2.0.3", ref, "The reference should have the tag.")
+
+	_, ref = imageReference("myregistry.com:5000/gps")
+	assert.Equal(t, "myregistry.com:5000/gps:latest", ref, "An image without a tag is the latest.")
+
+	repo, ref = imageReference("myregistry.com/gps@sha256:0123")
+	assert.Equal(t, "myregistry.com/gps", repo, "The repository should not have the digest.")
+	assert.Equal(t, "myregistry.com/gps@sha256:0123", ref, "The reference should have the digest.")
+
+	assert.Equal(t, "myregistry.com:5000/gps", imageRepo("myregistry.com:5000/gps:1.0"), "The tag should be removed.")
+	assert.Equal(t, "myregistry.com:5000/gps", imageRepo("myregistry.com:5000/gps"), "The registry port is not a tag.")
+	assert.Equal(t, "gps", imageRepo("gps@sha256:0123"), "The digest should be removed.")
+}
+
+func Test_imageReferences(t *testing.T) {
+
+	mirrors := &config.RegistryMirrorsConfig{Mirrors: []config.RegistryMirror{
+		{Mirror: "mirror.local:5000", Prefix: "dockerhub", Registries: []string{"docker.io"}},
+		{Mirror: "other.local"},
+	}}
+
+	repos, refs := imageReferences(mirrors, "openhorizon/gps@sha256:0123")
+	assert.Equal(t, []string{"openhorizon/gps", "mirror.local:5000/dockerhub/openhorizon/gps", "other.local/openhorizon/gps"}, repos, "The repositories of the mirrors should follow the repository of the image.")
+	assert.Equal(t, []string{"openhorizon/gps@sha256:0123", "mirror.local:5000/dockerhub/openhorizon/gps@sha256:0123", "other.local/openhorizon/gps@sha256:0123"}, refs, "The image pinned by digest keeps the name of its mirror.")
+
+	repos, refs = imageReferences(mirrors, "myregistry.com/gps:1.0")
+	assert.Equal(t, []string{"myregistry.com/gps", "other.local/gps"}, repos, "Only the mirrors of the registry of the image should be used.")
+	assert.Equal(t, []string{"myregistry.com/gps:1.0", "other.local/gps:1.0"}, refs, "The tag should be kept on the mirror.")
+
+	repos, refs = imageReferences(&config.RegistryMirrorsConfig{}, "openhorizon/gps")
+	assert.Equal(t, []string{"openhorizon/gps"}, repos, "Without mirrors only the repository of the image is used.")
+	assert.Equal(t, []string{"openhorizon/gps:latest"}, refs, "An image without a tag is the latest.")
+}
+
+func Test_unusedImages(t *testing.T) {
+
+	images := []docker.APIImages{
+		{ID: "sha256:1", RepoTags: []string{"openhorizon/gps:1.0"}, Created: 100},
+		{ID: "sha256:2", RepoTags: []string{"openhorizon/gps:2.0"}, Created: 200},
+		{ID: "sha256:3", RepoTags: []string{"openhorizon/gps:3.0"}, Created: 300},
+		{ID: "sha256:4", RepoTags: []string{"openhorizon/gps:4.0"}, Created: 400},
+		{ID: "sha256:5", RepoTags: []string{"openhorizon/cpu:1.0"}, Created: 50},
+		{ID: "sha256:6", RepoTags: []string{"openhorizon/cpu:2.0", "mine/cpu:2.0"}, Created: 60},
+		{ID: "sha256:7", RepoTags: []string{"ubuntu:18.04"}, Created: 10},
+		{ID: "sha256:8", RepoTags: []string{"<none>:<none>"}, RepoDigests: []string{"openhorizon/cpu@sha256:abc"}, Created: 40},
+	}
+	repos := map[string]bool{"openhorizon/gps": true, "openhorizon/cpu": true}
+	protected := map[string]bool{"openhorizon/gps:2.0": true}
+	inUse := map[string]bool{"sha256:1": true}
+
+	unused := unusedImages(images, repos, protected, inUse, 1)
+
+	ids := make([]string, 0)
+	for _, image := range unused {
+		ids = append(ids, image.ID)
+	}
+	assert.Equal(t, []string{"sha256:8", "sha256:5", "sha256:3"}, ids, "Only the old, unused and unprotected service images should be removed, oldest first.")
+
+	unused = unusedImages(images, repos, protected, inUse, 2)
+	assert.Equal(t, 1, len(unused), "Keeping more versions should remove fewer images.")
+	assert.Equal(t, "sha256:8", unused[0].ID, "The untagged image is beyond the versions kept.")
+}
+
+// the images pinned by digest and pulled through a mirror keep the name of the mirror, and are removed when they are replaced
+func Test_unusedImages_mirrored(t *testing.T) {
+
+	mirrors := &config.RegistryMirrorsConfig{Mirrors: []config.RegistryMirror{{Mirror: "mirror.local:5000"}}}
+	images := []docker.APIImages{
+		{ID: "sha256:1", RepoTags: []string{"<none>:<none>"}, RepoDigests: []string{"mirror.local:5000/openhorizon/gps@sha256:aaa"}, Created: 100},
+		{ID: "sha256:2", RepoTags: []string{"<none>:<none>"}, RepoDigests: []string{"mirror.local:5000/openhorizon/gps@sha256:bbb"}, Created: 200},
+		{ID: "sha256:3", RepoTags: []string{"<none>:<none>"}, RepoDigests: []string{"mirror.local:5000/openhorizon/gps@sha256:ccc"}, Created: 300},
+	}
+
+	repos, protected := make(map[string]bool), make(map[string]bool)
+	for _, image := range []string{"openhorizon/gps@sha256:aaa", "openhorizon/gps@sha256:bbb"} {
+		imageRepos, refs := imageReferences(mirrors, image)
+		for ix, repo := range imageRepos {
+			repos[repo] = true
+			if image == "openhorizon/gps@sha256:aaa" {
+				protected[refs[ix]] = true
+			}
+		}
+	}
+
+	unused := unusedImages(images, repos, protected, map[string]bool{}, 1)
+	assert.Equal(t, 1, len(unused), "Only the replaced mirrored image should be removed.")
+	assert.Equal(t, "sha256:2", unused[0].ID, "The needed and the newest mirrored images should be kept.")
+}
+
+func Test_protectPendingImages(t *testing.T) {
+
+	holdImages("ag1", &containermessage.DeploymentDescription{Services: map[string]*containermessage.Service{"gps": {Image: "openhorizon/gps:2.0"}}}, &config.RegistryMirrorsConfig{})
+	holdImages("ag2", &containermessage.DeploymentDescription{Services: map[string]*containermessage.Service{"cpu": {Image: "openhorizon/cpu:1.0"}}}, &config.RegistryMirrorsConfig{})
+	releaseImages("ag2")
+	defer releaseImages("ag1")
+
+	protected := make(map[string]bool)
+	protectPendingImages(protected, time.Now())
+	assert.Equal(t, map[string]bool{"openhorizon/gps:2.0": true}, protected, "Only the images that are still held should be protected.")
+
+	protected = make(map[string]bool)
+	protectPendingImages(protected, time.Now().Add(PENDING_IMAGES_TTL+time.Minute))
+	assert.Equal(t, 0, len(protected), "The images held for too long should not be protected.")
+	assert.Equal(t, 0, len(pendingImages), "The holds that are too old should be forgotten.")
+}
+
+func Test_protectPrefetchedImages(t *testing.T) {
+
+	now := time.Now()
+	prefetchedImages["openhorizon/gps:2.0"] = now
+	prefetchedImages["openhorizon/gps:3.0"] = now.Add(-2 * time.Hour)
+	prefetchedImages["openhorizon/cpu:2.0"] = now
+	defer func() { prefetchedImages = make(map[string]time.Time) }()
+
+	protected := make(map[string]bool)
+	protectPrefetchedImages(protected, map[string]bool{"openhorizon/cpu:2.0": true}, now, time.Hour)
+	assert.Equal(t, map[string]bool{"openhorizon/gps:2.0": true}, protected, "Only the recent prefetched images that are not in use should be protected.")
+	assert.Equal(t, 1, len(prefetchedImages), "The images in use and the expired images should be forgotten.")
+}
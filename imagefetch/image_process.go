@@ -38,6 +38,13 @@ func NewImageFetchWorker(name string, config *config.HorizonConfig, db *bolt.DB)
 	return worker
 }
 
+func (w *ImageFetchWorker) Initialize() bool {
+	if w.Config.Edge.ImageGC.Enabled {
+		w.DispatchSubworker(IMAGE_GC, w.collectImages, w.Config.Edge.ImageGC.IntervalS)
+	}
+	return true
+}
+
 func (w *ImageFetchWorker) Messages() chan events.Message {
 	return w.BaseWorker.Manager.Messages
 }
@@ -57,10 +64,32 @@ func (w *ImageFetchWorker) NewEvent(incoming events.Message) {
 		fCmd := w.NewFetchCommand(msg.LaunchContext())
 		w.Commands <- fCmd
 
+	case *events.ImagePrefetchMessage:
+		msg, _ := incoming.(*events.ImagePrefetchMessage)
+		switch msg.Event().Id {
+		case events.IMAGE_PREFETCH:
+			w.Commands <- w.NewPrefetchCommand(msg)
+		}
+
+	case *events.WorkloadMessage:
+		msg, _ := incoming.(*events.WorkloadMessage)
+		switch msg.Event().Id {
+		case events.EXECUTION_BEGUN, events.EXECUTION_FAILED:
+			w.Commands <- w.NewReleaseImagesCommand(msg.AgreementId)
+		}
+
+	case *events.ContainerMessage:
+		msg, _ := incoming.(*events.ContainerMessage)
+		switch msg.Event().Id {
+		case events.EXECUTION_BEGUN, events.EXECUTION_FAILED:
+			w.Commands <- w.NewReleaseImagesCommand(msg.LaunchContext.Name)
+		}
+
 	case *events.NodeShutdownCompleteMessage:
 		msg, _ := incoming.(*events.NodeShutdownCompleteMessage)
 		switch msg.Event().Id {
 		case events.UNCONFIGURE_COMPLETE:
+			w.TerminateSubworkers()
 			w.Commands <- worker.NewTerminateCommand("shutdown")
 		}
 
@@ -175,9 +204,14 @@ func processDeployment(cfg *config.HorizonConfig, containerConfig events.Contain
 	return pemFiles, &deploymentDesc, nil
 }
 
-func processFetch(cfg *config.HorizonConfig, client *docker.Client, db *bolt.DB, deploymentDesc *containermessage.DeploymentDescription, imageDockerAuths []events.ImageDockerAuth) error {
+func processFetch(cfg *config.HorizonConfig, client *docker.Client, db *bolt.DB, deploymentDesc *containermessage.DeploymentDescription, imageDockerAuths []events.ImageDockerAuth, owner string) (map[string]string, error) {
 	dockerAuthConfigurations := make(map[string][]docker.AuthConfiguration, 0)
 
+	// Refuse the images whose signatures are missing or invalid before pulling them.
+	if err := verifyImages(cfg, deploymentDesc); err != nil {
+		return nil, err
+	}
+
 	var err error
 	if cfg.Edge.TrustDockerAuthFromOrg {
 		err = authExchange(imageDockerAuths, dockerAuthConfigurations)
@@ -190,18 +224,19 @@ func processFetch(cfg *config.HorizonConfig, client *docker.Client, db *bolt.DB,
 		glog.Errorf("Failed to fetch authentication facts from the attributes before processing packages and / or Docker pulls: %v. Continuing anyway", err)
 	}
 
-	return fetchImage(cfg, client, db, deploymentDesc, dockerAuthConfigurations)
+	return fetchImage(cfg, client, db, deploymentDesc, dockerAuthConfigurations, owner)
 }
 
-func fetchImage(cfg *config.HorizonConfig, client *docker.Client, db *bolt.DB, deploymentDesc *containermessage.DeploymentDescription, dockerAuthConfigurations map[string][]docker.AuthConfiguration) error {
+// Pull the images of the deployment. Returns the registry or mirror each image was pulled from. The progress of the
+// pulls is kept for the owner, the agreement id or the service instance key.
+func fetchImage(cfg *config.HorizonConfig, client *docker.Client, db *bolt.DB, deploymentDesc *containermessage.DeploymentDescription, dockerAuthConfigurations map[string][]docker.AuthConfiguration, owner string) (map[string]string, error) {
 
 	skipCheckFn := SkipCheckFn(client)
 	// using Docker pull (newer option, uses docker client to pull images from repos in image names in deployment description)
 	// Note: we don't want to make this a fallback option, it's a potential security vector
 	glog.V(3).Infof("Using Docker pull mechanism to retrieve and load Docker images into local registry")
 
-	fetchErr := pullImageFromRepos(cfg.Edge, dockerAuthConfigurations, client, &skipCheckFn, deploymentDesc)
-	return fetchErr
+	return pullImageFromRepos(cfg.Edge, dockerAuthConfigurations, client, &skipCheckFn, deploymentDesc, owner)
 }
 
 // This function is used by external caller such as hzn command to load the container images.
@@ -237,7 +272,8 @@ func ProcessImageFetch(cfg *config.HorizonConfig, client *docker.Client, contain
 		return fmt.Errorf("Error Unmarshalling deployment string %v, error: %v", containerConfig.Deployment, err)
 	}
 
-	return fetchImage(cfg, client, nil, &deploymentDesc, dockerAuthNew)
+	_, err = fetchImage(cfg, client, nil, &deploymentDesc, dockerAuthNew, "")
+	return err
 }
 
 func (b *ImageFetchWorker) CommandHandler(command worker.Command) bool {
@@ -261,24 +297,65 @@ func (b *ImageFetchWorker) CommandHandler(command worker.Command) bool {
 			_, deploymentDesc, err := processDeployment(b.Config, lc.ContainerConfig())
 			if err != nil {
 				glog.Errorf("Failed to process deployment description and signature after agreement negotiation: %v", err)
-				b.Messages() <- events.NewImageFetchMessage(events.IMAGE_FETCH_ERROR, deploymentDesc, lc)
+				b.Messages() <- events.NewImageFetchMessage(events.IMAGE_FETCH_ERROR, deploymentDesc, lc, nil)
 				return true
 			}
 
-			if fetchErr := processFetch(b.Config, b.client, b.db, deploymentDesc, lc.ContainerConfig().ImageDockerAuths); fetchErr != nil {
-				var id events.EventId
-				if strings.Contains(fetchErr.Error(), "Auth error") {
-					id = events.IMAGE_FETCH_AUTH_ERROR
+			// the images are held until the containers are started. The pull retries with a backoff, it runs on its own
+			// goroutine so that it does not hold up the other fetches and the commands of this worker.
+			owner := imagePullOwner(lc)
+			holdImages(owner, deploymentDesc, &b.Config.Edge.RegistryMirrors)
+			go func() {
+				sources, fetchErr := processFetch(b.Config, b.client, b.db, deploymentDesc, lc.ContainerConfig().ImageDockerAuths, owner)
+
+				if fetchErr != nil {
+					releaseImages(owner)
+					var id events.EventId
+					if _, ok := fetchErr.(ImageVerificationError); ok {
+						id = events.IMAGE_SIG_VERIF_ERROR
+					} else if strings.Contains(fetchErr.Error(), "Auth error") {
+						id = events.IMAGE_FETCH_AUTH_ERROR
+					} else {
+						id = events.IMAGE_FETCH_ERROR
+					}
+					glog.Errorf("Failed to fetch image files: %v", fetchErr)
+					b.Messages() <- events.NewImageFetchMessage(id, deploymentDesc, lc, sources)
 				} else {
-					id = events.IMAGE_FETCH_ERROR
+					// a slow pull must not use up the time the images are held for the containers to start
+					holdImages(owner, deploymentDesc, &b.Config.Edge.RegistryMirrors)
+					b.Messages() <- events.NewImageFetchMessage(events.IMAGE_FETCHED, deploymentDesc, lc, sources)
 				}
-				glog.Errorf("Failed to fetch image files: %v", fetchErr)
-				b.Messages() <- events.NewImageFetchMessage(id, deploymentDesc, lc)
+			}()
+
+		}
+
+	case *PrefetchCommand:
+		cmd := command.(*PrefetchCommand)
+		msg := cmd.Msg
+
+		deploymentDesc, err := containermessage.GetNativeDeployment(msg.Config.Deployment)
+		if err != nil {
+			glog.Warningf("Ignoring prefetch of service %v/%v version %v: %v", msg.Org, msg.ServiceURL, msg.Version, err)
+			return true
+		}
+
+		recordPrefetchedImages(deploymentDesc, &b.Config.Edge.RegistryMirrors)
+		go func() {
+			sources, fetchErr := processFetch(b.Config, b.client, b.db, deploymentDesc, msg.Config.ImageDockerAuths, "")
+
+			id := events.IMAGE_PREFETCHED
+			if fetchErr != nil {
+				glog.Errorf("Failed to prefetch images of service %v/%v version %v: %v", msg.Org, msg.ServiceURL, msg.Version, fetchErr)
+				id = events.IMAGE_PREFETCH_ERROR
 			} else {
-				b.Messages() <- events.NewImageFetchMessage(events.IMAGE_FETCHED, deploymentDesc, lc)
+				glog.V(3).Infof("Prefetched images of service %v/%v version %v", msg.Org, msg.ServiceURL, msg.Version)
 			}
+			b.Messages() <- events.NewImagePrefetchMessage(id, msg.ServiceURL, msg.Org, msg.Version, msg.Arch, msg.AgreementId, events.ContainerConfig{}, sources)
+		}()
 
-		}
+	case *ReleaseImagesCommand:
+		cmd := command.(*ReleaseImagesCommand)
+		releaseImages(cmd.Owner)
 
 	default:
 		return false
@@ -287,6 +364,35 @@ func (b *ImageFetchWorker) CommandHandler(command worker.Command) bool {
 
 }
 
+type PrefetchCommand struct {
+	Msg *events.ImagePrefetchMessage
+}
+
+func (p PrefetchCommand) ShortString() string {
+	return p.Msg.ShortString()
+}
+
+func (t *ImageFetchWorker) NewPrefetchCommand(msg *events.ImagePrefetchMessage) *PrefetchCommand {
+	return &PrefetchCommand{
+		Msg: msg,
+	}
+}
+
+// Release the images held for an agreement or service instance whose containers are started or failed to start.
+type ReleaseImagesCommand struct {
+	Owner string
+}
+
+func (r ReleaseImagesCommand) ShortString() string {
+	return fmt.Sprintf("ReleaseImagesCommand Owner: %v", r.Owner)
+}
+
+func (t *ImageFetchWorker) NewReleaseImagesCommand(owner string) *ReleaseImagesCommand {
+	return &ReleaseImagesCommand{
+		Owner: owner,
+	}
+}
+
 type FetchCommand struct {
 	LaunchContext interface{}
 }
@@ -301,6 +407,17 @@ func (t *ImageFetchWorker) NewFetchCommand(launchContext interface{}) *FetchComm
 	}
 }
 
+// Returns the id the image pulls of a launch context are kept under, the agreement id or the service instance key.
+func imagePullOwner(lc events.LaunchContext) string {
+	switch lc.(type) {
+	case *events.AgreementLaunchContext:
+		return lc.(*events.AgreementLaunchContext).AgreementId
+	case *events.ContainerLaunchContext:
+		return lc.(*events.ContainerLaunchContext).Name
+	}
+	return ""
+}
+
 func (t *ImageFetchWorker) getLaunchContext(launchContext interface{}) events.LaunchContext {
 	switch launchContext.(type) {
 	case *events.ContainerLaunchContext:
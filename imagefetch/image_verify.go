@@ -0,0 +1,114 @@
+package imagefetch
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/open-horizon/anax/config"
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/rsapss-tool/verify"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Image signature verification checks that the digest of each service image was signed by one of the keys the node
+// trusts for service deployments, before the image is pulled. An image is signed by signing its repository and manifest
+// digest, see containermessage.ImageSignaturePayload, so only the images pinned by digest can be verified. The signatures
+// come from the image_signature field of the deployment and from a local signature directory, so that the images of a
+// local registry can be verified without network access.
+
+// The error returned when an image signature is missing or invalid. The deployment is refused.
+type ImageVerificationError struct {
+	msg string // description of error
+}
+
+func (e ImageVerificationError) Error() string { return e.msg }
+
+// Verify the signatures of the images of the deployment with the trusted keys of the node.
+func verifyImages(cfg *config.HorizonConfig, deploymentDesc *containermessage.DeploymentDescription) error {
+
+	keyFiles, err := cfg.Collaborators.KeyFileNamesFetcher.GetKeyFileNames(cfg.Edge.PublicKeyPath, cfg.Edge.UserPublicKeyPath)
+	if err != nil {
+		return fmt.Errorf("Unable to read pemFiles from KeyFileNamesFetcher. Error: %v", err)
+	}
+
+	return verifyImageSignatures(keyFiles, cfg.Edge.ImageVerification.SignatureDir, cfg.Edge.ImageVerification.RequireSignatures, deploymentDesc)
+}
+
+func verifyImageSignatures(keyFiles []string, signatureDir string, required bool, deploymentDesc *containermessage.DeploymentDescription) error {
+
+	for name, service := range deploymentDesc.Services {
+
+		signatures := make([]string, 0)
+		if service.ImageSignature != "" {
+			signatures = append(signatures, service.ImageSignature)
+		}
+		if stored, err := storedImageSignatures(signatureDir, service.Image); err != nil {
+			return err
+		} else {
+			signatures = append(signatures, stored...)
+		}
+
+		if len(signatures) == 0 {
+			if required {
+				return ImageVerificationError{msg: fmt.Sprintf("image %v of service %v has no signature", service.Image, name)}
+			}
+			continue
+		}
+
+		payload, err := containermessage.ImageSignaturePayload(service.Image)
+		if err != nil {
+			return ImageVerificationError{msg: fmt.Sprintf("unable to verify the signature of service %v, %v", name, err)}
+		} else if len(keyFiles) == 0 {
+			return ImageVerificationError{msg: fmt.Sprintf("unable to verify the signature of image %v of service %v, there are no trusted keys", service.Image, name)}
+		}
+
+		verified := false
+		for _, signature := range signatures {
+			if ok, keyFile, errs := verify.InputVerifiedByAnyKey(keyFiles, signature, payload); ok {
+				glog.V(3).Infof("Image %v of service %v verified with key %v", service.Image, name, keyFile)
+				verified = true
+				break
+			} else {
+				glog.V(5).Infof("Signature of image %v of service %v is not valid: %v", service.Image, name, errs)
+			}
+		}
+		if !verified {
+			return ImageVerificationError{msg: fmt.Sprintf("image %v of service %v has no signature that is valid for a trusted key", service.Image, name)}
+		}
+	}
+	return nil
+}
+
+// Returns the signatures of the image in the signature directory, from <repository>@sha256=<digest>/signature-1 until the
+// first missing number. An image that is not pinned by digest has no stored signatures.
+func storedImageSignatures(signatureDir string, image string) ([]string, error) {
+
+	signatures := make([]string, 0)
+	if signatureDir == "" {
+		return signatures, nil
+	}
+
+	repo, digest, err := containermessage.ImageDigestReference(image)
+	if err != nil {
+		return signatures, nil
+	}
+
+	dir := ImageSignatureDir(signatureDir, repo, digest)
+	for ix := 1; ; ix++ {
+		if sig, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("signature-%v", ix))); os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to read the signatures of image %v, error %v", image, err)
+		} else {
+			signatures = append(signatures, strings.TrimSpace(string(sig)))
+		}
+	}
+	return signatures, nil
+}
+
+// Returns the directory holding the stored signatures of an image digest.
+func ImageSignatureDir(signatureDir string, repo string, digest string) string {
+	return filepath.Join(signatureDir, repo+"@"+strings.Replace(digest, ":", "=", 1))
+}
@@ -0,0 +1,81 @@
+// +build unit
+
+package imagefetch
+
+import (
+	"github.com/open-horizon/anax/containermessage"
+	"github.com/open-horizon/rsapss-tool/generatekeys"
+	"github.com/open-horizon/rsapss-tool/sign"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_verifyImageSignatures(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "image-verify-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	trusted, err := generatekeys.Write(dir, 2048, "trusted", "myorg", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := generatekeys.Write(dir, 2048, "other", "otherorg", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyFiles := []string{trusted[1]}
+
+	image := "myregistry.com:5000/gps@sha256:0123456789abcdef"
+	signImage := func(keyFile string, image string) string {
+		payload, err := containermessage.ImageSignaturePayload(image)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := sign.Input(keyFile, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+	deployment := func(image string, signature string) *containermessage.DeploymentDescription {
+		return &containermessage.DeploymentDescription{Services: map[string]*containermessage.Service{"gps": {Image: image, ImageSignature: signature}}}
+	}
+
+	assert.Nil(t, verifyImageSignatures(keyFiles, "", true, deployment(image, signImage(trusted[0], image))), "The signature of a trusted key should be valid.")
+	assert.Nil(t, verifyImageSignatures(keyFiles, "", false, deployment("openhorizon/gps:1.0", "")), "An unsigned image is pulled when unsigned images are allowed.")
+
+	refused := []*containermessage.DeploymentDescription{
+		deployment(image, signImage(other[0], image)),
+		deployment(image, signImage(trusted[0], "myregistry.com:5000/cpu@sha256:0123456789abcdef")),
+		deployment("openhorizon/gps:1.0", signImage(trusted[0], image)),
+	}
+	for _, dd := range refused {
+		err := verifyImageSignatures(keyFiles, "", false, dd)
+		_, ok := err.(ImageVerificationError)
+		assert.True(t, ok, "The image %v should be refused, got %v", dd.Services["gps"].Image, err)
+	}
+
+	err = verifyImageSignatures(keyFiles, "", true, deployment(image, ""))
+	_, ok := err.(ImageVerificationError)
+	assert.True(t, ok, "An unsigned image should be refused when signatures are required, got %v", err)
+
+	// the signatures stored locally, after one that is not valid
+	sigDir := filepath.Join(dir, "signatures")
+	stored := ImageSignatureDir(sigDir, "myregistry.com:5000/gps", "sha256:0123456789abcdef")
+	assert.Equal(t, filepath.Join(sigDir, "myregistry.com:5000/gps@sha256=0123456789abcdef"), stored, "The signatures should be stored under the repository and digest.")
+	if err := os.MkdirAll(stored, 0755); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(stored, "signature-1"), []byte(signImage(other[0], image)), 0644)
+	ioutil.WriteFile(filepath.Join(stored, "signature-2"), []byte(signImage(trusted[0], image)+"\n"), 0644)
+
+	assert.Nil(t, verifyImageSignatures(keyFiles, sigDir, true, deployment(image, "")), "A stored signature of a trusted key should be valid.")
+	assert.NotNil(t, verifyImageSignatures(nil, sigDir, true, deployment(image, "")), "An image cannot be verified without trusted keys.")
+}
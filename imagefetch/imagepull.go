@@ -9,15 +9,13 @@ import (
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/containermessage"
 	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/metrics"
 	"os"
 	"time"
 )
 
-const (
-	pullAttemptDelayS = 15
-
-	maxPullAttempts = 3
-)
+// The time taken to pull each image, including retries, by whether the pull succeeded or failed.
+var imagePullDuration = metrics.NewHistogram("anax_image_pull_duration_seconds", "The time taken to pull a container image.", metrics.LongBuckets, "result")
 
 // read the given docker file and get the auths
 func dockerCredsFromConfigFile(configFilePath string) (*docker.AuthConfigurations, error) {
@@ -65,127 +63,248 @@ func authDockerFile(config config.Config, authConfigs map[string][]docker.AuthCo
 	return nil
 }
 
-func pullImageFromRepos(config config.Config, authConfigs map[string][]docker.AuthConfiguration, client *docker.Client, skipPartFetchFn *func(repotag string) (bool, error), deploymentDesc *containermessage.DeploymentDescription) error {
+// Pull the images of the deployment, from the registry mirrors first. Returns the registry that served each image.
+// The progress of the pulls is kept for the owner, the agreement id or the service instance key, when there is one.
+func pullImageFromRepos(config config.Config, authConfigs map[string][]docker.AuthConfiguration, client *docker.Client, skipPartFetchFn *func(repotag string) (bool, error), deploymentDesc *containermessage.DeploymentDescription, owner string) (map[string]string, error) {
 
 	// append docker auth from docker file
 	authDockerFile(config, authConfigs)
 
+	sources := make(map[string]string)
+	if owner != "" {
+		resetImagePullProgress(owner)
+	}
+
 	// TODO: can we fetch in parallel with the docker client? If so, lift pattern from https://github.com/open-horizon/horizon-pkg-fetch/blob/master/fetch.go#L350
 	for name, service := range deploymentDesc.Services {
 
 		glog.V(3).Infof("Pulling image %v for service %v", service.Image, name)
 
-		var opts docker.PullImageOptions
-
-		domain, path, tag, digest := cutil.ParseDockerImagePath(service.Image)
+		_, path, _, _ := cutil.ParseDockerImagePath(service.Image)
 		if path == "" {
 			glog.Errorf("Invalid image name format specified: %v", service.Image)
-			return fmt.Errorf("Invalid image name format specified: %v", service.Image)
+			return sources, fmt.Errorf("Invalid image name format specified: %v", service.Image)
 		}
 
-		// the image name format is [[repo][:port]/][somedir/]image[:tag][@digest].
-		// tag and digest do not contain '/'
-		if digest != "" {
-			// this is the case where image repo digest is used, just put whole name there
-			opts = docker.PullImageOptions{
-				Repository: service.Image,
-			}
+		pullStart := time.Now()
+		tracker := newImagePullTracker(owner, service.Image)
+		source, err := pullImage(config.ImagePull, client, imageSources(config, authConfigs, service.Image), name, service.Image, tracker)
+		tracker.done(err)
+		if err != nil {
+			imagePullDuration.ObserveSince(pullStart, "failure")
+			glog.Errorf("Docker image pull(s) failed for docker image %v. Error: %v.", service.Image, err)
+			return sources, err
 		} else {
-			// this is case where image name:tag is used. The image repo may contain :, image tag itself cannot contain : or /.
-			// These are valid formats:
-			//  repo/a/b:tag
-			//  repo:port/a/b:tag
-			//  repo:port/a/b
-
-			var repo string
-			if domain == "" {
-				repo = path
-			} else {
-				repo = fmt.Sprintf("%v/%v", domain, path)
-			}
+			imagePullDuration.ObserveSince(pullStart, "success")
+			sources[service.Image] = source
+			glog.V(3).Infof("Succeeded fetching image %v for service %v from %v", service.Image, name, source)
+		}
+	}
 
-			if tag == "" {
-				tag = "latest"
-			}
+	return sources, nil
+}
 
-			// TODO: check the on-disk image to make sure it still verifies
-			// N.B. It's possible to specify an outputstream here which means we could fetch a docker image and hash it, check the sig like we used to
-			opts = docker.PullImageOptions{
-				Repository: repo,
-				Tag:        tag,
+// Pull an image from the first source that has it. When every source fails and one of them failed with a transient
+// error, the sources are tried again after a backoff, until the attempts are used up. A source that failed with an
+// error that is not transient is not tried again. Returns the registry that served the image.
+func pullImage(retry config.ImagePullConfig, client *docker.Client, sources []imageSource, serviceName string, image string, tracker *imagePullTracker) (string, error) {
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		remaining := make([]imageSource, 0, len(sources))
+		for _, src := range sources {
+			tracker.attempt(src.registry, attempt)
+			if err = pullImageFromSource(client, src, serviceName, tracker); err == nil && src.mirror {
+				err = tagMirroredImage(client, src.image, image)
+			}
+			if err == nil {
+				return src.registry, nil
+			}
+			glog.Warningf("Docker image pull failed for docker image %v from %v, attempt %v. Error: %v.", image, src.registry, attempt, err)
+			if transientPullError(err) {
+				remaining = append(remaining, src)
 			}
 		}
 
-		var err error
-		if domain == "" {
-			err = pullSingleImageFromRepo(client, opts, docker.AuthConfiguration{})
-		} else if auth_array, ok := authConfigs[domain]; !ok {
-			err = pullSingleImageFromRepo(client, opts, docker.AuthConfiguration{})
-		} else {
-			for i, auth := range auth_array {
-				err = pullSingleImageFromRepo(client, opts, auth)
-				if err == nil {
-					break
-				} else if i < len(auth_array)-1 {
-					glog.V(5).Infof("Docker image pull(s) failed for service %v docker image %v with auth name %v. Error: %v. Try next auth.", name, service.Image, auth.Username, err)
-				}
-			}
+		sources = remaining
+		if len(sources) == 0 {
+			return "", err
+		} else if attempt >= retry.Attempts() {
+			return "", fmt.Errorf("Max pull attempts reached (%d) for fetching Docker image %v. Error: %v", attempt, image, err)
 		}
-		if err != nil {
-			glog.Errorf("Docker image pull(s) failed for docker image %v. Error: %v.", service.Image, err)
-			return err
-		} else {
-			glog.V(3).Infof("Succeeded fetching image %v for service %v", service.Image, name)
+
+		backoff := retry.Backoff(attempt)
+		tracker.retrying(err, time.Now().Add(backoff))
+		glog.V(3).Infof("Waiting %v before pulling docker image %v again. Error: %v", backoff, image, err)
+		time.Sleep(backoff)
+	}
+}
+
+// Returns true if a pull error might not happen again, such as a network error or an error of the registry service.
+// An auth error, or an image or tag that does not exist, is not transient.
+func transientPullError(err error) bool {
+	if strings.HasPrefix(err.Error(), "Auth error") {
+		return false
+	}
+
+	msg := err.Error()
+	if dErr, ok := err.(*docker.Error); ok {
+		if dErr.Status == 404 {
+			return false
 		}
+		msg = dErr.Message
 	}
+	msg = strings.ToLower(msg)
+	for _, permanent := range []string{"manifest unknown", "not found", "pull access denied", "unauthorized", "invalid reference format", "no matching manifest"} {
+		if strings.Contains(msg, permanent) {
+			return false
+		}
+	}
+	return true
+}
 
-	return nil
+// A registry an image can be pulled from, with the name of the image there and the auths to try.
+type imageSource struct {
+	registry string
+	image    string
+	auths    []docker.AuthConfiguration
+	mirror   bool
 }
 
-//  This function try maxPullAttempts times to pull the image from the repo. It exits out imediately if there is auth error.
-func pullSingleImageFromRepo(client *docker.Client, opts docker.PullImageOptions, auth docker.AuthConfiguration) error {
-	glog.V(5).Infof("Pulling image %v with auth name %v.", opts, auth.Username)
+// Returns the registries to pull an image from: the mirrors of its registry in order, then its own registry unless the
+// fallback to it is disabled. A mirror uses its own credentials, or the docker auths of the mirror host.
+func imageSources(cfg config.Config, authConfigs map[string][]docker.AuthConfiguration, image string) []imageSource {
 
-	var pullAttempts int
+	registryAuths := func(registry string) []docker.AuthConfiguration {
+		if auth_array, ok := authConfigs[registry]; ok && len(auth_array) != 0 {
+			return auth_array
+		}
+		return []docker.AuthConfiguration{docker.AuthConfiguration{}}
+	}
+
+	domain, _, _, _ := cutil.ParseDockerImagePath(image)
 
-	for pullAttempts <= maxPullAttempts {
-		if err := client.PullImage(opts, auth); err == nil {
-			return nil
+	sources := make([]imageSource, 0)
+	mirrors := cfg.RegistryMirrors.MirrorsOf(domain)
+	for ix, mirrored := range cutil.MirrorImagePaths(&cfg.RegistryMirrors, image) {
+		m := mirrors[ix]
+		auths := registryAuths(m.Mirror)
+		if m.UserName != "" || m.Password != "" {
+			auths = []docker.AuthConfiguration{docker.AuthConfiguration{Username: m.UserName, Password: m.Password, ServerAddress: m.Mirror}}
+		}
+		sources = append(sources, imageSource{registry: m.Mirror, image: mirrored, auths: auths, mirror: true})
+	}
+
+	if len(sources) == 0 || !cfg.RegistryMirrors.DisableOriginFallback {
+		if domain == "" {
+			sources = append(sources, imageSource{registry: config.DOCKER_HUB_REGISTRY, image: image, auths: []docker.AuthConfiguration{docker.AuthConfiguration{}}})
 		} else {
-			pullAttempts++
-
-			// no need to try more times if it is auth error
-			switch err.(type) {
-			case *docker.Error:
-				dErr := err.(*docker.Error)
-				if strings.Contains(dErr.Message, "cred") {
-					msg := fmt.Sprintf("Aborting fetch of Docker image %v.", opts.Repository)
-					return fmt.Errorf("Auth error. Msg: %v, InternalError: %v.", msg, dErr)
-				}
-			}
+			sources = append(sources, imageSource{registry: domain, image: image, auths: registryAuths(domain)})
+		}
+	}
+	return sources
+}
+
+// Pull an image from a registry, with each of the auths until one works.
+func pullImageFromSource(client *docker.Client, src imageSource, serviceName string, tracker *imagePullTracker) error {
+
+	opts := pullImageOptions(src.image)
+	opts.OutputStream = tracker
+	opts.RawJSONStream = true
 
-			if pullAttempts != maxPullAttempts {
-				glog.V(5).Infof("Waiting %d seconds before retry. Error: %v", pullAttemptDelayS, err)
-				time.Sleep(pullAttemptDelayS * time.Second)
-			} else {
-				msg := fmt.Sprintf("Max pull attempts reached (%d) for fetching Docker image %v.", pullAttempts, opts.Repository)
+	var err error
+	for i, auth := range src.auths {
+		err = pullSingleImageFromRepo(client, opts, auth, tracker)
+		if err == nil {
+			break
+		} else if i < len(src.auths)-1 {
+			glog.V(5).Infof("Docker image pull(s) failed for service %v docker image %v with auth name %v. Error: %v. Try next auth.", serviceName, src.image, auth.Username, err)
+		}
+	}
+	return err
+}
 
-				switch err.(type) {
-				case *docker.Error:
-					glog.V(5).Infof(msg+"Docker client error occurred %v", err)
-					return err
+// Returns the docker pull options of an image.
+func pullImageOptions(image string) docker.PullImageOptions {
 
-				default:
-					glog.V(5).Infof(msg+"(Unknown error type, %T) Internal error of unidentifiable type: %v. Original: %v", err, msg, err)
-					return err
+	domain, path, tag, digest := cutil.ParseDockerImagePath(image)
 
-				}
-			}
+	// the image name format is [[repo][:port]/][somedir/]image[:tag][@digest].
+	// tag and digest do not contain '/'
+	if digest != "" {
+		// this is the case where image repo digest is used, just put whole name there
+		return docker.PullImageOptions{
+			Repository: image,
 		}
 	}
+
+	// this is case where image name:tag is used. The image repo may contain :, image tag itself cannot contain : or /.
+	// These are valid formats:
+	//  repo/a/b:tag
+	//  repo:port/a/b:tag
+	//  repo:port/a/b
+
+	var repo string
+	if domain == "" {
+		repo = path
+	} else {
+		repo = fmt.Sprintf("%v/%v", domain, path)
+	}
+
+	if tag == "" {
+		tag = "latest"
+	}
+
+	// TODO: check the on-disk image to make sure it still verifies
+	// N.B. It's possible to specify an outputstream here which means we could fetch a docker image and hash it, check the sig like we used to
+	return docker.PullImageOptions{
+		Repository: repo,
+		Tag:        tag,
+	}
+}
+
+// Give an image pulled from a mirror its original name, so that the containers are created from the image in the
+// deployment, and remove the mirror's name. An image pinned by digest cannot be given another digest, it keeps the
+// name of the mirror and the container worker finds it there.
+func tagMirroredImage(client *docker.Client, mirrored string, image string) error {
+
+	domain, path, tag, digest := cutil.ParseDockerImagePath(image)
+	if digest != "" {
+		return nil
+	}
+	if tag == "" {
+		tag = "latest"
+		mirrored = mirrored + ":" + tag
+	}
+
+	repo := path
+	if domain != "" {
+		repo = domain + "/" + path
+	}
+	if err := client.TagImage(mirrored, docker.TagImageOptions{Repo: repo, Tag: tag, Force: true}); err != nil {
+		return fmt.Errorf("unable to tag image %v as %v:%v, error %v", mirrored, repo, tag, err)
+	} else if err := client.RemoveImage(mirrored); err != nil {
+		glog.Warningf("Unable to remove the mirror tag %v of image %v, error %v", mirrored, image, err)
+	}
 	return nil
 }
 
+// Pull the image from the repo once. The errors reported in the pull output are returned too.
+func pullSingleImageFromRepo(client *docker.Client, opts docker.PullImageOptions, auth docker.AuthConfiguration, tracker *imagePullTracker) error {
+	glog.V(5).Infof("Pulling image %v:%v with auth name %v.", opts.Repository, opts.Tag, auth.Username)
+
+	err := client.PullImage(opts, auth)
+	if err == nil {
+		err = tracker.err()
+	}
+
+	if dErr, ok := err.(*docker.Error); ok && strings.Contains(dErr.Message, "cred") {
+		msg := fmt.Sprintf("Aborting fetch of Docker image %v.", opts.Repository)
+		return fmt.Errorf("Auth error. Msg: %v, InternalError: %v.", msg, dErr)
+	}
+	return err
+}
+
 func listImages(client *docker.Client) ([]docker.APIImages, error) {
 
 	if images, err := client.ListImages(docker.ListImagesOptions{
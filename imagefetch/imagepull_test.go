@@ -61,3 +61,36 @@ func Test_authDockerFile(t *testing.T) {
 	assert.Equal(t, 1, len(dockerAuthConfigurations["myrepo3.com"]), "The docker auth array should have 1 items.")
 
 }
+
+func Test_imageSources(t *testing.T) {
+
+	var cfg config.Config
+	cfg.RegistryMirrors.Mirrors = []config.RegistryMirror{
+		{Mirror: "mirror.local:5000", Registries: []string{"myrepo1.com"}},
+		{Mirror: "cache.local", UserName: "edge", Password: "secret"},
+	}
+	authConfigs := map[string][]docker.AuthConfiguration{
+		"myrepo1.com":       []docker.AuthConfiguration{{Username: "token", Password: "t11", ServerAddress: "myrepo1.com"}},
+		"mirror.local:5000": []docker.AuthConfiguration{{Username: "token", Password: "m1", ServerAddress: "mirror.local:5000"}},
+	}
+
+	sources := imageSources(cfg, authConfigs, "myrepo1.com/gps:1.0")
+	assert.Equal(t, 3, len(sources), "The image should be pulled from both mirrors, then from its registry.")
+	assert.Equal(t, "mirror.local:5000/gps:1.0", sources[0].image, "The first mirror should be tried first.")
+	assert.Equal(t, "m1", sources[0].auths[0].Password, "The docker auth of the mirror host should be used.")
+	assert.Equal(t, "cache.local/gps:1.0", sources[1].image, "The second mirror serves all registries.")
+	assert.Equal(t, "secret", sources[1].auths[0].Password, "The credentials of the mirror should be used.")
+	assert.Equal(t, "myrepo1.com/gps:1.0", sources[2].image, "The registry of the image should be the fallback.")
+	assert.False(t, sources[2].mirror, "The registry of the image is not a mirror.")
+	assert.Equal(t, "t11", sources[2].auths[0].Password, "The docker auth of the registry should be used.")
+
+	cfg.RegistryMirrors.DisableOriginFallback = true
+	sources = imageSources(cfg, authConfigs, "openhorizon/gps:1.0")
+	assert.Equal(t, 1, len(sources), "Without the fallback, only the mirror should be tried.")
+	assert.Equal(t, "cache.local/openhorizon/gps:1.0", sources[0].image, "The docker hub image should be on the mirror.")
+
+	cfg.RegistryMirrors.Mirrors = cfg.RegistryMirrors.Mirrors[:1]
+	sources = imageSources(cfg, authConfigs, "openhorizon/gps:1.0")
+	assert.Equal(t, 1, len(sources), "An image without a mirror should be pulled from its registry.")
+	assert.Equal(t, config.DOCKER_HUB_REGISTRY, sources[0].registry, "The image should be pulled from docker hub.")
+}
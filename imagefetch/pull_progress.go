@@ -0,0 +1,233 @@
+package imagefetch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The states of an image pull.
+const (
+	IMAGE_PULL_PULLING  = "pulling"
+	IMAGE_PULL_RETRYING = "retrying"
+	IMAGE_PULL_PULLED   = "pulled"
+	IMAGE_PULL_FAILED   = "failed"
+)
+
+// How long the progress of a finished image pull is kept.
+const pullProgressRetentionS = 3600
+
+// The progress of an image pull, from the progress docker reports for each layer of the image. A retried pull only
+// downloads the layers that are not complete yet.
+type ImagePullProgress struct {
+	Image         string                        `json:"image"`
+	Source        string                        `json:"source,omitempty"` // the registry or mirror of the current attempt
+	Status        string                        `json:"status"`
+	Attempt       int                           `json:"attempt"`
+	CurrentBytes  int64                         `json:"current_bytes"` // the bytes downloaded, of the layers whose size is known
+	TotalBytes    int64                         `json:"total_bytes"`
+	Layers        map[string]*LayerPullProgress `json:"layers,omitempty"`
+	StartTime     uint64                        `json:"start_time"`
+	EndTime       uint64                        `json:"end_time,omitempty"`
+	NextRetryTime uint64                        `json:"next_retry_time,omitempty"`
+	Error         string                        `json:"error,omitempty"` // the error of the last failed attempt
+}
+
+type LayerPullProgress struct {
+	Status  string `json:"status"`
+	Current int64  `json:"current"`
+	Total   int64  `json:"total"`
+}
+
+// Returns the percentage of the image that is downloaded, 0 until the size of a layer is known.
+func (p *ImagePullProgress) Percent() int {
+	if p.Status == IMAGE_PULL_PULLED {
+		return 100
+	} else if p.TotalBytes == 0 {
+		return 0
+	}
+	return int(p.CurrentBytes * 100 / p.TotalBytes)
+}
+
+// The image pulls of each agreement and service instance, by image. The agreement id or the service instance key
+// identifies the pulls.
+var pullProgressLock sync.Mutex
+var pullProgress = make(map[string]map[string]*ImagePullProgress)
+
+// Returns the progress of the image pulls of an agreement or service instance, sorted by image.
+func GetImagePullProgress(owner string) []ImagePullProgress {
+	pullProgressLock.Lock()
+	defer pullProgressLock.Unlock()
+
+	progress := make([]ImagePullProgress, 0)
+	for _, p := range pullProgress[owner] {
+		c := *p
+		c.Layers = make(map[string]*LayerPullProgress, len(p.Layers))
+		for id, l := range p.Layers {
+			lc := *l
+			c.Layers[id] = &lc
+		}
+		progress = append(progress, c)
+	}
+	sort.Slice(progress, func(i, j int) bool { return progress[i].Image < progress[j].Image })
+	return progress
+}
+
+// Forget the previous image pulls of an agreement or service instance, and the pulls that finished a while ago.
+func resetImagePullProgress(owner string) {
+	pullProgressLock.Lock()
+	defer pullProgressLock.Unlock()
+
+	delete(pullProgress, owner)
+
+	cutoff := uint64(time.Now().Unix()) - pullProgressRetentionS
+	for o, pulls := range pullProgress {
+		finished := true
+		for _, p := range pulls {
+			if p.EndTime == 0 || p.EndTime > cutoff {
+				finished = false
+				break
+			}
+		}
+		if finished {
+			delete(pullProgress, o)
+		}
+	}
+}
+
+// Records the progress of the pull of an image. It is the output stream of the docker pull, which writes a JSON
+// message for each change of a layer. The pulls without an owner are not recorded, only their errors are kept.
+type imagePullTracker struct {
+	progress  *ImagePullProgress
+	buffer    bytes.Buffer
+	streamErr error
+}
+
+// A message of the docker pull output.
+type pullMessage struct {
+	Status         string `json:"status"`
+	ID             string `json:"id"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error string `json:"error"`
+}
+
+func newImagePullTracker(owner string, image string) *imagePullTracker {
+	t := &imagePullTracker{
+		progress: &ImagePullProgress{
+			Image:     image,
+			Status:    IMAGE_PULL_PULLING,
+			Layers:    make(map[string]*LayerPullProgress),
+			StartTime: uint64(time.Now().Unix()),
+		},
+	}
+	if owner != "" {
+		pullProgressLock.Lock()
+		if _, ok := pullProgress[owner]; !ok {
+			pullProgress[owner] = make(map[string]*ImagePullProgress)
+		}
+		pullProgress[owner][image] = t.progress
+		pullProgressLock.Unlock()
+	}
+	return t
+}
+
+// An attempt to pull the image from a registry or mirror.
+func (t *imagePullTracker) attempt(source string, attempt int) {
+	pullProgressLock.Lock()
+	defer pullProgressLock.Unlock()
+	t.progress.Source = source
+	t.progress.Attempt = attempt
+	t.progress.Status = IMAGE_PULL_PULLING
+	t.progress.NextRetryTime = 0
+	t.buffer.Reset()
+	t.streamErr = nil
+}
+
+// The pull failed with a transient error and is tried again at the given time.
+func (t *imagePullTracker) retrying(err error, next time.Time) {
+	pullProgressLock.Lock()
+	defer pullProgressLock.Unlock()
+	t.progress.Status = IMAGE_PULL_RETRYING
+	t.progress.Error = err.Error()
+	t.progress.NextRetryTime = uint64(next.Unix())
+}
+
+// The pull is over, it failed when there is an error.
+func (t *imagePullTracker) done(err error) {
+	pullProgressLock.Lock()
+	defer pullProgressLock.Unlock()
+	t.progress.EndTime = uint64(time.Now().Unix())
+	if err != nil {
+		t.progress.Status = IMAGE_PULL_FAILED
+		t.progress.Error = err.Error()
+	} else {
+		t.progress.Status = IMAGE_PULL_PULLED
+		t.progress.Error = ""
+	}
+}
+
+// Returns the error reported in the pull output. The docker client does not return it when the output is raw JSON.
+func (t *imagePullTracker) err() error {
+	pullProgressLock.Lock()
+	defer pullProgressLock.Unlock()
+	return t.streamErr
+}
+
+func (t *imagePullTracker) Write(p []byte) (int, error) {
+	pullProgressLock.Lock()
+	defer pullProgressLock.Unlock()
+
+	t.buffer.Write(p)
+	for {
+		line, err := t.buffer.ReadBytes('\n')
+		if err != nil {
+			// keep the partial message until the rest of it is written
+			t.buffer.Reset()
+			t.buffer.Write(line)
+			break
+		}
+		t.update(bytes.TrimSpace(line))
+	}
+	return len(p), nil
+}
+
+// Update the progress with a message of the pull output, the caller holds pullProgressLock.
+func (t *imagePullTracker) update(line []byte) {
+	var msg pullMessage
+	if len(line) == 0 || json.Unmarshal(line, &msg) != nil {
+		return
+	} else if msg.Error != "" {
+		t.streamErr = errors.New(msg.Error)
+		return
+	} else if msg.ID == "" || strings.HasPrefix(msg.Status, "Pulling from") {
+		// the messages about the image as a whole, the id of "Pulling from" is the tag
+		return
+	}
+
+	layer, ok := t.progress.Layers[msg.ID]
+	if !ok {
+		layer = &LayerPullProgress{}
+		t.progress.Layers[msg.ID] = layer
+	}
+	layer.Status = msg.Status
+
+	switch msg.Status {
+	case "Downloading":
+		layer.Current, layer.Total = msg.ProgressDetail.Current, msg.ProgressDetail.Total
+	case "Download complete", "Verifying Checksum", "Extracting", "Pull complete", "Already exists":
+		layer.Current = layer.Total
+	}
+
+	t.progress.CurrentBytes, t.progress.TotalBytes = 0, 0
+	for _, l := range t.progress.Layers {
+		t.progress.CurrentBytes += l.Current
+		t.progress.TotalBytes += l.Total
+	}
+}
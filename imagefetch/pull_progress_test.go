@@ -0,0 +1,88 @@
+// +build unit
+
+package imagefetch
+
+import (
+	"errors"
+	docker "github.com/fsouza/go-dockerclient"
+	"testing"
+	"time"
+)
+
+// the progress of each layer is read from the JSON messages of the pull output, which can be split across writes
+func Test_imagePullTracker(t *testing.T) {
+
+	owner := "agreement1"
+	resetImagePullProgress(owner)
+	tracker := newImagePullTracker(owner, "openhorizon/gps:1.0.0")
+	tracker.attempt("docker.io", 1)
+
+	output := `{"status":"Pulling from openhorizon/gps","id":"1.0.0"}
+{"status":"Pulling fs layer","progressDetail":{},"id":"a1"}
+{"status":"Already exists","progressDetail":{},"id":"b2"}
+{"status":"Downloading","progressDetail":{"current":250,"total":1000},"id":"a1"}
+{"status":"Downloading","progressDetail":{"current":100,"total":400},"id":"c3"}
+`
+	tracker.Write([]byte(output[:70]))
+	tracker.Write([]byte(output[70:]))
+
+	progress := GetImagePullProgress(owner)
+	if len(progress) != 1 {
+		t.Fatalf("expected the progress of 1 image, but got %v", progress)
+	} else if p := progress[0]; len(p.Layers) != 3 || p.CurrentBytes != 350 || p.TotalBytes != 1400 || p.Percent() != 25 {
+		t.Errorf("expected 3 layers with 350 of 1400 bytes, but got %v layers with %v of %v bytes", len(p.Layers), p.CurrentBytes, p.TotalBytes)
+	} else if p.Source != "docker.io" || p.Status != IMAGE_PULL_PULLING {
+		t.Errorf("expected a pull from docker.io in progress, but got %v", p)
+	}
+
+	tracker.Write([]byte(`{"status":"Download complete","progressDetail":{},"id":"c3"}` + "\n" + `{"errorDetail":{"message":"net/http: TLS handshake timeout"},"error":"net/http: TLS handshake timeout"}` + "\n"))
+	if err := tracker.err(); err == nil || err.Error() != "net/http: TLS handshake timeout" {
+		t.Errorf("expected the error of the pull output, but got %v", err)
+	} else if p := GetImagePullProgress(owner)[0]; p.Layers["c3"].Current != 400 || p.CurrentBytes != 650 {
+		t.Errorf("expected layer c3 to be complete, but got %v of %v bytes", p.Layers["c3"].Current, p.Layers["c3"].Total)
+	}
+
+	tracker.retrying(tracker.err(), time.Now().Add(time.Minute))
+	if p := GetImagePullProgress(owner)[0]; p.Status != IMAGE_PULL_RETRYING || p.NextRetryTime == 0 || p.Error == "" {
+		t.Errorf("expected the pull to be waiting for a retry, but got %v", p)
+	}
+
+	tracker.attempt("docker.io", 2)
+	if tracker.err() != nil {
+		t.Errorf("the error of the previous attempt should be cleared, but got %v", tracker.err())
+	}
+	tracker.done(nil)
+	if p := GetImagePullProgress(owner)[0]; p.Status != IMAGE_PULL_PULLED || p.Percent() != 100 || p.EndTime == 0 || p.Error != "" {
+		t.Errorf("expected the pull to be complete, but got %v", p)
+	}
+
+	resetImagePullProgress(owner)
+	if progress := GetImagePullProgress(owner); len(progress) != 0 {
+		t.Errorf("expected no progress after a reset, but got %v", progress)
+	}
+}
+
+func Test_transientPullError(t *testing.T) {
+
+	transient := []error{
+		errors.New("net/http: TLS handshake timeout"),
+		&docker.Error{Status: 500, Message: "received unexpected HTTP status: 503 Service Unavailable"},
+	}
+	for _, err := range transient {
+		if !transientPullError(err) {
+			t.Errorf("expected %v to be transient", err)
+		}
+	}
+
+	permanent := []error{
+		errors.New("Auth error. The registry refused the credentials"),
+		&docker.Error{Status: 404, Message: "no such image"},
+		errors.New("manifest for openhorizon/gps:9.9.9 not found: manifest unknown"),
+		errors.New("pull access denied for openhorizon/private, repository does not exist"),
+	}
+	for _, err := range permanent {
+		if transientPullError(err) {
+			t.Errorf("expected %v not to be transient", err)
+		}
+	}
+}
@@ -13,7 +13,9 @@ import (
 	"github.com/open-horizon/anax/api"
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/container"
+	"github.com/open-horizon/anax/eventforward"
 	"github.com/open-horizon/anax/exchange"
+	_ "github.com/open-horizon/anax/externalpolicy/json_language"
 	_ "github.com/open-horizon/anax/externalpolicy/text_language"
 	"github.com/open-horizon/anax/governance"
 	"github.com/open-horizon/anax/helm"
@@ -157,6 +159,9 @@ func main() {
 		workers.Add(imagefetch.NewImageFetchWorker("ImageFetch", cfg, db))
 		workers.Add(helm.NewHelmWorker("Helm", cfg, db))
 		workers.Add(resource.NewResourceWorker("Resource", cfg, db, authm))
+		if cfg.EventLogForwardingEnabled() {
+			workers.Add(eventforward.NewEventForwardWorker("EventLogForwarder", cfg, db))
+		}
 	}
 
 	// Get into the event processing loop until anax shuts itself down.
@@ -0,0 +1,369 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/golang/glog"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This package keeps the operational metrics of the anax process (either a node agent or an agbot) and renders them
+// in the Prometheus text exposition format. The metrics are owned by the packages that update them, they are created
+// once as package level variables and registered in a process wide registry. Metrics that are derived from the state
+// in the database are computed by collectors that run each time the metrics are scraped.
+
+const (
+	METRIC_COUNTER   = "counter"
+	METRIC_GAUGE     = "gauge"
+	METRIC_HISTOGRAM = "histogram"
+)
+
+// The content type of the Prometheus text exposition format.
+const TEXT_CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+// Histogram buckets (in seconds) suitable for timing remote calls.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Histogram buckets (in seconds) suitable for timing long running operations, like pulling an image.
+var LongBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800}
+
+// A single time series within a metric family.
+type sample struct {
+	labelValues []string
+	value       float64  // counters and gauges
+	buckets     []uint64 // histograms, the number of observations in each bucket (not cumulative)
+	sum         float64  // histograms
+	count       uint64   // histograms
+}
+
+// A metric family is a named metric and all of its time series, one per combination of label values.
+type family struct {
+	name       string
+	help       string
+	mtype      string
+	labelNames []string
+	buckets    []float64
+	lock       sync.Mutex
+	samples    map[string]*sample
+}
+
+func (f *family) String() string {
+	return fmt.Sprintf("Name: %v, Type: %v, Labels: %v", f.name, f.mtype, f.labelNames)
+}
+
+// Find or create the time series for the given label values. The caller must hold the family lock.
+func (f *family) sample(labelValues []string) *sample {
+	if len(labelValues) != len(f.labelNames) {
+		glog.Errorf(metricsLogString(fmt.Sprintf("metric %v expects labels %v, ignoring update with values %v", f.name, f.labelNames, labelValues)))
+		return nil
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.samples[key]
+	if !ok {
+		s = &sample{labelValues: append([]string{}, labelValues...)}
+		if f.mtype == METRIC_HISTOGRAM {
+			s.buckets = make([]uint64, len(f.buckets))
+		}
+		f.samples[key] = s
+	}
+	return s
+}
+
+// A counter only goes up, e.g. the number of requests made.
+type Counter struct {
+	f *family
+}
+
+// Add one to the counter with the given label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add a non-negative amount to the counter with the given label values.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		glog.Errorf(metricsLogString(fmt.Sprintf("counter %v cannot be decreased by %v", c.f.name, v)))
+		return
+	}
+	c.f.lock.Lock()
+	defer c.f.lock.Unlock()
+	if s := c.f.sample(labelValues); s != nil {
+		s.value += v
+	}
+}
+
+// A gauge is a value that can go up and down, e.g. the number of active agreements.
+type Gauge struct {
+	f *family
+}
+
+// Set the gauge with the given label values.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.lock.Lock()
+	defer g.f.lock.Unlock()
+	if s := g.f.sample(labelValues); s != nil {
+		s.value = v
+	}
+}
+
+// Remove the time series with the given label values.
+func (g *Gauge) Delete(labelValues ...string) {
+	g.f.lock.Lock()
+	defer g.f.lock.Unlock()
+	delete(g.f.samples, strings.Join(labelValues, "\xff"))
+}
+
+// Remove all the time series of the gauge. Collectors call this before setting the current values so that
+// label combinations which no longer exist disappear from the output.
+func (g *Gauge) Reset() {
+	g.f.lock.Lock()
+	defer g.f.lock.Unlock()
+	g.f.samples = make(map[string]*sample)
+}
+
+// A histogram counts observations in buckets, e.g. the latency of requests.
+type Histogram struct {
+	f *family
+}
+
+// Record an observation with the given label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.lock.Lock()
+	defer h.f.lock.Unlock()
+	if s := h.f.sample(labelValues); s != nil {
+		for i, upper := range h.f.buckets {
+			if v <= upper {
+				s.buckets[i]++
+				break
+			}
+		}
+		s.sum += v
+		s.count++
+	}
+}
+
+// Record the number of seconds since the start time with the given label values.
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// The registry holds all the metric families and collectors of the process.
+type Registry struct {
+	lock       sync.Mutex
+	families   map[string]*family
+	collectors map[string]func()
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		families:   make(map[string]*family),
+		collectors: make(map[string]func()),
+	}
+}
+
+// The process wide registry.
+var defaultRegistry = NewRegistry()
+
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register a metric family. Registering the same name again returns the existing family, so that the metric can be
+// declared in more than one place. It is a programming error to register the same name with a different type.
+func (r *Registry) register(name string, help string, mtype string, buckets []float64, labelNames []string) *family {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if f.mtype != mtype {
+			panic(fmt.Sprintf("metric %v is already registered as a %v", name, f.mtype))
+		}
+		return f
+	}
+
+	f := &family{
+		name:       name,
+		help:       help,
+		mtype:      mtype,
+		labelNames: labelNames,
+		buckets:    buckets,
+		samples:    make(map[string]*sample),
+	}
+	r.families[name] = f
+	return f
+}
+
+func (r *Registry) NewCounter(name string, help string, labelNames ...string) *Counter {
+	return &Counter{f: r.register(name, help, METRIC_COUNTER, nil, labelNames)}
+}
+
+func (r *Registry) NewGauge(name string, help string, labelNames ...string) *Gauge {
+	return &Gauge{f: r.register(name, help, METRIC_GAUGE, nil, labelNames)}
+}
+
+func (r *Registry) NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+	return &Histogram{f: r.register(name, help, METRIC_HISTOGRAM, sorted, labelNames)}
+}
+
+// Register a function that updates metrics from the current state of the process. The function is called every
+// time the metrics are written. Registering a collector with an existing name replaces it.
+func (r *Registry) RegisterCollector(name string, fn func()) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.collectors[name] = fn
+}
+
+func (r *Registry) UnregisterCollector(name string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	delete(r.collectors, name)
+}
+
+// Run the collectors and write all the metrics in the Prometheus text exposition format.
+func (r *Registry) WriteText(w io.Writer) error {
+
+	r.lock.Lock()
+	collectors := make([]func(), 0, len(r.collectors))
+	for _, fn := range r.collectors {
+		collectors = append(collectors, fn)
+	}
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.lock.Unlock()
+
+	// The collectors are run without holding the registry lock because they usually read the database.
+	for _, fn := range collectors {
+		fn()
+	}
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		writeFamily(bw, f)
+	}
+	return bw.Flush()
+}
+
+func writeFamily(w *bufio.Writer, f *family) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	fmt.Fprintf(w, "# HELP %v %v\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %v %v\n", f.name, f.mtype)
+
+	keys := make([]string, 0, len(f.samples))
+	for k := range f.samples {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := f.samples[k]
+		if f.mtype != METRIC_HISTOGRAM {
+			fmt.Fprintf(w, "%v%v %v\n", f.name, formatLabels(f.labelNames, s.labelValues, "", 0), formatValue(s.value))
+			continue
+		}
+
+		cumulative := uint64(0)
+		for i, upper := range f.buckets {
+			cumulative += s.buckets[i]
+			fmt.Fprintf(w, "%v_bucket%v %v\n", f.name, formatLabels(f.labelNames, s.labelValues, "le", upper), cumulative)
+		}
+		fmt.Fprintf(w, "%v_bucket%v %v\n", f.name, formatLabels(f.labelNames, s.labelValues, "le", math.Inf(1)), s.count)
+		fmt.Fprintf(w, "%v_sum%v %v\n", f.name, formatLabels(f.labelNames, s.labelValues, "", 0), formatValue(s.sum))
+		fmt.Fprintf(w, "%v_count%v %v\n", f.name, formatLabels(f.labelNames, s.labelValues, "", 0), s.count)
+	}
+}
+
+// Format the label set of a time series. The extra label is used for the histogram bucket boundary.
+func formatLabels(names []string, values []string, extraName string, extraValue float64) string {
+	pairs := make([]string, 0, len(names)+1)
+	for i, n := range names {
+		pairs = append(pairs, fmt.Sprintf("%v=\"%v\"", n, escapeLabelValue(values[i])))
+	}
+	if extraName != "" {
+		pairs = append(pairs, fmt.Sprintf("%v=\"%v\"", extraName, formatValue(extraValue)))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var helpEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n")
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"")
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+// Functions that work on the process wide registry.
+func NewCounter(name string, help string, labelNames ...string) *Counter {
+	return defaultRegistry.NewCounter(name, help, labelNames...)
+}
+
+func NewGauge(name string, help string, labelNames ...string) *Gauge {
+	return defaultRegistry.NewGauge(name, help, labelNames...)
+}
+
+func NewHistogram(name string, help string, buckets []float64, labelNames ...string) *Histogram {
+	return defaultRegistry.NewHistogram(name, help, buckets, labelNames...)
+}
+
+func RegisterCollector(name string, fn func()) {
+	defaultRegistry.RegisterCollector(name, fn)
+}
+
+func UnregisterCollector(name string) {
+	defaultRegistry.UnregisterCollector(name)
+}
+
+// The HTTP handler for the /metrics API. Both the node and agbot APIs route to it.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", TEXT_CONTENT_TYPE)
+		w.WriteHeader(http.StatusOK)
+		if err := defaultRegistry.WriteText(w); err != nil {
+			glog.Errorf(metricsLogString(fmt.Sprintf("error writing metrics, error: %v", err)))
+		}
+	case "OPTIONS":
+		w.Header().Set("Allow", "GET, OPTIONS")
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+var metricsLogString = func(v interface{}) string {
+	return fmt.Sprintf("Metrics: %v", v)
+}
@@ -0,0 +1,109 @@
+// +build unit
+
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_WriteText(t *testing.T) {
+
+	r := NewRegistry()
+
+	c := r.NewCounter("test_requests_total", "The number of requests.", "method")
+	c.Inc("GET")
+	c.Inc("GET")
+	c.Add(3, "PUT")
+	c.Add(-1, "PUT")  // ignored, counters only go up
+	c.Inc("GET", "x") // ignored, wrong number of labels
+
+	g := r.NewGauge("test_queue_depth", "The depth of the queue.")
+	g.Set(7)
+
+	h := r.NewHistogram("test_latency_seconds", "The latency.\nIn seconds.", []float64{1, 0.1}, "path")
+	h.Observe(0.05, "a\"b")
+	h.Observe(0.5, "a\"b")
+	h.Observe(5, "a\"b")
+
+	// Registering again returns the same metric.
+	r.NewCounter("test_requests_total", "The number of requests.", "method").Inc("GET")
+
+	collected := false
+	r.RegisterCollector("test", func() { collected = true; g.Set(9) })
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `# HELP test_latency_seconds The latency.\nIn seconds.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{path="a\"b",le="0.1"} 1
+test_latency_seconds_bucket{path="a\"b",le="1"} 2
+test_latency_seconds_bucket{path="a\"b",le="+Inf"} 3
+test_latency_seconds_sum{path="a\"b"} 5.55
+test_latency_seconds_count{path="a\"b"} 3
+# HELP test_queue_depth The depth of the queue.
+# TYPE test_queue_depth gauge
+test_queue_depth 9
+# HELP test_requests_total The number of requests.
+# TYPE test_requests_total counter
+test_requests_total{method="GET"} 3
+test_requests_total{method="PUT"} 3
+`
+	if !collected {
+		t.Errorf("the collector was not called")
+	} else if buf.String() != expected {
+		t.Errorf("unexpected output:\n%v\nexpected:\n%v", buf.String(), expected)
+	}
+
+	// Deleted and reset time series are no longer written.
+	r.UnregisterCollector("test")
+	g.Reset()
+	buf.Reset()
+	r.WriteText(&buf)
+	if strings.Contains(buf.String(), "test_queue_depth 9") {
+		t.Errorf("the gauge should have been reset: %v", buf.String())
+	}
+}
+
+func Test_RegisterTypeMismatch(t *testing.T) {
+
+	r := NewRegistry()
+	r.NewCounter("test_total", "A counter.")
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering a gauge with the name of a counter should panic")
+		}
+	}()
+	r.NewGauge("test_total", "A gauge.")
+}
+
+func Test_Handler(t *testing.T) {
+
+	NewCounter("test_handler_total", "A counter.").Inc()
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	Handler(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status %v, got %v", http.StatusOK, rr.Code)
+	} else if rr.Header().Get("Content-Type") != TEXT_CONTENT_TYPE {
+		t.Errorf("unexpected content type %v", rr.Header().Get("Content-Type"))
+	} else if !strings.Contains(rr.Body.String(), "test_handler_total 1\n") {
+		t.Errorf("the counter is missing from the output: %v", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/metrics", nil)
+	rr = httptest.NewRecorder()
+	Handler(rr, req)
+	if rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected status %v, got %v", http.StatusMethodNotAllowed, rr.Code)
+	}
+}
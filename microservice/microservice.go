@@ -32,6 +32,7 @@ const MS_DELETED_BY_UPGRADE_PROCESS = 205
 const MS_DELETED_FOR_AG_ENDED = 206
 const MS_IMAGE_FETCH_FAILED = 207
 const MS_DELETED_BY_DOWNGRADE_PROCESS = 208
+const MS_HEALTH_CHECK_FAILED = 209
 
 func DecodeReasonCode(code uint64) string {
 	// microservice termiated deccription
@@ -45,6 +46,7 @@ func DecodeReasonCode(code uint64) string {
 		MS_DELETED_BY_DOWNGRADE_PROCESS: "Deleted by downgrading process",
 		MS_DELETED_FOR_AG_ENDED:         "Deleted for agreement ended",
 		MS_IMAGE_FETCH_FAILED:           "Image fetching failed",
+		MS_HEALTH_CHECK_FAILED:          "Health check failed",
 	}
 
 	if reasonString, ok := codeMeanings[code]; !ok {
@@ -79,7 +81,7 @@ func ConvertServiceToPersistent(es *exchange.ServiceDefinition, org string) (*pe
 
 	user_inputs := make([]persistence.UserInput, 0)
 	for _, ui := range es.UserInputs {
-		new_ui := persistence.NewUserInput(ui.Name, ui.Label, ui.Type, ui.DefaultValue)
+		new_ui := persistence.NewUserInput(ui.Name, ui.Label, ui.Type, ui.DefaultValue, ui.Schema)
 		user_inputs = append(user_inputs, *new_ui)
 	}
 	pms.UserInputs = user_inputs
@@ -0,0 +1,140 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"strings"
+)
+
+// The bucket name in the bolt DB for the model management objects consumed by the services.
+const CONSUMED_OBJECTS = "consumed_objects"
+
+// The version of a model management object that a service consumed through the ESS API. There is one record per
+// service instance and object, the latest consumption replaces the previous one. The service key is the agreement id or
+// the service instance key that the service's ESS credential belongs to.
+type ConsumedObject struct {
+	ServiceKey     string `json:"service_key"`
+	ServiceId      string `json:"service_id"`      // <org>/<url> of the service
+	ServiceVersion string `json:"service_version"` // empty when the service's version is not known
+	ObjectType     string `json:"object_type"`
+	ObjectId       string `json:"object_id"`
+	ObjectVersion  string `json:"object_version"`
+	InstanceId     int64  `json:"instance_id"` // the instance of the object, it changes each time the object is published
+	ConsumedTime   uint64 `json:"consumed_time"`
+}
+
+func (c ConsumedObject) String() string {
+	return fmt.Sprintf("ServiceKey: %v, "+
+		"ServiceId: %v, "+
+		"ServiceVersion: %v, "+
+		"ObjectType: %v, "+
+		"ObjectId: %v, "+
+		"ObjectVersion: %v, "+
+		"InstanceId: %v, "+
+		"ConsumedTime: %v",
+		c.ServiceKey, c.ServiceId, c.ServiceVersion, c.ObjectType, c.ObjectId, c.ObjectVersion, c.InstanceId, c.ConsumedTime)
+}
+
+// The DB key of a consumed object record. The service key comes first so that the records of a service can be removed
+// together.
+func (c ConsumedObject) key() string {
+	return fmt.Sprintf("%v/%v/%v", c.ServiceKey, c.ObjectType, c.ObjectId)
+}
+
+// Save the object version consumed by a service, replacing the version it consumed before.
+func SaveConsumedObject(db *bolt.DB, co *ConsumedObject) error {
+
+	if co == nil || co.ServiceKey == "" || co.ObjectType == "" || co.ObjectId == "" {
+		return fmt.Errorf("Missing required consumed object service key, type or id")
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(CONSUMED_OBJECTS))
+		if err != nil {
+			return err
+		}
+
+		if serial, err := json.Marshal(co); err != nil {
+			return fmt.Errorf("Failed to serialize consumed object: %v. Error: %v", *co, err)
+		} else {
+			return b.Put([]byte(co.key()), serial)
+		}
+	})
+}
+
+// Find the consumed objects that pass all the filters.
+func FindConsumedObjects(db *bolt.DB, filters []COFilter) ([]ConsumedObject, error) {
+	cos := make([]ConsumedObject, 0)
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+
+		if b := tx.Bucket([]byte(CONSUMED_OBJECTS)); b != nil {
+			b.ForEach(func(k, v []byte) error {
+
+				var co ConsumedObject
+
+				if err := json.Unmarshal(v, &co); err != nil {
+					glog.Errorf("Unable to deserialize db record: %v", v)
+				} else {
+					exclude := false
+					for _, filterFn := range filters {
+						if !filterFn(co) {
+							exclude = true
+						}
+					}
+					if !exclude {
+						cos = append(cos, co)
+					}
+				}
+				return nil
+			})
+		}
+
+		return nil // end the transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	} else {
+		return cos, nil
+	}
+}
+
+// Remove the consumed object records of a service, when its agreement or service instance ends.
+func DeleteConsumedObjects(db *bolt.DB, serviceKey string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(CONSUMED_OBJECTS))
+		if b == nil {
+			return nil
+		}
+
+		keys := make([][]byte, 0)
+		prefix := serviceKey + "/"
+		b.ForEach(func(k, v []byte) error {
+			if strings.HasPrefix(string(k), prefix) {
+				keys = append(keys, k)
+			}
+			return nil
+		})
+
+		for _, k := range keys {
+			if err := b.Delete(k); err != nil {
+				return fmt.Errorf("Unable to delete consumed object %v: %v", string(k), err)
+			}
+		}
+		glog.V(5).Infof("Deleted %v consumed objects of %v", len(keys), serviceKey)
+		return nil
+	})
+}
+
+// filter on ConsumedObject
+type COFilter func(ConsumedObject) bool
+
+// filter on the object type and id, an empty id matches all the objects of the type
+func ObjectCOFilter(objectType string, objectId string) COFilter {
+	return func(e ConsumedObject) bool {
+		return e.ObjectType == objectType && (objectId == "" || e.ObjectId == objectId)
+	}
+}
@@ -0,0 +1,56 @@
+// +build unit
+
+package persistence
+
+import (
+	"testing"
+)
+
+// Verify that consumed objects can be saved, found and deleted.
+func Test_ConsumedObjects(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	co1 := &ConsumedObject{ServiceKey: "ag1", ServiceId: "myorg/svc1", ObjectType: "model", ObjectId: "m1", ObjectVersion: "1.0", InstanceId: 10, ConsumedTime: 100}
+	co2 := &ConsumedObject{ServiceKey: "ag1", ServiceId: "myorg/svc1", ObjectType: "config", ObjectId: "c1", ObjectVersion: "2.0", InstanceId: 20, ConsumedTime: 200}
+	co3 := &ConsumedObject{ServiceKey: "ag10", ServiceId: "myorg/svc2", ObjectType: "model", ObjectId: "m1", ObjectVersion: "1.0", InstanceId: 10, ConsumedTime: 300}
+
+	for _, co := range []*ConsumedObject{co1, co2, co3} {
+		if err := SaveConsumedObject(db, co); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if err := SaveConsumedObject(db, &ConsumedObject{ServiceKey: "ag1"}); err == nil {
+		t.Errorf("consumed object without a type and id should not be saved")
+	}
+
+	// A newer consumption of the same object replaces the previous one.
+	co1.ObjectVersion = "1.1"
+	co1.InstanceId = 11
+	if err := SaveConsumedObject(db, co1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if cos, err := FindConsumedObjects(db, []COFilter{ObjectCOFilter("model", "m1")}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if len(cos) != 2 {
+		t.Errorf("there should be 2 services that consumed model m1, but found %v", cos)
+	} else {
+		for _, co := range cos {
+			if co.ServiceKey == "ag1" && co.ObjectVersion != "1.1" {
+				t.Errorf("the consumed version should be updated: %v", co)
+			}
+		}
+	}
+
+	// Only the records of the given service are removed, not those of a service whose key has the same prefix.
+	if err := DeleteConsumedObjects(db, "ag1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if cos, err := FindConsumedObjects(db, []COFilter{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if len(cos) != 1 || cos[0].ServiceKey != "ag10" {
+		t.Errorf("only the consumed object of ag10 should be left, but found %v", cos)
+	}
+}
@@ -25,6 +25,9 @@ const (
 	EC_API_USER_INPUT_ERROR = "api_user_input_error"
 	EC_EXCHANGE_ERROR       = "exchange_error"
 
+	// event log maintenance
+	EC_EVENTLOGS_PURGED = "eventlogs_purged"
+
 	// node configuration/registration
 	EC_START_NODE_CONFIG_REG    = "start_node_configuration_registration"
 	EC_NODE_CONFIG_REG_COMPLETE = "node_configuration_registration_complete"
@@ -94,14 +97,25 @@ const (
 	EC_CANCEL_AGREEMENT_PER_AGBOT         = "cancel_agreement_per_agbot_request"
 	EC_CANCEL_AGREEMENT_SERVICE_SUSPENDED = "cancel_agreement_service_suspended"
 	EC_CANCEL_AGREEMENT_POLICY_CHANGED    = "cancel_agreement_policy_changed"
-
-	EC_CONTAINER_RUNNING          = "container_running"
-	EC_CONTAINER_STOPPED          = "container_stopped"
-	EC_ERROR_IN_DEPLOYMENT_CONFIG = "error_in_deployment_configuration"
-	EC_ERROR_START_CONTAINER      = "error_start_container"
+	EC_HOLD_AGREEMENT_UPGRADE             = "hold_agreement_upgrade"
+	EC_RELEASE_AGREEMENT_UPGRADE          = "release_agreement_upgrade"
+
+	EC_CONTAINER_RUNNING           = "container_running"
+	EC_CONTAINER_STOPPED           = "container_stopped"
+	EC_CONTAINER_HEALTHY           = "container_healthy"
+	EC_CONTAINER_UNHEALTHY         = "container_unhealthy"
+	EC_ERROR_IN_DEPLOYMENT_CONFIG  = "error_in_deployment_configuration"
+	EC_ERROR_START_CONTAINER       = "error_start_container"
+	EC_SERVICE_SECRETS_ROTATED     = "service_secrets_rotated"
+	EC_ERROR_SERVICE_SECRETS       = "error_service_secrets"
+	EC_RELOAD_SERVICE_CONFIG       = "reload_service_config"
+	EC_SERVICE_CONFIG_RELOADED     = "service_config_reloaded"
+	EC_ERROR_SERVICE_CONFIG_RELOAD = "error_service_config_reload"
 
 	EC_IMAGE_LOADED                       = "image_loaded"
 	EC_ERROR_IMAGE_LOADE                  = "error_image_load"
+	EC_IMAGE_PREFETCHED                   = "image_prefetched"
+	EC_ERROR_IMAGE_PREFETCH               = "error_image_prefetch"
 	EC_ERROR_AGREEMENT_VERIFICATION       = "error_in_agreement_verification"
 	EC_ERROR_DELETE_AGREEMENT_IN_EXCHANGE = "error_delete_agreement_in_exchange"
 
@@ -133,6 +147,7 @@ const (
 	EC_START_UPGRADE_SERVICE    = "start_rollback_service"
 	EC_COMPLETE_UPGRADE_SERVICE = "complete_rollback_service"
 	EC_ERROR_UPGRADE_SERVICE    = "error_rollback_service"
+	EC_HOLD_UPGRADE_SERVICE     = "hold_upgrade_service"
 
 	EC_START_CLEANUP_SERVICE    = "start_cleanup_service"
 	EC_COMPLETE_CLEANUP_SERVICE = "complete_cleanup_service"
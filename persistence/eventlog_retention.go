@@ -0,0 +1,137 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+	"sort"
+	"strconv"
+)
+
+// The rules for how long event logs are kept in the db. A zero value means there is no limit.
+type EventLogRetention struct {
+	MaxAgeS         uint64            // event logs older than this many seconds are deleted
+	SeverityMaxAgeS map[string]uint64 // overrides MaxAgeS for the given severities, 0 keeps the event logs of that severity forever
+	MaxRecords      int               // the number of event logs kept, the oldest are deleted first
+}
+
+func (r EventLogRetention) String() string {
+	return fmt.Sprintf("MaxAgeS: %v, SeverityMaxAgeS: %v, MaxRecords: %v", r.MaxAgeS, r.SeverityMaxAgeS, r.MaxRecords)
+}
+
+// Returns true if no event logs are ever deleted.
+func (r EventLogRetention) IsUnlimited() bool {
+	if r.MaxAgeS != 0 || r.MaxRecords != 0 {
+		return false
+	}
+	for _, age := range r.SeverityMaxAgeS {
+		if age != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the age in seconds after which an event log with the given severity is deleted, 0 if it is kept forever.
+func (r EventLogRetention) maxAge(severity string) uint64 {
+	if age, ok := r.SeverityMaxAgeS[severity]; ok {
+		return age
+	}
+	return r.MaxAgeS
+}
+
+// The parts of a saved event log needed to decide whether to delete it.
+type eventLogKey struct {
+	key       []byte
+	id        uint64
+	timestamp uint64
+	severity  string
+}
+
+// Delete the event logs that the retention rules no longer allow to be kept, as of the given time. Returns the
+// number of event logs deleted.
+func PruneEventLogs(db *bolt.DB, retention EventLogRetention, now uint64) (int, error) {
+	if retention.IsUnlimited() {
+		return 0, nil
+	}
+
+	return deleteEventLogs(db, func(keys []eventLogKey) []eventLogKey {
+
+		// The keys are sorted oldest first. Apply the age rules first, then remove the oldest of the rest until
+		// the count fits.
+		kept := make([]eventLogKey, 0, len(keys))
+		remove := make([]eventLogKey, 0)
+		for _, k := range keys {
+			if age := retention.maxAge(k.severity); age != 0 && k.timestamp+age < now {
+				remove = append(remove, k)
+			} else {
+				kept = append(kept, k)
+			}
+		}
+
+		if retention.MaxRecords != 0 && len(kept) > retention.MaxRecords {
+			remove = append(remove, kept[:len(kept)-retention.MaxRecords]...)
+		}
+		return remove
+	})
+}
+
+// Delete the event logs saved before the given time stamp. Returns the number of event logs deleted.
+func DeleteEventLogsBefore(db *bolt.DB, timestamp uint64) (int, error) {
+	return deleteEventLogs(db, func(keys []eventLogKey) []eventLogKey {
+		remove := make([]eventLogKey, 0)
+		for _, k := range keys {
+			if k.timestamp < timestamp {
+				remove = append(remove, k)
+			}
+		}
+		return remove
+	})
+}
+
+// Delete the event logs chosen by the given function, which is passed all the event logs, oldest first. The bucket
+// is never deleted, even when it becomes empty, so that new event logs keep getting increasing record ids. The last
+// unregistration time is kept in its own bucket so the current registration's event logs are still found correctly.
+func deleteEventLogs(db *bolt.DB, choose func([]eventLogKey) []eventLogKey) (int, error) {
+	deleted := 0
+
+	writeErr := db.Update(func(tx *bolt.Tx) error {
+
+		b := tx.Bucket([]byte(EVENT_LOGS))
+		if b == nil {
+			return nil
+		}
+
+		keys := make([]eventLogKey, 0)
+		if err := b.ForEach(func(k, v []byte) error {
+			var el EventLogBase
+			if err := json.Unmarshal(v, &el); err != nil {
+				glog.Errorf("Unable to deserialize event log db record: %v. Error: %v", v, err)
+			} else if id, err := strconv.ParseUint(string(k), 10, 64); err != nil {
+				glog.Errorf("Unable to convert event log key %v to a record id. Error: %v", string(k), err)
+			} else {
+				keys = append(keys, eventLogKey{key: append([]byte{}, k...), id: id, timestamp: el.Timestamp, severity: el.Severity})
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// The keys are sorted as strings in the db, sort them by record id, which is the order they were saved in.
+		sort.Slice(keys, func(i, j int) bool { return keys[i].id < keys[j].id })
+
+		for _, k := range choose(keys) {
+			if err := b.Delete(k.key); err != nil {
+				return fmt.Errorf("Unable to delete event log %v, error: %v", string(k.key), err)
+			}
+			deleted++
+		}
+		return nil
+	})
+
+	if writeErr != nil {
+		return 0, writeErr
+	}
+	return deleted, nil
+}
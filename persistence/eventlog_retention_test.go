@@ -0,0 +1,83 @@
+// +build unit
+
+package persistence
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_PruneEventLogs(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	source := NewNodeEventSource("node1", "myorg", "", "configured")
+
+	// 12 event logs, one every 100 seconds, alternating info and error.
+	for i := 0; i < 12; i++ {
+		severity := SEVERITY_INFO
+		if i%2 == 1 {
+			severity = SEVERITY_ERROR
+		}
+		el := NewEventLog(severity, "test", EC_NODE_CONFIG_REG_COMPLETE, SRC_TYPE_NODE, *source)
+		el.Timestamp = uint64(1000 + i*100)
+		if err := SaveEventLog(db, el); err != nil {
+			t.Fatalf("error saving event log: %v", err)
+		}
+	}
+
+	// The current registration started at 1550, unregistrations are kept across pruning.
+	if err := SaveLastUnregistrationTime(db, 1550); err != nil {
+		t.Fatalf("error saving last unregistration time: %v", err)
+	}
+
+	// no limits
+	n, err := PruneEventLogs(db, EventLogRetention{SeverityMaxAgeS: map[string]uint64{SEVERITY_INFO: 0}}, 5000)
+	assert.Nil(t, err, "Pruning should not fail.")
+	assert.Equal(t, 0, n, "Nothing should be pruned without limits.")
+
+	// info event logs are kept 500s, the others 900s
+	n, err = PruneEventLogs(db, EventLogRetention{MaxAgeS: 900, SeverityMaxAgeS: map[string]uint64{SEVERITY_INFO: 500}}, 2100)
+	assert.Nil(t, err, "Pruning should not fail.")
+	// info at 1000, 1200 and 1400, error at 1100
+	assert.Equal(t, 4, n, "The old info and error event logs should be pruned.")
+
+	// keep at most 5, the oldest go first
+	n, err = PruneEventLogs(db, EventLogRetention{MaxRecords: 5}, 2100)
+	assert.Nil(t, err, "Pruning should not fail.")
+	assert.Equal(t, 3, n, "The oldest event logs should be pruned down to the maximum.")
+
+	elogs, err := FindEventLogsWithSelectors(db, true, map[string][]Selector{})
+	assert.Nil(t, err, "Finding event logs should not fail.")
+	assert.Equal(t, 5, len(elogs), "There should be 5 event logs left.")
+	for _, el := range elogs {
+		assert.True(t, el.Timestamp >= 1700, "Only the newest event logs should be kept.")
+	}
+
+	// the current registration's event logs are still found
+	elogs, err = FindEventLogsWithSelectors(db, false, map[string][]Selector{})
+	assert.Nil(t, err, "Finding event logs should not fail.")
+	assert.Equal(t, 5, len(elogs), "The event logs for the current registration should be found.")
+
+	// purge before a time stamp
+	n, err = DeleteEventLogsBefore(db, 1900)
+	assert.Nil(t, err, "Purging should not fail.")
+	assert.Equal(t, 2, n, "The event logs before the time stamp should be purged.")
+
+	n, err = DeleteEventLogsBefore(db, 5000)
+	assert.Nil(t, err, "Purging should not fail.")
+	assert.Equal(t, 3, n, "All the event logs should be purged.")
+
+	// record ids keep increasing after everything was purged
+	el := NewEventLog(SEVERITY_INFO, "test", EC_NODE_CONFIG_REG_COMPLETE, SRC_TYPE_NODE, *source)
+	assert.Nil(t, SaveEventLog(db, el), "Saving should not fail.")
+	assert.Equal(t, "13", el.Id, "The record ids should not be reused.")
+
+	last, err := GetLastUnregistrationTime(db)
+	assert.Nil(t, err, "Getting the last unregistration time should not fail.")
+	assert.Equal(t, uint64(1550), last, "The last unregistration time should be kept.")
+}
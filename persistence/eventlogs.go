@@ -172,7 +172,9 @@ func GetLastUnregistrationTime(db *bolt.DB) (uint64, error) {
 
 		if b := tx.Bucket([]byte(LAST_UNREG)); b != nil {
 			v := b.Get([]byte("lastunreg"))
-			if s, err := strconv.ParseUint(string(v[:]), 10, 64); err != nil {
+			if v == nil {
+				return nil
+			} else if s, err := strconv.ParseUint(string(v[:]), 10, 64); err != nil {
 				return fmt.Errorf("Failed to convert the last unregistration time %v into uint64, error: %v", v, err)
 			} else {
 				last_unreg = s
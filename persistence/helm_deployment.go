@@ -10,8 +10,42 @@ import (
 // service is deployed via Helm to a Kubernetes cluster.
 
 type HelmDeploymentConfig struct {
-	ChartArchive string `json:"chart_archive"` // base64 encoded binary of helm package tar file
-	ReleaseName  string `json:"release_name"`
+	ChartArchive string                `json:"chart_archive"` // base64 encoded binary of helm package tar file
+	ReleaseName  string                `json:"release_name"`
+	History      []HelmReleaseRevision `json:"history,omitempty"`       // the revisions of the release made by the agent, oldest first
+	HandoverTime uint64                `json:"handover_time,omitempty"` // when the agreement ended and kept the release for the next agreement to upgrade it in place
+}
+
+// The actions that make a revision of a Helm release.
+const (
+	HELM_ACTION_INSTALL  = "install"
+	HELM_ACTION_UPGRADE  = "upgrade"
+	HELM_ACTION_ROLLBACK = "rollback"
+)
+
+// The number of revisions of a Helm release that are kept in the history.
+const HELM_MAX_HISTORY = 10
+
+// A revision of a Helm release. The history is carried over when the release is upgraded in place for a new agreement.
+type HelmReleaseRevision struct {
+	Revision    string `json:"revision"`
+	Action      string `json:"action"`
+	Chart       string `json:"chart"`
+	Status      string `json:"status"`
+	Time        uint64 `json:"time"`
+	AgreementId string `json:"agreement_id"`
+}
+
+func (r HelmReleaseRevision) String() string {
+	return fmt.Sprintf("Revision: %v, Action: %v, Chart: %v, Status: %v, Time: %v, AgreementId: %v", r.Revision, r.Action, r.Chart, r.Status, r.Time, r.AgreementId)
+}
+
+// Add a revision to the history of the release, dropping the oldest revisions beyond HELM_MAX_HISTORY.
+func (h *HelmDeploymentConfig) AddRevision(r HelmReleaseRevision) {
+	h.History = append(h.History, r)
+	if len(h.History) > HELM_MAX_HISTORY {
+		h.History = h.History[len(h.History)-HELM_MAX_HISTORY:]
+	}
 }
 
 func NewHelmDeployment(chartArchive string, releaseName string) *HelmDeploymentConfig {
@@ -26,7 +60,7 @@ func (h HelmDeploymentConfig) String() string {
 	if len(h.ChartArchive) < maxArchiveLength {
 		maxArchiveLength = len(h.ChartArchive)
 	}
-	return fmt.Sprintf("Release Name %v, Package %v", h.ReleaseName, h.ChartArchive[:maxArchiveLength])
+	return fmt.Sprintf("Release Name %v, Package %v, History %v", h.ReleaseName, h.ChartArchive[:maxArchiveLength], h.History)
 }
 
 func IsHelm(dep map[string]interface{}) bool {
@@ -5,6 +5,7 @@ package persistence
 import (
 	"encoding/json"
 	"flag"
+	"fmt"
 	"testing"
 )
 
@@ -71,3 +72,29 @@ func Test_PersistToFrom(t *testing.T) {
 	}
 
 }
+
+func Test_HelmReleaseHistory(t *testing.T) {
+
+	hd := NewHelmDeployment("1234567890", "test")
+	for i := 1; i <= HELM_MAX_HISTORY+2; i++ {
+		hd.AddRevision(HelmReleaseRevision{Revision: fmt.Sprintf("%v", i), Action: HELM_ACTION_UPGRADE, AgreementId: "ag1"})
+	}
+
+	if len(hd.History) != HELM_MAX_HISTORY {
+		t.Errorf("expected %v revisions in the history, but got %v", HELM_MAX_HISTORY, len(hd.History))
+	} else if hd.History[0].Revision != "3" || hd.History[HELM_MAX_HISTORY-1].Revision != fmt.Sprintf("%v", HELM_MAX_HISTORY+2) {
+		t.Errorf("expected the oldest revisions to be dropped, but got %v", hd.History)
+	}
+
+	// the history is kept in the persistent form of the agreement
+	if pf, err := hd.ToPersistentForm(); err != nil {
+		t.Errorf("unexpected error changing to persistent form: %v", err)
+	} else {
+		nhd := HelmDeploymentConfig{}
+		if err := nhd.FromPersistentForm(pf); err != nil {
+			t.Errorf("unexpected error changing from persistent form: %v", err)
+		} else if len(nhd.History) != HELM_MAX_HISTORY || nhd.History[0] != hd.History[0] {
+			t.Errorf("history from persistent form: %v doesnt match original: %v", nhd.History, hd.History)
+		}
+	}
+}
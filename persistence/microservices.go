@@ -7,6 +7,7 @@ import (
 	"github.com/boltdb/bolt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/cutil"
+	"github.com/open-horizon/anax/policy"
 	"github.com/satori/go.uuid"
 	"strconv"
 	"time"
@@ -17,18 +18,20 @@ const MICROSERVICE_INSTANCES = "microdevice_instances"
 const MICROSERVICE_DEFINITIONS = "microdevice_definitions"
 
 type UserInput struct {
-	Name         string `json:"name"`
-	Label        string `json:"label"`
-	Type         string `json:"type"`
-	DefaultValue string `json:"defaultValue"`
+	Name         string                  `json:"name"`
+	Label        string                  `json:"label"`
+	Type         string                  `json:"type"`
+	DefaultValue string                  `json:"defaultValue"`
+	Schema       *policy.UserInputSchema `json:"schema,omitempty"`
 }
 
-func NewUserInput(name string, label string, stype string, default_value string) *UserInput {
+func NewUserInput(name string, label string, stype string, default_value string, schema *policy.UserInputSchema) *UserInput {
 	return &UserInput{
 		Name:         name,
 		Label:        label,
 		Type:         stype,
 		DefaultValue: default_value,
+		Schema:       schema,
 	}
 }
 
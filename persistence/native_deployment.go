@@ -5,6 +5,10 @@ import (
 	docker "github.com/fsouza/go-dockerclient"
 )
 
+// The container label that holds the config reload of a service, in JSON. Only the containers with this label are told
+// to reload their configuration when the node user input changes.
+const LABEL_CONFIG_RELOAD = "openhorizon.anax.config_reload"
+
 type DeploymentConfig interface {
 	ToPersistentForm() (map[string]interface{}, error)
 	FromPersistentForm(pf map[string]interface{}) error
@@ -60,6 +64,12 @@ func ServiceConfigNames(serviceConfigs *map[string]ServiceConfig) []string {
 	return names
 }
 
+// Returns true when the containers of the service reload their configuration.
+func (c ServiceConfig) ReloadsConfig() bool {
+	_, ok := c.Config.Labels[LABEL_CONFIG_RELOAD]
+	return ok
+}
+
 func (c ServiceConfig) String() string {
 	return fmt.Sprintf("Config: %v, HostConfig: %v", c.Config, c.HostConfig)
 }
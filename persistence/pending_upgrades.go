@@ -0,0 +1,166 @@
+package persistence
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/golang/glog"
+)
+
+// The bucket name in the bolt DB for the upgrades that are waiting for a maintenance window.
+const PENDING_UPGRADES = "pending_upgrades"
+
+// The kinds of upgrades that can be held.
+const PENDING_UPGRADE_AGREEMENT = "agreement" // an agreement cancelled by the agbot because of a workload upgrade
+const PENDING_UPGRADE_SERVICE = "service"     // a service (dependency) upgrade found by the node
+
+// A pending upgrade is an upgrade that the node is holding until its next maintenance window opens. For agreements, the
+// key is the agreement id. For services, the key is the service definition key.
+type PendingUpgrade struct {
+	Key               string `json:"key"`
+	Type              string `json:"type"`                         // agreement or service
+	AgreementProtocol string `json:"agreement_protocol,omitempty"` // the protocol of the held agreement cancellation
+	Reason            uint   `json:"reason,omitempty"`             // the reason code of the held agreement cancellation
+	ReasonDesc        string `json:"reason_description,omitempty"` // the description of the reason code
+	ServiceURL        string `json:"service_url"`
+	ServiceOrg        string `json:"service_org"`
+	CurrentVersion    string `json:"current_version"`
+	NewVersion        string `json:"new_version,omitempty"` // only known for service upgrades
+	Windows           string `json:"maintenance_windows"`   // the maintenance windows that the upgrade is waiting for
+	ReceivedTime      uint64 `json:"received_time"`         // the time when the upgrade was first held
+	NextWindowTime    uint64 `json:"next_window_time"`      // the time when the next maintenance window opens, 0 if unknown
+}
+
+func (p PendingUpgrade) String() string {
+	return fmt.Sprintf("Key: %v, "+
+		"Type: %v, "+
+		"AgreementProtocol: %v, "+
+		"Reason: %v, "+
+		"ReasonDesc: %v, "+
+		"ServiceURL: %v, "+
+		"ServiceOrg: %v, "+
+		"CurrentVersion: %v, "+
+		"NewVersion: %v, "+
+		"Windows: %v, "+
+		"ReceivedTime: %v, "+
+		"NextWindowTime: %v",
+		p.Key, p.Type, p.AgreementProtocol, p.Reason, p.ReasonDesc, p.ServiceURL, p.ServiceOrg,
+		p.CurrentVersion, p.NewVersion, p.Windows, p.ReceivedTime, p.NextWindowTime)
+}
+
+// Save a pending upgrade. If the upgrade is already being held, the time it was first held is kept.
+func SavePendingUpgrade(db *bolt.DB, pu *PendingUpgrade) error {
+
+	if pu == nil || pu.Key == "" {
+		return fmt.Errorf("Missing required pending upgrade key")
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(PENDING_UPGRADES))
+		if err != nil {
+			return err
+		}
+
+		if current := b.Get([]byte(pu.Key)); current != nil {
+			var existing PendingUpgrade
+			if err := json.Unmarshal(current, &existing); err != nil {
+				return fmt.Errorf("Failed to unmarshal pending upgrade DB data: %v", string(current))
+			} else if existing.ReceivedTime != 0 {
+				pu.ReceivedTime = existing.ReceivedTime
+			}
+		}
+
+		if serial, err := json.Marshal(pu); err != nil {
+			return fmt.Errorf("Failed to serialize pending upgrade: %v. Error: %v", *pu, err)
+		} else {
+			return b.Put([]byte(pu.Key), serial)
+		}
+	})
+}
+
+// Find the pending upgrade with the given key, returns nil if the upgrade is not being held.
+func FindPendingUpgrade(db *bolt.DB, key string) (*PendingUpgrade, error) {
+	if pus, err := FindPendingUpgrades(db, []PUFilter{KeyPUFilter(key)}); err != nil {
+		return nil, err
+	} else if len(pus) == 0 {
+		return nil, nil
+	} else {
+		return &pus[0], nil
+	}
+}
+
+// Find the pending upgrades that pass all the filters.
+func FindPendingUpgrades(db *bolt.DB, filters []PUFilter) ([]PendingUpgrade, error) {
+	pus := make([]PendingUpgrade, 0)
+
+	readErr := db.View(func(tx *bolt.Tx) error {
+
+		if b := tx.Bucket([]byte(PENDING_UPGRADES)); b != nil {
+			b.ForEach(func(k, v []byte) error {
+
+				var pu PendingUpgrade
+
+				if err := json.Unmarshal(v, &pu); err != nil {
+					glog.Errorf("Unable to deserialize db record: %v", v)
+				} else {
+					exclude := false
+					for _, filterFn := range filters {
+						if !filterFn(pu) {
+							exclude = true
+						}
+					}
+					if !exclude {
+						pus = append(pus, pu)
+					}
+				}
+				return nil
+			})
+		}
+
+		return nil // end the transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	} else {
+		return pus, nil
+	}
+}
+
+// Remove a pending upgrade, removing one that is not being held is not an error.
+func DeletePendingUpgrade(db *bolt.DB, key string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(PENDING_UPGRADES)); b == nil || b.Get([]byte(key)) == nil {
+			return nil
+		} else if err := b.Delete([]byte(key)); err != nil {
+			return fmt.Errorf("Unable to delete pending upgrade %v: %v", key, err)
+		}
+		glog.V(5).Infof("Deleted pending upgrade %v", key)
+		return nil
+	})
+}
+
+// Remove all the pending upgrades.
+func DeleteAllPendingUpgrades(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(PENDING_UPGRADES)); b == nil {
+			return nil
+		} else if err := tx.DeleteBucket([]byte(PENDING_UPGRADES)); err != nil {
+			return fmt.Errorf("Unable to delete pending upgrades: %v", err)
+		}
+		return nil
+	})
+}
+
+// filter on PendingUpgrade
+type PUFilter func(PendingUpgrade) bool
+
+// filter on the key
+func KeyPUFilter(key string) PUFilter {
+	return func(e PendingUpgrade) bool { return e.Key == key }
+}
+
+// filter on the kind of upgrade
+func TypePUFilter(t string) PUFilter {
+	return func(e PendingUpgrade) bool { return e.Type == t }
+}
@@ -0,0 +1,71 @@
+// +build unit
+
+package persistence
+
+import (
+	"testing"
+)
+
+// Verify that pending upgrades can be saved, found and deleted.
+func Test_PendingUpgrades(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	if pu, err := FindPendingUpgrade(db, "ag1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if pu != nil {
+		t.Errorf("there should not be a pending upgrade, but found %v", *pu)
+	}
+
+	ag := &PendingUpgrade{Key: "ag1", Type: PENDING_UPGRADE_AGREEMENT, AgreementProtocol: "Basic", Reason: 204, ReceivedTime: 100, NextWindowTime: 200}
+	svc := &PendingUpgrade{Key: "svc1", Type: PENDING_UPGRADE_SERVICE, CurrentVersion: "1.0.0", NewVersion: "1.1.0", ReceivedTime: 150}
+
+	if err := SavePendingUpgrade(db, ag); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if err := SavePendingUpgrade(db, svc); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if err := SavePendingUpgrade(db, &PendingUpgrade{}); err == nil {
+		t.Errorf("pending upgrade without a key should not be saved")
+	}
+
+	if pus, err := FindPendingUpgrades(db, []PUFilter{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if len(pus) != 2 {
+		t.Errorf("there should be 2 pending upgrades, but found %v", pus)
+	} else if pus, err := FindPendingUpgrades(db, []PUFilter{TypePUFilter(PENDING_UPGRADE_SERVICE)}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if len(pus) != 1 || pus[0].Key != "svc1" {
+		t.Errorf("there should be 1 pending service upgrade, but found %v", pus)
+	}
+
+	// Saving again keeps the time the upgrade was first held.
+	ag.ReceivedTime = 300
+	ag.NextWindowTime = 400
+	if err := SavePendingUpgrade(db, ag); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if pu, err := FindPendingUpgrade(db, "ag1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if pu == nil || pu.ReceivedTime != 100 || pu.NextWindowTime != 400 {
+		t.Errorf("pending upgrade was not updated correctly: %v", pu)
+	}
+
+	if err := DeletePendingUpgrade(db, "ag1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if err := DeletePendingUpgrade(db, "ag1"); err != nil {
+		t.Errorf("deleting a missing pending upgrade should not fail: %v", err)
+	} else if pu, err := FindPendingUpgrade(db, "ag1"); err != nil || pu != nil {
+		t.Errorf("pending upgrade should be deleted, found %v, error %v", pu, err)
+	}
+
+	if err := DeleteAllPendingUpgrades(db); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if pus, err := FindPendingUpgrades(db, []PUFilter{}); err != nil || len(pus) != 0 {
+		t.Errorf("all pending upgrades should be deleted, found %v, error %v", pus, err)
+	} else if err := DeleteAllPendingUpgrades(db); err != nil {
+		t.Errorf("deleting pending upgrades when there are none should not fail: %v", err)
+	}
+}
@@ -187,6 +187,20 @@ func NewEstablishedAgreement(db *bolt.DB, name string, agreementId string, consu
 	})
 }
 
+// Returns true when every service of the native deployment reloads its configuration, so that a node user input
+// change can be applied without cancelling the agreement.
+func (a *EstablishedAgreement) ReloadsConfig() bool {
+	if len(a.CurrentDeployment) == 0 {
+		return false
+	}
+	for _, sc := range a.CurrentDeployment {
+		if !sc.ReloadsConfig() {
+			return false
+		}
+	}
+	return true
+}
+
 // Return either the CurrentDeployment or the ExtendedDeployment, depending on which is set, in a form that
 // implements the DeploymentConfig interface.
 func (a *EstablishedAgreement) GetDeploymentConfig() DeploymentConfig {
@@ -428,7 +442,7 @@ func persistUpdatedAgreement(db *bolt.DB, dbAgreementId string, protocol string,
 				if (len(mod.CurrentDeployment) == 0 && len(update.CurrentDeployment) != 0) || (len(mod.CurrentDeployment) != 0 && len(update.CurrentDeployment) == 0) {
 					mod.CurrentDeployment = update.CurrentDeployment
 				}
-				if len(mod.ExtendedDeployment) == 0 && len(update.ExtendedDeployment) != 0 { // valid transitions are from empty to non-empty
+				if len(update.ExtendedDeployment) != 0 { // valid transitions are from empty to non-empty, then the release history and handover are updated
 					mod.ExtendedDeployment = update.ExtendedDeployment
 				}
 				if mod.TerminatedReason == 0 { // 1 transition from zero to non-zero
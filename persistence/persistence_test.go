@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/boltdb/bolt"
+	docker "github.com/fsouza/go-dockerclient"
 	"testing"
 	"time"
 )
@@ -208,3 +209,24 @@ func Test_Backward_Compitibility_EstablishedAgreement(t *testing.T) {
 		}
 	}
 }
+
+func Test_EstablishedAgreement_ReloadsConfig(t *testing.T) {
+
+	withLabel := ServiceConfig{Config: docker.Config{Labels: map[string]string{LABEL_CONFIG_RELOAD: `{"signal":"SIGHUP"}`}}}
+	withoutLabel := ServiceConfig{Config: docker.Config{Labels: map[string]string{}}}
+
+	ag := &EstablishedAgreement{}
+	if ag.ReloadsConfig() {
+		t.Errorf("an agreement without a deployment cannot reload its configuration")
+	}
+
+	ag.CurrentDeployment = map[string]ServiceConfig{"s1": withLabel, "s2": withLabel}
+	if !ag.ReloadsConfig() {
+		t.Errorf("all the services of the agreement reload their configuration")
+	}
+
+	ag.CurrentDeployment["s3"] = withoutLabel
+	if ag.ReloadsConfig() {
+		t.Errorf("a service that does not reload its configuration needs a restart")
+	}
+}
@@ -0,0 +1,146 @@
+package persistence
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/policy"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const NODE_USERINPUT_SECRETS = "nodeuserinputsecrets" // The bucket name in the bolt DB.
+
+// The file holding the key that encrypts the secrets in the database. It is created next to the database file.
+const USERINPUT_SECRETS_KEY_FILE = "userinput_secrets.key"
+
+// Retrieve the secret node user inputs from the database. The secrets are stored encrypted, they are decrypted here.
+func FindNodeUserInputSecrets(db *bolt.DB) ([]policy.UserInput, error) {
+
+	secrets := make([]policy.UserInput, 0)
+
+	var sealed []byte
+	readErr := db.View(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(NODE_USERINPUT_SECRETS)); b != nil {
+			if v := b.Get([]byte(NODE_USERINPUT_SECRETS)); v != nil {
+				sealed = make([]byte, len(v))
+				copy(sealed, v)
+			}
+		}
+		return nil // end transaction
+	})
+
+	if readErr != nil {
+		return nil, readErr
+	} else if sealed == nil {
+		return secrets, nil
+	}
+
+	gcm, err := secretsCipher(db)
+	if err != nil {
+		return nil, err
+	} else if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("node user input secrets record is too short")
+	}
+
+	serial, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to decrypt node user input secrets, error %v", err))
+	} else if err := json.Unmarshal(serial, &secrets); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to deserialize node user input secrets, error %v", err))
+	}
+
+	return secrets, nil
+}
+
+// Encrypt the secret node user inputs and save them in the database. There is only 1 object in the bucket so we can use
+// the bucket name as the object key.
+func SaveNodeUserInputSecrets(db *bolt.DB, secrets []policy.UserInput) error {
+
+	gcm, err := secretsCipher(db)
+	if err != nil {
+		return err
+	}
+
+	serial, err := json.Marshal(secrets)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Failed to serialize node user input secrets, error %v", err))
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return errors.New(fmt.Sprintf("Failed to create a nonce for the node user input secrets, error %v", err))
+	}
+	sealed := gcm.Seal(nonce, nonce, serial, nil)
+
+	return db.Update(func(tx *bolt.Tx) error {
+		if b, err := tx.CreateBucketIfNotExists([]byte(NODE_USERINPUT_SECRETS)); err != nil {
+			return err
+		} else {
+			return b.Put([]byte(NODE_USERINPUT_SECRETS), sealed)
+		}
+	})
+}
+
+// Remove the secret node user inputs from the local database.
+func DeleteNodeUserInputSecrets(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if b := tx.Bucket([]byte(NODE_USERINPUT_SECRETS)); b == nil {
+			return nil
+		} else if err := b.Delete([]byte(NODE_USERINPUT_SECRETS)); err != nil {
+			return fmt.Errorf("Unable to delete node user input secrets: %v", err)
+		}
+		return nil
+	})
+}
+
+// Returns the secrets of a service by name. An empty version or arch matches any version or arch.
+func FindServiceSecrets(db *bolt.DB, url string, org string, version string, arch string) (map[string]string, error) {
+
+	secrets := make(map[string]string)
+
+	all, err := FindNodeUserInputSecrets(db)
+	if err != nil {
+		return nil, err
+	}
+
+	if ui, err := policy.FindUserInput(url, org, version, arch, all); err != nil {
+		return nil, err
+	} else if ui != nil {
+		for _, in := range ui.Inputs {
+			secrets[in.Name] = fmt.Sprintf("%v", in.Value)
+		}
+	}
+	return secrets, nil
+}
+
+// Returns the cipher for the secrets. The key is read from the key file next to the database, which is created the
+// first time with a random key that only the agent can read.
+func secretsCipher(db *bolt.DB) (cipher.AEAD, error) {
+
+	keyFile := filepath.Join(filepath.Dir(db.Path()), USERINPUT_SECRETS_KEY_FILE)
+
+	key, err := ioutil.ReadFile(keyFile)
+	if os.IsNotExist(err) {
+		key = make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, errors.New(fmt.Sprintf("Failed to create the node user input secrets key, error %v", err))
+		} else if err := ioutil.WriteFile(keyFile, key, 0600); err != nil {
+			return nil, errors.New(fmt.Sprintf("Failed to write the node user input secrets key file %v, error %v", keyFile, err))
+		}
+	} else if err != nil {
+		return nil, errors.New(fmt.Sprintf("Failed to read the node user input secrets key file %v, error %v", keyFile, err))
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid node user input secrets key in %v, error %v", keyFile, err))
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,59 @@
+// +build unit
+
+package persistence
+
+import (
+	"bytes"
+	"github.com/boltdb/bolt"
+	"github.com/open-horizon/anax/policy"
+	"testing"
+)
+
+// Verify that the secrets are stored encrypted and can be read back.
+func Test_NodeUserInputSecrets(t *testing.T) {
+
+	dir, db, err := utsetup()
+	if err != nil {
+		t.Error(err)
+	}
+	defer cleanTestDir(dir)
+
+	if secrets, err := FindNodeUserInputSecrets(db); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if len(secrets) != 0 {
+		t.Errorf("there should be no secrets, but found %v", secrets)
+	}
+
+	secrets := []policy.UserInput{
+		{ServiceOrgid: "myorg", ServiceUrl: "mysvc", ServiceVersionRange: "[2.0.0,INFINITY)", Inputs: []policy.Input{{Name: "DB_PASSWORD", Value: "s3cr3t", Type: policy.USER_INPUT_TYPE_SECRET}}},
+	}
+	if err := SaveNodeUserInputSecrets(db, secrets); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// the secret is not in the database in clear text
+	db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte(NODE_USERINPUT_SECRETS)).Get([]byte(NODE_USERINPUT_SECRETS)); bytes.Contains(v, []byte("s3cr3t")) {
+			t.Errorf("the secret should be encrypted in the database")
+		}
+		return nil
+	})
+
+	if found, err := FindServiceSecrets(db, "mysvc", "myorg", "2.1.0", "amd64"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if found["DB_PASSWORD"] != "s3cr3t" {
+		t.Errorf("expected the secret of the service, but found %v", found)
+	}
+
+	if found, err := FindServiceSecrets(db, "mysvc", "myorg", "1.0.0", "amd64"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if len(found) != 0 {
+		t.Errorf("the secret is not for version 1.0.0, but found %v", found)
+	}
+
+	if err := DeleteNodeUserInputSecrets(db); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	} else if secrets, err := FindNodeUserInputSecrets(db); err != nil || len(secrets) != 0 {
+		t.Errorf("there should be no secrets after the delete, but found %v, error %v", secrets, err)
+	}
+}
@@ -0,0 +1,124 @@
+package policy
+
+import (
+	"fmt"
+	"github.com/open-horizon/anax/externalpolicy"
+	"strings"
+)
+
+// The purpose of this file is to explain the result of the Are_Compatible function. The checks are made in the
+// same way as Are_Compatible makes them, but all of the checks are made and the details are returned in a
+// CompatibilityReport, so that a user can see which constraint or property caused a mismatch.
+
+// The names of the checks made by Are_Compatible.
+const (
+	COMPAT_CHECK_SCHEMA_VERSION       = "schemaVersion"
+	COMPAT_CHECK_CONSUMER_CONSTRAINTS = "consumerConstraints"
+	COMPAT_CHECK_PRODUCER_CONSTRAINTS = "producerConstraints"
+	COMPAT_CHECK_AGREEMENT_PROTOCOLS  = "agreementProtocols"
+	COMPAT_CHECK_DATA_VERIFICATION    = "dataVerification"
+)
+
+// The result of one of the compatibility checks.
+type CompatibilityCheck struct {
+	Name       string `json:"name"`
+	Compatible bool   `json:"compatible"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+func (c CompatibilityCheck) String() string {
+	return fmt.Sprintf("Name: %v, Compatible: %v, Reason: %v", c.Name, c.Compatible, c.Reason)
+}
+
+// The full explanation of a compatibility check between a producer (node) policy and a consumer policy.
+type CompatibilityReport struct {
+	Compatible          bool                                  `json:"compatible"`
+	ProducerPolicy      string                                `json:"producerPolicy"`
+	ConsumerPolicy      string                                `json:"consumerPolicy"`
+	Checks              []CompatibilityCheck                  `json:"checks"`
+	ConsumerConstraints *externalpolicy.ConstraintExplanation `json:"consumerConstraints,omitempty"` // The consumer's constraints evaluated against the producer's properties
+	ProducerConstraints *externalpolicy.ConstraintExplanation `json:"producerConstraints,omitempty"` // The producer's constraints evaluated against the consumer's properties
+}
+
+func (r CompatibilityReport) String() string {
+	return fmt.Sprintf("Compatible: %v, ProducerPolicy: %v, ConsumerPolicy: %v, Checks: %v, ConsumerConstraints: %v, ProducerConstraints: %v",
+		r.Compatible, r.ProducerPolicy, r.ConsumerPolicy, r.Checks, r.ConsumerConstraints, r.ProducerConstraints)
+}
+
+func (r *CompatibilityReport) addCheck(name string, compatible bool, reason string) {
+	r.Checks = append(r.Checks, CompatibilityCheck{Name: name, Compatible: compatible, Reason: reason})
+	if !compatible {
+		r.Compatible = false
+	}
+}
+
+// This function makes the same checks as Are_Compatible and returns a report with the details of each check. The
+// Compatible field in the report is true exactly when Are_Compatible returns no error. The order of the parameters is
+// important, just like in the Are_Compatible API. An error is returned only if one of the constraint expressions
+// cannot be parsed.
+func Explain_Compatibility(producer_policy *Policy, consumer_policy *Policy) (*CompatibilityReport, error) {
+
+	report := &CompatibilityReport{
+		Compatible:     true,
+		ProducerPolicy: producer_policy.Header.Name,
+		ConsumerPolicy: consumer_policy.Header.Name,
+		Checks:         make([]CompatibilityCheck, 0, 5),
+	}
+
+	if !consumer_policy.Is_Version(producer_policy.Header.Version) {
+		report.addCheck(COMPAT_CHECK_SCHEMA_VERSION, false, fmt.Sprintf("Schema versions are not the same, Consumer policy: %v, Producer policy %v", consumer_policy.Header.Version, producer_policy.Header.Version))
+	} else {
+		report.addCheck(COMPAT_CHECK_SCHEMA_VERSION, true, "")
+	}
+
+	if ce, err := (&consumer_policy.Constraints).Explain(producer_policy.Properties); err != nil {
+		return nil, fmt.Errorf("unable to explain consumer constraints %v, error %v", consumer_policy.Constraints, err)
+	} else {
+		report.ConsumerConstraints = ce
+		report.addCheck(COMPAT_CHECK_CONSUMER_CONSTRAINTS, ce.Satisfied, constraintReason(ce, "Producer properties do not satisfy the consumer constraints"))
+	}
+
+	if pe, err := (&producer_policy.Constraints).Explain(consumer_policy.Properties); err != nil {
+		return nil, fmt.Errorf("unable to explain producer constraints %v, error %v", producer_policy.Constraints, err)
+	} else {
+		report.ProducerConstraints = pe
+		report.addCheck(COMPAT_CHECK_PRODUCER_CONSTRAINTS, pe.Satisfied, constraintReason(pe, "Consumer properties do not satisfy the producer constraints"))
+	}
+
+	if _, err := (&producer_policy.AgreementProtocols).Intersects_With(&consumer_policy.AgreementProtocols); err != nil {
+		report.addCheck(COMPAT_CHECK_AGREEMENT_PROTOCOLS, false, fmt.Sprintf("No common Agreement Protocols between %v and %v. Underlying error: %v", producer_policy.AgreementProtocols, consumer_policy.AgreementProtocols, err))
+	} else {
+		report.addCheck(COMPAT_CHECK_AGREEMENT_PROTOCOLS, true, "")
+	}
+
+	if !producer_policy.DataVerify.IsCompatibleWith(consumer_policy.DataVerify) {
+		report.addCheck(COMPAT_CHECK_DATA_VERIFICATION, false, fmt.Sprintf("Data verification must be compatible, producer has %v and consumer has %v.", producer_policy.DataVerify, consumer_policy.DataVerify))
+	} else {
+		report.addCheck(COMPAT_CHECK_DATA_VERIFICATION, true, "")
+	}
+
+	return report, nil
+}
+
+// Summarize an unsatisfied constraint explanation in one line.
+func constraintReason(ce *externalpolicy.ConstraintExplanation, prefix string) string {
+	if ce.Satisfied {
+		return ""
+	}
+
+	unsatisfied := make([]string, 0)
+	for _, clause := range ce.Clauses {
+		if !clause.Satisfied {
+			unsatisfied = append(unsatisfied, clause.Constraint)
+		}
+	}
+
+	reason := fmt.Sprintf("%v: %v.", prefix, strings.Join(unsatisfied, ", "))
+	if len(ce.MissingProperties) != 0 {
+		reason += fmt.Sprintf(" Missing properties: %v.", ce.MissingProperties)
+	}
+	if len(ce.TypeMismatches) != 0 {
+		reason += fmt.Sprintf(" Properties with mismatched types: %v.", ce.TypeMismatches)
+	}
+	return reason
+}
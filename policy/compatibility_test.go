@@ -0,0 +1,48 @@
+// +build unit
+
+package policy
+
+import (
+	"github.com/open-horizon/anax/externalpolicy"
+	_ "github.com/open-horizon/anax/externalpolicy/text_language"
+	"testing"
+)
+
+// Verify that the compatibility report agrees with Are_Compatible and explains the constraint checks.
+func Test_Explain_Compatibility(t *testing.T) {
+
+	producer := Policy_Factory("node policy")
+	producer.Add_Property(externalpolicy.Property_Factory("purpose", "network-testing"), false)
+	producer.Add_Property(externalpolicy.Property_Factory("cpu", float64(2)), false)
+	producer.Add_Constraints(&externalpolicy.ConstraintExpression{"iame2edev == true"})
+
+	consumer := Policy_Factory("business policy")
+	consumer.Add_Property(externalpolicy.Property_Factory("iame2edev", true), false)
+	consumer.Add_Constraints(&externalpolicy.ConstraintExpression{"purpose == network-testing && cpu >= 2"})
+
+	if report, err := Explain_Compatibility(producer, consumer); err != nil {
+		t.Errorf("Error: unable to explain compatibility: %v", err)
+	} else if !report.Compatible {
+		t.Errorf("Error: policies should be compatible, report: %v", report)
+	} else if Are_Compatible(producer, consumer) != nil {
+		t.Errorf("Error: report should agree with Are_Compatible, report: %v", report)
+	} else if len(report.Checks) != 5 {
+		t.Errorf("Error: report should have 5 checks, has %v", report.Checks)
+	}
+
+	// the consumer requires more cpus than the node has, and a property the node does not have
+	consumer.Add_Constraints(&externalpolicy.ConstraintExpression{"cpu >= 4", "location == us"})
+	if report, err := Explain_Compatibility(producer, consumer); err != nil {
+		t.Errorf("Error: unable to explain compatibility: %v", err)
+	} else if report.Compatible {
+		t.Errorf("Error: policies should not be compatible, report: %v", report)
+	} else if Are_Compatible(producer, consumer) == nil {
+		t.Errorf("Error: report should agree with Are_Compatible, report: %v", report)
+	} else if report.ConsumerConstraints.Satisfied || !report.ProducerConstraints.Satisfied {
+		t.Errorf("Error: only the consumer constraints should fail, report: %v", report)
+	} else if len(report.ConsumerConstraints.MissingProperties) != 1 || report.ConsumerConstraints.MissingProperties[0] != "location" {
+		t.Errorf("Error: location should be missing, report: %v", report)
+	} else if report.Checks[1].Name != COMPAT_CHECK_CONSUMER_CONSTRAINTS || report.Checks[1].Compatible || report.Checks[1].Reason == "" {
+		t.Errorf("Error: wrong consumer constraint check %v", report.Checks[1])
+	}
+}
@@ -12,10 +12,24 @@ type Input struct {
 	Type  string      `json:"type,omitempty"`
 }
 
+// The type of the user inputs that hold a secret, such as a password or an API key. The value of a secret is kept on
+// the node, encrypted, and given to the service containers as a file. The exchange and the node APIs only see the
+// SECRET_PLACEHOLDER in its place.
+const USER_INPUT_TYPE_SECRET = "secret"
+const SECRET_PLACEHOLDER = "********"
+
 func (s Input) String() string {
+	value := s.Value
+	if s.IsSecret() {
+		value = SECRET_PLACEHOLDER
+	}
 	return fmt.Sprintf("Name: %v, "+
 		"Value: %v",
-		s.Name, s.Value)
+		s.Name, value)
+}
+
+func (s Input) IsSecret() bool {
+	return s.Type == USER_INPUT_TYPE_SECRET
 }
 
 type UserInput struct {
@@ -48,6 +62,76 @@ func (s UserInput) Copy() UserInput {
 	return out_ui
 }
 
+// Split the secret inputs from the user input. The public user input has the SECRET_PLACEHOLDER in place of the value
+// of each secret, the secrets have only the secret inputs. A secret whose value is the placeholder is left out of the
+// secrets, the value the node already has is kept.
+func SplitSecretUserInput(userInput []UserInput) ([]UserInput, []UserInput) {
+	public := make([]UserInput, 0, len(userInput))
+	secrets := make([]UserInput, 0)
+	for _, ui := range userInput {
+		pub := ui.Copy()
+		sec := ui.Copy()
+		sec.Inputs = []Input{}
+		for i, in := range pub.Inputs {
+			if !in.IsSecret() {
+				continue
+			}
+			if in.Value != SECRET_PLACEHOLDER {
+				sec.Inputs = append(sec.Inputs, in)
+			}
+			pub.Inputs[i].Value = SECRET_PLACEHOLDER
+		}
+		public = append(public, pub)
+		if len(sec.Inputs) != 0 {
+			secrets = append(secrets, sec)
+		}
+	}
+	return public, secrets
+}
+
+// Mark the inputs that the service definitions declare as secrets as secret inputs, so that the value of a declared
+// secret stays on the node even when the client did not give it the secret type. An input of a declared secret with
+// another type is an error. The user input is not changed, the classified copy is returned.
+func ClassifySecretUserInput(userInput []UserInput, declaredSecret func(ui UserInput, name string) bool) ([]UserInput, error) {
+	classified := make([]UserInput, 0, len(userInput))
+	for _, ui := range userInput {
+		out := ui.Copy()
+		for i, in := range out.Inputs {
+			if in.IsSecret() || !declaredSecret(ui, in.Name) {
+				continue
+			} else if in.Type != "" {
+				return nil, fmt.Errorf("input %v of service %v/%v is declared as a secret, it cannot have type %v", in.Name, ui.ServiceOrgid, ui.ServiceUrl, in.Type)
+			}
+			out.Inputs[i].Type = USER_INPUT_TYPE_SECRET
+		}
+		classified = append(classified, out)
+	}
+	return classified, nil
+}
+
+// Remove the secrets that no longer have a placeholder in the public user input.
+func PruneSecretUserInput(secrets []UserInput, public []UserInput) []UserInput {
+	pruned := make([]UserInput, 0, len(secrets))
+	for _, sec := range secrets {
+		out := sec.Copy()
+		out.Inputs = []Input{}
+		for _, in := range sec.Inputs {
+			for _, pub := range public {
+				if pub.ServiceOrgid != sec.ServiceOrgid || pub.ServiceUrl != sec.ServiceUrl || pub.ServiceArch != sec.ServiceArch {
+					continue
+				} else if p := pub.FindInput(in.Name); p != nil && p.IsSecret() {
+					out.Inputs = append(out.Inputs, in)
+					break
+				}
+			}
+		}
+		if len(out.Inputs) != 0 {
+			pruned = append(pruned, out)
+		}
+	}
+	return pruned
+}
+
 // Get the input given the name of the variable
 func (s UserInput) FindInput(name string) *Input {
 	if s.Inputs == nil || len(s.Inputs) == 0 {
@@ -201,6 +285,10 @@ func UpdateSettingsWithUserInputs(userInputs []UserInput, existingUserSettings m
 			if ui.Inputs != nil && len(ui.Inputs) > 0 {
 				if ui.ServiceUrl == svcUrl && ui.ServiceOrgid == svcOrg {
 					for _, item := range ui.Inputs {
+						// secrets are given to the service as files, not as environment variables
+						if item.IsSecret() {
+							continue
+						}
 						found := false
 						for k, _ := range existingUserSettings {
 							if item.Name == k {
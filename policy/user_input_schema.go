@@ -0,0 +1,226 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The user input type whose list elements are typed by the items of the user input schema.
+const USER_INPUT_TYPE_LIST = "list"
+
+// The constraints that a service definition puts on the value of one of its user inputs. It is modelled on JSON schema,
+// a field that is not set does not constrain the value.
+type UserInputSchema struct {
+	Enum      []interface{}    `json:"enum,omitempty"`      // the allowed values.
+	Minimum   *float64         `json:"minimum,omitempty"`   // the smallest allowed number.
+	Maximum   *float64         `json:"maximum,omitempty"`   // the largest allowed number.
+	MinLength *int             `json:"minLength,omitempty"` // the fewest characters in a string or elements in a list.
+	MaxLength *int             `json:"maxLength,omitempty"` // the most characters in a string or elements in a list.
+	Pattern   string           `json:"pattern,omitempty"`   // a regular expression that a string must match, it is not anchored.
+	Type      string           `json:"type,omitempty"`      // the type of each element of a list, only used in items. Valid values are "string", "int", "float", "boolean".
+	Items     *UserInputSchema `json:"items,omitempty"`     // the constraints on each element of a list.
+}
+
+func (s UserInputSchema) String() string {
+	serial, _ := json.Marshal(s)
+	return string(serial)
+}
+
+// Verify that the schema can be used for a user input of the given type. The input type is the type declared by the
+// service definition.
+func (s *UserInputSchema) Validate(inputType string) error {
+	if s == nil {
+		return nil
+	}
+
+	isList := inputType == "list of strings" || inputType == USER_INPUT_TYPE_LIST
+	if s.Items != nil && !isList {
+		return errors.New(fmt.Sprintf("items can only be set for a list, the type is %v", inputType))
+	} else if s.Type != "" {
+		return errors.New("type can only be set in items")
+	} else if s.Items != nil {
+		if inputType == "list of strings" && s.Items.Type != "" && s.Items.Type != "string" {
+			return errors.New(fmt.Sprintf("items type %v does not match the type %v", s.Items.Type, inputType))
+		}
+		itemType := s.Items.itemType()
+		if itemType != "string" && itemType != "int" && itemType != "float" && itemType != "boolean" {
+			return errors.New(fmt.Sprintf("items type %v is not supported, it must be string, int, float or boolean", itemType))
+		}
+		copied := *s.Items
+		copied.Type = ""
+		if err := copied.Validate(itemType); err != nil {
+			return errors.New(fmt.Sprintf("items %v", err))
+		}
+	}
+
+	isNumber := inputType == "int" || inputType == "float"
+	isString := inputType == "string" || inputType == USER_INPUT_TYPE_SECRET
+	if (s.Minimum != nil || s.Maximum != nil) && !isNumber {
+		return errors.New(fmt.Sprintf("minimum and maximum can only be set for an int or a float, the type is %v", inputType))
+	} else if s.Minimum != nil && s.Maximum != nil && *s.Minimum > *s.Maximum {
+		return errors.New(fmt.Sprintf("minimum %v is greater than maximum %v", *s.Minimum, *s.Maximum))
+	} else if (s.MinLength != nil || s.MaxLength != nil) && !isString && !isList {
+		return errors.New(fmt.Sprintf("minLength and maxLength can only be set for a string or a list, the type is %v", inputType))
+	} else if (s.MinLength != nil && *s.MinLength < 0) || (s.MaxLength != nil && *s.MaxLength < 0) {
+		return errors.New("minLength and maxLength cannot be negative")
+	} else if s.MinLength != nil && s.MaxLength != nil && *s.MinLength > *s.MaxLength {
+		return errors.New(fmt.Sprintf("minLength %v is greater than maxLength %v", *s.MinLength, *s.MaxLength))
+	} else if s.Pattern != "" && !isString {
+		return errors.New(fmt.Sprintf("pattern can only be set for a string, the type is %v", inputType))
+	} else if _, err := regexp.Compile(s.Pattern); err != nil {
+		return errors.New(fmt.Sprintf("pattern %v is not a valid regular expression, %v", s.Pattern, err))
+	} else if len(s.Enum) != 0 && isList {
+		return errors.New("enum cannot be set for a list, set it in items")
+	}
+
+	for _, e := range s.Enum {
+		if err := checkSchemaType(e, inputType); err != nil {
+			return errors.New(fmt.Sprintf("enum value %v %v", schemaDisplay(e), err))
+		}
+	}
+	return nil
+}
+
+// Verify that a user input value meets the schema. The input type is the type declared by the service definition, the
+// value of a secret is never shown in the error.
+func (s *UserInputSchema) Check(inputType string, value interface{}) error {
+	if s == nil {
+		return nil
+	}
+
+	shown := schemaDisplay(value)
+	if inputType == USER_INPUT_TYPE_SECRET {
+		shown = SECRET_PLACEHOLDER
+	}
+
+	if len(s.Enum) != 0 {
+		found := false
+		for _, e := range s.Enum {
+			if schemaEqual(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			allowed := make([]string, 0, len(s.Enum))
+			for _, e := range s.Enum {
+				allowed = append(allowed, schemaDisplay(e))
+			}
+			return errors.New(fmt.Sprintf("value %v is not one of the allowed values %v", shown, strings.Join(allowed, ", ")))
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			return errors.New(fmt.Sprintf("value %v is %v characters long, shorter than the minLength %v", shown, len(v), *s.MinLength))
+		} else if s.MaxLength != nil && len(v) > *s.MaxLength {
+			return errors.New(fmt.Sprintf("value %v is %v characters long, longer than the maxLength %v", shown, len(v), *s.MaxLength))
+		} else if s.Pattern != "" {
+			if matched, err := regexp.MatchString(s.Pattern, v); err != nil {
+				return errors.New(fmt.Sprintf("pattern %v is not a valid regular expression, %v", s.Pattern, err))
+			} else if !matched {
+				return errors.New(fmt.Sprintf("value %v does not match the pattern %v", shown, s.Pattern))
+			}
+		}
+
+	case []interface{}:
+		if s.MinLength != nil && len(v) < *s.MinLength {
+			return errors.New(fmt.Sprintf("list %v has %v elements, fewer than the minLength %v", shown, len(v), *s.MinLength))
+		} else if s.MaxLength != nil && len(v) > *s.MaxLength {
+			return errors.New(fmt.Sprintf("list %v has %v elements, more than the maxLength %v", shown, len(v), *s.MaxLength))
+		}
+		itemType := "string"
+		if s.Items != nil {
+			itemType = s.Items.itemType()
+		}
+		for ix, e := range v {
+			if err := checkSchemaType(e, itemType); err != nil {
+				return errors.New(fmt.Sprintf("list element %v with value %v %v", ix, schemaDisplay(e), err))
+			} else if err := s.Items.Check(itemType, e); err != nil {
+				return errors.New(fmt.Sprintf("list element %v %v", ix, err))
+			}
+		}
+
+	default:
+		if n, ok := schemaNumber(value); ok {
+			if s.Minimum != nil && n < *s.Minimum {
+				return errors.New(fmt.Sprintf("value %v is less than the minimum %v", shown, *s.Minimum))
+			} else if s.Maximum != nil && n > *s.Maximum {
+				return errors.New(fmt.Sprintf("value %v is greater than the maximum %v", shown, *s.Maximum))
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the type of the elements of a list, string when it is not set.
+func (s *UserInputSchema) itemType() string {
+	if s == nil || s.Type == "" {
+		return "string"
+	}
+	return s.Type
+}
+
+// Verify that a value has the given user input type. The JSON parser may or may not have decoded the numbers with
+// UseNumber(), both are accepted.
+func checkSchemaType(value interface{}, valueType string) error {
+	switch valueType {
+	case "string", USER_INPUT_TYPE_SECRET:
+		if _, ok := value.(string); !ok {
+			return errors.New("is not a string")
+		}
+	case "int":
+		if n, ok := schemaNumber(value); !ok || n != float64(int64(n)) {
+			return errors.New("is not an int")
+		}
+	case "float":
+		if _, ok := schemaNumber(value); !ok {
+			return errors.New("is not a float")
+		}
+	case "bool", "boolean":
+		if _, ok := value.(bool); !ok {
+			return errors.New("is not a boolean")
+		}
+	}
+	return nil
+}
+
+// Returns the number held by a user input value.
+func schemaNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		if n, err := v.Float64(); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// Returns true if the 2 user input values are the same. Numbers are compared by value.
+func schemaEqual(a interface{}, b interface{}) bool {
+	if na, ok := schemaNumber(a); ok {
+		nb, ok := schemaNumber(b)
+		return ok && na == nb
+	}
+	return schemaDisplay(a) == schemaDisplay(b)
+}
+
+// Returns the JSON form of a user input value so that strings are quoted in the error messages.
+func schemaDisplay(value interface{}) string {
+	if serial, err := json.Marshal(value); err == nil {
+		return string(serial)
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,115 @@
+// +build unit
+
+package policy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func Test_UserInputSchema_Validate(t *testing.T) {
+
+	var s *UserInputSchema
+	if err := s.Validate("string"); err != nil {
+		t.Errorf("a missing schema should be valid, error: %v", err)
+	}
+
+	valid := map[string]string{
+		`{"enum": ["a", "b"], "minLength": 1, "maxLength": 5, "pattern": "^[a-z]+$"}`: "string",
+		`{"minimum": 0, "maximum": 10, "enum": [1, 2]}`:                               "int",
+		`{"minimum": 0.5}`:                                                            "float",
+		`{"pattern": "^[a-z]+$"}`:                                                     "secret",
+		`{"minLength": 1, "items": {"pattern": "^[a-z]+$"}}`:                          "list of strings",
+		`{"maxLength": 3, "items": {"type": "int", "minimum": 1, "enum": [1, 2, 3]}}`: "list",
+	}
+	for serial, inputType := range valid {
+		s := schemaFromJSON(t, serial)
+		if err := s.Validate(inputType); err != nil {
+			t.Errorf("schema %v for type %v should be valid, error: %v", serial, inputType, err)
+		}
+	}
+
+	invalid := map[string]string{
+		`{"minimum": 0}`:                               "string",
+		`{"minimum": 5, "maximum": 1}`:                 "int",
+		`{"minLength": 2}`:                             "int",
+		`{"minLength": 3, "maxLength": 1}`:             "string",
+		`{"pattern": "["}`:                             "string",
+		`{"pattern": "^a$"}`:                           "boolean",
+		`{"enum": ["a", 1]}`:                           "string",
+		`{"enum": [1.5]}`:                              "int",
+		`{"items": {"type": "int"}}`:                   "list of strings",
+		`{"items": {"type": "object"}}`:                "list",
+		`{"items": {"minimum": 1}}`:                    "string",
+		`{"enum": [["a"]]}`:                            "list of strings",
+		`{"type": "int"}`:                              "list",
+		`{"items": {"type": "int", "pattern": "^1$"}}`: "list",
+	}
+	for serial, inputType := range invalid {
+		s := schemaFromJSON(t, serial)
+		if err := s.Validate(inputType); err == nil {
+			t.Errorf("schema %v for type %v should not be valid", serial, inputType)
+		}
+	}
+}
+
+func Test_UserInputSchema_Check(t *testing.T) {
+
+	type check struct {
+		schema    string
+		inputType string
+		value     string
+		err       string // the expected error text, empty if the value is valid
+	}
+
+	checks := []check{
+		{`{"enum": ["low", "high"]}`, "string", `"low"`, ""},
+		{`{"enum": ["low", "high"]}`, "string", `"medium"`, `value "medium" is not one of the allowed values "low", "high"`},
+		{`{"minLength": 2, "maxLength": 4}`, "string", `"a"`, `value "a" is 1 characters long, shorter than the minLength 2`},
+		{`{"minLength": 2, "maxLength": 4}`, "string", `"abcde"`, `longer than the maxLength 4`},
+		{`{"pattern": "^[0-9]+$"}`, "string", `"123"`, ""},
+		{`{"pattern": "^[0-9]+$"}`, "string", `"12a"`, `value "12a" does not match the pattern ^[0-9]+$`},
+		{`{"pattern": "^[0-9]+$"}`, "secret", `"12a"`, `value ******** does not match the pattern`},
+		{`{"minimum": 1, "maximum": 10}`, "int", `10`, ""},
+		{`{"minimum": 1, "maximum": 10}`, "int", `0`, `value 0 is less than the minimum 1`},
+		{`{"minimum": 1, "maximum": 10}`, "float", `10.5`, `value 10.5 is greater than the maximum 10`},
+		{`{"enum": [1, 2]}`, "int", `2`, ""},
+		{`{"minLength": 1, "items": {"pattern": "^[a-z]+$"}}`, "list of strings", `["a", "b"]`, ""},
+		{`{"minLength": 1, "items": {"pattern": "^[a-z]+$"}}`, "list of strings", `[]`, `list [] has 0 elements, fewer than the minLength 1`},
+		{`{"minLength": 1, "items": {"pattern": "^[a-z]+$"}}`, "list of strings", `["a", "B"]`, `list element 1 value "B" does not match the pattern ^[a-z]+$`},
+		{`{"items": {"type": "int", "maximum": 5}}`, "list", `[1, 5]`, ""},
+		{`{"items": {"type": "int", "maximum": 5}}`, "list", `[1, 1.5]`, `list element 1 with value 1.5 is not an int`},
+		{`{"items": {"type": "int", "maximum": 5}}`, "list", `[1, 6]`, `list element 1 value 6 is greater than the maximum 5`},
+		{`{"items": {"type": "boolean"}}`, "list", `[true, "x"]`, `list element 1 with value "x" is not a boolean`},
+	}
+
+	for _, c := range checks {
+		s := schemaFromJSON(t, c.schema)
+		var value interface{}
+		if err := json.Unmarshal([]byte(c.value), &value); err != nil {
+			t.Errorf("unable to unmarshal %v, error %v", c.value, err)
+		}
+
+		err := s.Check(c.inputType, value)
+		if c.err == "" && err != nil {
+			t.Errorf("value %v should meet schema %v, error: %v", c.value, c.schema, err)
+		} else if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("value %v should fail schema %v with %v, error: %v", c.value, c.schema, c.err, err)
+		}
+	}
+
+	// numbers decoded with UseNumber() are checked the same way
+	s := schemaFromJSON(t, `{"maximum": 10}`)
+	if err := s.Check("int", json.Number("11")); err == nil {
+		t.Errorf("json.Number 11 should be greater than the maximum 10")
+	}
+}
+
+func schemaFromJSON(t *testing.T, serial string) *UserInputSchema {
+	s := new(UserInputSchema)
+	if err := json.Unmarshal([]byte(serial), s); err != nil {
+		t.Errorf("unable to unmarshal schema %v, error %v", serial, err)
+	}
+	return s
+}
@@ -310,3 +310,74 @@ func Test_UpdateSettingsWithUserInputs(t *testing.T) {
 	}
 
 }
+
+func Test_SplitSecretUserInput(t *testing.T) {
+	userInput := []UserInput{
+		UserInput{
+			ServiceOrgid: "mycomp",
+			ServiceUrl:   "cpu",
+			Inputs:       []Input{Input{Name: "var1", Value: "val1"}, Input{Name: "password", Value: "s3cr3t", Type: USER_INPUT_TYPE_SECRET}, Input{Name: "apikey", Value: SECRET_PLACEHOLDER, Type: USER_INPUT_TYPE_SECRET}},
+		},
+		UserInput{
+			ServiceOrgid: "mycomp",
+			ServiceUrl:   "gps",
+			Inputs:       []Input{Input{Name: "var1", Value: "val1"}},
+		},
+	}
+
+	public, secrets := SplitSecretUserInput(userInput)
+	if len(public) != 2 || public[0].FindInput("password").Value != SECRET_PLACEHOLDER || public[0].FindInput("var1").Value != "val1" {
+		t.Errorf("the public user input should have the placeholder in place of the secrets, but got %v", public)
+	} else if userInput[0].Inputs[1].Value != "s3cr3t" {
+		t.Errorf("the original user input should not be changed, but got %v", userInput[0].Inputs[1])
+	} else if len(secrets) != 1 || len(secrets[0].Inputs) != 1 || secrets[0].Inputs[0].Value != "s3cr3t" {
+		t.Errorf("the secrets should only have the password of cpu, but got %v", secrets)
+	}
+
+	if settings, err := UpdateSettingsWithUserInputs(public, map[string]string{}, "cpu", "mycomp"); err != nil {
+		t.Errorf("UpdateSettingsWithUserInputs should not return errror but got %v", err)
+	} else if _, ok := settings["password"]; ok || len(settings) != 1 {
+		t.Errorf("the secrets should not be environment variables, but got %v", settings)
+	}
+
+	// the secret is dropped once its placeholder is gone
+	public[0].Inputs = public[0].Inputs[:1]
+	if pruned := PruneSecretUserInput(secrets, public); len(pruned) != 0 {
+		t.Errorf("the secret should be pruned, but got %v", pruned)
+	}
+}
+
+func Test_ClassifySecretUserInput(t *testing.T) {
+	userInput := []UserInput{
+		UserInput{
+			ServiceOrgid: "mycomp",
+			ServiceUrl:   "cpu",
+			Inputs:       []Input{Input{Name: "var1", Value: "val1"}, Input{Name: "password", Value: "s3cr3t"}},
+		},
+	}
+	declaredSecret := func(ui UserInput, name string) bool {
+		return ui.ServiceUrl == "cpu" && name == "password"
+	}
+
+	classified, err := ClassifySecretUserInput(userInput, declaredSecret)
+	if err != nil {
+		t.Errorf("ClassifySecretUserInput should not return error but got %v", err)
+	} else if !classified[0].FindInput("password").IsSecret() || classified[0].FindInput("var1").IsSecret() {
+		t.Errorf("only the declared secret should be a secret, but got %v", classified)
+	} else if userInput[0].Inputs[1].IsSecret() {
+		t.Errorf("the original user input should not be changed, but got %v", userInput[0].Inputs[1])
+	}
+
+	// the untyped value of the declared secret stays on the node
+	public, secrets := SplitSecretUserInput(classified)
+	if public[0].FindInput("password").Value != SECRET_PLACEHOLDER {
+		t.Errorf("the public user input should have the placeholder in place of the declared secret, but got %v", public)
+	} else if len(secrets) != 1 || secrets[0].FindInput("password").Value != "s3cr3t" {
+		t.Errorf("the secrets should have the declared secret, but got %v", secrets)
+	}
+
+	userInput[0].Inputs[1].Type = "string"
+	if _, err := ClassifySecretUserInput(userInput, declaredSecret); err == nil {
+		t.Errorf("ClassifySecretUserInput should return error for a declared secret with another type")
+	}
+}
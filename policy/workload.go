@@ -65,6 +65,7 @@ type Workload struct {
 	Arch                         string           `json:"arch,omitempty"`                           // Added with MS split, refers to the hardware architecture of the workload definition
 	DeploymentOverrides          string           `json:"deployment_overrides,omitempty"`           // Added with MS split, env var overrides for the workload
 	DeploymentOverridesSignature string           `json:"deployment_overrides_signature,omitempty"` // Added with MS split, signature of env var overrides
+	UpgradeTime                  string           `json:"upgrade_time,omitempty"`                   // The maintenance window in which the node should accept upgrades of this workload
 }
 
 func (w Workload) String() string {
@@ -78,9 +79,10 @@ func (w Workload) String() string {
 		"Version: %v, "+
 		"Arch: %v, "+
 		"Deployment Overrides: %v, "+
-		"Deployment Overrides Signature: %v",
+		"Deployment Overrides Signature: %v, "+
+		"Upgrade Time: %v",
 		w.Priority, w.Deployment, w.DeploymentSignature, w.DeploymentUserInfo, w.WorkloadPassword,
-		w.WorkloadURL, w.Org, w.Version, w.Arch, w.DeploymentOverrides, w.DeploymentOverridesSignature)
+		w.WorkloadURL, w.Org, w.Version, w.Arch, w.DeploymentOverrides, w.DeploymentOverridesSignature, w.UpgradeTime)
 }
 
 func (w Workload) ShortString() string {
@@ -112,9 +112,25 @@ func (c *BasicProtocolHandler) HandleExtensionMessages(msg *events.ExchangeDevic
 		return true, !verify.Exists, verify.AgreementId(), nil
 	}
 
+	// The workload prefetch hint is acted on by governance, see GetWorkloadPrefetch.
+	if prefetch, err := c.agreementPH.ValidateWorkloadPrefetch(msg.ProtocolMessage()); err == nil {
+		glog.V(5).Infof(BPHlogString(fmt.Sprintf("extension handler handled workload prefetch for %v", prefetch.AgreementId())))
+		return true, false, prefetch.AgreementId(), nil
+	}
+
 	return false, false, "", nil
 }
 
+// Returns the agreement id and the workload that the agbot is going to upgrade the agreement to, if the message is a
+// workload prefetch hint.
+func (c *BasicProtocolHandler) GetWorkloadPrefetch(msg *events.ExchangeDeviceMessage) (string, *policy.Workload) {
+	if prefetch, err := c.agreementPH.ValidateWorkloadPrefetch(msg.ProtocolMessage()); err != nil {
+		return "", nil
+	} else {
+		return prefetch.AgreementId(), policy.Workload_Factory(prefetch.URL, prefetch.Org, prefetch.Version, prefetch.Arch)
+	}
+}
+
 func (c *BasicProtocolHandler) GetTerminationCode(reason string) uint {
 	switch reason {
 	case TERM_REASON_POLICY_CHANGED:
@@ -157,6 +173,8 @@ func (c *BasicProtocolHandler) GetTerminationCode(reason string) uint {
 		return basicprotocol.CANCEL_SERVICE_SUSPENDED
 	case TERM_REASON_NODE_USERINPUT_CHANGED:
 		return basicprotocol.CANCEL_NODE_USERINPUT_CHANGED
+	case TERM_REASON_CONTAINER_UNHEALTHY:
+		return basicprotocol.CANCEL_CONTAINER_UNHEALTHY
 	default:
 		return 999
 	}
@@ -166,6 +184,11 @@ func (c *BasicProtocolHandler) GetTerminationReason(code uint) string {
 	return basicprotocol.DecodeReasonCode(uint64(code))
 }
 
+// Returns true if the agbot cancelled the agreement so that the node can be upgraded to a different workload version.
+func (c *BasicProtocolHandler) IsUpgradeCancelReason(code uint) bool {
+	return code == basicprotocol.AB_CANCEL_WORKLOAD_UPGRADE || code == basicprotocol.AB_CANCEL_FORCED_UPGRADE
+}
+
 func (c *BasicProtocolHandler) IsBlockchainClientAvailable(typeName string, name string, org string) bool {
 	return true
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/open-horizon/anax/eventlog"
 	"github.com/open-horizon/anax/events"
 	"github.com/open-horizon/anax/exchange"
+	"github.com/open-horizon/anax/metrics"
 	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/anax/policy"
 	"github.com/open-horizon/anax/worker"
@@ -19,6 +20,10 @@ import (
 	"time"
 )
 
+// Metrics for the proposals received from agbots.
+var proposalsReceived = metrics.NewCounter("anax_proposals_received_total", "The number of agreement proposals received from agbots.", "protocol")
+var proposalsRejected = metrics.NewCounter("anax_proposals_rejected_total", "The number of agreement proposals rejected by the node.", "protocol")
+
 func CreateProducerPH(name string, cfg *config.HorizonConfig, db *bolt.DB, pm *policy.PolicyManager, ec exchange.ExchangeContext) ProducerProtocolHandler {
 	if handler := NewBasicProtocolHandler(name, cfg, db, pm, ec); handler != nil {
 		return handler
@@ -37,6 +42,7 @@ type ProducerProtocolHandler interface {
 	GetSendMessage() func(mt interface{}, pay []byte) error
 	GetTerminationCode(reason string) uint
 	GetTerminationReason(code uint) string
+	IsUpgradeCancelReason(code uint) bool
 	SetBlockchainClientAvailable(cmd *BCInitializedCommand)
 	SetBlockchainClientNotAvailable(cmd *BCStoppingCommand)
 	IsBlockchainClientAvailable(typeName string, name string, org string) bool
@@ -44,6 +50,7 @@ type ProducerProtocolHandler interface {
 	IsBlockchainWritable(agreement *persistence.EstablishedAgreement) bool
 	IsAgreementVerifiable(agreement *persistence.EstablishedAgreement) bool
 	HandleExtensionMessages(msg *events.ExchangeDeviceMessage, exchangeMsg *exchange.DeviceMessage) (bool, bool, string, error)
+	GetWorkloadPrefetch(msg *events.ExchangeDeviceMessage) (string, *policy.Workload)
 	UpdateConsumer(ag *persistence.EstablishedAgreement)
 	UpdateConsumers()
 	GetKnownBlockchain(ag *persistence.EstablishedAgreement) (string, string, string)
@@ -182,6 +189,7 @@ func (w *BaseProducerProtocolHandler) HandleProposal(ph abstractprotocol.Protoco
 			ConvertToServiceSpecs(tcPolicy.APISpecs),
 			proposal.ConsumerId(),
 			proposal.Protocol())
+		proposalsReceived.Inc(proposal.Protocol())
 
 		err_log_event := ""
 
@@ -223,6 +231,7 @@ func (w *BaseProducerProtocolHandler) HandleProposal(ph abstractprotocol.Protoco
 				err_log_event = fmt.Sprintf("Respond to proposal with error: %v", err)
 			} else {
 				if !r.ProposalAccepted() {
+					proposalsRejected.Inc(proposal.Protocol())
 					eventlog.LogAgreementEvent2(
 						w.db,
 						persistence.SEVERITY_INFO,
@@ -427,6 +436,11 @@ func (b *BaseProducerProtocolHandler) HandleExtensionMessages(msg *events.Exchan
 	return false, false, "", nil
 }
 
+// Returns the agreement id and the workload of a workload prefetch hint, or nil if the message is not one.
+func (b *BaseProducerProtocolHandler) GetWorkloadPrefetch(msg *events.ExchangeDeviceMessage) (string, *policy.Workload) {
+	return "", nil
+}
+
 func (b *BaseProducerProtocolHandler) UpdateConsumer(ag *persistence.EstablishedAgreement) {}
 
 func (b *BaseProducerProtocolHandler) UpdateConsumers() {}
@@ -466,6 +480,7 @@ const TERM_REASON_IMAGE_SIG_VERIF_FAILURE = "ImageSignatureVerificationFailure"
 const TERM_REASON_NODE_SHUTDOWN = "NodeShutdown"
 const TERM_REASON_SERVICE_SUSPENDED = "ServiceSuspended"
 const TERM_REASON_NODE_USERINPUT_CHANGED = "NodeUserInputChanged"
+const TERM_REASON_CONTAINER_UNHEALTHY = "ContainerUnhealthy"
 
 // ==============================================================================================================
 type ExchangeMessageCommand struct {
@@ -2,11 +2,15 @@ package resource
 
 import (
 	"fmt"
+	"github.com/boltdb/bolt"
 	"github.com/golang/glog"
+	"github.com/open-horizon/anax/persistence"
 	"github.com/open-horizon/edge-sync-service/common"
+	"github.com/open-horizon/edge-sync-service/core/base"
 	"github.com/open-horizon/edge-sync-service/core/security"
 	"net/http"
 	"strings"
+	"time"
 )
 
 // FSSAuthenticate is the plugin for authenticating FSS (ESS) API calls from a service to anax.
@@ -17,6 +21,7 @@ type FSSAuthenticate struct {
 	nodeID    string
 	nodeToken string
 	AuthMgr   *AuthenticationManager
+	db        *bolt.DB
 }
 
 // Start initializes the HorizonAuthenticate plugin.
@@ -49,7 +54,7 @@ func (auth *FSSAuthenticate) Authenticate(request *http.Request) (int, string, s
 	authId := appKey
 
 	// Verify that this identity is still in use. If there is an error, log it and return not authenticated.
-	ok, vers, err := auth.AuthMgr.Authenticate(authId, appSecret)
+	ok, vers, key, err := auth.AuthMgr.Authenticate(authId, appSecret)
 	if err != nil {
 		glog.Errorf(essALS(fmt.Sprintf("unable to verify %v, error %v", authId, err)))
 		return authCode, "", ""
@@ -70,6 +75,11 @@ func (auth *FSSAuthenticate) Authenticate(request *http.Request) (int, string, s
 		authId = fmt.Sprintf("%v/%v/%v", sname[0], vers, sname[1])
 	}
 
+	// Remember which version of an object the service consumed, so that the node can report it.
+	if objectType, objectId, consumed := consumedObjectRequest(request); consumed {
+		auth.recordConsumed(key, appKey, vers, objectType, objectId)
+	}
+
 	glog.V(3).Infof(essALS(fmt.Sprintf("returned authentication result code %v org %v id %v", authCode, auth.nodeOrg, authId)))
 
 	return authCode, auth.nodeOrg, authId
@@ -90,6 +100,50 @@ func (auth *FSSAuthenticate) KeyandSecretForURL(url string) (string, string) {
 	return "", ""
 }
 
+// Save the version of the object that a service is marking as consumed. The request has not been handled by the ESS
+// yet, so the object is still the one the service received. An error is logged but does not fail the request.
+func (auth *FSSAuthenticate) recordConsumed(key string, serviceId string, serviceVersion string, objectType string, objectId string) {
+	if auth.db == nil {
+		return
+	}
+
+	meta, err := base.GetObject(auth.nodeOrg, objectType, objectId)
+	if err != nil {
+		glog.Errorf(essALS(fmt.Sprintf("unable to get object %v %v consumed by %v, error %v", objectType, objectId, serviceId, err)))
+		return
+	} else if meta == nil {
+		glog.Warningf(essALS(fmt.Sprintf("object %v %v consumed by %v not found", objectType, objectId, serviceId)))
+		return
+	}
+
+	co := &persistence.ConsumedObject{
+		ServiceKey:     key,
+		ServiceId:      serviceId,
+		ServiceVersion: serviceVersion,
+		ObjectType:     objectType,
+		ObjectId:       objectId,
+		ObjectVersion:  meta.Version,
+		InstanceId:     meta.InstanceID,
+		ConsumedTime:   uint64(time.Now().Unix()),
+	}
+	if err := persistence.SaveConsumedObject(auth.db, co); err != nil {
+		glog.Errorf(essALS(fmt.Sprintf("unable to save consumed object %v, error %v", co, err)))
+	}
+}
+
+// Returns the type and id of the object when the request marks an object as consumed, which is a PUT on
+// /api/v1/objects/{type}/{id}/consumed.
+func consumedObjectRequest(request *http.Request) (string, string, bool) {
+	if request.Method != http.MethodPut || request.URL == nil || !strings.HasPrefix(request.URL.Path, "/api/v1/objects/") {
+		return "", "", false
+	}
+	parts := strings.Split(strings.TrimPrefix(request.URL.Path, "/api/v1/objects/"), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] != "consumed" {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
 // Logging function
 var essALS = func(v interface{}) string {
 	return fmt.Sprintf("ESS: Authenticator %v", v)
@@ -0,0 +1,33 @@
+// +build unit
+
+package resource
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func Test_consumedObjectRequest(t *testing.T) {
+
+	req, _ := http.NewRequest(http.MethodPut, "https://localhost/api/v1/objects/model/m1/consumed", nil)
+	objectType, objectId, consumed := consumedObjectRequest(req)
+	assert.True(t, consumed, "The request should mark the object as consumed.")
+	assert.Equal(t, "model", objectType)
+	assert.Equal(t, "m1", objectId)
+
+	notConsumed := []string{
+		"https://localhost/api/v1/objects/model/m1/received",
+		"https://localhost/api/v1/objects/model/m1",
+		"https://localhost/api/v1/objects/model//consumed",
+	}
+	for _, url := range notConsumed {
+		req, _ := http.NewRequest(http.MethodPut, url, nil)
+		_, _, consumed := consumedObjectRequest(req)
+		assert.False(t, consumed, "%v should not mark an object as consumed.", url)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, "https://localhost/api/v1/objects/model/m1/consumed", nil)
+	_, _, consumed = consumedObjectRequest(req)
+	assert.False(t, consumed, "A GET should not mark an object as consumed.")
+}
@@ -25,3 +25,17 @@ func GenerateNewCredential(id string, version string) (*AuthenticationCredential
 		Version: version,
 	}, nil
 }
+
+// The token that a credential had before it was rotated. It still authenticates until it expires, so that a running
+// service has time to read the new credential file.
+type PreviousCredential struct {
+	Token   string `json:"token"`
+	Expires int64  `json:"expires"` // seconds since the epoch
+}
+
+// The credential of a service after it has been rotated. The token is not included.
+type RotatedCredential struct {
+	Key     string `json:"key"` // the agreement id or service instance key that the credential belongs to
+	Id      string `json:"id"`
+	Version string `json:"version"`
+}
@@ -9,8 +9,14 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"sync"
+	"time"
 )
 
+// Credential rotations are requested by the resource worker and by the API, each through its own manager. They do not
+// overlap, otherwise the previous token saved by one of them could be overwritten and stop authenticating too soon.
+var rotateLock sync.Mutex
+
 type AuthenticationManager struct {
 	AuthPath string
 }
@@ -38,14 +44,10 @@ func (a *AuthenticationManager) CreateCredential(key string, id string, ver stri
 		return errors.New("unable to generate new authentication token")
 	}
 
-	fileName := path.Join(a.GetCredentialPath(key), config.HZN_FSS_AUTH_FILE)
-
-	if credBytes, err := json.Marshal(cred); err != nil {
-		return errors.New(fmt.Sprintf("unable to marshal new authentication credential, error: %v", err))
-	} else if err := os.MkdirAll(a.GetCredentialPath(key), 0700); err != nil {
+	if err := os.MkdirAll(a.GetCredentialPath(key), 0700); err != nil {
 		return errors.New(fmt.Sprintf("unable to create directory path %v for authentication credential, error: %v", a.GetCredentialPath(key), err))
-	} else if err := ioutil.WriteFile(fileName, credBytes, 0700); err != nil {
-		return errors.New(fmt.Sprintf("unable to write authentication credential file %v, error: %v", fileName, err))
+	} else if err := writeAuthFile(path.Join(a.GetCredentialPath(key), config.HZN_FSS_AUTH_FILE), cred); err != nil {
+		return err
 	}
 
 	glog.V(5).Infof(authLogString(fmt.Sprintf("Created credential for service %v, assigned id %v.", key, id)))
@@ -53,13 +55,14 @@ func (a *AuthenticationManager) CreateCredential(key string, id string, ver stri
 	return nil
 }
 
-// Verify that the input credentials are in the auth manager.
-func (a *AuthenticationManager) Authenticate(authId string, appSecret string) (bool, string, error) {
+// Verify that the input credentials are in the auth manager. Returns the version of the authenticated service and the
+// key of its credential.
+func (a *AuthenticationManager) Authenticate(authId string, appSecret string) (bool, string, string, error) {
 
 	// Iterate through the list of all directories in the auth manager. Each directory represents a running service
 	// that has been assigned FSS (ESS) API credentials.
 	if dirs, err := ioutil.ReadDir(a.AuthPath); err != nil {
-		return false, "", errors.New(fmt.Sprintf("unable to read authentication credential file directories in %v, error: %v", a.AuthPath, err))
+		return false, "", "", errors.New(fmt.Sprintf("unable to read authentication credential file directories in %v, error: %v", a.AuthPath, err))
 	} else {
 		for _, d := range dirs {
 
@@ -69,24 +72,121 @@ func (a *AuthenticationManager) Authenticate(authId string, appSecret string) (b
 			}
 
 			// Demarshal the auth.json file and check to see if the id and pw contained within it matches the input authId and appSecret.
-			authFileName := path.Join(a.GetCredentialPath(d.Name()), config.HZN_FSS_AUTH_FILE)
-			if authFile, err := os.Open(authFileName); err != nil {
-				return false, "", errors.New(fmt.Sprintf("unable to open auth file %v, error: %v", authFileName, err))
-			} else if bytes, err := ioutil.ReadAll(authFile); err != nil {
-				return false, "", errors.New(fmt.Sprintf("unable to read auth file %v, error: %v", authFileName, err))
-			} else {
-				authObj := new(AuthenticationCredential)
-				if err := json.Unmarshal(bytes, authObj); err != nil {
-					return false, "", errors.New(fmt.Sprintf("unable to demarshal auth file %v, error: %v", authFileName, err))
-				} else if authObj.Id == authId && authObj.Token == appSecret {
-					glog.V(5).Infof(authLogString(fmt.Sprintf("Found valid credential for %v.", authId)))
-					return true, authObj.Version, nil
-				}
+			authObj := new(AuthenticationCredential)
+			if err := readAuthFile(path.Join(a.GetCredentialPath(d.Name()), config.HZN_FSS_AUTH_FILE), authObj); err != nil {
+				return false, "", "", err
+			} else if authObj.Id != authId {
+				continue
+			} else if authObj.Token == appSecret {
+				glog.V(5).Infof(authLogString(fmt.Sprintf("Found valid credential for %v.", authId)))
+				return true, authObj.Version, d.Name(), nil
+			}
+
+			// The previous token of a rotated credential is valid until its grace period ends.
+			prevFileName := path.Join(a.GetCredentialPath(d.Name()), config.HZN_FSS_AUTH_PREVIOUS_FILE)
+			prev := new(PreviousCredential)
+			if err := readAuthFile(prevFileName, prev); os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				return false, "", "", err
+			} else if prev.Token == appSecret && time.Now().Unix() < prev.Expires {
+				glog.V(5).Infof(authLogString(fmt.Sprintf("Found valid previous credential for %v.", authId)))
+				return true, authObj.Version, d.Name(), nil
+			}
+		}
+	}
+
+	return false, "", "", nil
+}
+
+// Replace the token of a container authentication credential. The credential file is replaced by a rename so that a
+// service reading it from its mount never sees a partial file, and the previous token keeps authenticating for the
+// grace period so that the service has time to read the new one.
+func (a *AuthenticationManager) RotateCredential(key string, gracePeriodS uint64) (*RotatedCredential, error) {
+	rotateLock.Lock()
+	defer rotateLock.Unlock()
+
+	return a.rotateCredential(key, gracePeriodS)
+}
+
+// Replace the token of a container authentication credential, the caller holds rotateLock.
+func (a *AuthenticationManager) rotateCredential(key string, gracePeriodS uint64) (*RotatedCredential, error) {
+
+	fileName := path.Join(a.GetCredentialPath(key), config.HZN_FSS_AUTH_FILE)
+	cred := new(AuthenticationCredential)
+	if err := readAuthFile(fileName, cred); err != nil {
+		return nil, err
+	}
+
+	newCred, err := GenerateNewCredential(cred.Id, cred.Version)
+	if err != nil {
+		return nil, errors.New("unable to generate new authentication token")
+	}
+
+	prev := &PreviousCredential{
+		Token:   cred.Token,
+		Expires: time.Now().Unix() + int64(gracePeriodS),
+	}
+	if err := writeAuthFile(path.Join(a.GetCredentialPath(key), config.HZN_FSS_AUTH_PREVIOUS_FILE), prev); err != nil {
+		return nil, err
+	} else if err := writeAuthFile(fileName, newCred); err != nil {
+		return nil, err
+	}
+
+	glog.V(3).Infof(authLogString(fmt.Sprintf("Rotated credential for service %v, id %v.", key, cred.Id)))
+
+	return &RotatedCredential{Key: key, Id: cred.Id, Version: cred.Version}, nil
+}
+
+// Rotate the container authentication credentials that are at least maxAgeS seconds old, or all of them when maxAgeS
+// is 0. The previous tokens whose grace period has ended are removed. A credential that cannot be rotated is logged
+// and skipped, so that it does not keep the others from being rotated.
+func (a *AuthenticationManager) RotateCredentials(maxAgeS uint64, gracePeriodS uint64) ([]RotatedCredential, error) {
+	rotateLock.Lock()
+	defer rotateLock.Unlock()
+
+	rotated := make([]RotatedCredential, 0)
+
+	dirs, err := ioutil.ReadDir(a.AuthPath)
+	if os.IsNotExist(err) {
+		return rotated, nil
+	} else if err != nil {
+		return rotated, errors.New(fmt.Sprintf("unable to read authentication credential file directories in %v, error: %v", a.AuthPath, err))
+	}
+
+	now := time.Now()
+	for _, d := range dirs {
+
+		// Skip the SSL cert and key path in the auth filesystem.
+		if d.Name() == "SSL" || !d.IsDir() {
+			continue
+		}
+
+		prevFileName := path.Join(a.GetCredentialPath(d.Name()), config.HZN_FSS_AUTH_PREVIOUS_FILE)
+		prev := new(PreviousCredential)
+		if err := readAuthFile(prevFileName, prev); err == nil && now.Unix() >= prev.Expires {
+			if err := os.Remove(prevFileName); err != nil {
+				glog.Errorf(authLogString(fmt.Sprintf("unable to remove previous credential file %v, error: %v", prevFileName, err)))
 			}
 		}
+
+		if maxAgeS != 0 {
+			if info, err := os.Stat(path.Join(a.GetCredentialPath(d.Name()), config.HZN_FSS_AUTH_FILE)); err != nil {
+				glog.Errorf(authLogString(fmt.Sprintf("unable to read authentication credential file for %v, error: %v", d.Name(), err)))
+				continue
+			} else if now.Sub(info.ModTime()) < time.Duration(maxAgeS)*time.Second {
+				continue
+			}
+		}
+
+		if rc, err := a.rotateCredential(d.Name(), gracePeriodS); err != nil {
+			glog.Errorf(authLogString(fmt.Sprintf("unable to rotate credential for service %v, error: %v", d.Name(), err)))
+		} else {
+			rotated = append(rotated, *rc)
+		}
 	}
 
-	return false, "", nil
+	return rotated, nil
 }
 
 // Remove a container authentication credential from the Agent's host file system.
@@ -113,6 +213,33 @@ func (a *AuthenticationManager) RemoveAll() error {
 	return nil
 }
 
+// Read and demarshal a credential file.
+func readAuthFile(fileName string, obj interface{}) error {
+	if bytes, err := ioutil.ReadFile(fileName); os.IsNotExist(err) {
+		return err
+	} else if err != nil {
+		return errors.New(fmt.Sprintf("unable to read auth file %v, error: %v", fileName, err))
+	} else if err := json.Unmarshal(bytes, obj); err != nil {
+		return errors.New(fmt.Sprintf("unable to demarshal auth file %v, error: %v", fileName, err))
+	}
+	return nil
+}
+
+// Write a credential file through a temporary file in the same directory and a rename.
+func writeAuthFile(fileName string, obj interface{}) error {
+	tmp := path.Join(path.Dir(fileName), "."+path.Base(fileName)+".tmp")
+	if credBytes, err := json.Marshal(obj); err != nil {
+		return errors.New(fmt.Sprintf("unable to marshal authentication credential, error: %v", err))
+	} else if err := ioutil.WriteFile(tmp, credBytes, 0700); err != nil {
+		os.Remove(tmp)
+		return errors.New(fmt.Sprintf("unable to write authentication credential file %v, error: %v", fileName, err))
+	} else if err := os.Rename(tmp, fileName); err != nil {
+		os.Remove(tmp)
+		return errors.New(fmt.Sprintf("unable to write authentication credential file %v, error: %v", fileName, err))
+	}
+	return nil
+}
+
 // Logging function
 var authLogString = func(v interface{}) string {
 	return fmt.Sprintf("Container Authentication Manager: %v", v)
@@ -0,0 +1,82 @@
+// +build unit
+
+package resource
+
+import (
+	"github.com/open-horizon/anax/config"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func Test_RotateCredential(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "ess-auth")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	am := NewAuthenticationManager(dir)
+	assert.Nil(t, am.CreateCredential("ag1", "myorg/svc1", "1.0.0"))
+	assert.Nil(t, am.CreateCredential("ag2", "myorg/svc2", ""))
+
+	old := readToken(t, am, "ag1")
+	ok, vers, key, err := am.Authenticate("myorg/svc1", old)
+	assert.Nil(t, err)
+	assert.True(t, ok, "The new credential should authenticate.")
+	assert.Equal(t, "1.0.0", vers)
+	assert.Equal(t, "ag1", key)
+
+	// both tokens authenticate during the grace period
+	rc, err := am.RotateCredential("ag1", 300)
+	assert.Nil(t, err)
+	assert.Equal(t, RotatedCredential{Key: "ag1", Id: "myorg/svc1", Version: "1.0.0"}, *rc)
+
+	rotated := readToken(t, am, "ag1")
+	assert.NotEqual(t, old, rotated, "The token should change.")
+	for _, token := range []string{old, rotated} {
+		ok, _, _, err := am.Authenticate("myorg/svc1", token)
+		assert.Nil(t, err)
+		assert.True(t, ok, "Both tokens should authenticate during the grace period.")
+	}
+
+	// the previous token of another service does not authenticate
+	ok, _, _, err = am.Authenticate("myorg/svc2", old)
+	assert.Nil(t, err)
+	assert.False(t, ok, "A token should only authenticate its own service.")
+
+	// without a grace period, only the new tokens authenticate and the expired previous tokens are removed
+	all, err := am.RotateCredentials(0, 0)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(all), "All the credentials should be rotated.")
+
+	ok, _, _, err = am.Authenticate("myorg/svc1", rotated)
+	assert.Nil(t, err)
+	assert.False(t, ok, "The previous token should not authenticate after the grace period.")
+
+	ok, _, _, err = am.Authenticate("myorg/svc1", readToken(t, am, "ag1"))
+	assert.Nil(t, err)
+	assert.True(t, ok, "The latest token should authenticate.")
+
+	// credentials that are not yet due are not rotated
+	all, err = am.RotateCredentials(3600, 300)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(all), "No credential is due for rotation.")
+	_, err = os.Stat(path.Join(am.GetCredentialPath("ag1"), config.HZN_FSS_AUTH_PREVIOUS_FILE))
+	assert.True(t, os.IsNotExist(err), "The expired previous token should be removed.")
+
+	// a credential that cannot be rotated does not keep the others from being rotated
+	assert.Nil(t, os.MkdirAll(am.GetCredentialPath("broken"), 0700))
+	all, err = am.RotateCredentials(0, 300)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(all), "The other credentials should be rotated.")
+}
+
+func readToken(t *testing.T, am *AuthenticationManager, key string) string {
+	cred := new(AuthenticationCredential)
+	if err := readAuthFile(path.Join(am.GetCredentialPath(key), config.HZN_FSS_AUTH_FILE), cred); err != nil {
+		t.Errorf("unable to read the credential of %v, error %v", key, err)
+	}
+	return cred.Token
+}
@@ -3,6 +3,7 @@ package resource
 import (
 	"errors"
 	"fmt"
+	"github.com/boltdb/bolt"
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/exchange"
@@ -58,7 +59,7 @@ func (r ResourceManager) String() string {
 		r.org, r.pattern, r.id, r.token)
 }
 
-func (r ResourceManager) StartFileSyncService(am *AuthenticationManager) error {
+func (r ResourceManager) StartFileSyncService(am *AuthenticationManager, db *bolt.DB) error {
 
 	// Generate a self signed certificate to be used for TLS between a service and the embedded ESS API.
 	// The SSL private key is stored in a different location from the certificate so that the services
@@ -142,7 +143,7 @@ func (r ResourceManager) StartFileSyncService(am *AuthenticationManager) error {
 	censorAndDumpConfig()
 
 	// Set the authenticator that we're going to use.
-	security.SetAuthentication(&FSSAuthenticate{nodeOrg: r.org, nodeID: r.id, nodeToken: r.token, AuthMgr: am})
+	security.SetAuthentication(&FSSAuthenticate{nodeOrg: r.org, nodeID: r.id, nodeToken: r.token, AuthMgr: am, db: db})
 
 	// Start the embedded ESS.
 	if err := base.Start("", true); err != nil {
@@ -11,6 +11,9 @@ import (
 	"github.com/open-horizon/anax/worker"
 )
 
+// The number of seconds between checks for service credentials that are due to be rotated.
+const CREDENTIAL_ROTATION_CHECK_S = 60
+
 type ResourceWorker struct {
 	worker.BaseWorker // embedded field
 	db                *bolt.DB
@@ -40,8 +43,13 @@ func NewResourceWorker(name string, config *config.HorizonConfig, db *bolt.DB, a
 	}
 
 	glog.Info(reslog(fmt.Sprintf("Starting Resource worker")))
-	// Establish the no work interval at 1 hour for garbage collection of resources.
-	worker.Start(worker, 3600)
+	// Establish the no work interval at 1 hour for garbage collection of resources, or more often when the service
+	// credentials are rotated so that the rotation is close to the configured interval.
+	noWorkInterval := 3600
+	if config.GetESSCredentialRotationS() != 0 {
+		noWorkInterval = CREDENTIAL_ROTATION_CHECK_S
+	}
+	worker.Start(worker, noWorkInterval)
 	return worker
 }
 
@@ -51,7 +59,7 @@ func (w *ResourceWorker) Messages() chan events.Message {
 
 func (w *ResourceWorker) Initialize() bool {
 	if w.rm.Configured() {
-		if err := w.rm.StartFileSyncService(w.am); err != nil {
+		if err := w.rm.StartFileSyncService(w.am, w.db); err != nil {
 			glog.Errorf(reslog(fmt.Sprintf("Error starting ESS: %v", err)))
 			return false
 		}
@@ -111,6 +119,15 @@ func (w *ResourceWorker) CommandHandler(command worker.Command) bool {
 func (w *ResourceWorker) NoWorkHandler() {
 	glog.V(5).Infof(reslog(fmt.Sprintf("beginning garbage collection.")))
 
+	// Rotate the service credentials that are due, and forget the previous tokens whose grace period has ended.
+	if w.Config.GetESSCredentialRotationS() != 0 {
+		if rotated, err := w.am.RotateCredentials(w.Config.GetESSCredentialRotationS(), w.Config.GetESSCredentialGracePeriodS()); err != nil {
+			glog.Errorf(reslog(fmt.Sprintf("Error rotating service credentials: %v", err)))
+		} else if len(rotated) != 0 {
+			glog.V(3).Infof(reslog(fmt.Sprintf("rotated service credentials %v", rotated)))
+		}
+	}
+
 	glog.V(5).Infof(reslog(fmt.Sprintf("ending garbage collection.")))
 
 }
@@ -124,7 +141,7 @@ func (w *ResourceWorker) handleNodeConfigCommand(cmd *NodeConfigCommand) error {
 		destinationType = "openhorizon/openhorizon.edgenode"
 	}
 	w.rm.NodeConfigUpdate(cmd.msg.Org(), destinationType, cmd.msg.DeviceId(), cmd.msg.Token())
-	return w.rm.StartFileSyncService(w.am)
+	return w.rm.StartFileSyncService(w.am, w.db)
 }
 
 // The node has just been unconfigured so we can stop the file sync service.
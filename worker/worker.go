@@ -5,6 +5,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/open-horizon/anax/config"
 	"github.com/open-horizon/anax/events"
+	"github.com/open-horizon/anax/metrics"
 	"runtime"
 	"time"
 )
@@ -288,6 +289,9 @@ func (w *BaseWorker) internalCommandhandler(worker Worker, command Command) bool
 	return false
 }
 
+// The number of commands waiting on each worker's command queue.
+var commandQueueDepth = metrics.NewGauge("anax_worker_command_queue_depth", "The number of commands waiting to be processed by a worker.", "worker")
+
 // This function kicks off the go routine that the worker's logic runs in.
 func (w *BaseWorker) Start(worker Worker, noWorkInterval int) {
 	go func() {
@@ -295,6 +299,14 @@ func (w *BaseWorker) Start(worker Worker, noWorkInterval int) {
 		// log worker status
 		workerStatusManager.SetWorkerStatus(w.GetName(), STATUS_STARTED)
 
+		// Report the depth of the command queue whenever metrics are collected, until the worker terminates.
+		collectorName := fmt.Sprintf("%v command queue", w.GetName())
+		metrics.RegisterCollector(collectorName, func() { commandQueueDepth.Set(float64(len(w.Commands)), w.GetName()) })
+		defer func() {
+			metrics.UnregisterCollector(collectorName)
+			commandQueueDepth.Delete(w.GetName())
+		}()
+
 		// Allow the worker to initialize itself, or stop it if initialization determines that.
 		if !worker.Initialize() {
 			workerStatusManager.SetWorkerStatus(w.GetName(), STATUS_INIT_FAILED)